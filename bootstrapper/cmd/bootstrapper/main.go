@@ -25,6 +25,7 @@ import (
 	"github.com/edgelesssys/constellation/v2/internal/attestation/gcp"
 	"github.com/edgelesssys/constellation/v2/internal/attestation/qemu"
 	"github.com/edgelesssys/constellation/v2/internal/attestation/simulator"
+	"github.com/edgelesssys/constellation/v2/internal/attestation/tdx"
 	"github.com/edgelesssys/constellation/v2/internal/attestation/vtpm"
 	azurecloud "github.com/edgelesssys/constellation/v2/internal/cloud/azure"
 	"github.com/edgelesssys/constellation/v2/internal/cloud/cloudprovider"
@@ -139,13 +140,22 @@ func main() {
 		openTPM = vtpm.OpenVTPM
 		fs = afero.NewOsFs()
 	case cloudprovider.QEMU:
-		pcrs, err := vtpm.GetSelectedPCRs(vtpm.OpenVTPM, vtpm.QEMUPCRSelection)
-		if err != nil {
-			log.With(zap.Error(err)).Fatalf("Failed to get selected PCRs")
+		var pcrs map[uint32][]byte
+		var err error
+		if tdx.Available() {
+			pcrs, err = tdx.GetSelectedMeasurements()
+			if err != nil {
+				log.With(zap.Error(err)).Fatalf("Failed to get TDX measurements")
+			}
+			issuer = initserver.NewIssuerWrapper(tdx.NewIssuer(), vmtype.QEMUTDX, nil)
+		} else {
+			pcrs, err = vtpm.GetSelectedPCRs(vtpm.OpenVTPM, vtpm.QEMUPCRSelection)
+			if err != nil {
+				log.With(zap.Error(err)).Fatalf("Failed to get selected PCRs")
+			}
+			issuer = initserver.NewIssuerWrapper(qemu.NewIssuer(), vmtype.Unknown, nil)
 		}
 
-		issuer = initserver.NewIssuerWrapper(qemu.NewIssuer(), vmtype.Unknown, nil)
-
 		cloudLogger = qemucloud.NewLogger()
 		metadata := &qemucloud.Metadata{}
 		pcrsJSON, err := json.Marshal(pcrs)
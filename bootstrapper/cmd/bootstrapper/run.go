@@ -10,6 +10,7 @@ import (
 	"context"
 	"net"
 
+	"github.com/edgelesssys/constellation/v2/bootstrapper/internal/certrenewal"
 	"github.com/edgelesssys/constellation/v2/bootstrapper/internal/clean"
 	"github.com/edgelesssys/constellation/v2/bootstrapper/internal/diskencryption"
 	"github.com/edgelesssys/constellation/v2/bootstrapper/internal/initserver"
@@ -49,17 +50,20 @@ func run(issuerWrapper initserver.IssuerWrapper, tpm vtpm.TPMOpenFunc, fileHandl
 		log.With(zap.Error(err)).Fatalf("Failed to check if node was previously bootstrapped")
 	}
 
+	dialer := dialer.New(issuerWrapper, nil, &net.Dialer{})
+	renewer := certrenewal.New(dialer, metadata, kube, log)
+
 	if nodeBootstrapped {
 		if err := kube.StartKubelet(log); err != nil {
 			log.With(zap.Error(err)).Fatalf("Failed to restart kubelet")
 		}
+		renewer.Run(context.Background())
 		return
 	}
 
 	nodeLock := nodelock.New(tpm)
 	initServer := initserver.New(nodeLock, kube, issuerWrapper, fileHandler, log)
 
-	dialer := dialer.New(issuerWrapper, nil, &net.Dialer{})
 	joinClient := joinclient.New(nodeLock, dialer, kube, metadata, log)
 
 	cleaner := clean.New().With(initServer).With(joinClient)
@@ -74,6 +78,10 @@ func run(issuerWrapper initserver.IssuerWrapper, tpm vtpm.TPMOpenFunc, fileHandl
 
 	log.Infof("bootstrapper done")
 	cloudLogger.Disclose("bootstrapper done")
+
+	// keep the node's kubelet certificate valid for the lifetime of the node
+	cleaner.Done()
+	renewer.Run(context.Background())
 }
 
 func getDiskUUID() (string, error) {
@@ -89,6 +97,7 @@ type clusterInitJoiner interface {
 	joinclient.ClusterJoiner
 	initserver.ClusterInitializer
 	StartKubelet(*logger.Logger) error
+	RestartKubelet() error
 }
 
 type metadataAPI interface {
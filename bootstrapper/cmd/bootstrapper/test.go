@@ -8,11 +8,14 @@ package main
 
 import (
 	"context"
+	"time"
 
 	"github.com/edgelesssys/constellation/v2/bootstrapper/internal/kubernetes/k8sapi/resources"
 	"github.com/edgelesssys/constellation/v2/internal/cloud/metadata"
+	"github.com/edgelesssys/constellation/v2/internal/cloud/vmtype"
 	"github.com/edgelesssys/constellation/v2/internal/logger"
 	"github.com/edgelesssys/constellation/v2/internal/role"
+	"github.com/edgelesssys/constellation/v2/internal/versions"
 	kubeadm "k8s.io/kubernetes/cmd/kubeadm/app/apis/kubeadm/v1beta3"
 )
 
@@ -21,14 +24,14 @@ type clusterFake struct{}
 
 // InitCluster fakes bootstrapping a new cluster with the current node being the master, returning the arguments required to join the cluster.
 func (c *clusterFake) InitCluster(
-	context.Context, string, string, []byte, []uint32, bool, []byte, bool,
-	resources.KMSConfig, map[string]string, []byte, bool, *logger.Logger,
+	context.Context, string, string, []byte, []uint32, bool, []byte, vmtype.VMType, string, string, time.Duration,
+	[]byte, resources.KMSConfig, string, map[string]string, []byte, bool, *logger.Logger,
 ) ([]byte, error) {
 	return []byte{}, nil
 }
 
 // JoinCluster will fake joining the current node to an existing cluster.
-func (c *clusterFake) JoinCluster(context.Context, *kubeadm.BootstrapTokenDiscovery, role.Role, string, *logger.Logger) error {
+func (c *clusterFake) JoinCluster(context.Context, *kubeadm.BootstrapTokenDiscovery, role.Role, string, []versions.Component, *logger.Logger) error {
 	return nil
 }
 
@@ -37,6 +40,11 @@ func (c *clusterFake) StartKubelet(*logger.Logger) error {
 	return nil
 }
 
+// RestartKubelet restarts the kubelet service.
+func (c *clusterFake) RestartKubelet() error {
+	return nil
+}
+
 type providerMetadataFake struct{}
 
 func (f *providerMetadataFake) List(ctx context.Context) ([]metadata.InstanceMetadata, error) {
@@ -0,0 +1,221 @@
+/*
+Copyright (c) Edgeless Systems GmbH
+
+SPDX-License-Identifier: AGPL-3.0-only
+*/
+
+/*
+Package certrenewal renews the kubelet certificate of a node before it expires.
+
+The renewed certificate is requested from the join service, which attests the node again
+before issuing a new certificate. After the certificate was written to disk, the kubelet is restarted.
+*/
+package certrenewal
+
+import (
+	"context"
+	"crypto/tls"
+	"crypto/x509"
+	"encoding/pem"
+	"errors"
+	"fmt"
+	"net"
+	"time"
+
+	"github.com/edgelesssys/constellation/v2/bootstrapper/internal/kubelet"
+	"github.com/edgelesssys/constellation/v2/internal/cloud/metadata"
+	"github.com/edgelesssys/constellation/v2/internal/file"
+	"github.com/edgelesssys/constellation/v2/internal/logger"
+	"github.com/edgelesssys/constellation/v2/internal/role"
+	"github.com/edgelesssys/constellation/v2/joinservice/joinproto"
+	"github.com/spf13/afero"
+	"go.uber.org/zap"
+	"google.golang.org/grpc"
+	"k8s.io/utils/clock"
+)
+
+const (
+	// interval is the time between two checks of the certificate.
+	interval = 10 * time.Minute
+	// timeout is the maximum duration of a single renewal.
+	timeout = 2 * time.Minute
+	// renewalThreshold is the fraction of the certificate's validity period
+	// remaining when the certificate is renewed.
+	renewalThreshold = 1.0 / 3
+)
+
+// Renewer renews the kubelet certificate of the node through the join service.
+type Renewer struct {
+	dialer      grpcDialer
+	metadataAPI MetadataAPI
+	kubelet     kubeletRestarter
+	fileHandler file.Handler
+
+	interval         time.Duration
+	timeout          time.Duration
+	renewalThreshold float64
+	clock            clock.WithTicker
+
+	log *logger.Logger
+}
+
+// New creates a new Renewer.
+func New(dial grpcDialer, meta MetadataAPI, kubelet kubeletRestarter, log *logger.Logger) *Renewer {
+	return &Renewer{
+		dialer:           dial,
+		metadataAPI:      meta,
+		kubelet:          kubelet,
+		fileHandler:      file.NewHandler(afero.NewOsFs()),
+		interval:         interval,
+		timeout:          timeout,
+		renewalThreshold: renewalThreshold,
+		clock:            clock.RealClock{},
+		log:              log.Named("cert-renewal"),
+	}
+}
+
+// Run checks the kubelet certificate periodically and renews it when it is about to expire.
+// Run blocks until the context is canceled.
+func (r *Renewer) Run(ctx context.Context) {
+	r.log.With(zap.Duration("interval", r.interval)).Infof("Starting kubelet certificate renewal")
+	ticker := r.clock.NewTicker(r.interval)
+	defer ticker.Stop()
+
+	for {
+		if err := r.renewIfDue(ctx); err != nil {
+			r.log.With(zap.Error(err)).Errorf("Failed to renew kubelet certificate")
+		}
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C():
+		}
+	}
+}
+
+// renewIfDue renews the kubelet certificate if less than the renewal threshold of its validity period remains,
+// or if the certificate on disk is unusable.
+func (r *Renewer) renewIfDue(ctx context.Context) error {
+	cert, err := r.loadCertificate()
+	if err != nil {
+		r.log.With(zap.Error(err)).Warnf("Kubelet certificate is unusable, requesting a new certificate")
+	} else {
+		validity := cert.NotAfter.Sub(cert.NotBefore)
+		renewAt := cert.NotAfter.Add(-time.Duration(float64(validity) * r.renewalThreshold))
+		if r.clock.Now().Before(renewAt) {
+			r.log.With(zap.Time("notAfter", cert.NotAfter)).Debugf("Kubelet certificate does not need renewal")
+			return nil
+		}
+		r.log.With(zap.Time("notAfter", cert.NotAfter)).Infof("Kubelet certificate is about to expire, renewing")
+	}
+
+	ctx, cancel := context.WithTimeout(ctx, r.timeout)
+	defer cancel()
+
+	self, err := r.metadataAPI.Self(ctx)
+	if err != nil {
+		return fmt.Errorf("retrieving own instance metadata: %w", err)
+	}
+	var ips []net.IP
+	if self.VPCIP != "" {
+		ips = append(ips, net.ParseIP(self.VPCIP))
+	}
+	if self.PublicIP != "" {
+		ips = append(ips, net.ParseIP(self.PublicIP))
+	}
+	certificateRequest, kubeletKey, err := kubelet.GetCertificateRequest(self.Name, ips)
+	if err != nil {
+		return fmt.Errorf("creating certificate request: %w", err)
+	}
+
+	endpoints, err := metadata.JoinServiceEndpoints(ctx, r.metadataAPI)
+	if err != nil {
+		return err
+	}
+	if len(endpoints) == 0 {
+		return errors.New("no join service endpoints found")
+	}
+
+	req := &joinproto.RenewKubeletCertificateRequest{
+		CertificateRequest: certificateRequest,
+		IsControlPlane:     self.Role == role.ControlPlane,
+	}
+	var kubeletCert []byte
+	for _, endpoint := range endpoints {
+		kubeletCert, err = r.renew(ctx, endpoint, req)
+		if err == nil {
+			break
+		}
+		r.log.With(zap.String("endpoint", endpoint), zap.Error(err)).Warnf("Renewing kubelet certificate failed")
+	}
+	if err != nil {
+		return fmt.Errorf("renewing kubelet certificate with all join service endpoints: %w", err)
+	}
+
+	if err := r.fileHandler.Write(kubelet.KeyFilename, kubeletKey, file.OptOverwrite, file.OptMkdirAll); err != nil {
+		return fmt.Errorf("writing kubelet key: %w", err)
+	}
+	if err := r.fileHandler.Write(kubelet.CertificateFilename, kubeletCert, file.OptOverwrite, file.OptMkdirAll); err != nil {
+		return fmt.Errorf("writing kubelet certificate: %w", err)
+	}
+
+	r.log.Infof("Restarting kubelet to load renewed certificate")
+	if err := r.kubelet.RestartKubelet(); err != nil {
+		return err
+	}
+	r.log.Infof("Kubelet certificate renewed")
+	return nil
+}
+
+func (r *Renewer) renew(ctx context.Context, endpoint string, req *joinproto.RenewKubeletCertificateRequest) ([]byte, error) {
+	conn, err := r.dialer.Dial(ctx, endpoint)
+	if err != nil {
+		return nil, fmt.Errorf("dialing join service endpoint: %w", err)
+	}
+	defer conn.Close()
+
+	resp, err := joinproto.NewAPIClient(conn).RenewKubeletCertificate(ctx, req)
+	if err != nil {
+		return nil, err
+	}
+	return resp.KubeletCert, nil
+}
+
+// loadCertificate loads the kubelet certificate and verifies it matches the kubelet key.
+func (r *Renewer) loadCertificate() (*x509.Certificate, error) {
+	certPEM, err := r.fileHandler.Read(kubelet.CertificateFilename)
+	if err != nil {
+		return nil, fmt.Errorf("reading kubelet certificate: %w", err)
+	}
+	keyPEM, err := r.fileHandler.Read(kubelet.KeyFilename)
+	if err != nil {
+		return nil, fmt.Errorf("reading kubelet key: %w", err)
+	}
+	// a renewal interrupted after writing the key leaves a mismatching key pair behind
+	if _, err := tls.X509KeyPair(certPEM, keyPEM); err != nil {
+		return nil, fmt.Errorf("loading kubelet key pair: %w", err)
+	}
+	block, _ := pem.Decode(certPEM)
+	if block == nil {
+		return nil, errors.New("kubelet certificate is not PEM encoded")
+	}
+	return x509.ParseCertificate(block.Bytes)
+}
+
+type grpcDialer interface {
+	Dial(ctx context.Context, target string) (*grpc.ClientConn, error)
+}
+
+// MetadataAPI provides information about the instances.
+type MetadataAPI interface {
+	// List retrieves all instances belonging to the current constellation.
+	List(ctx context.Context) ([]metadata.InstanceMetadata, error)
+	// Self retrieves the current instance.
+	Self(ctx context.Context) (metadata.InstanceMetadata, error)
+}
+
+type kubeletRestarter interface {
+	// RestartKubelet restarts the kubelet service.
+	RestartKubelet() error
+}
@@ -0,0 +1,229 @@
+/*
+Copyright (c) Edgeless Systems GmbH
+
+SPDX-License-Identifier: AGPL-3.0-only
+*/
+
+package certrenewal
+
+import (
+	"context"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/pem"
+	"errors"
+	"math/big"
+	"net"
+	"strconv"
+	"testing"
+	"time"
+
+	"github.com/edgelesssys/constellation/v2/bootstrapper/internal/kubelet"
+	"github.com/edgelesssys/constellation/v2/internal/cloud/metadata"
+	"github.com/edgelesssys/constellation/v2/internal/constants"
+	"github.com/edgelesssys/constellation/v2/internal/file"
+	"github.com/edgelesssys/constellation/v2/internal/grpc/atlscredentials"
+	"github.com/edgelesssys/constellation/v2/internal/grpc/dialer"
+	"github.com/edgelesssys/constellation/v2/internal/grpc/testdialer"
+	"github.com/edgelesssys/constellation/v2/internal/logger"
+	"github.com/edgelesssys/constellation/v2/internal/role"
+	"github.com/edgelesssys/constellation/v2/joinservice/joinproto"
+	"github.com/spf13/afero"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/goleak"
+	"google.golang.org/grpc"
+	testclock "k8s.io/utils/clock/testing"
+)
+
+func TestMain(m *testing.M) {
+	goleak.VerifyTestMain(m)
+}
+
+func TestRenewIfDue(t *testing.T) {
+	now := time.Now()
+	someErr := errors.New("failed")
+	renewedCert := []byte("renewed certificate")
+	controlPlane := metadata.InstanceMetadata{Name: "control-plane-0", Role: role.ControlPlane, VPCIP: "192.0.2.1"}
+	worker := metadata.InstanceMetadata{Name: "worker-0", Role: role.Worker, VPCIP: "192.0.2.2"}
+
+	testCases := map[string]struct {
+		notBefore     time.Time
+		notAfter      time.Time
+		noCertificate bool
+		metadata      stubMetadata
+		joinService   stubJoinService
+		restartErr    error
+		wantRenewal   bool
+		wantErr       bool
+	}{
+		"certificate valid": {
+			notBefore: now.Add(-10 * time.Hour),
+			notAfter:  now.Add(90 * time.Hour),
+			metadata:  stubMetadata{self: worker, instances: []metadata.InstanceMetadata{controlPlane, worker}},
+		},
+		"certificate about to expire": {
+			notBefore:   now.Add(-90 * time.Hour),
+			notAfter:    now.Add(10 * time.Hour),
+			metadata:    stubMetadata{self: worker, instances: []metadata.InstanceMetadata{controlPlane, worker}},
+			joinService: stubJoinService{cert: renewedCert},
+			wantRenewal: true,
+		},
+		"certificate expired": {
+			notBefore:   now.Add(-100 * time.Hour),
+			notAfter:    now.Add(-time.Hour),
+			metadata:    stubMetadata{self: controlPlane, instances: []metadata.InstanceMetadata{controlPlane, worker}},
+			joinService: stubJoinService{cert: renewedCert},
+			wantRenewal: true,
+		},
+		"certificate missing": {
+			noCertificate: true,
+			metadata:      stubMetadata{self: worker, instances: []metadata.InstanceMetadata{controlPlane, worker}},
+			joinService:   stubJoinService{cert: renewedCert},
+			wantRenewal:   true,
+		},
+		"renewal denied": {
+			notBefore:   now.Add(-90 * time.Hour),
+			notAfter:    now.Add(10 * time.Hour),
+			metadata:    stubMetadata{self: worker, instances: []metadata.InstanceMetadata{controlPlane, worker}},
+			joinService: stubJoinService{renewErr: someErr},
+			wantErr:     true,
+		},
+		"no join service endpoints": {
+			notBefore: now.Add(-90 * time.Hour),
+			notAfter:  now.Add(10 * time.Hour),
+			metadata:  stubMetadata{self: worker, instances: []metadata.InstanceMetadata{worker}},
+			wantErr:   true,
+		},
+		"metadata unavailable": {
+			notBefore: now.Add(-90 * time.Hour),
+			notAfter:  now.Add(10 * time.Hour),
+			metadata:  stubMetadata{selfErr: someErr},
+			wantErr:   true,
+		},
+		"kubelet restart fails": {
+			notBefore:   now.Add(-90 * time.Hour),
+			notAfter:    now.Add(10 * time.Hour),
+			metadata:    stubMetadata{self: worker, instances: []metadata.InstanceMetadata{controlPlane, worker}},
+			joinService: stubJoinService{cert: renewedCert},
+			restartErr:  someErr,
+			wantRenewal: true,
+			wantErr:     true,
+		},
+	}
+
+	for name, tc := range testCases {
+		t.Run(name, func(t *testing.T) {
+			assert := assert.New(t)
+			require := require.New(t)
+
+			fileHandler := file.NewHandler(afero.NewMemMapFs())
+			var oldCert []byte
+			if !tc.noCertificate {
+				var key []byte
+				oldCert, key = newKeyPair(t, tc.notBefore, tc.notAfter)
+				require.NoError(fileHandler.Write(kubelet.CertificateFilename, oldCert, file.OptMkdirAll))
+				require.NoError(fileHandler.Write(kubelet.KeyFilename, key, file.OptMkdirAll))
+			}
+
+			netDialer := testdialer.NewBufconnDialer()
+			joinServer := grpc.NewServer(grpc.Creds(atlscredentials.New(nil, nil)))
+			joinproto.RegisterAPIServer(joinServer, &tc.joinService)
+			listener := netDialer.GetListener(net.JoinHostPort(controlPlane.VPCIP, strconv.Itoa(constants.JoinServiceNodePort)))
+			go joinServer.Serve(listener)
+			defer joinServer.GracefulStop()
+
+			restarter := &stubKubelet{restartErr: tc.restartErr}
+			renewer := &Renewer{
+				dialer:           dialer.New(nil, nil, netDialer),
+				metadataAPI:      tc.metadata,
+				kubelet:          restarter,
+				fileHandler:      fileHandler,
+				timeout:          time.Minute,
+				renewalThreshold: renewalThreshold,
+				clock:            testclock.NewFakeClock(now),
+				log:              logger.NewTest(t),
+			}
+
+			err := renewer.renewIfDue(context.Background())
+			if tc.wantErr {
+				assert.Error(err)
+			} else {
+				assert.NoError(err)
+			}
+
+			cert, readErr := fileHandler.Read(kubelet.CertificateFilename)
+			if tc.wantRenewal {
+				require.NoError(readErr)
+				assert.Equal(tc.joinService.cert, cert)
+				assert.True(restarter.restarted)
+				assert.NotEmpty(tc.joinService.req.CertificateRequest)
+				assert.Equal(tc.metadata.self.Role == role.ControlPlane, tc.joinService.req.IsControlPlane)
+				return
+			}
+			assert.False(restarter.restarted)
+			if !tc.noCertificate {
+				assert.Equal(oldCert, cert)
+			}
+		})
+	}
+}
+
+func newKeyPair(t *testing.T, notBefore, notAfter time.Time) (certPEM, keyPEM []byte) {
+	t.Helper()
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	require.NoError(t, err)
+	tmpl := &x509.Certificate{
+		SerialNumber: big.NewInt(1),
+		Subject:      pkix.Name{CommonName: "system:node:worker-0"},
+		NotBefore:    notBefore,
+		NotAfter:     notAfter,
+	}
+	certDER, err := x509.CreateCertificate(rand.Reader, tmpl, tmpl, &key.PublicKey, key)
+	require.NoError(t, err)
+	keyDER, err := x509.MarshalECPrivateKey(key)
+	require.NoError(t, err)
+	return pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: certDER}),
+		pem.EncodeToMemory(&pem.Block{Type: "EC PRIVATE KEY", Bytes: keyDER})
+}
+
+type stubMetadata struct {
+	self      metadata.InstanceMetadata
+	selfErr   error
+	instances []metadata.InstanceMetadata
+	listErr   error
+}
+
+func (s stubMetadata) Self(context.Context) (metadata.InstanceMetadata, error) {
+	return s.self, s.selfErr
+}
+
+func (s stubMetadata) List(context.Context) ([]metadata.InstanceMetadata, error) {
+	return s.instances, s.listErr
+}
+
+type stubJoinService struct {
+	cert     []byte
+	renewErr error
+	req      *joinproto.RenewKubeletCertificateRequest
+
+	joinproto.UnimplementedAPIServer
+}
+
+func (s *stubJoinService) RenewKubeletCertificate(_ context.Context, req *joinproto.RenewKubeletCertificateRequest) (*joinproto.RenewKubeletCertificateResponse, error) {
+	s.req = req
+	return &joinproto.RenewKubeletCertificateResponse{KubeletCert: s.cert}, s.renewErr
+}
+
+type stubKubelet struct {
+	restarted  bool
+	restartErr error
+}
+
+func (s *stubKubelet) RestartKubelet() error {
+	s.restarted = true
+	return s.restartErr
+}
@@ -19,6 +19,7 @@ import (
 	"github.com/edgelesssys/constellation/v2/internal/atls"
 	"github.com/edgelesssys/constellation/v2/internal/attestation"
 	"github.com/edgelesssys/constellation/v2/internal/cloud/vmtype"
+	"github.com/edgelesssys/constellation/v2/internal/constants"
 	"github.com/edgelesssys/constellation/v2/internal/crypto"
 	"github.com/edgelesssys/constellation/v2/internal/file"
 	"github.com/edgelesssys/constellation/v2/internal/grpc/atlscredentials"
@@ -112,7 +113,8 @@ func (s *Server) Init(ctx context.Context, req *initproto.InitRequest) (*initpro
 		return nil, status.Error(codes.FailedPrecondition, "node is already being activated")
 	}
 
-	if err := s.setupDisk(req.MasterSecret, req.Salt); err != nil {
+	diskUUID, err := s.setupDisk(req.MasterSecret, req.Salt)
+	if err != nil {
 		return nil, status.Errorf(codes.Internal, "setting up disk: %s", err)
 	}
 
@@ -131,7 +133,11 @@ func (s *Server) Init(ctx context.Context, req *initproto.InitRequest) (*initpro
 		req.EnforcedPcrs,
 		req.EnforceIdkeydigest,
 		s.issuerWrapper.IdKeyDigest(),
-		s.issuerWrapper.VMType() == vmtype.AzureCVM,
+		s.issuerWrapper.VMType(),
+		req.AttestationPolicy,
+		req.EkCertificateAuthority,
+		kubeletCertValidity(req.KubeletCertificateValidityDays),
+		req.OwnerPublicKey,
 		resources.KMSConfig{
 			MasterSecret:       req.MasterSecret,
 			Salt:               req.Salt,
@@ -140,6 +146,7 @@ func (s *Server) Init(ctx context.Context, req *initproto.InitRequest) (*initpro
 			KeyEncryptionKeyID: req.KeyEncryptionKeyId,
 			UseExistingKEK:     req.UseExistingKek,
 		},
+		diskUUID,
 		sshProtoKeysToMap(req.SshUserKeys),
 		req.HelmDeployments,
 		req.ConformanceMode,
@@ -156,29 +163,37 @@ func (s *Server) Init(ctx context.Context, req *initproto.InitRequest) (*initpro
 	}, nil
 }
 
+// kubeletCertValidity returns the validity of kubelet certificates requested by the user, or the default validity.
+func kubeletCertValidity(days uint32) time.Duration {
+	if days == 0 {
+		return constants.KubeletCertificateValidity
+	}
+	return time.Duration(days) * 24 * time.Hour
+}
+
 // Stop stops the initialization server gracefully.
 func (s *Server) Stop() {
 	s.grpcServer.GracefulStop()
 }
 
-func (s *Server) setupDisk(masterSecret, salt []byte) error {
+func (s *Server) setupDisk(masterSecret, salt []byte) (string, error) {
 	if err := s.disk.Open(); err != nil {
-		return fmt.Errorf("opening encrypted disk: %w", err)
+		return "", fmt.Errorf("opening encrypted disk: %w", err)
 	}
 	defer s.disk.Close()
 
 	uuid, err := s.disk.UUID()
 	if err != nil {
-		return fmt.Errorf("retrieving uuid of disk: %w", err)
+		return "", fmt.Errorf("retrieving uuid of disk: %w", err)
 	}
 	uuid = strings.ToLower(uuid)
 
 	diskKey, err := crypto.DeriveKey(masterSecret, salt, []byte(crypto.HKDFInfoPrefix+uuid), crypto.DerivedKeyLengthDefault)
 	if err != nil {
-		return err
+		return "", err
 	}
 
-	return s.disk.UpdatePassphrase(string(diskKey))
+	return uuid, s.disk.UpdatePassphrase(string(diskKey))
 }
 
 type IssuerWrapper struct {
@@ -239,8 +254,13 @@ type ClusterInitializer interface {
 		enforcedPcrs []uint32,
 		enforceIdKeyDigest bool,
 		idKeyDigest []byte,
-		azureCVM bool,
+		vmType vmtype.VMType,
+		attestationPolicy string,
+		ekCertificateAuthority string,
+		kubeletCertValidity time.Duration,
+		ownerPublicKey []byte,
 		kmsConfig resources.KMSConfig,
+		diskUUID string,
 		sshUserKeys map[string]string,
 		helmDeployments []byte,
 		conformanceMode bool,
@@ -17,6 +17,7 @@ import (
 
 	"github.com/edgelesssys/constellation/v2/bootstrapper/initproto"
 	"github.com/edgelesssys/constellation/v2/bootstrapper/internal/kubernetes/k8sapi/resources"
+	"github.com/edgelesssys/constellation/v2/internal/cloud/vmtype"
 	"github.com/edgelesssys/constellation/v2/internal/crypto/testvector"
 	"github.com/edgelesssys/constellation/v2/internal/file"
 	"github.com/edgelesssys/constellation/v2/internal/logger"
@@ -228,7 +229,9 @@ func TestSetupDisk(t *testing.T) {
 				disk: disk,
 			}
 
-			assert.NoError(server.setupDisk(tc.masterSecret, tc.salt))
+			uuid, err := server.setupDisk(tc.masterSecret, tc.salt)
+			assert.NoError(err)
+			assert.Equal(strings.ToLower(tc.uuid), uuid)
 		})
 	}
 }
@@ -289,8 +292,8 @@ type stubClusterInitializer struct {
 }
 
 func (i *stubClusterInitializer) InitCluster(
-	context.Context, string, string, []byte, []uint32, bool, []byte, bool,
-	resources.KMSConfig, map[string]string, []byte, bool, *logger.Logger,
+	context.Context, string, string, []byte, []uint32, bool, []byte, vmtype.VMType, string, string, time.Duration,
+	[]byte, resources.KMSConfig, string, map[string]string, []byte, bool, *logger.Logger,
 ) ([]byte, error) {
 	return i.initClusterKubeconfig, i.initClusterErr
 }
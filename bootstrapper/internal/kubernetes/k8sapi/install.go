@@ -10,6 +10,8 @@ import (
 	"archive/tar"
 	"compress/gzip"
 	"context"
+	"crypto/sha256"
+	"encoding/hex"
 	"errors"
 	"fmt"
 	"io"
@@ -55,6 +57,23 @@ func newOSInstaller() *osInstaller {
 func (i *osInstaller) Install(
 	ctx context.Context, sourceURL string, destinations []string, perm fs.FileMode,
 	extract bool, transforms ...transform.Transformer,
+) error {
+	return i.install(ctx, sourceURL, "", destinations, perm, extract, transforms...)
+}
+
+// InstallVerified is like Install, but only installs the resource if the hex encoded SHA256 checksum of the download matches checksum.
+func (i *osInstaller) InstallVerified(
+	ctx context.Context, sourceURL, checksum string, destinations []string, perm fs.FileMode, extract bool,
+) error {
+	if checksum == "" {
+		return fmt.Errorf("installing from %q: no checksum given", sourceURL)
+	}
+	return i.install(ctx, sourceURL, checksum, destinations, perm, extract)
+}
+
+func (i *osInstaller) install(
+	ctx context.Context, sourceURL, checksum string, destinations []string, perm fs.FileMode,
+	extract bool, transforms ...transform.Transformer,
 ) error {
 	tempPath, err := i.retryDownloadToTempDir(ctx, sourceURL, transforms...)
 	if err != nil {
@@ -63,6 +82,11 @@ func (i *osInstaller) Install(
 	defer func() {
 		_ = i.fs.Remove(tempPath)
 	}()
+	if checksum != "" {
+		if err := i.verifyChecksum(tempPath, checksum); err != nil {
+			return fmt.Errorf("installing from %q: %w", sourceURL, err)
+		}
+	}
 	for _, destination := range destinations {
 		var err error
 		if extract {
@@ -77,6 +101,23 @@ func (i *osInstaller) Install(
 	return nil
 }
 
+// verifyChecksum checks that the hex encoded SHA256 checksum of the file at path is checksum.
+func (i *osInstaller) verifyChecksum(path, checksum string) error {
+	file, err := i.fs.Open(path)
+	if err != nil {
+		return fmt.Errorf("opening download: %w", err)
+	}
+	defer file.Close()
+	hash := sha256.New()
+	if _, err := io.Copy(hash, file); err != nil {
+		return fmt.Errorf("hashing download: %w", err)
+	}
+	if got := hex.EncodeToString(hash.Sum(nil)); got != checksum {
+		return fmt.Errorf("checksum mismatch: expected %s, got %s", checksum, got)
+	}
+	return nil
+}
+
 // extractArchive extracts tar gz archives to a prefixed destination.
 func (i *osInstaller) extractArchive(archivePath, prefix string, perm fs.FileMode) error {
 	archiveFile, err := i.fs.Open(archivePath)
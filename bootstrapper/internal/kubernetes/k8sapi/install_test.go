@@ -18,6 +18,7 @@ import (
 	"net/http"
 	"net/http/httptest"
 	"path"
+	"strings"
 	"sync"
 	"testing"
 	"time"
@@ -38,6 +39,7 @@ func TestInstall(t *testing.T) {
 		destination string
 		extract     bool
 		transforms  []transform.Transformer
+		checksum    string
 		readonly    bool
 		wantErr     bool
 		wantFiles   map[string][]byte
@@ -66,6 +68,18 @@ func TestInstall(t *testing.T) {
 			destination: "/destination",
 			wantErr:     true,
 		},
+		"download with matching checksum works": {
+			server:      newHTTPBufconnServerWithBody([]byte("file-contents")),
+			destination: "/destination",
+			checksum:    "f03779b36bece74893fd6533a67549675e21573eb0e288d87158738f9c24594e",
+			wantFiles:   map[string][]byte{"/destination": []byte("file-contents")},
+		},
+		"download with mismatching checksum fails": {
+			server:      newHTTPBufconnServerWithBody([]byte("file-contents")),
+			destination: "/destination",
+			checksum:    strings.Repeat("0", 64),
+			wantErr:     true,
+		},
 	}
 
 	for name, tc := range testCases {
@@ -92,9 +106,16 @@ func TestInstall(t *testing.T) {
 				retriable: func(err error) bool { return false },
 			}
 
-			err := inst.Install(context.Background(), "http://server/path", []string{tc.destination}, fs.ModePerm, tc.extract, tc.transforms...)
+			var err error
+			if tc.checksum != "" {
+				err = inst.InstallVerified(context.Background(), "http://server/path", tc.checksum, []string{tc.destination}, fs.ModePerm, tc.extract)
+			} else {
+				err = inst.Install(context.Background(), "http://server/path", []string{tc.destination}, fs.ModePerm, tc.extract, tc.transforms...)
+			}
 			if tc.wantErr {
 				assert.Error(err)
+				_, statErr := inst.fs.Stat(tc.destination)
+				assert.Error(statErr)
 				return
 			}
 
@@ -231,6 +231,24 @@ func (k *KubeadmJoinYAML) SetNodeIP(nodeIP string) {
 	}
 }
 
+// SetNodeLabels sets the labels the kubelet registers the node with, as comma separated list of key=value pairs.
+func (k *KubeadmJoinYAML) SetNodeLabels(labels string) {
+	if k.JoinConfiguration.NodeRegistration.KubeletExtraArgs == nil {
+		k.JoinConfiguration.NodeRegistration.KubeletExtraArgs = map[string]string{"node-labels": labels}
+	} else {
+		k.JoinConfiguration.NodeRegistration.KubeletExtraArgs["node-labels"] = labels
+	}
+}
+
+// SetNodeTaints sets the taints the kubelet registers the node with, as comma separated list in the format key[=value]:effect.
+func (k *KubeadmJoinYAML) SetNodeTaints(taints string) {
+	if k.JoinConfiguration.NodeRegistration.KubeletExtraArgs == nil {
+		k.JoinConfiguration.NodeRegistration.KubeletExtraArgs = map[string]string{"register-with-taints": taints}
+	} else {
+		k.JoinConfiguration.NodeRegistration.KubeletExtraArgs["register-with-taints"] = taints
+	}
+}
+
 func (k *KubeadmJoinYAML) SetProviderID(providerID string) {
 	k.KubeletConfiguration.ProviderID = providerID
 }
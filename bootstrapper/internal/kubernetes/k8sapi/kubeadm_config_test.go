@@ -109,6 +109,8 @@ func TestJoinConfiguration(t *testing.T) {
 				c.SetToken("token")
 				c.AppendDiscoveryTokenCaCertHash("discovery-token-ca-cert-hash")
 				c.SetProviderID("somecloudprovider://instance-id")
+				c.SetNodeLabels("pool=highmem")
+				c.SetNodeTaints("pool=highmem:NoSchedule")
 				c.SetControlPlane("192.0.2.0")
 				return c
 			}(),
@@ -8,6 +8,7 @@ package resources
 
 import (
 	"fmt"
+	"time"
 
 	"github.com/edgelesssys/constellation/v2/internal/cloud/cloudprovider"
 	"github.com/edgelesssys/constellation/v2/internal/constants"
@@ -16,21 +17,34 @@ import (
 	apps "k8s.io/api/apps/v1"
 	k8s "k8s.io/api/core/v1"
 	rbac "k8s.io/api/rbac/v1"
+	apiextensions "k8s.io/apiextensions-apiserver/pkg/apis/apiextensions/v1"
 	meta "k8s.io/apimachinery/pkg/apis/meta/v1"
 	"k8s.io/apimachinery/pkg/util/intstr"
 )
 
 type joinServiceDaemonset struct {
-	ClusterRole        rbac.ClusterRole
-	ClusterRoleBinding rbac.ClusterRoleBinding
-	ConfigMap          k8s.ConfigMap
-	DaemonSet          apps.DaemonSet
-	ServiceAccount     k8s.ServiceAccount
-	Service            k8s.Service
+	NodeAttestationCRD      apiextensions.CustomResourceDefinition
+	RevokedNodeCRD          apiextensions.CustomResourceDefinition
+	JoinRecordCRD           apiextensions.CustomResourceDefinition
+	ClusterRole             rbac.ClusterRole
+	ClusterRoleBinding      rbac.ClusterRoleBinding
+	ConfigMap               k8s.ConfigMap
+	OwnerPublicKeyConfigMap k8s.ConfigMap
+	TokenKeySecret          k8s.Secret
+	DaemonSet               apps.DaemonSet
+	ServiceAccount          k8s.ServiceAccount
+	Service                 k8s.Service
 }
 
 // NewJoinServiceDaemonset returns a daemonset for the join service.
-func NewJoinServiceDaemonset(csp, measurementsJSON, enforcedPCRsJSON, initialIdKeyDigest, enforceIdKeyDigest string, measurementSalt []byte) *joinServiceDaemonset {
+// attestationTokenKey is the PEM encoded signing key of the join service's attestation verifier.
+// ownerPublicKey is the PEM encoded public key of the cluster owner, the only key trusted to sign the join-config.
+// It is stored in an immutable ConfigMap, since the join service must never accept a different key.
+// kubeletCertValidity is the validity of the kubelet certificates issued by the join service.
+func NewJoinServiceDaemonset(
+	csp, measurementsJSON, enforcedPCRsJSON, initialIdKeyDigest, enforceIdKeyDigest, attestationPolicy, ekCertificateAuthority string,
+	measurementSalt, attestationTokenKey, ownerPublicKey []byte, kubeletCertValidity time.Duration,
+) *joinServiceDaemonset {
 	joinConfigData := map[string]string{
 		constants.MeasurementsFilename: measurementsJSON,
 		constants.EnforcedPCRsFilename: enforcedPCRsJSON,
@@ -39,8 +53,18 @@ func NewJoinServiceDaemonset(csp, measurementsJSON, enforcedPCRsJSON, initialIdK
 		joinConfigData[constants.EnforceIdKeyDigestFilename] = enforceIdKeyDigest
 		joinConfigData[constants.IdKeyDigestFilename] = initialIdKeyDigest
 	}
+	if attestationPolicy != "" {
+		joinConfigData[constants.AttestationPolicyFilename] = attestationPolicy
+	}
+	if ekCertificateAuthority != "" {
+		joinConfigData[constants.EKCertificateAuthorityFilename] = ekCertificateAuthority
+	}
+	immutable := true
 
 	return &joinServiceDaemonset{
+		NodeAttestationCRD: newNodeAttestationCRD(),
+		RevokedNodeCRD:     newRevokedNodeCRD(),
+		JoinRecordCRD:      newJoinRecordCRD(),
 		ClusterRole: rbac.ClusterRole{
 			TypeMeta: meta.TypeMeta{
 				APIVersion: "rbac.authorization.k8s.io/v1",
@@ -63,6 +87,47 @@ func NewJoinServiceDaemonset(csp, measurementsJSON, enforcedPCRsJSON, initialIdK
 					Resources: []string{"roles", "rolebindings"},
 					Verbs:     []string{"create", "update"},
 				},
+				{
+					// nodes are only modified to cordon or taint nodes failing re-attestation, using patches
+					APIGroups: []string{""},
+					Resources: []string{"nodes"},
+					Verbs:     []string{"get", "list", "patch"},
+				},
+				{
+					APIGroups: []string{""},
+					Resources: []string{"pods"},
+					Verbs:     []string{"list"},
+				},
+				{
+					APIGroups: []string{""},
+					Resources: []string{"configmaps"},
+					Verbs:     []string{"get", "create", "update"},
+				},
+				{
+					APIGroups: []string{""},
+					Resources: []string{"events"},
+					Verbs:     []string{"create"},
+				},
+				{
+					APIGroups: []string{"coordination.k8s.io"},
+					Resources: []string{"leases"},
+					Verbs:     []string{"get", "create", "update"},
+				},
+				{
+					APIGroups: []string{constants.NodeAttestationGroup},
+					Resources: []string{constants.NodeAttestationResource, constants.NodeAttestationResource + "/status"},
+					Verbs:     []string{"get", "list", "create", "update"},
+				},
+				{
+					APIGroups: []string{constants.NodeAttestationGroup},
+					Resources: []string{constants.RevokedNodeResource},
+					Verbs:     []string{"list"},
+				},
+				{
+					APIGroups: []string{constants.NodeAttestationGroup},
+					Resources: []string{constants.JoinRecordResource},
+					Verbs:     []string{"create", "list", "delete"},
+				},
 			},
 		},
 		ClusterRoleBinding: rbac.ClusterRoleBinding{
@@ -153,6 +218,10 @@ func NewJoinServiceDaemonset(csp, measurementsJSON, enforcedPCRsJSON, initialIdK
 										ContainerPort: constants.JoinServicePort,
 										Name:          "tcp",
 									},
+									{
+										ContainerPort: constants.JoinServiceMetricsPort,
+										Name:          "metrics",
+									},
 								},
 								SecurityContext: &k8s.SecurityContext{
 									Privileged: func(b bool) *bool { return &b }(true),
@@ -160,6 +229,7 @@ func NewJoinServiceDaemonset(csp, measurementsJSON, enforcedPCRsJSON, initialIdK
 								Args: []string{
 									fmt.Sprintf("--cloud-provider=%s", csp),
 									fmt.Sprintf("--kms-endpoint=kms.kube-system:%d", constants.KMSPort),
+									fmt.Sprintf("--kubelet-cert-validity=%s", kubeletCertValidity),
 								},
 								VolumeMounts: []k8s.VolumeMount{
 									{
@@ -172,6 +242,11 @@ func NewJoinServiceDaemonset(csp, measurementsJSON, enforcedPCRsJSON, initialIdK
 										ReadOnly:  true,
 										MountPath: "/etc/kubernetes",
 									},
+									{
+										Name:      "attestation-token-key",
+										ReadOnly:  true,
+										MountPath: constants.AttestationTokenKeyPath,
+									},
 								},
 							},
 						},
@@ -202,6 +277,13 @@ func NewJoinServiceDaemonset(csp, measurementsJSON, enforcedPCRsJSON, initialIdK
 													},
 												},
 											},
+											{
+												ConfigMap: &k8s.ConfigMapProjection{
+													LocalObjectReference: k8s.LocalObjectReference{
+														Name: constants.OwnerPublicKeyConfigMap,
+													},
+												},
+											},
 										},
 									},
 								},
@@ -214,6 +296,14 @@ func NewJoinServiceDaemonset(csp, measurementsJSON, enforcedPCRsJSON, initialIdK
 									},
 								},
 							},
+							{
+								Name: "attestation-token-key",
+								VolumeSource: k8s.VolumeSource{
+									Secret: &k8s.SecretVolumeSource{
+										SecretName: constants.AttestationTokenKeySecret,
+									},
+								},
+							},
 						},
 					},
 				},
@@ -268,6 +358,271 @@ func NewJoinServiceDaemonset(csp, measurementsJSON, enforcedPCRsJSON, initialIdK
 				constants.MeasurementSaltFilename: measurementSalt,
 			},
 		},
+		OwnerPublicKeyConfigMap: k8s.ConfigMap{
+			TypeMeta: meta.TypeMeta{
+				APIVersion: "v1",
+				Kind:       "ConfigMap",
+			},
+			ObjectMeta: meta.ObjectMeta{
+				Name:      constants.OwnerPublicKeyConfigMap,
+				Namespace: constants.ConstellationNamespace,
+			},
+			Immutable: &immutable,
+			Data: map[string]string{
+				constants.OwnerPublicKeyFilename: string(ownerPublicKey),
+			},
+		},
+		TokenKeySecret: k8s.Secret{
+			TypeMeta: meta.TypeMeta{
+				APIVersion: "v1",
+				Kind:       "Secret",
+			},
+			ObjectMeta: meta.ObjectMeta{
+				Name:      constants.AttestationTokenKeySecret,
+				Namespace: constants.ConstellationNamespace,
+			},
+			Data: map[string][]byte{
+				constants.AttestationTokenKeyFilename: attestationTokenKey,
+			},
+			Type: "Opaque",
+		},
+	}
+}
+
+// newNodeAttestationCRD returns the definition of NodeAttestation resources,
+// which record the results of periodic re-attestation by the join service.
+func newNodeAttestationCRD() apiextensions.CustomResourceDefinition {
+	return apiextensions.CustomResourceDefinition{
+		TypeMeta: meta.TypeMeta{
+			APIVersion: "apiextensions.k8s.io/v1",
+			Kind:       "CustomResourceDefinition",
+		},
+		ObjectMeta: meta.ObjectMeta{
+			Name: constants.NodeAttestationResource + "." + constants.NodeAttestationGroup,
+		},
+		Spec: apiextensions.CustomResourceDefinitionSpec{
+			Group: constants.NodeAttestationGroup,
+			Names: apiextensions.CustomResourceDefinitionNames{
+				Kind:     "NodeAttestation",
+				ListKind: "NodeAttestationList",
+				Plural:   constants.NodeAttestationResource,
+				Singular: "nodeattestation",
+			},
+			Scope: apiextensions.ClusterScoped,
+			Conversion: &apiextensions.CustomResourceConversion{
+				Strategy: apiextensions.NoneConverter,
+			},
+			Versions: []apiextensions.CustomResourceDefinitionVersion{
+				{
+					Name:    constants.NodeAttestationVersion,
+					Served:  true,
+					Storage: true,
+					Subresources: &apiextensions.CustomResourceSubresources{
+						Status: &apiextensions.CustomResourceSubresourceStatus{},
+					},
+					AdditionalPrinterColumns: []apiextensions.CustomResourceColumnDefinition{
+						{Name: "State", Type: "string", JSONPath: ".status.state"},
+						{Name: "Failures", Type: "integer", JSONPath: ".status.consecutiveFailures"},
+						{Name: "Last Attestation", Type: "date", JSONPath: ".status.lastAttestationTime"},
+					},
+					Schema: &apiextensions.CustomResourceValidation{
+						OpenAPIV3Schema: &apiextensions.JSONSchemaProps{
+							Type: "object",
+							Properties: map[string]apiextensions.JSONSchemaProps{
+								"apiVersion": {Type: "string"},
+								"kind":       {Type: "string"},
+								"metadata":   {Type: "object"},
+								"spec": {
+									Type:     "object",
+									Required: []string{"nodeName"},
+									Properties: map[string]apiextensions.JSONSchemaProps{
+										"nodeName": {Type: "string"},
+									},
+								},
+								"status": {
+									Type: "object",
+									Properties: map[string]apiextensions.JSONSchemaProps{
+										"state": {
+											Type: "string",
+											Enum: []apiextensions.JSON{
+												{Raw: []byte(`"Trusted"`)},
+												{Raw: []byte(`"Failed"`)},
+												{Raw: []byte(`"Quarantined"`)},
+											},
+										},
+										"lastAttestationTime":           {Type: "string", Format: "date-time"},
+										"lastSuccessfulAttestationTime": {Type: "string", Format: "date-time"},
+										"consecutiveFailures":           {Type: "integer"},
+										"message":                       {Type: "string"},
+										"quarantineAction":              {Type: "string"},
+										"attestedIdentity":              {Type: "string"},
+									},
+								},
+							},
+						},
+					},
+				},
+			},
+		},
+		// set explicitly to match the defaults applied by the API server
+		Status: apiextensions.CustomResourceDefinitionStatus{
+			StoredVersions: []string{constants.NodeAttestationVersion},
+		},
+	}
+}
+
+// newRevokedNodeCRD returns the definition of RevokedNode resources,
+// which deny nodes to join or rejoin the cluster.
+func newRevokedNodeCRD() apiextensions.CustomResourceDefinition {
+	return apiextensions.CustomResourceDefinition{
+		TypeMeta: meta.TypeMeta{
+			APIVersion: "apiextensions.k8s.io/v1",
+			Kind:       "CustomResourceDefinition",
+		},
+		ObjectMeta: meta.ObjectMeta{
+			Name: constants.RevokedNodeResource + "." + constants.NodeAttestationGroup,
+		},
+		Spec: apiextensions.CustomResourceDefinitionSpec{
+			Group: constants.NodeAttestationGroup,
+			Names: apiextensions.CustomResourceDefinitionNames{
+				Kind:     "RevokedNode",
+				ListKind: "RevokedNodeList",
+				Plural:   constants.RevokedNodeResource,
+				Singular: "revokednode",
+			},
+			Scope: apiextensions.ClusterScoped,
+			Conversion: &apiextensions.CustomResourceConversion{
+				Strategy: apiextensions.NoneConverter,
+			},
+			Versions: []apiextensions.CustomResourceDefinitionVersion{
+				{
+					Name:    constants.NodeAttestationVersion,
+					Served:  true,
+					Storage: true,
+					AdditionalPrinterColumns: []apiextensions.CustomResourceColumnDefinition{
+						{Name: "Node", Type: "string", JSONPath: ".spec.nodeName"},
+						{Name: "Reason", Type: "string", JSONPath: ".spec.reason"},
+						{Name: "Age", Type: "date", JSONPath: ".metadata.creationTimestamp"},
+					},
+					Schema: &apiextensions.CustomResourceValidation{
+						OpenAPIV3Schema: &apiextensions.JSONSchemaProps{
+							Type: "object",
+							Properties: map[string]apiextensions.JSONSchemaProps{
+								"apiVersion": {Type: "string"},
+								"kind":       {Type: "string"},
+								"metadata":   {Type: "object"},
+								"spec": {
+									Type: "object",
+									Properties: map[string]apiextensions.JSONSchemaProps{
+										"nodeName":   {Type: "string"},
+										"providerID": {Type: "string"},
+										"attestationIdentities": {
+											Type:  "array",
+											Items: &apiextensions.JSONSchemaPropsOrArray{Schema: &apiextensions.JSONSchemaProps{Type: "string"}},
+										},
+										"diskUUIDs": {
+											Type:  "array",
+											Items: &apiextensions.JSONSchemaPropsOrArray{Schema: &apiextensions.JSONSchemaProps{Type: "string"}},
+										},
+										"reason": {Type: "string"},
+										"count":  {Type: "integer", Minimum: &minJoinRecordCount},
+									},
+								},
+							},
+						},
+					},
+				},
+			},
+		},
+		// set explicitly to match the defaults applied by the API server
+		Status: apiextensions.CustomResourceDefinitionStatus{
+			StoredVersions: []string{constants.NodeAttestationVersion},
+		},
+	}
+}
+
+// minJoinRecordCount is the minimum number of requests aggregated in a JoinRecord.
+var minJoinRecordCount = 1.0
+
+// newJoinRecordCRD returns the definition of JoinRecord resources,
+// which record the decisions of the JoinService on requests of nodes.
+func newJoinRecordCRD() apiextensions.CustomResourceDefinition {
+	return apiextensions.CustomResourceDefinition{
+		TypeMeta: meta.TypeMeta{
+			APIVersion: "apiextensions.k8s.io/v1",
+			Kind:       "CustomResourceDefinition",
+		},
+		ObjectMeta: meta.ObjectMeta{
+			Name: constants.JoinRecordResource + "." + constants.NodeAttestationGroup,
+		},
+		Spec: apiextensions.CustomResourceDefinitionSpec{
+			Group: constants.NodeAttestationGroup,
+			Names: apiextensions.CustomResourceDefinitionNames{
+				Kind:     "JoinRecord",
+				ListKind: "JoinRecordList",
+				Plural:   constants.JoinRecordResource,
+				Singular: "joinrecord",
+			},
+			Scope: apiextensions.ClusterScoped,
+			Conversion: &apiextensions.CustomResourceConversion{
+				Strategy: apiextensions.NoneConverter,
+			},
+			Versions: []apiextensions.CustomResourceDefinitionVersion{
+				{
+					Name:    constants.NodeAttestationVersion,
+					Served:  true,
+					Storage: true,
+					AdditionalPrinterColumns: []apiextensions.CustomResourceColumnDefinition{
+						{Name: "Time", Type: "date", JSONPath: ".spec.time"},
+						{Name: "Request", Type: "string", JSONPath: ".spec.request"},
+						{Name: "Result", Type: "string", JSONPath: ".spec.result"},
+						{Name: "Node", Type: "string", JSONPath: ".spec.nodeName"},
+						{Name: "Peer", Type: "string", JSONPath: ".spec.peerAddress"},
+						{Name: "Count", Type: "integer", JSONPath: ".spec.count", Priority: 1},
+						{Name: "Reason", Type: "string", JSONPath: ".spec.reason", Priority: 1},
+					},
+					Schema: &apiextensions.CustomResourceValidation{
+						OpenAPIV3Schema: &apiextensions.JSONSchemaProps{
+							Type: "object",
+							Properties: map[string]apiextensions.JSONSchemaProps{
+								"apiVersion": {Type: "string"},
+								"kind":       {Type: "string"},
+								"metadata":   {Type: "object"},
+								"spec": {
+									Type:     "object",
+									Required: []string{"time", "request", "result"},
+									Properties: map[string]apiextensions.JSONSchemaProps{
+										"time":                {Type: "string", Format: "date-time"},
+										"request":             {Type: "string"},
+										"peerAddress":         {Type: "string"},
+										"attestationVariant":  {Type: "string"},
+										"instanceID":          {Type: "string"},
+										"attestationIdentity": {Type: "string"},
+										"nodeName":            {Type: "string"},
+										"diskUUID":            {Type: "string"},
+										"isControlPlane":      {Type: "boolean"},
+										"result": {
+											Type: "string",
+											Enum: []apiextensions.JSON{
+												{Raw: []byte(`"Accepted"`)},
+												{Raw: []byte(`"Denied"`)},
+												{Raw: []byte(`"Failed"`)},
+											},
+										},
+										"reason": {Type: "string"},
+										"count":  {Type: "integer", Minimum: &minJoinRecordCount},
+									},
+								},
+							},
+						},
+					},
+				},
+			},
+		},
+		// set explicitly to match the defaults applied by the API server
+		Status: apiextensions.CustomResourceDefinitionStatus{
+			StoredVersions: []string{constants.NodeAttestationVersion},
+		},
 	}
 }
 
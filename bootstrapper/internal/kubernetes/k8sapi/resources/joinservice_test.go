@@ -9,17 +9,22 @@ package resources
 import (
 	"testing"
 
+	"github.com/edgelesssys/constellation/v2/internal/constants"
 	"github.com/edgelesssys/constellation/v2/internal/kubernetes"
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/require"
 )
 
 func TestNewJoinServiceDaemonset(t *testing.T) {
-	deployment := NewJoinServiceDaemonset("csp", "measurementsJSON", "enforcedPCRsJSON", "deadbeef", "true", []byte{0x0, 0x1, 0x2})
+	deployment := NewJoinServiceDaemonset("csp", "measurementsJSON", "enforcedPCRsJSON", "deadbeef", "true", "attestationPolicy", "ekCertificateAuthority", []byte{0x0, 0x1, 0x2}, []byte("attestationTokenKey"), []byte("ownerPublicKey"), constants.KubeletCertificateValidity)
 	deploymentYAML, err := deployment.Marshal()
 	require.NoError(t, err)
 
 	var recreated joinServiceDaemonset
 	require.NoError(t, kubernetes.UnmarshalK8SResources(deploymentYAML, &recreated))
 	assert.Equal(t, deployment, &recreated)
+	assert.Contains(t, deployment.DaemonSet.Spec.Template.Spec.Containers[0].Args, "--kubelet-cert-validity=8760h0m0s")
+	assert.Equal(t, "ownerPublicKey", deployment.OwnerPublicKeyConfigMap.Data[constants.OwnerPublicKeyFilename])
+	require.NotNil(t, deployment.OwnerPublicKeyConfigMap.Immutable)
+	assert.True(t, *deployment.OwnerPublicKeyConfigMap.Immutable)
 }
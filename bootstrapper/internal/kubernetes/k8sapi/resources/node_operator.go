@@ -87,6 +87,7 @@ func NewNodeOperatorDeployment(cloudProvider string, uid string) *nodeOperatorDe
 					Env: []corev1.EnvVar{
 						{Name: "CONSTEL_CSP", Value: cloudProvider},
 						{Name: "constellation-uid", Value: uid},
+						{Name: "CONSTEL_KUBERNETES_UPGRADE_IMAGE", Value: versions.BusyboxImage},
 					},
 				},
 			},
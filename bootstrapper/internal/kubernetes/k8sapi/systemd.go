@@ -37,6 +37,23 @@ func startSystemdUnit(ctx context.Context, unit string) error {
 	}
 }
 
+func restartSystemdUnit(ctx context.Context, unit string) error {
+	conn, err := dbus.NewSystemdConnectionContext(ctx)
+	if err != nil {
+		return fmt.Errorf("establishing systemd connection: %w", err)
+	}
+
+	restartChan := make(chan string)
+	if _, err := conn.RestartUnitContext(ctx, unit, "replace", restartChan); err != nil {
+		return fmt.Errorf("restarting systemd unit %q: %w", unit, err)
+	}
+
+	if result := <-restartChan; result != "done" {
+		return fmt.Errorf("restarting systemd unit %q failed: expected %v but received %v", unit, "done", result)
+	}
+	return nil
+}
+
 func enableSystemdUnit(ctx context.Context, unitPath string) error {
 	conn, err := dbus.NewSystemdConnectionContext(ctx)
 	if err != nil {
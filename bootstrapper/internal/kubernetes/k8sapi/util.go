@@ -71,6 +71,9 @@ type installer interface {
 		ctx context.Context, sourceURL string, destinations []string, perm fs.FileMode,
 		extract bool, transforms ...transform.Transformer,
 	) error
+	InstallVerified(
+		ctx context.Context, sourceURL, checksum string, destinations []string, perm fs.FileMode, extract bool,
+	) error
 }
 
 // KubernetesUtil provides low level management of the kubernetes cluster.
@@ -130,8 +133,21 @@ func (k *KubernetesUtil) InstallComponents(ctx context.Context, version versions
 	return enableSystemdUnit(ctx, kubeletServiceEtcPath)
 }
 
+// InstallPinnedComponents installs components on top of the ones installed by InstallComponents.
+// Every component is only installed if its download matches the pinned checksum.
+func (k *KubernetesUtil) InstallPinnedComponents(ctx context.Context, components []versions.Component) error {
+	for _, component := range components {
+		if err := k.inst.InstallVerified(
+			ctx, component.URL, component.Hash, []string{component.InstallPath}, executablePerm, component.Extract,
+		); err != nil {
+			return fmt.Errorf("installing pinned component: %w", err)
+		}
+	}
+	return nil
+}
+
 func (k *KubernetesUtil) InitCluster(
-	ctx context.Context, initConfig []byte, nodeName string, ips []net.IP, controlPlaneEndpoint string, conformanceMode bool, log *logger.Logger,
+	ctx context.Context, initConfig []byte, nodeName string, ips []net.IP, controlPlaneEndpoint string, kubeletCertValidity time.Duration, conformanceMode bool, log *logger.Logger,
 ) error {
 	// TODO: audit policy should be user input
 	auditPolicy, err := resources.NewDefaultAuditPolicy().Marshal()
@@ -177,7 +193,7 @@ func (k *KubernetesUtil) InitCluster(
 
 	// create kubelet key and CA signed certificate for the node
 	log.Infof("Creating signed kubelet certificate")
-	if err := k.createSignedKubeletCert(nodeName, ips); err != nil {
+	if err := k.createSignedKubeletCert(nodeName, ips, kubeletCertValidity); err != nil {
 		return err
 	}
 
@@ -510,10 +526,6 @@ func (k *KubernetesUtil) SetupOperatorLifecycleManager(ctx context.Context, kube
 	return kubectl.Apply(olmConfiguration, true)
 }
 
-func (k *KubernetesUtil) SetupNodeMaintenanceOperator(kubectl Client, nodeMaintenanceOperatorConfiguration kubernetes.Marshaler) error {
-	return kubectl.Apply(nodeMaintenanceOperatorConfiguration, true)
-}
-
 func (k *KubernetesUtil) SetupNodeOperator(ctx context.Context, kubectl Client, nodeOperatorConfiguration kubernetes.Marshaler) error {
 	return kubectl.Apply(nodeOperatorConfiguration, true)
 }
@@ -570,9 +582,16 @@ func (k *KubernetesUtil) StartKubelet() error {
 	return startSystemdUnit(ctx, "kubelet.service")
 }
 
+// RestartKubelet restarts the kubelet systemd unit, e.g. to load a renewed certificate.
+func (k *KubernetesUtil) RestartKubelet() error {
+	ctx, cancel := context.WithTimeout(context.TODO(), kubeletStartTimeout)
+	defer cancel()
+	return restartSystemdUnit(ctx, "kubelet.service")
+}
+
 // createSignedKubeletCert manually creates a Kubernetes CA signed kubelet certificate for the bootstrapper node.
 // This is necessary because this node does not request a certificate from the join service.
-func (k *KubernetesUtil) createSignedKubeletCert(nodeName string, ips []net.IP) error {
+func (k *KubernetesUtil) createSignedKubeletCert(nodeName string, ips []net.IP, validity time.Duration) error {
 	// Create CSR
 	certRequestRaw, kubeletKey, err := kubelet.GetCertificateRequest(nodeName, ips)
 	if err != nil {
@@ -599,7 +618,7 @@ func (k *KubernetesUtil) createSignedKubeletCert(nodeName string, ips []net.IP)
 	certTmpl := &x509.Certificate{
 		SerialNumber: serialNumber,
 		NotBefore:    now.Add(-2 * time.Hour),
-		NotAfter:     now.Add(24 * 365 * time.Hour),
+		NotAfter:     now.Add(validity),
 		Subject:      certRequest.Subject,
 		KeyUsage:     x509.KeyUsageKeyEncipherment | x509.KeyUsageDigitalSignature,
 		ExtKeyUsage: []x509.ExtKeyUsage{
@@ -14,10 +14,13 @@ import (
 	"net"
 	"strconv"
 	"strings"
+	"time"
 
 	"github.com/edgelesssys/constellation/v2/bootstrapper/internal/kubernetes/k8sapi"
 	"github.com/edgelesssys/constellation/v2/bootstrapper/internal/kubernetes/k8sapi/resources"
+	"github.com/edgelesssys/constellation/v2/internal/attestation/eat"
 	"github.com/edgelesssys/constellation/v2/internal/cloud/metadata"
+	"github.com/edgelesssys/constellation/v2/internal/cloud/vmtype"
 	"github.com/edgelesssys/constellation/v2/internal/constants"
 	"github.com/edgelesssys/constellation/v2/internal/logger"
 	"github.com/edgelesssys/constellation/v2/internal/role"
@@ -77,7 +80,8 @@ func New(cloudProvider string, clusterUtil clusterUtil, configProvider configura
 // InitCluster initializes a new Kubernetes cluster and applies pod network provider.
 func (k *KubeWrapper) InitCluster(
 	ctx context.Context, cloudServiceAccountURI, versionString string, measurementSalt []byte, enforcedPCRs []uint32,
-	enforceIdKeyDigest bool, idKeyDigest []byte, azureCVM bool, kmsConfig resources.KMSConfig, sshUsers map[string]string,
+	enforceIdKeyDigest bool, idKeyDigest []byte, vmType vmtype.VMType, attestationPolicy, ekCertificateAuthority string, kubeletCertValidity time.Duration,
+	ownerPublicKey []byte, kmsConfig resources.KMSConfig, diskUUID string, sshUsers map[string]string,
 	helmDeployments []byte, conformanceMode bool, log *logger.Logger,
 ) ([]byte, error) {
 	k8sVersion, err := versions.NewValidK8sVersion(versionString)
@@ -156,7 +160,7 @@ func (k *KubeWrapper) InitCluster(
 		return nil, fmt.Errorf("encoding kubeadm init configuration as YAML: %w", err)
 	}
 	log.Infof("Initializing Kubernetes cluster")
-	if err := k.clusterUtil.InitCluster(ctx, initConfigYAML, nodeName, validIPs, controlPlaneEndpoint, conformanceMode, log); err != nil {
+	if err := k.clusterUtil.InitCluster(ctx, initConfigYAML, nodeName, validIPs, controlPlaneEndpoint, kubeletCertValidity, conformanceMode, log); err != nil {
 		return nil, fmt.Errorf("kubeadm init: %w", err)
 	}
 	kubeConfig, err := k.GetKubeconfig()
@@ -196,11 +200,17 @@ func (k *KubeWrapper) InitCluster(
 		return nil, fmt.Errorf("setting up kms: %w", err)
 	}
 
-	if err := k.setupInternalConfigMap(ctx, strconv.FormatBool(azureCVM)); err != nil {
+	if err := k.setupInternalConfigMap(ctx, vmType); err != nil {
 		return nil, fmt.Errorf("failed to setup internal ConfigMap: %w", err)
 	}
 
-	if err := k.setupJoinService(k.cloudProvider, k.initialMeasurementsJSON, measurementSalt, enforcedPCRs, idKeyDigest, enforceIdKeyDigest); err != nil {
+	if k.providerMetadata.Supported() {
+		if err := k.setupDiskOwnersConfigMap(ctx, diskUUID, instance.Name); err != nil {
+			return nil, fmt.Errorf("failed to setup disk owners ConfigMap: %w", err)
+		}
+	}
+
+	if err := k.setupJoinService(k.cloudProvider, k.initialMeasurementsJSON, measurementSalt, enforcedPCRs, idKeyDigest, enforceIdKeyDigest, attestationPolicy, ekCertificateAuthority, ownerPublicKey, kubeletCertValidity); err != nil {
 		return nil, fmt.Errorf("setting up join service failed: %w", err)
 	}
 
@@ -248,15 +258,27 @@ func (k *KubeWrapper) InitCluster(
 }
 
 // JoinCluster joins existing Kubernetes cluster.
-func (k *KubeWrapper) JoinCluster(ctx context.Context, args *kubeadm.BootstrapTokenDiscovery, peerRole role.Role, versionString string, log *logger.Logger) error {
+// components are installed on top of the release of the given Kubernetes version, e.g. after the node operator upgraded the cluster to a newer patch version.
+func (k *KubeWrapper) JoinCluster(ctx context.Context, args *kubeadm.BootstrapTokenDiscovery, peerRole role.Role, versionString string, components []versions.Component, log *logger.Logger) error {
 	k8sVersion, err := versions.NewValidK8sVersion(versionString)
-	if err != nil {
+	if err != nil && len(components) == 0 {
 		return err
 	}
+	if err != nil {
+		// the cluster was upgraded to a version this bootstrapper doesn't know,
+		// all version dependent binaries are replaced by the pinned components below
+		k8sVersion = versions.Default
+	}
 	log.With(zap.String("version", string(k8sVersion))).Infof("Installing Kubernetes components")
 	if err := k.clusterUtil.InstallComponents(ctx, k8sVersion); err != nil {
 		return err
 	}
+	if len(components) > 0 {
+		log.With(zap.String("version", versionString)).Infof("Installing pinned Kubernetes components")
+		if err := k.clusterUtil.InstallPinnedComponents(ctx, components); err != nil {
+			return err
+		}
+	}
 
 	// Step 1: retrieve cloud metadata for Kubernetes configuration
 	nodeInternalIP, err := k.getIPAddr()
@@ -266,6 +288,7 @@ func (k *KubeWrapper) JoinCluster(ctx context.Context, args *kubeadm.BootstrapTo
 	nodeName := nodeInternalIP
 	var providerID string
 	var loadbalancerEndpoint string
+	var nodeLabels, nodeTaints string
 	if k.providerMetadata.Supported() {
 		log.Infof("Retrieving node metadata")
 		instance, err := k.providerMetadata.Self(ctx)
@@ -275,6 +298,8 @@ func (k *KubeWrapper) JoinCluster(ctx context.Context, args *kubeadm.BootstrapTo
 		providerID = instance.ProviderID
 		nodeName = instance.Name
 		nodeInternalIP = instance.VPCIP
+		nodeLabels = instance.NodeLabels
+		nodeTaints = instance.NodeTaints
 		if k.providerMetadata.SupportsLoadBalancer() {
 			loadbalancerEndpoint, err = k.providerMetadata.GetLoadBalancerEndpoint(ctx)
 			if err != nil {
@@ -298,6 +323,14 @@ func (k *KubeWrapper) JoinCluster(ctx context.Context, args *kubeadm.BootstrapTo
 	joinConfig.SetNodeIP(nodeInternalIP)
 	joinConfig.SetNodeName(nodeName)
 	joinConfig.SetProviderID(providerID)
+	// nodes of worker groups register with the labels and taints of their group,
+	// so no workloads are scheduled on them before the node operator sees them
+	if nodeLabels != "" {
+		joinConfig.SetNodeLabels(nodeLabels)
+	}
+	if nodeTaints != "" {
+		joinConfig.SetNodeTaints(nodeTaints)
+	}
 	if peerRole == role.ControlPlane {
 		joinConfig.SetControlPlane(nodeInternalIP)
 	}
@@ -322,14 +355,24 @@ func (k *KubeWrapper) GetKubeconfig() ([]byte, error) {
 
 func (k *KubeWrapper) setupJoinService(
 	csp string, measurementsJSON, measurementSalt []byte, enforcedPCRs []uint32, initialIdKeyDigest []byte, enforceIdKeyDigest bool,
+	attestationPolicy string,
+	ekCertificateAuthority string,
+	ownerPublicKey []byte,
+	kubeletCertValidity time.Duration,
 ) error {
 	enforcedPCRsJSON, err := json.Marshal(enforcedPCRs)
 	if err != nil {
 		return fmt.Errorf("marshaling enforcedPCRs: %w", err)
 	}
 
+	// the attestation verifier signs tokens with a dedicated key, which is not derived from the KMS
+	attestationTokenKey, err := eat.GenerateSigningKey()
+	if err != nil {
+		return fmt.Errorf("generating attestation token signing key: %w", err)
+	}
+
 	joinConfiguration := resources.NewJoinServiceDaemonset(
-		csp, string(measurementsJSON), string(enforcedPCRsJSON), hex.EncodeToString(initialIdKeyDigest), strconv.FormatBool(enforceIdKeyDigest), measurementSalt,
+		csp, string(measurementsJSON), string(enforcedPCRsJSON), hex.EncodeToString(initialIdKeyDigest), strconv.FormatBool(enforceIdKeyDigest), attestationPolicy, ekCertificateAuthority, measurementSalt, attestationTokenKey, ownerPublicKey, kubeletCertValidity,
 	)
 
 	return k.clusterUtil.SetupJoinService(k.client, joinConfiguration)
@@ -425,7 +468,7 @@ func (k *KubeWrapper) setupK8sVersionConfigMap(ctx context.Context, k8sVersion v
 }
 
 // setupInternalConfigMap applies a ConfigMap (cf. server-side apply) to store information that is not supposed to be user-editable.
-func (k *KubeWrapper) setupInternalConfigMap(ctx context.Context, azureCVM string) error {
+func (k *KubeWrapper) setupInternalConfigMap(ctx context.Context, vmType vmtype.VMType) error {
 	config := corev1.ConfigMap{
 		TypeMeta: metav1.TypeMeta{
 			APIVersion: "v1",
@@ -436,7 +479,8 @@ func (k *KubeWrapper) setupInternalConfigMap(ctx context.Context, azureCVM strin
 			Namespace: "kube-system",
 		},
 		Data: map[string]string{
-			constants.AzureCVM: azureCVM,
+			constants.AzureCVM: strconv.FormatBool(vmType == vmtype.AzureCVM),
+			constants.QEMUTDX:  strconv.FormatBool(vmType == vmtype.QEMUTDX),
 		},
 	}
 
@@ -449,16 +493,38 @@ func (k *KubeWrapper) setupInternalConfigMap(ctx context.Context, azureCVM strin
 	return nil
 }
 
+// setupDiskOwnersConfigMap registers the state disk of the first control-plane node with the join service.
+// The owner is left empty, since the node's attested identity is only known to the join service.
+// It is recorded when the node requests the key of its disk for the first time.
+func (k *KubeWrapper) setupDiskOwnersConfigMap(ctx context.Context, diskUUID, nodeName string) error {
+	config := corev1.ConfigMap{
+		TypeMeta: metav1.TypeMeta{
+			APIVersion: "v1",
+			Kind:       "ConfigMap",
+		},
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      constants.DiskOwnersConfigMap,
+			Namespace: constants.ConstellationNamespace,
+		},
+		Data: map[string]string{
+			diskUUID: "",
+			diskUUID + constants.DiskOwnersNodeKeySuffix: nodeName,
+		},
+	}
+
+	if err := k.client.CreateConfigMap(ctx, config); err != nil {
+		return fmt.Errorf("apply in KubeWrapper.setupDiskOwnersConfigMap failed with: %w", err)
+	}
+
+	return nil
+}
+
 // setupOperators deploys the operator lifecycle manager and subscriptions to operators.
 func (k *KubeWrapper) setupOperators(ctx context.Context) error {
 	if err := k.clusterUtil.SetupOperatorLifecycleManager(ctx, k.client, &resources.OperatorLifecycleManagerCRDs{}, &resources.OperatorLifecycleManager{}, resources.OLMCRDNames); err != nil {
 		return fmt.Errorf("setting up OLM: %w", err)
 	}
 
-	if err := k.clusterUtil.SetupNodeMaintenanceOperator(k.client, resources.NewNodeMaintenanceOperatorDeployment()); err != nil {
-		return fmt.Errorf("setting up node maintenance operator: %w", err)
-	}
-
 	uid, err := k.providerMetadata.UID(ctx)
 	if err != nil {
 		return fmt.Errorf("retrieving constellation UID: %w", err)
@@ -490,6 +556,14 @@ func (k *KubeWrapper) StartKubelet(log *logger.Logger) error {
 	return nil
 }
 
+// RestartKubelet restarts the kubelet service.
+func (k *KubeWrapper) RestartKubelet() error {
+	if err := k.clusterUtil.RestartKubelet(); err != nil {
+		return fmt.Errorf("restarting kubelet: %w", err)
+	}
+	return nil
+}
+
 // getIPAddr retrieves to default sender IP used for outgoing connection.
 func getIPAddr() (string, error) {
 	conn, err := net.Dial("udp", "8.8.8.8:80")
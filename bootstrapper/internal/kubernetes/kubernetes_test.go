@@ -13,10 +13,12 @@ import (
 	"regexp"
 	"strconv"
 	"testing"
+	"time"
 
 	"github.com/edgelesssys/constellation/v2/bootstrapper/internal/kubernetes/k8sapi"
 	"github.com/edgelesssys/constellation/v2/bootstrapper/internal/kubernetes/k8sapi/resources"
 	"github.com/edgelesssys/constellation/v2/internal/cloud/metadata"
+	"github.com/edgelesssys/constellation/v2/internal/cloud/vmtype"
 	"github.com/edgelesssys/constellation/v2/internal/constants"
 	"github.com/edgelesssys/constellation/v2/internal/kubernetes"
 	"github.com/edgelesssys/constellation/v2/internal/logger"
@@ -37,6 +39,7 @@ func TestInitCluster(t *testing.T) {
 	someErr := errors.New("failed")
 	serviceAccountURI := "some-service-account-uri"
 	masterSecret := []byte("some-master-secret")
+	diskUUID := "5c2d9f0e-7a4b-4d1c-9e3f-0a1b2c3d4e5f"
 
 	nodeName := "node-name"
 	providerID := "provider-id"
@@ -319,7 +322,7 @@ func TestInitCluster(t *testing.T) {
 
 			_, err := kube.InitCluster(
 				context.Background(), serviceAccountURI, string(tc.k8sVersion),
-				nil, nil, false, nil, true, resources.KMSConfig{MasterSecret: masterSecret}, nil, nil, false, logger.NewTest(t),
+				nil, nil, false, nil, vmtype.AzureCVM, "", "", constants.KubeletCertificateValidity, nil, resources.KMSConfig{MasterSecret: masterSecret}, diskUUID, nil, nil, false, logger.NewTest(t),
 			)
 
 			if tc.wantErr {
@@ -332,6 +335,19 @@ func TestInitCluster(t *testing.T) {
 			require.NoError(kubernetes.UnmarshalK8SResources(tc.clusterUtil.initConfigs[0], &kubeadmConfig))
 			require.Equal(tc.wantConfig.ClusterConfiguration, kubeadmConfig.ClusterConfiguration)
 			require.Equal(tc.wantConfig.InitConfiguration, kubeadmConfig.InitConfiguration)
+
+			diskOwners, ok := tc.kubectl.configMaps[constants.DiskOwnersConfigMap]
+			if tc.providerMetadata.Supported() {
+				instance, err := tc.providerMetadata.Self(context.Background())
+				require.NoError(err)
+				require.True(ok)
+				assert.Equal(map[string]string{
+					diskUUID: "",
+					diskUUID + constants.DiskOwnersNodeKeySuffix: instance.Name,
+				}, diskOwners.Data)
+			} else {
+				assert.False(ok)
+			}
 		})
 	}
 }
@@ -346,15 +362,62 @@ func TestJoinCluster(t *testing.T) {
 
 	privateIP := "192.0.2.1"
 	k8sVersion := versions.Default
+	components := []versions.Component{
+		{URL: "https://example.com/kubeadm", Hash: "aaaa", InstallPath: "/run/state/bin/kubeadm"},
+	}
+	workerConfig := kubeadm.JoinConfiguration{
+		Discovery: kubeadm.Discovery{
+			BootstrapToken: joinCommand,
+		},
+		NodeRegistration: kubeadm.NodeRegistrationOptions{
+			Name:             privateIP,
+			KubeletExtraArgs: map[string]string{"node-ip": privateIP},
+		},
+	}
 
 	testCases := map[string]struct {
 		clusterUtil            stubClusterUtil
 		providerMetadata       ProviderMetadata
 		CloudControllerManager CloudControllerManager
+		k8sVersion             string
+		components             []versions.Component
 		wantConfig             kubeadm.JoinConfiguration
 		role                   role.Role
 		wantErr                bool
 	}{
+		"kubeadm join worker installs pinned components": {
+			clusterUtil:            stubClusterUtil{},
+			providerMetadata:       &stubProviderMetadata{},
+			CloudControllerManager: &stubCloudControllerManager{},
+			components:             components,
+			role:                   role.Worker,
+			wantConfig:             workerConfig,
+		},
+		"kubeadm join worker with unknown version installs pinned components": {
+			clusterUtil:            stubClusterUtil{},
+			providerMetadata:       &stubProviderMetadata{},
+			CloudControllerManager: &stubCloudControllerManager{},
+			k8sVersion:             "1.99",
+			components:             components,
+			role:                   role.Worker,
+			wantConfig:             workerConfig,
+		},
+		"kubeadm join worker fails with unknown version and no pinned components": {
+			clusterUtil:            stubClusterUtil{},
+			providerMetadata:       &stubProviderMetadata{},
+			CloudControllerManager: &stubCloudControllerManager{},
+			k8sVersion:             "1.99",
+			role:                   role.Worker,
+			wantErr:                true,
+		},
+		"kubeadm join worker fails when installing pinned components": {
+			clusterUtil:            stubClusterUtil{installPinnedComponentsErr: someErr},
+			providerMetadata:       &stubProviderMetadata{},
+			CloudControllerManager: &stubCloudControllerManager{},
+			components:             components,
+			role:                   role.Worker,
+			wantErr:                true,
+		},
 		"kubeadm join worker works without metadata": {
 			clusterUtil:            stubClusterUtil{},
 			providerMetadata:       &stubProviderMetadata{},
@@ -392,6 +455,34 @@ func TestJoinCluster(t *testing.T) {
 				},
 			},
 		},
+		"kubeadm join worker of worker group registers with labels and taints": {
+			clusterUtil: stubClusterUtil{},
+			providerMetadata: &stubProviderMetadata{
+				SupportedResp: true,
+				SelfResp: metadata.InstanceMetadata{
+					ProviderID: "provider-id",
+					Name:       "metadata-name",
+					VPCIP:      "192.0.2.1",
+					NodeLabels: "pool=highmem,tier=batch",
+					NodeTaints: "pool=highmem:NoSchedule",
+				},
+			},
+			CloudControllerManager: &stubCloudControllerManager{},
+			role:                   role.Worker,
+			wantConfig: kubeadm.JoinConfiguration{
+				Discovery: kubeadm.Discovery{
+					BootstrapToken: joinCommand,
+				},
+				NodeRegistration: kubeadm.NodeRegistrationOptions{
+					Name: "metadata-name",
+					KubeletExtraArgs: map[string]string{
+						"node-ip":              "192.0.2.1",
+						"node-labels":          "pool=highmem,tier=batch",
+						"register-with-taints": "pool=highmem:NoSchedule",
+					},
+				},
+			},
+		},
 		"kubeadm join worker works with metadata and cloud controller manager": {
 			clusterUtil: stubClusterUtil{},
 			providerMetadata: &stubProviderMetadata{
@@ -477,12 +568,17 @@ func TestJoinCluster(t *testing.T) {
 				getIPAddr:              func() (string, error) { return privateIP, nil },
 			}
 
-			err := kube.JoinCluster(context.Background(), joinCommand, tc.role, string(k8sVersion), logger.NewTest(t))
+			version := tc.k8sVersion
+			if version == "" {
+				version = string(k8sVersion)
+			}
+			err := kube.JoinCluster(context.Background(), joinCommand, tc.role, version, tc.components, logger.NewTest(t))
 			if tc.wantErr {
 				assert.Error(err)
 				return
 			}
 			require.NoError(err)
+			assert.Equal(tc.components, tc.clusterUtil.pinnedComponents)
 
 			var joinYaml k8sapi.KubeadmJoinYAML
 			joinYaml, err = joinYaml.Unmarshal(tc.clusterUtil.joinConfigs[0])
@@ -527,6 +623,7 @@ func TestK8sCompliantHostname(t *testing.T) {
 
 type stubClusterUtil struct {
 	installComponentsErr             error
+	installPinnedComponentsErr       error
 	initClusterErr                   error
 	setupHelmDeploymentsErr          error
 	setupAutoscalingError            error
@@ -539,13 +636,13 @@ type stubClusterUtil struct {
 	setupVerificationServiceErr      error
 	setupGCPGuestAgentErr            error
 	setupOLMErr                      error
-	setupNMOErr                      error
 	setupNodeOperatorErr             error
 	joinClusterErr                   error
 	startKubeletErr                  error
 
-	initConfigs [][]byte
-	joinConfigs [][]byte
+	initConfigs      [][]byte
+	joinConfigs      [][]byte
+	pinnedComponents []versions.Component
 }
 
 func (s *stubClusterUtil) SetupKonnectivity(kubectl k8sapi.Client, konnectivityAgentsDaemonSet kubernetes.Marshaler) error {
@@ -556,7 +653,12 @@ func (s *stubClusterUtil) InstallComponents(ctx context.Context, version version
 	return s.installComponentsErr
 }
 
-func (s *stubClusterUtil) InitCluster(ctx context.Context, initConfig []byte, nodeName string, ips []net.IP, controlPlaneEndpoint string, conformanceMode bool, log *logger.Logger) error {
+func (s *stubClusterUtil) InstallPinnedComponents(ctx context.Context, components []versions.Component) error {
+	s.pinnedComponents = components
+	return s.installPinnedComponentsErr
+}
+
+func (s *stubClusterUtil) InitCluster(ctx context.Context, initConfig []byte, nodeName string, ips []net.IP, controlPlaneEndpoint string, kubeletCertValidity time.Duration, conformanceMode bool, log *logger.Logger) error {
 	s.initConfigs = append(s.initConfigs, initConfig)
 	return s.initClusterErr
 }
@@ -601,10 +703,6 @@ func (s *stubClusterUtil) SetupOperatorLifecycleManager(ctx context.Context, kub
 	return s.setupOLMErr
 }
 
-func (s *stubClusterUtil) SetupNodeMaintenanceOperator(kubectl k8sapi.Client, nodeMaintenanceOperatorConfiguration kubernetes.Marshaler) error {
-	return s.setupNMOErr
-}
-
 func (s *stubClusterUtil) SetupNodeOperator(ctx context.Context, kubectl k8sapi.Client, nodeOperatorConfiguration kubernetes.Marshaler) error {
 	return s.setupNodeOperatorErr
 }
@@ -618,6 +716,10 @@ func (s *stubClusterUtil) StartKubelet() error {
 	return s.startKubeletErr
 }
 
+func (s *stubClusterUtil) RestartKubelet() error {
+	return nil
+}
+
 func (s *stubClusterUtil) FixCilium(log *logger.Logger) {
 }
 
@@ -650,6 +752,7 @@ type stubKubectl struct {
 
 	resources   []kubernetes.Marshaler
 	kubeconfigs [][]byte
+	configMaps  map[string]corev1.ConfigMap
 }
 
 func (s *stubKubectl) Apply(resources kubernetes.Marshaler, forceConflicts bool) error {
@@ -662,7 +765,14 @@ func (s *stubKubectl) SetKubeconfig(kubeconfig []byte) {
 }
 
 func (s *stubKubectl) CreateConfigMap(ctx context.Context, configMap corev1.ConfigMap) error {
-	return s.createConfigMapErr
+	if s.createConfigMapErr != nil {
+		return s.createConfigMapErr
+	}
+	if s.configMaps == nil {
+		s.configMaps = make(map[string]corev1.ConfigMap)
+	}
+	s.configMaps[configMap.Name] = configMap
+	return nil
 }
 
 func (s *stubKubectl) AddTolerationsToDeployment(ctx context.Context, tolerations []corev1.Toleration, name string, namespace string) error {
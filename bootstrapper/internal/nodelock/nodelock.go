@@ -9,6 +9,7 @@ package nodelock
 import (
 	"sync"
 
+	"github.com/edgelesssys/constellation/v2/internal/attestation/tdx"
 	"github.com/edgelesssys/constellation/v2/internal/attestation/vtpm"
 )
 
@@ -39,5 +40,12 @@ func (l *Lock) TryLockOnce(clusterID []byte) (bool, error) {
 		return false, nil
 	}
 
-	return true, vtpm.MarkNodeAsBootstrapped(l.tpm, clusterID)
+	if err := vtpm.MarkNodeAsBootstrapped(l.tpm, clusterID); err != nil {
+		return true, err
+	}
+	// TDX guests are attested using their RTMRs instead of the TPM
+	if tdx.Available() {
+		return true, tdx.MarkNodeAsBootstrapped(clusterID)
+	}
+	return true, nil
 }
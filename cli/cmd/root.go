@@ -40,13 +40,17 @@ func NewRootCmd() *cobra.Command {
 
 	rootCmd.PersistentFlags().String("config", constants.ConfigFilename, "path to the configuration file")
 	must(rootCmd.MarkPersistentFlagFilename("config", "json"))
+	rootCmd.PersistentFlags().String("proxy", "", "proxy URL for connections to the cluster (http, https or socks5), defaults to HTTPS_PROXY or ALL_PROXY")
 
 	rootCmd.AddCommand(cmd.NewConfigCmd())
 	rootCmd.AddCommand(cmd.NewCreateCmd())
 	rootCmd.AddCommand(cmd.NewInitCmd())
 	rootCmd.AddCommand(cmd.NewVerifyCmd())
 	rootCmd.AddCommand(cmd.NewUpgradeCmd())
+	rootCmd.AddCommand(cmd.NewNodeCmd())
+	rootCmd.AddCommand(cmd.NewJoinLogCmd())
 	rootCmd.AddCommand(cmd.NewRecoverCmd())
+	rootCmd.AddCommand(cmd.NewRestoreCmd())
 	rootCmd.AddCommand(cmd.NewTerminateCmd())
 	rootCmd.AddCommand(cmd.NewVersionCmd())
 
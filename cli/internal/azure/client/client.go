@@ -60,6 +60,7 @@ type Client struct {
 	subnetID             string
 	controlPlaneScaleSet string
 	workerScaleSet       string
+	workerGroupScaleSets []string
 	loadBalancerName     string
 	loadBalancerPubIP    string
 	networkSecurityGroup string
@@ -184,6 +185,7 @@ func (c *Client) GetState() state.ConstellationState {
 		AzureSubnet:                c.subnetID,
 		AzureWorkerScaleSet:        c.workerScaleSet,
 		AzureControlPlaneScaleSet:  c.controlPlaneScaleSet,
+		AzureWorkerGroupScaleSets:  c.workerGroupScaleSets,
 		AzureWorkerInstances:       c.workers,
 		AzureControlPlaneInstances: c.controlPlanes,
 		AzureADAppObjectID:         c.adAppObjectID,
@@ -203,6 +205,7 @@ func (c *Client) SetState(stat state.ConstellationState) {
 	c.networkSecurityGroup = stat.AzureNetworkSecurityGroup
 	c.workerScaleSet = stat.AzureWorkerScaleSet
 	c.controlPlaneScaleSet = stat.AzureControlPlaneScaleSet
+	c.workerGroupScaleSets = stat.AzureWorkerGroupScaleSets
 	c.workers = stat.AzureWorkerInstances
 	c.controlPlanes = stat.AzureControlPlaneInstances
 	c.adAppObjectID = stat.AzureADAppObjectID
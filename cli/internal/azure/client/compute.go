@@ -12,6 +12,7 @@ import (
 	"fmt"
 	"net/http"
 	"strconv"
+	"strings"
 	"sync"
 	"time"
 
@@ -20,6 +21,7 @@ import (
 	"github.com/edgelesssys/constellation/v2/cli/internal/azure"
 	"github.com/edgelesssys/constellation/v2/cli/internal/azure/internal/poller"
 	"github.com/edgelesssys/constellation/v2/internal/cloud/cloudtypes"
+	"github.com/edgelesssys/constellation/v2/internal/constants"
 )
 
 const (
@@ -27,21 +29,34 @@ const (
 	scaleSetCreateTimeout = 5 * time.Minute
 	powerStateStarting    = "PowerState/starting"
 	powerStateRunning     = "PowerState/running"
+	// autoscalerNodeTemplateLabelTag is the tag name prefix for node labels of the cluster-autoscaler node template.
+	autoscalerNodeTemplateLabelTag = "k8s.io_cluster-autoscaler_node-template_label_"
+	// autoscalerNodeTemplateTaintTag is the tag name prefix for node taints of the cluster-autoscaler node template.
+	autoscalerNodeTemplateTaintTag = "k8s.io_cluster-autoscaler_node-template_taint_"
 )
 
 func (c *Client) CreateInstances(ctx context.Context, input CreateInstancesInput) error {
-	// Create worker scale set
-	createWorkerInput := CreateScaleSetInput{
-		Name:                           "constellation-scale-set-workers-" + c.uid,
-		NamePrefix:                     c.name + "-worker-" + c.uid + "-",
-		Count:                          input.CountWorkers,
-		InstanceType:                   input.InstanceType,
-		StateDiskSizeGB:                int32(input.StateDiskSizeGB),
-		StateDiskType:                  input.StateDiskType,
-		Image:                          input.Image,
-		UserAssingedIdentity:           input.UserAssingedIdentity,
-		LoadBalancerBackendAddressPool: azure.BackendAddressPoolWorkerName + "-" + c.uid,
-		ConfidentialVM:                 input.ConfidentialVM,
+	// Create worker scale sets
+	createWorkerInputs := make([]CreateScaleSetInput, 0, len(input.WorkerGroups))
+	for _, group := range input.WorkerGroups {
+		createWorkerInput := CreateScaleSetInput{
+			Name:                           "constellation-scale-set-workers-" + c.uid,
+			NamePrefix:                     c.name + "-worker-" + c.uid + "-",
+			Count:                          group.Count,
+			InstanceType:                   group.InstanceType,
+			StateDiskSizeGB:                int32(group.StateDiskSizeGB),
+			StateDiskType:                  group.StateDiskType,
+			Image:                          input.Image,
+			UserAssingedIdentity:           input.UserAssingedIdentity,
+			LoadBalancerBackendAddressPool: azure.BackendAddressPoolWorkerName + "-" + c.uid,
+			ConfidentialVM:                 input.ConfidentialVM,
+		}
+		if group.Name != "" {
+			createWorkerInput.Name += "-" + group.Name
+			createWorkerInput.NamePrefix = c.name + "-worker-" + group.Name + "-" + c.uid + "-"
+			createWorkerInput.Tags = workerGroupTags(group)
+		}
+		createWorkerInputs = append(createWorkerInputs, createWorkerInput)
 	}
 
 	// Create control plane scale set
@@ -59,13 +74,16 @@ func (c *Client) CreateInstances(ctx context.Context, input CreateInstancesInput
 	}
 
 	var wg sync.WaitGroup
-	var controlPlaneErr, workerErr error
+	var controlPlaneErr error
+	workerErrs := make([]error, len(createWorkerInputs))
 
-	wg.Add(1)
-	go func() {
-		defer wg.Done()
-		workerErr = c.createScaleSet(ctx, createWorkerInput)
-	}()
+	for i := range createWorkerInputs {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			workerErrs[i] = c.createScaleSet(ctx, createWorkerInputs[i])
+		}(i)
+	}
 
 	wg.Add(1)
 	go func() {
@@ -77,22 +95,38 @@ func (c *Client) CreateInstances(ctx context.Context, input CreateInstancesInput
 	if controlPlaneErr != nil {
 		return fmt.Errorf("creating control-plane scaleset: %w", controlPlaneErr)
 	}
-	if workerErr != nil {
-		return fmt.Errorf("creating worker scaleset: %w", workerErr)
+	for _, workerErr := range workerErrs {
+		if workerErr != nil {
+			return fmt.Errorf("creating worker scaleset: %w", workerErr)
+		}
 	}
 
 	// TODO: Remove getInstanceIPs calls after init has been refactored to not use node IPs
 	// Get worker IPs
-	c.workerScaleSet = createWorkerInput.Name
-	instances, err := c.getInstanceIPs(ctx, createWorkerInput.Name, createWorkerInput.Count)
-	if err != nil {
-		return err
+	c.workers = cloudtypes.Instances{}
+	for i, createWorkerInput := range createWorkerInputs {
+		group := input.WorkerGroups[i].Name
+		if group == "" {
+			c.workerScaleSet = createWorkerInput.Name
+		} else {
+			c.workerGroupScaleSets = append(c.workerGroupScaleSets, createWorkerInput.Name)
+		}
+		instances, err := c.getInstanceIPs(ctx, createWorkerInput.Name, createWorkerInput.Count)
+		if err != nil {
+			return err
+		}
+		// instance IDs are only unique within a scale set
+		for id, instance := range instances {
+			if group != "" {
+				id = group + "-" + id
+			}
+			c.workers[id] = instance
+		}
 	}
-	c.workers = instances
 
 	// Get control plane IPs
 	c.controlPlaneScaleSet = createControlPlaneInput.Name
-	instances, err = c.getInstanceIPs(ctx, createControlPlaneInput.Name, createControlPlaneInput.Count)
+	instances, err := c.getInstanceIPs(ctx, createControlPlaneInput.Name, createControlPlaneInput.Count)
 	if err != nil {
 		return err
 	}
@@ -102,9 +136,10 @@ func (c *Client) CreateInstances(ctx context.Context, input CreateInstancesInput
 }
 
 // CreateInstancesInput is the input for a CreateInstances operation.
+// A separate scale set is created for each of the WorkerGroups.
 type CreateInstancesInput struct {
-	CountWorkers         int
 	CountControlPlanes   int
+	WorkerGroups         []cloudtypes.WorkerGroup
 	InstanceType         string
 	StateDiskSizeGB      int
 	StateDiskType        string
@@ -113,6 +148,34 @@ type CreateInstancesInput struct {
 	ConfidentialVM       bool
 }
 
+// workerGroupTags returns the scale set tags storing the settings of a worker group for the node operator.
+// The labels and taints are additionally stored as node template tags of the cluster-autoscaler,
+// so it can scale up an empty scale set for pods that require them.
+func workerGroupTags(group cloudtypes.WorkerGroup) map[string]string {
+	tags := map[string]string{
+		constants.WorkerGroupTag:       group.Name,
+		constants.WorkerGroupMinTag:    strconv.Itoa(group.Min),
+		constants.WorkerGroupMaxTag:    strconv.Itoa(group.Max),
+		constants.WorkerGroupLabelsTag: group.LabelsString(),
+		constants.WorkerGroupTaintsTag: group.TaintsString(),
+	}
+	for key, value := range group.Labels {
+		tags[autoscalerNodeTemplateLabelTag+autoscalerTagKey(key)] = value
+	}
+	for _, taint := range group.Taints {
+		keyValue, effect, _ := strings.Cut(taint, ":")
+		key, value, _ := strings.Cut(keyValue, "=")
+		tags[autoscalerNodeTemplateTaintTag+autoscalerTagKey(key)] = value + ":" + effect
+	}
+	return tags
+}
+
+// autoscalerTagKey escapes a label or taint key for use in an Azure tag name.
+// Azure tag names must not contain "/", so the cluster-autoscaler expects "_" in its place and "~2" in place of "_".
+func autoscalerTagKey(key string) string {
+	return strings.ReplaceAll(strings.ReplaceAll(key, "_", "~2"), "/", "_")
+}
+
 func (c *Client) createScaleSet(ctx context.Context, input CreateScaleSetInput) error {
 	// TODO: Generating a random password to be able
 	// to create the scale set. This is a temporary fix.
@@ -141,6 +204,7 @@ func (c *Client) createScaleSet(ctx context.Context, input CreateScaleSetInput)
 		LoadBalancerName:               c.loadBalancerName,
 		LoadBalancerBackendAddressPool: input.LoadBalancerBackendAddressPool,
 		ConfidentialVM:                 input.ConfidentialVM,
+		Tags:                           input.Tags,
 	}.Azure()
 
 	_, err = c.scaleSetsAPI.BeginCreateOrUpdate(
@@ -222,6 +286,7 @@ type CreateScaleSetInput struct {
 	UserAssingedIdentity           string
 	LoadBalancerBackendAddressPool string
 	ConfidentialVM                 bool
+	Tags                           map[string]string
 }
 
 // scaleSetCreationPollingHandler is a custom poller used to check if a scale set was created successfully.
@@ -14,6 +14,7 @@ import (
 	"github.com/Azure/azure-sdk-for-go/sdk/azcore/to"
 	armcomputev2 "github.com/Azure/azure-sdk-for-go/sdk/resourcemanager/compute/armcompute/v2"
 	"github.com/edgelesssys/constellation/v2/internal/cloud/cloudtypes"
+	"github.com/edgelesssys/constellation/v2/internal/constants"
 	"github.com/stretchr/testify/assert"
 )
 
@@ -24,6 +25,8 @@ func TestCreateInstances(t *testing.T) {
 		networkInterfacesAPI networkInterfacesAPI
 		scaleSetsAPI         scaleSetsAPI
 		createInstancesInput CreateInstancesInput
+		wantWorkers          int
+		wantScaleSets        []string
 		wantErr              bool
 	}{
 		"successful create": {
@@ -36,12 +39,35 @@ func TestCreateInstances(t *testing.T) {
 			},
 			createInstancesInput: CreateInstancesInput{
 				CountControlPlanes:   3,
-				CountWorkers:         3,
+				WorkerGroups:         []cloudtypes.WorkerGroup{{Count: 3, InstanceType: "type"}},
 				InstanceType:         "type",
 				Image:                "image",
 				UserAssingedIdentity: "identity",
 				ConfidentialVM:       true,
 			},
+			wantWorkers: 3,
+		},
+		"successful create with worker groups": {
+			publicIPAddressesAPI: stubPublicIPAddressesAPI{},
+			networkInterfacesAPI: stubNetworkInterfacesAPI{},
+			scaleSetsAPI: stubScaleSetsAPI{
+				getResponse: armcomputev2.VirtualMachineScaleSet{
+					Identity: &armcomputev2.VirtualMachineScaleSetIdentity{PrincipalID: to.Ptr("principal-id")}, SKU: &armcomputev2.SKU{Capacity: to.Ptr[int64](0)},
+				},
+			},
+			createInstancesInput: CreateInstancesInput{
+				CountControlPlanes: 3,
+				WorkerGroups: []cloudtypes.WorkerGroup{
+					{Name: "highmem", Count: 2, Min: 2, Max: 5, InstanceType: "type"},
+					{Name: "spot", Count: 1, Min: 1, Max: 10, InstanceType: "type"},
+				},
+				InstanceType:         "type",
+				Image:                "image",
+				UserAssingedIdentity: "identity",
+				ConfidentialVM:       true,
+			},
+			wantWorkers:   3,
+			wantScaleSets: []string{"constellation-scale-set-workers-uid-highmem", "constellation-scale-set-workers-uid-spot"},
 		},
 		"error when creating scale set": {
 			publicIPAddressesAPI: stubPublicIPAddressesAPI{},
@@ -49,7 +75,7 @@ func TestCreateInstances(t *testing.T) {
 			scaleSetsAPI:         stubScaleSetsAPI{createErr: someErr},
 			createInstancesInput: CreateInstancesInput{
 				CountControlPlanes:   3,
-				CountWorkers:         3,
+				WorkerGroups:         []cloudtypes.WorkerGroup{{Count: 3, InstanceType: "type"}},
 				InstanceType:         "type",
 				Image:                "image",
 				UserAssingedIdentity: "identity",
@@ -63,7 +89,7 @@ func TestCreateInstances(t *testing.T) {
 			scaleSetsAPI:         stubScaleSetsAPI{getErr: someErr},
 			createInstancesInput: CreateInstancesInput{
 				CountControlPlanes:   3,
-				CountWorkers:         3,
+				WorkerGroups:         []cloudtypes.WorkerGroup{{Count: 3, InstanceType: "type"}},
 				InstanceType:         "type",
 				Image:                "image",
 				UserAssingedIdentity: "identity",
@@ -76,7 +102,7 @@ func TestCreateInstances(t *testing.T) {
 			networkInterfacesAPI: stubNetworkInterfacesAPI{getErr: someErr},
 			scaleSetsAPI:         stubScaleSetsAPI{},
 			createInstancesInput: CreateInstancesInput{
-				CountWorkers:         3,
+				WorkerGroups:         []cloudtypes.WorkerGroup{{Count: 3, InstanceType: "type"}},
 				InstanceType:         "type",
 				Image:                "image",
 				UserAssingedIdentity: "identity",
@@ -109,12 +135,59 @@ func TestCreateInstances(t *testing.T) {
 			} else {
 				assert.NoError(client.CreateInstances(ctx, tc.createInstancesInput))
 				assert.Equal(tc.createInstancesInput.CountControlPlanes, len(client.controlPlanes))
-				assert.Equal(tc.createInstancesInput.CountWorkers, len(client.workers))
-				assert.NotEmpty(client.workers["0"].PrivateIP)
-				assert.NotEmpty(client.workers["0"].PublicIP)
+				assert.Equal(tc.wantWorkers, len(client.workers))
+				assert.Equal(tc.wantScaleSets, client.workerGroupScaleSets)
+				for _, worker := range client.workers {
+					assert.NotEmpty(worker.PrivateIP)
+					assert.NotEmpty(worker.PublicIP)
+				}
 				assert.NotEmpty(client.controlPlanes["0"].PrivateIP)
 				assert.NotEmpty(client.controlPlanes["0"].PublicIP)
 			}
 		})
 	}
 }
+
+func TestWorkerGroupTags(t *testing.T) {
+	testCases := map[string]struct {
+		group    cloudtypes.WorkerGroup
+		wantTags map[string]string
+	}{
+		"group without labels and taints": {
+			group: cloudtypes.WorkerGroup{Name: "general", Min: 1, Max: 3},
+			wantTags: map[string]string{
+				constants.WorkerGroupTag:       "general",
+				constants.WorkerGroupMinTag:    "1",
+				constants.WorkerGroupMaxTag:    "3",
+				constants.WorkerGroupLabelsTag: "",
+				constants.WorkerGroupTaintsTag: "",
+			},
+		},
+		"group with labels and taints": {
+			group: cloudtypes.WorkerGroup{
+				Name: "spot", Min: 0, Max: 5,
+				Labels: map[string]string{"pool": "spot", "example.com/node_type": "preemptible"},
+				Taints: []string{"pool=spot:NoSchedule", "example.com/spot:NoExecute"},
+			},
+			wantTags: map[string]string{
+				constants.WorkerGroupTag:                                               "spot",
+				constants.WorkerGroupMinTag:                                            "0",
+				constants.WorkerGroupMaxTag:                                            "5",
+				constants.WorkerGroupLabelsTag:                                         "example.com/node_type=preemptible,pool=spot",
+				constants.WorkerGroupTaintsTag:                                         "pool=spot:NoSchedule,example.com/spot:NoExecute",
+				"k8s.io_cluster-autoscaler_node-template_label_pool":                   "spot",
+				"k8s.io_cluster-autoscaler_node-template_label_example.com_node~2type": "preemptible",
+				"k8s.io_cluster-autoscaler_node-template_taint_pool":                   "spot:NoSchedule",
+				"k8s.io_cluster-autoscaler_node-template_taint_example.com_spot":       ":NoExecute",
+			},
+		},
+	}
+
+	for name, tc := range testCases {
+		t.Run(name, func(t *testing.T) {
+			assert := assert.New(t)
+
+			assert.Equal(tc.wantTags, workerGroupTags(tc.group))
+		})
+	}
+}
@@ -36,6 +36,8 @@ type ScaleSet struct {
 	LoadBalancerName               string
 	LoadBalancerBackendAddressPool string
 	ConfidentialVM                 bool
+	// Tags are set on the scale set in addition to the uid tag.
+	Tags map[string]string
 }
 
 // Azure returns the Azure representation of ScaleSet.
@@ -147,10 +149,18 @@ func (s ScaleSet) Azure() armcomputev2.VirtualMachineScaleSet {
 				s.UserAssignedIdentity: {},
 			},
 		},
-		Tags: map[string]*string{"uid": to.Ptr(s.UID)},
+		Tags: s.tags(),
 	}
 }
 
+func (s ScaleSet) tags() map[string]*string {
+	tags := map[string]*string{"uid": to.Ptr(s.UID)}
+	for key, value := range s.Tags {
+		tags[key] = to.Ptr(value)
+	}
+	return tags
+}
+
 // GeneratePassword is a helper function to generate a random password
 // for Azure's scale set.
 func GeneratePassword() (string, error) {
@@ -104,9 +104,11 @@ func (c *fakeAzureClient) CreateInstances(ctx context.Context, input azurecl.Cre
 	c.controlPlaneScaleSet = "controlplanes-scale-set"
 	c.workerScaleSet = "workers-scale-set"
 	c.workers = make(cloudtypes.Instances)
-	for i := 0; i < input.CountWorkers; i++ {
-		id := "id-" + strconv.Itoa(i)
-		c.workers[id] = cloudtypes.Instance{PublicIP: "192.0.2.1", PrivateIP: "192.0.2.1"}
+	for _, group := range input.WorkerGroups {
+		for i := 0; i < group.Count; i++ {
+			id := "id-" + strconv.Itoa(len(c.workers))
+			c.workers[id] = cloudtypes.Instance{PublicIP: "192.0.2.1", PrivateIP: "192.0.2.1"}
+		}
 	}
 	c.controlPlanes = make(cloudtypes.Instances)
 	for i := 0; i < input.CountControlPlanes; i++ {
@@ -272,9 +274,11 @@ func (c *fakeGcpClient) CreateInstances(ctx context.Context, input gcpcl.CreateI
 	c.workerTemplate = "worker-template"
 	c.controlPlaneTemplate = "controlplane-template"
 	c.workers = make(cloudtypes.Instances)
-	for i := 0; i < input.CountWorkers; i++ {
-		id := "id-" + strconv.Itoa(i)
-		c.workers[id] = cloudtypes.Instance{PublicIP: "192.0.2.1", PrivateIP: "192.0.2.1"}
+	for _, group := range input.WorkerGroups {
+		for i := 0; i < group.Count; i++ {
+			id := "id-" + strconv.Itoa(len(c.workers))
+			c.workers[id] = cloudtypes.Instance{PublicIP: "192.0.2.1", PrivateIP: "192.0.2.1"}
+		}
 	}
 	c.controlPlanes = make(cloudtypes.Instances)
 	for i := 0; i < input.CountControlPlanes; i++ {
@@ -156,7 +156,7 @@ func (c *Creator) createGCP(ctx context.Context, cl gcpclient, config *config.Co
 	createInput := gcpcl.CreateInstancesInput{
 		EnableSerialConsole: config.IsDebugCluster(),
 		CountControlPlanes:  controlPlaneCount,
-		CountWorkers:        workerCount,
+		WorkerGroups:        workerGroups(config, insType, config.Provider.GCP.StateDiskType, workerCount),
 		ImageID:             config.Provider.GCP.Image,
 		InstanceType:        insType,
 		StateDiskSizeGB:     config.StateDiskSizeGB,
@@ -196,7 +196,7 @@ func (c *Creator) createAzure(ctx context.Context, cl azureclient, config *confi
 	}
 	createInput := azurecl.CreateInstancesInput{
 		CountControlPlanes:   controlPlaneCount,
-		CountWorkers:         workerCount,
+		WorkerGroups:         workerGroups(config, insType, config.Provider.Azure.StateDiskType, workerCount),
 		InstanceType:         insType,
 		StateDiskSizeGB:      config.StateDiskSizeGB,
 		StateDiskType:        config.Provider.Azure.StateDiskType,
@@ -235,3 +235,41 @@ func (c *Creator) createQEMU(ctx context.Context, cl qemuclient, name string, co
 
 	return cl.GetState(), nil
 }
+
+// workerGroups returns the worker groups defined in the config.
+// If the config defines no worker groups, a single default group with workerCount nodes is returned.
+func workerGroups(config *config.Config, insType, stateDiskType string, workerCount int) []cloudtypes.WorkerGroup {
+	if len(config.WorkerGroups) == 0 {
+		return []cloudtypes.WorkerGroup{
+			{
+				Count:           workerCount,
+				InstanceType:    insType,
+				StateDiskSizeGB: config.StateDiskSizeGB,
+				StateDiskType:   stateDiskType,
+			},
+		}
+	}
+
+	groups := make([]cloudtypes.WorkerGroup, 0, len(config.WorkerGroups))
+	for _, group := range config.WorkerGroups {
+		workerGroup := cloudtypes.WorkerGroup{
+			Name:            group.Name,
+			Count:           group.Min,
+			Min:             group.Min,
+			Max:             group.Max,
+			InstanceType:    group.InstanceType,
+			StateDiskSizeGB: group.StateDiskSizeGB,
+			StateDiskType:   group.StateDiskType,
+			Labels:          group.Labels,
+			Taints:          group.Taints,
+		}
+		if workerGroup.StateDiskSizeGB == 0 {
+			workerGroup.StateDiskSizeGB = config.StateDiskSizeGB
+		}
+		if workerGroup.StateDiskType == "" {
+			workerGroup.StateDiskType = stateDiskType
+		}
+		groups = append(groups, workerGroup)
+	}
+	return groups
+}
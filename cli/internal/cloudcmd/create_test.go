@@ -194,3 +194,54 @@ func TestCreator(t *testing.T) {
 		})
 	}
 }
+
+func TestWorkerGroups(t *testing.T) {
+	testCases := map[string]struct {
+		config *config.Config
+		want   []cloudtypes.WorkerGroup
+	}{
+		"default group": {
+			config: &config.Config{StateDiskSizeGB: 30},
+			want: []cloudtypes.WorkerGroup{
+				{Count: 3, InstanceType: "type", StateDiskSizeGB: 30, StateDiskType: "disk-type"},
+			},
+		},
+		"worker groups": {
+			config: &config.Config{
+				StateDiskSizeGB: 30,
+				WorkerGroups: []config.WorkerGroup{
+					{
+						Name:         "highmem",
+						InstanceType: "highmem-type",
+						Min:          1,
+						Max:          5,
+						Labels:       map[string]string{"pool": "highmem"},
+						Taints:       []string{"pool=highmem:NoSchedule"},
+					},
+					{Name: "spot", InstanceType: "spot-type", StateDiskSizeGB: 50, StateDiskType: "other-disk-type", Max: 10},
+				},
+			},
+			want: []cloudtypes.WorkerGroup{
+				{
+					Name:            "highmem",
+					Count:           1,
+					Min:             1,
+					Max:             5,
+					InstanceType:    "highmem-type",
+					StateDiskSizeGB: 30,
+					StateDiskType:   "disk-type",
+					Labels:          map[string]string{"pool": "highmem"},
+					Taints:          []string{"pool=highmem:NoSchedule"},
+				},
+				{Name: "spot", Max: 10, InstanceType: "spot-type", StateDiskSizeGB: 50, StateDiskType: "other-disk-type"},
+			},
+		},
+	}
+
+	for name, tc := range testCases {
+		t.Run(name, func(t *testing.T) {
+			assert := assert.New(t)
+			assert.Equal(tc.want, workerGroups(tc.config, "type", "disk-type", 3))
+		})
+	}
+}
@@ -0,0 +1,168 @@
+/*
+Copyright (c) Edgeless Systems GmbH
+
+SPDX-License-Identifier: AGPL-3.0-only
+*/
+
+package cloudcmd
+
+import (
+	"context"
+	"crypto"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strconv"
+
+	"github.com/edgelesssys/constellation/v2/internal/cloud/cloudprovider"
+	"github.com/edgelesssys/constellation/v2/internal/config"
+	"github.com/edgelesssys/constellation/v2/internal/constants"
+	"github.com/edgelesssys/constellation/v2/internal/joinconfig"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/client-go/kubernetes"
+	"k8s.io/client-go/tools/clientcmd"
+)
+
+// JoinConfigSigner signs the join-config of a cluster with the cluster owner's key.
+type JoinConfigSigner struct {
+	configMaps measurementsUpdater
+}
+
+// NewJoinConfigSigner returns a new JoinConfigSigner.
+// Requests to the Kubernetes API server use the proxy selected by proxy.
+func NewJoinConfigSigner(proxy func(*http.Request) (*url.URL, error)) (*JoinConfigSigner, error) {
+	kubeConfig, err := clientcmd.BuildConfigFromFlags("", constants.AdminConfFilename)
+	if err != nil {
+		return nil, fmt.Errorf("building kubernetes config: %w", err)
+	}
+	kubeConfig.Proxy = proxy
+
+	kubeClient, err := kubernetes.NewForConfig(kubeConfig)
+	if err != nil {
+		return nil, fmt.Errorf("setting up kubernetes client: %w", err)
+	}
+
+	return &JoinConfigSigner{configMaps: &kubeMeasurementsUpdater{client: kubeClient}}, nil
+}
+
+// Sign writes values to the join-config of a newly initialized cluster and signs them with key.
+// The join service only trusts a join-config signed with the key pinned at initialization,
+// so nodes can't join a newly initialized cluster until its join-config is signed.
+// values are expected to be created by JoinConfigValues. The measurements and idkeydigest written by the
+// bootstrapper are those of the first node and are replaced, all other values must already match.
+func (s *JoinConfigSigner) Sign(ctx context.Context, values map[string]string, key crypto.Signer) error {
+	joinConfig, err := s.configMaps.getCurrent(ctx, constants.JoinConfigMap)
+	if err != nil {
+		return fmt.Errorf("retrieving join-config: %w", err)
+	}
+
+	if err := signJoinConfig(joinConfig, values, key, constants.MeasurementsFilename, constants.IdKeyDigestFilename); err != nil {
+		return err
+	}
+
+	if _, err := s.configMaps.update(ctx, joinConfig); err != nil {
+		return fmt.Errorf("updating join-config: %w", err)
+	}
+	return nil
+}
+
+// JoinConfigValues returns the values of the join-config signed by the cluster owner.
+// They are created from the local config and measurements only, never from the join-config in the cluster,
+// so the owner doesn't sign values changed by anyone with write access to the ConfigMap.
+func JoinConfigValues(conf *config.Config, measurements map[uint32][]byte) (map[string]string, error) {
+	measurementsJSON, err := json.Marshal(measurements)
+	if err != nil {
+		return nil, fmt.Errorf("marshaling measurements: %w", err)
+	}
+
+	var enforcedPCRs []uint32
+	provider := conf.GetProvider()
+	switch provider {
+	case cloudprovider.Azure:
+		enforcedPCRs = conf.Provider.Azure.EnforcedMeasurements
+	case cloudprovider.GCP:
+		enforcedPCRs = conf.Provider.GCP.EnforcedMeasurements
+	case cloudprovider.QEMU:
+		enforcedPCRs = conf.Provider.QEMU.EnforcedMeasurements
+	}
+	// the bootstrapper receives the enforced PCRs through protobuf, which doesn't distinguish nil from empty
+	if len(enforcedPCRs) == 0 {
+		enforcedPCRs = nil
+	}
+	enforcedPCRsJSON, err := json.Marshal(enforcedPCRs)
+	if err != nil {
+		return nil, fmt.Errorf("marshaling enforced PCRs: %w", err)
+	}
+
+	values := map[string]string{
+		constants.MeasurementsFilename: string(measurementsJSON),
+		constants.EnforcedPCRsFilename: string(enforcedPCRsJSON),
+	}
+	if provider == cloudprovider.Azure {
+		idKeyDigest, err := hex.DecodeString(conf.Provider.Azure.IdKeyDigest)
+		if err != nil {
+			return nil, fmt.Errorf("decoding idkeydigest: %w", err)
+		}
+		values[constants.IdKeyDigestFilename] = hex.EncodeToString(idKeyDigest)
+		values[constants.EnforceIdKeyDigestFilename] = strconv.FormatBool(conf.EnforcesIdKeyDigest())
+	}
+	if conf.AttestationPolicy != "" {
+		values[constants.AttestationPolicyFilename] = conf.AttestationPolicy
+	}
+	if provider == cloudprovider.QEMU && conf.Provider.QEMU.EKCertificateAuthority != "" {
+		values[constants.EKCertificateAuthorityFilename] = conf.Provider.QEMU.EKCertificateAuthority
+	}
+	return values, nil
+}
+
+// signJoinConfig replaces the signed values of joinConfig with values and signs them with key.
+// Only the values of the keys in replace may differ from the values in the cluster.
+// Signing is refused if any other signed value was changed in the cluster, since the owner would otherwise
+// approve a change they never made.
+// The version of the join-config is incremented, so the join service rejects previously signed values.
+func signJoinConfig(joinConfig *corev1.ConfigMap, values map[string]string, key crypto.Signer, replace ...string) error {
+	if joinConfig.Data == nil {
+		joinConfig.Data = map[string]string{}
+	}
+	version, err := joinconfig.Version(joinConfig.Data)
+	if err != nil {
+		return err
+	}
+
+	for _, signedKey := range joinconfig.SignedKeys {
+		if signedKey == constants.JoinConfigVersionFilename || contains(replace, signedKey) {
+			continue
+		}
+		clusterValue, inCluster := joinConfig.Data[signedKey]
+		localValue, isLocal := values[signedKey]
+		if inCluster != isLocal || clusterValue != localValue {
+			return fmt.Errorf("join-config value %q in the cluster differs from the local configuration, refusing to sign it", signedKey)
+		}
+	}
+
+	for _, signedKey := range joinconfig.SignedKeys {
+		delete(joinConfig.Data, signedKey)
+		if value, ok := values[signedKey]; ok {
+			joinConfig.Data[signedKey] = value
+		}
+	}
+	joinConfig.Data[constants.JoinConfigVersionFilename] = strconv.FormatUint(version+1, 10)
+
+	signature, err := joinconfig.Sign(joinConfig.Data, key)
+	if err != nil {
+		return fmt.Errorf("signing join-config: %w", err)
+	}
+	joinConfig.Data[constants.MeasurementsSignatureFilename] = string(signature)
+	return nil
+}
+
+func contains(list []string, s string) bool {
+	for _, item := range list {
+		if item == s {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,183 @@
+/*
+Copyright (c) Edgeless Systems GmbH
+
+SPDX-License-Identifier: AGPL-3.0-only
+*/
+
+package cloudcmd
+
+import (
+	"context"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"errors"
+	"testing"
+
+	"github.com/edgelesssys/constellation/v2/internal/cloud/cloudprovider"
+	"github.com/edgelesssys/constellation/v2/internal/config"
+	"github.com/edgelesssys/constellation/v2/internal/constants"
+	"github.com/edgelesssys/constellation/v2/internal/joinconfig"
+	"github.com/sigstore/sigstore/pkg/cryptoutils"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	corev1 "k8s.io/api/core/v1"
+)
+
+func TestSignJoinConfig(t *testing.T) {
+	someErr := errors.New("error")
+	ownerKey, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	require.NoError(t, err)
+	publicKey, err := cryptoutils.MarshalPublicKeyToPEM(ownerKey.Public())
+	require.NoError(t, err)
+	values := map[string]string{
+		constants.MeasurementsFilename: `{"0":"MQ=="}`,
+		constants.EnforcedPCRsFilename: "[0]",
+	}
+
+	testCases := map[string]struct {
+		configMaps *stubMeasurementsUpdater
+		wantErr    bool
+	}{
+		"success": {
+			configMaps: &stubMeasurementsUpdater{
+				oldMeasurements: &corev1.ConfigMap{Data: map[string]string{
+					constants.MeasurementsFilename: `{"0":"MQ==","1":"Mg=="}`,
+					constants.EnforcedPCRsFilename: "[0]",
+					constants.AzureCVM:             "true",
+				}},
+			},
+		},
+		"values changed in the cluster": {
+			configMaps: &stubMeasurementsUpdater{
+				oldMeasurements: &corev1.ConfigMap{Data: map[string]string{
+					constants.MeasurementsFilename: `{"0":"MQ=="}`,
+					constants.EnforcedPCRsFilename: "[]",
+				}},
+			},
+			wantErr: true,
+		},
+		"values added in the cluster": {
+			configMaps: &stubMeasurementsUpdater{
+				oldMeasurements: &corev1.ConfigMap{Data: map[string]string{
+					constants.MeasurementsFilename:      `{"0":"MQ=="}`,
+					constants.EnforcedPCRsFilename:      "[0]",
+					constants.AttestationPolicyFilename: "true",
+				}},
+			},
+			wantErr: true,
+		},
+		"getting join-config fails": {
+			configMaps: &stubMeasurementsUpdater{getErr: someErr},
+			wantErr:    true,
+		},
+		"updating join-config fails": {
+			configMaps: &stubMeasurementsUpdater{
+				oldMeasurements: &corev1.ConfigMap{Data: map[string]string{
+					constants.MeasurementsFilename: `{"0":"MQ=="}`,
+					constants.EnforcedPCRsFilename: "[0]",
+				}},
+				updateErr: someErr,
+			},
+			wantErr: true,
+		},
+	}
+
+	for name, tc := range testCases {
+		t.Run(name, func(t *testing.T) {
+			assert := assert.New(t)
+			require := require.New(t)
+
+			signer := &JoinConfigSigner{configMaps: tc.configMaps}
+
+			err := signer.Sign(context.Background(), values, ownerKey)
+			if tc.wantErr {
+				assert.Error(err)
+				return
+			}
+			require.NoError(err)
+			updated := tc.configMaps.updatedMeasurements.Data
+			assert.Equal(values[constants.MeasurementsFilename], updated[constants.MeasurementsFilename])
+			assert.Equal("1", updated[constants.JoinConfigVersionFilename])
+			assert.Equal("true", updated[constants.AzureCVM])
+			assert.NoError(joinconfig.Verify(updated, []byte(updated[constants.MeasurementsSignatureFilename]), publicKey))
+		})
+	}
+}
+
+func TestJoinConfigValues(t *testing.T) {
+	measurements := map[uint32][]byte{11: {0x1}}
+
+	testCases := map[string]struct {
+		config     func() *config.Config
+		wantValues map[string]string
+		wantErr    bool
+	}{
+		"gcp": {
+			config: func() *config.Config {
+				conf := config.Default()
+				conf.RemoveProviderExcept(cloudprovider.GCP)
+				conf.Provider.GCP.EnforcedMeasurements = []uint32{11}
+				return conf
+			},
+			wantValues: map[string]string{
+				constants.MeasurementsFilename: `{"11":"AQ=="}`,
+				constants.EnforcedPCRsFilename: "[11]",
+			},
+		},
+		"azure with attestation policy": {
+			config: func() *config.Config {
+				conf := config.Default()
+				conf.RemoveProviderExcept(cloudprovider.Azure)
+				conf.Provider.Azure.EnforcedMeasurements = nil
+				conf.Provider.Azure.IdKeyDigest = "ABCD"
+				conf.AttestationPolicy = "true"
+				return conf
+			},
+			wantValues: map[string]string{
+				constants.MeasurementsFilename:       `{"11":"AQ=="}`,
+				constants.EnforcedPCRsFilename:       "null",
+				constants.IdKeyDigestFilename:        "abcd",
+				constants.EnforceIdKeyDigestFilename: "true",
+				constants.AttestationPolicyFilename:  "true",
+			},
+		},
+		"qemu": {
+			config: func() *config.Config {
+				conf := config.Default()
+				conf.RemoveProviderExcept(cloudprovider.QEMU)
+				conf.Provider.QEMU.EnforcedMeasurements = []uint32{}
+				conf.Provider.QEMU.EKCertificateAuthority = "ca"
+				return conf
+			},
+			wantValues: map[string]string{
+				constants.MeasurementsFilename:           `{"11":"AQ=="}`,
+				constants.EnforcedPCRsFilename:           "null",
+				constants.EKCertificateAuthorityFilename: "ca",
+			},
+		},
+		"invalid idkeydigest": {
+			config: func() *config.Config {
+				conf := config.Default()
+				conf.RemoveProviderExcept(cloudprovider.Azure)
+				conf.Provider.Azure.IdKeyDigest = "not hex"
+				return conf
+			},
+			wantErr: true,
+		},
+	}
+
+	for name, tc := range testCases {
+		t.Run(name, func(t *testing.T) {
+			assert := assert.New(t)
+
+			values, err := JoinConfigValues(tc.config(), measurements)
+			if tc.wantErr {
+				assert.Error(err)
+				return
+			}
+			assert.NoError(err)
+			assert.Equal(tc.wantValues, values)
+		})
+	}
+}
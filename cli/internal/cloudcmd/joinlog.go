@@ -0,0 +1,108 @@
+/*
+Copyright (c) Edgeless Systems GmbH
+
+SPDX-License-Identifier: AGPL-3.0-only
+*/
+
+package cloudcmd
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/url"
+	"sort"
+
+	"github.com/edgelesssys/constellation/v2/internal/constants"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/client-go/dynamic"
+	"k8s.io/client-go/tools/clientcmd"
+)
+
+// joinRecordGVR is the resource of JoinRecord objects.
+var joinRecordGVR = schema.GroupVersionResource{
+	Group:    constants.NodeAttestationGroup,
+	Version:  constants.NodeAttestationVersion,
+	Resource: constants.JoinRecordResource,
+}
+
+// JoinRecord is the decision of the JoinService on a join, rejoin or certificate renewal request of a node.
+type JoinRecord struct {
+	Time                metav1.Time `json:"time"`
+	Request             string      `json:"request"`
+	PeerAddress         string      `json:"peerAddress,omitempty"`
+	AttestationVariant  string      `json:"attestationVariant,omitempty"`
+	InstanceID          string      `json:"instanceID,omitempty"`
+	AttestationIdentity string      `json:"attestationIdentity,omitempty"`
+	NodeName            string      `json:"nodeName,omitempty"`
+	DiskUUID            string      `json:"diskUUID,omitempty"`
+	IsControlPlane      bool        `json:"isControlPlane,omitempty"`
+	Result              string      `json:"result"`
+	Reason              string      `json:"reason,omitempty"`
+	Count               int         `json:"count,omitempty"`
+}
+
+// JoinLogReader reads the join history of a cluster.
+type JoinLogReader struct {
+	kube joinRecordLister
+}
+
+// NewJoinLogReader returns a new JoinLogReader.
+// Requests to the Kubernetes API server use the proxy selected by proxy.
+func NewJoinLogReader(proxy func(*http.Request) (*url.URL, error)) (*JoinLogReader, error) {
+	kubeConfig, err := clientcmd.BuildConfigFromFlags("", constants.AdminConfFilename)
+	if err != nil {
+		return nil, fmt.Errorf("building kubernetes config: %w", err)
+	}
+	kubeConfig.Proxy = proxy
+
+	unstructuredClient, err := dynamic.NewForConfig(kubeConfig)
+	if err != nil {
+		return nil, fmt.Errorf("setting up custom resource client: %w", err)
+	}
+	return &JoinLogReader{kube: &kubeJoinRecordLister{dynamic: unstructuredClient}}, nil
+}
+
+// JoinLog returns the JoinRecords of the cluster, ordered from oldest to newest.
+func (r *JoinLogReader) JoinLog(ctx context.Context) ([]JoinRecord, error) {
+	items, err := r.kube.listJoinRecords(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("listing join records: %w", err)
+	}
+
+	records := make([]JoinRecord, 0, len(items))
+	for _, item := range items {
+		spec, ok, err := unstructured.NestedMap(item.Object, "spec")
+		if err != nil || !ok {
+			return nil, fmt.Errorf("join record %q has no valid spec", item.GetName())
+		}
+		var record JoinRecord
+		if err := runtime.DefaultUnstructuredConverter.FromUnstructured(spec, &record); err != nil {
+			return nil, fmt.Errorf("converting join record %q: %w", item.GetName(), err)
+		}
+		records = append(records, record)
+	}
+	sort.SliceStable(records, func(i, j int) bool {
+		return records[i].Time.Before(&records[j].Time)
+	})
+	return records, nil
+}
+
+type joinRecordLister interface {
+	listJoinRecords(ctx context.Context) ([]unstructured.Unstructured, error)
+}
+
+type kubeJoinRecordLister struct {
+	dynamic dynamic.Interface
+}
+
+func (k *kubeJoinRecordLister) listJoinRecords(ctx context.Context) ([]unstructured.Unstructured, error) {
+	list, err := k.dynamic.Resource(joinRecordGVR).List(ctx, metav1.ListOptions{})
+	if err != nil {
+		return nil, err
+	}
+	return list.Items, nil
+}
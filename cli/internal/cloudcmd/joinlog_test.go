@@ -0,0 +1,99 @@
+/*
+Copyright (c) Edgeless Systems GmbH
+
+SPDX-License-Identifier: AGPL-3.0-only
+*/
+
+package cloudcmd
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+)
+
+func TestJoinLog(t *testing.T) {
+	joinRecord := func(name, timestamp, nodeName, result string) unstructured.Unstructured {
+		return unstructured.Unstructured{Object: map[string]any{
+			"metadata": map[string]any{"name": name},
+			"spec": map[string]any{
+				"time":           timestamp,
+				"request":        "IssueJoinTicket",
+				"nodeName":       nodeName,
+				"diskUUID":       "disk-" + name,
+				"isControlPlane": true,
+				"result":         result,
+			},
+		}}
+	}
+
+	testCases := map[string]struct {
+		kube      stubJoinRecordLister
+		wantNodes []string
+		wantErr   bool
+	}{
+		"records are sorted by time": {
+			kube: stubJoinRecordLister{records: []unstructured.Unstructured{
+				joinRecord("b", "2022-10-01T12:00:00Z", "worker-1", "Accepted"),
+				joinRecord("a", "2022-10-01T11:00:00Z", "worker-0", "Denied"),
+				joinRecord("c", "2022-10-02T08:00:00Z", "worker-2", "Failed"),
+			}},
+			wantNodes: []string{"worker-0", "worker-1", "worker-2"},
+		},
+		"no records": {
+			wantNodes: []string{},
+		},
+		"record without spec": {
+			kube: stubJoinRecordLister{records: []unstructured.Unstructured{
+				{Object: map[string]any{"metadata": map[string]any{"name": "a"}}},
+			}},
+			wantErr: true,
+		},
+		"list fails": {
+			kube:    stubJoinRecordLister{err: errors.New("failed")},
+			wantErr: true,
+		},
+	}
+
+	for name, tc := range testCases {
+		t.Run(name, func(t *testing.T) {
+			assert := assert.New(t)
+			require := require.New(t)
+
+			reader := &JoinLogReader{kube: tc.kube}
+			records, err := reader.JoinLog(context.Background())
+			if tc.wantErr {
+				assert.Error(err)
+				return
+			}
+			require.NoError(err)
+
+			nodes := []string{}
+			for _, record := range records {
+				nodes = append(nodes, record.NodeName)
+				assert.True(record.IsControlPlane)
+				assert.Equal("IssueJoinTicket", record.Request)
+			}
+			assert.Equal(tc.wantNodes, nodes)
+			if len(records) > 0 {
+				assert.Equal(time.Date(2022, 10, 1, 11, 0, 0, 0, time.UTC), records[0].Time.UTC())
+				assert.Equal("disk-a", records[0].DiskUUID)
+				assert.Equal("Denied", records[0].Result)
+			}
+		})
+	}
+}
+
+type stubJoinRecordLister struct {
+	records []unstructured.Unstructured
+	err     error
+}
+
+func (s stubJoinRecordLister) listJoinRecords(context.Context) ([]unstructured.Unstructured, error) {
+	return s.records, s.err
+}
@@ -0,0 +1,627 @@
+/*
+Copyright (c) Edgeless Systems GmbH
+
+SPDX-License-Identifier: AGPL-3.0-only
+*/
+
+package cloudcmd
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"crypto/aes"
+	"crypto/cipher"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+
+	"github.com/edgelesssys/constellation/v2/internal/constants"
+	"github.com/edgelesssys/constellation/v2/internal/crypto"
+	"github.com/edgelesssys/constellation/v2/internal/versions"
+	corev1 "k8s.io/api/core/v1"
+	k8serrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/client-go/dynamic"
+	"k8s.io/client-go/kubernetes"
+	"k8s.io/client-go/kubernetes/scheme"
+	"k8s.io/client-go/rest"
+	"k8s.io/client-go/tools/clientcmd"
+	"k8s.io/client-go/tools/remotecommand"
+)
+
+const (
+	// etcdRestorePodName is the name of the pod restoring the etcd snapshot on the control-plane node.
+	etcdRestorePodName = "constellation-etcd-restore"
+	// etcdRestoreUploadContainer receives the snapshot from the CLI.
+	etcdRestoreUploadContainer = "upload"
+	// etcdRestoreHostDir is the host directory used to prepare the restored etcd data directory.
+	// It is on the same file system as the etcd data directory, so the restored data can be moved in place atomically.
+	etcdRestoreHostDir = "/var/lib/etcd-restore"
+)
+
+// Restorer replaces the etcd keyspace of a Constellation with a single control-plane node by a snapshot.
+type Restorer struct {
+	kube restorerKubeClient
+
+	writer       io.Writer
+	pollInterval time.Duration
+	timeout      time.Duration
+}
+
+// NewRestorer returns a new Restorer.
+// Requests to the Kubernetes API server use the proxy selected by proxy.
+func NewRestorer(writer io.Writer, proxy func(*http.Request) (*url.URL, error)) (*Restorer, error) {
+	kubeConfig, err := clientcmd.BuildConfigFromFlags("", constants.AdminConfFilename)
+	if err != nil {
+		return nil, fmt.Errorf("building kubernetes config: %w", err)
+	}
+	kubeConfig.Proxy = proxy
+
+	kubeClient, err := kubernetes.NewForConfig(kubeConfig)
+	if err != nil {
+		return nil, fmt.Errorf("setting up kubernetes client: %w", err)
+	}
+	unstructuredClient, err := dynamic.NewForConfig(kubeConfig)
+	if err != nil {
+		return nil, fmt.Errorf("setting up custom resource client: %w", err)
+	}
+
+	return &Restorer{
+		kube:         &kubeRestorer{client: kubeClient, dynamic: unstructuredClient, config: kubeConfig},
+		writer:       writer,
+		pollInterval: 2 * time.Second,
+		timeout:      10 * time.Minute,
+	}, nil
+}
+
+// Restore replaces the etcd keyspace of the cluster by the given decrypted snapshot.
+//
+// The cluster must have exactly one control-plane node. A restore pod on this node restores the snapshot into
+// a new data directory, stops etcd, swaps the data directories and starts etcd again.
+// The Kubernetes API server is unavailable while etcd restarts.
+//
+// The objects describing the infrastructure of the cluster, e.g., its scaling groups, nodes and owner key,
+// are captured before the restore and written back afterwards, see clusterResources.
+func (r *Restorer) Restore(ctx context.Context, snapshot []byte) error {
+	ctx, cancel := context.WithTimeout(ctx, r.timeout)
+	defer cancel()
+
+	nodes, err := r.kube.listControlPlaneNodes(ctx)
+	if err != nil {
+		return fmt.Errorf("listing control-plane nodes: %w", err)
+	}
+	if len(nodes) != 1 {
+		return fmt.Errorf("restoring requires a cluster with exactly one control-plane node, found %d", len(nodes))
+	}
+	node := nodes[0]
+	nodeIP := internalIP(&node)
+	if nodeIP == "" {
+		return fmt.Errorf("control-plane node %q has no internal IP", node.Name)
+	}
+	etcdImage, err := r.kube.getEtcdImage(ctx, node.Name)
+	if err != nil {
+		return fmt.Errorf("getting etcd image: %w", err)
+	}
+	captured, err := r.captureClusterResources(ctx)
+	if err != nil {
+		return err
+	}
+
+	if err := r.kube.createPod(ctx, newEtcdRestorePod(node.Name, nodeIP, etcdImage)); err != nil {
+		return fmt.Errorf("creating restore pod: %w", err)
+	}
+	fmt.Fprintf(r.writer, "Created restore pod on node %s\n", node.Name)
+
+	if err := r.waitForUpload(ctx); err != nil {
+		r.deletePod()
+		return err
+	}
+	upload := []string{"sh", "-c", "cat > /restore/snapshot.db.tmp && mv /restore/snapshot.db.tmp /restore/snapshot.db"}
+	if err := r.kube.exec(ctx, etcdRestorePodName, etcdRestoreUploadContainer, upload, bytes.NewReader(snapshot)); err != nil {
+		r.deletePod()
+		return fmt.Errorf("uploading snapshot: %w", err)
+	}
+	fmt.Fprintln(r.writer, "Uploaded snapshot, waiting for etcd to restart")
+
+	if err := r.waitForRestore(ctx); err != nil {
+		r.deletePod()
+		return err
+	}
+	r.deletePod()
+
+	if err := r.writeBackClusterResources(ctx, captured); err != nil {
+		return fmt.Errorf("restored snapshot, but failed to replace the resources of the snapshot's cluster: %w", err)
+	}
+	fmt.Fprintln(r.writer, "Replaced nodes, scaling groups and cluster configuration of the snapshot's cluster")
+	return nil
+}
+
+// clusterResource is a set of objects describing the infrastructure of a cluster instead of its workloads.
+// A snapshot's objects of a clusterResource belong to the cluster the snapshot was taken of.
+// When restoring the snapshot into a new cluster, they are replaced by the objects of the new cluster.
+// Otherwise, the node operator would manage the scaling groups of the snapshot's cluster,
+// and the new cluster would expect join requests signed by the owner of the snapshot's cluster.
+type clusterResource struct {
+	gvr       schema.GroupVersionResource
+	namespace string
+	// names limits the clusterResource to the objects with these names. It includes all objects of the resource if empty.
+	names []string
+	// immutable objects are deleted and created again instead of updated.
+	immutable bool
+	// removeOnly only removes the snapshot's objects. Objects of the new cluster aren't written back,
+	// since they are owned by other objects of the new cluster that the restore doesn't keep.
+	removeOnly bool
+}
+
+// clusterResources are the objects a restore keeps from the cluster the snapshot is restored into.
+// Scaling groups come first, so the node operator stops managing the scaling groups of the snapshot's cluster as soon as possible.
+var clusterResources = []clusterResource{
+	{gvr: scalingGroupResource},
+	{
+		gvr:       schema.GroupVersionResource{Group: "operators.coreos.com", Version: "v1alpha1", Resource: "subscriptions"},
+		namespace: constants.ConstellationNamespace,
+		names:     []string{"constellation-node-operator-sub"},
+	},
+	{
+		gvr:       schema.GroupVersionResource{Version: "v1", Resource: "configmaps"},
+		namespace: constants.ConstellationNamespace,
+		names:     []string{constants.OwnerPublicKeyConfigMap},
+		immutable: true,
+	},
+	{
+		gvr:       schema.GroupVersionResource{Version: "v1", Resource: "configmaps"},
+		namespace: constants.ConstellationNamespace,
+		names:     []string{constants.JoinConfigMap, constants.InternalConfigMap},
+	},
+	{gvr: pendingNodeResource, removeOnly: true},
+	{gvr: schema.GroupVersionResource{Version: "v1", Resource: "nodes"}},
+}
+
+var (
+	scalingGroupResource = schema.GroupVersionResource{
+		Group:    "update.edgeless.systems",
+		Version:  "v1alpha1",
+		Resource: "scalinggroups",
+	}
+	pendingNodeResource = schema.GroupVersionResource{
+		Group:    "update.edgeless.systems",
+		Version:  "v1alpha1",
+		Resource: "pendingnodes",
+	}
+)
+
+// captureClusterResources returns the objects of each of the clusterResources of the cluster.
+func (r *Restorer) captureClusterResources(ctx context.Context) ([][]unstructured.Unstructured, error) {
+	captured := make([][]unstructured.Unstructured, len(clusterResources))
+	for i, resource := range clusterResources {
+		objs, err := r.listClusterResource(ctx, resource)
+		if err != nil {
+			return nil, fmt.Errorf("listing %s: %w", resource.gvr.Resource, err)
+		}
+		captured[i] = objs
+	}
+	return captured, nil
+}
+
+// writeBackClusterResources replaces the restored objects of the clusterResources by the captured ones.
+// Restored objects that weren't captured are deleted, captured objects are updated or created.
+func (r *Restorer) writeBackClusterResources(ctx context.Context, captured [][]unstructured.Unstructured) error {
+	for i, resource := range clusterResources {
+		restored, err := r.listClusterResource(ctx, resource)
+		if err != nil {
+			return fmt.Errorf("listing %s: %w", resource.gvr.Resource, err)
+		}
+		capturedNames := make(map[string]struct{}, len(captured[i]))
+		for _, obj := range captured[i] {
+			capturedNames[obj.GetName()] = struct{}{}
+		}
+		restoredObjs := make(map[string]unstructured.Unstructured, len(restored))
+		for _, obj := range restored {
+			if _, ok := capturedNames[obj.GetName()]; ok {
+				restoredObjs[obj.GetName()] = obj
+				continue
+			}
+			if err := r.kube.deleteResource(ctx, resource.gvr, resource.namespace, obj.GetName()); err != nil && !k8serrors.IsNotFound(err) {
+				return fmt.Errorf("deleting %s %s: %w", resource.gvr.Resource, obj.GetName(), err)
+			}
+			fmt.Fprintf(r.writer, "Deleted %s %s of the snapshot's cluster\n", resource.gvr.Resource, obj.GetName())
+		}
+		if resource.removeOnly {
+			continue
+		}
+
+		for _, obj := range captured[i] {
+			obj := obj.DeepCopy()
+			obj.SetUID("")
+			obj.SetResourceVersion("")
+			obj.SetCreationTimestamp(metav1.Time{})
+			obj.SetManagedFields(nil)
+
+			existing, ok := restoredObjs[obj.GetName()]
+			switch {
+			case !ok:
+				err = r.kube.createResource(ctx, resource.gvr, obj)
+			case resource.immutable:
+				err = r.kube.deleteResource(ctx, resource.gvr, resource.namespace, obj.GetName())
+				if err == nil || k8serrors.IsNotFound(err) {
+					err = r.kube.createResource(ctx, resource.gvr, obj)
+				}
+			default:
+				obj.SetResourceVersion(existing.GetResourceVersion())
+				err = r.kube.updateResource(ctx, resource.gvr, obj)
+			}
+			if err != nil {
+				return fmt.Errorf("writing back %s %s: %w", resource.gvr.Resource, obj.GetName(), err)
+			}
+		}
+	}
+	return nil
+}
+
+// listClusterResource returns the objects of the clusterResource.
+func (r *Restorer) listClusterResource(ctx context.Context, resource clusterResource) ([]unstructured.Unstructured, error) {
+	objs, err := r.kube.listResources(ctx, resource.gvr, resource.namespace)
+	if err != nil {
+		return nil, err
+	}
+	if len(resource.names) == 0 {
+		return objs, nil
+	}
+	var filtered []unstructured.Unstructured
+	for _, obj := range objs {
+		for _, name := range resource.names {
+			if obj.GetName() == name {
+				filtered = append(filtered, obj)
+			}
+		}
+	}
+	return filtered, nil
+}
+
+// waitForUpload waits until the restore pod is ready to receive the snapshot.
+func (r *Restorer) waitForUpload(ctx context.Context) error {
+	for {
+		pod, err := r.kube.getPod(ctx, etcdRestorePodName)
+		if err == nil {
+			if pod.Status.Phase == corev1.PodFailed {
+				return errors.New("restore pod failed before receiving the snapshot")
+			}
+			for _, status := range pod.Status.InitContainerStatuses {
+				if status.Name == etcdRestoreUploadContainer && status.State.Running != nil {
+					return nil
+				}
+			}
+		}
+		select {
+		case <-ctx.Done():
+			return fmt.Errorf("waiting for restore pod: %w", ctx.Err())
+		case <-time.After(r.pollInterval):
+		}
+	}
+}
+
+// waitForRestore waits until the Kubernetes API server serves the restored keyspace.
+// The restore pod was created after the snapshot was taken, so it doesn't exist in the restored keyspace.
+// Errors are expected while etcd and the API server restart.
+func (r *Restorer) waitForRestore(ctx context.Context) error {
+	for {
+		pod, err := r.kube.getPod(ctx, etcdRestorePodName)
+		switch {
+		case k8serrors.IsNotFound(err):
+			return nil
+		case err != nil:
+			// API server is restarting
+		case pod.Status.Phase == corev1.PodSucceeded:
+			return nil
+		case pod.Status.Phase == corev1.PodFailed:
+			return fmt.Errorf("restore pod failed: %s", podFailureMessage(pod))
+		}
+		select {
+		case <-ctx.Done():
+			return fmt.Errorf("waiting for etcd restore: %w", ctx.Err())
+		case <-time.After(r.pollInterval):
+		}
+	}
+}
+
+// deletePod removes the restore pod. Errors are ignored, since the pod doesn't exist in the restored keyspace.
+func (r *Restorer) deletePod() {
+	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+	defer cancel()
+	if err := r.kube.deletePod(ctx, etcdRestorePodName); err != nil && !k8serrors.IsNotFound(err) {
+		fmt.Fprintf(r.writer, "Failed to delete restore pod %s: %s\n", etcdRestorePodName, err)
+	}
+}
+
+// newEtcdRestorePod returns a pod restoring an etcd snapshot on the given control-plane node.
+//
+// The upload init container waits for the CLI to upload the snapshot.
+// The etcdutl init container restores the snapshot into a new data directory, using the image of the running etcd.
+// The restore container stops etcd by moving its static pod manifest, swaps the data directories and moves the manifest back.
+// Data directories are swapped inside the /var/lib host mount, so they are renamed instead of copied.
+// The previous data directory is kept as /var/lib/etcd/member.pre-restore.
+func newEtcdRestorePod(nodeName, nodeIP, etcdImage string) *corev1.Pod {
+	peerURL := (&url.URL{Scheme: "https", Host: net.JoinHostPort(nodeIP, "2380")}).String()
+	privileged := true
+
+	restoreScript := strings.Join([]string{
+		"set -e",
+		"cd /host/var/lib",
+		"mv /host/etc/kubernetes/manifests/etcd.yaml etcd-restore/etcd.yaml",
+		"trap 'mv etcd-restore/etcd.yaml /host/etc/kubernetes/manifests/etcd.yaml' EXIT",
+		"while pidof etcd > /dev/null; do sleep 1; done",
+		"rm -rf etcd/member.pre-restore",
+		"mv etcd/member etcd/member.pre-restore",
+		"mv etcd-restore/data/member etcd/member",
+		"rm -rf etcd-restore/data etcd-restore/snapshot.db",
+	}, "\n")
+
+	return &corev1.Pod{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      etcdRestorePodName,
+			Namespace: "kube-system",
+		},
+		Spec: corev1.PodSpec{
+			NodeName:      nodeName,
+			HostPID:       true,
+			RestartPolicy: corev1.RestartPolicyNever,
+			Tolerations:   []corev1.Toleration{{Operator: corev1.TolerationOpExists}},
+			InitContainers: []corev1.Container{
+				{
+					Name:    etcdRestoreUploadContainer,
+					Image:   versions.BusyboxImage,
+					Command: []string{"sh", "-c", "rm -rf /restore/data /restore/snapshot.db; until [ -f /restore/snapshot.db ]; do sleep 1; done"},
+					VolumeMounts: []corev1.VolumeMount{
+						{Name: "restore", MountPath: "/restore"},
+					},
+				},
+				{
+					Name:  "etcdutl",
+					Image: etcdImage,
+					Command: []string{
+						"etcdutl", "snapshot", "restore", "/restore/snapshot.db",
+						"--data-dir=/restore/data",
+						"--name=" + nodeName,
+						"--initial-cluster=" + nodeName + "=" + peerURL,
+						"--initial-advertise-peer-urls=" + peerURL,
+					},
+					VolumeMounts: []corev1.VolumeMount{
+						{Name: "restore", MountPath: "/restore"},
+					},
+				},
+			},
+			Containers: []corev1.Container{
+				{
+					Name:            "restore",
+					Image:           versions.BusyboxImage,
+					Command:         []string{"sh", "-c", restoreScript},
+					SecurityContext: &corev1.SecurityContext{Privileged: &privileged},
+					VolumeMounts: []corev1.VolumeMount{
+						{Name: "var-lib", MountPath: "/host/var/lib"},
+						{Name: "manifests", MountPath: "/host/etc/kubernetes/manifests"},
+					},
+				},
+			},
+			Volumes: []corev1.Volume{
+				hostPathVolume("var-lib", "/var/lib", corev1.HostPathDirectory),
+				hostPathVolume("restore", etcdRestoreHostDir, corev1.HostPathDirectoryOrCreate),
+				hostPathVolume("manifests", "/etc/kubernetes/manifests", corev1.HostPathDirectory),
+			},
+		},
+	}
+}
+
+func hostPathVolume(name, path string, pathType corev1.HostPathType) corev1.Volume {
+	return corev1.Volume{
+		Name: name,
+		VolumeSource: corev1.VolumeSource{
+			HostPath: &corev1.HostPathVolumeSource{Path: path, Type: &pathType},
+		},
+	}
+}
+
+// podFailureMessage returns the termination message of the first failed container of a pod.
+func podFailureMessage(pod *corev1.Pod) string {
+	var statuses []corev1.ContainerStatus
+	statuses = append(statuses, pod.Status.InitContainerStatuses...)
+	statuses = append(statuses, pod.Status.ContainerStatuses...)
+	for _, status := range statuses {
+		if terminated := status.State.Terminated; terminated != nil && terminated.ExitCode != 0 {
+			return fmt.Sprintf("container %s exited with code %d: %s", status.Name, terminated.ExitCode, terminated.Message)
+		}
+	}
+	return pod.Status.Message
+}
+
+// The format of encrypted etcd snapshots is defined by the node operator.
+// A header (version, salt, snapshot name) is followed by AES-GCM sealed chunks of etcdSnapshotChunkSize bytes of plaintext.
+// The nonce of a chunk encodes its position and whether it is the last chunk. Every chunk is bound to the header.
+const (
+	etcdSnapshotVersion   = 1
+	etcdSnapshotSaltSize  = 32
+	etcdSnapshotChunkSize = 64 * 1024
+	etcdSnapshotHKDFInfo  = "constellation etcd backup v1"
+)
+
+// DecryptEtcdSnapshot decrypts an etcd snapshot uploaded by the node operator.
+// dataKey is the key the node operator requested from the KMS.
+// It returns the name the snapshot was uploaded with, which includes the time it was taken, and the decrypted snapshot.
+// An error is returned if any part of the snapshot was modified, reordered, or cut off.
+func DecryptEtcdSnapshot(dataKey []byte, ciphertext io.Reader) (string, []byte, error) {
+	reader := bufio.NewReaderSize(ciphertext, etcdSnapshotChunkSize)
+	header := make([]byte, 1+etcdSnapshotSaltSize+2)
+	if _, err := io.ReadFull(reader, header); err != nil {
+		return "", nil, fmt.Errorf("reading snapshot header: %w", err)
+	}
+	if header[0] != etcdSnapshotVersion {
+		return "", nil, fmt.Errorf("unsupported snapshot version %d", header[0])
+	}
+	salt := header[1 : 1+etcdSnapshotSaltSize]
+	name := make([]byte, binary.BigEndian.Uint16(header[1+etcdSnapshotSaltSize:]))
+	if _, err := io.ReadFull(reader, name); err != nil {
+		return "", nil, fmt.Errorf("reading snapshot header: %w", err)
+	}
+	header = append(header, name...)
+
+	key, err := crypto.DeriveKey(dataKey, salt, []byte(etcdSnapshotHKDFInfo), 32)
+	if err != nil {
+		return "", nil, err
+	}
+	block, err := aes.NewCipher(key)
+	if err != nil {
+		return "", nil, err
+	}
+	aead, err := cipher.NewGCM(block)
+	if err != nil {
+		return "", nil, err
+	}
+
+	snapshot := []byte{}
+	sealed := make([]byte, etcdSnapshotChunkSize+aead.Overhead())
+	nonce := make([]byte, aead.NonceSize())
+	for counter := uint64(0); ; counter++ {
+		n, err := io.ReadFull(reader, sealed)
+		if err != nil && !errors.Is(err, io.EOF) && !errors.Is(err, io.ErrUnexpectedEOF) {
+			return "", nil, fmt.Errorf("reading snapshot: %w", err)
+		}
+		last := n < len(sealed)
+		if !last {
+			if _, err := reader.Peek(1); errors.Is(err, io.EOF) {
+				last = true
+			} else if err != nil {
+				return "", nil, fmt.Errorf("reading snapshot: %w", err)
+			}
+		}
+
+		binary.BigEndian.PutUint64(nonce[3:11], counter)
+		nonce[11] = 0
+		if last {
+			nonce[11] = 1
+		}
+		snapshot, err = aead.Open(snapshot, nonce, sealed[:n], header)
+		if err != nil {
+			return "", nil, errors.New("snapshot was not encrypted with a key derived from this master secret, or is corrupted")
+		}
+		if last {
+			return string(name), snapshot, nil
+		}
+	}
+}
+
+type restorerKubeClient interface {
+	listControlPlaneNodes(ctx context.Context) ([]corev1.Node, error)
+	getEtcdImage(ctx context.Context, nodeName string) (string, error)
+	createPod(ctx context.Context, pod *corev1.Pod) error
+	getPod(ctx context.Context, name string) (*corev1.Pod, error)
+	deletePod(ctx context.Context, name string) error
+	exec(ctx context.Context, pod, container string, command []string, stdin io.Reader) error
+	listResources(ctx context.Context, gvr schema.GroupVersionResource, namespace string) ([]unstructured.Unstructured, error)
+	createResource(ctx context.Context, gvr schema.GroupVersionResource, obj *unstructured.Unstructured) error
+	updateResource(ctx context.Context, gvr schema.GroupVersionResource, obj *unstructured.Unstructured) error
+	deleteResource(ctx context.Context, gvr schema.GroupVersionResource, namespace, name string) error
+}
+
+type kubeRestorer struct {
+	client  kubernetes.Interface
+	dynamic dynamic.Interface
+	config  *rest.Config
+}
+
+func (k *kubeRestorer) listControlPlaneNodes(ctx context.Context) ([]corev1.Node, error) {
+	nodes, err := k.client.CoreV1().Nodes().List(ctx, metav1.ListOptions{LabelSelector: controlPlaneRoleLabel})
+	if err != nil {
+		return nil, err
+	}
+	return nodes.Items, nil
+}
+
+// getEtcdImage returns the image of the etcd static pod running on the node.
+func (k *kubeRestorer) getEtcdImage(ctx context.Context, nodeName string) (string, error) {
+	pod, err := k.client.CoreV1().Pods("kube-system").Get(ctx, "etcd-"+nodeName, metav1.GetOptions{})
+	if err != nil {
+		return "", err
+	}
+	for _, container := range pod.Spec.Containers {
+		if container.Name == "etcd" {
+			return container.Image, nil
+		}
+	}
+	return "", fmt.Errorf("pod %s has no etcd container", pod.Name)
+}
+
+func (k *kubeRestorer) createPod(ctx context.Context, pod *corev1.Pod) error {
+	_, err := k.client.CoreV1().Pods("kube-system").Create(ctx, pod, metav1.CreateOptions{})
+	return err
+}
+
+func (k *kubeRestorer) getPod(ctx context.Context, name string) (*corev1.Pod, error) {
+	return k.client.CoreV1().Pods("kube-system").Get(ctx, name, metav1.GetOptions{})
+}
+
+func (k *kubeRestorer) deletePod(ctx context.Context, name string) error {
+	return k.client.CoreV1().Pods("kube-system").Delete(ctx, name, metav1.DeleteOptions{})
+}
+
+// exec runs the command in a container of the pod, streaming stdin to it.
+func (k *kubeRestorer) exec(ctx context.Context, pod, container string, command []string, stdin io.Reader) error {
+	req := k.client.CoreV1().RESTClient().Post().
+		Resource("pods").Namespace("kube-system").Name(pod).SubResource("exec").
+		VersionedParams(&corev1.PodExecOptions{
+			Container: container,
+			Command:   command,
+			Stdin:     true,
+			Stdout:    true,
+			Stderr:    true,
+		}, scheme.ParameterCodec)
+	executor, err := remotecommand.NewSPDYExecutor(k.config, http.MethodPost, req.URL())
+	if err != nil {
+		return err
+	}
+
+	var stdout, stderr bytes.Buffer
+	errC := make(chan error, 1)
+	go func() {
+		errC <- executor.Stream(remotecommand.StreamOptions{Stdin: stdin, Stdout: &stdout, Stderr: &stderr})
+	}()
+	select {
+	case err := <-errC:
+		if err != nil {
+			return fmt.Errorf("%w: %s", err, strings.TrimSpace(stderr.String()))
+		}
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+// listResources returns the objects of a resource in the namespace. An empty namespace is used for cluster-scoped resources.
+func (k *kubeRestorer) listResources(ctx context.Context, gvr schema.GroupVersionResource, namespace string) ([]unstructured.Unstructured, error) {
+	list, err := k.dynamic.Resource(gvr).Namespace(namespace).List(ctx, metav1.ListOptions{})
+	if err != nil {
+		return nil, err
+	}
+	return list.Items, nil
+}
+
+func (k *kubeRestorer) createResource(ctx context.Context, gvr schema.GroupVersionResource, obj *unstructured.Unstructured) error {
+	_, err := k.dynamic.Resource(gvr).Namespace(obj.GetNamespace()).Create(ctx, obj, metav1.CreateOptions{})
+	return err
+}
+
+func (k *kubeRestorer) updateResource(ctx context.Context, gvr schema.GroupVersionResource, obj *unstructured.Unstructured) error {
+	_, err := k.dynamic.Resource(gvr).Namespace(obj.GetNamespace()).Update(ctx, obj, metav1.UpdateOptions{})
+	return err
+}
+
+func (k *kubeRestorer) deleteResource(ctx context.Context, gvr schema.GroupVersionResource, namespace, name string) error {
+	return k.dynamic.Resource(gvr).Namespace(namespace).Delete(ctx, name, metav1.DeleteOptions{})
+}
@@ -0,0 +1,523 @@
+/*
+Copyright (c) Edgeless Systems GmbH
+
+SPDX-License-Identifier: AGPL-3.0-only
+*/
+
+package cloudcmd
+
+import (
+	"bytes"
+	"context"
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/rand"
+	"encoding/binary"
+	"errors"
+	"io"
+	"testing"
+	"time"
+
+	"github.com/edgelesssys/constellation/v2/internal/crypto"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	corev1 "k8s.io/api/core/v1"
+	k8serrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/apimachinery/pkg/types"
+)
+
+func TestRestore(t *testing.T) {
+	someErr := errors.New("failed")
+	notFoundErr := k8serrors.NewNotFound(schema.GroupResource{}, etcdRestorePodName)
+
+	controlPlaneNode := corev1.Node{
+		ObjectMeta: metav1.ObjectMeta{Name: "control-plane-0"},
+		Status: corev1.NodeStatus{Addresses: []corev1.NodeAddress{
+			{Type: corev1.NodeInternalIP, Address: "192.0.2.1"},
+		}},
+	}
+	uploadingPod := &corev1.Pod{Status: corev1.PodStatus{
+		Phase: corev1.PodPending,
+		InitContainerStatuses: []corev1.ContainerStatus{
+			{Name: etcdRestoreUploadContainer, State: corev1.ContainerState{Running: &corev1.ContainerStateRunning{}}},
+		},
+	}}
+	failedPod := &corev1.Pod{Status: corev1.PodStatus{
+		Phase: corev1.PodFailed,
+		ContainerStatuses: []corev1.ContainerStatus{
+			{Name: "restore", State: corev1.ContainerState{Terminated: &corev1.ContainerStateTerminated{ExitCode: 1}}},
+		},
+	}}
+
+	testCases := map[string]struct {
+		kube        *stubRestorerKubeClient
+		wantUpload  bool
+		wantDeleted bool
+		wantErr     bool
+	}{
+		"restore succeeds when the pod is gone": {
+			kube: &stubRestorerKubeClient{
+				nodes: []corev1.Node{controlPlaneNode},
+				pods:  []stubPodResponse{{pod: uploadingPod}, {err: someErr}, {err: notFoundErr}},
+			},
+			wantUpload:  true,
+			wantDeleted: true,
+		},
+		"restore succeeds when the pod succeeded": {
+			kube: &stubRestorerKubeClient{
+				nodes: []corev1.Node{controlPlaneNode},
+				pods:  []stubPodResponse{{pod: uploadingPod}, {pod: &corev1.Pod{Status: corev1.PodStatus{Phase: corev1.PodSucceeded}}}},
+			},
+			wantUpload:  true,
+			wantDeleted: true,
+		},
+		"multiple control-plane nodes": {
+			kube: &stubRestorerKubeClient{
+				nodes: []corev1.Node{controlPlaneNode, controlPlaneNode},
+			},
+			wantErr: true,
+		},
+		"node without internal IP": {
+			kube: &stubRestorerKubeClient{
+				nodes: []corev1.Node{{ObjectMeta: metav1.ObjectMeta{Name: "control-plane-0"}}},
+			},
+			wantErr: true,
+		},
+		"listing nodes fails": {
+			kube:    &stubRestorerKubeClient{listErr: someErr},
+			wantErr: true,
+		},
+		"getting etcd image fails": {
+			kube: &stubRestorerKubeClient{
+				nodes:      []corev1.Node{controlPlaneNode},
+				etcdImgErr: someErr,
+			},
+			wantErr: true,
+		},
+		"creating pod fails": {
+			kube: &stubRestorerKubeClient{
+				nodes:     []corev1.Node{controlPlaneNode},
+				createErr: someErr,
+			},
+			wantErr: true,
+		},
+		"pod fails before upload": {
+			kube: &stubRestorerKubeClient{
+				nodes: []corev1.Node{controlPlaneNode},
+				pods:  []stubPodResponse{{pod: failedPod}},
+			},
+			wantDeleted: true,
+			wantErr:     true,
+		},
+		"upload fails": {
+			kube: &stubRestorerKubeClient{
+				nodes:   []corev1.Node{controlPlaneNode},
+				pods:    []stubPodResponse{{pod: uploadingPod}},
+				execErr: someErr,
+			},
+			wantUpload:  true,
+			wantDeleted: true,
+			wantErr:     true,
+		},
+		"restore fails": {
+			kube: &stubRestorerKubeClient{
+				nodes: []corev1.Node{controlPlaneNode},
+				pods:  []stubPodResponse{{pod: uploadingPod}, {pod: failedPod}},
+			},
+			wantUpload:  true,
+			wantDeleted: true,
+			wantErr:     true,
+		},
+		"restore times out": {
+			kube: &stubRestorerKubeClient{
+				nodes: []corev1.Node{controlPlaneNode},
+				pods:  []stubPodResponse{{pod: uploadingPod}, {err: someErr}},
+			},
+			wantUpload:  true,
+			wantDeleted: true,
+			wantErr:     true,
+		},
+		"capturing cluster resources fails": {
+			kube: &stubRestorerKubeClient{
+				nodes:            []corev1.Node{controlPlaneNode},
+				listResourcesErr: someErr,
+			},
+			wantErr: true,
+		},
+		"writing back cluster resources fails": {
+			kube: &stubRestorerKubeClient{
+				nodes:            []corev1.Node{controlPlaneNode},
+				pods:             []stubPodResponse{{pod: uploadingPod}, {err: notFoundErr}},
+				objects:          map[schema.GroupVersionResource][]unstructured.Unstructured{scalingGroupResource: {newUnstructured("", "new-group", nil)}},
+				writeResourceErr: someErr,
+			},
+			wantUpload:  true,
+			wantDeleted: true,
+			wantErr:     true,
+		},
+	}
+
+	for name, tc := range testCases {
+		t.Run(name, func(t *testing.T) {
+			assert := assert.New(t)
+
+			restorer := &Restorer{
+				kube:         tc.kube,
+				writer:       &bytes.Buffer{},
+				pollInterval: time.Millisecond,
+				timeout:      100 * time.Millisecond,
+			}
+
+			err := restorer.Restore(context.Background(), []byte("snapshot"))
+			if tc.wantErr {
+				assert.Error(err)
+			} else {
+				assert.NoError(err)
+			}
+			if tc.wantUpload {
+				assert.Equal([]byte("snapshot"), tc.kube.uploaded)
+			} else {
+				assert.Nil(tc.kube.uploaded)
+			}
+			assert.Equal(tc.wantDeleted, tc.kube.deleted)
+		})
+	}
+}
+
+func TestRestoreClusterResources(t *testing.T) {
+	assert := assert.New(t)
+	require := require.New(t)
+
+	nodeResource := schema.GroupVersionResource{Version: "v1", Resource: "nodes"}
+	configMapResource := schema.GroupVersionResource{Version: "v1", Resource: "configmaps"}
+	controlPlaneNode := corev1.Node{
+		ObjectMeta: metav1.ObjectMeta{Name: "new-control-plane"},
+		Status: corev1.NodeStatus{Addresses: []corev1.NodeAddress{
+			{Type: corev1.NodeInternalIP, Address: "192.0.2.1"},
+		}},
+	}
+	kube := &stubRestorerKubeClient{
+		nodes: []corev1.Node{controlPlaneNode},
+		pods: []stubPodResponse{
+			{pod: &corev1.Pod{Status: corev1.PodStatus{InitContainerStatuses: []corev1.ContainerStatus{
+				{Name: etcdRestoreUploadContainer, State: corev1.ContainerState{Running: &corev1.ContainerStateRunning{}}},
+			}}}},
+			{err: k8serrors.NewNotFound(schema.GroupResource{}, etcdRestorePodName)},
+		},
+		objects: map[schema.GroupVersionResource][]unstructured.Unstructured{
+			scalingGroupResource: {newUnstructured("", "new-group", nil)},
+			nodeResource:         {newUnstructured("", "new-control-plane", nil)},
+			configMapResource: {
+				newUnstructured("kube-system", "owner-public-key", map[string]interface{}{"key": "new"}),
+				newUnstructured("kube-system", "join-config", map[string]interface{}{"measurements": "new"}),
+				newUnstructured("kube-system", "workload-config", map[string]interface{}{"value": "new"}),
+			},
+		},
+		restoredObjects: map[schema.GroupVersionResource][]unstructured.Unstructured{
+			scalingGroupResource: {newUnstructured("", "old-group", nil), newUnstructured("", "new-group", map[string]interface{}{"group": "old"})},
+			nodeResource:         {newUnstructured("", "old-control-plane", nil), newUnstructured("", "old-worker", nil)},
+			pendingNodeResource:  {newUnstructured("", "old-pending", nil)},
+			configMapResource: {
+				newUnstructured("kube-system", "owner-public-key", map[string]interface{}{"key": "old"}),
+				newUnstructured("kube-system", "join-config", map[string]interface{}{"measurements": "old"}),
+				newUnstructured("kube-system", "workload-config", map[string]interface{}{"value": "old"}),
+			},
+		},
+	}
+	restorer := &Restorer{
+		kube:         kube,
+		writer:       &bytes.Buffer{},
+		pollInterval: time.Millisecond,
+		timeout:      time.Second,
+	}
+
+	require.NoError(restorer.Restore(context.Background(), []byte("snapshot")))
+
+	names := func(gvr schema.GroupVersionResource) []string {
+		var names []string
+		for _, obj := range kube.restoredObjects[gvr] {
+			names = append(names, obj.GetName())
+		}
+		return names
+	}
+	data := func(name string) interface{} {
+		for _, obj := range kube.restoredObjects[configMapResource] {
+			if obj.GetName() == name {
+				return obj.Object["data"]
+			}
+		}
+		return nil
+	}
+	assert.ElementsMatch([]string{"new-group"}, names(scalingGroupResource))
+	assert.Nil(kube.restoredObjects[scalingGroupResource][0].Object["data"])
+	assert.ElementsMatch([]string{"new-control-plane"}, names(nodeResource))
+	assert.Empty(names(pendingNodeResource))
+	assert.Equal(map[string]interface{}{"key": "new"}, data("owner-public-key"))
+	assert.Equal(map[string]interface{}{"measurements": "new"}, data("join-config"))
+	// workloads are restored from the snapshot
+	assert.Equal(map[string]interface{}{"value": "old"}, data("workload-config"))
+}
+
+func newUnstructured(namespace, name string, data map[string]interface{}) unstructured.Unstructured {
+	obj := unstructured.Unstructured{Object: map[string]interface{}{}}
+	obj.SetNamespace(namespace)
+	obj.SetName(name)
+	obj.SetUID(types.UID("uid-" + name))
+	obj.SetResourceVersion("1")
+	if data != nil {
+		obj.Object["data"] = data
+	}
+	return obj
+}
+
+func TestNewEtcdRestorePod(t *testing.T) {
+	assert := assert.New(t)
+
+	pod := newEtcdRestorePod("control-plane-0", "192.0.2.1", "registry.k8s.io/etcd:3.5.4-0")
+
+	assert.Equal(etcdRestorePodName, pod.Name)
+	assert.Equal("control-plane-0", pod.Spec.NodeName)
+	assert.Equal(corev1.RestartPolicyNever, pod.Spec.RestartPolicy)
+	assert.Len(pod.Spec.InitContainers, 2)
+	assert.Equal(etcdRestoreUploadContainer, pod.Spec.InitContainers[0].Name)
+	assert.Equal("registry.k8s.io/etcd:3.5.4-0", pod.Spec.InitContainers[1].Image)
+	assert.Contains(pod.Spec.InitContainers[1].Command, "--initial-cluster=control-plane-0=https://192.0.2.1:2380")
+	assert.Contains(pod.Spec.InitContainers[1].Command, "--initial-advertise-peer-urls=https://192.0.2.1:2380")
+}
+
+func TestDecryptEtcdSnapshot(t *testing.T) {
+	key := bytes.Repeat([]byte{0x01}, 32)
+	otherKey := bytes.Repeat([]byte{0x02}, 32)
+	name := "backups/etcd-snapshot-20220810T030000Z.enc"
+	largeSnapshot := bytes.Repeat([]byte{0x03}, 2*etcdSnapshotChunkSize+1)
+	headerSize := 1 + etcdSnapshotSaltSize + 2 + len(name)
+	sealedChunkSize := etcdSnapshotChunkSize + 16
+
+	testCases := map[string]struct {
+		key          []byte
+		ciphertext   []byte
+		wantSnapshot []byte
+		wantErr      bool
+	}{
+		"decrypt succeeds": {
+			key:          key,
+			ciphertext:   encryptEtcdSnapshotForTest(t, key, name, []byte("snapshot")),
+			wantSnapshot: []byte("snapshot"),
+		},
+		"empty snapshot": {
+			key:          key,
+			ciphertext:   encryptEtcdSnapshotForTest(t, key, name, []byte{}),
+			wantSnapshot: []byte{},
+		},
+		"multiple chunks": {
+			key:          key,
+			ciphertext:   encryptEtcdSnapshotForTest(t, key, name, largeSnapshot),
+			wantSnapshot: largeSnapshot,
+		},
+		"wrong key": {
+			key:        otherKey,
+			ciphertext: encryptEtcdSnapshotForTest(t, key, name, []byte("snapshot")),
+			wantErr:    true,
+		},
+		"renamed snapshot": {
+			key: key,
+			ciphertext: func() []byte {
+				ciphertext := encryptEtcdSnapshotForTest(t, key, name, []byte("snapshot"))
+				ciphertext[headerSize-len(".enc")-1] = '1'
+				return ciphertext
+			}(),
+			wantErr: true,
+		},
+		"truncated at chunk boundary": {
+			key:        key,
+			ciphertext: encryptEtcdSnapshotForTest(t, key, name, largeSnapshot)[:headerSize+2*sealedChunkSize],
+			wantErr:    true,
+		},
+		"unsupported version": {
+			key: key,
+			ciphertext: func() []byte {
+				ciphertext := encryptEtcdSnapshotForTest(t, key, name, []byte("snapshot"))
+				ciphertext[0] = 2
+				return ciphertext
+			}(),
+			wantErr: true,
+		},
+		"ciphertext too short": {
+			key:        key,
+			ciphertext: []byte("short"),
+			wantErr:    true,
+		},
+	}
+
+	for testName, tc := range testCases {
+		t.Run(testName, func(t *testing.T) {
+			assert := assert.New(t)
+
+			gotName, snapshot, err := DecryptEtcdSnapshot(tc.key, bytes.NewReader(tc.ciphertext))
+			if tc.wantErr {
+				assert.Error(err)
+				return
+			}
+			assert.NoError(err)
+			assert.Equal(name, gotName)
+			assert.Equal(tc.wantSnapshot, snapshot)
+		})
+	}
+}
+
+// encryptEtcdSnapshotForTest mirrors the encryption done by the node operator.
+func encryptEtcdSnapshotForTest(t *testing.T, dataKey []byte, name string, snapshot []byte) []byte {
+	salt := make([]byte, etcdSnapshotSaltSize)
+	_, err := rand.Read(salt)
+	require.NoError(t, err)
+	header := make([]byte, 1+etcdSnapshotSaltSize+2, 1+etcdSnapshotSaltSize+2+len(name))
+	header[0] = etcdSnapshotVersion
+	copy(header[1:], salt)
+	binary.BigEndian.PutUint16(header[1+etcdSnapshotSaltSize:], uint16(len(name)))
+	header = append(header, name...)
+
+	key, err := crypto.DeriveKey(dataKey, salt, []byte(etcdSnapshotHKDFInfo), 32)
+	require.NoError(t, err)
+	block, err := aes.NewCipher(key)
+	require.NoError(t, err)
+	aead, err := cipher.NewGCM(block)
+	require.NoError(t, err)
+
+	ciphertext := append([]byte{}, header...)
+	for counter := uint64(0); ; counter++ {
+		chunk := snapshot
+		last := len(snapshot) < etcdSnapshotChunkSize
+		if !last {
+			chunk = snapshot[:etcdSnapshotChunkSize]
+		}
+		snapshot = snapshot[len(chunk):]
+		nonce := make([]byte, aead.NonceSize())
+		binary.BigEndian.PutUint64(nonce[3:11], counter)
+		if last {
+			nonce[11] = 1
+		}
+		ciphertext = aead.Seal(ciphertext, nonce, chunk, header)
+		if last {
+			return ciphertext
+		}
+	}
+}
+
+type stubPodResponse struct {
+	pod *corev1.Pod
+	err error
+}
+
+type stubRestorerKubeClient struct {
+	nodes      []corev1.Node
+	listErr    error
+	etcdImgErr error
+	createErr  error
+	// pods are returned by getPod in order, the last response is repeated
+	pods     []stubPodResponse
+	execErr  error
+	uploaded []byte
+	deleted  bool
+	// objects are returned by listResources before the snapshot is uploaded, restoredObjects afterwards
+	objects          map[schema.GroupVersionResource][]unstructured.Unstructured
+	restoredObjects  map[schema.GroupVersionResource][]unstructured.Unstructured
+	listResourcesErr error
+	writeResourceErr error
+}
+
+func (s *stubRestorerKubeClient) listControlPlaneNodes(context.Context) ([]corev1.Node, error) {
+	return s.nodes, s.listErr
+}
+
+func (s *stubRestorerKubeClient) getEtcdImage(context.Context, string) (string, error) {
+	return "registry.k8s.io/etcd:3.5.4-0", s.etcdImgErr
+}
+
+func (s *stubRestorerKubeClient) createPod(context.Context, *corev1.Pod) error {
+	return s.createErr
+}
+
+func (s *stubRestorerKubeClient) getPod(context.Context, string) (*corev1.Pod, error) {
+	if len(s.pods) == 0 {
+		return nil, errors.New("no pod")
+	}
+	resp := s.pods[0]
+	if len(s.pods) > 1 {
+		s.pods = s.pods[1:]
+	}
+	return resp.pod, resp.err
+}
+
+func (s *stubRestorerKubeClient) deletePod(context.Context, string) error {
+	s.deleted = true
+	return nil
+}
+
+func (s *stubRestorerKubeClient) exec(_ context.Context, _, _ string, _ []string, stdin io.Reader) error {
+	uploaded, err := io.ReadAll(stdin)
+	if err != nil {
+		return err
+	}
+	s.uploaded = uploaded
+	return s.execErr
+}
+
+func (s *stubRestorerKubeClient) listResources(_ context.Context, gvr schema.GroupVersionResource, namespace string) ([]unstructured.Unstructured, error) {
+	objects := s.objects
+	if s.uploaded != nil {
+		objects = s.restoredObjects
+	}
+	var objs []unstructured.Unstructured
+	for _, obj := range objects[gvr] {
+		if obj.GetNamespace() == namespace {
+			objs = append(objs, obj)
+		}
+	}
+	return objs, s.listResourcesErr
+}
+
+func (s *stubRestorerKubeClient) createResource(_ context.Context, gvr schema.GroupVersionResource, obj *unstructured.Unstructured) error {
+	if s.writeResourceErr != nil {
+		return s.writeResourceErr
+	}
+	if obj.GetResourceVersion() != "" {
+		return errors.New("resource version set on create")
+	}
+	if s.restoredObjects == nil {
+		s.restoredObjects = make(map[schema.GroupVersionResource][]unstructured.Unstructured)
+	}
+	s.restoredObjects[gvr] = append(s.restoredObjects[gvr], *obj)
+	return nil
+}
+
+func (s *stubRestorerKubeClient) updateResource(_ context.Context, gvr schema.GroupVersionResource, obj *unstructured.Unstructured) error {
+	if s.writeResourceErr != nil {
+		return s.writeResourceErr
+	}
+	for i, existing := range s.restoredObjects[gvr] {
+		if existing.GetNamespace() == obj.GetNamespace() && existing.GetName() == obj.GetName() {
+			if existing.GetResourceVersion() != obj.GetResourceVersion() {
+				return errors.New("resource version mismatch")
+			}
+			s.restoredObjects[gvr][i] = *obj
+			return nil
+		}
+	}
+	return k8serrors.NewNotFound(gvr.GroupResource(), obj.GetName())
+}
+
+func (s *stubRestorerKubeClient) deleteResource(_ context.Context, gvr schema.GroupVersionResource, namespace, name string) error {
+	if s.writeResourceErr != nil {
+		return s.writeResourceErr
+	}
+	for i, existing := range s.restoredObjects[gvr] {
+		if existing.GetNamespace() == namespace && existing.GetName() == name {
+			s.restoredObjects[gvr] = append(s.restoredObjects[gvr][:i], s.restoredObjects[gvr][i+1:]...)
+			return nil
+		}
+	}
+	return k8serrors.NewNotFound(gvr.GroupResource(), name)
+}
@@ -0,0 +1,349 @@
+/*
+Copyright (c) Edgeless Systems GmbH
+
+SPDX-License-Identifier: AGPL-3.0-only
+*/
+
+package cloudcmd
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"net/url"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/edgelesssys/constellation/v2/internal/constants"
+	"github.com/edgelesssys/constellation/v2/internal/gcpshared"
+	"github.com/edgelesssys/constellation/v2/internal/revokednode"
+	corev1 "k8s.io/api/core/v1"
+	k8serrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/client-go/dynamic"
+	"k8s.io/client-go/kubernetes"
+	"k8s.io/client-go/kubernetes/scheme"
+	"k8s.io/client-go/rest"
+	"k8s.io/client-go/tools/clientcmd"
+	"k8s.io/client-go/tools/remotecommand"
+)
+
+// controlPlaneRoleLabel is the label marking control-plane nodes.
+const controlPlaneRoleLabel = "node-role.kubernetes.io/control-plane"
+
+// Revoker revokes nodes, denying them to join or rejoin the cluster.
+type Revoker struct {
+	kube revokerKubeClient
+	etcd etcdMemberRemover
+
+	writer io.Writer
+}
+
+// NewRevoker returns a new Revoker.
+// Requests to the Kubernetes API server use the proxy selected by proxy.
+func NewRevoker(writer io.Writer, proxy func(*http.Request) (*url.URL, error)) (*Revoker, error) {
+	kubeConfig, err := clientcmd.BuildConfigFromFlags("", constants.AdminConfFilename)
+	if err != nil {
+		return nil, fmt.Errorf("building kubernetes config: %w", err)
+	}
+	kubeConfig.Proxy = proxy
+
+	kubeClient, err := kubernetes.NewForConfig(kubeConfig)
+	if err != nil {
+		return nil, fmt.Errorf("setting up kubernetes client: %w", err)
+	}
+	unstructuredClient, err := dynamic.NewForConfig(kubeConfig)
+	if err != nil {
+		return nil, fmt.Errorf("setting up custom resource client: %w", err)
+	}
+
+	return &Revoker{
+		kube:   &kubeRevoker{client: kubeClient, dynamic: unstructuredClient},
+		etcd:   &kubeEtcdMemberRemover{client: kubeClient, config: kubeConfig},
+		writer: writer,
+	}, nil
+}
+
+// Revoke revokes the node with the given name.
+// The node's name, provider ID, attested identities and state disks are added to the revocation list of the join service.
+// Afterwards, control-plane nodes are removed from the etcd cluster, and the node is deleted from Kubernetes.
+// Nodes already deleted from Kubernetes are only added to the revocation list.
+// The instance of the node isn't terminated, so a warning asks the user to terminate it.
+func (r *Revoker) Revoke(ctx context.Context, nodeName, reason string) error {
+	node, err := r.kube.getNode(ctx, nodeName)
+	if k8serrors.IsNotFound(err) {
+		node = nil
+	} else if err != nil {
+		return fmt.Errorf("retrieving node: %w", err)
+	}
+
+	diskOwners, err := r.kube.getDiskOwners(ctx)
+	if k8serrors.IsNotFound(err) {
+		diskOwners = &corev1.ConfigMap{}
+	} else if err != nil {
+		return fmt.Errorf("retrieving disk owners: %w", err)
+	}
+
+	spec := newRevokedNodeSpec(nodeName, node, diskOwners.Data, reason)
+	if node == nil && len(spec.DiskUUIDs) == 0 {
+		return fmt.Errorf("node %q not found", nodeName)
+	}
+	// the name alone doesn't identify a node, since joining nodes choose it themselves
+	if spec.ProviderID == "" && len(spec.AttestationIdentities) == 0 && len(spec.DiskUUIDs) == 0 {
+		return fmt.Errorf("no instance, attested identity or disk of node %q found, revoking it wouldn't deny the node to rejoin", nodeName)
+	}
+	revokedNode, err := newRevokedNode(nodeName, spec)
+	if err != nil {
+		return fmt.Errorf("creating revoked node: %w", err)
+	}
+	if err := r.kube.applyRevokedNode(ctx, revokedNode); err != nil {
+		return fmt.Errorf("adding node to revocation list: %w", err)
+	}
+	fmt.Fprintf(r.writer, "Node %s was added to the revocation list (%d attestation identities, %d disks)\n",
+		nodeName, len(spec.AttestationIdentities), len(spec.DiskUUIDs))
+
+	if node == nil {
+		fmt.Fprintf(r.writer, "Node %s does not exist in Kubernetes, skipping node deletion\n", nodeName)
+		r.warnInstanceRunning(nodeName, spec.ProviderID)
+		return nil
+	}
+
+	if _, ok := node.Labels[controlPlaneRoleLabel]; ok {
+		nodeIP := internalIP(node)
+		if nodeIP == "" {
+			return fmt.Errorf("control-plane node %q has no internal IP, can't remove it from etcd", nodeName)
+		}
+		if err := r.etcd.removeMember(ctx, nodeIP, nodeName); err != nil {
+			return fmt.Errorf("removing node from etcd: %w", err)
+		}
+		fmt.Fprintf(r.writer, "Node %s was removed from etcd\n", nodeName)
+	}
+
+	if err := r.kube.deleteNode(ctx, nodeName); err != nil && !k8serrors.IsNotFound(err) {
+		return fmt.Errorf("deleting node: %w", err)
+	}
+	fmt.Fprintf(r.writer, "Node %s was deleted\n", nodeName)
+	r.warnInstanceRunning(nodeName, spec.ProviderID)
+	return nil
+}
+
+// warnInstanceRunning warns that revoking doesn't terminate the instance of a node.
+// The revoked node can't rejoin, but it keeps its kubelet credentials, and control-plane nodes keep the cluster's certificates and keys.
+// The kubelet can use them to register the node again until the instance is terminated.
+func (r *Revoker) warnInstanceRunning(nodeName, providerID string) {
+	instance := providerID
+	if instance == "" {
+		instance = "unknown provider ID"
+	}
+	fmt.Fprintf(r.writer, "Warning: the instance of node %s (%s) is still running.\n", nodeName, instance)
+	fmt.Fprintln(r.writer, "It keeps its Kubernetes credentials and can register the node again until it's terminated.")
+	fmt.Fprintln(r.writer, "Terminate the instance with your cloud provider now.")
+}
+
+// newRevokedNodeSpec collects the identifiers of a node.
+// The disks used by the node, and the attested identity owning them, are looked up in the join service's disk owner records.
+// node is nil if the node was already deleted from Kubernetes.
+func newRevokedNodeSpec(nodeName string, node *corev1.Node, diskOwners map[string]string, reason string) revokednode.Spec {
+	spec := revokednode.Spec{NodeName: nodeName, Reason: reason}
+
+	identities := make(map[string]struct{})
+	if node != nil {
+		spec.ProviderID = node.Spec.ProviderID
+		// the attested identity of GCP nodes is their instance ID
+		if project, zone, instance, err := gcpshared.SplitProviderID(node.Spec.ProviderID); err == nil {
+			identities[fmt.Sprintf("projects/%s/zones/%s/instances/%s", project, zone, instance)] = struct{}{}
+		}
+	}
+	for key, value := range diskOwners {
+		diskUUID := strings.TrimSuffix(key, constants.DiskOwnersNodeKeySuffix)
+		if diskUUID == key || value != nodeName {
+			continue
+		}
+		spec.DiskUUIDs = append(spec.DiskUUIDs, diskUUID)
+		if owner := diskOwners[diskUUID]; owner != "" {
+			identities[owner] = struct{}{}
+		}
+	}
+	for identity := range identities {
+		spec.AttestationIdentities = append(spec.AttestationIdentities, identity)
+	}
+	sort.Strings(spec.DiskUUIDs)
+	sort.Strings(spec.AttestationIdentities)
+	return spec
+}
+
+func newRevokedNode(name string, spec revokednode.Spec) (*unstructured.Unstructured, error) {
+	specMap, err := runtime.DefaultUnstructuredConverter.ToUnstructured(&spec)
+	if err != nil {
+		return nil, err
+	}
+	return &unstructured.Unstructured{Object: map[string]any{
+		"apiVersion": constants.NodeAttestationGroup + "/" + constants.NodeAttestationVersion,
+		"kind":       revokednode.Kind,
+		"metadata":   map[string]any{"name": name},
+		"spec":       specMap,
+	}}, nil
+}
+
+func internalIP(node *corev1.Node) string {
+	for _, addr := range node.Status.Addresses {
+		if addr.Type == corev1.NodeInternalIP {
+			return addr.Address
+		}
+	}
+	return ""
+}
+
+type revokerKubeClient interface {
+	getNode(ctx context.Context, name string) (*corev1.Node, error)
+	deleteNode(ctx context.Context, name string) error
+	getDiskOwners(ctx context.Context) (*corev1.ConfigMap, error)
+	applyRevokedNode(ctx context.Context, revokedNode *unstructured.Unstructured) error
+}
+
+type etcdMemberRemover interface {
+	// removeMember removes the etcd member with the given peer IP, using an etcd instance running on a different node.
+	removeMember(ctx context.Context, peerIP, excludeNode string) error
+}
+
+type kubeRevoker struct {
+	client  kubernetes.Interface
+	dynamic dynamic.Interface
+}
+
+func (k *kubeRevoker) getNode(ctx context.Context, name string) (*corev1.Node, error) {
+	return k.client.CoreV1().Nodes().Get(ctx, name, metav1.GetOptions{})
+}
+
+func (k *kubeRevoker) deleteNode(ctx context.Context, name string) error {
+	return k.client.CoreV1().Nodes().Delete(ctx, name, metav1.DeleteOptions{})
+}
+
+func (k *kubeRevoker) getDiskOwners(ctx context.Context) (*corev1.ConfigMap, error) {
+	return k.client.CoreV1().ConfigMaps(constants.ConstellationNamespace).Get(ctx, constants.DiskOwnersConfigMap, metav1.GetOptions{})
+}
+
+// applyRevokedNode creates the RevokedNode, or replaces the spec of an existing one.
+func (k *kubeRevoker) applyRevokedNode(ctx context.Context, revokedNode *unstructured.Unstructured) error {
+	client := k.dynamic.Resource(revokednode.GroupVersionResource)
+	_, err := client.Create(ctx, revokedNode, metav1.CreateOptions{})
+	if !k8serrors.IsAlreadyExists(err) {
+		return err
+	}
+	existing, err := client.Get(ctx, revokedNode.GetName(), metav1.GetOptions{})
+	if err != nil {
+		return err
+	}
+	existing.Object["spec"] = revokedNode.Object["spec"]
+	_, err = client.Update(ctx, existing, metav1.UpdateOptions{})
+	return err
+}
+
+// kubeEtcdMemberRemover removes etcd members using etcdctl in the etcd pods of the cluster.
+// The CLI can't access etcd directly, since etcd only accepts clients using certificates issued by its CA.
+type kubeEtcdMemberRemover struct {
+	client kubernetes.Interface
+	config *rest.Config
+}
+
+func (k *kubeEtcdMemberRemover) removeMember(ctx context.Context, peerIP, excludeNode string) error {
+	pods, err := k.client.CoreV1().Pods("kube-system").List(ctx, metav1.ListOptions{LabelSelector: "component=etcd"})
+	if err != nil {
+		return fmt.Errorf("listing etcd pods: %w", err)
+	}
+	var pod string
+	for _, p := range pods.Items {
+		if p.Spec.NodeName != excludeNode && p.Status.Phase == corev1.PodRunning {
+			pod = p.Name
+			break
+		}
+	}
+	if pod == "" {
+		return errors.New("no running etcd pod found on another control-plane node")
+	}
+
+	memberList, err := k.etcdctl(ctx, pod, "member", "list", "--write-out=json")
+	if err != nil {
+		return fmt.Errorf("listing etcd members: %w", err)
+	}
+	memberID, found, err := findEtcdMember(memberList, peerIP)
+	if err != nil {
+		return err
+	}
+	if !found {
+		return nil
+	}
+	if _, err := k.etcdctl(ctx, pod, "member", "remove", strconv.FormatUint(memberID, 16)); err != nil {
+		return fmt.Errorf("removing etcd member: %w", err)
+	}
+	return nil
+}
+
+// etcdctl runs etcdctl with the given arguments in the etcd pod, and returns its output.
+func (k *kubeEtcdMemberRemover) etcdctl(ctx context.Context, pod string, args ...string) ([]byte, error) {
+	command := append([]string{
+		"etcdctl",
+		"--endpoints=https://127.0.0.1:2379",
+		"--cacert=/etc/kubernetes/pki/etcd/ca.crt",
+		"--cert=/etc/kubernetes/pki/etcd/healthcheck-client.crt",
+		"--key=/etc/kubernetes/pki/etcd/healthcheck-client.key",
+	}, args...)
+
+	req := k.client.CoreV1().RESTClient().Post().
+		Resource("pods").Namespace("kube-system").Name(pod).SubResource("exec").
+		VersionedParams(&corev1.PodExecOptions{
+			Container: "etcd",
+			Command:   command,
+			Stdout:    true,
+			Stderr:    true,
+		}, scheme.ParameterCodec)
+	executor, err := remotecommand.NewSPDYExecutor(k.config, http.MethodPost, req.URL())
+	if err != nil {
+		return nil, err
+	}
+
+	var stdout, stderr bytes.Buffer
+	errC := make(chan error, 1)
+	go func() {
+		errC <- executor.Stream(remotecommand.StreamOptions{Stdout: &stdout, Stderr: &stderr})
+	}()
+	select {
+	case err := <-errC:
+		if err != nil {
+			return nil, fmt.Errorf("%w: %s", err, strings.TrimSpace(stderr.String()))
+		}
+		return stdout.Bytes(), nil
+	case <-ctx.Done():
+		return nil, ctx.Err()
+	}
+}
+
+// findEtcdMember returns the ID of the etcd member with the given peer IP from the JSON output of "etcdctl member list".
+func findEtcdMember(memberList []byte, peerIP string) (uint64, bool, error) {
+	var list struct {
+		Members []struct {
+			ID       uint64   `json:"ID"`
+			PeerURLs []string `json:"peerURLs"`
+		} `json:"members"`
+	}
+	if err := json.Unmarshal(memberList, &list); err != nil {
+		return 0, false, fmt.Errorf("parsing etcd member list: %w", err)
+	}
+	wantPeerURL := (&url.URL{Scheme: "https", Host: net.JoinHostPort(peerIP, "2380")}).String()
+	for _, member := range list.Members {
+		for _, peerURL := range member.PeerURLs {
+			if peerURL == wantPeerURL {
+				return member.ID, true, nil
+			}
+		}
+	}
+	return 0, false, nil
+}
@@ -0,0 +1,295 @@
+/*
+Copyright (c) Edgeless Systems GmbH
+
+SPDX-License-Identifier: AGPL-3.0-only
+*/
+
+package cloudcmd
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	corev1 "k8s.io/api/core/v1"
+	k8serrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+)
+
+func TestRevoke(t *testing.T) {
+	someErr := errors.New("failed")
+	notFoundErr := k8serrors.NewNotFound(schema.GroupResource{}, "")
+
+	workerNode := &corev1.Node{
+		ObjectMeta: metav1.ObjectMeta{Name: "worker-0"},
+		Spec:       corev1.NodeSpec{ProviderID: "gce://project/zone/worker-0"},
+	}
+	controlPlaneNode := &corev1.Node{
+		ObjectMeta: metav1.ObjectMeta{Name: "control-plane-0", Labels: map[string]string{controlPlaneRoleLabel: ""}},
+		Spec:       corev1.NodeSpec{ProviderID: "azure:///subscriptions/sub/resourceGroups/rg/providers/Microsoft.Compute/virtualMachineScaleSets/cp/virtualMachines/0"},
+		Status: corev1.NodeStatus{Addresses: []corev1.NodeAddress{
+			{Type: corev1.NodeHostName, Address: "control-plane-0"},
+			{Type: corev1.NodeInternalIP, Address: "192.0.2.1"},
+		}},
+	}
+	diskOwners := &corev1.ConfigMap{Data: map[string]string{
+		"disk-0":                 "projects/project/zones/zone/instances/worker-0",
+		"disk-0.node":            "worker-0",
+		"disk-1":                 "ak:abcd",
+		"disk-1.node":            "control-plane-0",
+		"disk-2":                 "ak:abcd",
+		"disk-2.node":            "control-plane-0",
+		"disk-3":                 "ak:1234",
+		"disk-3.node":            "worker-1",
+		"disk-without-node-name": "ak:5678",
+	}}
+
+	testCases := map[string]struct {
+		kube           *stubRevokerKubeClient
+		etcd           *stubEtcdMemberRemover
+		nodeName       string
+		wantSpec       map[string]any
+		wantEtcdRemove string
+		wantDelete     bool
+		wantErr        bool
+	}{
+		"worker": {
+			kube:     &stubRevokerKubeClient{node: workerNode, diskOwners: diskOwners},
+			etcd:     &stubEtcdMemberRemover{},
+			nodeName: "worker-0",
+			wantSpec: map[string]any{
+				"nodeName":              "worker-0",
+				"providerID":            "gce://project/zone/worker-0",
+				"attestationIdentities": []any{"projects/project/zones/zone/instances/worker-0"},
+				"diskUUIDs":             []any{"disk-0"},
+				"reason":                "compromised",
+			},
+			wantDelete: true,
+		},
+		"control plane": {
+			kube:     &stubRevokerKubeClient{node: controlPlaneNode, diskOwners: diskOwners},
+			etcd:     &stubEtcdMemberRemover{},
+			nodeName: "control-plane-0",
+			wantSpec: map[string]any{
+				"nodeName":              "control-plane-0",
+				"providerID":            controlPlaneNode.Spec.ProviderID,
+				"attestationIdentities": []any{"ak:abcd"},
+				"diskUUIDs":             []any{"disk-1", "disk-2"},
+				"reason":                "compromised",
+			},
+			wantEtcdRemove: "192.0.2.1",
+			wantDelete:     true,
+		},
+		"node already deleted": {
+			kube:     &stubRevokerKubeClient{getNodeErr: notFoundErr, diskOwners: diskOwners},
+			etcd:     &stubEtcdMemberRemover{},
+			nodeName: "worker-1",
+			wantSpec: map[string]any{
+				"nodeName":              "worker-1",
+				"attestationIdentities": []any{"ak:1234"},
+				"diskUUIDs":             []any{"disk-3"},
+				"reason":                "compromised",
+			},
+		},
+		"no disk owner records": {
+			kube:     &stubRevokerKubeClient{node: workerNode, getDiskOwnersErr: notFoundErr},
+			etcd:     &stubEtcdMemberRemover{},
+			nodeName: "worker-0",
+			wantSpec: map[string]any{
+				"nodeName":              "worker-0",
+				"providerID":            "gce://project/zone/worker-0",
+				"attestationIdentities": []any{"projects/project/zones/zone/instances/worker-0"},
+				"reason":                "compromised",
+			},
+			wantDelete: true,
+		},
+		"unknown node": {
+			kube:     &stubRevokerKubeClient{getNodeErr: notFoundErr, diskOwners: diskOwners},
+			etcd:     &stubEtcdMemberRemover{},
+			nodeName: "worker-2",
+			wantErr:  true,
+		},
+		"nothing identifies node": {
+			kube:     &stubRevokerKubeClient{node: &corev1.Node{ObjectMeta: metav1.ObjectMeta{Name: "worker-2"}}, diskOwners: diskOwners},
+			etcd:     &stubEtcdMemberRemover{},
+			nodeName: "worker-2",
+			wantErr:  true,
+		},
+		"get node fails": {
+			kube:     &stubRevokerKubeClient{getNodeErr: someErr, diskOwners: diskOwners},
+			etcd:     &stubEtcdMemberRemover{},
+			nodeName: "worker-0",
+			wantErr:  true,
+		},
+		"apply revoked node fails": {
+			kube:     &stubRevokerKubeClient{node: workerNode, diskOwners: diskOwners, applyErr: someErr},
+			etcd:     &stubEtcdMemberRemover{},
+			nodeName: "worker-0",
+			wantErr:  true,
+		},
+		"removing etcd member fails": {
+			kube:     &stubRevokerKubeClient{node: controlPlaneNode, diskOwners: diskOwners},
+			etcd:     &stubEtcdMemberRemover{removeErr: someErr},
+			nodeName: "control-plane-0",
+			wantSpec: map[string]any{
+				"nodeName":              "control-plane-0",
+				"providerID":            controlPlaneNode.Spec.ProviderID,
+				"attestationIdentities": []any{"ak:abcd"},
+				"diskUUIDs":             []any{"disk-1", "disk-2"},
+				"reason":                "compromised",
+			},
+			wantEtcdRemove: "192.0.2.1",
+			wantErr:        true,
+		},
+		"deleting node fails": {
+			kube:     &stubRevokerKubeClient{node: workerNode, diskOwners: diskOwners, deleteErr: someErr},
+			etcd:     &stubEtcdMemberRemover{},
+			nodeName: "worker-0",
+			wantSpec: map[string]any{
+				"nodeName":              "worker-0",
+				"providerID":            "gce://project/zone/worker-0",
+				"attestationIdentities": []any{"projects/project/zones/zone/instances/worker-0"},
+				"diskUUIDs":             []any{"disk-0"},
+				"reason":                "compromised",
+			},
+			wantErr: true,
+		},
+	}
+
+	for name, tc := range testCases {
+		t.Run(name, func(t *testing.T) {
+			assert := assert.New(t)
+			require := require.New(t)
+
+			out := &bytes.Buffer{}
+			revoker := &Revoker{kube: tc.kube, etcd: tc.etcd, writer: out}
+			err := revoker.Revoke(context.Background(), tc.nodeName, "compromised")
+			if tc.wantErr {
+				assert.Error(err)
+			} else {
+				assert.NoError(err)
+				// the instance isn't terminated, so the user must be warned
+				assert.Contains(out.String(), "Warning: the instance of node "+tc.nodeName)
+			}
+
+			if tc.wantSpec != nil {
+				require.NotNil(tc.kube.applied)
+				assert.Equal(tc.nodeName, tc.kube.applied.GetName())
+				assert.Equal("RevokedNode", tc.kube.applied.GetKind())
+				assert.Equal(tc.wantSpec, tc.kube.applied.Object["spec"])
+			}
+			assert.Equal(tc.wantEtcdRemove, tc.etcd.removedIP)
+			if tc.wantEtcdRemove != "" {
+				assert.Equal(tc.nodeName, tc.etcd.excludedNode)
+			}
+			assert.Equal(tc.wantDelete, tc.kube.deleted)
+		})
+	}
+}
+
+func TestFindEtcdMember(t *testing.T) {
+	memberList := []byte(`{
+		"header": {"cluster_id": 1, "member_id": 2},
+		"members": [
+			{"ID": 17216541287009566719, "name": "control-plane-0", "peerURLs": ["https://192.0.2.1:2380"]},
+			{"ID": 42, "name": "control-plane-1", "peerURLs": ["https://192.0.2.2:2380"]}
+		]
+	}`)
+
+	testCases := map[string]struct {
+		memberList []byte
+		peerIP     string
+		wantID     uint64
+		wantFound  bool
+		wantErr    bool
+	}{
+		"large member ID": {
+			memberList: memberList,
+			peerIP:     "192.0.2.1",
+			wantID:     17216541287009566719,
+			wantFound:  true,
+		},
+		"member found": {
+			memberList: memberList,
+			peerIP:     "192.0.2.2",
+			wantID:     42,
+			wantFound:  true,
+		},
+		"member not found": {
+			memberList: memberList,
+			peerIP:     "192.0.2.3",
+		},
+		"invalid member list": {
+			memberList: []byte("Error: context deadline exceeded"),
+			peerIP:     "192.0.2.1",
+			wantErr:    true,
+		},
+	}
+
+	for name, tc := range testCases {
+		t.Run(name, func(t *testing.T) {
+			assert := assert.New(t)
+
+			id, found, err := findEtcdMember(tc.memberList, tc.peerIP)
+			if tc.wantErr {
+				assert.Error(err)
+				return
+			}
+			assert.NoError(err)
+			assert.Equal(tc.wantFound, found)
+			assert.Equal(tc.wantID, id)
+		})
+	}
+}
+
+type stubRevokerKubeClient struct {
+	node             *corev1.Node
+	getNodeErr       error
+	diskOwners       *corev1.ConfigMap
+	getDiskOwnersErr error
+	applied          *unstructured.Unstructured
+	applyErr         error
+	deleted          bool
+	deleteErr        error
+}
+
+func (s *stubRevokerKubeClient) getNode(context.Context, string) (*corev1.Node, error) {
+	return s.node, s.getNodeErr
+}
+
+func (s *stubRevokerKubeClient) deleteNode(context.Context, string) error {
+	if s.deleteErr != nil {
+		return s.deleteErr
+	}
+	s.deleted = true
+	return nil
+}
+
+func (s *stubRevokerKubeClient) getDiskOwners(context.Context) (*corev1.ConfigMap, error) {
+	return s.diskOwners, s.getDiskOwnersErr
+}
+
+func (s *stubRevokerKubeClient) applyRevokedNode(_ context.Context, revokedNode *unstructured.Unstructured) error {
+	if s.applyErr != nil {
+		return s.applyErr
+	}
+	s.applied = revokedNode
+	return nil
+}
+
+type stubEtcdMemberRemover struct {
+	removedIP    string
+	excludedNode string
+	removeErr    error
+}
+
+func (s *stubEtcdMemberRemover) removeMember(_ context.Context, peerIP, excludeNode string) error {
+	s.removedIP = peerIP
+	s.excludedNode = excludeNode
+	return s.removeErr
+}
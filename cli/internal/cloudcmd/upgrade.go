@@ -9,16 +9,27 @@ package cloudcmd
 import (
 	"bytes"
 	"context"
+	"crypto"
+	"crypto/sha256"
+	"encoding/hex"
 	"encoding/json"
 	"errors"
 	"fmt"
 	"io"
+	"net/http"
+	"net/url"
 
 	"github.com/edgelesssys/constellation/v2/internal/constants"
+	"github.com/edgelesssys/constellation/v2/internal/joinconfig"
+	"github.com/edgelesssys/constellation/v2/internal/versions"
+	"github.com/sigstore/sigstore/pkg/cryptoutils"
 	corev1 "k8s.io/api/core/v1"
+	k8serrors "k8s.io/apimachinery/pkg/api/errors"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
 	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/apimachinery/pkg/util/version"
+	k8sversion "k8s.io/apimachinery/pkg/version"
 	"k8s.io/client-go/dynamic"
 	"k8s.io/client-go/kubernetes"
 	"k8s.io/client-go/tools/clientcmd"
@@ -26,18 +37,23 @@ import (
 
 // Upgrader handles upgrading the cluster's components using the CLI.
 type Upgrader struct {
-	measurementsUpdater measurementsUpdater
-	imageUpdater        imageUpdater
+	measurementsUpdater      measurementsUpdater
+	imageUpdater             imageUpdater
+	kubernetesVersionUpdater kubernetesVersionUpdater
+	serverVersionGetter      serverVersionGetter
+	componentHasher          componentHasher
 
 	writer io.Writer
 }
 
 // NewUpgrader returns a new Upgrader.
-func NewUpgrader(writer io.Writer) (*Upgrader, error) {
+// Requests to the Kubernetes API server use the proxy selected by proxy.
+func NewUpgrader(writer io.Writer, proxy func(*http.Request) (*url.URL, error)) (*Upgrader, error) {
 	kubeConfig, err := clientcmd.BuildConfigFromFlags("", constants.AdminConfFilename)
 	if err != nil {
 		return nil, fmt.Errorf("building kubernetes config: %w", err)
 	}
+	kubeConfig.Proxy = proxy
 
 	kubeClient, err := kubernetes.NewForConfig(kubeConfig)
 	if err != nil {
@@ -51,15 +67,26 @@ func NewUpgrader(writer io.Writer) (*Upgrader, error) {
 	}
 
 	return &Upgrader{
-		measurementsUpdater: &kubeMeasurementsUpdater{client: kubeClient},
-		imageUpdater:        &kubeImageUpdater{client: unstructuredClient},
-		writer:              writer,
+		measurementsUpdater:      &kubeMeasurementsUpdater{client: kubeClient},
+		imageUpdater:             &kubeImageUpdater{client: unstructuredClient},
+		kubernetesVersionUpdater: &kubeKubernetesVersionUpdater{client: unstructuredClient},
+		serverVersionGetter:      kubeClient.Discovery(),
+		componentHasher:          &httpComponentHasher{client: http.DefaultClient},
+		writer:                   writer,
 	}, nil
 }
 
-// Upgrade upgrades the cluster to the given measurements and image.
-func (u *Upgrader) Upgrade(ctx context.Context, image string, measurements map[uint32][]byte) error {
-	if err := u.updateMeasurements(ctx, measurements); err != nil {
+// Upgrade upgrades the cluster to the given join-config values and image.
+// The join-config values, created by JoinConfigValues, are signed using signingKey,
+// the join service rejects unsigned measurements.
+// If image is empty, image and measurements are left unchanged.
+func (u *Upgrader) Upgrade(ctx context.Context, image string, joinConfig map[string]string, signingKey crypto.Signer) error {
+	if image == "" {
+		fmt.Fprintln(u.writer, "No image configured, skipping image upgrade")
+		return nil
+	}
+
+	if err := u.updateMeasurements(ctx, joinConfig, signingKey); err != nil {
 		return fmt.Errorf("updating measurements: %w", err)
 	}
 
@@ -97,7 +124,125 @@ func (u *Upgrader) GetCurrentImage(ctx context.Context) (*unstructured.Unstructu
 	return imageStruct, imageDefinition, nil
 }
 
-func (u *Upgrader) updateMeasurements(ctx context.Context, measurements map[uint32][]byte) error {
+// UpgradeKubernetes upgrades all nodes of the cluster to the latest patch version of the given Kubernetes version.
+// The upgrade is carried out by the node operator.
+func (u *Upgrader) UpgradeKubernetes(ctx context.Context, k8sVersion versions.ValidK8sVersion) error {
+	versionConfig, ok := versions.VersionConfigs[k8sVersion]
+	if !ok {
+		return fmt.Errorf("unsupported Kubernetes version: %s", k8sVersion)
+	}
+	target := "v" + versionConfig.PatchVersion
+
+	current, err := u.GetCurrentKubernetesVersion(ctx)
+	if err != nil {
+		return fmt.Errorf("retrieving current Kubernetes version: %w", err)
+	}
+	if current == target {
+		fmt.Fprintln(u.writer, "Cluster is already using the chosen Kubernetes version, skipping Kubernetes upgrade")
+		return nil
+	}
+	if err := CheckKubernetesUpgrade(current, target); err != nil {
+		return err
+	}
+
+	fmt.Fprintln(u.writer, "Downloading Kubernetes components to pin their checksums")
+	components, err := u.kubernetesComponents(ctx, versionConfig)
+	if err != nil {
+		return err
+	}
+	spec := map[string]any{
+		"version":    target,
+		"components": components,
+	}
+	kubernetesVersion, err := u.kubernetesVersionUpdater.getCurrent(ctx, constants.NodeKubernetesVersionName)
+	if k8serrors.IsNotFound(err) {
+		kubernetesVersion = &unstructured.Unstructured{Object: map[string]any{
+			"apiVersion": "update.edgeless.systems/v1alpha1",
+			"kind":       "NodeKubernetesVersion",
+			"metadata": map[string]any{
+				"name": constants.NodeKubernetesVersionName,
+			},
+			"spec": spec,
+		}}
+		if _, err := u.kubernetesVersionUpdater.create(ctx, kubernetesVersion); err != nil {
+			return fmt.Errorf("setting new Kubernetes version: %w", err)
+		}
+	} else if err != nil {
+		return fmt.Errorf("retrieving desired Kubernetes version: %w", err)
+	} else {
+		kubernetesVersion.Object["spec"] = spec
+		if _, err := u.kubernetesVersionUpdater.update(ctx, kubernetesVersion); err != nil {
+			return fmt.Errorf("setting new Kubernetes version: %w", err)
+		}
+	}
+
+	fmt.Fprintf(u.writer, "Successfully updated the cluster's Kubernetes version to %s, upgrades will be applied automatically\n", target)
+	return nil
+}
+
+// GetCurrentKubernetesVersion returns the Kubernetes version of the cluster's API server.
+func (u *Upgrader) GetCurrentKubernetesVersion(ctx context.Context) (string, error) {
+	serverVersion, err := u.serverVersionGetter.ServerVersion()
+	if err != nil {
+		return "", err
+	}
+	return serverVersion.GitVersion, nil
+}
+
+// CheckKubernetesUpgrade checks if a cluster running the current Kubernetes version can be upgraded to target.
+// Kubernetes only supports upgrading by one minor version at a time.
+func CheckKubernetesUpgrade(current, target string) error {
+	currentVersion, err := version.ParseSemantic(current)
+	if err != nil {
+		return fmt.Errorf("parsing current Kubernetes version: %w", err)
+	}
+	targetVersion, err := version.ParseSemantic(target)
+	if err != nil {
+		return fmt.Errorf("parsing target Kubernetes version: %w", err)
+	}
+	if !currentVersion.LessThan(targetVersion) {
+		return fmt.Errorf("cannot upgrade from Kubernetes %s to %s: downgrades are not supported", current, target)
+	}
+	if currentVersion.Major() != targetVersion.Major() || targetVersion.Minor() > currentVersion.Minor()+1 {
+		return fmt.Errorf("cannot upgrade from Kubernetes %s to %s: minor versions cannot be skipped", current, target)
+	}
+	return nil
+}
+
+// kubernetesComponents returns the components installed by the node operator when upgrading nodes.
+// The install paths match the ones used by the bootstrapper.
+// Every component is pinned to the SHA256 of its download, so all nodes install the exact files seen by the CLI.
+func (u *Upgrader) kubernetesComponents(ctx context.Context, versionConfig versions.KubernetesVersion) ([]any, error) {
+	componentSpecs := []struct {
+		url         string
+		installPath string
+		extract     bool
+	}{
+		{versionConfig.CNIPluginsURL, "/opt/cni/bin", true},
+		{versionConfig.CrictlURL, "/run/state/bin", true},
+		{versionConfig.KubeletURL, "/run/state/bin/kubelet", false},
+		{versionConfig.KubeadmURL, "/run/state/bin/kubeadm", false},
+		{versionConfig.KubectlURL, "/run/state/bin/kubectl", false},
+	}
+	var components []any
+	for _, spec := range componentSpecs {
+		checksum, err := u.componentHasher.hash(ctx, spec.url)
+		if err != nil {
+			return nil, fmt.Errorf("computing checksum of %s: %w", spec.url, err)
+		}
+		components = append(components, map[string]any{
+			"url":         spec.url,
+			"sha256":      checksum,
+			"installPath": spec.installPath,
+			"extract":     spec.extract,
+		})
+	}
+	return components, nil
+}
+
+// updateMeasurements writes the join-config values to the cluster and signs them with signingKey.
+// Only the measurements may differ from the join-config in the cluster, all other values are expected to be unchanged.
+func (u *Upgrader) updateMeasurements(ctx context.Context, values map[string]string, signingKey crypto.Signer) error {
 	existingConf, err := u.measurementsUpdater.getCurrent(ctx, constants.JoinConfigMap)
 	if err != nil {
 		return fmt.Errorf("retrieving current measurements: %w", err)
@@ -107,30 +252,39 @@ func (u *Upgrader) updateMeasurements(ctx context.Context, measurements map[uint
 	if err := json.Unmarshal([]byte(existingConf.Data[constants.MeasurementsFilename]), &currentMeasurements); err != nil {
 		return fmt.Errorf("retrieving current measurements: %w", err)
 	}
-	if len(currentMeasurements) == len(measurements) {
-		changed := false
-		for k, v := range currentMeasurements {
-			if !bytes.Equal(v, measurements[k]) {
-				// measurements have changed
-				changed = true
-				break
-			}
-		}
-		if !changed {
-			// measurements are the same, nothing to be done
-			fmt.Fprintln(u.writer, "Cluster is already using the chosen measurements, skipping measurements upgrade")
-			return nil
+	var measurements map[uint32][]byte
+	if err := json.Unmarshal([]byte(values[constants.MeasurementsFilename]), &measurements); err != nil {
+		return fmt.Errorf("parsing new measurements: %w", err)
+	}
+	changed := len(currentMeasurements) != len(measurements)
+	for k, v := range currentMeasurements {
+		if !bytes.Equal(v, measurements[k]) {
+			// measurements have changed
+			changed = true
+			break
 		}
 	}
 
-	// backup of previous measurements
-	existingConf.Data["oldMeasurements"] = existingConf.Data[constants.MeasurementsFilename]
-
-	measurementsJSON, err := json.Marshal(measurements)
+	publicKey, err := cryptoutils.MarshalPublicKeyToPEM(signingKey.Public())
 	if err != nil {
-		return fmt.Errorf("marshaling measurements: %w", err)
+		return fmt.Errorf("marshaling measurements signing key: %w", err)
+	}
+	signature := []byte(existingConf.Data[constants.MeasurementsSignatureFilename])
+	if !changed && joinconfig.Verify(existingConf.Data, signature, publicKey) == nil {
+		// measurements are the same and signed by us, nothing to be done
+		fmt.Fprintln(u.writer, "Cluster is already using the chosen measurements, skipping measurements upgrade")
+		return nil
 	}
-	existingConf.Data[constants.MeasurementsFilename] = string(measurementsJSON)
+
+	if changed {
+		// backup of previous measurements
+		existingConf.Data["oldMeasurements"] = existingConf.Data[constants.MeasurementsFilename]
+	}
+
+	if err := signJoinConfig(existingConf, values, signingKey, constants.MeasurementsFilename); err != nil {
+		return fmt.Errorf("signing measurements: %w", err)
+	}
+
 	_, err = u.measurementsUpdater.update(ctx, existingConf)
 	if err != nil {
 		return fmt.Errorf("setting new measurements: %w", err)
@@ -165,6 +319,20 @@ type imageUpdater interface {
 	update(ctx context.Context, obj *unstructured.Unstructured) (*unstructured.Unstructured, error)
 }
 
+type kubernetesVersionUpdater interface {
+	getCurrent(ctx context.Context, name string) (*unstructured.Unstructured, error)
+	create(ctx context.Context, obj *unstructured.Unstructured) (*unstructured.Unstructured, error)
+	update(ctx context.Context, obj *unstructured.Unstructured) (*unstructured.Unstructured, error)
+}
+
+type serverVersionGetter interface {
+	ServerVersion() (*k8sversion.Info, error)
+}
+
+type componentHasher interface {
+	hash(ctx context.Context, url string) (string, error)
+}
+
 type measurementsUpdater interface {
 	getCurrent(ctx context.Context, name string) (*corev1.ConfigMap, error)
 	update(ctx context.Context, configMap *corev1.ConfigMap) (*corev1.ConfigMap, error)
@@ -192,6 +360,56 @@ func (u *kubeImageUpdater) update(ctx context.Context, obj *unstructured.Unstruc
 	}).Update(ctx, obj, metav1.UpdateOptions{})
 }
 
+type kubeKubernetesVersionUpdater struct {
+	client dynamic.Interface
+}
+
+// getCurrent returns the desired Kubernetes version of the cluster.
+func (u *kubeKubernetesVersionUpdater) getCurrent(ctx context.Context, name string) (*unstructured.Unstructured, error) {
+	return u.client.Resource(nodeKubernetesVersionResource).Get(ctx, name, metav1.GetOptions{})
+}
+
+// create creates the desired Kubernetes version of the cluster.
+func (u *kubeKubernetesVersionUpdater) create(ctx context.Context, obj *unstructured.Unstructured) (*unstructured.Unstructured, error) {
+	return u.client.Resource(nodeKubernetesVersionResource).Create(ctx, obj, metav1.CreateOptions{})
+}
+
+// update updates the desired Kubernetes version of the cluster.
+func (u *kubeKubernetesVersionUpdater) update(ctx context.Context, obj *unstructured.Unstructured) (*unstructured.Unstructured, error) {
+	return u.client.Resource(nodeKubernetesVersionResource).Update(ctx, obj, metav1.UpdateOptions{})
+}
+
+type httpComponentHasher struct {
+	client *http.Client
+}
+
+// hash downloads a component and returns the hex encoded SHA256 of its content.
+func (h *httpComponentHasher) hash(ctx context.Context, url string) (string, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, url, http.NoBody)
+	if err != nil {
+		return "", err
+	}
+	resp, err := h.client.Do(req)
+	if err != nil {
+		return "", err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return "", fmt.Errorf("unexpected status code %d", resp.StatusCode)
+	}
+	hash := sha256.New()
+	if _, err := io.Copy(hash, resp.Body); err != nil {
+		return "", err
+	}
+	return hex.EncodeToString(hash.Sum(nil)), nil
+}
+
+var nodeKubernetesVersionResource = schema.GroupVersionResource{
+	Group:    "update.edgeless.systems",
+	Version:  "v1alpha1",
+	Resource: "nodekubernetesversions",
+}
+
 type kubeMeasurementsUpdater struct {
 	client kubernetes.Interface
 }
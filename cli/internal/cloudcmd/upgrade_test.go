@@ -9,19 +9,41 @@ package cloudcmd
 import (
 	"bytes"
 	"context"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
 	"encoding/json"
 	"errors"
+	"net/http"
+	"net/http/httptest"
 	"testing"
 
 	"github.com/edgelesssys/constellation/v2/internal/constants"
+	"github.com/edgelesssys/constellation/v2/internal/joinconfig"
+	"github.com/edgelesssys/constellation/v2/internal/versions"
+	"github.com/sigstore/sigstore/pkg/cryptoutils"
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/require"
 	corev1 "k8s.io/api/core/v1"
+	k8serrors "k8s.io/apimachinery/pkg/api/errors"
 	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	k8sversion "k8s.io/apimachinery/pkg/version"
 )
 
 func TestUpdateMeasurements(t *testing.T) {
 	someErr := errors.New("error")
+	signingKey, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	require.NoError(t, err)
+	publicKey, err := cryptoutils.MarshalPublicKeyToPEM(signingKey.Public())
+	require.NoError(t, err)
+	signedData := map[string]string{
+		constants.MeasurementsFilename: `{"0":"MQ=="}`,
+		constants.EnforcedPCRsFilename: "[0]",
+	}
+	signature, err := joinconfig.Sign(signedData, signingKey)
+	require.NoError(t, err)
+
 	testCases := map[string]struct {
 		updater         *stubMeasurementsUpdater
 		newMeasurements map[uint32][]byte
@@ -33,6 +55,7 @@ func TestUpdateMeasurements(t *testing.T) {
 				oldMeasurements: &corev1.ConfigMap{
 					Data: map[string]string{
 						constants.MeasurementsFilename: `{"0":"AAAAAA=="}`,
+						constants.EnforcedPCRsFilename: "[0]",
 					},
 				},
 			},
@@ -42,16 +65,46 @@ func TestUpdateMeasurements(t *testing.T) {
 			wantUpdate: true,
 		},
 		"measurements are the same": {
+			updater: &stubMeasurementsUpdater{
+				oldMeasurements: &corev1.ConfigMap{
+					Data: map[string]string{
+						constants.MeasurementsFilename:          signedData[constants.MeasurementsFilename],
+						constants.EnforcedPCRsFilename:          signedData[constants.EnforcedPCRsFilename],
+						constants.MeasurementsSignatureFilename: string(signature),
+					},
+				},
+			},
+			newMeasurements: map[uint32][]byte{
+				0: []byte("1"),
+			},
+		},
+		"measurements are the same but unsigned": {
 			updater: &stubMeasurementsUpdater{
 				oldMeasurements: &corev1.ConfigMap{
 					Data: map[string]string{
 						constants.MeasurementsFilename: `{"0":"MQ=="}`,
+						constants.EnforcedPCRsFilename: "[0]",
 					},
 				},
 			},
 			newMeasurements: map[uint32][]byte{
 				0: []byte("1"),
 			},
+			wantUpdate: true,
+		},
+		"enforced PCRs changed in the cluster": {
+			updater: &stubMeasurementsUpdater{
+				oldMeasurements: &corev1.ConfigMap{
+					Data: map[string]string{
+						constants.MeasurementsFilename: `{"0":"AAAAAA=="}`,
+						constants.EnforcedPCRsFilename: "[]",
+					},
+				},
+			},
+			newMeasurements: map[uint32][]byte{
+				0: []byte("1"),
+			},
+			wantErr: true,
 		},
 		"getCurrent error": {
 			updater: &stubMeasurementsUpdater{getErr: someErr},
@@ -62,6 +115,7 @@ func TestUpdateMeasurements(t *testing.T) {
 				oldMeasurements: &corev1.ConfigMap{
 					Data: map[string]string{
 						constants.MeasurementsFilename: `{"0":"AAAAAA=="}`,
+						constants.EnforcedPCRsFilename: "[0]",
 					},
 				},
 				updateErr: someErr,
@@ -79,17 +133,24 @@ func TestUpdateMeasurements(t *testing.T) {
 				writer:              &bytes.Buffer{},
 			}
 
-			err := upgrader.updateMeasurements(context.Background(), tc.newMeasurements)
+			newMeasurementsJSON, err := json.Marshal(tc.newMeasurements)
+			require.NoError(t, err)
+			values := map[string]string{
+				constants.MeasurementsFilename: string(newMeasurementsJSON),
+				constants.EnforcedPCRsFilename: "[0]",
+			}
+
+			err = upgrader.updateMeasurements(context.Background(), values, signingKey)
 			if tc.wantErr {
-				assert.ErrorIs(err, someErr)
+				assert.Error(err)
 				return
 			}
 
 			assert.NoError(err)
 			if tc.wantUpdate {
-				newMeasurementsJSON, err := json.Marshal(tc.newMeasurements)
-				require.NoError(t, err)
-				assert.JSONEq(string(newMeasurementsJSON), tc.updater.updatedMeasurements.Data[constants.MeasurementsFilename])
+				updated := tc.updater.updatedMeasurements.Data
+				assert.JSONEq(string(newMeasurementsJSON), updated[constants.MeasurementsFilename])
+				assert.NoError(joinconfig.Verify(updated, []byte(updated[constants.MeasurementsSignatureFilename]), publicKey))
 			} else {
 				assert.Nil(tc.updater.updatedMeasurements)
 			}
@@ -238,3 +299,242 @@ func (u *stubImageUpdater) update(_ context.Context, updatedImage *unstructured.
 	u.updatedImage = updatedImage
 	return nil, u.updateErr
 }
+
+func TestUpgradeKubernetes(t *testing.T) {
+	someErr := errors.New("error")
+	notFoundErr := k8serrors.NewNotFound(schema.GroupResource{}, constants.NodeKubernetesVersionName)
+	target := "v" + versions.VersionConfigs[versions.V1_24].PatchVersion
+
+	testCases := map[string]struct {
+		serverVersion string
+		versionErr    error
+		updater       *stubKubernetesVersionUpdater
+		hashErr       error
+		wantCreate    bool
+		wantUpdate    bool
+		wantErr       bool
+	}{
+		"resource is created": {
+			serverVersion: "v1.23.9",
+			updater:       &stubKubernetesVersionUpdater{getErr: notFoundErr},
+			wantCreate:    true,
+		},
+		"resource is updated": {
+			serverVersion: "v1.23.9",
+			updater: &stubKubernetesVersionUpdater{
+				current: &unstructured.Unstructured{Object: map[string]any{
+					"spec": map[string]any{"version": "v1.23.9"},
+				}},
+			},
+			wantUpdate: true,
+		},
+		"version is the same": {
+			serverVersion: target,
+			updater:       &stubKubernetesVersionUpdater{},
+		},
+		"downgrade": {
+			serverVersion: "v1.25.0",
+			updater:       &stubKubernetesVersionUpdater{},
+			wantErr:       true,
+		},
+		"minor version is skipped": {
+			serverVersion: "v1.22.12",
+			updater:       &stubKubernetesVersionUpdater{},
+			wantErr:       true,
+		},
+		"server version error": {
+			versionErr: someErr,
+			updater:    &stubKubernetesVersionUpdater{},
+			wantErr:    true,
+		},
+		"getCurrent error": {
+			serverVersion: "v1.23.9",
+			updater:       &stubKubernetesVersionUpdater{getErr: someErr},
+			wantErr:       true,
+		},
+		"create error": {
+			serverVersion: "v1.23.9",
+			updater:       &stubKubernetesVersionUpdater{getErr: notFoundErr, createErr: someErr},
+			wantCreate:    true,
+			wantErr:       true,
+		},
+		"hashing fails": {
+			serverVersion: "v1.23.9",
+			updater:       &stubKubernetesVersionUpdater{getErr: notFoundErr},
+			hashErr:       someErr,
+			wantErr:       true,
+		},
+		"update error": {
+			serverVersion: "v1.23.9",
+			updater: &stubKubernetesVersionUpdater{
+				current:   &unstructured.Unstructured{Object: map[string]any{}},
+				updateErr: someErr,
+			},
+			wantUpdate: true,
+			wantErr:    true,
+		},
+	}
+
+	for name, tc := range testCases {
+		t.Run(name, func(t *testing.T) {
+			assert := assert.New(t)
+			require := require.New(t)
+
+			upgrader := &Upgrader{
+				kubernetesVersionUpdater: tc.updater,
+				serverVersionGetter:      &stubServerVersionGetter{version: tc.serverVersion, err: tc.versionErr},
+				componentHasher:          &stubComponentHasher{err: tc.hashErr},
+				writer:                   &bytes.Buffer{},
+			}
+
+			err := upgrader.UpgradeKubernetes(context.Background(), versions.V1_24)
+			if tc.wantErr {
+				assert.Error(err)
+			} else {
+				assert.NoError(err)
+			}
+
+			var written *unstructured.Unstructured
+			switch {
+			case tc.wantCreate:
+				require.NotNil(tc.updater.created)
+				assert.Nil(tc.updater.updated)
+				assert.Equal(constants.NodeKubernetesVersionName, tc.updater.created.GetName())
+				written = tc.updater.created
+			case tc.wantUpdate:
+				require.NotNil(tc.updater.updated)
+				assert.Nil(tc.updater.created)
+				written = tc.updater.updated
+			default:
+				assert.Nil(tc.updater.created)
+				assert.Nil(tc.updater.updated)
+				return
+			}
+			version, _, err := unstructured.NestedString(written.Object, "spec", "version")
+			require.NoError(err)
+			assert.Equal(target, version)
+			components, _, err := unstructured.NestedSlice(written.Object, "spec", "components")
+			require.NoError(err)
+			assert.NotEmpty(components)
+			for _, component := range components {
+				url, _, _ := unstructured.NestedString(component.(map[string]any), "url")
+				checksum, _, _ := unstructured.NestedString(component.(map[string]any), "sha256")
+				assert.Equal("sha256-of-"+url, checksum)
+			}
+		})
+	}
+}
+
+func TestHTTPComponentHasher(t *testing.T) {
+	testCases := map[string]struct {
+		statusCode int
+		want       string
+		wantErr    bool
+	}{
+		"success": {
+			statusCode: http.StatusOK,
+			// sha256sum of "component"
+			want: "6985ca1f4daa5a584a28eae043a239cb96689af1337ea13afb63e00c2bf512fa",
+		},
+		"not found": {
+			statusCode: http.StatusNotFound,
+			wantErr:    true,
+		},
+	}
+
+	for name, tc := range testCases {
+		t.Run(name, func(t *testing.T) {
+			assert := assert.New(t)
+
+			server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				w.WriteHeader(tc.statusCode)
+				_, _ = w.Write([]byte("component"))
+			}))
+			defer server.Close()
+
+			hasher := &httpComponentHasher{client: server.Client()}
+			got, err := hasher.hash(context.Background(), server.URL)
+			if tc.wantErr {
+				assert.Error(err)
+				return
+			}
+			assert.NoError(err)
+			assert.Equal(tc.want, got)
+		})
+	}
+}
+
+func TestCheckKubernetesUpgrade(t *testing.T) {
+	testCases := map[string]struct {
+		current string
+		target  string
+		wantErr bool
+	}{
+		"patch upgrade":       {current: "v1.24.1", target: "v1.24.3"},
+		"minor upgrade":       {current: "v1.23.9", target: "v1.24.3"},
+		"minor version skip":  {current: "v1.22.12", target: "v1.24.3", wantErr: true},
+		"same version":        {current: "v1.24.3", target: "v1.24.3", wantErr: true},
+		"downgrade":           {current: "v1.25.0", target: "v1.24.3", wantErr: true},
+		"major upgrade":       {current: "v1.24.3", target: "v2.0.0", wantErr: true},
+		"invalid current":     {current: "invalid", target: "v1.24.3", wantErr: true},
+		"invalid target":      {current: "v1.24.3", target: "invalid", wantErr: true},
+		"provider git suffix": {current: "v1.23.9+k3s1", target: "v1.24.3"},
+	}
+
+	for name, tc := range testCases {
+		t.Run(name, func(t *testing.T) {
+			err := CheckKubernetesUpgrade(tc.current, tc.target)
+			if tc.wantErr {
+				assert.Error(t, err)
+				return
+			}
+			assert.NoError(t, err)
+		})
+	}
+}
+
+type stubKubernetesVersionUpdater struct {
+	current   *unstructured.Unstructured
+	created   *unstructured.Unstructured
+	updated   *unstructured.Unstructured
+	getErr    error
+	createErr error
+	updateErr error
+}
+
+func (u *stubKubernetesVersionUpdater) getCurrent(context.Context, string) (*unstructured.Unstructured, error) {
+	return u.current, u.getErr
+}
+
+func (u *stubKubernetesVersionUpdater) create(_ context.Context, obj *unstructured.Unstructured) (*unstructured.Unstructured, error) {
+	u.created = obj
+	return nil, u.createErr
+}
+
+func (u *stubKubernetesVersionUpdater) update(_ context.Context, obj *unstructured.Unstructured) (*unstructured.Unstructured, error) {
+	u.updated = obj
+	return nil, u.updateErr
+}
+
+type stubComponentHasher struct {
+	err error
+}
+
+func (s *stubComponentHasher) hash(_ context.Context, url string) (string, error) {
+	if s.err != nil {
+		return "", s.err
+	}
+	return "sha256-of-" + url, nil
+}
+
+type stubServerVersionGetter struct {
+	version string
+	err     error
+}
+
+func (s *stubServerVersionGetter) ServerVersion() (*k8sversion.Info, error) {
+	if s.err != nil {
+		return nil, s.err
+	}
+	return &k8sversion.Info{GitVersion: s.version}, nil
+}
@@ -8,6 +8,7 @@ package cloudcmd
 
 import (
 	"crypto/sha256"
+	"crypto/x509"
 	"encoding/base64"
 	"encoding/hex"
 	"errors"
@@ -17,7 +18,9 @@ import (
 	"github.com/edgelesssys/constellation/v2/internal/attestation/azure/snp"
 	"github.com/edgelesssys/constellation/v2/internal/attestation/azure/trustedlaunch"
 	"github.com/edgelesssys/constellation/v2/internal/attestation/gcp"
+	"github.com/edgelesssys/constellation/v2/internal/attestation/policy"
 	"github.com/edgelesssys/constellation/v2/internal/attestation/qemu"
+	"github.com/edgelesssys/constellation/v2/internal/attestation/tdx"
 	"github.com/edgelesssys/constellation/v2/internal/attestation/vtpm"
 	"github.com/edgelesssys/constellation/v2/internal/cloud/cloudprovider"
 	"github.com/edgelesssys/constellation/v2/internal/config"
@@ -31,15 +34,26 @@ type Validator struct {
 	idkeydigest        []byte
 	enforceIdKeyDigest bool
 	azureCVM           bool
+	qemuTDX            bool
+	ekRoots            *x509.CertPool
+	policy             *policy.Policy
 	validator          atls.Validator
 }
 
 func NewValidator(provider cloudprovider.Provider, config *config.Config) (*Validator, error) {
 	v := Validator{}
-	if provider == cloudprovider.Unknown {
+	switch provider {
+	case cloudprovider.Azure, cloudprovider.GCP, cloudprovider.QEMU:
+	case cloudprovider.Unknown:
 		return nil, errors.New("unknown cloud provider")
+	default:
+		// the attestation policy is evaluated by the validator, providers without one can't be verified
+		return nil, fmt.Errorf("attestation is not supported on %s", provider)
 	}
 	v.provider = provider
+	if v.provider == cloudprovider.QEMU && config.Provider.QEMU != nil && config.Provider.QEMU.TDX != nil {
+		v.qemuTDX = *config.Provider.QEMU.TDX
+	}
 	if err := v.setPCRs(config); err != nil {
 		return nil, err
 	}
@@ -56,10 +70,31 @@ func NewValidator(provider cloudprovider.Provider, config *config.Config) (*Vali
 		}
 	}
 
+	// attestation keys of QEMU VMs can't be trusted without the CA certifying them
+	if v.provider == cloudprovider.QEMU && !v.qemuTDX {
+		ekRoots, err := qemu.ParseEKRoots(config.Provider.QEMU.EKCertificateAuthority)
+		if err != nil {
+			return nil, fmt.Errorf("bad config: ekCertificateAuthority: %w", err)
+		}
+		v.ekRoots = ekRoots
+	}
+
+	if config.AttestationPolicy != "" {
+		attestationPolicy, err := policy.New(config.AttestationPolicy)
+		if err != nil {
+			return nil, fmt.Errorf("bad config: %w", err)
+		}
+		v.policy = attestationPolicy
+	}
+
 	return &v, nil
 }
 
 func (v *Validator) UpdateInitPCRs(ownerID, clusterID string) error {
+	// TDX guests only measure the cluster ID, into RTMR[3]
+	if v.qemuTDX {
+		return v.updateRTMR(tdx.RTMRIndexClusterID, clusterID)
+	}
 	if err := v.updatePCR(uint32(vtpm.PCRIndexOwnerID), ownerID); err != nil {
 		return err
 	}
@@ -98,6 +133,26 @@ func (v *Validator) updatePCR(pcrIndex uint32, encoded string) error {
 	return nil
 }
 
+// updateRTMR sets the expected value of an RTMR to its initial value extended with the base64 encoded input.
+// Unlike PCRs, the RTMR is always enforced, since it is the only measurement binding a TDX guest to the cluster.
+func (v *Validator) updateRTMR(rtmrIndex uint32, encoded string) error {
+	if encoded == "" {
+		return errors.New("cluster ID is required to verify Intel TDX guests")
+	}
+	decoded, err := base64.StdEncoding.DecodeString(encoded)
+	if err != nil {
+		return fmt.Errorf("input [%s] is not base64 encoded: %w", encoded, err)
+	}
+	v.pcrs[rtmrIndex] = tdx.ExtendMeasurement(v.pcrs[rtmrIndex], decoded)
+	for _, enforcedIdx := range v.enforcedPCRs {
+		if enforcedIdx == rtmrIndex {
+			return nil
+		}
+	}
+	v.enforcedPCRs = append(v.enforcedPCRs, rtmrIndex)
+	return nil
+}
+
 func (v *Validator) setPCRs(config *config.Config) error {
 	switch v.provider {
 	case cloudprovider.GCP:
@@ -143,15 +198,19 @@ func (v *Validator) updateValidator(cmd *cobra.Command) {
 	log := warnLogger{cmd: cmd}
 	switch v.provider {
 	case cloudprovider.GCP:
-		v.validator = gcp.NewValidator(v.pcrs, v.enforcedPCRs, log)
+		v.validator = gcp.NewValidator(v.pcrs, v.enforcedPCRs, v.policy, log)
 	case cloudprovider.Azure:
 		if v.azureCVM {
-			v.validator = snp.NewValidator(v.pcrs, v.enforcedPCRs, v.idkeydigest, v.enforceIdKeyDigest, log)
+			v.validator = snp.NewValidator(v.pcrs, v.enforcedPCRs, v.idkeydigest, v.enforceIdKeyDigest, v.policy, log)
 		} else {
-			v.validator = trustedlaunch.NewValidator(v.pcrs, v.enforcedPCRs, log)
+			v.validator = trustedlaunch.NewValidator(v.pcrs, v.enforcedPCRs, v.policy, log)
 		}
 	case cloudprovider.QEMU:
-		v.validator = qemu.NewValidator(v.pcrs, v.enforcedPCRs, log)
+		if v.qemuTDX {
+			v.validator = tdx.NewValidator(v.pcrs, v.enforcedPCRs, v.policy, log)
+		} else {
+			v.validator = qemu.NewValidator(v.pcrs, v.enforcedPCRs, v.ekRoots, v.policy, log)
+		}
 	}
 }
 
@@ -159,9 +218,14 @@ func (v *Validator) checkPCRs(pcrs map[uint32][]byte, enforcedPCRs []uint32) err
 	if len(pcrs) == 0 {
 		return errors.New("no PCR values provided")
 	}
+	// TDX measurements (MRTD and RTMRs) are SHA384 digests
+	expectedLen := 32
+	if v.qemuTDX {
+		expectedLen = 48
+	}
 	for k, v := range pcrs {
-		if len(v) != 32 {
-			return fmt.Errorf("bad config: PCR[%d]: expected length: %d, but got: %d", k, 32, len(v))
+		if len(v) != expectedLen {
+			return fmt.Errorf("bad config: PCR[%d]: expected length: %d, but got: %d", k, expectedLen, len(v))
 		}
 	}
 	for _, v := range enforcedPCRs {
@@ -16,6 +16,7 @@ import (
 	"github.com/edgelesssys/constellation/v2/internal/attestation/azure/trustedlaunch"
 	"github.com/edgelesssys/constellation/v2/internal/attestation/gcp"
 	"github.com/edgelesssys/constellation/v2/internal/attestation/qemu"
+	"github.com/edgelesssys/constellation/v2/internal/attestation/tdx"
 	"github.com/edgelesssys/constellation/v2/internal/attestation/vtpm"
 	"github.com/edgelesssys/constellation/v2/internal/cloud/cloudprovider"
 	"github.com/edgelesssys/constellation/v2/internal/config"
@@ -26,6 +27,7 @@ import (
 func TestNewValidator(t *testing.T) {
 	zero := []byte("00000000000000000000000000000000")
 	one := []byte("11111111111111111111111111111111")
+	zero48 := []byte("000000000000000000000000000000000000000000000000")
 	testPCRs := map[uint32][]byte{
 		0: zero,
 		1: one,
@@ -42,6 +44,9 @@ func TestNewValidator(t *testing.T) {
 		enforceIdKeyDigest bool
 		idkeydigest        string
 		azureCVM           bool
+		qemuTDX            bool
+		attestationPolicy  string
+		ekCA               string
 		wantErr            bool
 	}{
 		"gcp": {
@@ -58,9 +63,32 @@ func TestNewValidator(t *testing.T) {
 			pcrs:     testPCRs,
 			azureCVM: false,
 		},
-		"qemu": {
+		"qemu without EK CA": {
+			provider: cloudprovider.QEMU,
+			pcrs:     testPCRs,
+			wantErr:  true,
+		},
+		"qemu with EK CA": {
+			provider: cloudprovider.QEMU,
+			pcrs:     testPCRs,
+			ekCA:     testEKCA,
+		},
+		"qemu with invalid EK CA": {
+			provider: cloudprovider.QEMU,
+			pcrs:     testPCRs,
+			ekCA:     "not a certificate",
+			wantErr:  true,
+		},
+		"qemu tdx": {
+			provider: cloudprovider.QEMU,
+			pcrs:     map[uint32][]byte{0: zero48, 1: zero48},
+			qemuTDX:  true,
+		},
+		"qemu tdx with PCR length": {
 			provider: cloudprovider.QEMU,
 			pcrs:     testPCRs,
+			qemuTDX:  true,
+			wantErr:  true,
 		},
 		"no pcrs provided": {
 			provider: cloudprovider.Azure,
@@ -77,6 +105,11 @@ func TestNewValidator(t *testing.T) {
 			pcrs:     testPCRs,
 			wantErr:  true,
 		},
+		"unsupported provider": {
+			provider: cloudprovider.AWS,
+			pcrs:     testPCRs,
+			wantErr:  true,
+		},
 		"set idkeydigest": {
 			provider:           cloudprovider.Azure,
 			pcrs:               testPCRs,
@@ -91,13 +124,24 @@ func TestNewValidator(t *testing.T) {
 			azureCVM:           true,
 			wantErr:            true,
 		},
+		"attestation policy": {
+			provider:          cloudprovider.GCP,
+			pcrs:              testPCRs,
+			attestationPolicy: `claims.measurements[4] == "00"`,
+		},
+		"invalid attestation policy": {
+			provider:          cloudprovider.GCP,
+			pcrs:              testPCRs,
+			attestationPolicy: "claims.measurements[",
+			wantErr:           true,
+		},
 	}
 
 	for name, tc := range testCases {
 		t.Run(name, func(t *testing.T) {
 			assert := assert.New(t)
 
-			conf := &config.Config{Provider: config.ProviderConfig{}}
+			conf := &config.Config{Provider: config.ProviderConfig{}, AttestationPolicy: tc.attestationPolicy}
 			if tc.provider == cloudprovider.GCP {
 				measurements := config.Measurements(tc.pcrs)
 				conf.Provider.GCP = &config.GCPConfig{Measurements: measurements}
@@ -108,7 +152,7 @@ func TestNewValidator(t *testing.T) {
 			}
 			if tc.provider == cloudprovider.QEMU {
 				measurements := config.Measurements(tc.pcrs)
-				conf.Provider.QEMU = &config.QEMUConfig{Measurements: measurements}
+				conf.Provider.QEMU = &config.QEMUConfig{Measurements: measurements, TDX: &tc.qemuTDX, EKCertificateAuthority: tc.ekCA}
 			}
 
 			validators, err := NewValidator(tc.provider, conf)
@@ -119,6 +163,8 @@ func TestNewValidator(t *testing.T) {
 				assert.NoError(err)
 				assert.Equal(tc.pcrs, validators.pcrs)
 				assert.Equal(tc.provider, validators.provider)
+				assert.Equal(tc.attestationPolicy != "", validators.policy != nil)
+				assert.Equal(tc.ekCA != "", validators.ekRoots != nil)
 			}
 		})
 	}
@@ -150,27 +196,34 @@ func TestValidatorV(t *testing.T) {
 		pcrs     map[uint32][]byte
 		wantVs   atls.Validator
 		azureCVM bool
+		qemuTDX  bool
 	}{
 		"gcp": {
 			provider: cloudprovider.GCP,
 			pcrs:     newTestPCRs(),
-			wantVs:   gcp.NewValidator(newTestPCRs(), nil, nil),
+			wantVs:   gcp.NewValidator(newTestPCRs(), nil, nil, nil),
 		},
 		"azure cvm": {
 			provider: cloudprovider.Azure,
 			pcrs:     newTestPCRs(),
-			wantVs:   snp.NewValidator(newTestPCRs(), nil, nil, false, nil),
+			wantVs:   snp.NewValidator(newTestPCRs(), nil, nil, false, nil, nil),
 			azureCVM: true,
 		},
 		"azure trusted launch": {
 			provider: cloudprovider.Azure,
 			pcrs:     newTestPCRs(),
-			wantVs:   trustedlaunch.NewValidator(newTestPCRs(), nil, nil),
+			wantVs:   trustedlaunch.NewValidator(newTestPCRs(), nil, nil, nil),
 		},
 		"qemu": {
 			provider: cloudprovider.QEMU,
 			pcrs:     newTestPCRs(),
-			wantVs:   qemu.NewValidator(newTestPCRs(), nil, nil),
+			wantVs:   qemu.NewValidator(newTestPCRs(), nil, nil, nil, nil),
+		},
+		"qemu tdx": {
+			provider: cloudprovider.QEMU,
+			pcrs:     newTestPCRs(),
+			wantVs:   tdx.NewValidator(newTestPCRs(), nil, nil, nil),
+			qemuTDX:  true,
 		},
 	}
 
@@ -178,7 +231,7 @@ func TestValidatorV(t *testing.T) {
 		t.Run(name, func(t *testing.T) {
 			assert := assert.New(t)
 
-			validators := &Validator{provider: tc.provider, pcrs: tc.pcrs, azureCVM: tc.azureCVM}
+			validators := &Validator{provider: tc.provider, pcrs: tc.pcrs, azureCVM: tc.azureCVM, qemuTDX: tc.qemuTDX}
 
 			resultValidator := validators.V(&cobra.Command{})
 
@@ -216,6 +269,7 @@ func TestValidatorUpdateInitPCRs(t *testing.T) {
 		pcrs      map[uint32][]byte
 		ownerID   string
 		clusterID string
+		qemuTDX   bool
 		wantErr   bool
 	}{
 		"gcp update owner ID": {
@@ -250,6 +304,20 @@ func TestValidatorUpdateInitPCRs(t *testing.T) {
 			ownerID:   one64,
 			clusterID: one64,
 		},
+		"qemu tdx updates RTMR[3]": {
+			provider:  cloudprovider.QEMU,
+			pcrs:      newTestPCRs(),
+			ownerID:   one64,
+			clusterID: one64,
+			qemuTDX:   true,
+		},
+		"qemu tdx requires cluster ID": {
+			provider: cloudprovider.QEMU,
+			pcrs:     newTestPCRs(),
+			ownerID:  one64,
+			qemuTDX:  true,
+			wantErr:  true,
+		},
 		"owner ID and cluster ID empty": {
 			provider: cloudprovider.GCP,
 			pcrs:     newTestPCRs(),
@@ -266,7 +334,7 @@ func TestValidatorUpdateInitPCRs(t *testing.T) {
 		t.Run(name, func(t *testing.T) {
 			assert := assert.New(t)
 
-			validators := &Validator{provider: tc.provider, pcrs: tc.pcrs}
+			validators := &Validator{provider: tc.provider, pcrs: tc.pcrs, qemuTDX: tc.qemuTDX}
 
 			err := validators.UpdateInitPCRs(tc.ownerID, tc.clusterID)
 
@@ -275,6 +343,13 @@ func TestValidatorUpdateInitPCRs(t *testing.T) {
 				return
 			}
 			assert.NoError(err)
+			if tc.qemuTDX {
+				wantPCRs := newTestPCRs()
+				wantPCRs[tdx.RTMRIndexClusterID] = tdx.ExtendMeasurement(zero, one)
+				assert.Equal(wantPCRs, validators.pcrs)
+				assert.Contains(validators.enforcedPCRs, uint32(tdx.RTMRIndexClusterID))
+				return
+			}
 			for i := 0; i < len(tc.pcrs); i++ {
 				switch {
 				case i == int(vtpm.PCRIndexClusterID) && tc.clusterID == "":
@@ -397,3 +472,17 @@ func TestUpdatePCR(t *testing.T) {
 		})
 	}
 }
+
+// testEKCA is a self-signed CA certificate used as swtpm EK CA in tests.
+const testEKCA = `-----BEGIN CERTIFICATE-----
+MIIBhTCCASugAwIBAgIUKfcpSI+8JTHY4VQEyX/eXClvUwcwCgYIKoZIzj0EAwIw
+GDEWMBQGA1UEAwwNc3d0cG0tbG9jYWxjYTAeFw0yNjEwMTgxNDA4NTVaFw0zNjEw
+MTUxNDA4NTVaMBgxFjAUBgNVBAMMDXN3dHBtLWxvY2FsY2EwWTATBgcqhkjOPQIB
+BggqhkjOPQMBBwNCAARHYKyYkWhB+YQvYeerA+Ukf/3SGKWk92B1NaaxhwjWbHpO
+Yl93m1mA528SXN4jd5xnVGZFAyUgT7gK9djjdfNxo1MwUTAdBgNVHQ4EFgQUy0nz
+0J2cLG5l8yh4dVj/Lfy7F1kwHwYDVR0jBBgwFoAUy0nz0J2cLG5l8yh4dVj/Lfy7
+F1kwDwYDVR0TAQH/BAUwAwEB/zAKBggqhkjOPQQDAgNIADBFAiEA0BuPhvmMeFv7
+rP71RuzmMm+R/jEuwE5G5D5iPjhN5UcCIBdLeZbSWK5v4elTngpAwc5gzu7tSATn
+Uiwaw3lS+39Z
+-----END CERTIFICATE-----
+`
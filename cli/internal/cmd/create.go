@@ -13,6 +13,7 @@ import (
 
 	"github.com/edgelesssys/constellation/v2/cli/internal/cloudcmd"
 	"github.com/edgelesssys/constellation/v2/internal/cloud/cloudprovider"
+	"github.com/edgelesssys/constellation/v2/internal/config"
 	"github.com/edgelesssys/constellation/v2/internal/constants"
 	"github.com/edgelesssys/constellation/v2/internal/file"
 	"github.com/edgelesssys/constellation/v2/internal/state"
@@ -35,8 +36,7 @@ func NewCreateCmd() *cobra.Command {
 	cmd.Flags().BoolP("yes", "y", false, "create the cluster without further confirmation")
 	cmd.Flags().IntP("control-plane-nodes", "c", 0, "number of control-plane nodes (required)")
 	must(cobra.MarkFlagRequired(cmd.Flags(), "control-plane-nodes"))
-	cmd.Flags().IntP("worker-nodes", "w", 0, "number of worker nodes (required)")
-	must(cobra.MarkFlagRequired(cmd.Flags(), "worker-nodes"))
+	cmd.Flags().IntP("worker-nodes", "w", 0, "number of worker nodes (required unless workerGroups are defined in the config)")
 	return cmd
 }
 
@@ -47,6 +47,11 @@ func runCreate(cmd *cobra.Command, args []string) error {
 	return create(cmd, creator, fileHandler)
 }
 
+// errTrustedLaunchUnsupported is returned when creating or initializing a cluster of Azure trusted launch VMs.
+// Their attestation doesn't report an instance ID, so the join service can't verify their scaling group membership.
+var errTrustedLaunchUnsupported = errors.New("Azure trusted launch VMs are not supported, set confidentialVM to true: " +
+	"their attestation doesn't identify the VM, which is required for nodes to join the cluster")
+
 func create(cmd *cobra.Command, creator cloudCreator, fileHandler file.Handler) (retErr error) {
 	flags, err := parseCreateFlags(cmd)
 	if err != nil {
@@ -63,6 +68,10 @@ func create(cmd *cobra.Command, creator cloudCreator, fileHandler file.Handler)
 	}
 
 	var printedAWarning bool
+	if err := validateWorkerNodes(flags, config); err != nil {
+		return err
+	}
+
 	if config.IsDebugImage() {
 		cmd.Println("Configured image doesn't look like a released production image. Double check image before deploying to production.")
 		printedAWarning = true
@@ -75,11 +84,7 @@ func create(cmd *cobra.Command, creator cloudCreator, fileHandler file.Handler)
 	}
 
 	if config.IsAzureNonCVM() {
-		cmd.Println("Disabling Confidential VMs is insecure. Use only for evaluation purposes.")
-		printedAWarning = true
-		if config.EnforcesIdKeyDigest() {
-			cmd.Println("Your config asks for enforcing the idkeydigest. This is only available on Confidential VMs. It will not be enforced.")
-		}
+		return errTrustedLaunchUnsupported
 	}
 
 	// Print an extra new line later to separate warnings from the prompt message of the create command
@@ -103,7 +108,12 @@ func create(cmd *cobra.Command, creator cloudCreator, fileHandler file.Handler)
 		// Ask user to confirm action.
 		cmd.Printf("The following Constellation cluster will be created:\n")
 		cmd.Printf("%d control-planes nodes of type %s will be created.\n", flags.controllerCount, instanceType)
-		cmd.Printf("%d worker nodes of type %s will be created.\n", flags.workerCount, instanceType)
+		if len(config.WorkerGroups) == 0 {
+			cmd.Printf("%d worker nodes of type %s will be created.\n", flags.workerCount, instanceType)
+		}
+		for _, group := range config.WorkerGroups {
+			cmd.Printf("Worker group %s: %d worker nodes of type %s will be created, autoscaling up to %d nodes.\n", group.Name, group.Min, group.InstanceType, group.Max)
+		}
 		ok, err := askToConfirm(cmd, "Do you want to create this cluster?")
 		if err != nil {
 			return err
@@ -145,9 +155,6 @@ func parseCreateFlags(cmd *cobra.Command) (createFlags, error) {
 	if err != nil {
 		return createFlags{}, fmt.Errorf("parsing number of worker nodes: %w", err)
 	}
-	if workerCount < constants.MinWorkerCount {
-		return createFlags{}, fmt.Errorf("number of worker nodes must be at least %d", constants.MinWorkerCount)
-	}
 
 	name, err := cmd.Flags().GetString("name")
 	if err != nil {
@@ -173,6 +180,7 @@ func parseCreateFlags(cmd *cobra.Command) (createFlags, error) {
 	return createFlags{
 		controllerCount: controllerCount,
 		workerCount:     workerCount,
+		workerCountSet:  cmd.Flags().Changed("worker-nodes"),
 		name:            name,
 		configPath:      configPath,
 		yes:             yes,
@@ -183,11 +191,42 @@ func parseCreateFlags(cmd *cobra.Command) (createFlags, error) {
 type createFlags struct {
 	controllerCount int
 	workerCount     int
+	workerCountSet  bool
 	name            string
 	configPath      string
 	yes             bool
 }
 
+// validateWorkerNodes checks the number of worker nodes against the worker groups defined in the config.
+// Without worker groups, the number of worker nodes is set by flag. Otherwise, the groups define their own sizes.
+func validateWorkerNodes(flags createFlags, config *config.Config) error {
+	if len(config.WorkerGroups) == 0 {
+		if flags.workerCount < constants.MinWorkerCount {
+			return fmt.Errorf("number of worker nodes must be at least %d", constants.MinWorkerCount)
+		}
+		return nil
+	}
+
+	if flags.workerCountSet {
+		return errors.New("flag --worker-nodes can't be used when workerGroups are defined in the config")
+	}
+	var minWorkerCount int
+	for _, group := range config.WorkerGroups {
+		minWorkerCount += group.Min
+		// the group name is part of the names of the group's cloud resources
+		if len(flags.name)+1+len(group.Name) > constants.ConstellationNameLength {
+			return fmt.Errorf(
+				"name for Constellation cluster too long for worker group %s, maximum combined length of name and group name is %d",
+				group.Name, constants.ConstellationNameLength-1,
+			)
+		}
+	}
+	if minWorkerCount < constants.MinWorkerCount {
+		return fmt.Errorf("minimum number of nodes of all worker groups must be at least %d", constants.MinWorkerCount)
+	}
+	return nil
+}
+
 // checkDirClean checks if files of a previous Constellation are left in the current working dir.
 func checkDirClean(fileHandler file.Handler) error {
 	if _, err := fileHandler.Stat(constants.StateFilename); !errors.Is(err, fs.ErrNotExist) {
@@ -14,6 +14,7 @@ import (
 	"testing"
 
 	"github.com/edgelesssys/constellation/v2/internal/cloud/cloudprovider"
+	"github.com/edgelesssys/constellation/v2/internal/config"
 	"github.com/edgelesssys/constellation/v2/internal/constants"
 	"github.com/edgelesssys/constellation/v2/internal/file"
 	"github.com/edgelesssys/constellation/v2/internal/state"
@@ -164,6 +165,24 @@ func TestCreate(t *testing.T) {
 			configFlag:          constants.ConfigFilename,
 			wantErr:             true,
 		},
+		"azure trusted launch": {
+			setupFs: func(require *require.Assertions) afero.Fs {
+				fs := afero.NewMemMapFs()
+				conf := defaultConfigWithExpectedMeasurements(t, config.Default(), cloudprovider.Azure)
+				conf.Provider.Azure.ConfidentialVM = func() *bool { b := false; return &b }()
+				conf.Provider.Azure.InstanceType = "Standard_D4a_v4"
+				fileHandler := file.NewHandler(fs)
+				require.NoError(fileHandler.WriteYAML(constants.ConfigFilename, conf))
+				return fs
+			},
+			creator:             &stubCloudCreator{},
+			provider:            cloudprovider.Azure,
+			controllerCountFlag: intPtr(1),
+			workerCountFlag:     intPtr(1),
+			yesFlag:             true,
+			configFlag:          constants.ConfigFilename,
+			wantErr:             true,
+		},
 		"create error": {
 			setupFs:             func(require *require.Assertions) afero.Fs { return afero.NewMemMapFs() },
 			creator:             &stubCloudCreator{createErr: someErr},
@@ -287,6 +306,64 @@ func TestCheckDirClean(t *testing.T) {
 	}
 }
 
+func TestValidateWorkerNodes(t *testing.T) {
+	workerGroups := []config.WorkerGroup{
+		{Name: "highmem", InstanceType: "n2d-highmem-4", Min: 1, Max: 5},
+		{Name: "spot", InstanceType: "n2d-standard-4", Min: 0, Max: 10},
+	}
+
+	testCases := map[string]struct {
+		flags        createFlags
+		workerGroups []config.WorkerGroup
+		wantErr      bool
+	}{
+		"worker nodes flag": {
+			flags: createFlags{name: "constell", workerCount: 2, workerCountSet: true},
+		},
+		"worker nodes flag too small": {
+			flags:   createFlags{name: "constell", workerCount: 0, workerCountSet: true},
+			wantErr: true,
+		},
+		"worker nodes flag missing": {
+			flags:   createFlags{name: "constell"},
+			wantErr: true,
+		},
+		"worker groups": {
+			flags:        createFlags{name: "constell"},
+			workerGroups: workerGroups,
+		},
+		"worker groups with worker nodes flag": {
+			flags:        createFlags{name: "constell", workerCount: 2, workerCountSet: true},
+			workerGroups: workerGroups,
+			wantErr:      true,
+		},
+		"worker groups without nodes": {
+			flags:        createFlags{name: "constell"},
+			workerGroups: []config.WorkerGroup{{Name: "spot", InstanceType: "n2d-standard-4", Min: 0, Max: 10}},
+			wantErr:      true,
+		},
+		"name too long for worker group": {
+			flags:        createFlags{name: strings.Repeat("a", constants.ConstellationNameLength-len("highmem"))},
+			workerGroups: workerGroups,
+			wantErr:      true,
+		},
+	}
+
+	for name, tc := range testCases {
+		t.Run(name, func(t *testing.T) {
+			assert := assert.New(t)
+
+			err := validateWorkerNodes(tc.flags, &config.Config{WorkerGroups: tc.workerGroups})
+
+			if tc.wantErr {
+				assert.Error(err)
+			} else {
+				assert.NoError(err)
+			}
+		})
+	}
+}
+
 func intPtr(i int) *int {
 	return &i
 }
@@ -50,27 +50,36 @@ func NewInitCmd() *cobra.Command {
 	cmd.Flags().String("master-secret", "", "path to base64-encoded master secret")
 	cmd.Flags().String("endpoint", "", "endpoint of the bootstrapper, passed as HOST[:PORT]")
 	cmd.Flags().Bool("conformance", false, "enable conformance mode")
+	cmd.Flags().String("owner-key", constants.OwnerKeyFilename, "path to the cosign private key of the cluster owner, used to sign the measurements")
 	return cmd
 }
 
 // runInitialize runs the initialize command.
 func runInitialize(cmd *cobra.Command, args []string) error {
 	fileHandler := file.NewHandler(afero.NewOsFs())
+	netDialer, err := newProxyDialer(cmd)
+	if err != nil {
+		return err
+	}
 	newDialer := func(validator *cloudcmd.Validator) *dialer.Dialer {
-		return dialer.New(nil, validator.V(cmd), &net.Dialer{})
+		return dialer.New(nil, validator.V(cmd), netDialer)
 	}
 	helmLoader := &helm.ChartLoader{}
+	newSigner := func() (joinConfigSigner, error) {
+		return cloudcmd.NewJoinConfigSigner(netDialer.HTTPProxy)
+	}
 
 	ctx, cancel := context.WithTimeout(cmd.Context(), time.Hour)
 	defer cancel()
 	cmd.SetContext(ctx)
 
-	return initialize(cmd, newDialer, fileHandler, helmLoader, license.NewClient())
+	return initialize(cmd, newDialer, newSigner, fileHandler, helmLoader, license.NewClient())
 }
 
 // initialize initializes a Constellation.
+// The public key of the owner key is pinned in the cluster, and the join-config is signed with the owner key.
 func initialize(cmd *cobra.Command, newDialer func(validator *cloudcmd.Validator) *dialer.Dialer,
-	fileHandler file.Handler, helmLoader helmLoader, quotaChecker license.QuotaChecker,
+	newSigner func() (joinConfigSigner, error), fileHandler file.Handler, helmLoader helmLoader, quotaChecker license.QuotaChecker,
 ) error {
 	flags, err := evalFlagArgs(cmd, fileHandler)
 	if err != nil {
@@ -81,6 +90,9 @@ func initialize(cmd *cobra.Command, newDialer func(validator *cloudcmd.Validator
 	if err != nil {
 		return fmt.Errorf("reading and validating config: %w", err)
 	}
+	if config.IsAzureNonCVM() {
+		return errTrustedLaunchUnsupported
+	}
 
 	k8sVersion, err := versions.NewValidK8sVersion(config.KubernetesVersion)
 	if err != nil {
@@ -119,6 +131,15 @@ func initialize(cmd *cobra.Command, newDialer func(validator *cloudcmd.Validator
 		return fmt.Errorf("loading Helm charts: %w", err)
 	}
 
+	ownerKey, err := readOwnerKey(fileHandler, flags.ownerKeyPath)
+	if err != nil {
+		return err
+	}
+	ownerPublicKey, err := ownerPublicKey(ownerKey)
+	if err != nil {
+		return err
+	}
+
 	masterSecret, err := readOrGenerateMasterSecret(cmd.OutOrStdout(), fileHandler, flags.masterSecretPath)
 	if err != nil {
 		return fmt.Errorf("parsing or generating master secret from file %s: %w", flags.masterSecretPath, err)
@@ -126,19 +147,23 @@ func initialize(cmd *cobra.Command, newDialer func(validator *cloudcmd.Validator
 
 	cmd.Println("Initializing cluster ...")
 	req := &initproto.InitRequest{
-		MasterSecret:           masterSecret.Key,
-		Salt:                   masterSecret.Salt,
-		KmsUri:                 kms.ClusterKMSURI,
-		StorageUri:             kms.NoStoreURI,
-		KeyEncryptionKeyId:     "",
-		UseExistingKek:         false,
-		CloudServiceAccountUri: serviceAccURI,
-		KubernetesVersion:      config.KubernetesVersion,
-		SshUserKeys:            ssh.ToProtoSlice(sshUsers),
-		HelmDeployments:        helmDeployments,
-		EnforcedPcrs:           getEnforcedMeasurements(provider, config),
-		EnforceIdkeydigest:     getEnforceIdKeyDigest(provider, config),
-		ConformanceMode:        flags.conformance,
+		MasterSecret:                   masterSecret.Key,
+		Salt:                           masterSecret.Salt,
+		KmsUri:                         kms.ClusterKMSURI,
+		StorageUri:                     kms.NoStoreURI,
+		KeyEncryptionKeyId:             "",
+		UseExistingKek:                 false,
+		CloudServiceAccountUri:         serviceAccURI,
+		KubernetesVersion:              config.KubernetesVersion,
+		SshUserKeys:                    ssh.ToProtoSlice(sshUsers),
+		HelmDeployments:                helmDeployments,
+		EnforcedPcrs:                   getEnforcedMeasurements(provider, config),
+		EnforceIdkeydigest:             getEnforceIdKeyDigest(provider, config),
+		ConformanceMode:                flags.conformance,
+		AttestationPolicy:              config.AttestationPolicy,
+		EkCertificateAuthority:         getEKCertificateAuthority(provider, config),
+		KubeletCertificateValidityDays: uint32(config.KubeletCertificateValidityDays),
+		OwnerPublicKey:                 ownerPublicKey,
 	}
 	resp, err := initCall(cmd.Context(), newDialer(validator), flags.endpoint, req)
 	if err != nil {
@@ -149,6 +174,20 @@ func initialize(cmd *cobra.Command, newDialer func(validator *cloudcmd.Validator
 		return err
 	}
 
+	// the join service only trusts measurements signed with the owner key, nodes can't join before.
+	// The signed values are the ones the first node was verified against, not the ones reported by the cluster.
+	joinConfig, err := cloudcmd.JoinConfigValues(config, validator.PCRS())
+	if err != nil {
+		return err
+	}
+	signer, err := newSigner()
+	if err != nil {
+		return err
+	}
+	if err := signer.Sign(cmd.Context(), joinConfig, ownerKey); err != nil {
+		return fmt.Errorf("signing measurements, nodes can't join the cluster until they are signed: %w", err)
+	}
+
 	return nil
 }
 
@@ -244,6 +283,15 @@ func getEnforceIdKeyDigest(provider cloudprovider.Provider, config *config.Confi
 	}
 }
 
+func getEKCertificateAuthority(provider cloudprovider.Provider, config *config.Config) string {
+	switch provider {
+	case cloudprovider.QEMU:
+		return config.Provider.QEMU.EKCertificateAuthority
+	default:
+		return ""
+	}
+}
+
 // evalFlagArgs gets the flag values and does preprocessing of these values like
 // reading the content from file path flags and deriving other values from flag combinations.
 func evalFlagArgs(cmd *cobra.Command, fileHandler file.Handler) (initFlags, error) {
@@ -269,9 +317,14 @@ func evalFlagArgs(cmd *cobra.Command, fileHandler file.Handler) (initFlags, erro
 	if err != nil {
 		return initFlags{}, fmt.Errorf("parsing config path flag: %w", err)
 	}
+	ownerKeyPath, err := cmd.Flags().GetString("owner-key")
+	if err != nil {
+		return initFlags{}, fmt.Errorf("parsing owner-key path flag: %w", err)
+	}
 
 	return initFlags{
 		configPath:       configPath,
+		ownerKeyPath:     ownerKeyPath,
 		endpoint:         endpoint,
 		conformance:      conformance,
 		masterSecretPath: masterSecretPath,
@@ -282,6 +335,7 @@ func evalFlagArgs(cmd *cobra.Command, fileHandler file.Handler) (initFlags, erro
 type initFlags struct {
 	configPath       string
 	masterSecretPath string
+	ownerKeyPath     string
 	endpoint         string
 	conformance      bool
 }
@@ -9,6 +9,7 @@ package cmd
 import (
 	"bytes"
 	"context"
+	"crypto"
 	"encoding/base64"
 	"encoding/json"
 	"errors"
@@ -32,6 +33,7 @@ import (
 	"github.com/edgelesssys/constellation/v2/internal/license"
 	"github.com/edgelesssys/constellation/v2/internal/oid"
 	"github.com/edgelesssys/constellation/v2/internal/state"
+	"github.com/sigstore/sigstore/pkg/cryptoutils"
 	"github.com/spf13/afero"
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/require"
@@ -80,6 +82,8 @@ func TestInitialize(t *testing.T) {
 		helmLoader              stubHelmLoader
 		initServerAPI           *stubInitServer
 		endpointFlag            string
+		noOwnerKey              bool
+		signErr                 error
 		masterSecretShouldExist bool
 		wantErr                 bool
 	}{
@@ -95,6 +99,16 @@ func TestInitialize(t *testing.T) {
 			idFile:        &clusterIDsFile{IP: "192.0.2.1"},
 			initServerAPI: &stubInitServer{initResp: testInitResp},
 		},
+		"azure trusted launch is rejected": {
+			state:  testAzureState,
+			idFile: &clusterIDsFile{IP: "192.0.2.1"},
+			configMutator: func(c *config.Config) {
+				c.Provider.Azure.ConfidentialVM = func() *bool { b := false; return &b }()
+				c.Provider.Azure.InstanceType = "Standard_D4a_v4"
+			},
+			initServerAPI: &stubInitServer{initResp: testInitResp},
+			wantErr:       true,
+		},
 		"initialize some qemu instances": {
 			state:         testQemuState,
 			idFile:        &clusterIDsFile{IP: "192.0.2.1"},
@@ -123,6 +137,21 @@ func TestInitialize(t *testing.T) {
 			initServerAPI: &stubInitServer{initErr: someErr},
 			wantErr:       true,
 		},
+		"missing owner key": {
+			state:         testQemuState,
+			idFile:        &clusterIDsFile{IP: "192.0.2.1"},
+			initServerAPI: &stubInitServer{initResp: testInitResp},
+			noOwnerKey:    true,
+			wantErr:       true,
+		},
+		"signing join-config fails": {
+			state:                   testQemuState,
+			idFile:                  &clusterIDsFile{IP: "192.0.2.1"},
+			initServerAPI:           &stubInitServer{initResp: testInitResp},
+			signErr:                 someErr,
+			masterSecretShouldExist: true,
+			wantErr:                 true,
+		},
 		"fail missing enforced PCR": {
 			state:  testGcpState,
 			idFile: &clusterIDsFile{IP: "192.0.2.1"},
@@ -183,13 +212,19 @@ func TestInitialize(t *testing.T) {
 			if tc.serviceAccKey != nil {
 				require.NoError(fileHandler.WriteJSON(serviceAccPath, tc.serviceAccKey, file.OptNone))
 			}
+			ownerKey, ownerPublicKey := newOwnerKey(t, nil)
+			if !tc.noOwnerKey {
+				require.NoError(fileHandler.Write(constants.OwnerKeyFilename, ownerKey))
+			}
+			signer := &stubJoinConfigSigner{signErr: tc.signErr}
+			newSigner := func() (joinConfigSigner, error) { return signer, nil }
 
 			ctx := context.Background()
 			ctx, cancel := context.WithTimeout(ctx, 4*time.Second)
 			defer cancel()
 			cmd.SetContext(ctx)
 
-			err := initialize(cmd, newDialer, fileHandler, &tc.helmLoader, &stubLicenseClient{})
+			err := initialize(cmd, newDialer, newSigner, fileHandler, &tc.helmLoader, &stubLicenseClient{})
 
 			if tc.wantErr {
 				assert.Error(err)
@@ -206,6 +241,12 @@ func TestInitialize(t *testing.T) {
 			assert.NoError(fileHandler.ReadJSON(constants.MasterSecretFilename, &secret))
 			assert.NotEmpty(secret.Key)
 			assert.NotEmpty(secret.Salt)
+			assert.Equal(ownerPublicKey, tc.initServerAPI.req.OwnerPublicKey)
+			require.NotNil(signer.key)
+			signerPublicKey, err := cryptoutils.MarshalPublicKeyToPEM(signer.key.Public())
+			require.NoError(err)
+			assert.Equal(ownerPublicKey, signerPublicKey)
+			assert.NotEmpty(signer.values[constants.MeasurementsFilename])
 		})
 	}
 }
@@ -414,6 +455,7 @@ func TestAttestation(t *testing.T) {
 	cfg := config.Default()
 	cfg.RemoveProviderExcept(cloudprovider.QEMU)
 	cfg.Provider.QEMU.Image = "some/image/location"
+	cfg.Provider.QEMU.EKCertificateAuthority = testEKCA
 	cfg.Provider.QEMU.Measurements[0] = []byte("00000000000000000000000000000000")
 	cfg.Provider.QEMU.Measurements[1] = []byte("11111111111111111111111111111111")
 	cfg.Provider.QEMU.Measurements[2] = []byte("22222222222222222222222222222222")
@@ -425,7 +467,11 @@ func TestAttestation(t *testing.T) {
 	defer cancel()
 	cmd.SetContext(ctx)
 
-	err := initialize(cmd, newDialer, fileHandler, &stubHelmLoader{}, &stubLicenseClient{})
+	ownerKey, _ := newOwnerKey(t, nil)
+	require.NoError(fileHandler.Write(constants.OwnerKeyFilename, ownerKey))
+	newSigner := func() (joinConfigSigner, error) { return &stubJoinConfigSigner{}, nil }
+
+	err := initialize(cmd, newDialer, newSigner, fileHandler, &stubHelmLoader{}, &stubLicenseClient{})
 	assert.Error(err)
 	// make sure the error is actually a TLS handshake error
 	assert.Contains(err.Error(), "transport: authentication handshake failed")
@@ -473,14 +519,28 @@ func (i *testIssuer) Issue(userData []byte, nonce []byte) ([]byte, error) {
 type stubInitServer struct {
 	initResp *initproto.InitResponse
 	initErr  error
+	req      *initproto.InitRequest
 
 	initproto.UnimplementedAPIServer
 }
 
 func (s *stubInitServer) Init(ctx context.Context, req *initproto.InitRequest) (*initproto.InitResponse, error) {
+	s.req = req
 	return s.initResp, s.initErr
 }
 
+type stubJoinConfigSigner struct {
+	values  map[string]string
+	key     crypto.Signer
+	signErr error
+}
+
+func (s *stubJoinConfigSigner) Sign(_ context.Context, values map[string]string, key crypto.Signer) error {
+	s.values = values
+	s.key = key
+	return s.signErr
+}
+
 func defaultConfigWithExpectedMeasurements(t *testing.T, conf *config.Config, csp cloudprovider.Provider) *config.Config {
 	t.Helper()
 
@@ -508,6 +568,7 @@ func defaultConfigWithExpectedMeasurements(t *testing.T, conf *config.Config, cs
 		conf.Provider.GCP.Measurements[9] = []byte("11111111111111111111111111111111")
 	case cloudprovider.QEMU:
 		conf.Provider.QEMU.Image = "some/image/location"
+		conf.Provider.QEMU.EKCertificateAuthority = testEKCA
 		conf.Provider.QEMU.Measurements[8] = []byte("00000000000000000000000000000000")
 		conf.Provider.QEMU.Measurements[9] = []byte("11111111111111111111111111111111")
 	}
@@ -523,3 +584,17 @@ func (c *stubLicenseClient) QuotaCheck(ctx context.Context, checkRequest license
 		Quota: 25,
 	}, nil
 }
+
+// testEKCA is a self-signed CA certificate used as swtpm EK CA in tests.
+const testEKCA = `-----BEGIN CERTIFICATE-----
+MIIBhTCCASugAwIBAgIUKfcpSI+8JTHY4VQEyX/eXClvUwcwCgYIKoZIzj0EAwIw
+GDEWMBQGA1UEAwwNc3d0cG0tbG9jYWxjYTAeFw0yNjEwMTgxNDA4NTVaFw0zNjEw
+MTUxNDA4NTVaMBgxFjAUBgNVBAMMDXN3dHBtLWxvY2FsY2EwWTATBgcqhkjOPQIB
+BggqhkjOPQMBBwNCAARHYKyYkWhB+YQvYeerA+Ukf/3SGKWk92B1NaaxhwjWbHpO
+Yl93m1mA528SXN4jd5xnVGZFAyUgT7gK9djjdfNxo1MwUTAdBgNVHQ4EFgQUy0nz
+0J2cLG5l8yh4dVj/Lfy7F1kwHwYDVR0jBBgwFoAUy0nz0J2cLG5l8yh4dVj/Lfy7
+F1kwDwYDVR0TAQH/BAUwAwEB/zAKBggqhkjOPQQDAgNIADBFAiEA0BuPhvmMeFv7
+rP71RuzmMm+R/jEuwE5G5D5iPjhN5UcCIBdLeZbSWK5v4elTngpAwc5gzu7tSATn
+Uiwaw3lS+39Z
+-----END CERTIFICATE-----
+`
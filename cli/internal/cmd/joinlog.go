@@ -0,0 +1,106 @@
+/*
+Copyright (c) Edgeless Systems GmbH
+
+SPDX-License-Identifier: AGPL-3.0-only
+*/
+
+package cmd
+
+import (
+	"context"
+	"fmt"
+	"text/tabwriter"
+	"time"
+
+	"github.com/edgelesssys/constellation/v2/cli/internal/cloudcmd"
+	"github.com/spf13/cobra"
+)
+
+// NewJoinLogCmd returns a new cobra.Command for the join-log command.
+func NewJoinLogCmd() *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "join-log",
+		Short: "Show the join history of a Constellation cluster",
+		Long: "Show the join history of a Constellation cluster.\n\n" +
+			"The JoinService records every join, rejoin, and kubelet certificate renewal request of a node, including the node's address, " +
+			"its attested identity, the requested state disk, and whether the request was accepted, denied, or failed.",
+		Args: cobra.ExactArgs(0),
+		RunE: runJoinLog,
+	}
+	cmd.Flags().Duration("since", 0, "only show requests newer than a relative duration like 5m or 3h")
+	cmd.Flags().String("node", "", "only show requests of the node with the given name")
+	cmd.Flags().Bool("rejected", false, "only show denied and failed requests")
+	return cmd
+}
+
+func runJoinLog(cmd *cobra.Command, args []string) error {
+	proxyDialer, err := newProxyDialer(cmd)
+	if err != nil {
+		return err
+	}
+	reader, err := cloudcmd.NewJoinLogReader(proxyDialer.HTTPProxy)
+	if err != nil {
+		return err
+	}
+
+	return joinLog(cmd, reader)
+}
+
+func joinLog(cmd *cobra.Command, reader joinLogReader) error {
+	since, err := cmd.Flags().GetDuration("since")
+	if err != nil {
+		return err
+	}
+	nodeName, err := cmd.Flags().GetString("node")
+	if err != nil {
+		return err
+	}
+	rejected, err := cmd.Flags().GetBool("rejected")
+	if err != nil {
+		return err
+	}
+
+	records, err := reader.JoinLog(cmd.Context())
+	if err != nil {
+		return fmt.Errorf("reading join log: %w", err)
+	}
+
+	w := tabwriter.NewWriter(cmd.OutOrStdout(), 0, 0, 2, ' ', 0)
+	fmt.Fprintln(w, "TIME\tREQUEST\tRESULT\tNODE\tROLE\tPEER\tIDENTITY\tDISK\tREASON")
+	for _, record := range records {
+		if since > 0 && record.Time.Time.Before(time.Now().Add(-since)) {
+			continue
+		}
+		if nodeName != "" && record.NodeName != nodeName {
+			continue
+		}
+		if rejected && record.Result == "Accepted" {
+			continue
+		}
+		role := "worker"
+		if record.IsControlPlane {
+			role = "control-plane"
+		}
+		reason := record.Reason
+		if record.Count > 1 {
+			// failed handshakes of a peer are aggregated into one record
+			reason = fmt.Sprintf("%s (%d times)", reason, record.Count)
+		}
+		fmt.Fprintf(w, "%s\t%s\t%s\t%s\t%s\t%s\t%s\t%s\t%s\n",
+			record.Time.Format(time.RFC3339), record.Request, record.Result, orNone(record.NodeName), role,
+			orNone(record.PeerAddress), orNone(record.AttestationIdentity), orNone(record.DiskUUID), orNone(reason))
+	}
+	return w.Flush()
+}
+
+// orNone returns s, or "-" if s is empty.
+func orNone(s string) string {
+	if s == "" {
+		return "-"
+	}
+	return s
+}
+
+type joinLogReader interface {
+	JoinLog(ctx context.Context) ([]cloudcmd.JoinRecord, error)
+}
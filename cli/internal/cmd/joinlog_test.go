@@ -0,0 +1,116 @@
+/*
+Copyright (c) Edgeless Systems GmbH
+
+SPDX-License-Identifier: AGPL-3.0-only
+*/
+
+package cmd
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/edgelesssys/constellation/v2/cli/internal/cloudcmd"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+func TestJoinLog(t *testing.T) {
+	now := time.Now()
+	records := []cloudcmd.JoinRecord{
+		{
+			Time: metav1.NewTime(now.Add(-48 * time.Hour)), Request: "IssueJoinTicket", Result: "Accepted",
+			NodeName: "worker-0", PeerAddress: "192.0.2.1:1234", AttestationIdentity: "ak:abcd", DiskUUID: "disk-0",
+		},
+		{
+			Time: metav1.NewTime(now.Add(-time.Hour)), Request: "IssueRejoinTicket", Result: "Denied",
+			PeerAddress: "192.0.2.2:1234", DiskUUID: "disk-1", Reason: "node was revoked",
+		},
+		{
+			Time: metav1.NewTime(now.Add(-time.Minute)), Request: "RenewKubeletCertificate", Result: "Accepted",
+			NodeName: "control-plane-0", IsControlPlane: true,
+		},
+	}
+
+	testCases := map[string]struct {
+		flags     map[string]string
+		reader    stubJoinLogReader
+		wantLines []string
+		wantErr   bool
+	}{
+		"all records": {
+			reader:    stubJoinLogReader{records: records},
+			wantLines: []string{"worker-0", "node was revoked", "control-plane-0"},
+		},
+		"since": {
+			flags:     map[string]string{"since": "2h"},
+			reader:    stubJoinLogReader{records: records},
+			wantLines: []string{"node was revoked", "control-plane-0"},
+		},
+		"node": {
+			flags:     map[string]string{"node": "worker-0"},
+			reader:    stubJoinLogReader{records: records},
+			wantLines: []string{"worker-0"},
+		},
+		"rejected": {
+			flags:     map[string]string{"rejected": "true"},
+			reader:    stubJoinLogReader{records: records},
+			wantLines: []string{"node was revoked"},
+		},
+		"aggregated handshakes": {
+			reader: stubJoinLogReader{records: []cloudcmd.JoinRecord{{
+				Time: metav1.NewTime(now), Request: "Handshake", Result: "Denied",
+				PeerAddress: "192.0.2.3", Reason: "aTLS handshake failed", Count: 42,
+			}}},
+			wantLines: []string{"aTLS handshake failed (42 times)"},
+		},
+		"read fails": {
+			reader:  stubJoinLogReader{err: errors.New("failed")},
+			wantErr: true,
+		},
+	}
+
+	for name, tc := range testCases {
+		t.Run(name, func(t *testing.T) {
+			assert := assert.New(t)
+			require := require.New(t)
+
+			cmd := NewJoinLogCmd()
+			out := &bytes.Buffer{}
+			cmd.SetOut(out)
+			cmd.SetErr(&bytes.Buffer{})
+			cmd.SetContext(context.Background())
+			for flag, value := range tc.flags {
+				require.NoError(cmd.Flags().Set(flag, value))
+			}
+
+			err := joinLog(cmd, tc.reader)
+			if tc.wantErr {
+				assert.Error(err)
+				return
+			}
+			require.NoError(err)
+
+			lines := strings.Split(strings.TrimSpace(out.String()), "\n")
+			require.Len(lines, len(tc.wantLines)+1)
+			assert.True(strings.HasPrefix(lines[0], "TIME"))
+			for i, want := range tc.wantLines {
+				assert.Contains(lines[i+1], want)
+			}
+		})
+	}
+}
+
+type stubJoinLogReader struct {
+	records []cloudcmd.JoinRecord
+	err     error
+}
+
+func (s stubJoinLogReader) JoinLog(context.Context) ([]cloudcmd.JoinRecord, error) {
+	return s.records, s.err
+}
@@ -0,0 +1,25 @@
+/*
+Copyright (c) Edgeless Systems GmbH
+
+SPDX-License-Identifier: AGPL-3.0-only
+*/
+
+package cmd
+
+import (
+	"github.com/spf13/cobra"
+)
+
+// NewNodeCmd returns a new cobra.Command for the node command.
+func NewNodeCmd() *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "node",
+		Short: "Manage the nodes of a Constellation cluster",
+		Long:  "Manage the nodes of a Constellation cluster.",
+		Args:  cobra.ExactArgs(0),
+	}
+
+	cmd.AddCommand(newNodeRevokeCmd())
+
+	return cmd
+}
@@ -0,0 +1,76 @@
+/*
+Copyright (c) Edgeless Systems GmbH
+
+SPDX-License-Identifier: AGPL-3.0-only
+*/
+
+package cmd
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/edgelesssys/constellation/v2/cli/internal/cloudcmd"
+	"github.com/spf13/cobra"
+)
+
+func newNodeRevokeCmd() *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "revoke NODE",
+		Short: "Revoke a node and remove it from the cluster",
+		Long: "Revoke a node and remove it from the cluster.\n\n" +
+			"The node is added to the revocation list of the JoinService, which denies the node, its attested identity, and its state disks to join or rejoin the cluster. " +
+			"Control-plane nodes are removed from etcd. Finally, the node is deleted from Kubernetes.\n" +
+			"Revoking a node doesn't terminate its instance, which can register the node again using its kubelet credentials. Remove the instance from its scaling group with your cloud provider right after revoking the node.",
+		Args: cobra.ExactArgs(1),
+		RunE: runNodeRevoke,
+	}
+	cmd.Flags().String("reason", "", "reason for revoking the node, recorded in the revocation list")
+	cmd.Flags().BoolP("yes", "y", false, "revoke the node without further confirmation")
+	return cmd
+}
+
+func runNodeRevoke(cmd *cobra.Command, args []string) error {
+	proxyDialer, err := newProxyDialer(cmd)
+	if err != nil {
+		return err
+	}
+	revoker, err := cloudcmd.NewRevoker(cmd.OutOrStdout(), proxyDialer.HTTPProxy)
+	if err != nil {
+		return err
+	}
+
+	return nodeRevoke(cmd, args[0], revoker)
+}
+
+func nodeRevoke(cmd *cobra.Command, nodeName string, revoker nodeRevoker) error {
+	reason, err := cmd.Flags().GetString("reason")
+	if err != nil {
+		return err
+	}
+	yes, err := cmd.Flags().GetBool("yes")
+	if err != nil {
+		return err
+	}
+
+	if !yes {
+		ok, err := askToConfirm(cmd, fmt.Sprintf("Node %s will be denied to rejoin and deleted from the cluster. Do you want to continue?", nodeName))
+		if err != nil {
+			return err
+		}
+		if !ok {
+			cmd.Println("The revocation was aborted.")
+			return nil
+		}
+	}
+
+	if err := revoker.Revoke(cmd.Context(), nodeName, reason); err != nil {
+		return fmt.Errorf("revoking node %s: %w", nodeName, err)
+	}
+	cmd.Printf("Node %s was revoked successfully.\n", nodeName)
+	return nil
+}
+
+type nodeRevoker interface {
+	Revoke(ctx context.Context, nodeName, reason string) error
+}
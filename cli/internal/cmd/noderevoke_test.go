@@ -0,0 +1,89 @@
+/*
+Copyright (c) Edgeless Systems GmbH
+
+SPDX-License-Identifier: AGPL-3.0-only
+*/
+
+package cmd
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestNodeRevoke(t *testing.T) {
+	testCases := map[string]struct {
+		yes         bool
+		stdin       string
+		revoker     *stubNodeRevoker
+		wantRevoked bool
+		wantErr     bool
+	}{
+		"revoke with confirmation": {
+			stdin:       "y\n",
+			revoker:     &stubNodeRevoker{},
+			wantRevoked: true,
+		},
+		"revoke without confirmation": {
+			yes:         true,
+			revoker:     &stubNodeRevoker{},
+			wantRevoked: true,
+		},
+		"abort": {
+			stdin:   "n\n",
+			revoker: &stubNodeRevoker{},
+		},
+		"revoke fails": {
+			yes:         true,
+			revoker:     &stubNodeRevoker{err: errors.New("failed")},
+			wantRevoked: true,
+			wantErr:     true,
+		},
+	}
+
+	for name, tc := range testCases {
+		t.Run(name, func(t *testing.T) {
+			assert := assert.New(t)
+			require := require.New(t)
+
+			cmd := newNodeRevokeCmd()
+			cmd.SetIn(bytes.NewBufferString(tc.stdin))
+			cmd.SetOut(&bytes.Buffer{})
+			cmd.SetErr(&bytes.Buffer{})
+			require.NoError(cmd.Flags().Set("reason", "compromised"))
+			if tc.yes {
+				require.NoError(cmd.Flags().Set("yes", "true"))
+			}
+
+			err := nodeRevoke(cmd, "worker-0", tc.revoker)
+			if tc.wantErr {
+				assert.Error(err)
+			} else {
+				assert.NoError(err)
+			}
+			if tc.wantRevoked {
+				assert.Equal("worker-0", tc.revoker.nodeName)
+				assert.Equal("compromised", tc.revoker.reason)
+			} else {
+				assert.Empty(tc.revoker.nodeName)
+			}
+		})
+	}
+}
+
+type stubNodeRevoker struct {
+	nodeName string
+	reason   string
+	err      error
+}
+
+func (s *stubNodeRevoker) Revoke(_ context.Context, nodeName, reason string) error {
+	s.nodeName = nodeName
+	s.reason = reason
+	return s.err
+}
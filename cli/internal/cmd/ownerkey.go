@@ -0,0 +1,55 @@
+/*
+Copyright (c) Edgeless Systems GmbH
+
+SPDX-License-Identifier: AGPL-3.0-only
+*/
+
+package cmd
+
+import (
+	"context"
+	"crypto"
+	"errors"
+	"fmt"
+	"os"
+
+	"github.com/edgelesssys/constellation/v2/internal/file"
+	"github.com/sigstore/sigstore/pkg/cryptoutils"
+)
+
+// cosignPasswordEnv is the environment variable holding the password of an encrypted cosign key, as used by cosign.
+const cosignPasswordEnv = "COSIGN_PASSWORD"
+
+// readOwnerKey reads the cluster owner's private key, as generated by `cosign generate-key-pair`.
+// Encrypted keys are decrypted using the password in the COSIGN_PASSWORD environment variable.
+func readOwnerKey(fileHandler file.Handler, path string) (crypto.Signer, error) {
+	keyPEM, err := fileHandler.Read(path)
+	if err != nil {
+		return nil, fmt.Errorf("reading owner key: %w", err)
+	}
+	key, err := cryptoutils.UnmarshalPEMToPrivateKey(keyPEM, func(bool) ([]byte, error) {
+		return []byte(os.Getenv(cosignPasswordEnv)), nil
+	})
+	if err != nil {
+		return nil, fmt.Errorf("decoding owner key %s (is %s set?): %w", path, cosignPasswordEnv, err)
+	}
+	signer, ok := key.(crypto.Signer)
+	if !ok {
+		return nil, errors.New("owner key can't be used for signing")
+	}
+	return signer, nil
+}
+
+// ownerPublicKey returns the PEM encoded public key of the owner key.
+func ownerPublicKey(key crypto.Signer) ([]byte, error) {
+	publicKey, err := cryptoutils.MarshalPublicKeyToPEM(key.Public())
+	if err != nil {
+		return nil, fmt.Errorf("marshaling owner public key: %w", err)
+	}
+	return publicKey, nil
+}
+
+// joinConfigSigner signs the join-config of a cluster with the owner key.
+type joinConfigSigner interface {
+	Sign(ctx context.Context, values map[string]string, key crypto.Signer) error
+}
@@ -0,0 +1,87 @@
+/*
+Copyright (c) Edgeless Systems GmbH
+
+SPDX-License-Identifier: AGPL-3.0-only
+*/
+
+package cmd
+
+import (
+	"crypto/elliptic"
+	"testing"
+
+	"github.com/edgelesssys/constellation/v2/internal/constants"
+	"github.com/edgelesssys/constellation/v2/internal/file"
+	"github.com/sigstore/sigstore/pkg/cryptoutils"
+	"github.com/spf13/afero"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestReadOwnerKey(t *testing.T) {
+	plainKey, plainPublicKey := newOwnerKey(t, nil)
+	encryptedKey, encryptedPublicKey := newOwnerKey(t, []byte("password"))
+
+	testCases := map[string]struct {
+		keyPEM        []byte
+		password      string
+		wantPublicKey []byte
+		wantErr       bool
+	}{
+		"unencrypted key": {
+			keyPEM:        plainKey,
+			wantPublicKey: plainPublicKey,
+		},
+		"encrypted cosign key": {
+			keyPEM:        encryptedKey,
+			password:      "password",
+			wantPublicKey: encryptedPublicKey,
+		},
+		"wrong password": {
+			keyPEM:   encryptedKey,
+			password: "wrong",
+			wantErr:  true,
+		},
+		"missing key": {
+			wantErr: true,
+		},
+		"invalid key": {
+			keyPEM:  []byte("invalid"),
+			wantErr: true,
+		},
+	}
+
+	for name, tc := range testCases {
+		t.Run(name, func(t *testing.T) {
+			assert := assert.New(t)
+			require := require.New(t)
+
+			t.Setenv(cosignPasswordEnv, tc.password)
+			fileHandler := file.NewHandler(afero.NewMemMapFs())
+			if tc.keyPEM != nil {
+				require.NoError(fileHandler.Write(constants.OwnerKeyFilename, tc.keyPEM))
+			}
+
+			key, err := readOwnerKey(fileHandler, constants.OwnerKeyFilename)
+			if tc.wantErr {
+				assert.Error(err)
+				return
+			}
+			require.NoError(err)
+			publicKey, err := ownerPublicKey(key)
+			require.NoError(err)
+			assert.Equal(tc.wantPublicKey, publicKey)
+		})
+	}
+}
+
+// newOwnerKey returns a PEM encoded owner key pair, encrypted with password if it is not nil.
+func newOwnerKey(t *testing.T, password []byte) (privateKey, publicKey []byte) {
+	var passFunc cryptoutils.PassFunc
+	if password != nil {
+		passFunc = cryptoutils.StaticPasswordFunc(password)
+	}
+	privateKey, publicKey, err := cryptoutils.GeneratePEMEncodedECDSAKeyPair(elliptic.P256(), passFunc)
+	require.NoError(t, err)
+	return privateKey, publicKey
+}
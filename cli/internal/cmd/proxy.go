@@ -0,0 +1,24 @@
+/*
+Copyright (c) Edgeless Systems GmbH
+
+SPDX-License-Identifier: AGPL-3.0-only
+*/
+
+package cmd
+
+import (
+	"net"
+
+	"github.com/edgelesssys/constellation/v2/internal/proxy"
+	"github.com/spf13/cobra"
+)
+
+// newProxyDialer returns a dialer for connections to the cluster.
+// Connections use the proxy set with the --proxy flag, or the proxy configured in the environment.
+func newProxyDialer(cmd *cobra.Command) (*proxy.Dialer, error) {
+	proxyURL, err := cmd.Flags().GetString("proxy")
+	if err != nil {
+		return nil, err
+	}
+	return proxy.New(proxyURL, &net.Dialer{})
+}
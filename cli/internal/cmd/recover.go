@@ -11,7 +11,6 @@ import (
 	"errors"
 	"fmt"
 	"io"
-	"net"
 	"sync"
 	"time"
 
@@ -46,8 +45,12 @@ func NewRecoverCmd() *cobra.Command {
 
 func runRecover(cmd *cobra.Command, _ []string) error {
 	fileHandler := file.NewHandler(afero.NewOsFs())
+	netDialer, err := newProxyDialer(cmd)
+	if err != nil {
+		return err
+	}
 	newDialer := func(validator *cloudcmd.Validator) *dialer.Dialer {
-		return dialer.New(nil, validator.V(cmd), &net.Dialer{})
+		return dialer.New(nil, validator.V(cmd), netDialer)
 	}
 	return recover(cmd, fileHandler, 5*time.Second, &recoverDoer{}, newDialer)
 }
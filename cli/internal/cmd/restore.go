@@ -0,0 +1,102 @@
+/*
+Copyright (c) Edgeless Systems GmbH
+
+SPDX-License-Identifier: AGPL-3.0-only
+*/
+
+package cmd
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+
+	"github.com/edgelesssys/constellation/v2/cli/internal/cloudcmd"
+	"github.com/edgelesssys/constellation/v2/internal/constants"
+	"github.com/edgelesssys/constellation/v2/internal/crypto"
+	"github.com/edgelesssys/constellation/v2/internal/file"
+	"github.com/spf13/afero"
+	"github.com/spf13/cobra"
+)
+
+// NewRestoreCmd returns a new cobra.Command for the restore command.
+func NewRestoreCmd() *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "restore SNAPSHOT",
+		Short: "Restore the Kubernetes state of a Constellation from an etcd snapshot",
+		Long: "Restore the Kubernetes state of a Constellation from an encrypted etcd snapshot uploaded by an EtcdBackup.\n\n" +
+			"The snapshot is decrypted with a key derived from the master secret of the cluster that took the snapshot. " +
+			"The target cluster must be initialized with the same master secret and must have exactly one control-plane node. " +
+			"All Kubernetes resources of the target cluster are replaced by the state stored in the snapshot.",
+		Args: cobra.ExactArgs(1),
+		RunE: runRestore,
+	}
+	cmd.Flags().String("master-secret", constants.MasterSecretFilename, "path to master secret file")
+	cmd.Flags().BoolP("yes", "y", false, "restore the snapshot without further confirmation")
+	return cmd
+}
+
+func runRestore(cmd *cobra.Command, args []string) error {
+	fileHandler := file.NewHandler(afero.NewOsFs())
+	proxyDialer, err := newProxyDialer(cmd)
+	if err != nil {
+		return err
+	}
+	restorer, err := cloudcmd.NewRestorer(cmd.OutOrStdout(), proxyDialer.HTTPProxy)
+	if err != nil {
+		return err
+	}
+	return restore(cmd, args[0], fileHandler, restorer)
+}
+
+func restore(cmd *cobra.Command, snapshotPath string, fileHandler file.Handler, restorer etcdRestorer) error {
+	secretPath, err := cmd.Flags().GetString("master-secret")
+	if err != nil {
+		return fmt.Errorf("parsing master-secret path argument: %w", err)
+	}
+	yes, err := cmd.Flags().GetBool("yes")
+	if err != nil {
+		return err
+	}
+
+	var masterSecret masterSecret
+	if err := fileHandler.ReadJSON(secretPath, &masterSecret); err != nil {
+		return fmt.Errorf("reading master secret: %w", err)
+	}
+	encryptedSnapshot, err := fileHandler.Read(snapshotPath)
+	if err != nil {
+		return fmt.Errorf("reading snapshot: %w", err)
+	}
+	// the node operator requests the key from the KMS, which derives it from the master secret
+	key, err := crypto.DeriveKey(masterSecret.Key, masterSecret.Salt, []byte(crypto.HKDFInfoPrefix+constants.EtcdBackupKeyID), constants.EtcdBackupKeyLength)
+	if err != nil {
+		return fmt.Errorf("deriving snapshot encryption key: %w", err)
+	}
+	// the snapshot is fully decrypted and authenticated before the cluster is modified
+	snapshotName, snapshot, err := cloudcmd.DecryptEtcdSnapshot(key, bytes.NewReader(encryptedSnapshot))
+	if err != nil {
+		return fmt.Errorf("decrypting snapshot: %w", err)
+	}
+	cmd.Printf("Decrypted snapshot %s.\n", snapshotName)
+
+	if !yes {
+		ok, err := askToConfirm(cmd, "All Kubernetes resources of the cluster will be replaced by the snapshot. Do you want to continue?")
+		if err != nil {
+			return err
+		}
+		if !ok {
+			cmd.Println("The restore was aborted.")
+			return nil
+		}
+	}
+
+	if err := restorer.Restore(cmd.Context(), snapshot); err != nil {
+		return fmt.Errorf("restoring snapshot: %w", err)
+	}
+	cmd.Println("Snapshot restored successfully.")
+	return nil
+}
+
+type etcdRestorer interface {
+	Restore(ctx context.Context, snapshot []byte) error
+}
@@ -0,0 +1,152 @@
+/*
+Copyright (c) Edgeless Systems GmbH
+
+SPDX-License-Identifier: AGPL-3.0-only
+*/
+
+package cmd
+
+import (
+	"bytes"
+	"context"
+	"crypto/aes"
+	"crypto/cipher"
+	"errors"
+	"testing"
+
+	"github.com/edgelesssys/constellation/v2/internal/constants"
+	"github.com/edgelesssys/constellation/v2/internal/crypto"
+	"github.com/edgelesssys/constellation/v2/internal/file"
+	"github.com/spf13/afero"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestRestore(t *testing.T) {
+	secret := masterSecret{Key: bytes.Repeat([]byte{0x01}, 32), Salt: bytes.Repeat([]byte{0x02}, 32)}
+	otherSecret := masterSecret{Key: bytes.Repeat([]byte{0x03}, 32), Salt: bytes.Repeat([]byte{0x02}, 32)}
+
+	testCases := map[string]struct {
+		secret       *masterSecret
+		snapshot     []byte
+		yes          bool
+		stdin        string
+		restorer     *stubEtcdRestorer
+		wantRestored bool
+		wantErr      bool
+	}{
+		"restore with confirmation": {
+			secret:       &secret,
+			snapshot:     encryptSnapshotForTest(t, secret, []byte("snapshot")),
+			stdin:        "y\n",
+			restorer:     &stubEtcdRestorer{},
+			wantRestored: true,
+		},
+		"restore without confirmation": {
+			secret:       &secret,
+			snapshot:     encryptSnapshotForTest(t, secret, []byte("snapshot")),
+			yes:          true,
+			restorer:     &stubEtcdRestorer{},
+			wantRestored: true,
+		},
+		"abort": {
+			secret:   &secret,
+			snapshot: encryptSnapshotForTest(t, secret, []byte("snapshot")),
+			stdin:    "n\n",
+			restorer: &stubEtcdRestorer{},
+		},
+		"snapshot of a different master secret": {
+			secret:   &secret,
+			snapshot: encryptSnapshotForTest(t, otherSecret, []byte("snapshot")),
+			yes:      true,
+			restorer: &stubEtcdRestorer{},
+			wantErr:  true,
+		},
+		"missing master secret": {
+			snapshot: encryptSnapshotForTest(t, secret, []byte("snapshot")),
+			yes:      true,
+			restorer: &stubEtcdRestorer{},
+			wantErr:  true,
+		},
+		"missing snapshot": {
+			secret:   &secret,
+			yes:      true,
+			restorer: &stubEtcdRestorer{},
+			wantErr:  true,
+		},
+		"restore fails": {
+			secret:       &secret,
+			snapshot:     encryptSnapshotForTest(t, secret, []byte("snapshot")),
+			yes:          true,
+			restorer:     &stubEtcdRestorer{err: errors.New("failed")},
+			wantRestored: true,
+			wantErr:      true,
+		},
+	}
+
+	for name, tc := range testCases {
+		t.Run(name, func(t *testing.T) {
+			assert := assert.New(t)
+			require := require.New(t)
+
+			cmd := NewRestoreCmd()
+			cmd.SetContext(context.Background())
+			cmd.SetIn(bytes.NewBufferString(tc.stdin))
+			cmd.SetOut(&bytes.Buffer{})
+			cmd.SetErr(&bytes.Buffer{})
+			if tc.yes {
+				require.NoError(cmd.Flags().Set("yes", "true"))
+			}
+
+			fileHandler := file.NewHandler(afero.NewMemMapFs())
+			if tc.secret != nil {
+				require.NoError(fileHandler.WriteJSON(constants.MasterSecretFilename, tc.secret, file.OptNone))
+			}
+			if tc.snapshot != nil {
+				require.NoError(fileHandler.Write("snapshot.enc", tc.snapshot, file.OptNone))
+			}
+
+			err := restore(cmd, "snapshot.enc", fileHandler, tc.restorer)
+			if tc.wantErr {
+				assert.Error(err)
+			} else {
+				assert.NoError(err)
+			}
+			if tc.wantRestored {
+				assert.Equal([]byte("snapshot"), tc.restorer.snapshot)
+			} else {
+				assert.Nil(tc.restorer.snapshot)
+			}
+		})
+	}
+}
+
+func encryptSnapshotForTest(t *testing.T, secret masterSecret, snapshot []byte) []byte {
+	dataKey, err := crypto.DeriveKey(secret.Key, secret.Salt, []byte(crypto.HKDFInfoPrefix+constants.EtcdBackupKeyID), constants.EtcdBackupKeyLength)
+	require.NoError(t, err)
+	// a single chunk snapshot in the format of the node operator, see cloudcmd.DecryptEtcdSnapshot
+	name := "etcd-snapshot-20220810T030000Z.enc"
+	salt := bytes.Repeat([]byte{0x04}, 32)
+	header := append([]byte{1}, salt...)
+	header = append(header, 0, byte(len(name)))
+	header = append(header, name...)
+	key, err := crypto.DeriveKey(dataKey, salt, []byte("constellation etcd backup v1"), 32)
+	require.NoError(t, err)
+	block, err := aes.NewCipher(key)
+	require.NoError(t, err)
+	aead, err := cipher.NewGCM(block)
+	require.NoError(t, err)
+	nonce := make([]byte, aead.NonceSize())
+	nonce[11] = 1
+	return aead.Seal(header, nonce, snapshot, header)
+}
+
+type stubEtcdRestorer struct {
+	snapshot []byte
+	err      error
+}
+
+func (s *stubEtcdRestorer) Restore(_ context.Context, snapshot []byte) error {
+	s.snapshot = snapshot
+	return s.err
+}
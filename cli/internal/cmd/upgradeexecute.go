@@ -8,10 +8,13 @@ package cmd
 
 import (
 	"context"
+	"crypto"
 
 	"github.com/edgelesssys/constellation/v2/cli/internal/cloudcmd"
 	"github.com/edgelesssys/constellation/v2/internal/config"
+	"github.com/edgelesssys/constellation/v2/internal/constants"
 	"github.com/edgelesssys/constellation/v2/internal/file"
+	"github.com/edgelesssys/constellation/v2/internal/versions"
 	"github.com/spf13/afero"
 	"github.com/spf13/cobra"
 )
@@ -24,13 +27,18 @@ func newUpgradeExecuteCmd() *cobra.Command {
 		Args:  cobra.NoArgs,
 		RunE:  runUpgradeExecute,
 	}
+	cmd.Flags().String("owner-key", constants.OwnerKeyFilename, "path to the cosign private key of the cluster owner, used to sign the measurements")
 
 	return cmd
 }
 
 func runUpgradeExecute(cmd *cobra.Command, args []string) error {
 	fileHandler := file.NewHandler(afero.NewOsFs())
-	upgrader, err := cloudcmd.NewUpgrader(cmd.OutOrStdout())
+	proxyDialer, err := newProxyDialer(cmd)
+	if err != nil {
+		return err
+	}
+	upgrader, err := cloudcmd.NewUpgrader(cmd.OutOrStdout(), proxyDialer.HTTPProxy)
 	if err != nil {
 		return err
 	}
@@ -47,13 +55,38 @@ func upgradeExecute(cmd *cobra.Command, upgrader cloudUpgrader, fileHandler file
 	if err != nil {
 		return err
 	}
+	ownerKeyPath, err := cmd.Flags().GetString("owner-key")
+	if err != nil {
+		return err
+	}
+	signingKey, err := readOwnerKey(fileHandler, ownerKeyPath)
+	if err != nil {
+		return err
+	}
 
 	// TODO: validate upgrade config? Should be basic things like checking image is not an empty string
 	// More sophisticated validation, like making sure we don't downgrade the cluster, should be done by `constellation upgrade plan`
 
-	return upgrader.Upgrade(cmd.Context(), config.Upgrade.Image, config.Upgrade.Measurements)
+	// the join-config is created from the local config only, values changed in the cluster are never signed
+	joinConfig, err := cloudcmd.JoinConfigValues(config, config.Upgrade.Measurements)
+	if err != nil {
+		return err
+	}
+	if err := upgrader.Upgrade(cmd.Context(), config.Upgrade.Image, joinConfig, signingKey); err != nil {
+		return err
+	}
+
+	if config.Upgrade.KubernetesVersion == "" {
+		return nil
+	}
+	k8sVersion, err := versions.NewValidK8sVersion(config.Upgrade.KubernetesVersion)
+	if err != nil {
+		return err
+	}
+	return upgrader.UpgradeKubernetes(cmd.Context(), k8sVersion)
 }
 
 type cloudUpgrader interface {
-	Upgrade(ctx context.Context, image string, measurements map[uint32][]byte) error
+	Upgrade(ctx context.Context, image string, joinConfig map[string]string, signingKey crypto.Signer) error
+	UpgradeKubernetes(ctx context.Context, k8sVersion versions.ValidK8sVersion) error
 }
@@ -8,12 +8,14 @@ package cmd
 
 import (
 	"context"
+	"crypto"
 	"errors"
 	"testing"
 
 	"github.com/edgelesssys/constellation/v2/internal/config"
 	"github.com/edgelesssys/constellation/v2/internal/constants"
 	"github.com/edgelesssys/constellation/v2/internal/file"
+	"github.com/edgelesssys/constellation/v2/internal/versions"
 	"github.com/spf13/afero"
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/require"
@@ -21,16 +23,40 @@ import (
 
 func TestUpgradeExecute(t *testing.T) {
 	testCases := map[string]struct {
-		upgrader stubUpgrader
-		wantErr  bool
+		upgrader          *stubUpgrader
+		kubernetesVersion string
+		noOwnerKey        bool
+		wantK8sVersion    versions.ValidK8sVersion
+		wantErr           bool
 	}{
 		"success": {
-			upgrader: stubUpgrader{},
+			upgrader: &stubUpgrader{},
 		},
 		"upgrade error": {
-			upgrader: stubUpgrader{err: errors.New("error")},
+			upgrader: &stubUpgrader{err: errors.New("error")},
 			wantErr:  true,
 		},
+		"missing owner key": {
+			upgrader:   &stubUpgrader{},
+			noOwnerKey: true,
+			wantErr:    true,
+		},
+		"kubernetes upgrade": {
+			upgrader:          &stubUpgrader{},
+			kubernetesVersion: string(versions.V1_24),
+			wantK8sVersion:    versions.V1_24,
+		},
+		"kubernetes upgrade error": {
+			upgrader:          &stubUpgrader{k8sErr: errors.New("error")},
+			kubernetesVersion: string(versions.V1_24),
+			wantK8sVersion:    versions.V1_24,
+			wantErr:           true,
+		},
+		"invalid kubernetes version": {
+			upgrader:          &stubUpgrader{},
+			kubernetesVersion: "1.0",
+			wantErr:           true,
+		},
 	}
 
 	for name, tc := range testCases {
@@ -41,7 +67,13 @@ func TestUpgradeExecute(t *testing.T) {
 			cmd.Flags().String("config", constants.ConfigFilename, "") // register persistent flag manually
 
 			handler := file.NewHandler(afero.NewMemMapFs())
-			require.NoError(handler.WriteYAML(constants.ConfigFilename, config.Default()))
+			cfg := config.Default()
+			cfg.Upgrade.KubernetesVersion = tc.kubernetesVersion
+			require.NoError(handler.WriteYAML(constants.ConfigFilename, cfg))
+			if !tc.noOwnerKey {
+				ownerKey, _ := newOwnerKey(t, nil)
+				require.NoError(handler.Write(constants.OwnerKeyFilename, ownerKey))
+			}
 
 			err := upgradeExecute(cmd, tc.upgrader, handler)
 			if tc.wantErr {
@@ -49,14 +81,22 @@ func TestUpgradeExecute(t *testing.T) {
 			} else {
 				assert.NoError(err)
 			}
+			assert.Equal(tc.wantK8sVersion, tc.upgrader.k8sVersion)
 		})
 	}
 }
 
 type stubUpgrader struct {
-	err error
+	err        error
+	k8sErr     error
+	k8sVersion versions.ValidK8sVersion
 }
 
-func (u stubUpgrader) Upgrade(context.Context, string, map[uint32][]byte) error {
+func (u *stubUpgrader) Upgrade(context.Context, string, map[string]string, crypto.Signer) error {
 	return u.err
 }
+
+func (u *stubUpgrader) UpgradeKubernetes(_ context.Context, k8sVersion versions.ValidK8sVersion) error {
+	u.k8sVersion = k8sVersion
+	return u.k8sErr
+}
@@ -14,6 +14,7 @@ import (
 	"net/http"
 	"net/url"
 	"regexp"
+	"sort"
 	"strings"
 
 	"github.com/edgelesssys/constellation/v2/cli/internal/cloudcmd"
@@ -21,6 +22,7 @@ import (
 	"github.com/edgelesssys/constellation/v2/internal/config"
 	"github.com/edgelesssys/constellation/v2/internal/constants"
 	"github.com/edgelesssys/constellation/v2/internal/file"
+	"github.com/edgelesssys/constellation/v2/internal/versions"
 	"github.com/manifoldco/promptui"
 	"github.com/spf13/afero"
 	"github.com/spf13/cobra"
@@ -52,11 +54,15 @@ func newUpgradePlanCmd() *cobra.Command {
 
 func runUpgradePlan(cmd *cobra.Command, args []string) error {
 	fileHandler := file.NewHandler(afero.NewOsFs())
+	proxyDialer, err := newProxyDialer(cmd)
+	if err != nil {
+		return err
+	}
 	flags, err := parseUpgradePlanFlags(cmd)
 	if err != nil {
 		return err
 	}
-	planner, err := cloudcmd.NewUpgrader(cmd.OutOrStdout())
+	planner, err := cloudcmd.NewUpgrader(cmd.OutOrStdout(), proxyDialer.HTTPProxy)
 	if err != nil {
 		return err
 	}
@@ -87,8 +93,16 @@ func upgradePlan(cmd *cobra.Command, planner upgradePlanner,
 		return fmt.Errorf("fetching available images: %w", err)
 	}
 	compatibleImages := getCompatibleImages(csp, version, images)
-	if len(compatibleImages) == 0 {
-		cmd.Println("No compatible images found to upgrade to.")
+
+	// get Kubernetes versions the cluster can be upgraded to
+	k8sVersion, err := planner.GetCurrentKubernetesVersion(cmd.Context())
+	if err != nil {
+		return fmt.Errorf("checking current Kubernetes version: %w", err)
+	}
+	compatibleK8sVersions := getCompatibleKubernetesVersions(k8sVersion)
+
+	if len(compatibleImages) == 0 && len(compatibleK8sVersions) == 0 {
+		cmd.Println("No compatible images or Kubernetes versions found to upgrade to.")
 		return nil
 	}
 
@@ -100,14 +114,21 @@ func upgradePlan(cmd *cobra.Command, planner upgradePlanner,
 	// interactive mode
 	if flags.filePath == "" {
 		fmt.Fprintf(cmd.OutOrStdout(), "Current version: %s\n", version)
+		fmt.Fprintf(cmd.OutOrStdout(), "Current Kubernetes version: %s\n", k8sVersion)
 		return upgradePlanInteractive(
 			&nopWriteCloser{cmd.OutOrStdout()},
 			io.NopCloser(cmd.InOrStdin()),
 			flags.configPath, config, fileHandler,
-			compatibleImages,
+			compatibleImages, compatibleK8sVersions,
 		)
 	}
 
+	// the upgrade plan only lists images, Kubernetes versions are set directly in the config
+	if len(compatibleK8sVersions) > 0 {
+		fmt.Fprintf(cmd.ErrOrStderr(), "Compatible Kubernetes versions: %s\n", strings.Join(compatibleK8sVersions, ", "))
+		fmt.Fprintln(cmd.ErrOrStderr(), "Set upgrade.kubernetesVersion in your config to upgrade Kubernetes.")
+	}
+
 	// write upgrade plan to stdout
 	if flags.filePath == "-" {
 		content, err := encoder.NewEncoder(compatibleImages).Encode()
@@ -173,6 +194,18 @@ func getCompatibleImages(csp cloudprovider.Provider, currentVersion string, imag
 	return compatibleImages
 }
 
+// getCompatibleKubernetesVersions returns the supported Kubernetes versions a cluster running currentVersion can be upgraded to.
+func getCompatibleKubernetesVersions(currentVersion string) []string {
+	var compatibleVersions []string
+	for k8sVersion, versionConfig := range versions.VersionConfigs {
+		if cloudcmd.CheckKubernetesUpgrade(currentVersion, "v"+versionConfig.PatchVersion) == nil {
+			compatibleVersions = append(compatibleVersions, string(k8sVersion))
+		}
+	}
+	sort.Strings(compatibleVersions)
+	return compatibleVersions
+}
+
 // getCompatibleImageMeasurements retrieves the expected measurements for each image.
 func getCompatibleImageMeasurements(ctx context.Context, client *http.Client, pubK []byte, images map[string]config.UpgradeConfig) error {
 	for idx, img := range images {
@@ -246,8 +279,29 @@ func parseUpgradePlanFlags(cmd *cobra.Command) (upgradePlanFlags, error) {
 
 func upgradePlanInteractive(out io.WriteCloser, in io.ReadCloser,
 	configPath string, config *config.Config, fileHandler file.Handler,
-	compatibleImages map[string]config.UpgradeConfig,
+	compatibleImages map[string]config.UpgradeConfig, compatibleK8sVersions []string,
 ) error {
+	if len(compatibleImages) > 0 {
+		upgrade, err := selectImageInteractive(out, in, compatibleImages)
+		if err != nil {
+			return err
+		}
+		config.Upgrade.Image = upgrade.Image
+		config.Upgrade.Measurements = upgrade.Measurements
+	}
+
+	if len(compatibleK8sVersions) > 0 {
+		k8sVersion, err := selectKubernetesVersionInteractive(out, in, compatibleK8sVersions)
+		if err != nil {
+			return err
+		}
+		config.Upgrade.KubernetesVersion = k8sVersion
+	}
+
+	return fileHandler.WriteYAML(configPath, config, file.OptOverwrite)
+}
+
+func selectImageInteractive(out io.WriteCloser, in io.ReadCloser, compatibleImages map[string]config.UpgradeConfig) (config.UpgradeConfig, error) {
 	var imageVersions []string
 	for k := range compatibleImages {
 		imageVersions = append(imageVersions, k)
@@ -270,7 +324,7 @@ func upgradePlanInteractive(out io.WriteCloser, in io.ReadCloser,
 
 	_, res, err := prompt.Run()
 	if err != nil {
-		return err
+		return config.UpgradeConfig{}, err
 	}
 
 	fmt.Fprintln(out, "Updating config to the following:")
@@ -279,13 +333,34 @@ func upgradePlanInteractive(out io.WriteCloser, in io.ReadCloser,
 	fmt.Fprintln(out, "Measurements:")
 	content, err := encoder.NewEncoder(compatibleImages[res].Measurements).Encode()
 	if err != nil {
-		return fmt.Errorf("encoding measurements: %w", err)
+		return config.UpgradeConfig{}, fmt.Errorf("encoding measurements: %w", err)
 	}
 	measurements := strings.TrimSuffix(strings.Replace("\t"+string(content), "\n", "\n\t", -1), "\n\t")
 	fmt.Fprintln(out, measurements)
 
-	config.Upgrade = compatibleImages[res]
-	return fileHandler.WriteYAML(configPath, config, file.OptOverwrite)
+	return compatibleImages[res], nil
+}
+
+func selectKubernetesVersionInteractive(out io.WriteCloser, in io.ReadCloser, compatibleK8sVersions []string) (string, error) {
+	const keepCurrent = "keep current version"
+	prompt := promptui.Select{
+		Label:  "Select a Kubernetes version to upgrade to",
+		Items:  append([]string{keepCurrent}, compatibleK8sVersions...),
+		Size:   10,
+		Stdin:  in,
+		Stdout: out,
+	}
+
+	_, res, err := prompt.Run()
+	if err != nil {
+		return "", err
+	}
+	if res == keepCurrent {
+		return "", nil
+	}
+
+	fmt.Fprintf(out, "Kubernetes version: %s\n", res)
+	return res, nil
 }
 
 type upgradePlanFlags struct {
@@ -307,4 +382,5 @@ func (c *nopWriteCloser) Close() error { return nil }
 
 type upgradePlanner interface {
 	GetCurrentImage(ctx context.Context) (*unstructured.Unstructured, string, error)
+	GetCurrentKubernetesVersion(ctx context.Context) (string, error)
 }
@@ -20,6 +20,7 @@ import (
 	"github.com/edgelesssys/constellation/v2/internal/config"
 	"github.com/edgelesssys/constellation/v2/internal/constants"
 	"github.com/edgelesssys/constellation/v2/internal/file"
+	"github.com/edgelesssys/constellation/v2/internal/versions"
 	"github.com/spf13/afero"
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/require"
@@ -93,14 +94,20 @@ func TestGetCurrentImageVersion(t *testing.T) {
 }
 
 type stubUpgradePlanner struct {
-	image string
-	err   error
+	image      string
+	err        error
+	k8sVersion string
+	k8sErr     error
 }
 
 func (u stubUpgradePlanner) GetCurrentImage(context.Context) (*unstructured.Unstructured, string, error) {
 	return nil, u.image, u.err
 }
 
+func (u stubUpgradePlanner) GetCurrentKubernetesVersion(context.Context) (string, error) {
+	return u.k8sVersion, u.k8sErr
+}
+
 func TestFetchImages(t *testing.T) {
 	testImages := map[string]imageManifest{
 		"v0.0.0": {
@@ -279,6 +286,32 @@ func TestGetCompatibleImageMeasurements(t *testing.T) {
 	}
 }
 
+func TestGetCompatibleKubernetesVersions(t *testing.T) {
+	testCases := map[string]struct {
+		currentVersion string
+		wantVersions   []string
+	}{
+		"patch and minor upgrade": {
+			currentVersion: "v1.23.0",
+			wantVersions:   []string{string(versions.V1_23), string(versions.V1_24)},
+		},
+		"latest version": {
+			currentVersion: "v" + versions.VersionConfigs[versions.V1_25].PatchVersion,
+		},
+		"invalid version": {
+			currentVersion: "invalid",
+		},
+	}
+
+	for name, tc := range testCases {
+		t.Run(name, func(t *testing.T) {
+			assert := assert.New(t)
+
+			assert.Equal(tc.wantVersions, getCompatibleKubernetesVersions(tc.currentVersion))
+		})
+	}
+}
+
 func TestUpgradePlan(t *testing.T) {
 	testImages := map[string]imageManifest{
 		"v1.0.0": {
@@ -302,7 +335,8 @@ func TestUpgradePlan(t *testing.T) {
 	}{
 		"no compatible images": {
 			planner: stubUpgradePlanner{
-				image: "projects/constellation-images/global/images/constellation-v999-999-999",
+				image:      "projects/constellation-images/global/images/constellation-v999-999-999",
+				k8sVersion: "v" + versions.VersionConfigs[versions.V1_25].PatchVersion,
 			},
 			imageFetchStatus:        http.StatusOK,
 			measurementsFetchStatus: http.StatusOK,
@@ -356,6 +390,21 @@ func TestUpgradePlan(t *testing.T) {
 			csp:         cloudprovider.GCP,
 			wantUpgrade: true,
 		},
+		"kubernetes version error": {
+			planner: stubUpgradePlanner{
+				image:  "projects/constellation-images/global/images/constellation-v1-0-0",
+				k8sErr: errors.New("error"),
+			},
+			imageFetchStatus:        http.StatusOK,
+			measurementsFetchStatus: http.StatusOK,
+			flags: upgradePlanFlags{
+				configPath:   constants.ConfigFilename,
+				filePath:     "upgrade-plan.yaml",
+				cosignPubKey: "-----BEGIN PUBLIC KEY-----\nMFkwEwYHKoZIzj0CAQYIKoZIzj0DAQcDQgAEUs5fDUIz9aiwrfr8BK4VjN7jE6sl\ngz7UuXsOin8+dB0SGrbNHy7TJToa2fAiIKPVLTOfvY75DqRAtffhO1fpBA==\n-----END PUBLIC KEY-----",
+			},
+			csp:     cloudprovider.GCP,
+			wantErr: true,
+		},
 		"current image not valid": {
 			planner: stubUpgradePlanner{
 				image: "not-valid",
@@ -18,6 +18,8 @@ import (
 
 	"github.com/edgelesssys/constellation/v2/cli/internal/cloudcmd"
 	"github.com/edgelesssys/constellation/v2/internal/atls"
+	"github.com/edgelesssys/constellation/v2/internal/attestation/eat"
+	"github.com/edgelesssys/constellation/v2/internal/attestation/policy"
 	"github.com/edgelesssys/constellation/v2/internal/constants"
 	"github.com/edgelesssys/constellation/v2/internal/crypto"
 	"github.com/edgelesssys/constellation/v2/internal/file"
@@ -28,6 +30,9 @@ import (
 	"google.golang.org/grpc"
 )
 
+// attestationTokenIssuer is the issuer of attestation result tokens signed by the CLI.
+const attestationTokenIssuer = "constellation-cli"
+
 // NewVerifyCmd returns a new cobra.Command for the verify command.
 func NewVerifyCmd() *cobra.Command {
 	cmd := &cobra.Command{
@@ -35,7 +40,10 @@ func NewVerifyCmd() *cobra.Command {
 		Short: "Verify the confidential properties of a Constellation cluster",
 		Long: `Verify the confidential properties of a Constellation cluster.
 
-If arguments aren't specified, values are read from ` + "`" + constants.ClusterIDsFileName + "`.",
+If arguments aren't specified, values are read from ` + "`" + constants.ClusterIDsFileName + "`." + `
+
+With --token-out, a signed attestation result token (EAT in JWT form) summarizing the verified claims is written to the given file.
+Services trusting the public key of --token-key can accept the token instead of verifying the node themselves.`,
 		Args: cobra.MatchAll(
 			cobra.ExactArgs(0),
 		),
@@ -43,12 +51,18 @@ If arguments aren't specified, values are read from ` + "`" + constants.ClusterI
 	}
 	cmd.Flags().String("cluster-id", "", "expected cluster identifier")
 	cmd.Flags().StringP("node-endpoint", "e", "", "endpoint of the node to verify, passed as HOST[:PORT]")
+	cmd.Flags().String("token-out", "", "write a signed attestation result token to the given file")
+	cmd.Flags().String("token-key", "", "PEM encoded P-256 private key to sign the attestation result token with")
 	return cmd
 }
 
 func runVerify(cmd *cobra.Command, args []string) error {
 	fileHandler := file.NewHandler(afero.NewOsFs())
-	verifyClient := &constellationVerifier{dialer: dialer.New(nil, nil, &net.Dialer{})}
+	netDialer, err := newProxyDialer(cmd)
+	if err != nil {
+		return err
+	}
+	verifyClient := &constellationVerifier{dialer: dialer.New(nil, nil, netDialer)}
 	return verify(cmd, fileHandler, verifyClient)
 }
 
@@ -82,22 +96,59 @@ func verify(cmd *cobra.Command, fileHandler file.Handler, verifyClient verifyCli
 		return err
 	}
 
-	if err := verifyClient.Verify(
+	validator := validators.V(cmd)
+	claims, err := verifyClient.Verify(
 		cmd.Context(),
 		flags.endpoint,
 		&verifyproto.GetAttestationRequest{
 			Nonce:    nonce,
 			UserData: userData,
 		},
-		validators.V(cmd),
-	); err != nil {
+		validator,
+	)
+	if err != nil {
 		return err
 	}
 
+	if flags.tokenOut != "" {
+		token, err := signAttestationResult(fileHandler, flags.tokenKey, eat.Appraisal{
+			Provider: provider.String(),
+			Variant:  validator.OID(),
+			Nonce:    nonce,
+			UserData: userData,
+			Claims:   claims,
+			Policy:   config.AttestationPolicy,
+		})
+		if err != nil {
+			return err
+		}
+		if err := fileHandler.Write(flags.tokenOut, []byte(token), file.OptOverwrite); err != nil {
+			return fmt.Errorf("writing attestation result token: %w", err)
+		}
+		cmd.Printf("Attestation result token written to %s\n", flags.tokenOut)
+	}
+
 	cmd.Println("OK")
 	return nil
 }
 
+// signAttestationResult issues an attestation result token for the appraisal, signed with the key at keyPath.
+func signAttestationResult(fileHandler file.Handler, keyPath string, appraisal eat.Appraisal) (string, error) {
+	keyRaw, err := fileHandler.Read(keyPath)
+	if err != nil {
+		return "", fmt.Errorf("reading token signing key: %w", err)
+	}
+	key, err := eat.ParseSigningKey(keyRaw)
+	if err != nil {
+		return "", fmt.Errorf("parsing token signing key: %w", err)
+	}
+	signer, err := eat.NewSigner(attestationTokenIssuer, key, eat.DefaultValidity)
+	if err != nil {
+		return "", err
+	}
+	return signer.Sign(appraisal)
+}
+
 func parseVerifyFlags(cmd *cobra.Command, fileHandler file.Handler) (verifyFlags, error) {
 	configPath, err := cmd.Flags().GetString("config")
 	if err != nil {
@@ -112,6 +163,17 @@ func parseVerifyFlags(cmd *cobra.Command, fileHandler file.Handler) (verifyFlags
 	if err != nil {
 		return verifyFlags{}, fmt.Errorf("parsing node-endpoint argument: %w", err)
 	}
+	tokenOut, err := cmd.Flags().GetString("token-out")
+	if err != nil {
+		return verifyFlags{}, fmt.Errorf("parsing token-out argument: %w", err)
+	}
+	tokenKey, err := cmd.Flags().GetString("token-key")
+	if err != nil {
+		return verifyFlags{}, fmt.Errorf("parsing token-key argument: %w", err)
+	}
+	if tokenOut != "" && tokenKey == "" {
+		return verifyFlags{}, errors.New("token-key is required to sign the attestation result token")
+	}
 
 	// Get empty values from ID file
 	emptyEndpoint := endpoint == ""
@@ -147,6 +209,8 @@ func parseVerifyFlags(cmd *cobra.Command, fileHandler file.Handler) (verifyFlags
 		configPath: configPath,
 		ownerID:    ownerID,
 		clusterID:  clusterID,
+		tokenOut:   tokenOut,
+		tokenKey:   tokenKey,
 	}, nil
 }
 
@@ -155,6 +219,8 @@ type verifyFlags struct {
 	ownerID    string
 	clusterID  string
 	configPath string
+	tokenOut   string
+	tokenKey   string
 }
 
 func addPortIfMissing(endpoint string, defaultPort int) (string, error) {
@@ -179,12 +245,13 @@ type constellationVerifier struct {
 }
 
 // Verify retrieves an attestation statement from the Constellation and verifies it using the validator.
+// The claims established by the attestation are returned, if the validator supports reporting them.
 func (v *constellationVerifier) Verify(
 	ctx context.Context, endpoint string, req *verifyproto.GetAttestationRequest, validator atls.Validator,
-) error {
+) (policy.Claims, error) {
 	conn, err := v.dialer.DialInsecure(ctx, endpoint)
 	if err != nil {
-		return fmt.Errorf("dialing init server: %w", err)
+		return policy.Claims{}, fmt.Errorf("dialing init server: %w", err)
 	}
 	defer conn.Close()
 
@@ -192,22 +259,28 @@ func (v *constellationVerifier) Verify(
 
 	resp, err := client.GetAttestation(ctx, req)
 	if err != nil {
-		return fmt.Errorf("getting attestation: %w", err)
+		return policy.Claims{}, fmt.Errorf("getting attestation: %w", err)
 	}
 
-	signedData, err := validator.Validate(resp.Attestation, req.Nonce)
+	var signedData []byte
+	var claims policy.Claims
+	if claimsValidator, ok := validator.(eat.Validator); ok {
+		signedData, claims, err = claimsValidator.ValidateWithClaims(resp.Attestation, req.Nonce)
+	} else {
+		signedData, err = validator.Validate(resp.Attestation, req.Nonce)
+	}
 	if err != nil {
-		return fmt.Errorf("validating attestation: %w", err)
+		return policy.Claims{}, fmt.Errorf("validating attestation: %w", err)
 	}
 
 	if !bytes.Equal(signedData, req.UserData) {
-		return errors.New("signed data in attestation does not match provided user data")
+		return policy.Claims{}, errors.New("signed data in attestation does not match provided user data")
 	}
-	return nil
+	return claims, nil
 }
 
 type verifyClient interface {
-	Verify(ctx context.Context, endpoint string, req *verifyproto.GetAttestationRequest, validator atls.Validator) error
+	Verify(ctx context.Context, endpoint string, req *verifyproto.GetAttestationRequest, validator atls.Validator) (policy.Claims, error)
 }
 
 type grpcInsecureDialer interface {
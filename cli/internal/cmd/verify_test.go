@@ -9,14 +9,21 @@ package cmd
 import (
 	"bytes"
 	"context"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/x509"
 	"encoding/base64"
 	"encoding/json"
+	"encoding/pem"
 	"errors"
 	"net"
 	"strconv"
 	"testing"
 
 	"github.com/edgelesssys/constellation/v2/internal/atls"
+	"github.com/edgelesssys/constellation/v2/internal/attestation/eat"
+	"github.com/edgelesssys/constellation/v2/internal/attestation/policy"
 	"github.com/edgelesssys/constellation/v2/internal/cloud/cloudprovider"
 	"github.com/edgelesssys/constellation/v2/internal/config"
 	"github.com/edgelesssys/constellation/v2/internal/constants"
@@ -45,6 +52,8 @@ func TestVerify(t *testing.T) {
 		ownerIDFlag      string
 		clusterIDFlag    string
 		idFile           *clusterIDsFile
+		tokenOutFlag     string
+		tokenKeyFlag     string
 		wantEndpoint     string
 		wantErr          bool
 	}{
@@ -116,6 +125,41 @@ func TestVerify(t *testing.T) {
 			configFlag:       "./file",
 			wantErr:          true,
 		},
+		"attestation result token": {
+			provider:         cloudprovider.GCP,
+			nodeEndpointFlag: "192.0.2.1:1234",
+			clusterIDFlag:    zeroBase64,
+			tokenOutFlag:     "token.jwt",
+			tokenKeyFlag:     "token.key",
+			protoClient:      &stubVerifyClient{claims: policy.Claims{InstanceID: "projects/p/zones/z/instances/i"}},
+			wantEndpoint:     "192.0.2.1:1234",
+		},
+		"attestation result token without attested identity": {
+			provider:         cloudprovider.GCP,
+			nodeEndpointFlag: "192.0.2.1:1234",
+			clusterIDFlag:    zeroBase64,
+			tokenOutFlag:     "token.jwt",
+			tokenKeyFlag:     "token.key",
+			protoClient:      &stubVerifyClient{},
+			wantErr:          true,
+		},
+		"attestation result token without key": {
+			provider:         cloudprovider.GCP,
+			nodeEndpointFlag: "192.0.2.1:1234",
+			clusterIDFlag:    zeroBase64,
+			tokenOutFlag:     "token.jwt",
+			protoClient:      &stubVerifyClient{},
+			wantErr:          true,
+		},
+		"attestation result token key not existing": {
+			provider:         cloudprovider.GCP,
+			nodeEndpointFlag: "192.0.2.1:1234",
+			clusterIDFlag:    zeroBase64,
+			tokenOutFlag:     "token.jwt",
+			tokenKeyFlag:     "other.key",
+			protoClient:      &stubVerifyClient{},
+			wantErr:          true,
+		},
 		"error protoClient GetState": {
 			provider:         cloudprovider.Azure,
 			nodeEndpointFlag: "192.0.2.1:1234",
@@ -154,15 +198,27 @@ func TestVerify(t *testing.T) {
 			if tc.nodeEndpointFlag != "" {
 				require.NoError(cmd.Flags().Set("node-endpoint", tc.nodeEndpointFlag))
 			}
+			if tc.tokenOutFlag != "" {
+				require.NoError(cmd.Flags().Set("token-out", tc.tokenOutFlag))
+			}
+			if tc.tokenKeyFlag != "" {
+				require.NoError(cmd.Flags().Set("token-key", tc.tokenKeyFlag))
+			}
 			fileHandler := file.NewHandler(afero.NewMemMapFs())
 
+			tokenKey, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+			require.NoError(err)
+			tokenKeyDER, err := x509.MarshalECPrivateKey(tokenKey)
+			require.NoError(err)
+			require.NoError(fileHandler.Write("token.key", pem.EncodeToMemory(&pem.Block{Type: "EC PRIVATE KEY", Bytes: tokenKeyDER})))
+
 			config := defaultConfigWithExpectedMeasurements(t, config.Default(), tc.provider)
 			require.NoError(fileHandler.WriteYAML(constants.ConfigFilename, config))
 			if tc.idFile != nil {
 				require.NoError(fileHandler.WriteJSON(constants.ClusterIDsFileName, tc.idFile, file.OptNone))
 			}
 
-			err := verify(cmd, fileHandler, tc.protoClient)
+			err = verify(cmd, fileHandler, tc.protoClient)
 
 			if tc.wantErr {
 				assert.Error(err)
@@ -171,6 +227,15 @@ func TestVerify(t *testing.T) {
 				assert.Contains(out.String(), "OK")
 				assert.Equal(tc.wantEndpoint, tc.protoClient.endpoint)
 			}
+
+			if tc.tokenOutFlag != "" && !tc.wantErr {
+				token, err := fileHandler.Read(tc.tokenOutFlag)
+				require.NoError(err)
+				claims, err := eat.Verify(string(token), &tokenKey.PublicKey, attestationTokenIssuer, nil)
+				require.NoError(err)
+				assert.Equal(tc.protoClient.claims.Identity(), claims.Subject)
+				assert.Equal(tc.provider.String(), claims.Provider)
+			}
 		})
 	}
 }
@@ -249,7 +314,7 @@ func TestVerifyClient(t *testing.T) {
 				Nonce:    tc.nonce,
 			}
 
-			err = verifier.Verify(context.Background(), addr, request, atls.NewFakeValidator(oid.Dummy{}))
+			_, err = verifier.Verify(context.Background(), addr, request, atls.NewFakeValidator(oid.Dummy{}))
 
 			if tc.wantErr {
 				assert.Error(err)
@@ -261,13 +326,14 @@ func TestVerifyClient(t *testing.T) {
 }
 
 type stubVerifyClient struct {
+	claims    policy.Claims
 	verifyErr error
 	endpoint  string
 }
 
-func (c *stubVerifyClient) Verify(ctx context.Context, endpoint string, req *verifyproto.GetAttestationRequest, validator atls.Validator) error {
+func (c *stubVerifyClient) Verify(ctx context.Context, endpoint string, req *verifyproto.GetAttestationRequest, validator atls.Validator) (policy.Claims, error) {
 	c.endpoint = endpoint
-	return c.verifyErr
+	return c.claims, c.verifyErr
 }
 
 type stubVerifyAPI struct {
@@ -50,6 +50,8 @@ type Client struct {
 	controlPlaneInstanceGroup string
 	controlPlaneTemplate      string
 	workerTemplate            string
+	workerGroupInstanceGroups []string
+	workerGroupTemplates      []string
 	network                   string
 	subnetwork                string
 	secondarySubnetworkRange  string
@@ -252,6 +254,8 @@ func (c *Client) GetState() state.ConstellationState {
 		GCPWorkerInstances:              c.workers,
 		GCPWorkerInstanceGroup:          c.workerInstanceGroup,
 		GCPWorkerInstanceTemplate:       c.workerTemplate,
+		GCPWorkerGroupInstanceGroups:    c.workerGroupInstanceGroups,
+		GCPWorkerGroupInstanceTemplates: c.workerGroupTemplates,
 		GCPControlPlaneInstances:        c.controlPlanes,
 		GCPControlPlaneInstanceGroup:    c.controlPlaneInstanceGroup,
 		GCPControlPlaneInstanceTemplate: c.controlPlaneTemplate,
@@ -281,6 +285,8 @@ func (c *Client) SetState(stat state.ConstellationState) {
 	c.network = stat.GCPNetwork
 	c.subnetwork = stat.GCPSubnetwork
 	c.workerTemplate = stat.GCPWorkerInstanceTemplate
+	c.workerGroupInstanceGroups = stat.GCPWorkerGroupInstanceGroups
+	c.workerGroupTemplates = stat.GCPWorkerGroupInstanceTemplates
 	c.controlPlaneTemplate = stat.GCPControlPlaneInstanceTemplate
 	c.loadbalancerIPname = stat.GCPLoadbalancerIPname
 	c.loadbalancerIP = stat.LoadBalancerIP
@@ -35,29 +35,38 @@ func (c *Client) CreateInstances(ctx context.Context, input CreateInstancesInput
 
 	enableSerialConsole := strconv.FormatBool(input.EnableSerialConsole)
 
-	workerTemplateInput := insertInstanceTemplateInput{
-		Name:                         c.buildResourceName("worker"),
-		Network:                      c.network,
-		SecondarySubnetworkRangeName: c.secondarySubnetworkRange,
-		Subnetwork:                   c.subnetwork,
-		EnableSerialConsole:          enableSerialConsole,
-		ImageID:                      input.ImageID,
-		InstanceType:                 input.InstanceType,
-		StateDiskSizeGB:              int64(input.StateDiskSizeGB),
-		StateDiskType:                input.StateDiskType,
-		Role:                         role.Worker.String(),
-		KubeEnv:                      input.KubeEnv,
-		Project:                      c.project,
-		Zone:                         c.zone,
-		Region:                       c.region,
-		UID:                          c.uid,
-	}
-	op, err := c.insertInstanceTemplate(ctx, workerTemplateInput)
-	if err != nil {
-		return fmt.Errorf("inserting instanceTemplate: %w", err)
+	workerTemplateInputs := make([]insertInstanceTemplateInput, 0, len(input.WorkerGroups))
+	for _, group := range input.WorkerGroups {
+		workerTemplateInput := insertInstanceTemplateInput{
+			Name:                         c.buildResourceName(workerResourceName(group.Name)),
+			Network:                      c.network,
+			SecondarySubnetworkRangeName: c.secondarySubnetworkRange,
+			Subnetwork:                   c.subnetwork,
+			EnableSerialConsole:          enableSerialConsole,
+			ImageID:                      input.ImageID,
+			InstanceType:                 group.InstanceType,
+			StateDiskSizeGB:              int64(group.StateDiskSizeGB),
+			StateDiskType:                group.StateDiskType,
+			Role:                         role.Worker.String(),
+			KubeEnv:                      workerGroupKubeEnv(input.KubeEnv, group),
+			WorkerGroup:                  group,
+			Project:                      c.project,
+			Zone:                         c.zone,
+			Region:                       c.region,
+			UID:                          c.uid,
+		}
+		op, err := c.insertInstanceTemplate(ctx, workerTemplateInput)
+		if err != nil {
+			return fmt.Errorf("inserting instanceTemplate: %w", err)
+		}
+		ops = append(ops, op)
+		if group.Name == "" {
+			c.workerTemplate = workerTemplateInput.Name
+		} else {
+			c.workerGroupTemplates = append(c.workerGroupTemplates, workerTemplateInput.Name)
+		}
+		workerTemplateInputs = append(workerTemplateInputs, workerTemplateInput)
 	}
-	ops = append(ops, op)
-	c.workerTemplate = workerTemplateInput.Name
 
 	controlPlaneTemplateInput := insertInstanceTemplateInput{
 		Name:                         c.buildResourceName("control-plane"),
@@ -76,7 +85,7 @@ func (c *Client) CreateInstances(ctx context.Context, input CreateInstancesInput
 		Region:                       c.region,
 		UID:                          c.uid,
 	}
-	op, err = c.insertInstanceTemplate(ctx, controlPlaneTemplateInput)
+	op, err := c.insertInstanceTemplate(ctx, controlPlaneTemplateInput)
 	if err != nil {
 		return fmt.Errorf("inserting instanceTemplate: %w", err)
 	}
@@ -110,35 +119,47 @@ func (c *Client) CreateInstances(ctx context.Context, input CreateInstancesInput
 	ops = append(ops, op)
 	c.controlPlaneInstanceGroup = controlPlaneGroupInput.Name
 
-	workerGroupInput := instanceGroupManagerInput{
-		Count:    input.CountWorkers,
-		Name:     strings.Join([]string{c.name, "worker", c.uid}, "-"),
-		Template: c.workerTemplate,
-		UID:      c.uid,
-		Project:  c.project,
-		Zone:     c.zone,
-	}
-	op, err = c.insertInstanceGroupManger(ctx, workerGroupInput)
-	if err != nil {
-		return fmt.Errorf("inserting instanceGroupManager: %w", err)
+	workerInstanceGroups := make([]string, 0, len(input.WorkerGroups))
+	for i, group := range input.WorkerGroups {
+		workerGroupInput := instanceGroupManagerInput{
+			Count:    group.Count,
+			Name:     strings.Join([]string{c.name, workerResourceName(group.Name), c.uid}, "-"),
+			Template: workerTemplateInputs[i].Name,
+			UID:      c.uid,
+			Project:  c.project,
+			Zone:     c.zone,
+		}
+		op, err = c.insertInstanceGroupManger(ctx, workerGroupInput)
+		if err != nil {
+			return fmt.Errorf("inserting instanceGroupManager: %w", err)
+		}
+		ops = append(ops, op)
+		if group.Name == "" {
+			c.workerInstanceGroup = workerGroupInput.Name
+		} else {
+			c.workerGroupInstanceGroups = append(c.workerGroupInstanceGroups, workerGroupInput.Name)
+		}
+		workerInstanceGroups = append(workerInstanceGroups, workerGroupInput.Name)
 	}
-	ops = append(ops, op)
-	c.workerInstanceGroup = workerGroupInput.Name
 
 	if err := c.waitForOperations(ctx, ops); err != nil {
 		return err
 	}
 
-	if err := c.waitForInstanceGroupScaling(ctx, c.workerInstanceGroup); err != nil {
-		return fmt.Errorf("waiting for instanceGroupScaling: %w", err)
+	for _, group := range workerInstanceGroups {
+		if err := c.waitForInstanceGroupScaling(ctx, group); err != nil {
+			return fmt.Errorf("waiting for instanceGroupScaling: %w", err)
+		}
 	}
 
 	if err := c.waitForInstanceGroupScaling(ctx, c.controlPlaneInstanceGroup); err != nil {
 		return fmt.Errorf("waiting for instanceGroupScaling: %w", err)
 	}
 
-	if err := c.getInstanceIPs(ctx, c.workerInstanceGroup, c.workers); err != nil {
-		return fmt.Errorf("getting instanceIPs: %w", err)
+	for _, group := range workerInstanceGroups {
+		if err := c.getInstanceIPs(ctx, group, c.workers); err != nil {
+			return fmt.Errorf("getting instanceIPs: %w", err)
+		}
 	}
 	if err := c.getInstanceIPs(ctx, c.controlPlaneInstanceGroup, c.controlPlanes); err != nil {
 		return fmt.Errorf("getting instanceIPs: %w", err)
@@ -161,6 +182,19 @@ func (c *Client) TerminateInstances(ctx context.Context) error {
 		c.workers = make(cloudtypes.Instances)
 	}
 
+	for len(c.workerGroupInstanceGroups) > 0 {
+		group := c.workerGroupInstanceGroups[0]
+		op, err := c.deleteInstanceGroupManager(ctx, group)
+		if err != nil && !isNotFoundError(err) {
+			return fmt.Errorf("deleting instanceGroupManager '%s': %w", group, err)
+		}
+		if err == nil {
+			ops = append(ops, op)
+		}
+		c.workerGroupInstanceGroups = c.workerGroupInstanceGroups[1:]
+		c.workers = make(cloudtypes.Instances)
+	}
+
 	if c.controlPlaneInstanceGroup != "" {
 		op, err := c.deleteInstanceGroupManager(ctx, c.controlPlaneInstanceGroup)
 		if err != nil && !isNotFoundError(err) {
@@ -187,6 +221,17 @@ func (c *Client) TerminateInstances(ctx context.Context) error {
 		}
 		c.workerTemplate = ""
 	}
+	for len(c.workerGroupTemplates) > 0 {
+		template := c.workerGroupTemplates[0]
+		op, err := c.deleteInstanceTemplate(ctx, template)
+		if err != nil && !isNotFoundError(err) {
+			return fmt.Errorf("deleting instanceTemplate: %w", err)
+		}
+		if err == nil {
+			ops = append(ops, op)
+		}
+		c.workerGroupTemplates = c.workerGroupTemplates[1:]
+	}
 	if c.controlPlaneTemplate != "" {
 		op, err := c.deleteInstanceTemplate(ctx, c.controlPlaneTemplate)
 		if err != nil && !isNotFoundError(err) {
@@ -321,10 +366,11 @@ func (i *instanceGroupManagerInput) InsertInstanceGroupManagerRequest() computep
 }
 
 // CreateInstancesInput is the input for a CreatInstances operation.
+// A separate instance group is created for each of the WorkerGroups.
 type CreateInstancesInput struct {
 	EnableSerialConsole bool
-	CountWorkers        int
 	CountControlPlanes  int
+	WorkerGroups        []cloudtypes.WorkerGroup
 	ImageID             string
 	InstanceType        string
 	StateDiskSizeGB     int
@@ -332,6 +378,25 @@ type CreateInstancesInput struct {
 	KubeEnv             string
 }
 
+// workerResourceName returns the name component of the resources of a worker group.
+// The default worker group keeps the name used by clusters without worker groups.
+func workerResourceName(group string) string {
+	if group == "" {
+		return "worker"
+	}
+	return "worker-" + group
+}
+
+// workerGroupKubeEnv adds the node labels and taints of a worker group to the kube-env
+// read by the cluster-autoscaler when scaling up from zero nodes.
+func workerGroupKubeEnv(kubeEnv string, group cloudtypes.WorkerGroup) string {
+	kubeEnv = strings.Replace(kubeEnv, "node_labels=", "node_labels="+group.LabelsString(), 1)
+	if len(group.Taints) > 0 {
+		kubeEnv += ";node_taints=" + group.TaintsString()
+	}
+	return kubeEnv
+}
+
 type insertInstanceTemplateInput struct {
 	Name                         string
 	Network                      string
@@ -344,6 +409,7 @@ type insertInstanceTemplateInput struct {
 	StateDiskType                string
 	Role                         string
 	KubeEnv                      string
+	WorkerGroup                  cloudtypes.WorkerGroup
 	Project                      string
 	Zone                         string
 	Region                       string
@@ -440,6 +506,18 @@ func (i insertInstanceTemplateInput) insertInstanceTemplateRequest() *computepb.
 		Project: i.Project,
 	}
 
+	// worker groups store their settings in the instance metadata to be picked up by the node operator
+	if i.WorkerGroup.Name != "" {
+		metadata := req.InstanceTemplateResource.Properties.Metadata
+		metadata.Items = append(metadata.Items,
+			&computepb.Items{Key: proto.String(constants.WorkerGroupTag), Value: proto.String(i.WorkerGroup.Name)},
+			&computepb.Items{Key: proto.String(constants.WorkerGroupMinTag), Value: proto.String(strconv.Itoa(i.WorkerGroup.Min))},
+			&computepb.Items{Key: proto.String(constants.WorkerGroupMaxTag), Value: proto.String(strconv.Itoa(i.WorkerGroup.Max))},
+			&computepb.Items{Key: proto.String(constants.WorkerGroupLabelsTag), Value: proto.String(i.WorkerGroup.LabelsString())},
+			&computepb.Items{Key: proto.String(constants.WorkerGroupTaintsTag), Value: proto.String(i.WorkerGroup.TaintsString())},
+		)
+	}
+
 	// if there is an secondary IP range defined, we use it as an alias IP range
 	if i.SecondarySubnetworkRangeName != "" {
 		req.InstanceTemplateResource.Properties.NetworkInterfaces[0].AliasIpRanges = []*computepb.AliasIpRange{
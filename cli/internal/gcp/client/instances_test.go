@@ -50,11 +50,21 @@ func TestCreateInstances(t *testing.T) {
 	}
 	testInput := CreateInstancesInput{
 		CountControlPlanes: 3,
-		CountWorkers:       4,
+		WorkerGroups:       []cloudtypes.WorkerGroup{{Count: 4, InstanceType: "n2d-standard-4"}},
 		ImageID:            "img",
 		InstanceType:       "n2d-standard-4",
 		KubeEnv:            "kube-env",
 	}
+	testWorkerGroupsInput := CreateInstancesInput{
+		CountControlPlanes: 3,
+		WorkerGroups: []cloudtypes.WorkerGroup{
+			{Name: "highmem", Count: 1, Min: 1, Max: 5, InstanceType: "n2d-highmem-4"},
+			{Name: "spot", Count: 2, Min: 2, Max: 10, InstanceType: "n2d-standard-4"},
+		},
+		ImageID:      "img",
+		InstanceType: "n2d-standard-4",
+		KubeEnv:      "kube-env",
+	}
 	someErr := errors.New("failed")
 
 	testCases := map[string]struct {
@@ -65,6 +75,7 @@ func TestCreateInstances(t *testing.T) {
 		instanceGroupManagersAPI instanceGroupManagersAPI
 		input                    CreateInstancesInput
 		network                  string
+		wantWorkerGroups         []string
 		wantErr                  bool
 	}{
 		"successful create": {
@@ -76,6 +87,16 @@ func TestCreateInstances(t *testing.T) {
 			network:                  "network",
 			input:                    testInput,
 		},
+		"successful create with worker groups": {
+			instanceAPI:              stubInstanceAPI{listIterator: &stubInstanceIterator{instances: testInstances}},
+			operationZoneAPI:         stubOperationZoneAPI{},
+			operationGlobalAPI:       stubOperationGlobalAPI{},
+			instanceTemplateAPI:      stubInstanceTemplateAPI{},
+			instanceGroupManagersAPI: stubInstanceGroupManagersAPI{listIterator: &stubManagedInstanceIterator{instances: testManagedInstances}},
+			network:                  "network",
+			input:                    testWorkerGroupsInput,
+			wantWorkerGroups:         []string{"name-worker-highmem-uid", "name-worker-spot-uid"},
+		},
 		"failed no network": {
 			instanceAPI:              stubInstanceAPI{listIterator: &stubInstanceIterator{instances: testInstances}},
 			operationZoneAPI:         stubOperationZoneAPI{waitErr: someErr},
@@ -181,6 +202,8 @@ func TestCreateInstances(t *testing.T) {
 				assert.NotNil(client.controlPlaneInstanceGroup)
 				assert.NotNil(client.controlPlaneTemplate)
 				assert.NotNil(client.workerTemplate)
+				assert.Equal(tc.wantWorkerGroups, client.workerGroupInstanceGroups)
+				assert.Len(client.workerGroupTemplates, len(tc.wantWorkerGroups))
 			}
 		})
 	}
@@ -197,6 +220,7 @@ func TestTerminateInstances(t *testing.T) {
 		instanceGroupManagersAPI instanceGroupManagersAPI
 
 		missingWorkerInstanceGroup bool
+		workerGroups               bool
 		wantErr                    bool
 	}{
 		"successful terminate": {
@@ -212,6 +236,13 @@ func TestTerminateInstances(t *testing.T) {
 			instanceGroupManagersAPI:   stubInstanceGroupManagersAPI{},
 			missingWorkerInstanceGroup: true,
 		},
+		"successful terminate with worker groups": {
+			operationZoneAPI:         stubOperationZoneAPI{},
+			operationGlobalAPI:       stubOperationGlobalAPI{},
+			instanceTemplateAPI:      stubInstanceTemplateAPI{},
+			instanceGroupManagersAPI: stubInstanceGroupManagersAPI{},
+			workerGroups:             true,
+		},
 		"instances not found": {
 			operationZoneAPI:         stubOperationZoneAPI{},
 			operationGlobalAPI:       stubOperationGlobalAPI{},
@@ -266,6 +297,10 @@ func TestTerminateInstances(t *testing.T) {
 				client.workerInstanceGroup = ""
 				client.workers = cloudtypes.Instances{}
 			}
+			if tc.workerGroups {
+				client.workerGroupInstanceGroups = []string{"workerInstanceGroup-id-2", "workerInstanceGroup-id-3"}
+				client.workerGroupTemplates = []string{"template-id-2", "template-id-3"}
+			}
 
 			if tc.wantErr {
 				assert.Error(client.TerminateInstances(ctx))
@@ -279,7 +314,36 @@ func TestTerminateInstances(t *testing.T) {
 				assert.Empty(client.controlPlaneInstanceGroup)
 				assert.Empty(client.controlPlaneTemplate)
 				assert.Empty(client.workerTemplate)
+				assert.Empty(client.workerGroupInstanceGroups)
+				assert.Empty(client.workerGroupTemplates)
 			}
 		})
 	}
 }
+
+func TestWorkerGroupKubeEnv(t *testing.T) {
+	kubeEnv := "AUTOSCALER_ENV_VARS: kube_reserved=cpu=1060m;node_labels=;os=linux"
+	testCases := map[string]struct {
+		group cloudtypes.WorkerGroup
+		want  string
+	}{
+		"default group": {
+			want: kubeEnv,
+		},
+		"labels and taints": {
+			group: cloudtypes.WorkerGroup{
+				Name:   "spot",
+				Labels: map[string]string{"pool": "spot", "tier": "batch"},
+				Taints: []string{"pool=spot:NoSchedule"},
+			},
+			want: "AUTOSCALER_ENV_VARS: kube_reserved=cpu=1060m;node_labels=pool=spot,tier=batch;os=linux;node_taints=pool=spot:NoSchedule",
+		},
+	}
+
+	for name, tc := range testCases {
+		t.Run(name, func(t *testing.T) {
+			assert := assert.New(t)
+			assert.Equal(tc.want, workerGroupKubeEnv(kubeEnv, tc.group))
+		})
+	}
+}
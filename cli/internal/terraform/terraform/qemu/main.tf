@@ -37,12 +37,22 @@ resource "docker_container" "qemu-metadata" {
   command = [ 
     "--network",
     "${var.name}-network",
+    "--tpm-ca-cert",
+    "/var/lib/swtpm-localca/issuercert.pem",
+    "--tpm-ca-key",
+    "/var/lib/swtpm-localca/signkey.pem",
   ]
   mounts {
     source = "/var/run/libvirt/libvirt-sock"
     target = "/var/run/libvirt/libvirt-sock"
     type   = "bind"
   }
+  # created by libvirt when the first VM with a TPM is set up
+  volumes {
+    host_path      = var.swtpm_localca_dir
+    container_path = "/var/lib/swtpm-localca"
+    read_only      = true
+  }
 }
 
 module "control_plane" {
@@ -50,6 +50,12 @@ variable "metadata_api_image" {
   description = "container image of the QEMU metadata api server"
 }
 
+variable "swtpm_localca_dir" {
+  type        = string
+  default     = "/var/lib/swtpm-localca"
+  description = "directory of the swtpm-localca CA issuing EK certificates, used by the metadata api to certify attestation keys"
+}
+
 variable "name" {
   type        = string
   default     = "constellation"
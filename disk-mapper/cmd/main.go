@@ -25,6 +25,7 @@ import (
 	"github.com/edgelesssys/constellation/v2/internal/attestation/azure"
 	"github.com/edgelesssys/constellation/v2/internal/attestation/gcp"
 	"github.com/edgelesssys/constellation/v2/internal/attestation/qemu"
+	"github.com/edgelesssys/constellation/v2/internal/attestation/tdx"
 	"github.com/edgelesssys/constellation/v2/internal/attestation/vtpm"
 	azurecloud "github.com/edgelesssys/constellation/v2/internal/cloud/azure"
 	gcpcloud "github.com/edgelesssys/constellation/v2/internal/cloud/gcp"
@@ -89,7 +90,11 @@ func main() {
 
 	case "qemu":
 		diskPath = qemuStateDiskPath
-		issuer = qemu.NewIssuer()
+		if tdx.Available() {
+			issuer = tdx.NewIssuer()
+		} else {
+			issuer = qemu.NewIssuer()
+		}
 		metadataAPI = &qemucloud.Metadata{}
 		_ = exportPCRs()
 
@@ -164,7 +169,7 @@ func exportPCRs() error {
 	// send PCRs to metadata API
 	url := &url.URL{
 		Scheme: "http",
-		Host:   "10.42.0.1:8080", // QEMU metadata endpoint
+		Host:   constants.QEMUMetadataEndpoint,
 		Path:   "/pcrs",
 	}
 	req, err := http.NewRequestWithContext(context.Background(), http.MethodPost, url.String(), bytes.NewBuffer(pcrsPretty))
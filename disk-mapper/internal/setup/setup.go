@@ -20,6 +20,7 @@ import (
 
 	"github.com/edgelesssys/constellation/v2/disk-mapper/internal/systemd"
 	"github.com/edgelesssys/constellation/v2/internal/attestation"
+	"github.com/edgelesssys/constellation/v2/internal/attestation/tdx"
 	"github.com/edgelesssys/constellation/v2/internal/attestation/vtpm"
 	"github.com/edgelesssys/constellation/v2/internal/constants"
 	"github.com/edgelesssys/constellation/v2/internal/crypto"
@@ -104,6 +105,12 @@ func (s *SetupManager) PrepareExistingDisk(recover RecoveryDoer) error {
 	if err := vtpm.MarkNodeAsBootstrapped(s.openTPM, clusterID); err != nil {
 		return err
 	}
+	// TDX guests are attested using their RTMRs instead of the TPM
+	if tdx.Available() {
+		if err := tdx.MarkNodeAsBootstrapped(clusterID); err != nil {
+			return err
+		}
+	}
 
 	if err := s.saveConfiguration(passphrase); err != nil {
 		return err
@@ -143,6 +143,11 @@ const sidebars = {
           label: 'Recover your cluster',
           id: 'workflows/recovery',
         },
+        {
+          type: 'doc',
+          label: 'Back up and restore your cluster',
+          id: 'workflows/backup',
+        },
         {
           type: 'doc',
           label: 'Verify your cluster',
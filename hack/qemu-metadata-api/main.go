@@ -8,9 +8,14 @@ package main
 
 import (
 	"flag"
+	"fmt"
+	"os"
+	"sync"
 
+	"github.com/edgelesssys/constellation/v2/hack/qemu-metadata-api/scalinggroup"
 	"github.com/edgelesssys/constellation/v2/hack/qemu-metadata-api/server"
 	"github.com/edgelesssys/constellation/v2/hack/qemu-metadata-api/virtwrapper"
+	"github.com/edgelesssys/constellation/v2/internal/attestation/qemu"
 	"github.com/edgelesssys/constellation/v2/internal/logger"
 	"go.uber.org/zap"
 	"go.uber.org/zap/zapcore"
@@ -20,6 +25,8 @@ import (
 func main() {
 	bindPort := flag.String("port", "8080", "Port to bind to")
 	targetNetwork := flag.String("network", "constellation-network", "Name of the network in QEMU to use")
+	tpmCACert := flag.String("tpm-ca-cert", "", "Path to the PEM encoded certificate of the CA issuing swtpm EK certificates, e.g., /var/lib/swtpm-localca/issuercert.pem")
+	tpmCAKey := flag.String("tpm-ca-key", "", "Path to the PEM encoded private key of the CA issuing swtpm EK certificates, e.g., /var/lib/swtpm-localca/signkey.pem")
 	flag.Parse()
 
 	log := logger.New(logger.JSONLog, zapcore.InfoLevel)
@@ -30,8 +37,65 @@ func main() {
 	}
 	defer conn.Close()
 
-	serv := server.New(log, *targetNetwork, &virtwrapper.Connect{Conn: conn})
+	virt := &virtwrapper.Connect{Conn: conn}
+	groups := scalinggroup.New(*targetNetwork, virt)
+	var serv *server.Server
+	if *tpmCACert != "" && *tpmCAKey != "" {
+		serv = server.New(log, *targetNetwork, virt, groups, &fileAKCA{certPath: *tpmCACert, keyPath: *tpmCAKey})
+	} else {
+		log.Warnf("No TPM CA configured: attestation keys of the VMs can't be certified")
+		serv = server.New(log, *targetNetwork, virt, groups, nil)
+	}
 	if err := serv.ListenAndServe(*bindPort); err != nil {
 		log.With(zap.Error(err)).Fatalf("Failed to serve")
 	}
 }
+
+// fileAKCA certifies attestation keys using a CA loaded from files.
+// The CA is loaded on first use, since swtpm-localca only creates it when the first TPM is set up.
+type fileAKCA struct {
+	certPath string
+	keyPath  string
+
+	mux sync.Mutex
+	ca  *qemu.AKCA
+}
+
+func (f *fileAKCA) Challenge(req qemu.AKChallengeRequest, instanceID string) (qemu.AKChallengeResponse, error) {
+	ca, err := f.load()
+	if err != nil {
+		return qemu.AKChallengeResponse{}, err
+	}
+	return ca.Challenge(req, instanceID)
+}
+
+func (f *fileAKCA) Certify(req qemu.AKCertificateRequest) (qemu.AKCertificateResponse, error) {
+	ca, err := f.load()
+	if err != nil {
+		return qemu.AKCertificateResponse{}, err
+	}
+	return ca.Certify(req)
+}
+
+func (f *fileAKCA) load() (*qemu.AKCA, error) {
+	f.mux.Lock()
+	defer f.mux.Unlock()
+	if f.ca != nil {
+		return f.ca, nil
+	}
+
+	certPEM, err := os.ReadFile(f.certPath)
+	if err != nil {
+		return nil, fmt.Errorf("reading TPM CA certificate: %w", err)
+	}
+	keyPEM, err := os.ReadFile(f.keyPath)
+	if err != nil {
+		return nil, fmt.Errorf("reading TPM CA key: %w", err)
+	}
+	ca, err := qemu.NewAKCA(certPEM, keyPEM)
+	if err != nil {
+		return nil, err
+	}
+	f.ca = ca
+	return ca, nil
+}
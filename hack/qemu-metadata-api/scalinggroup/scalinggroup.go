@@ -0,0 +1,520 @@
+/*
+Copyright (c) Edgeless Systems GmbH
+
+SPDX-License-Identifier: AGPL-3.0-only
+*/
+
+/*
+Package scalinggroup emulates the scaling groups of cloud providers for Constellation clusters running on QEMU.
+
+Domains are expected to be named "<cluster name>-<role>-<index>" as done by Terraform.
+All domains sharing the prefix "<cluster name>-<role>" form a scaling group, which is identified by that prefix.
+The hostname of a domain is "<role>-<index>".
+
+The image of a node is the path of the libvirt volume its boot disk is backed by.
+New nodes are created by cloning the definition of the newest node of a scaling group.
+*/
+package scalinggroup
+
+import (
+	"crypto/rand"
+	"errors"
+	"fmt"
+	"regexp"
+	"sort"
+	"strconv"
+	"sync"
+
+	"github.com/edgelesssys/constellation/v2/internal/role"
+	"libvirt.org/go/libvirtxml"
+)
+
+// ErrNotFound is returned if a scaling group or node does not exist.
+var ErrNotFound = errors.New("not found")
+
+var domainNameRegexp = regexp.MustCompile(`^(.+-(control-plane|worker))-([0-9]+)$`)
+
+// Group is a scaling group.
+type Group struct {
+	ID    string    `json:"id"`
+	Role  role.Role `json:"role"`
+	Image string    `json:"image"`
+}
+
+// NodeState is the state of the domain of a node.
+type NodeState string
+
+const (
+	// NodeStateRunning means the domain of the node is running.
+	NodeStateRunning NodeState = "running"
+	// NodeStateStopped means the domain of the node is defined, but not running.
+	NodeStateStopped NodeState = "stopped"
+)
+
+// Node is a node of a scaling group.
+type Node struct {
+	Name       string    `json:"name"`
+	ProviderID string    `json:"providerID"`
+	GroupID    string    `json:"groupID"`
+	Image      string    `json:"image"`
+	State      NodeState `json:"state"`
+}
+
+// Manager manages the scaling groups of a Constellation cluster running on QEMU.
+type Manager struct {
+	virt    virConnect
+	network string
+	// images holds the images set for scaling groups.
+	// Groups without an explicitly set image use the image of their newest node.
+	images map[string]string
+	mux    sync.Mutex
+}
+
+// New creates a new scaling group manager.
+// network is the name of the libvirt network the nodes are connected to.
+func New(network string, virt virConnect) *Manager {
+	return &Manager{
+		virt:    virt,
+		network: network,
+		images:  make(map[string]string),
+	}
+}
+
+// ListGroups returns all scaling groups.
+func (m *Manager) ListGroups() ([]Group, error) {
+	m.mux.Lock()
+	defer m.mux.Unlock()
+
+	domains, err := m.listDomains()
+	if err != nil {
+		return nil, err
+	}
+	newest := make(map[string]domain)
+	for _, dom := range domains {
+		if current, ok := newest[dom.groupID]; !ok || dom.index > current.index {
+			newest[dom.groupID] = dom
+		}
+	}
+
+	groups := make([]Group, 0, len(newest))
+	for groupID, dom := range newest {
+		image, err := m.groupImage(dom)
+		if err != nil {
+			return nil, fmt.Errorf("getting image of scaling group %q: %w", groupID, err)
+		}
+		groups = append(groups, Group{ID: groupID, Role: dom.role(), Image: image})
+	}
+	sort.Slice(groups, func(i, j int) bool { return groups[i].ID < groups[j].ID })
+	return groups, nil
+}
+
+// SetGroupImage sets the image used for new nodes of a scaling group.
+func (m *Manager) SetGroupImage(groupID, image string) error {
+	m.mux.Lock()
+	defer m.mux.Unlock()
+
+	if _, err := m.newestDomain(groupID); err != nil {
+		return err
+	}
+	if _, err := m.volume(image); err != nil {
+		return fmt.Errorf("looking up image volume %q: %w", image, err)
+	}
+	m.images[groupID] = image
+	return nil
+}
+
+// GetNode returns the node with the given hostname.
+func (m *Manager) GetNode(name string) (Node, error) {
+	m.mux.Lock()
+	defer m.mux.Unlock()
+
+	dom, err := m.findDomain(name)
+	if err != nil {
+		return Node{}, err
+	}
+	image, err := m.nodeImage(dom)
+	if err != nil {
+		return Node{}, fmt.Errorf("getting image of node %q: %w", name, err)
+	}
+	active, err := m.virt.DomainActive(dom.name)
+	if err != nil {
+		return Node{}, fmt.Errorf("getting state of node %q: %w", name, err)
+	}
+	state := NodeStateStopped
+	if active {
+		state = NodeStateRunning
+	}
+	return dom.node(image, state), nil
+}
+
+// CreateNode creates and starts a new node in a scaling group.
+// The node is a clone of the newest node of the group, using the image of the group.
+func (m *Manager) CreateNode(groupID string) (Node, error) {
+	m.mux.Lock()
+	defer m.mux.Unlock()
+
+	template, err := m.newestDomain(groupID)
+	if err != nil {
+		return Node{}, err
+	}
+	image, err := m.groupImage(template)
+	if err != nil {
+		return Node{}, fmt.Errorf("getting image of scaling group %q: %w", groupID, err)
+	}
+	domainXML, err := m.virt.DomainXML(template.name)
+	if err != nil {
+		return Node{}, fmt.Errorf("getting definition of node %q: %w", template.hostname(), err)
+	}
+	var def libvirtxml.Domain
+	if err := def.Unmarshal(domainXML); err != nil {
+		return Node{}, fmt.Errorf("parsing definition of node %q: %w", template.hostname(), err)
+	}
+	bootDisk, stateDisk, err := nodeDisks(&def)
+	if err != nil {
+		return Node{}, fmt.Errorf("node %q: %w", template.hostname(), err)
+	}
+
+	newDom := domain{
+		name:     groupID + "-" + strconv.Itoa(template.index+1),
+		groupID:  groupID,
+		roleName: template.roleName,
+		index:    template.index + 1,
+	}
+	mac, err := randomMAC()
+	if err != nil {
+		return Node{}, err
+	}
+
+	pool, err := m.virt.VolumePool(bootDisk.Source.File.File)
+	if err != nil {
+		return Node{}, fmt.Errorf("getting storage pool of node %q: %w", template.hostname(), err)
+	}
+	bootVolume, err := m.createBootVolume(pool, newDom.name+"-boot", image)
+	if err != nil {
+		return Node{}, fmt.Errorf("creating boot volume: %w", err)
+	}
+	stateVolume, err := m.createStateVolume(pool, newDom.name+"-state", stateDisk.Source.File.File)
+	if err != nil {
+		_ = m.virt.DeleteVolume(bootVolume)
+		return Node{}, fmt.Errorf("creating state volume: %w", err)
+	}
+
+	def.Name = newDom.name
+	def.UUID = ""
+	def.ID = nil
+	if def.OS != nil {
+		// let libvirt create a new UEFI variable store for the node
+		def.OS.NVRam = nil
+	}
+	bootDisk.Source.File.File = bootVolume
+	stateDisk.Source.File.File = stateVolume
+	for i := range def.Devices.Interfaces {
+		def.Devices.Interfaces[i].MAC = &libvirtxml.DomainInterfaceMAC{Address: mac}
+		def.Devices.Interfaces[i].Target = nil
+	}
+	newDomainXML, err := def.Marshal()
+	if err != nil {
+		_ = m.virt.DeleteVolume(bootVolume)
+		_ = m.virt.DeleteVolume(stateVolume)
+		return Node{}, fmt.Errorf("marshaling definition of node %q: %w", newDom.hostname(), err)
+	}
+
+	hostXML, err := (&libvirtxml.NetworkDHCPHost{MAC: mac, Name: newDom.hostname()}).Marshal()
+	if err != nil {
+		_ = m.virt.DeleteVolume(bootVolume)
+		_ = m.virt.DeleteVolume(stateVolume)
+		return Node{}, err
+	}
+	if err := m.virt.AddDHCPHost(m.network, hostXML); err != nil {
+		_ = m.virt.DeleteVolume(bootVolume)
+		_ = m.virt.DeleteVolume(stateVolume)
+		return Node{}, fmt.Errorf("adding DHCP host entry for node %q: %w", newDom.hostname(), err)
+	}
+	if err := m.virt.StartDomain(newDomainXML); err != nil {
+		_ = m.virt.RemoveDHCPHost(m.network, hostXML)
+		_ = m.virt.DeleteVolume(bootVolume)
+		_ = m.virt.DeleteVolume(stateVolume)
+		return Node{}, fmt.Errorf("starting node %q: %w", newDom.hostname(), err)
+	}
+	return newDom.node(image, NodeStateRunning), nil
+}
+
+// DeleteNode stops and removes a node, including its volumes.
+func (m *Manager) DeleteNode(name string) error {
+	m.mux.Lock()
+	defer m.mux.Unlock()
+
+	dom, err := m.findDomain(name)
+	if err != nil {
+		return err
+	}
+	domainXML, err := m.virt.DomainXML(dom.name)
+	if err != nil {
+		return fmt.Errorf("getting definition of node %q: %w", name, err)
+	}
+	var def libvirtxml.Domain
+	if err := def.Unmarshal(domainXML); err != nil {
+		return fmt.Errorf("parsing definition of node %q: %w", name, err)
+	}
+	bootDisk, stateDisk, err := nodeDisks(&def)
+	if err != nil {
+		return fmt.Errorf("node %q: %w", name, err)
+	}
+
+	if err := m.virt.DeleteDomain(dom.name); err != nil {
+		return fmt.Errorf("deleting node %q: %w", name, err)
+	}
+	for _, disk := range []*libvirtxml.DomainDisk{bootDisk, stateDisk} {
+		if err := m.virt.DeleteVolume(disk.Source.File.File); err != nil {
+			return fmt.Errorf("deleting volume %q of node %q: %w", disk.Source.File.File, name, err)
+		}
+	}
+	for _, iface := range def.Devices.Interfaces {
+		if iface.MAC == nil {
+			continue
+		}
+		hostXML, err := (&libvirtxml.NetworkDHCPHost{MAC: iface.MAC.Address, Name: name}).Marshal()
+		if err != nil {
+			return err
+		}
+		if err := m.virt.RemoveDHCPHost(m.network, hostXML); err != nil {
+			return fmt.Errorf("removing DHCP host entry of node %q: %w", name, err)
+		}
+	}
+	return nil
+}
+
+// createBootVolume creates a copy-on-write volume backed by the image.
+func (m *Manager) createBootVolume(pool, name, image string) (string, error) {
+	imageVolume, err := m.volume(image)
+	if err != nil {
+		return "", fmt.Errorf("looking up image volume %q: %w", image, err)
+	}
+	imageFormat := &libvirtxml.StorageVolumeTargetFormat{Type: "qcow2"}
+	if imageVolume.Target != nil && imageVolume.Target.Format != nil {
+		imageFormat = imageVolume.Target.Format
+	}
+	volumeXML, err := (&libvirtxml.StorageVolume{
+		Name:     name,
+		Capacity: imageVolume.Capacity,
+		Target: &libvirtxml.StorageVolumeTarget{
+			Format: &libvirtxml.StorageVolumeTargetFormat{Type: "qcow2"},
+		},
+		BackingStore: &libvirtxml.StorageVolumeBackingStore{
+			Path:   image,
+			Format: imageFormat,
+		},
+	}).Marshal()
+	if err != nil {
+		return "", err
+	}
+	return m.virt.CreateVolume(pool, volumeXML)
+}
+
+// createStateVolume creates an empty volume with the same capacity as the given state volume.
+func (m *Manager) createStateVolume(pool, name, templateState string) (string, error) {
+	templateVolume, err := m.volume(templateState)
+	if err != nil {
+		return "", fmt.Errorf("looking up state volume %q: %w", templateState, err)
+	}
+	volumeXML, err := (&libvirtxml.StorageVolume{
+		Name:     name,
+		Capacity: templateVolume.Capacity,
+		Target: &libvirtxml.StorageVolumeTarget{
+			Format: &libvirtxml.StorageVolumeTargetFormat{Type: "qcow2"},
+		},
+	}).Marshal()
+	if err != nil {
+		return "", err
+	}
+	return m.virt.CreateVolume(pool, volumeXML)
+}
+
+// groupImage returns the image of the scaling group of the given domain,
+// which is expected to be the newest domain of the group.
+func (m *Manager) groupImage(newest domain) (string, error) {
+	if image, ok := m.images[newest.groupID]; ok {
+		return image, nil
+	}
+	return m.nodeImage(newest)
+}
+
+// nodeImage returns the path of the volume backing the boot disk of a domain.
+func (m *Manager) nodeImage(dom domain) (string, error) {
+	domainXML, err := m.virt.DomainXML(dom.name)
+	if err != nil {
+		return "", err
+	}
+	var def libvirtxml.Domain
+	if err := def.Unmarshal(domainXML); err != nil {
+		return "", err
+	}
+	bootDisk, _, err := nodeDisks(&def)
+	if err != nil {
+		return "", err
+	}
+	bootVolume, err := m.volume(bootDisk.Source.File.File)
+	if err != nil {
+		return "", err
+	}
+	if bootVolume.BackingStore == nil || bootVolume.BackingStore.Path == "" {
+		return "", fmt.Errorf("boot volume %q has no backing store", bootDisk.Source.File.File)
+	}
+	return bootVolume.BackingStore.Path, nil
+}
+
+func (m *Manager) volume(path string) (libvirtxml.StorageVolume, error) {
+	volumeXML, err := m.virt.VolumeXML(path)
+	if err != nil {
+		return libvirtxml.StorageVolume{}, err
+	}
+	var volume libvirtxml.StorageVolume
+	if err := volume.Unmarshal(volumeXML); err != nil {
+		return libvirtxml.StorageVolume{}, err
+	}
+	return volume, nil
+}
+
+// findDomain returns the domain of the node with the given hostname.
+func (m *Manager) findDomain(hostname string) (domain, error) {
+	domains, err := m.listDomains()
+	if err != nil {
+		return domain{}, err
+	}
+	for _, dom := range domains {
+		if dom.hostname() == hostname {
+			return dom, nil
+		}
+	}
+	return domain{}, fmt.Errorf("node %q: %w", hostname, ErrNotFound)
+}
+
+// newestDomain returns the domain with the highest index in a scaling group.
+func (m *Manager) newestDomain(groupID string) (domain, error) {
+	domains, err := m.listDomains()
+	if err != nil {
+		return domain{}, err
+	}
+	var newest domain
+	found := false
+	for _, dom := range domains {
+		if dom.groupID == groupID && (!found || dom.index > newest.index) {
+			newest = dom
+			found = true
+		}
+	}
+	if !found {
+		return domain{}, fmt.Errorf("scaling group %q: %w", groupID, ErrNotFound)
+	}
+	return newest, nil
+}
+
+// listDomains returns all domains belonging to a scaling group.
+func (m *Manager) listDomains() ([]domain, error) {
+	names, err := m.virt.ListDomainNames()
+	if err != nil {
+		return nil, fmt.Errorf("listing domains: %w", err)
+	}
+	var domains []domain
+	for _, name := range names {
+		matches := domainNameRegexp.FindStringSubmatch(name)
+		if matches == nil {
+			continue
+		}
+		index, err := strconv.Atoi(matches[3])
+		if err != nil {
+			continue
+		}
+		domains = append(domains, domain{
+			name:     name,
+			groupID:  matches[1],
+			roleName: matches[2],
+			index:    index,
+		})
+	}
+	return domains, nil
+}
+
+// nodeDisks returns the boot disk and state disk of a domain definition.
+func nodeDisks(def *libvirtxml.Domain) (bootDisk, stateDisk *libvirtxml.DomainDisk, err error) {
+	if def.Devices == nil {
+		return nil, nil, errors.New("domain has no devices")
+	}
+	var disks []*libvirtxml.DomainDisk
+	for i := range def.Devices.Disks {
+		disk := &def.Devices.Disks[i]
+		if disk.Device != "" && disk.Device != "disk" {
+			continue
+		}
+		if disk.Source == nil || disk.Source.File == nil || disk.Source.File.File == "" {
+			return nil, nil, errors.New("domain has a disk not backed by a file")
+		}
+		disks = append(disks, disk)
+	}
+	if len(disks) != 2 {
+		return nil, nil, fmt.Errorf("expected domain to have 2 disks, found %d", len(disks))
+	}
+	return disks[0], disks[1], nil
+}
+
+// randomMAC returns a random MAC address using the prefix reserved for QEMU.
+func randomMAC() (string, error) {
+	suffix := make([]byte, 3)
+	if _, err := rand.Read(suffix); err != nil {
+		return "", fmt.Errorf("generating MAC address: %w", err)
+	}
+	return fmt.Sprintf("52:54:00:%02x:%02x:%02x", suffix[0], suffix[1], suffix[2]), nil
+}
+
+type domain struct {
+	name     string
+	groupID  string
+	roleName string
+	index    int
+}
+
+func (d domain) hostname() string {
+	return d.roleName + "-" + strconv.Itoa(d.index)
+}
+
+func (d domain) role() role.Role {
+	if d.roleName == "control-plane" {
+		return role.ControlPlane
+	}
+	return role.Worker
+}
+
+func (d domain) node(image string, state NodeState) Node {
+	return Node{
+		Name:       d.hostname(),
+		ProviderID: "qemu:///hostname/" + d.hostname(),
+		GroupID:    d.groupID,
+		Image:      image,
+		State:      state,
+	}
+}
+
+type virConnect interface {
+	// ListDomainNames returns the names of all defined domains.
+	ListDomainNames() ([]string, error)
+	// DomainXML returns the persistent definition of a domain.
+	DomainXML(name string) (string, error)
+	// DomainActive reports whether a domain is running.
+	DomainActive(name string) (bool, error)
+	// StartDomain defines and starts a new domain.
+	StartDomain(domainXML string) error
+	// DeleteDomain stops and undefines a domain.
+	DeleteDomain(name string) error
+	// VolumeXML returns the definition of the volume at path.
+	VolumeXML(path string) (string, error)
+	// VolumePool returns the name of the storage pool containing the volume at path.
+	VolumePool(path string) (string, error)
+	// CreateVolume creates a volume in a storage pool and returns its path.
+	CreateVolume(pool, volumeXML string) (string, error)
+	// DeleteVolume deletes the volume at path.
+	DeleteVolume(path string) error
+	// AddDHCPHost adds a DHCP host entry to a network.
+	AddDHCPHost(network, hostXML string) error
+	// RemoveDHCPHost removes a DHCP host entry from a network.
+	RemoveDHCPHost(network, hostXML string) error
+}
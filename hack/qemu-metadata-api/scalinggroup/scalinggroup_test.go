@@ -0,0 +1,489 @@
+/*
+Copyright (c) Edgeless Systems GmbH
+
+SPDX-License-Identifier: AGPL-3.0-only
+*/
+
+package scalinggroup
+
+import (
+	"errors"
+	"fmt"
+	"path"
+	"strings"
+	"testing"
+
+	"github.com/edgelesssys/constellation/v2/internal/role"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/goleak"
+	"libvirt.org/go/libvirtxml"
+)
+
+func TestMain(m *testing.M) {
+	goleak.VerifyTestMain(m)
+}
+
+const (
+	poolPath  = "/var/lib/libvirt/images/"
+	baseImage = poolPath + "constell-node-image"
+)
+
+func TestListGroups(t *testing.T) {
+	testCases := map[string]struct {
+		virt       *stubVirt
+		images     map[string]string
+		wantGroups []Group
+		wantErr    bool
+	}{
+		"groups are listed": {
+			virt: newStubVirt(t, "constell-control-plane-0", "constell-worker-0", "constell-worker-1", "unrelated-domain"),
+			wantGroups: []Group{
+				{ID: "constell-control-plane", Role: role.ControlPlane, Image: baseImage},
+				{ID: "constell-worker", Role: role.Worker, Image: baseImage},
+			},
+		},
+		"image is set": {
+			virt:   newStubVirt(t, "constell-control-plane-0", "constell-worker-0"),
+			images: map[string]string{"constell-worker": poolPath + "new-image"},
+			wantGroups: []Group{
+				{ID: "constell-control-plane", Role: role.ControlPlane, Image: baseImage},
+				{ID: "constell-worker", Role: role.Worker, Image: poolPath + "new-image"},
+			},
+		},
+		"no groups": {
+			virt:       newStubVirt(t),
+			wantGroups: []Group{},
+		},
+		"listing domains fails": {
+			virt:    &stubVirt{listErr: errors.New("failed")},
+			wantErr: true,
+		},
+	}
+
+	for name, tc := range testCases {
+		t.Run(name, func(t *testing.T) {
+			assert := assert.New(t)
+
+			manager := New("constell-network", tc.virt)
+			for groupID, image := range tc.images {
+				manager.images[groupID] = image
+			}
+			groups, err := manager.ListGroups()
+			if tc.wantErr {
+				assert.Error(err)
+				return
+			}
+			assert.NoError(err)
+			assert.Equal(tc.wantGroups, groups)
+		})
+	}
+}
+
+func TestSetGroupImage(t *testing.T) {
+	testCases := map[string]struct {
+		groupID string
+		image   string
+		wantErr bool
+	}{
+		"image is set": {
+			groupID: "constell-worker",
+			image:   poolPath + "new-image",
+		},
+		"unknown group": {
+			groupID: "constell-other",
+			image:   poolPath + "new-image",
+			wantErr: true,
+		},
+		"unknown image": {
+			groupID: "constell-worker",
+			image:   poolPath + "unknown-image",
+			wantErr: true,
+		},
+	}
+
+	for name, tc := range testCases {
+		t.Run(name, func(t *testing.T) {
+			assert := assert.New(t)
+
+			virt := newStubVirt(t, "constell-worker-0")
+			virt.addVolume(t, "new-image", "")
+			manager := New("constell-network", virt)
+
+			err := manager.SetGroupImage(tc.groupID, tc.image)
+			if tc.wantErr {
+				assert.Error(err)
+				return
+			}
+			assert.NoError(err)
+			assert.Equal(tc.image, manager.images[tc.groupID])
+		})
+	}
+}
+
+func TestGetNode(t *testing.T) {
+	testCases := map[string]struct {
+		name     string
+		active   bool
+		wantNode Node
+		wantErr  error
+	}{
+		"running node": {
+			name:   "worker-1",
+			active: true,
+			wantNode: Node{
+				Name:       "worker-1",
+				ProviderID: "qemu:///hostname/worker-1",
+				GroupID:    "constell-worker",
+				Image:      baseImage,
+				State:      NodeStateRunning,
+			},
+		},
+		"stopped node": {
+			name: "worker-1",
+			wantNode: Node{
+				Name:       "worker-1",
+				ProviderID: "qemu:///hostname/worker-1",
+				GroupID:    "constell-worker",
+				Image:      baseImage,
+				State:      NodeStateStopped,
+			},
+		},
+		"unknown node": {
+			name:    "worker-2",
+			wantErr: ErrNotFound,
+		},
+	}
+
+	for name, tc := range testCases {
+		t.Run(name, func(t *testing.T) {
+			assert := assert.New(t)
+
+			virt := newStubVirt(t, "constell-control-plane-1", "constell-worker-1")
+			virt.active["constell-worker-1"] = tc.active
+			manager := New("constell-network", virt)
+
+			node, err := manager.GetNode(tc.name)
+			if tc.wantErr != nil {
+				assert.ErrorIs(err, tc.wantErr)
+				return
+			}
+			assert.NoError(err)
+			assert.Equal(tc.wantNode, node)
+		})
+	}
+}
+
+func TestCreateNode(t *testing.T) {
+	testCases := map[string]struct {
+		groupID     string
+		images      map[string]string
+		startErr    error
+		wantNode    Node
+		wantErr     bool
+		wantVolumes []string
+	}{
+		"node is created": {
+			groupID: "constell-worker",
+			wantNode: Node{
+				Name:       "worker-2",
+				ProviderID: "qemu:///hostname/worker-2",
+				GroupID:    "constell-worker",
+				Image:      baseImage,
+				State:      NodeStateRunning,
+			},
+			wantVolumes: []string{"constell-worker-2-boot", "constell-worker-2-state"},
+		},
+		"node uses group image": {
+			groupID: "constell-worker",
+			images:  map[string]string{"constell-worker": poolPath + "new-image"},
+			wantNode: Node{
+				Name:       "worker-2",
+				ProviderID: "qemu:///hostname/worker-2",
+				GroupID:    "constell-worker",
+				Image:      poolPath + "new-image",
+				State:      NodeStateRunning,
+			},
+			wantVolumes: []string{"constell-worker-2-boot", "constell-worker-2-state"},
+		},
+		"unknown group": {
+			groupID: "constell-other",
+			wantErr: true,
+		},
+		"starting domain fails": {
+			groupID:  "constell-worker",
+			startErr: errors.New("failed"),
+			wantErr:  true,
+		},
+	}
+
+	for name, tc := range testCases {
+		t.Run(name, func(t *testing.T) {
+			assert := assert.New(t)
+			require := require.New(t)
+
+			virt := newStubVirt(t, "constell-worker-0", "constell-worker-1")
+			virt.addVolume(t, "new-image", "")
+			virt.startErr = tc.startErr
+			manager := New("constell-network", virt)
+			for groupID, image := range tc.images {
+				manager.images[groupID] = image
+			}
+
+			node, err := manager.CreateNode(tc.groupID)
+			if tc.wantErr {
+				assert.Error(err)
+				assert.Len(virt.dhcpHosts, 2)
+				assert.NotContains(virt.volumes, poolPath+"constell-worker-2-boot")
+				assert.NotContains(virt.volumes, poolPath+"constell-worker-2-state")
+				return
+			}
+			require.NoError(err)
+			assert.Equal(tc.wantNode, node)
+			for _, volume := range tc.wantVolumes {
+				assert.Contains(virt.volumes, poolPath+volume)
+			}
+			require.Len(virt.dhcpHosts, 3)
+			var host libvirtxml.NetworkDHCPHost
+			require.NoError(host.Unmarshal(virt.dhcpHosts[2]))
+			assert.Equal("worker-2", host.Name)
+
+			// the new node is a clone of the newest node of the group
+			var def libvirtxml.Domain
+			require.NoError(def.Unmarshal(virt.domains["constell-worker-2"]))
+			assert.Empty(def.UUID)
+			require.Len(def.Devices.Interfaces, 1)
+			assert.Equal(host.MAC, def.Devices.Interfaces[0].MAC.Address)
+			assert.Equal(poolPath+"constell-worker-2-boot", def.Devices.Disks[0].Source.File.File)
+			assert.Equal(poolPath+"constell-worker-2-state", def.Devices.Disks[1].Source.File.File)
+
+			var bootVolume libvirtxml.StorageVolume
+			require.NoError(bootVolume.Unmarshal(virt.volumes[poolPath+"constell-worker-2-boot"]))
+			assert.Equal(tc.wantNode.Image, bootVolume.BackingStore.Path)
+
+			// the created node is found by its name
+			gotNode, err := manager.GetNode(node.Name)
+			require.NoError(err)
+			assert.Equal(node, gotNode)
+		})
+	}
+}
+
+func TestDeleteNode(t *testing.T) {
+	testCases := map[string]struct {
+		name      string
+		deleteErr error
+		wantErr   bool
+	}{
+		"node is deleted": {
+			name: "worker-0",
+		},
+		"unknown node": {
+			name:    "worker-5",
+			wantErr: true,
+		},
+		"deleting domain fails": {
+			name:      "worker-0",
+			deleteErr: errors.New("failed"),
+			wantErr:   true,
+		},
+	}
+
+	for name, tc := range testCases {
+		t.Run(name, func(t *testing.T) {
+			assert := assert.New(t)
+
+			virt := newStubVirt(t, "constell-worker-0", "constell-worker-1")
+			virt.deleteErr = tc.deleteErr
+			manager := New("constell-network", virt)
+
+			err := manager.DeleteNode(tc.name)
+			if tc.wantErr {
+				assert.Error(err)
+				assert.Contains(virt.domains, "constell-worker-0")
+				return
+			}
+			assert.NoError(err)
+			assert.NotContains(virt.domains, "constell-worker-0")
+			assert.Contains(virt.domains, "constell-worker-1")
+			assert.NotContains(virt.volumes, poolPath+"constell-worker-0-boot")
+			assert.NotContains(virt.volumes, poolPath+"constell-worker-0-state")
+			assert.Len(virt.dhcpHosts, 1)
+		})
+	}
+}
+
+type stubVirt struct {
+	domains   map[string]string
+	active    map[string]bool
+	volumes   map[string]string
+	dhcpHosts []string
+	listErr   error
+	startErr  error
+	deleteErr error
+}
+
+// newStubVirt creates a stub with domains shaped like the ones created by Terraform.
+func newStubVirt(t *testing.T, domainNames ...string) *stubVirt {
+	virt := &stubVirt{
+		domains: make(map[string]string),
+		active:  make(map[string]bool),
+		volumes: make(map[string]string),
+	}
+	virt.addVolume(t, path.Base(baseImage), "")
+	for i, name := range domainNames {
+		mac := fmt.Sprintf("52:54:00:00:00:%02x", i)
+		def := libvirtxml.Domain{
+			Type: "kvm",
+			Name: name,
+			UUID: fmt.Sprintf("00000000-0000-0000-0000-0000000000%02x", i),
+			Devices: &libvirtxml.DomainDeviceList{
+				Disks: []libvirtxml.DomainDisk{
+					{
+						Device: "disk",
+						Source: &libvirtxml.DomainDiskSource{File: &libvirtxml.DomainDiskSourceFile{File: virt.addVolume(t, name+"-boot", baseImage)}},
+						Target: &libvirtxml.DomainDiskTarget{Dev: "sda", Bus: "scsi"},
+					},
+					{
+						Device: "disk",
+						Source: &libvirtxml.DomainDiskSource{File: &libvirtxml.DomainDiskSourceFile{File: virt.addVolume(t, name+"-state", "")}},
+						Target: &libvirtxml.DomainDiskTarget{Dev: "vda", Bus: "virtio"},
+					},
+				},
+				Interfaces: []libvirtxml.DomainInterface{
+					{
+						MAC: &libvirtxml.DomainInterfaceMAC{Address: mac},
+						Source: &libvirtxml.DomainInterfaceSource{
+							Network: &libvirtxml.DomainInterfaceSourceNetwork{Network: "constell-network"},
+						},
+					},
+				},
+			},
+		}
+		domainXML, err := def.Marshal()
+		require.NoError(t, err)
+		virt.domains[name] = domainXML
+		virt.active[name] = true
+
+		hostXML, err := (&libvirtxml.NetworkDHCPHost{MAC: mac, Name: strings.TrimPrefix(name, "constell-")}).Marshal()
+		require.NoError(t, err)
+		virt.dhcpHosts = append(virt.dhcpHosts, hostXML)
+	}
+	return virt
+}
+
+// addVolume adds a volume to the stub and returns its path.
+func (s *stubVirt) addVolume(t *testing.T, name, backingStore string) string {
+	volume := libvirtxml.StorageVolume{
+		Name:     name,
+		Capacity: &libvirtxml.StorageVolumeSize{Unit: "bytes", Value: 1073741824},
+		Target: &libvirtxml.StorageVolumeTarget{
+			Path:   poolPath + name,
+			Format: &libvirtxml.StorageVolumeTargetFormat{Type: "qcow2"},
+		},
+	}
+	if backingStore != "" {
+		volume.BackingStore = &libvirtxml.StorageVolumeBackingStore{Path: backingStore}
+	}
+	volumeXML, err := volume.Marshal()
+	require.NoError(t, err)
+	s.volumes[poolPath+name] = volumeXML
+	return poolPath + name
+}
+
+func (s *stubVirt) ListDomainNames() ([]string, error) {
+	var names []string
+	for name := range s.domains {
+		names = append(names, name)
+	}
+	return names, s.listErr
+}
+
+func (s *stubVirt) DomainXML(name string) (string, error) {
+	domainXML, ok := s.domains[name]
+	if !ok {
+		return "", errors.New("domain not found")
+	}
+	return domainXML, nil
+}
+
+func (s *stubVirt) DomainActive(name string) (bool, error) {
+	return s.active[name], nil
+}
+
+func (s *stubVirt) StartDomain(domainXML string) error {
+	if s.startErr != nil {
+		return s.startErr
+	}
+	var def libvirtxml.Domain
+	if err := def.Unmarshal(domainXML); err != nil {
+		return err
+	}
+	s.domains[def.Name] = domainXML
+	s.active[def.Name] = true
+	return nil
+}
+
+func (s *stubVirt) DeleteDomain(name string) error {
+	if s.deleteErr != nil {
+		return s.deleteErr
+	}
+	delete(s.domains, name)
+	delete(s.active, name)
+	return nil
+}
+
+func (s *stubVirt) VolumeXML(path string) (string, error) {
+	volumeXML, ok := s.volumes[path]
+	if !ok {
+		return "", errors.New("volume not found")
+	}
+	return volumeXML, nil
+}
+
+func (s *stubVirt) VolumePool(path string) (string, error) {
+	if _, ok := s.volumes[path]; !ok {
+		return "", errors.New("volume not found")
+	}
+	return "constell-storage-pool", nil
+}
+
+func (s *stubVirt) CreateVolume(_, volumeXML string) (string, error) {
+	var volume libvirtxml.StorageVolume
+	if err := volume.Unmarshal(volumeXML); err != nil {
+		return "", err
+	}
+	s.volumes[poolPath+volume.Name] = volumeXML
+	return poolPath + volume.Name, nil
+}
+
+func (s *stubVirt) DeleteVolume(path string) error {
+	if _, ok := s.volumes[path]; !ok {
+		return errors.New("volume not found")
+	}
+	delete(s.volumes, path)
+	return nil
+}
+
+func (s *stubVirt) AddDHCPHost(_, hostXML string) error {
+	s.dhcpHosts = append(s.dhcpHosts, hostXML)
+	return nil
+}
+
+func (s *stubVirt) RemoveDHCPHost(_, hostXML string) error {
+	var remove libvirtxml.NetworkDHCPHost
+	if err := remove.Unmarshal(hostXML); err != nil {
+		return err
+	}
+	for i, existing := range s.dhcpHosts {
+		var host libvirtxml.NetworkDHCPHost
+		if err := host.Unmarshal(existing); err != nil {
+			return err
+		}
+		if host.MAC == remove.MAC {
+			s.dhcpHosts = append(s.dhcpHosts[:i], s.dhcpHosts[i+1:]...)
+			return nil
+		}
+	}
+	return errors.New("host not found")
+}
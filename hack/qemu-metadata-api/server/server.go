@@ -8,13 +8,16 @@ package server
 
 import (
 	"encoding/json"
+	"errors"
 	"fmt"
 	"io"
 	"net"
 	"net/http"
 	"strings"
 
+	"github.com/edgelesssys/constellation/v2/hack/qemu-metadata-api/scalinggroup"
 	"github.com/edgelesssys/constellation/v2/hack/qemu-metadata-api/virtwrapper"
+	"github.com/edgelesssys/constellation/v2/internal/attestation/qemu"
 	"github.com/edgelesssys/constellation/v2/internal/cloud/metadata"
 	"github.com/edgelesssys/constellation/v2/internal/logger"
 	"github.com/edgelesssys/constellation/v2/internal/role"
@@ -24,13 +27,19 @@ import (
 type Server struct {
 	log     *logger.Logger
 	virt    virConnect
+	groups  scalingGroupManager
+	akCA    akCA
 	network string
 }
 
-func New(log *logger.Logger, network string, conn virConnect) *Server {
+// New creates a new QEMU metadata API server.
+// If akCA is nil, attestation keys of the VMs can't be certified.
+func New(log *logger.Logger, network string, conn virConnect, groups scalingGroupManager, akCA akCA) *Server {
 	return &Server{
 		log:     log,
 		virt:    conn,
+		groups:  groups,
+		akCA:    akCA,
 		network: network,
 	}
 }
@@ -41,6 +50,11 @@ func (s *Server) ListenAndServe(port string) error {
 	mux.Handle("/peers", http.HandlerFunc(s.listPeers))
 	mux.Handle("/log", http.HandlerFunc(s.postLog))
 	mux.Handle("/pcrs", http.HandlerFunc(s.exportPCRs))
+	mux.Handle("/scalinggroups", http.HandlerFunc(s.listScalingGroups))
+	mux.Handle("/scalinggroups/image", http.HandlerFunc(s.setScalingGroupImage))
+	mux.Handle("/nodes", http.HandlerFunc(s.nodes))
+	mux.Handle(qemu.AKChallengePath, http.HandlerFunc(s.akChallenge))
+	mux.Handle(qemu.AKCertificatePath, http.HandlerFunc(s.akCertificate))
 
 	server := http.Server{
 		Handler: mux,
@@ -186,6 +200,197 @@ func (s *Server) exportPCRs(w http.ResponseWriter, r *http.Request) {
 	log.With(zap.String("node", nodeName)).With(zap.Any("pcrs", pcrs)).Infof("Received PCRs from node")
 }
 
+// listScalingGroups returns the scaling groups of the cluster.
+func (s *Server) listScalingGroups(w http.ResponseWriter, r *http.Request) {
+	log := s.log.With(zap.String("peer", r.RemoteAddr))
+	log.Infof("Serving GET request for /scalinggroups")
+
+	groups, err := s.groups.ListGroups()
+	if err != nil {
+		log.With(zap.Error(err)).Errorf("Failed to list scaling groups")
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	if err := json.NewEncoder(w).Encode(groups); err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	log.Infof("Request successful")
+}
+
+// setScalingGroupImage sets the image used for new nodes of a scaling group.
+func (s *Server) setScalingGroupImage(w http.ResponseWriter, r *http.Request) {
+	log := s.log.With(zap.String("peer", r.RemoteAddr))
+	if r.Method != http.MethodPost {
+		log.With(zap.String("method", r.Method)).Errorf("Invalid method for /scalinggroups/image")
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	log.Infof("Serving POST request for /scalinggroups/image")
+
+	groupID, image := r.FormValue("group"), r.FormValue("image")
+	if groupID == "" || image == "" {
+		log.Errorf("Missing scaling group or image")
+		http.Error(w, "Missing scaling group or image", http.StatusBadRequest)
+		return
+	}
+	log = log.With(zap.String("group", groupID), zap.String("image", image))
+
+	if err := s.groups.SetGroupImage(groupID, image); err != nil {
+		log.With(zap.Error(err)).Errorf("Failed to set scaling group image")
+		http.Error(w, err.Error(), scalingGroupErrorStatus(err))
+		return
+	}
+	log.Infof("Request successful")
+}
+
+// nodes gets (GET), creates (POST) or deletes (DELETE) nodes of scaling groups.
+func (s *Server) nodes(w http.ResponseWriter, r *http.Request) {
+	log := s.log.With(zap.String("peer", r.RemoteAddr))
+
+	var node scalinggroup.Node
+	var err error
+	switch r.Method {
+	case http.MethodGet:
+		log.Infof("Serving GET request for /nodes")
+		node, err = s.groups.GetNode(r.FormValue("name"))
+	case http.MethodPost:
+		log.Infof("Serving POST request for /nodes")
+		node, err = s.groups.CreateNode(r.FormValue("group"))
+	case http.MethodDelete:
+		log.Infof("Serving DELETE request for /nodes")
+		err = s.groups.DeleteNode(r.FormValue("name"))
+	default:
+		log.With(zap.String("method", r.Method)).Errorf("Invalid method for /nodes")
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+	if err != nil {
+		log.With(zap.Error(err)).Errorf("Failed to process node request")
+		http.Error(w, err.Error(), scalingGroupErrorStatus(err))
+		return
+	}
+
+	if r.Method != http.MethodDelete {
+		w.Header().Set("Content-Type", "application/json")
+		if err := json.NewEncoder(w).Encode(node); err != nil {
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+			return
+		}
+	}
+	log.Infof("Request successful")
+}
+
+// akChallenge issues a credential activation challenge for the attestation key of a VM's TPM.
+// The certificate issued for the attestation key contains the provider ID of the requesting VM.
+func (s *Server) akChallenge(w http.ResponseWriter, r *http.Request) {
+	log := s.log.With(zap.String("peer", r.RemoteAddr))
+	if !s.checkAKCARequest(w, r) {
+		return
+	}
+	log.Infof("Serving POST request for %s", qemu.AKChallengePath)
+
+	var req qemu.AKChallengeRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		log.With(zap.Error(err)).Errorf("Failed to read request body")
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	peer, err := s.findPeer(r.RemoteAddr)
+	if err != nil {
+		log.With(zap.Error(err)).Errorf("Failed to find peer in active leases")
+		http.Error(w, err.Error(), http.StatusForbidden)
+		return
+	}
+	challenge, err := s.akCA.Challenge(req, peer.ProviderID)
+	if err != nil {
+		log.With(zap.Error(err)).Errorf("Failed to create attestation key challenge")
+		http.Error(w, err.Error(), http.StatusForbidden)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	if err := json.NewEncoder(w).Encode(challenge); err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	log.Infof("Request successful")
+}
+
+// akCertificate issues a certificate for the attestation key of a VM's TPM if it solved its challenge.
+func (s *Server) akCertificate(w http.ResponseWriter, r *http.Request) {
+	log := s.log.With(zap.String("peer", r.RemoteAddr))
+	if !s.checkAKCARequest(w, r) {
+		return
+	}
+	log.Infof("Serving POST request for %s", qemu.AKCertificatePath)
+
+	var req qemu.AKCertificateRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		log.With(zap.Error(err)).Errorf("Failed to read request body")
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	certificate, err := s.akCA.Certify(req)
+	if err != nil {
+		log.With(zap.Error(err)).Errorf("Failed to certify attestation key")
+		http.Error(w, err.Error(), http.StatusForbidden)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	if err := json.NewEncoder(w).Encode(certificate); err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	log.Infof("Request successful")
+}
+
+// checkAKCARequest writes an error response and returns false if the request can't be served by the attestation key CA.
+func (s *Server) checkAKCARequest(w http.ResponseWriter, r *http.Request) bool {
+	log := s.log.With(zap.String("peer", r.RemoteAddr))
+	if r.Method != http.MethodPost {
+		log.With(zap.String("method", r.Method)).Errorf("Invalid method for %s", r.URL.Path)
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return false
+	}
+	if s.akCA == nil {
+		log.Errorf("No attestation key CA configured")
+		http.Error(w, "No attestation key CA configured", http.StatusServiceUnavailable)
+		return false
+	}
+	return true
+}
+
+// scalingGroupErrorStatus returns the HTTP status code for an error returned by the scaling group manager.
+func scalingGroupErrorStatus(err error) int {
+	if errors.Is(err, scalinggroup.ErrNotFound) {
+		return http.StatusNotFound
+	}
+	return http.StatusInternalServerError
+}
+
+// findPeer returns the active peer with the IP address of remoteAddr.
+func (s *Server) findPeer(remoteAddr string) (metadata.InstanceMetadata, error) {
+	remoteIP, _, err := net.SplitHostPort(remoteAddr)
+	if err != nil {
+		return metadata.InstanceMetadata{}, fmt.Errorf("parsing remote address: %w", err)
+	}
+	peers, err := s.listAll()
+	if err != nil {
+		return metadata.InstanceMetadata{}, fmt.Errorf("listing peers: %w", err)
+	}
+	for _, peer := range peers {
+		if peer.PublicIP == remoteIP {
+			return peer, nil
+		}
+	}
+	return metadata.InstanceMetadata{}, fmt.Errorf("no active lease for %s", remoteIP)
+}
+
 // listAll returns a list of all active peers.
 func (s *Server) listAll() ([]metadata.InstanceMetadata, error) {
 	net, err := s.virt.LookupNetworkByName(s.network)
@@ -221,3 +426,16 @@ func (s *Server) listAll() ([]metadata.InstanceMetadata, error) {
 type virConnect interface {
 	LookupNetworkByName(name string) (*virtwrapper.Network, error)
 }
+
+type akCA interface {
+	Challenge(req qemu.AKChallengeRequest, instanceID string) (qemu.AKChallengeResponse, error)
+	Certify(req qemu.AKCertificateRequest) (qemu.AKCertificateResponse, error)
+}
+
+type scalingGroupManager interface {
+	ListGroups() ([]scalinggroup.Group, error)
+	SetGroupImage(groupID, image string) error
+	GetNode(name string) (scalinggroup.Node, error)
+	CreateNode(groupID string) (scalinggroup.Node, error)
+	DeleteNode(name string) error
+}
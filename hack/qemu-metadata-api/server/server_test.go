@@ -16,9 +16,12 @@ import (
 	"strings"
 	"testing"
 
+	"github.com/edgelesssys/constellation/v2/hack/qemu-metadata-api/scalinggroup"
 	"github.com/edgelesssys/constellation/v2/hack/qemu-metadata-api/virtwrapper"
+	"github.com/edgelesssys/constellation/v2/internal/attestation/qemu"
 	"github.com/edgelesssys/constellation/v2/internal/cloud/metadata"
 	"github.com/edgelesssys/constellation/v2/internal/logger"
+	"github.com/edgelesssys/constellation/v2/internal/role"
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/require"
 	"libvirt.org/go/libvirt"
@@ -71,7 +74,7 @@ func TestListAll(t *testing.T) {
 		t.Run(name, func(t *testing.T) {
 			assert := assert.New(t)
 
-			server := New(logger.NewTest(t), "test", tc.connect)
+			server := New(logger.NewTest(t), "test", tc.connect, nil, nil)
 
 			res, err := server.listAll()
 
@@ -148,7 +151,7 @@ func TestListSelf(t *testing.T) {
 			assert := assert.New(t)
 			require := require.New(t)
 
-			server := New(logger.NewTest(t), "test", tc.connect)
+			server := New(logger.NewTest(t), "test", tc.connect, nil, nil)
 
 			req, err := http.NewRequestWithContext(context.Background(), http.MethodGet, "http://192.0.0.1/self", nil)
 			require.NoError(err)
@@ -210,7 +213,7 @@ func TestListPeers(t *testing.T) {
 			assert := assert.New(t)
 			require := require.New(t)
 
-			server := New(logger.NewTest(t), "test", tc.connect)
+			server := New(logger.NewTest(t), "test", tc.connect, nil, nil)
 
 			req, err := http.NewRequestWithContext(context.Background(), http.MethodGet, "http://192.0.0.1/peers", nil)
 			require.NoError(err)
@@ -265,7 +268,7 @@ func TestPostLog(t *testing.T) {
 			assert := assert.New(t)
 			require := require.New(t)
 
-			server := New(logger.NewTest(t), "test", &stubConnect{})
+			server := New(logger.NewTest(t), "test", &stubConnect{}, nil, nil)
 
 			req, err := http.NewRequestWithContext(context.Background(), tc.method, "http://192.0.0.1/logs", tc.message)
 			require.NoError(err)
@@ -345,7 +348,7 @@ func TestExportPCRs(t *testing.T) {
 			assert := assert.New(t)
 			require := require.New(t)
 
-			server := New(logger.NewTest(t), "test", tc.connect)
+			server := New(logger.NewTest(t), "test", tc.connect, nil, nil)
 
 			req, err := http.NewRequestWithContext(context.Background(), tc.method, "http://192.0.0.1/pcrs", strings.NewReader(tc.message))
 			require.NoError(err)
@@ -371,6 +374,294 @@ func mustMarshal(t *testing.T, v interface{}) string {
 	return string(b)
 }
 
+func TestListScalingGroups(t *testing.T) {
+	testCases := map[string]struct {
+		groups  *stubScalingGroupManager
+		wantErr bool
+	}{
+		"success": {
+			groups: &stubScalingGroupManager{
+				groups: []scalinggroup.Group{
+					{ID: "constell-control-plane", Role: role.ControlPlane, Image: "/images/image"},
+					{ID: "constell-worker", Role: role.Worker, Image: "/images/image"},
+				},
+			},
+		},
+		"ListGroups error": {
+			groups:  &stubScalingGroupManager{listErr: errors.New("error")},
+			wantErr: true,
+		},
+	}
+
+	for name, tc := range testCases {
+		t.Run(name, func(t *testing.T) {
+			assert := assert.New(t)
+			require := require.New(t)
+
+			server := New(logger.NewTest(t), "test", &stubConnect{}, tc.groups, nil)
+
+			req, err := http.NewRequestWithContext(context.Background(), http.MethodGet, "http://192.0.0.1/scalinggroups", nil)
+			require.NoError(err)
+
+			w := httptest.NewRecorder()
+			server.listScalingGroups(w, req)
+
+			if tc.wantErr {
+				assert.Equal(http.StatusInternalServerError, w.Code)
+				return
+			}
+			assert.Equal(http.StatusOK, w.Code)
+			var groups []scalinggroup.Group
+			require.NoError(json.NewDecoder(w.Body).Decode(&groups))
+			assert.Equal(tc.groups.groups, groups)
+		})
+	}
+}
+
+func TestSetScalingGroupImage(t *testing.T) {
+	testCases := map[string]struct {
+		method   string
+		target   string
+		groups   *stubScalingGroupManager
+		wantCode int
+	}{
+		"success": {
+			method:   http.MethodPost,
+			target:   "http://192.0.0.1/scalinggroups/image?group=constell-worker&image=/images/new",
+			groups:   &stubScalingGroupManager{},
+			wantCode: http.StatusOK,
+		},
+		"wrong method": {
+			method:   http.MethodGet,
+			target:   "http://192.0.0.1/scalinggroups/image?group=constell-worker&image=/images/new",
+			groups:   &stubScalingGroupManager{},
+			wantCode: http.StatusMethodNotAllowed,
+		},
+		"missing image": {
+			method:   http.MethodPost,
+			target:   "http://192.0.0.1/scalinggroups/image?group=constell-worker",
+			groups:   &stubScalingGroupManager{},
+			wantCode: http.StatusBadRequest,
+		},
+		"unknown group": {
+			method:   http.MethodPost,
+			target:   "http://192.0.0.1/scalinggroups/image?group=constell-other&image=/images/new",
+			groups:   &stubScalingGroupManager{setImageErr: scalinggroup.ErrNotFound},
+			wantCode: http.StatusNotFound,
+		},
+	}
+
+	for name, tc := range testCases {
+		t.Run(name, func(t *testing.T) {
+			assert := assert.New(t)
+			require := require.New(t)
+
+			server := New(logger.NewTest(t), "test", &stubConnect{}, tc.groups, nil)
+
+			req, err := http.NewRequestWithContext(context.Background(), tc.method, tc.target, nil)
+			require.NoError(err)
+
+			w := httptest.NewRecorder()
+			server.setScalingGroupImage(w, req)
+
+			assert.Equal(tc.wantCode, w.Code)
+			if tc.wantCode == http.StatusOK {
+				assert.Equal("constell-worker", tc.groups.imageGroupID)
+				assert.Equal("/images/new", tc.groups.image)
+			}
+		})
+	}
+}
+
+func TestNodes(t *testing.T) {
+	node := scalinggroup.Node{
+		Name:       "worker-1",
+		ProviderID: "qemu:///hostname/worker-1",
+		GroupID:    "constell-worker",
+		Image:      "/images/image",
+		State:      scalinggroup.NodeStateRunning,
+	}
+
+	testCases := map[string]struct {
+		method   string
+		target   string
+		groups   *stubScalingGroupManager
+		wantCode int
+		wantNode bool
+	}{
+		"get node": {
+			method:   http.MethodGet,
+			target:   "http://192.0.0.1/nodes?name=worker-1",
+			groups:   &stubScalingGroupManager{node: node},
+			wantCode: http.StatusOK,
+			wantNode: true,
+		},
+		"get unknown node": {
+			method:   http.MethodGet,
+			target:   "http://192.0.0.1/nodes?name=worker-2",
+			groups:   &stubScalingGroupManager{getNodeErr: scalinggroup.ErrNotFound},
+			wantCode: http.StatusNotFound,
+		},
+		"create node": {
+			method:   http.MethodPost,
+			target:   "http://192.0.0.1/nodes?group=constell-worker",
+			groups:   &stubScalingGroupManager{node: node},
+			wantCode: http.StatusOK,
+			wantNode: true,
+		},
+		"create node error": {
+			method:   http.MethodPost,
+			target:   "http://192.0.0.1/nodes?group=constell-worker",
+			groups:   &stubScalingGroupManager{createNodeErr: errors.New("error")},
+			wantCode: http.StatusInternalServerError,
+		},
+		"delete node": {
+			method:   http.MethodDelete,
+			target:   "http://192.0.0.1/nodes?name=worker-1",
+			groups:   &stubScalingGroupManager{},
+			wantCode: http.StatusOK,
+		},
+		"delete unknown node": {
+			method:   http.MethodDelete,
+			target:   "http://192.0.0.1/nodes?name=worker-2",
+			groups:   &stubScalingGroupManager{deleteNodeErr: scalinggroup.ErrNotFound},
+			wantCode: http.StatusNotFound,
+		},
+		"wrong method": {
+			method:   http.MethodPut,
+			target:   "http://192.0.0.1/nodes?name=worker-1",
+			groups:   &stubScalingGroupManager{},
+			wantCode: http.StatusMethodNotAllowed,
+		},
+	}
+
+	for name, tc := range testCases {
+		t.Run(name, func(t *testing.T) {
+			assert := assert.New(t)
+			require := require.New(t)
+
+			server := New(logger.NewTest(t), "test", &stubConnect{}, tc.groups, nil)
+
+			req, err := http.NewRequestWithContext(context.Background(), tc.method, tc.target, nil)
+			require.NoError(err)
+
+			w := httptest.NewRecorder()
+			server.nodes(w, req)
+
+			assert.Equal(tc.wantCode, w.Code)
+			if tc.wantNode {
+				var gotNode scalinggroup.Node
+				require.NoError(json.NewDecoder(w.Body).Decode(&gotNode))
+				assert.Equal(node, gotNode)
+			}
+		})
+	}
+}
+
+func TestAttestationKeyCA(t *testing.T) {
+	leases := []libvirt.NetworkDHCPLease{{IPaddr: "192.0.100.1", Hostname: "worker-0"}}
+
+	testCases := map[string]struct {
+		method         string
+		path           string
+		akCA           *stubAKCA
+		body           string
+		remoteAddr     string
+		wantCode       int
+		wantInstanceID string
+	}{
+		"challenge": {
+			method:         http.MethodPost,
+			path:           qemu.AKChallengePath,
+			akCA:           &stubAKCA{},
+			body:           "{}",
+			wantCode:       http.StatusOK,
+			wantInstanceID: "qemu:///hostname/worker-0",
+		},
+		"challenge from unknown peer": {
+			method:     http.MethodPost,
+			path:       qemu.AKChallengePath,
+			akCA:       &stubAKCA{},
+			body:       "{}",
+			remoteAddr: "192.0.100.2:1234",
+			wantCode:   http.StatusForbidden,
+		},
+		"certificate": {
+			method:   http.MethodPost,
+			path:     qemu.AKCertificatePath,
+			akCA:     &stubAKCA{},
+			body:     "{}",
+			wantCode: http.StatusOK,
+		},
+		"challenge error": {
+			method:         http.MethodPost,
+			path:           qemu.AKChallengePath,
+			akCA:           &stubAKCA{challengeErr: errors.New("error")},
+			body:           "{}",
+			wantCode:       http.StatusForbidden,
+			wantInstanceID: "qemu:///hostname/worker-0",
+		},
+		"certificate error": {
+			method:   http.MethodPost,
+			path:     qemu.AKCertificatePath,
+			akCA:     &stubAKCA{certifyErr: errors.New("error")},
+			body:     "{}",
+			wantCode: http.StatusForbidden,
+		},
+		"invalid body": {
+			method:   http.MethodPost,
+			path:     qemu.AKChallengePath,
+			akCA:     &stubAKCA{},
+			body:     "invalid",
+			wantCode: http.StatusBadRequest,
+		},
+		"invalid method": {
+			method:   http.MethodGet,
+			path:     qemu.AKCertificatePath,
+			akCA:     &stubAKCA{},
+			wantCode: http.StatusMethodNotAllowed,
+		},
+		"no CA configured": {
+			method:   http.MethodPost,
+			path:     qemu.AKChallengePath,
+			body:     "{}",
+			wantCode: http.StatusServiceUnavailable,
+		},
+	}
+
+	for name, tc := range testCases {
+		t.Run(name, func(t *testing.T) {
+			assert := assert.New(t)
+			require := require.New(t)
+
+			var ca akCA
+			if tc.akCA != nil {
+				ca = tc.akCA
+			}
+			server := New(logger.NewTest(t), "test", &stubConnect{network: stubNetwork{leases: leases}}, nil, ca)
+
+			req, err := http.NewRequestWithContext(context.Background(), tc.method, "http://192.0.0.1"+tc.path, strings.NewReader(tc.body))
+			require.NoError(err)
+			req.RemoteAddr = "192.0.100.1:1234"
+			if tc.remoteAddr != "" {
+				req.RemoteAddr = tc.remoteAddr
+			}
+
+			w := httptest.NewRecorder()
+			if tc.path == qemu.AKChallengePath {
+				server.akChallenge(w, req)
+			} else {
+				server.akCertificate(w, req)
+			}
+
+			assert.Equal(tc.wantCode, w.Code)
+			if tc.akCA != nil {
+				assert.Equal(tc.wantInstanceID, tc.akCA.instanceID)
+			}
+		})
+	}
+}
+
 type stubConnect struct {
 	network       stubNetwork
 	getNetworkErr error
@@ -392,3 +683,52 @@ func (n stubNetwork) GetDHCPLeases() ([]libvirt.NetworkDHCPLease, error) {
 func (n stubNetwork) Free() error {
 	return nil
 }
+
+type stubScalingGroupManager struct {
+	groups        []scalinggroup.Group
+	node          scalinggroup.Node
+	imageGroupID  string
+	image         string
+	listErr       error
+	setImageErr   error
+	getNodeErr    error
+	createNodeErr error
+	deleteNodeErr error
+}
+
+func (m *stubScalingGroupManager) ListGroups() ([]scalinggroup.Group, error) {
+	return m.groups, m.listErr
+}
+
+func (m *stubScalingGroupManager) SetGroupImage(groupID, image string) error {
+	m.imageGroupID = groupID
+	m.image = image
+	return m.setImageErr
+}
+
+func (m *stubScalingGroupManager) GetNode(string) (scalinggroup.Node, error) {
+	return m.node, m.getNodeErr
+}
+
+func (m *stubScalingGroupManager) CreateNode(string) (scalinggroup.Node, error) {
+	return m.node, m.createNodeErr
+}
+
+func (m *stubScalingGroupManager) DeleteNode(string) error {
+	return m.deleteNodeErr
+}
+
+type stubAKCA struct {
+	instanceID   string
+	challengeErr error
+	certifyErr   error
+}
+
+func (c *stubAKCA) Challenge(_ qemu.AKChallengeRequest, instanceID string) (qemu.AKChallengeResponse, error) {
+	c.instanceID = instanceID
+	return qemu.AKChallengeResponse{ID: "id"}, c.challengeErr
+}
+
+func (c *stubAKCA) Certify(qemu.AKCertificateRequest) (qemu.AKCertificateResponse, error) {
+	return qemu.AKCertificateResponse{Chain: [][]byte{{0x1}}}, c.certifyErr
+}
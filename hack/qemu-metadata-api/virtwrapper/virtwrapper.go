@@ -20,6 +20,147 @@ func (c *Connect) LookupNetworkByName(name string) (*Network, error) {
 	return &Network{Net: net}, nil
 }
 
+// ListDomainNames returns the names of all defined domains.
+func (c *Connect) ListDomainNames() ([]string, error) {
+	domains, err := c.Conn.ListAllDomains(libvirt.CONNECT_LIST_DOMAINS_PERSISTENT)
+	if err != nil {
+		return nil, err
+	}
+	names := make([]string, 0, len(domains))
+	for i := range domains {
+		name, err := domains[i].GetName()
+		_ = domains[i].Free()
+		if err != nil {
+			return nil, err
+		}
+		names = append(names, name)
+	}
+	return names, nil
+}
+
+// DomainXML returns the persistent definition of a domain.
+func (c *Connect) DomainXML(name string) (string, error) {
+	dom, err := c.Conn.LookupDomainByName(name)
+	if err != nil {
+		return "", err
+	}
+	defer func() { _ = dom.Free() }()
+	return dom.GetXMLDesc(libvirt.DOMAIN_XML_INACTIVE)
+}
+
+// DomainActive reports whether a domain is running.
+func (c *Connect) DomainActive(name string) (bool, error) {
+	dom, err := c.Conn.LookupDomainByName(name)
+	if err != nil {
+		return false, err
+	}
+	defer func() { _ = dom.Free() }()
+	return dom.IsActive()
+}
+
+// StartDomain defines and starts a new domain.
+func (c *Connect) StartDomain(domainXML string) error {
+	dom, err := c.Conn.DomainDefineXML(domainXML)
+	if err != nil {
+		return err
+	}
+	defer func() { _ = dom.Free() }()
+	if err := dom.Create(); err != nil {
+		_ = dom.UndefineFlags(libvirt.DOMAIN_UNDEFINE_NVRAM)
+		return err
+	}
+	return nil
+}
+
+// DeleteDomain stops and undefines a domain, including its UEFI variable store.
+func (c *Connect) DeleteDomain(name string) error {
+	dom, err := c.Conn.LookupDomainByName(name)
+	if err != nil {
+		return err
+	}
+	defer func() { _ = dom.Free() }()
+	active, err := dom.IsActive()
+	if err != nil {
+		return err
+	}
+	if active {
+		if err := dom.Destroy(); err != nil {
+			return err
+		}
+	}
+	return dom.UndefineFlags(libvirt.DOMAIN_UNDEFINE_NVRAM)
+}
+
+// VolumeXML returns the definition of the volume at path.
+func (c *Connect) VolumeXML(path string) (string, error) {
+	vol, err := c.Conn.LookupStorageVolByPath(path)
+	if err != nil {
+		return "", err
+	}
+	defer func() { _ = vol.Free() }()
+	return vol.GetXMLDesc(0)
+}
+
+// VolumePool returns the name of the storage pool containing the volume at path.
+func (c *Connect) VolumePool(path string) (string, error) {
+	vol, err := c.Conn.LookupStorageVolByPath(path)
+	if err != nil {
+		return "", err
+	}
+	defer func() { _ = vol.Free() }()
+	pool, err := vol.LookupPoolByVolume()
+	if err != nil {
+		return "", err
+	}
+	defer func() { _ = pool.Free() }()
+	return pool.GetName()
+}
+
+// CreateVolume creates a volume in a storage pool and returns its path.
+func (c *Connect) CreateVolume(poolName, volumeXML string) (string, error) {
+	pool, err := c.Conn.LookupStoragePoolByName(poolName)
+	if err != nil {
+		return "", err
+	}
+	defer func() { _ = pool.Free() }()
+	vol, err := pool.StorageVolCreateXML(volumeXML, 0)
+	if err != nil {
+		return "", err
+	}
+	defer func() { _ = vol.Free() }()
+	return vol.GetPath()
+}
+
+// DeleteVolume deletes the volume at path.
+func (c *Connect) DeleteVolume(path string) error {
+	vol, err := c.Conn.LookupStorageVolByPath(path)
+	if err != nil {
+		return err
+	}
+	defer func() { _ = vol.Free() }()
+	return vol.Delete(libvirt.STORAGE_VOL_DELETE_NORMAL)
+}
+
+// AddDHCPHost adds a DHCP host entry to the running and persistent configuration of a network.
+func (c *Connect) AddDHCPHost(network, hostXML string) error {
+	return c.updateDHCPHosts(network, libvirt.NETWORK_UPDATE_COMMAND_ADD_LAST, hostXML)
+}
+
+// RemoveDHCPHost removes a DHCP host entry from the running and persistent configuration of a network.
+func (c *Connect) RemoveDHCPHost(network, hostXML string) error {
+	return c.updateDHCPHosts(network, libvirt.NETWORK_UPDATE_COMMAND_DELETE, hostXML)
+}
+
+func (c *Connect) updateDHCPHosts(network string, cmd libvirt.NetworkUpdateCommand, hostXML string) error {
+	net, err := c.Conn.LookupNetworkByName(network)
+	if err != nil {
+		return err
+	}
+	defer func() { _ = net.Free() }()
+	return net.Update(cmd, libvirt.NETWORK_SECTION_IP_DHCP_HOST, -1, hostXML,
+		libvirt.NETWORK_UPDATE_AFFECT_LIVE|libvirt.NETWORK_UPDATE_AFFECT_CONFIG)
+}
+
 type Network struct {
 	Net virNetwork
 }
@@ -8,6 +8,7 @@ package atls
 
 import (
 	"bytes"
+	"context"
 	"crypto/ecdsa"
 	"crypto/elliptic"
 	"crypto/rand"
@@ -23,6 +24,9 @@ import (
 	"math/big"
 	"time"
 
+	"github.com/edgelesssys/constellation/v2/internal/attestation/policy"
+	"github.com/edgelesssys/constellation/v2/internal/cloud/cloudprovider"
+	"github.com/edgelesssys/constellation/v2/internal/cloud/vmtype"
 	"github.com/edgelesssys/constellation/v2/internal/crypto"
 	"github.com/edgelesssys/constellation/v2/internal/oid"
 )
@@ -30,8 +34,15 @@ import (
 // CreateAttestationServerTLSConfig creates a tls.Config object with a self-signed certificate and an embedded attestation document.
 // Pass a list of validators to enable mutual aTLS.
 // If issuer is nil, no attestation will be embedded.
-func CreateAttestationServerTLSConfig(issuer Issuer, validators []Validator) (*tls.Config, error) {
-	getConfigForClient, err := getATLSConfigForClientFunc(issuer, validators)
+func CreateAttestationServerTLSConfig(issuer Issuer, validators []Validator, opts ...Option) (*tls.Config, error) {
+	return CreateAttestationServerTLSConfigForPeer(issuer, validators, nil, opts...)
+}
+
+// CreateAttestationServerTLSConfigForPeer creates a tls.Config like CreateAttestationServerTLSConfig,
+// and records the claims of the attested client in peer.
+// If peer is not nil, the tls.Config must only be used for a single connection.
+func CreateAttestationServerTLSConfigForPeer(issuer Issuer, validators []Validator, peer *Peer, opts ...Option) (*tls.Config, error) {
+	getConfigForClient, err := getATLSConfigForClientFunc(issuer, validators, peer, newOptions(opts))
 	if err != nil {
 		return nil, err
 	}
@@ -49,7 +60,15 @@ func CreateAttestationServerTLSConfig(issuer Issuer, validators []Validator) (*t
 //
 // If no validators are set, the server's attestation document will not be verified.
 // If issuer is nil, the client will be unable to perform mutual aTLS.
-func CreateAttestationClientTLSConfig(issuer Issuer, validators []Validator) (*tls.Config, error) {
+func CreateAttestationClientTLSConfig(issuer Issuer, validators []Validator, opts ...Option) (*tls.Config, error) {
+	return CreateAttestationClientTLSConfigForPeer(issuer, validators, nil, opts...)
+}
+
+// CreateAttestationClientTLSConfigForPeer creates a tls.Config like CreateAttestationClientTLSConfig,
+// and records the claims of the attested server in peer.
+// If peer is not nil, the tls.Config must only be used for a single connection.
+func CreateAttestationClientTLSConfigForPeer(issuer Issuer, validators []Validator, peer *Peer, opts ...Option) (*tls.Config, error) {
+	o := newOptions(opts)
 	clientNonce, err := crypto.GenerateRandomBytes(crypto.RNGLengthDefault)
 	if err != nil {
 		return nil, err
@@ -58,15 +77,35 @@ func CreateAttestationClientTLSConfig(issuer Issuer, validators []Validator) (*t
 		issuer:      issuer,
 		validators:  validators,
 		clientNonce: clientNonce,
+		peer:        peer,
+		cache:       o.cache,
+	}
+	if o.cache != nil {
+		clientConn.generation = o.cache.currentGeneration()
 	}
 
-	return &tls.Config{
+	cfg := &tls.Config{
 		VerifyPeerCertificate: clientConn.verify,
 		GetClientCertificate:  clientConn.getCertificate,                      // use custom certificate for mutual aTLS connections
 		InsecureSkipVerify:    true,                                           // disable default verification because we use our own verify func
 		ServerName:            base64.StdEncoding.EncodeToString(clientNonce), // abuse ServerName as a channel to transmit the nonce
 		MinVersion:            tls.VersionTLS12,
-	}, nil
+	}
+
+	if o.cache != nil {
+		// signal the server that cached attestations are accepted
+		cfg.NextProtos = []string{cachedAttestationProtocol}
+	}
+
+	// resume sessions only with TLS 1.3, where resumption always uses a fresh key exchange
+	if o.cache != nil && o.sessionKey != "" {
+		clientConn.sessions = &clientSessionCache{cache: o.cache, key: o.sessionKey}
+		cfg.ClientSessionCache = clientConn.sessions
+		cfg.VerifyConnection = clientConn.verifyConnection
+		cfg.MinVersion = tls.VersionTLS13
+	}
+
+	return cfg, nil
 }
 
 type Issuer interface {
@@ -79,10 +118,62 @@ type Validator interface {
 	Validate(attDoc []byte, nonce []byte) ([]byte, error)
 }
 
+// ClaimsValidator is a Validator that also reports the claims established by a validated attestation.
+type ClaimsValidator interface {
+	Validator
+	ValidateWithClaims(attDoc []byte, nonce []byte) ([]byte, policy.Claims, error)
+}
+
+// PeerClaims describe the remote party of an aTLS connection, as established by validating its attestation.
+type PeerClaims struct {
+	// Variant is the OID of the attestation variant used by the peer.
+	Variant asn1.ObjectIdentifier
+	// Provider is the cloud provider the peer is running on.
+	Provider cloudprovider.Provider
+	// VMType is the type of confidential VM the peer is running on, if the provider offers several.
+	VMType vmtype.VMType
+	// Claims are the claims reported by the validator.
+	// They are only set if the validator is a ClaimsValidator.
+	policy.Claims
+}
+
+// Peer records the claims of the remote party of a single aTLS connection.
+type Peer struct {
+	claims *PeerClaims
+}
+
+// Claims returns the claims of the peer, or nil if the peer was not attested.
+// It must only be called after the handshake of the connection completed.
+func (p *Peer) Claims() *PeerClaims {
+	return p.claims
+}
+
+type peerContextKey struct{}
+
+// ContextWithPeer returns a context recording the claims of the peer in peer,
+// when used for the handshake of a connection using a tls.Config created by CreateAttestationServerTLSConfig.
+// This allows retrieving the claims of connections accepted using a shared tls.Config,
+// e.g. by setting http.Server.ConnContext, whose result is used as handshake context.
+func ContextWithPeer(ctx context.Context, peer *Peer) context.Context {
+	return context.WithValue(ctx, peerContextKey{}, peer)
+}
+
+// PeerFromContext returns the Peer set using ContextWithPeer, or nil if none is set.
+func PeerFromContext(ctx context.Context) *Peer {
+	peer, _ := ctx.Value(peerContextKey{}).(*Peer)
+	return peer
+}
+
+func (p *Peer) set(claims *PeerClaims) {
+	if p != nil {
+		p.claims = claims
+	}
+}
+
 // getATLSConfigForClientFunc returns a config setup function that is called once for every client connecting to the server.
 // This allows for different server configuration for every client.
 // In aTLS this is used to generate unique nonces for every client.
-func getATLSConfigForClientFunc(issuer Issuer, validators []Validator) (func(*tls.ClientHelloInfo) (*tls.Config, error), error) {
+func getATLSConfigForClientFunc(issuer Issuer, validators []Validator, peer *Peer, o options) (func(*tls.ClientHelloInfo) (*tls.Config, error), error) {
 	// generate key for the server
 	priv, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
 	if err != nil {
@@ -97,11 +188,21 @@ func getATLSConfigForClientFunc(issuer Issuer, validators []Validator) (func(*tl
 			return nil, err
 		}
 
+		connPeer := peer
+		if connPeer == nil {
+			connPeer = PeerFromContext(chi.Context())
+		}
+
 		serverConn := &serverConnection{
 			privKey:     priv,
 			issuer:      issuer,
 			validators:  validators,
 			serverNonce: serverNonce,
+			peer:        connPeer,
+			cache:       o.cache,
+		}
+		if o.cache != nil {
+			serverConn.generation = o.cache.currentGeneration()
 		}
 
 		cfg := &tls.Config{
@@ -110,6 +211,19 @@ func getATLSConfigForClientFunc(issuer Issuer, validators []Validator) (func(*tl
 			MinVersion:            tls.VersionTLS12,
 		}
 
+		// resumed sessions skip the attestation, so they are only allowed if the claims of the client are cached
+		if o.cache != nil {
+			ticketKeys, err := o.cache.sessionTicketKeys()
+			if err != nil {
+				return nil, fmt.Errorf("getting session ticket keys: %w", err)
+			}
+			cfg.SetSessionTicketKeys(ticketKeys)
+			cfg.VerifyConnection = serverConn.verifyConnection
+			cfg.MinVersion = tls.VersionTLS13
+		} else {
+			cfg.SessionTicketsDisabled = true
+		}
+
 		// enable mutual aTLS if any validators are set
 		if len(validators) > 0 {
 			cfg.ClientAuth = tls.RequireAnyClientCert // validity of certificate will be checked by our custom verify function
@@ -126,14 +240,12 @@ func getATLSConfigForClientFunc(issuer Issuer, validators []Validator) (func(*tl
 
 // getCertificate creates a client or server certificate for aTLS connections.
 // The certificate uses certificate extensions to embed an attestation document generated using nonce.
-func getCertificate(issuer Issuer, priv, pub any, nonce []byte) (*tls.Certificate, error) {
+func getCertificate(issuer Issuer, priv, pub any, nonce []byte, extensions ...pkix.Extension) (*tls.Certificate, error) {
 	serialNumber, err := crypto.GenerateCertificateSerialNumber()
 	if err != nil {
 		return nil, err
 	}
 
-	var extensions []pkix.Extension
-
 	// create and embed attestation if quote Issuer is available
 	if issuer != nil {
 		hash, err := hashPublicKey(pub)
@@ -193,23 +305,77 @@ func processCertificate(rawCerts [][]byte, _ [][]*x509.Certificate) (*x509.Certi
 }
 
 // verifyEmbeddedReport verifies an aTLS certificate by validating the attestation document embedded in the TLS certificate.
-func verifyEmbeddedReport(validators []Validator, cert *x509.Certificate, hash, nonce []byte) error {
+// On success, the claims of the attested peer and the time of attestation are returned.
+// Cached attestations, which are not bound to nonce, are only accepted if cache is set.
+func verifyEmbeddedReport(validators []Validator, cert *x509.Certificate, hash, nonce []byte, cache *Cache) (*PeerClaims, time.Time, error) {
+	cachedNonce := cachedAttestationNonce(cert)
+	if cachedNonce != nil && cache == nil {
+		return nil, time.Time{}, errors.New("certificate contains a cached attestation document, but caching is disabled")
+	}
+
 	for _, ex := range cert.Extensions {
 		for _, validator := range validators {
 			if ex.Id.Equal(validator.OID()) {
-				userData, err := validator.Validate(ex.Value, nonce)
+				var userData []byte
+				var claims *PeerClaims
+				attestedAt := time.Now()
+				var err error
+				if cachedNonce != nil {
+					userData, claims, attestedAt, err = cache.validate(validator, ex.Value, cachedNonce)
+				} else {
+					userData, claims, err = validate(validator, ex.Value, nonce)
+				}
 				if err != nil {
-					return err
+					return nil, time.Time{}, err
 				}
 				if !bytes.Equal(userData, hash) {
-					return errors.New("certificate hash does not match user data")
+					return nil, time.Time{}, errors.New("certificate hash does not match user data")
 				}
-				return nil
+				return claims, attestedAt, nil
 			}
 		}
 	}
 
-	return errors.New("certificate does not contain attestation document")
+	return nil, time.Time{}, errors.New("certificate does not contain attestation document")
+}
+
+// validate validates an attestation document and returns its user data and the claims of the attested peer.
+func validate(validator Validator, attDoc, nonce []byte) ([]byte, *PeerClaims, error) {
+	var userData []byte
+	var claims policy.Claims
+	var err error
+	if claimsValidator, ok := validator.(ClaimsValidator); ok {
+		userData, claims, err = claimsValidator.ValidateWithClaims(attDoc, nonce)
+	} else {
+		userData, err = validator.Validate(attDoc, nonce)
+	}
+	if err != nil {
+		return nil, nil, err
+	}
+	return userData, newPeerClaims(validator.OID(), claims), nil
+}
+
+// newPeerClaims derives the provider and VM type of a peer from its attestation variant.
+func newPeerClaims(variant asn1.ObjectIdentifier, claims policy.Claims) *PeerClaims {
+	peerClaims := &PeerClaims{Variant: variant, Claims: claims}
+	switch {
+	case variant.Equal(oid.AWS{}.OID()):
+		peerClaims.Provider = cloudprovider.AWS
+	case variant.Equal(oid.GCP{}.OID()):
+		peerClaims.Provider = cloudprovider.GCP
+	case variant.Equal(oid.AzureSNP{}.OID()):
+		peerClaims.Provider = cloudprovider.Azure
+		peerClaims.VMType = vmtype.AzureCVM
+	case variant.Equal(oid.AzureTrustedLaunch{}.OID()):
+		peerClaims.Provider = cloudprovider.Azure
+		peerClaims.VMType = vmtype.AzureTrustedLaunch
+	case variant.Equal(oid.QEMU{}.OID()):
+		peerClaims.Provider = cloudprovider.QEMU
+	case variant.Equal(oid.QEMUTDX{}.OID()):
+		peerClaims.Provider = cloudprovider.QEMU
+		peerClaims.VMType = vmtype.QEMUTDX
+	}
+	return peerClaims
 }
 
 func hashPublicKey(pub any) ([]byte, error) {
@@ -274,6 +440,13 @@ type clientConnection struct {
 	issuer      Issuer
 	validators  []Validator
 	clientNonce []byte
+	peer        *Peer
+	cache       *Cache
+	// generation is the generation of cache when the connection was created.
+	generation uint64
+	sessions   *clientSessionCache
+	// serverCached is set if the server presented a cached attestation.
+	serverCached bool
 }
 
 // verify the validity of an aTLS server certificate.
@@ -283,12 +456,42 @@ func (c *clientConnection) verify(rawCerts [][]byte, verifiedChains [][]*x509.Ce
 		return err
 	}
 
+	// a server presenting a cached attestation accepts cached attestations of the client
+	if c.cache != nil {
+		c.serverCached = hasCachedAttestation(cert)
+	}
+
 	// don't perform verification of attestation document if no validators are set
 	if len(c.validators) == 0 {
 		return nil
 	}
 
-	return verifyEmbeddedReport(c.validators, cert, hash, c.clientNonce)
+	claims, attestedAt, err := verifyEmbeddedReport(c.validators, cert, hash, c.clientNonce, c.cache)
+	if err != nil {
+		return err
+	}
+	c.peer.set(claims)
+	if c.sessions != nil {
+		c.sessions.established = &attestedSession{claims: claims, attestedAt: attestedAt, generation: c.generation}
+	}
+	return nil
+}
+
+// verifyConnection restores the claims of the server for resumed sessions, which skip verification of the certificate.
+func (c *clientConnection) verifyConnection(state tls.ConnectionState) error {
+	if !state.DidResume {
+		// the server's certificate was verified, but not attested if no validators are set
+		if c.sessions.established == nil {
+			c.sessions.established = &attestedSession{attestedAt: time.Now(), generation: c.generation}
+		}
+		return nil
+	}
+	if c.sessions.offered == nil {
+		return errors.New("resumed unknown session")
+	}
+	c.sessions.established = &c.sessions.offered.attestedSession
+	c.peer.set(c.sessions.offered.claims)
+	return nil
 }
 
 // getCertificate generates a client certificate for mutual aTLS connections.
@@ -299,6 +502,10 @@ func (c *clientConnection) getCertificate(cri *tls.CertificateRequestInfo) (*tls
 		return nil, err
 	}
 
+	if c.serverCached && c.issuer != nil {
+		return c.cache.certificate(c.issuer)
+	}
+
 	// ugly hack: abuse acceptable client CAs as a channel to receive the nonce
 	serverNonce, err := decodeNonceFromAcceptableCAs(cri.AcceptableCAs)
 	if err != nil {
@@ -314,6 +521,10 @@ type serverConnection struct {
 	validators  []Validator
 	privKey     *ecdsa.PrivateKey
 	serverNonce []byte
+	peer        *Peer
+	cache       *Cache
+	// generation is the generation of cache when the connection was created.
+	generation uint64
 }
 
 // verify the validity of a clients aTLS certificate.
@@ -324,7 +535,31 @@ func (c *serverConnection) verify(rawCerts [][]byte, verifiedChains [][]*x509.Ce
 		return err
 	}
 
-	return verifyEmbeddedReport(c.validators, cert, hash, c.serverNonce)
+	claims, attestedAt, err := verifyEmbeddedReport(c.validators, cert, hash, c.serverNonce, c.cache)
+	if err != nil {
+		return err
+	}
+	c.peer.set(claims)
+	if c.cache != nil {
+		c.cache.putServerSession(rawCerts[0], attestedSession{claims: claims, attestedAt: attestedAt, generation: c.generation})
+	}
+	return nil
+}
+
+// verifyConnection restores the claims of the client for resumed sessions, which skip verification of the certificate.
+func (c *serverConnection) verifyConnection(state tls.ConnectionState) error {
+	if !state.DidResume || len(c.validators) == 0 {
+		return nil
+	}
+	if len(state.PeerCertificates) == 0 {
+		return errors.New("resumed session has no client certificate")
+	}
+	session, err := c.cache.getServerSession(state.PeerCertificates[0].Raw)
+	if err != nil {
+		return err
+	}
+	c.peer.set(session.claims)
+	return nil
 }
 
 // getCertificate generates a client certificate for aTLS connections.
@@ -336,6 +571,10 @@ func (c *serverConnection) getCertificate(chi *tls.ClientHelloInfo) (*tls.Certif
 		return nil, err
 	}
 
+	if c.cache != nil && c.issuer != nil && acceptsCachedAttestation(chi.SupportedProtos) {
+		return c.cache.certificate(c.issuer)
+	}
+
 	// create aTLS certificate using the nonce as extracted from the client-hello message
 	return getCertificate(c.issuer, c.privKey, &c.privKey.PublicKey, clientNonce)
 }
@@ -8,13 +8,18 @@ package atls
 
 import (
 	"context"
+	"crypto/tls"
 	"encoding/asn1"
 	"errors"
 	"io"
+	"net"
 	"net/http"
 	"net/http/httptest"
 	"testing"
 
+	"github.com/edgelesssys/constellation/v2/internal/attestation/policy"
+	"github.com/edgelesssys/constellation/v2/internal/cloud/cloudprovider"
+	"github.com/edgelesssys/constellation/v2/internal/cloud/vmtype"
 	"github.com/edgelesssys/constellation/v2/internal/oid"
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/require"
@@ -180,6 +185,70 @@ func TestTLSConfig(t *testing.T) {
 	}
 }
 
+func TestPeerClaims(t *testing.T) {
+	assert := assert.New(t)
+	require := require.New(t)
+
+	gcpClaims := policy.Claims{InstanceID: "projects/constellation/zones/europe-west3-b/instances/worker-0"}
+
+	// the server is attested using a validator reporting claims, the client using a plain validator
+	var serverPeer, clientPeer Peer
+	serverConfig, err := CreateAttestationServerTLSConfigForPeer(
+		NewFakeIssuer(oid.GCP{}), []Validator{NewFakeValidator(oid.AzureTrustedLaunch{})}, &serverPeer,
+	)
+	require.NoError(err)
+	clientConfig, err := CreateAttestationClientTLSConfigForPeer(
+		NewFakeIssuer(oid.AzureTrustedLaunch{}), []Validator{fakeClaimsValidator{NewFakeValidator(oid.GCP{}), gcpClaims}}, &clientPeer,
+	)
+	require.NoError(err)
+
+	serverConn, clientConn := net.Pipe()
+	server := tls.Server(serverConn, serverConfig)
+	defer server.Close()
+	client := tls.Client(clientConn, clientConfig)
+	defer client.Close()
+
+	errChan := make(chan error)
+	go func() { errChan <- server.Handshake() }()
+	require.NoError(client.Handshake())
+	require.NoError(<-errChan)
+
+	require.NotNil(clientPeer.Claims())
+	assert.Equal(oid.GCP{}.OID(), clientPeer.Claims().Variant)
+	assert.Equal(cloudprovider.GCP, clientPeer.Claims().Provider)
+	assert.Equal(vmtype.Unknown, clientPeer.Claims().VMType)
+	assert.Equal(gcpClaims, clientPeer.Claims().Claims)
+
+	require.NotNil(serverPeer.Claims())
+	assert.Equal(oid.AzureTrustedLaunch{}.OID(), serverPeer.Claims().Variant)
+	assert.Equal(cloudprovider.Azure, serverPeer.Claims().Provider)
+	assert.Equal(vmtype.AzureTrustedLaunch, serverPeer.Claims().VMType)
+	assert.Equal(policy.Claims{}, serverPeer.Claims().Claims)
+}
+
+func TestPeerClaimsNotAttested(t *testing.T) {
+	require := require.New(t)
+
+	var clientPeer Peer
+	serverConfig, err := CreateAttestationServerTLSConfig(NewFakeIssuer(oid.Dummy{}), nil)
+	require.NoError(err)
+	clientConfig, err := CreateAttestationClientTLSConfigForPeer(nil, nil, &clientPeer)
+	require.NoError(err)
+
+	serverConn, clientConn := net.Pipe()
+	server := tls.Server(serverConn, serverConfig)
+	defer server.Close()
+	client := tls.Client(clientConn, clientConfig)
+	defer client.Close()
+
+	errChan := make(chan error)
+	go func() { errChan <- server.Handshake() }()
+	require.NoError(client.Handshake())
+	require.NoError(<-errChan)
+
+	require.Nil(clientPeer.Claims())
+}
+
 func TestClientConnectionConcurrency(t *testing.T) {
 	require := require.New(t)
 	assert := assert.New(t)
@@ -324,3 +393,13 @@ func TestServerConnectionConcurrency(t *testing.T) {
 		assert.NoError(<-errChan)
 	}
 }
+
+type fakeClaimsValidator struct {
+	*FakeValidator
+	claims policy.Claims
+}
+
+func (v fakeClaimsValidator) ValidateWithClaims(attDoc []byte, nonce []byte) ([]byte, policy.Claims, error) {
+	userData, err := v.Validate(attDoc, nonce)
+	return userData, v.claims, err
+}
@@ -0,0 +1,412 @@
+/*
+Copyright (c) Edgeless Systems GmbH
+
+SPDX-License-Identifier: AGPL-3.0-only
+*/
+
+package atls
+
+import (
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/sha256"
+	"crypto/tls"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/edgelesssys/constellation/v2/internal/crypto"
+	"github.com/edgelesssys/constellation/v2/internal/oid"
+)
+
+// clockSkew is the tolerated difference between the clocks of issuer and validator of a cached attestation.
+const clockSkew = time.Minute
+
+// Cache reuses attestations to speed up repeated aTLS handshakes.
+// Caching trades freshness for handshake latency: attestation results are reused for up to maxAge.
+//
+//   - Issuers attest a short-lived certificate key once, and present the certificate to all peers until it is maxAge old.
+//     Since the attestation is not bound to the peer's nonce, the certificate carries the nonce it was issued for,
+//     which encodes the time of attestation.
+//   - Validators only accept such certificates if they use a Cache themselves.
+//     The age of an attestation is measured from the time the validator first received it, or the time of attestation
+//     claimed by the issuer if that is earlier, so issuers can't extend the lifetime of their attestations.
+//     Results of validating these attestations are cached, so each is only validated once.
+//     Invalidate discards all results, e.g. after the expected measurements of the validators were updated.
+//     Cached attestations are only presented to peers signaling that they accept them, so both sides may enable caching independently.
+//   - TLS 1.3 session resumption is enabled. Resumed sessions keep the claims of the attested peer,
+//     and are only resumed until the attestation of the peer established in the original handshake expires.
+//
+// A Cache is safe for concurrent use, and should be shared by all connections of an application.
+type Cache struct {
+	maxAge time.Duration
+	now    func() time.Time
+
+	issueMux     sync.Mutex
+	certificates map[string]cachedCertificate
+
+	mux               sync.Mutex
+	generation        uint64
+	results           map[[sha256.Size]byte]cachedResult
+	serverSessions    map[[sha256.Size]byte]attestedSession
+	clientSessions    map[string]clientSession
+	ticketKeys        [][32]byte
+	ticketKeysRotated time.Time
+	lastPrune         time.Time
+}
+
+// NewCache creates a new Cache reusing attestation results for up to maxAge.
+func NewCache(maxAge time.Duration) *Cache {
+	return &Cache{
+		maxAge:         maxAge,
+		now:            time.Now,
+		certificates:   make(map[string]cachedCertificate),
+		results:        make(map[[sha256.Size]byte]cachedResult),
+		serverSessions: make(map[[sha256.Size]byte]attestedSession),
+		clientSessions: make(map[string]clientSession),
+	}
+}
+
+// Option configures a tls.Config created by this package.
+type Option func(*options)
+
+type options struct {
+	cache      *Cache
+	sessionKey string
+}
+
+// WithCache enables caching of attestations using cache.
+func WithCache(cache *Cache) Option {
+	return func(o *options) {
+		o.cache = cache
+	}
+}
+
+// WithSessionKey enables TLS session resumption for a client, storing the session under key, e.g. the server address.
+// It has no effect unless a Cache is set.
+// The tls.Config must only be used for a single connection.
+func WithSessionKey(key string) Option {
+	return func(o *options) {
+		o.sessionKey = key
+	}
+}
+
+func newOptions(opts []Option) options {
+	var o options
+	for _, opt := range opts {
+		opt(&o)
+	}
+	return o
+}
+
+type cachedCertificate struct {
+	certificate *tls.Certificate
+	attestedAt  time.Time
+}
+
+type cachedResult struct {
+	userData []byte
+	claims   *PeerClaims
+	// receivedAt is the time the attestation was first received. It is kept when the result is invalidated.
+	receivedAt time.Time
+	// generation is the generation of the cache the attestation was validated in.
+	generation uint64
+}
+
+// attestedSession holds the claims of the peer of a TLS session.
+type attestedSession struct {
+	claims     *PeerClaims
+	attestedAt time.Time
+	// generation is the generation of the cache when the session was established.
+	generation uint64
+}
+
+type clientSession struct {
+	attestedSession
+	state *tls.ClientSessionState
+}
+
+// certificate returns an aTLS certificate using an attestation of issuer, which is reused until it is maxAge old.
+func (c *Cache) certificate(issuer Issuer) (*tls.Certificate, error) {
+	c.issueMux.Lock()
+	defer c.issueMux.Unlock()
+
+	key := issuer.OID().String()
+	if cached, ok := c.certificates[key]; ok && c.now().Sub(cached.attestedAt) < c.maxAge {
+		return cached.certificate, nil
+	}
+
+	priv, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		return nil, err
+	}
+	attestedAt := c.now()
+	nonce, err := newCachedAttestationNonce(attestedAt)
+	if err != nil {
+		return nil, err
+	}
+	cert, err := getCertificate(issuer, priv, &priv.PublicKey, nonce, pkix.Extension{Id: cachedAttestationNonceExtension, Value: nonce})
+	if err != nil {
+		return nil, err
+	}
+
+	c.certificates[key] = cachedCertificate{certificate: cert, attestedAt: attestedAt}
+	return cert, nil
+}
+
+// validate validates a cached attestation, or returns the result of its previous validation.
+// The returned time of attestation is the time the attestation was first received,
+// or the time claimed by the issuer if that is earlier.
+func (c *Cache) validate(validator Validator, attDoc, nonce []byte) ([]byte, *PeerClaims, time.Time, error) {
+	issuedAt, err := parseCachedAttestationNonce(nonce)
+	if err != nil {
+		return nil, nil, time.Time{}, err
+	}
+	now := c.now()
+	if issuedAt.After(now.Add(clockSkew)) {
+		return nil, nil, time.Time{}, fmt.Errorf("cached attestation was issued in the future: %s", issuedAt)
+	}
+
+	hash := sha256.New()
+	hash.Write([]byte(validator.OID().String()))
+	hash.Write(nonce)
+	hash.Write(attDoc)
+	var key [sha256.Size]byte
+	copy(key[:], hash.Sum(nil))
+
+	c.mux.Lock()
+	cached, ok := c.results[key]
+	generation := c.generation
+	c.mux.Unlock()
+
+	receivedAt := now
+	if ok {
+		receivedAt = cached.receivedAt
+	}
+	attestedAt := receivedAt
+	if issuedAt.Before(attestedAt) {
+		attestedAt = issuedAt
+	}
+	if now.Sub(attestedAt) > c.maxAge {
+		return nil, nil, time.Time{}, fmt.Errorf("cached attestation expired: attested at %s", attestedAt)
+	}
+	if ok && cached.generation == generation {
+		return cached.userData, cached.claims, attestedAt, nil
+	}
+
+	userData, claims, err := validate(validator, attDoc, nonce)
+	if err != nil {
+		return nil, nil, time.Time{}, err
+	}
+
+	c.mux.Lock()
+	defer c.mux.Unlock()
+	c.pruneLocked()
+	c.results[key] = cachedResult{userData: userData, claims: claims, receivedAt: receivedAt, generation: generation}
+	return userData, claims, attestedAt, nil
+}
+
+// Invalidate discards the results of all validated attestations and all resumable sessions,
+// so that peers are validated again on their next connection.
+// It must be called when the validators used with the Cache change, e.g. after an update of the expected measurements.
+func (c *Cache) Invalidate() {
+	c.mux.Lock()
+	defer c.mux.Unlock()
+	c.generation++
+	c.serverSessions = make(map[[sha256.Size]byte]attestedSession)
+	c.clientSessions = make(map[string]clientSession)
+	// session tickets issued before can't be decrypted anymore, so clients fall back to a full handshake
+	c.ticketKeys = nil
+}
+
+// currentGeneration returns the generation of the cache, which is incremented by Invalidate.
+func (c *Cache) currentGeneration() uint64 {
+	c.mux.Lock()
+	defer c.mux.Unlock()
+	return c.generation
+}
+
+// sessionTicketKeys returns the keys for TLS session tickets.
+// Keys are rotated every maxAge, and the previous key is kept to decrypt tickets issued before the rotation.
+func (c *Cache) sessionTicketKeys() ([][32]byte, error) {
+	c.mux.Lock()
+	defer c.mux.Unlock()
+
+	now := c.now()
+	if len(c.ticketKeys) == 0 || now.Sub(c.ticketKeysRotated) >= c.maxAge {
+		var key [32]byte
+		if _, err := rand.Read(key[:]); err != nil {
+			return nil, err
+		}
+		c.ticketKeys = append([][32]byte{key}, c.ticketKeys...)
+		if len(c.ticketKeys) > 2 {
+			c.ticketKeys = c.ticketKeys[:2]
+		}
+		c.ticketKeysRotated = now
+	}
+	return append([][32]byte{}, c.ticketKeys...), nil
+}
+
+// putServerSession records the claims of a client, to be restored when the client resumes a session.
+func (c *Cache) putServerSession(clientCert []byte, session attestedSession) {
+	c.mux.Lock()
+	defer c.mux.Unlock()
+	c.pruneLocked()
+	c.serverSessions[sha256.Sum256(clientCert)] = session
+}
+
+// getServerSession returns the claims of a client resuming a session.
+// An honest client only resumes sessions for maxAge, and its attestation may have been up to maxAge old when the session was established.
+func (c *Cache) getServerSession(clientCert []byte) (attestedSession, error) {
+	c.mux.Lock()
+	defer c.mux.Unlock()
+	session, ok := c.serverSessions[sha256.Sum256(clientCert)]
+	if !ok || session.generation != c.generation {
+		return attestedSession{}, errors.New("no attestation known for resumed session")
+	}
+	if c.now().Sub(session.attestedAt) > 2*c.maxAge+clockSkew {
+		return attestedSession{}, errors.New("attestation of resumed session expired")
+	}
+	return session, nil
+}
+
+func (c *Cache) putClientSession(key string, session clientSession) {
+	c.mux.Lock()
+	defer c.mux.Unlock()
+	c.pruneLocked()
+	c.clientSessions[key] = session
+}
+
+// getClientSession returns the session stored under key, if the attestation of the server is not expired.
+func (c *Cache) getClientSession(key string) (clientSession, bool) {
+	c.mux.Lock()
+	defer c.mux.Unlock()
+	session, ok := c.clientSessions[key]
+	if !ok || session.generation != c.generation || c.now().Sub(session.attestedAt) >= c.maxAge {
+		return clientSession{}, false
+	}
+	return session, true
+}
+
+func (c *Cache) deleteClientSession(key string) {
+	c.mux.Lock()
+	defer c.mux.Unlock()
+	delete(c.clientSessions, key)
+}
+
+// pruneLocked removes expired entries. c.mux must be held.
+func (c *Cache) pruneLocked() {
+	now := c.now()
+	if now.Sub(c.lastPrune) < c.maxAge {
+		return
+	}
+	c.lastPrune = now
+
+	// results are kept until the time of attestation claimed by the issuer expired as well,
+	// so that the time an attestation was first received can't be reset by presenting it again
+	for key, result := range c.results {
+		if now.Sub(result.receivedAt) > c.maxAge+clockSkew {
+			delete(c.results, key)
+		}
+	}
+	for key, session := range c.serverSessions {
+		if now.Sub(session.attestedAt) > 2*c.maxAge+clockSkew {
+			delete(c.serverSessions, key)
+		}
+	}
+	for key, session := range c.clientSessions {
+		if now.Sub(session.attestedAt) >= c.maxAge {
+			delete(c.clientSessions, key)
+		}
+	}
+}
+
+// clientSessionCache stores the TLS session of a single client connection in a Cache.
+// Sessions are stored under a fixed key, since crypto/tls uses the ServerName, which aTLS uses to transmit the nonce.
+type clientSessionCache struct {
+	cache *Cache
+	key   string
+
+	// offered is the session offered for resumption.
+	offered *clientSession
+	// established is the attestation of the server, set once the handshake completed.
+	established *attestedSession
+}
+
+// Get returns the session for resumption.
+func (s *clientSessionCache) Get(string) (*tls.ClientSessionState, bool) {
+	session, ok := s.cache.getClientSession(s.key)
+	if !ok {
+		return nil, false
+	}
+	s.offered = &session
+	return session.state, true
+}
+
+// Put stores a session ticket received from the server, keeping the attestation of the original handshake.
+func (s *clientSessionCache) Put(_ string, state *tls.ClientSessionState) {
+	if state == nil {
+		s.cache.deleteClientSession(s.key)
+		return
+	}
+	if s.established == nil {
+		return
+	}
+	s.cache.putClientSession(s.key, clientSession{attestedSession: *s.established, state: state})
+}
+
+// newCachedAttestationNonce returns a nonce for a cached attestation, encoding the time of attestation.
+func newCachedAttestationNonce(attestedAt time.Time) ([]byte, error) {
+	random, err := crypto.GenerateRandomBytes(crypto.RNGLengthDefault)
+	if err != nil {
+		return nil, err
+	}
+	nonce := make([]byte, 8, 8+len(random))
+	binary.BigEndian.PutUint64(nonce, uint64(attestedAt.Unix()))
+	return append(nonce, random...), nil
+}
+
+// parseCachedAttestationNonce returns the time of attestation encoded in the nonce of a cached attestation.
+func parseCachedAttestationNonce(nonce []byte) (time.Time, error) {
+	if len(nonce) < 8 {
+		return time.Time{}, errors.New("invalid cached attestation nonce")
+	}
+	return time.Unix(int64(binary.BigEndian.Uint64(nonce[:8])), 0), nil
+}
+
+// cachedAttestationProtocol is offered as ALPN protocol by clients accepting cached attestations.
+// Servers never select it, so it does not interfere with the negotiation of the application protocol.
+const cachedAttestationProtocol = "constellation-atls-cache"
+
+// acceptsCachedAttestation checks whether a client accepts cached attestations.
+func acceptsCachedAttestation(supportedProtos []string) bool {
+	for _, proto := range supportedProtos {
+		if proto == cachedAttestationProtocol {
+			return true
+		}
+	}
+	return false
+}
+
+// hasCachedAttestation checks whether cert embeds a cached attestation.
+func hasCachedAttestation(cert *x509.Certificate) bool {
+	return cachedAttestationNonce(cert) != nil
+}
+
+// cachedAttestationNonce returns the nonce of the cached attestation embedded in cert, or nil if the attestation is not cached.
+func cachedAttestationNonce(cert *x509.Certificate) []byte {
+	for _, ex := range cert.Extensions {
+		if ex.Id.Equal(cachedAttestationNonceExtension) {
+			return ex.Value
+		}
+	}
+	return nil
+}
+
+// cachedAttestationNonceExtension is the OID of the certificate extension holding the nonce of a cached attestation.
+var cachedAttestationNonceExtension = oid.CachedAttestationNonce{}.OID()
@@ -0,0 +1,326 @@
+/*
+Copyright (c) Edgeless Systems GmbH
+
+SPDX-License-Identifier: AGPL-3.0-only
+*/
+
+package atls
+
+import (
+	"crypto/tls"
+	"net"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/edgelesssys/constellation/v2/internal/attestation/policy"
+	"github.com/edgelesssys/constellation/v2/internal/oid"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestCache(t *testing.T) {
+	gcpClaims := policy.Claims{InstanceID: "projects/constellation/zones/europe-west3-b/instances/control-plane-0"}
+	qemuClaims := policy.Claims{InstanceID: "worker-0"}
+
+	testCases := map[string]struct {
+		serverCache  bool
+		clientCache  bool
+		sessionKey   string
+		elapsed      time.Duration
+		invalidate   bool
+		wantIssued   int
+		wantVerified int
+		wantResumed  bool
+	}{
+		"no caching": {
+			wantIssued:   2,
+			wantVerified: 2,
+		},
+		"attestation reused": {
+			serverCache:  true,
+			clientCache:  true,
+			elapsed:      time.Minute,
+			wantIssued:   1,
+			wantVerified: 1,
+		},
+		"attestation expired": {
+			serverCache:  true,
+			clientCache:  true,
+			elapsed:      time.Hour,
+			wantIssued:   2,
+			wantVerified: 2,
+		},
+		"session resumed": {
+			serverCache:  true,
+			clientCache:  true,
+			sessionKey:   "server",
+			elapsed:      time.Minute,
+			wantIssued:   1,
+			wantVerified: 1,
+			wantResumed:  true,
+		},
+		"session of expired attestation not resumed": {
+			serverCache:  true,
+			clientCache:  true,
+			sessionKey:   "server",
+			elapsed:      time.Hour,
+			wantIssued:   2,
+			wantVerified: 2,
+		},
+		"invalidated cache validates again": {
+			serverCache:  true,
+			clientCache:  true,
+			sessionKey:   "server",
+			elapsed:      time.Minute,
+			invalidate:   true,
+			wantIssued:   1,
+			wantVerified: 2,
+		},
+		"client only caches sessions": {
+			clientCache:  true,
+			sessionKey:   "server",
+			elapsed:      time.Minute,
+			wantIssued:   2,
+			wantVerified: 2,
+		},
+		"server only caches for clients using a cache": {
+			serverCache:  true,
+			elapsed:      time.Minute,
+			wantIssued:   2,
+			wantVerified: 2,
+		},
+	}
+
+	for name, tc := range testCases {
+		t.Run(name, func(t *testing.T) {
+			assert := assert.New(t)
+			require := require.New(t)
+
+			now := time.Now()
+			clock := func() time.Time { return now }
+			var serverOpts, clientOpts []Option
+			if tc.serverCache {
+				cache := NewCache(10 * time.Minute)
+				cache.now = clock
+				serverOpts = append(serverOpts, WithCache(cache))
+			}
+			var clientCache *Cache
+			if tc.clientCache {
+				clientCache = NewCache(10 * time.Minute)
+				clientCache.now = clock
+				clientOpts = append(clientOpts, WithCache(clientCache), WithSessionKey(tc.sessionKey))
+			}
+
+			serverIssuer := &countingIssuer{Issuer: NewFakeIssuer(oid.GCP{})}
+			clientValidator := &countingValidator{fakeClaimsValidator: fakeClaimsValidator{NewFakeValidator(oid.GCP{}), gcpClaims}}
+			serverValidator := fakeClaimsValidator{NewFakeValidator(oid.QEMU{}), qemuClaims}
+
+			for i := 0; i < 2; i++ {
+				var serverPeer, clientPeer Peer
+				serverConfig, err := CreateAttestationServerTLSConfigForPeer(serverIssuer, []Validator{serverValidator}, &serverPeer, serverOpts...)
+				require.NoError(err)
+				clientConfig, err := CreateAttestationClientTLSConfigForPeer(NewFakeIssuer(oid.QEMU{}), []Validator{clientValidator}, &clientPeer, clientOpts...)
+				require.NoError(err)
+
+				state, err := handshake(t, serverConfig, clientConfig)
+				require.NoError(err)
+
+				assert.Equal(i == 1 && tc.wantResumed, state.DidResume)
+				require.NotNil(clientPeer.Claims())
+				assert.Equal(gcpClaims, clientPeer.Claims().Claims)
+				require.NotNil(serverPeer.Claims())
+				assert.Equal(qemuClaims, serverPeer.Claims().Claims)
+
+				now = now.Add(tc.elapsed)
+				if tc.invalidate {
+					clientCache.Invalidate()
+				}
+			}
+
+			assert.Equal(tc.wantIssued, serverIssuer.count())
+			assert.Equal(tc.wantVerified, clientValidator.count())
+		})
+	}
+}
+
+func TestVerifyCachedAttestation(t *testing.T) {
+	require := require.New(t)
+
+	now := time.Now()
+	issuerCache := NewCache(10 * time.Minute)
+	// the issuer claims a time of attestation in the future, to extend the lifetime of its attestation
+	issuerCache.now = func() time.Time { return now.Add(clockSkew / 2) }
+	cert, err := issuerCache.certificate(NewFakeIssuer(oid.GCP{}))
+	require.NoError(err)
+	x509Cert, hash, err := processCertificate(cert.Certificate, nil)
+	require.NoError(err)
+
+	// cached attestations are not bound to a nonce of the validator
+	_, _, err = verifyEmbeddedReport(NewFakeValidators(oid.GCP{}), x509Cert, hash, nil, nil)
+	require.Error(err)
+
+	validatorCache := NewCache(10 * time.Minute)
+	validatorCache.now = func() time.Time { return now }
+	_, attestedAt, err := verifyEmbeddedReport(NewFakeValidators(oid.GCP{}), x509Cert, hash, nil, validatorCache)
+	require.NoError(err)
+	require.Equal(now, attestedAt)
+
+	// the age of the attestation is measured from the time the validator received it
+	validatorCache.now = func() time.Time { return now.Add(10*time.Minute + clockSkew/4) }
+	_, _, err = verifyEmbeddedReport(NewFakeValidators(oid.GCP{}), x509Cert, hash, nil, validatorCache)
+	require.Error(err)
+
+	// invalidating the cache doesn't reset the time of receipt
+	validatorCache.Invalidate()
+	_, _, err = verifyEmbeddedReport(NewFakeValidators(oid.GCP{}), x509Cert, hash, nil, validatorCache)
+	require.Error(err)
+
+	validatorCache.now = func() time.Time { return now.Add(time.Hour) }
+	_, _, err = verifyEmbeddedReport(NewFakeValidators(oid.GCP{}), x509Cert, hash, nil, validatorCache)
+	require.Error(err)
+
+	validatorCache.now = func() time.Time { return now.Add(-time.Hour) }
+	_, _, err = verifyEmbeddedReport(NewFakeValidators(oid.GCP{}), x509Cert, hash, nil, validatorCache)
+	require.Error(err)
+}
+
+func BenchmarkHandshake(b *testing.B) {
+	// issuing and validating attestations is slow on real hardware, mostly due to TPM operations and fetching certificates
+	const attestationLatency = 5 * time.Millisecond
+
+	testCases := map[string]struct {
+		serverOpts []Option
+		clientOpts []Option
+	}{
+		"uncached": {},
+		"cached attestation": {
+			serverOpts: []Option{WithCache(NewCache(time.Hour))},
+			clientOpts: []Option{WithCache(NewCache(time.Hour))},
+		},
+		"session resumption": {
+			serverOpts: []Option{WithCache(NewCache(time.Hour))},
+			clientOpts: []Option{WithCache(NewCache(time.Hour)), WithSessionKey("server")},
+		},
+	}
+
+	for name, tc := range testCases {
+		b.Run(name, func(b *testing.B) {
+			issuer := slowIssuer{Issuer: NewFakeIssuer(oid.GCP{}), latency: attestationLatency}
+			validator := slowValidator{Validator: NewFakeValidator(oid.GCP{}), latency: attestationLatency}
+
+			for i := 0; i < b.N; i++ {
+				serverConfig, err := CreateAttestationServerTLSConfig(issuer, nil, tc.serverOpts...)
+				if err != nil {
+					b.Fatal(err)
+				}
+				clientConfig, err := CreateAttestationClientTLSConfig(nil, []Validator{validator}, tc.clientOpts...)
+				if err != nil {
+					b.Fatal(err)
+				}
+				if _, err := handshake(b, serverConfig, clientConfig); err != nil {
+					b.Fatal(err)
+				}
+			}
+		})
+	}
+}
+
+// handshake connects a client and a server over loopback TCP.
+// After the handshake, the server sends a byte, so the client processes session tickets.
+func handshake(tb testing.TB, serverConfig, clientConfig *tls.Config) (tls.ConnectionState, error) {
+	lis, err := net.Listen("tcp", "127.0.0.1:0")
+	require.NoError(tb, err)
+	defer lis.Close()
+
+	serverErr := make(chan error, 1)
+	go func() {
+		conn, err := lis.Accept()
+		if err != nil {
+			serverErr <- err
+			return
+		}
+		server := tls.Server(conn, serverConfig)
+		defer server.Close()
+		if err := server.Handshake(); err != nil {
+			serverErr <- err
+			return
+		}
+		_, err = server.Write([]byte{0})
+		serverErr <- err
+	}()
+
+	conn, err := net.Dial("tcp", lis.Addr().String())
+	require.NoError(tb, err)
+	client := tls.Client(conn, clientConfig)
+	defer client.Close()
+
+	if err := client.Handshake(); err != nil {
+		conn.Close()
+		<-serverErr
+		return tls.ConnectionState{}, err
+	}
+	if _, err := client.Read(make([]byte, 1)); err != nil {
+		<-serverErr
+		return tls.ConnectionState{}, err
+	}
+	return client.ConnectionState(), <-serverErr
+}
+
+type countingIssuer struct {
+	Issuer
+	mux    sync.Mutex
+	issued int
+}
+
+func (i *countingIssuer) Issue(userData []byte, nonce []byte) ([]byte, error) {
+	i.mux.Lock()
+	i.issued++
+	i.mux.Unlock()
+	return i.Issuer.Issue(userData, nonce)
+}
+
+func (i *countingIssuer) count() int {
+	i.mux.Lock()
+	defer i.mux.Unlock()
+	return i.issued
+}
+
+type countingValidator struct {
+	fakeClaimsValidator
+	mux       sync.Mutex
+	validated int
+}
+
+func (v *countingValidator) ValidateWithClaims(attDoc []byte, nonce []byte) ([]byte, policy.Claims, error) {
+	v.mux.Lock()
+	v.validated++
+	v.mux.Unlock()
+	return v.fakeClaimsValidator.ValidateWithClaims(attDoc, nonce)
+}
+
+func (v *countingValidator) count() int {
+	v.mux.Lock()
+	defer v.mux.Unlock()
+	return v.validated
+}
+
+type slowIssuer struct {
+	Issuer
+	latency time.Duration
+}
+
+func (i slowIssuer) Issue(userData []byte, nonce []byte) ([]byte, error) {
+	time.Sleep(i.latency)
+	return i.Issuer.Issue(userData, nonce)
+}
+
+type slowValidator struct {
+	Validator
+	latency time.Duration
+}
+
+func (v slowValidator) Validate(attDoc []byte, nonce []byte) ([]byte, error) {
+	time.Sleep(v.latency)
+	return v.Validator.Validate(attDoc, nonce)
+}
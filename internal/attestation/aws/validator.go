@@ -7,13 +7,18 @@ SPDX-License-Identifier: AGPL-3.0-only
 package aws
 
 import (
+	"errors"
+
 	"github.com/edgelesssys/constellation/v2/internal/oid"
 )
 
+// Validator for AWS attestation.
+// AWS attestation is not implemented yet, all attestation documents are rejected.
 type Validator struct {
 	oid.AWS
 }
 
+// Validate rejects the attestation document, since AWS attestation is not supported.
 func (a *Validator) Validate(attDoc []byte, nonce []byte) ([]byte, error) {
-	panic("aws validator not implemented")
+	return nil, errors.New("aws attestation is not supported")
 }
@@ -18,7 +18,9 @@ import (
 	"errors"
 	"fmt"
 	"math/big"
+	"strings"
 
+	"github.com/edgelesssys/constellation/v2/internal/attestation/policy"
 	"github.com/edgelesssys/constellation/v2/internal/attestation/vtpm"
 	internalCrypto "github.com/edgelesssys/constellation/v2/internal/crypto"
 	"github.com/edgelesssys/constellation/v2/internal/oid"
@@ -40,8 +42,8 @@ type Validator struct {
 	*vtpm.Validator
 }
 
-// NewValidator initializes a new Azure validator with the provided PCR values.
-func NewValidator(pcrs map[uint32][]byte, enforcedPCRs []uint32, idKeyDigest []byte, enforceIDKeyDigest bool, log vtpm.WarnLogger) *Validator {
+// NewValidator initializes a new Azure validator with the provided PCR values and attestation policy.
+func NewValidator(pcrs map[uint32][]byte, enforcedPCRs []uint32, idKeyDigest []byte, enforceIDKeyDigest bool, attestationPolicy *policy.Policy, log vtpm.WarnLogger) *Validator {
 	return &Validator{
 		Validator: vtpm.NewValidator(
 			pcrs,
@@ -49,13 +51,49 @@ func NewValidator(pcrs map[uint32][]byte, enforcedPCRs []uint32, idKeyDigest []b
 			getTrustedKey(&azureInstanceInfo{}, idKeyDigest, enforceIDKeyDigest, log),
 			validateCVM,
 			vtpm.VerifyPKCS1v15,
+			attestationPolicy,
 			log,
 		),
 	}
 }
 
-// validateCVM is a stub, since SEV-SNP attestation is already verified in trustedKeyFromSNP().
-func validateCVM(attestation vtpm.AttestationDocument) error {
+// validateCVM adds the fields of the SEV-SNP report and the ID of the VM to the claims.
+// The report itself is already verified in getTrustedKey(). The VM ID is taken from the runtime data of the HCL,
+// which is bound to the report, so it can't be forged by the guest.
+func validateCVM(attestation vtpm.AttestationDocument, claims *policy.Claims) error {
+	var instanceInfo azureInstanceInfo
+	if err := json.Unmarshal(attestation.InstanceInfo, &instanceInfo); err != nil {
+		return fmt.Errorf("unmarshalling instanceInfoRaw: %w", err)
+	}
+	report, err := newSNPReportFromBytes(instanceInfo.AttestationReport)
+	if err != nil {
+		return fmt.Errorf("parsing attestation report: %w", err)
+	}
+
+	var runtimeData runtimeData
+	if err := json.Unmarshal(instanceInfo.RuntimeData, &runtimeData); err != nil {
+		return fmt.Errorf("unmarshalling runtime data: %w", err)
+	}
+	if runtimeData.VMConfiguration.VMUniqueID == "" {
+		return errors.New("runtime data does not contain the VM ID")
+	}
+	// IMDS and the Azure API report the VM ID in lower case, the HCL in upper case
+	claims.InstanceID = policy.AzureInstanceIDPrefix + strings.ToLower(runtimeData.VMConfiguration.VMUniqueID)
+
+	claims.SNP = &policy.SNPReport{
+		Version:         report.Version,
+		GuestSVN:        report.GuestSVN,
+		Debug:           report.Policy.Debug(),
+		CurrentTCB:      report.CurrentTCB.claims(),
+		ReportedTCB:     report.ReportedTCB.claims(),
+		CommittedTCB:    report.CommittedTCB.claims(),
+		LaunchTCB:       report.LaunchTCB.claims(),
+		Measurement:     report.Measurement[:],
+		HostData:        report.HostData[:],
+		IDKeyDigest:     report.IDKeyDigest[:],
+		AuthorKeyDigest: report.AuthorKeyDigest[:],
+		ChipID:          report.ChipID[:],
+	}
 	return nil
 }
 
@@ -368,6 +406,15 @@ type tcbVersion struct {
 	Microcode  uint8   // 0x3F
 }
 
+func (t *tcbVersion) claims() policy.TCBVersion {
+	return policy.TCBVersion{
+		Bootloader: t.Bootloader,
+		TEE:        t.TEE,
+		SNP:        t.SNP,
+		Microcode:  t.Microcode,
+	}
+}
+
 func (t *tcbVersion) isVersion(expectedBootloader, expectedTEE, expectedSNP, expectedMicrocode uint8) bool {
 	return t.Bootloader >= expectedBootloader && t.TEE >= expectedTEE && t.SNP >= expectedSNP && t.Microcode >= expectedMicrocode
 }
@@ -391,5 +438,10 @@ type akPub struct {
 }
 
 type runtimeData struct {
-	Keys []akPub
+	Keys            []akPub
+	VMConfiguration vmConfiguration `json:"vm-configuration"`
+}
+
+type vmConfiguration struct {
+	VMUniqueID string `json:"vmUniqueId"`
 }
@@ -17,6 +17,7 @@ import (
 	"fmt"
 	"testing"
 
+	"github.com/edgelesssys/constellation/v2/internal/attestation/policy"
 	"github.com/edgelesssys/constellation/v2/internal/attestation/simulator"
 	"github.com/edgelesssys/constellation/v2/internal/attestation/vtpm"
 	"github.com/google/go-tpm-tools/client"
@@ -307,26 +308,57 @@ func int32ToByte(val uint32) []byte {
 }
 
 func TestValidateAzureCVM(t *testing.T) {
+	newAttDoc := func(report, runtimeData string) vtpm.AttestationDocument {
+		instanceInfo, err := newStubAzureInstanceInfo("", "", report, hex.EncodeToString([]byte(runtimeData)))
+		require.NoError(t, err)
+		instanceInfoRaw, err := json.Marshal(instanceInfo)
+		require.NoError(t, err)
+		return vtpm.AttestationDocument{InstanceInfo: instanceInfoRaw}
+	}
+	runtimeData := `{"keys":[],"vm-configuration":{"tpm-enabled":true,"vmUniqueId":"B6C98C3B-4EC7-4DA6-BD2F-7D98D20D7B75"}}`
+
 	testCases := map[string]struct {
 		attDoc  vtpm.AttestationDocument
 		wantErr bool
 	}{
 		"success": {
-			attDoc:  vtpm.AttestationDocument{},
-			wantErr: false,
+			attDoc: newAttDoc(testSNPReport, runtimeData),
+		},
+		"invalid instance info": {
+			attDoc:  vtpm.AttestationDocument{InstanceInfo: []byte("invalid")},
+			wantErr: true,
+		},
+		"report too short": {
+			attDoc:  newAttDoc(testSNPReport[:100], runtimeData),
+			wantErr: true,
+		},
+		"invalid runtime data": {
+			attDoc:  newAttDoc(testSNPReport, "invalid"),
+			wantErr: true,
+		},
+		"runtime data without VM ID": {
+			attDoc:  newAttDoc(testSNPReport, `{"keys":[],"vm-configuration":{"tpm-enabled":true}}`),
+			wantErr: true,
 		},
 	}
 
 	for name, tc := range testCases {
 		t.Run(name, func(t *testing.T) {
 			assert := assert.New(t)
+			require := require.New(t)
 
-			err := validateCVM(tc.attDoc)
+			var claims policy.Claims
+			err := validateCVM(tc.attDoc, &claims)
 			if tc.wantErr {
 				assert.Error(err)
-			} else {
-				assert.NoError(err)
+				return
 			}
+			require.NoError(err)
+			require.NotNil(claims.SNP)
+			assert.Equal("57e229e0ffe5fa92d0faddff6cae0e61c926fc9ef9afd20a8b8cfcf7129db9338cbe5bf3f6987733a2bf65d06dc38fc1", hex.EncodeToString(claims.SNP.IDKeyDigest))
+			assert.Equal(uint8(snpVersion), claims.SNP.LaunchTCB.SNP)
+			assert.False(claims.SNP.Debug)
+			assert.Equal("azure/vms/b6c98c3b-4ec7-4da6-bd2f-7d98d20d7b75", claims.InstanceID)
 		})
 	}
 }
@@ -337,7 +369,7 @@ func TestNewSNPReportFromBytes(t *testing.T) {
 		wantErr bool
 	}{
 		"success": {
-			raw:     "02000000020000001f0003000000000001000000000000000000000000000000020000000000000000000000000000000000000001000000020000000000065d01000000000000000000000000000000d288b28c3e9640f4e8167c742c1dc9487dc7e2b23b3f52c96d0fb07ce60c675400000000000000000000000000000000000000000000000000000000000000005677f1de87289e7ad2c7e99c805d0468b1a9ccd83f0d245afa5242d405da4d5725852f8c6550564870e5f3206dfb1841000000000000000000000000000000000000000000000000000000000000000057e229e0ffe5fa92d0faddff6cae0e61c926fc9ef9afd20a8b8cfcf7129db9338cbe5bf3f6987733a2bf65d06dc38fc1000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000c45c7e7a4a581d28d7f2ba99de06efc0282ace9ab62eb97044ce8aa6b215071affffffffffffffffffffffffffffffffffffffffffffffffffffffffffffffff020000000000065d000000000000000000000000000000000000000000000000d3cb828216ba0543a3d623bf904c7a908f4739b5c87856cab06e1762b15fe45e2cd4b8d88406789520b88eda62d8df36583181ca1039b735b8426953be40e812020000000000065d0133010001330100020000000000065d000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000b11a48bf77e60979777e25739acdf8b791ea9bab3ace88f173e3239acc657b02f418f5cc60615e36a95f4360334a9751000000000000000000000000000000000000000000000000c0280bf824034fbff4b18899f77f2557b98fa0bf3572d062bf860dbf9b9b498fd2a377f0209f6b993687f791981de71b0000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000",
+			raw:     testSNPReport,
 			wantErr: false,
 		},
 		"too short": {
@@ -366,6 +398,9 @@ func TestNewSNPReportFromBytes(t *testing.T) {
 	}
 }
 
+// testSNPReport is a raw SEV-SNP attestation report of an Azure CVM, hex encoded.
+const testSNPReport = "02000000020000001f0003000000000001000000000000000000000000000000020000000000000000000000000000000000000001000000020000000000065d01000000000000000000000000000000d288b28c3e9640f4e8167c742c1dc9487dc7e2b23b3f52c96d0fb07ce60c675400000000000000000000000000000000000000000000000000000000000000005677f1de87289e7ad2c7e99c805d0468b1a9ccd83f0d245afa5242d405da4d5725852f8c6550564870e5f3206dfb1841000000000000000000000000000000000000000000000000000000000000000057e229e0ffe5fa92d0faddff6cae0e61c926fc9ef9afd20a8b8cfcf7129db9338cbe5bf3f6987733a2bf65d06dc38fc1000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000c45c7e7a4a581d28d7f2ba99de06efc0282ace9ab62eb97044ce8aa6b215071affffffffffffffffffffffffffffffffffffffffffffffffffffffffffffffff020000000000065d000000000000000000000000000000000000000000000000d3cb828216ba0543a3d623bf904c7a908f4739b5c87856cab06e1762b15fe45e2cd4b8d88406789520b88eda62d8df36583181ca1039b735b8426953be40e812020000000000065d0133010001330100020000000000065d000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000b11a48bf77e60979777e25739acdf8b791ea9bab3ace88f173e3239acc657b02f418f5cc60615e36a95f4360334a9751000000000000000000000000000000000000000000000000c0280bf824034fbff4b18899f77f2557b98fa0bf3572d062bf860dbf9b9b498fd2a377f0209f6b993687f791981de71b0000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000"
+
 type stubAzureInstanceInfo struct {
 	Vcek              []byte
 	CertChain         []byte
@@ -9,6 +9,7 @@ package trustedlaunch
 import (
 	"crypto"
 
+	"github.com/edgelesssys/constellation/v2/internal/attestation/policy"
 	"github.com/edgelesssys/constellation/v2/internal/attestation/vtpm"
 	"github.com/edgelesssys/constellation/v2/internal/oid"
 	"github.com/google/go-tpm/tpm2"
@@ -20,8 +21,8 @@ type Validator struct {
 	*vtpm.Validator
 }
 
-// NewValidator initializes a new Azure validator with the provided PCR values.
-func NewValidator(pcrs map[uint32][]byte, enforcedPCRs []uint32, log vtpm.WarnLogger) *Validator {
+// NewValidator initializes a new Azure validator with the provided PCR values and attestation policy.
+func NewValidator(pcrs map[uint32][]byte, enforcedPCRs []uint32, attestationPolicy *policy.Policy, log vtpm.WarnLogger) *Validator {
 	return &Validator{
 		Validator: vtpm.NewValidator(
 			pcrs,
@@ -29,6 +30,7 @@ func NewValidator(pcrs map[uint32][]byte, enforcedPCRs []uint32, log vtpm.WarnLo
 			trustedKey,
 			validateVM,
 			vtpm.VerifyPKCS1v15,
+			attestationPolicy,
 			log,
 		),
 	}
@@ -44,6 +46,6 @@ func trustedKey(akPub, instanceInfo []byte) (crypto.PublicKey, error) {
 }
 
 // validateVM returns nil.
-func validateVM(attestation vtpm.AttestationDocument) error {
+func validateVM(attestation vtpm.AttestationDocument, _ *policy.Claims) error {
 	return nil
 }
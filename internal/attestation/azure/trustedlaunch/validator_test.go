@@ -70,7 +70,7 @@ func TestValidateAzureCVM(t *testing.T) {
 		t.Run(name, func(t *testing.T) {
 			assert := assert.New(t)
 
-			err := validateVM(tc.attDoc)
+			err := validateVM(tc.attDoc, nil)
 			if tc.wantErr {
 				assert.Error(err)
 			} else {
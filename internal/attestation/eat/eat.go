@@ -0,0 +1,217 @@
+/*
+Copyright (c) Edgeless Systems GmbH
+
+SPDX-License-Identifier: AGPL-3.0-only
+*/
+
+/*
+Package eat implements attestation result tokens.
+
+An attestation result token is an Entity Attestation Token (EAT, RFC draft-ietf-rats-eat) in JWT form.
+It is issued by a verifier after successfully appraising the attestation of a Constellation node,
+and summarizes the appraised claims. Relying parties following the RATS passport model
+only need to verify the token's signature, and don't need to understand Constellation's attestation formats.
+
+Tokens are signed using ES256. The public key of a verifier can be distributed as JSON Web Key Set.
+*/
+package eat
+
+import (
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/sha256"
+	"encoding/asn1"
+	"encoding/base64"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"strconv"
+	"time"
+
+	"github.com/edgelesssys/constellation/v2/internal/atls"
+	"github.com/edgelesssys/constellation/v2/internal/attestation/policy"
+	"gopkg.in/square/go-jose.v2"
+	"gopkg.in/square/go-jose.v2/jwt"
+)
+
+const (
+	// Profile identifies the claims set of Constellation attestation result tokens.
+	Profile = "tag:edgeless.systems,2022:constellation-attestation-result"
+	// StatusAffirming is the appraisal status of a node whose attestation was successfully verified.
+	StatusAffirming = "affirming"
+	// DefaultValidity is the default lifetime of a token.
+	DefaultValidity = 5 * time.Minute
+)
+
+// Validator is an atls.Validator that can report the claims established by a validated attestation.
+type Validator = atls.ClaimsValidator
+
+// Appraisal is the result of successfully validating the attestation of a node.
+type Appraisal struct {
+	// Provider is the cloud provider the node is running on.
+	Provider string
+	// Variant is the OID of the attestation variant used by the node.
+	Variant asn1.ObjectIdentifier
+	// Nonce is the freshness nonce the attestation was bound to.
+	Nonce []byte
+	// UserData is the data signed by the attestation, e.g., a public key of the node.
+	UserData []byte
+	// Claims are the claims established by the attestation.
+	// The attested identity of the node is used as subject of the token.
+	Claims policy.Claims
+	// Policy is the source of the attestation policy that was enforced, if any.
+	Policy string
+}
+
+// Claims are the claims of an attestation result token.
+type Claims struct {
+	jwt.Claims
+	// Nonce is the base64url encoded nonce the attestation was bound to.
+	Nonce string `json:"eat_nonce"`
+	// UserDataDigest is the hex encoded SHA-256 digest of the data signed by the attestation.
+	// Relying parties compare it to the data, e.g., a public key, the node presents along with the token.
+	UserDataDigest string `json:"user_data_digest"`
+	// Profile is always set to Profile.
+	Profile string `json:"eat_profile"`
+	// Status is the appraisal status of the node.
+	Status string `json:"status"`
+	// Provider is the cloud provider the node is running on.
+	Provider string `json:"provider"`
+	// Variant is the OID of the attestation variant used by the node.
+	Variant string `json:"attestation_variant"`
+	// Measurements are the hex encoded, verified measurements of the node, keyed by their decimal index.
+	Measurements map[string]string `json:"measurements"`
+	// PolicyDigest is the hex encoded SHA-256 digest of the enforced attestation policy.
+	PolicyDigest string `json:"policy_digest,omitempty"`
+}
+
+// Signer issues attestation result tokens.
+type Signer struct {
+	issuer   string
+	keyID    string
+	key      *ecdsa.PrivateKey
+	signer   jose.Signer
+	validity time.Duration
+	now      func() time.Time
+}
+
+// NewSigner creates a new Signer for tokens issued by issuer.
+// key must be a P-256 key.
+func NewSigner(issuer string, key *ecdsa.PrivateKey, validity time.Duration) (*Signer, error) {
+	keyID, err := KeyID(&key.PublicKey)
+	if err != nil {
+		return nil, err
+	}
+
+	signer, err := jose.NewSigner(
+		jose.SigningKey{Algorithm: jose.ES256, Key: key},
+		(&jose.SignerOptions{}).WithType("JWT").WithHeader("kid", keyID),
+	)
+	if err != nil {
+		return nil, fmt.Errorf("creating token signer: %w", err)
+	}
+
+	return &Signer{
+		issuer:   issuer,
+		keyID:    keyID,
+		key:      key,
+		signer:   signer,
+		validity: validity,
+		now:      time.Now,
+	}, nil
+}
+
+// Sign issues a signed token for the given appraisal.
+// Tokens are only issued for attestations that identify the attested node.
+func (s *Signer) Sign(appraisal Appraisal) (string, error) {
+	subject := appraisal.Claims.Identity()
+	if subject == "" {
+		return "", errors.New("attestation does not identify the node")
+	}
+	now := s.now()
+
+	measurements := make(map[string]string, len(appraisal.Claims.Measurements))
+	for idx, value := range appraisal.Claims.Measurements {
+		measurements[strconv.FormatUint(uint64(idx), 10)] = hex.EncodeToString(value)
+	}
+
+	userDataDigest := sha256.Sum256(appraisal.UserData)
+	claims := Claims{
+		Claims: jwt.Claims{
+			Issuer:    s.issuer,
+			Subject:   subject,
+			IssuedAt:  jwt.NewNumericDate(now),
+			NotBefore: jwt.NewNumericDate(now),
+			Expiry:    jwt.NewNumericDate(now.Add(s.validity)),
+		},
+		Nonce:          base64.RawURLEncoding.EncodeToString(appraisal.Nonce),
+		UserDataDigest: hex.EncodeToString(userDataDigest[:]),
+		Profile:        Profile,
+		Status:         StatusAffirming,
+		Provider:       appraisal.Provider,
+		Variant:        appraisal.Variant.String(),
+		Measurements:   measurements,
+	}
+	if appraisal.Policy != "" {
+		digest := sha256.Sum256([]byte(appraisal.Policy))
+		claims.PolicyDigest = hex.EncodeToString(digest[:])
+	}
+
+	token, err := jwt.Signed(s.signer).Claims(claims).CompactSerialize()
+	if err != nil {
+		return "", fmt.Errorf("signing token: %w", err)
+	}
+	return token, nil
+}
+
+// PublicKeySet returns the public key of the signer as JSON Web Key Set.
+func (s *Signer) PublicKeySet() jose.JSONWebKeySet {
+	return jose.JSONWebKeySet{
+		Keys: []jose.JSONWebKey{
+			{
+				Key:       &s.key.PublicKey,
+				KeyID:     s.keyID,
+				Algorithm: string(jose.ES256),
+				Use:       "sig",
+			},
+		},
+	}
+}
+
+// Verify verifies the signature of a token issued by issuer, and checks it is valid at the current time.
+// If nonce is not nil, the token must be bound to it.
+func Verify(token string, key crypto.PublicKey, issuer string, nonce []byte) (Claims, error) {
+	parsed, err := jwt.ParseSigned(token)
+	if err != nil {
+		return Claims{}, fmt.Errorf("parsing token: %w", err)
+	}
+	for _, header := range parsed.Headers {
+		if header.Algorithm != string(jose.ES256) {
+			return Claims{}, fmt.Errorf("unsupported signature algorithm %q", header.Algorithm)
+		}
+	}
+
+	var claims Claims
+	if err := parsed.Claims(key, &claims); err != nil {
+		return Claims{}, fmt.Errorf("verifying token signature: %w", err)
+	}
+	if err := claims.ValidateWithLeeway(jwt.Expected{Issuer: issuer, Time: time.Now()}, jwt.DefaultLeeway); err != nil {
+		return Claims{}, fmt.Errorf("validating token: %w", err)
+	}
+	if claims.Profile != Profile {
+		return Claims{}, fmt.Errorf("unexpected token profile %q", claims.Profile)
+	}
+	if nonce != nil && claims.Nonce != base64.RawURLEncoding.EncodeToString(nonce) {
+		return Claims{}, errors.New("token is not bound to the expected nonce")
+	}
+	return claims, nil
+}
+
+// KeyID returns the JWK thumbprint (RFC 7638) of a public key, used to identify a signer's key.
+func KeyID(key crypto.PublicKey) (string, error) {
+	thumbprint, err := (&jose.JSONWebKey{Key: key}).Thumbprint(crypto.SHA256)
+	if err != nil {
+		return "", fmt.Errorf("computing key thumbprint: %w", err)
+	}
+	return base64.RawURLEncoding.EncodeToString(thumbprint), nil
+}
@@ -0,0 +1,310 @@
+/*
+Copyright (c) Edgeless Systems GmbH
+
+SPDX-License-Identifier: AGPL-3.0-only
+*/
+
+package eat
+
+import (
+	"bytes"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/sha256"
+	"crypto/x509"
+	"encoding/asn1"
+	"encoding/hex"
+	"encoding/pem"
+	"testing"
+	"time"
+
+	"github.com/edgelesssys/constellation/v2/internal/attestation/policy"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/goleak"
+)
+
+func TestMain(m *testing.M) {
+	goleak.VerifyTestMain(m)
+}
+
+func TestSignVerify(t *testing.T) {
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	require.NoError(t, err)
+	otherKey, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	require.NoError(t, err)
+
+	appraisal := Appraisal{
+		Provider: "gcp",
+		Variant:  asn1.ObjectIdentifier{1, 3, 9900, 2, 1},
+		Nonce:    []byte("nonce"),
+		UserData: []byte("user data"),
+		Claims: policy.Claims{
+			Measurements: map[uint32][]byte{4: {0xAA, 0xBB}},
+			InstanceID:   "projects/p/zones/z/instances/i",
+		},
+		Policy: "true",
+	}
+
+	testCases := map[string]struct {
+		now       time.Time
+		verifyKey *ecdsa.PublicKey
+		issuer    string
+		nonce     []byte
+		wantErr   bool
+	}{
+		"success": {
+			now:       time.Now(),
+			verifyKey: &key.PublicKey,
+			issuer:    "constellation-cli",
+			nonce:     []byte("nonce"),
+		},
+		"nonce not checked": {
+			now:       time.Now(),
+			verifyKey: &key.PublicKey,
+			issuer:    "constellation-cli",
+		},
+		"wrong key": {
+			now:       time.Now(),
+			verifyKey: &otherKey.PublicKey,
+			issuer:    "constellation-cli",
+			nonce:     []byte("nonce"),
+			wantErr:   true,
+		},
+		"wrong issuer": {
+			now:       time.Now(),
+			verifyKey: &key.PublicKey,
+			issuer:    "someone-else",
+			nonce:     []byte("nonce"),
+			wantErr:   true,
+		},
+		"wrong nonce": {
+			now:       time.Now(),
+			verifyKey: &key.PublicKey,
+			issuer:    "constellation-cli",
+			nonce:     []byte("other nonce"),
+			wantErr:   true,
+		},
+		"expired": {
+			now:       time.Now().Add(-time.Hour),
+			verifyKey: &key.PublicKey,
+			issuer:    "constellation-cli",
+			nonce:     []byte("nonce"),
+			wantErr:   true,
+		},
+	}
+
+	for name, tc := range testCases {
+		t.Run(name, func(t *testing.T) {
+			assert := assert.New(t)
+			require := require.New(t)
+
+			signer, err := NewSigner("constellation-cli", key, DefaultValidity)
+			require.NoError(err)
+			signer.now = func() time.Time { return tc.now }
+
+			token, err := signer.Sign(appraisal)
+			require.NoError(err)
+
+			claims, err := Verify(token, tc.verifyKey, tc.issuer, tc.nonce)
+			if tc.wantErr {
+				assert.Error(err)
+				return
+			}
+			require.NoError(err)
+			assert.Equal("projects/p/zones/z/instances/i", claims.Subject)
+			assert.Equal("gcp", claims.Provider)
+			assert.Equal("1.3.9900.2.1", claims.Variant)
+			assert.Equal(StatusAffirming, claims.Status)
+			assert.Equal(Profile, claims.Profile)
+			assert.Equal(map[string]string{"4": "aabb"}, claims.Measurements)
+			assert.Equal("b5bea41b6c623f7c09f1bf24dcae58ebab3c0cdd90ad966bc43a45b44867e12b", claims.PolicyDigest)
+			userDataDigest := sha256.Sum256([]byte("user data"))
+			assert.Equal(hex.EncodeToString(userDataDigest[:]), claims.UserDataDigest)
+		})
+	}
+}
+
+func TestSignRequiresIdentity(t *testing.T) {
+	testCases := map[string]struct {
+		claims      policy.Claims
+		wantSubject string
+		wantErr     bool
+	}{
+		"instance ID": {
+			claims:      policy.Claims{InstanceID: "instance", AttestationKeyDigest: []byte{0xAB}},
+			wantSubject: "instance",
+		},
+		"attestation key digest": {
+			claims:      policy.Claims{AttestationKeyDigest: []byte{0xAB}},
+			wantSubject: "ak:ab",
+		},
+		"no identity": {
+			claims:  policy.Claims{Measurements: map[uint32][]byte{4: {0xAA}}},
+			wantErr: true,
+		},
+	}
+
+	for name, tc := range testCases {
+		t.Run(name, func(t *testing.T) {
+			assert := assert.New(t)
+			require := require.New(t)
+
+			key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+			require.NoError(err)
+			signer, err := NewSigner("verifier", key, DefaultValidity)
+			require.NoError(err)
+
+			token, err := signer.Sign(Appraisal{Claims: tc.claims})
+			if tc.wantErr {
+				assert.Error(err)
+				return
+			}
+			require.NoError(err)
+			claims, err := Verify(token, &key.PublicKey, "verifier", nil)
+			require.NoError(err)
+			assert.Equal(tc.wantSubject, claims.Subject)
+		})
+	}
+}
+
+func TestPublicKeySet(t *testing.T) {
+	assert := assert.New(t)
+	require := require.New(t)
+
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	require.NoError(err)
+	signer, err := NewSigner("verifier", key, DefaultValidity)
+	require.NoError(err)
+
+	token, err := signer.Sign(Appraisal{Claims: policy.Claims{InstanceID: "instance"}})
+	require.NoError(err)
+
+	keys := signer.PublicKeySet()
+	require.Len(keys.Keys, 1)
+	keyID, err := KeyID(&key.PublicKey)
+	require.NoError(err)
+	matching := keys.Key(keyID)
+	require.Len(matching, 1)
+
+	_, err = Verify(token, matching[0].Key, "verifier", nil)
+	assert.NoError(err)
+}
+
+func TestDeriveSigningKey(t *testing.T) {
+	testCases := map[string]struct {
+		secret  []byte
+		wantErr bool
+	}{
+		"success": {
+			secret: bytes.Repeat([]byte{0x42}, SigningKeyLength),
+		},
+		"all ones": {
+			secret: bytes.Repeat([]byte{0xFF}, SigningKeyLength),
+		},
+		"all zeros": {
+			secret: make([]byte, SigningKeyLength),
+		},
+		"too short": {
+			secret:  bytes.Repeat([]byte{0x42}, 32),
+			wantErr: true,
+		},
+	}
+
+	for name, tc := range testCases {
+		t.Run(name, func(t *testing.T) {
+			assert := assert.New(t)
+			require := require.New(t)
+
+			key, err := DeriveSigningKey(tc.secret)
+			if tc.wantErr {
+				assert.Error(err)
+				return
+			}
+			require.NoError(err)
+			assert.True(key.Curve.IsOnCurve(key.X, key.Y))
+
+			// derivation is deterministic
+			again, err := DeriveSigningKey(tc.secret)
+			require.NoError(err)
+			assert.True(key.Equal(again))
+
+			// the derived key must be usable for signing
+			signer, err := NewSigner("verifier", key, DefaultValidity)
+			require.NoError(err)
+			token, err := signer.Sign(Appraisal{Claims: policy.Claims{InstanceID: "instance"}})
+			require.NoError(err)
+			_, err = Verify(token, &key.PublicKey, "verifier", nil)
+			assert.NoError(err)
+		})
+	}
+}
+
+func TestGenerateSigningKey(t *testing.T) {
+	assert := assert.New(t)
+	require := require.New(t)
+
+	raw, err := GenerateSigningKey()
+	require.NoError(err)
+	key, err := ParseSigningKey(raw)
+	require.NoError(err)
+
+	other, err := GenerateSigningKey()
+	require.NoError(err)
+	otherKey, err := ParseSigningKey(other)
+	require.NoError(err)
+	assert.False(key.Equal(otherKey))
+}
+
+func TestParseSigningKey(t *testing.T) {
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	require.NoError(t, err)
+	p384Key, err := ecdsa.GenerateKey(elliptic.P384(), rand.Reader)
+	require.NoError(t, err)
+
+	ecDER, err := x509.MarshalECPrivateKey(key)
+	require.NoError(t, err)
+	pkcs8DER, err := x509.MarshalPKCS8PrivateKey(key)
+	require.NoError(t, err)
+	p384DER, err := x509.MarshalECPrivateKey(p384Key)
+	require.NoError(t, err)
+
+	testCases := map[string]struct {
+		raw     []byte
+		wantErr bool
+	}{
+		"EC private key": {
+			raw: pem.EncodeToMemory(&pem.Block{Type: "EC PRIVATE KEY", Bytes: ecDER}),
+		},
+		"PKCS #8 private key": {
+			raw: pem.EncodeToMemory(&pem.Block{Type: "PRIVATE KEY", Bytes: pkcs8DER}),
+		},
+		"P-384 key": {
+			raw:     pem.EncodeToMemory(&pem.Block{Type: "EC PRIVATE KEY", Bytes: p384DER}),
+			wantErr: true,
+		},
+		"unsupported block type": {
+			raw:     pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: ecDER}),
+			wantErr: true,
+		},
+		"no PEM": {
+			raw:     []byte("not a key"),
+			wantErr: true,
+		},
+	}
+
+	for name, tc := range testCases {
+		t.Run(name, func(t *testing.T) {
+			assert := assert.New(t)
+
+			parsed, err := ParseSigningKey(tc.raw)
+			if tc.wantErr {
+				assert.Error(err)
+				return
+			}
+			assert.NoError(err)
+			assert.True(key.Equal(parsed))
+		})
+	}
+}
@@ -0,0 +1,83 @@
+/*
+Copyright (c) Edgeless Systems GmbH
+
+SPDX-License-Identifier: AGPL-3.0-only
+*/
+
+package eat
+
+import (
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/x509"
+	"encoding/pem"
+	"errors"
+	"fmt"
+	"math/big"
+)
+
+// SigningKeyLength is the number of bytes of key material required by DeriveSigningKey.
+// The additional 16 bytes over the size of the curve order make the bias of the reduction negligible.
+const SigningKeyLength = 48
+
+// DeriveSigningKey deterministically derives a P-256 signing key from secret key material,
+// so that all replicas of a verifier sign with the same key.
+func DeriveSigningKey(secret []byte) (*ecdsa.PrivateKey, error) {
+	if len(secret) < SigningKeyLength {
+		return nil, fmt.Errorf("key material too short: need %d bytes, got %d", SigningKeyLength, len(secret))
+	}
+
+	curve := elliptic.P256()
+	// d = secret mod (n-1) + 1, which is in [1, n-1]
+	n := new(big.Int).Sub(curve.Params().N, big.NewInt(1))
+	d := new(big.Int).SetBytes(secret)
+	d.Mod(d, n)
+	d.Add(d, big.NewInt(1))
+
+	key := &ecdsa.PrivateKey{D: d}
+	key.PublicKey.Curve = curve
+	key.PublicKey.X, key.PublicKey.Y = curve.ScalarBaseMult(d.FillBytes(make([]byte, 32)))
+	return key, nil
+}
+
+// GenerateSigningKey generates a new P-256 signing key and returns it PEM encoded.
+func GenerateSigningKey() ([]byte, error) {
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		return nil, fmt.Errorf("generating signing key: %w", err)
+	}
+	der, err := x509.MarshalECPrivateKey(key)
+	if err != nil {
+		return nil, fmt.Errorf("marshaling signing key: %w", err)
+	}
+	return pem.EncodeToMemory(&pem.Block{Type: "EC PRIVATE KEY", Bytes: der}), nil
+}
+
+// ParseSigningKey parses a PEM encoded EC or PKCS #8 P-256 private key.
+func ParseSigningKey(raw []byte) (*ecdsa.PrivateKey, error) {
+	block, _ := pem.Decode(raw)
+	if block == nil {
+		return nil, errors.New("no PEM data found")
+	}
+
+	var key interface{}
+	var err error
+	switch block.Type {
+	case "EC PRIVATE KEY":
+		key, err = x509.ParseECPrivateKey(block.Bytes)
+	case "PRIVATE KEY":
+		key, err = x509.ParsePKCS8PrivateKey(block.Bytes)
+	default:
+		return nil, fmt.Errorf("unsupported PEM block type %q", block.Type)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("parsing private key: %w", err)
+	}
+
+	ecKey, ok := key.(*ecdsa.PrivateKey)
+	if !ok || ecKey.Curve != elliptic.P256() {
+		return nil, errors.New("signing key must be a P-256 ECDSA key")
+	}
+	return ecKey, nil
+}
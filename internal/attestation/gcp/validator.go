@@ -18,6 +18,7 @@ import (
 	"time"
 
 	compute "cloud.google.com/go/compute/apiv1"
+	"github.com/edgelesssys/constellation/v2/internal/attestation/policy"
 	"github.com/edgelesssys/constellation/v2/internal/attestation/vtpm"
 	"github.com/edgelesssys/constellation/v2/internal/oid"
 	"github.com/google/go-tpm-tools/proto/attest"
@@ -33,15 +34,16 @@ type Validator struct {
 	*vtpm.Validator
 }
 
-// NewValidator initializes a new GCP validator with the provided PCR values.
-func NewValidator(pcrs map[uint32][]byte, enforcedPCRs []uint32, log vtpm.WarnLogger) *Validator {
+// NewValidator initializes a new GCP validator with the provided PCR values and attestation policy.
+func NewValidator(pcrs map[uint32][]byte, enforcedPCRs []uint32, attestationPolicy *policy.Policy, log vtpm.WarnLogger) *Validator {
 	return &Validator{
 		Validator: vtpm.NewValidator(
 			pcrs,
 			enforcedPCRs,
 			trustedKeyFromGCEAPI(newInstanceClient),
-			gceNonHostInfoEvent,
+			validateCVM,
 			vtpm.VerifyPKCS1v15,
+			attestationPolicy,
 			log,
 		),
 	}
@@ -103,9 +105,24 @@ func trustedKeyFromGCEAPI(getClient func(ctx context.Context, opts ...option.Cli
 	}
 }
 
+// validateCVM checks that the VM is a confidential GCE VM, and sets the ID of the instance in claims.
+// The instance info is trustworthy at this point, since the attestation key was retrieved for this instance from the GCE API.
+func validateCVM(attDoc vtpm.AttestationDocument, claims *policy.Claims) error {
+	if err := gceNonHostInfoEvent(attDoc, claims); err != nil {
+		return err
+	}
+	var instanceInfo attest.GCEInstanceInfo
+	if err := json.Unmarshal(attDoc.InstanceInfo, &instanceInfo); err != nil {
+		return fmt.Errorf("unmarshaling instance info: %w", err)
+	}
+	claims.InstanceID = fmt.Sprintf("projects/%s/zones/%s/instances/%s",
+		instanceInfo.GetProjectId(), instanceInfo.GetZone(), instanceInfo.GetInstanceName())
+	return nil
+}
+
 // gceNonHostInfoEvent looks for the GCE Non-Host info event in an event log.
 // Returns an error if the event is not found, or if the event is missing the required flag to mark the VM confidential.
-func gceNonHostInfoEvent(attDoc vtpm.AttestationDocument) error {
+func gceNonHostInfoEvent(attDoc vtpm.AttestationDocument, _ *policy.Claims) error {
 	if attDoc.Attestation == nil {
 		return errors.New("missing attestation in attestation document")
 	}
@@ -13,6 +13,7 @@ import (
 	"errors"
 	"testing"
 
+	"github.com/edgelesssys/constellation/v2/internal/attestation/policy"
 	"github.com/edgelesssys/constellation/v2/internal/attestation/vtpm"
 	"github.com/google/go-tpm-tools/proto/attest"
 	"github.com/googleapis/gax-go/v2"
@@ -62,7 +63,7 @@ func TestGceNonHostInfoEvent(t *testing.T) {
 	for name, tc := range testCases {
 		t.Run(name, func(t *testing.T) {
 			assert := assert.New(t)
-			err := gceNonHostInfoEvent(tc.attDoc)
+			err := gceNonHostInfoEvent(tc.attDoc, nil)
 			if tc.wantErr {
 				assert.Error(err)
 			} else {
@@ -72,6 +73,53 @@ func TestGceNonHostInfoEvent(t *testing.T) {
 	}
 }
 
+func TestValidateCVM(t *testing.T) {
+	cvmEventLog := []byte("\x00\x00\x00GCE NonHostInfo\x00\x01\x00\x00")
+
+	testCases := map[string]struct {
+		attDoc         vtpm.AttestationDocument
+		wantInstanceID string
+		wantErr        bool
+	}{
+		"success": {
+			attDoc: vtpm.AttestationDocument{
+				Attestation:  &attest.Attestation{EventLog: cvmEventLog},
+				InstanceInfo: []byte(`{"zone":"europe-west3-b","project_id":"constellation","instance_name":"worker-0"}`),
+			},
+			wantInstanceID: "projects/constellation/zones/europe-west3-b/instances/worker-0",
+		},
+		"not a cvm": {
+			attDoc: vtpm.AttestationDocument{
+				Attestation:  &attest.Attestation{EventLog: []byte("No GCE Event")},
+				InstanceInfo: []byte(`{"zone":"europe-west3-b","project_id":"constellation","instance_name":"worker-0"}`),
+			},
+			wantErr: true,
+		},
+		"invalid instance info": {
+			attDoc: vtpm.AttestationDocument{
+				Attestation:  &attest.Attestation{EventLog: cvmEventLog},
+				InstanceInfo: []byte("invalid"),
+			},
+			wantErr: true,
+		},
+	}
+
+	for name, tc := range testCases {
+		t.Run(name, func(t *testing.T) {
+			assert := assert.New(t)
+
+			var claims policy.Claims
+			err := validateCVM(tc.attDoc, &claims)
+			if tc.wantErr {
+				assert.Error(err)
+				return
+			}
+			assert.NoError(err)
+			assert.Equal(tc.wantInstanceID, claims.InstanceID)
+		})
+	}
+}
+
 func TestTrustedKeyFromGCEAPI(t *testing.T) {
 	testPubK := `-----BEGIN PUBLIC KEY-----
 MIICIjANBgkqhkiG9w0BAQEFAAOCAg8AMIICCgKCAgEAu+OepfHCTiTi27nkTGke
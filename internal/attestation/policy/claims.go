@@ -0,0 +1,164 @@
+/*
+Copyright (c) Edgeless Systems GmbH
+
+SPDX-License-Identifier: AGPL-3.0-only
+*/
+
+package policy
+
+import (
+	"encoding/hex"
+	"encoding/json"
+)
+
+// Claims are the properties of a node established by validating its attestation document.
+// They are normalized across CSPs, so that a single policy language can be used for all attestation variants.
+type Claims struct {
+	// Measurements holds the verified PCR values, or MRTD and RTMR[0-3] for Intel TDX.
+	Measurements map[uint32][]byte
+	// EventLog holds the replayed TPM event log entries, if available.
+	EventLog []Event
+	// InstanceInfo is the CSP specific instance information embedded in the attestation document.
+	InstanceInfo []byte
+	// InstanceID identifies the attested instance, if the attestation variant binds the attestation to it.
+	// On GCP, this is the resource name of the instance: projects/<project>/zones/<zone>/instances/<name>.
+	// On Azure with SEV-SNP, this is the VM ID prefixed with AzureInstanceIDPrefix.
+	// On QEMU, this is the provider ID of the VM: qemu:///hostname/<name>.
+	// Azure trusted launch VMs don't report an instance ID.
+	InstanceID string
+	// AttestationKeyDigest is the SHA-256 digest of the public attestation key of a TPM based attestation.
+	// The key is stable across reboots, so it identifies the TPM of the attested instance.
+	AttestationKeyDigest []byte
+	// SNP holds fields of a verified AMD SEV-SNP attestation report, if available.
+	SNP *SNPReport
+	// TDX holds fields of a verified Intel TDX quote, if available.
+	TDX *TDXReport
+}
+
+// AzureInstanceIDPrefix is the prefix of the instance ID of Azure VMs, followed by the lower case VM ID.
+const AzureInstanceIDPrefix = "azure/vms/"
+
+// Identity returns the attested identity of the node.
+// The instance ID is used if the attestation reports it, otherwise the digest of the node's attestation key.
+// An empty string is returned if the attestation does not identify the node.
+func (c Claims) Identity() string {
+	if c.InstanceID != "" {
+		return c.InstanceID
+	}
+	if len(c.AttestationKeyDigest) > 0 {
+		return "ak:" + hex.EncodeToString(c.AttestationKeyDigest)
+	}
+	return ""
+}
+
+// Event is a single entry of a TPM event log.
+type Event struct {
+	PCRIndex uint32
+	Type     uint32
+	Digest   []byte
+	Data     []byte
+}
+
+// SNPReport holds fields of an AMD SEV-SNP attestation report.
+type SNPReport struct {
+	Version         uint32
+	GuestSVN        uint32
+	Debug           bool
+	CurrentTCB      TCBVersion
+	ReportedTCB     TCBVersion
+	CommittedTCB    TCBVersion
+	LaunchTCB       TCBVersion
+	Measurement     []byte
+	HostData        []byte
+	IDKeyDigest     []byte
+	AuthorKeyDigest []byte
+	ChipID          []byte
+}
+
+// TCBVersion is the security version of the components of an AMD SEV-SNP platform.
+type TCBVersion struct {
+	Bootloader uint8
+	TEE        uint8
+	SNP        uint8
+	Microcode  uint8
+}
+
+// TDXReport holds fields of an Intel TDX quote.
+type TDXReport struct {
+	TCBStatus    string
+	TEETCBSVN    []byte
+	TDAttributes []byte
+	FMSPC        []byte
+}
+
+// activation converts the claims to the input of a policy.
+// Byte values are encoded as lower case hex strings, numbers as CEL integers.
+// SNP and TDX claims are only set if available, use has() to check for them.
+func (c Claims) activation() map[string]interface{} {
+	measurements := make(map[int64]string, len(c.Measurements))
+	for idx, value := range c.Measurements {
+		measurements[int64(idx)] = hex.EncodeToString(value)
+	}
+
+	eventLog := make([]interface{}, 0, len(c.EventLog))
+	for _, event := range c.EventLog {
+		eventLog = append(eventLog, map[string]interface{}{
+			"pcrIndex": int64(event.PCRIndex),
+			"type":     int64(event.Type),
+			"digest":   hex.EncodeToString(event.Digest),
+			"data":     hex.EncodeToString(event.Data),
+		})
+	}
+
+	// instance info is JSON for all CSPs supporting it, but not guaranteed to be an object
+	instanceInfo := map[string]interface{}{}
+	if err := json.Unmarshal(c.InstanceInfo, &instanceInfo); err != nil {
+		instanceInfo = map[string]interface{}{}
+	}
+
+	claims := map[string]interface{}{
+		"measurements": measurements,
+		"eventLog":     eventLog,
+		"instanceInfo": instanceInfo,
+		"instanceID":   c.InstanceID,
+		// the attestation key digest is only set for TPM based attestation
+		"attestationKeyDigest": hex.EncodeToString(c.AttestationKeyDigest),
+	}
+
+	if c.SNP != nil {
+		claims["snp"] = map[string]interface{}{
+			"version":         int64(c.SNP.Version),
+			"guestSVN":        int64(c.SNP.GuestSVN),
+			"debug":           c.SNP.Debug,
+			"currentTCB":      c.SNP.CurrentTCB.activation(),
+			"reportedTCB":     c.SNP.ReportedTCB.activation(),
+			"committedTCB":    c.SNP.CommittedTCB.activation(),
+			"launchTCB":       c.SNP.LaunchTCB.activation(),
+			"measurement":     hex.EncodeToString(c.SNP.Measurement),
+			"hostData":        hex.EncodeToString(c.SNP.HostData),
+			"idKeyDigest":     hex.EncodeToString(c.SNP.IDKeyDigest),
+			"authorKeyDigest": hex.EncodeToString(c.SNP.AuthorKeyDigest),
+			"chipID":          hex.EncodeToString(c.SNP.ChipID),
+		}
+	}
+
+	if c.TDX != nil {
+		claims["tdx"] = map[string]interface{}{
+			"tcbStatus":    c.TDX.TCBStatus,
+			"teeTCBSVN":    hex.EncodeToString(c.TDX.TEETCBSVN),
+			"tdAttributes": hex.EncodeToString(c.TDX.TDAttributes),
+			"fmspc":        hex.EncodeToString(c.TDX.FMSPC),
+		}
+	}
+
+	return map[string]interface{}{claimsVariable: claims}
+}
+
+func (t TCBVersion) activation() map[string]interface{} {
+	return map[string]interface{}{
+		"bootloader": int64(t.Bootloader),
+		"tee":        int64(t.TEE),
+		"snp":        int64(t.SNP),
+		"microcode":  int64(t.Microcode),
+	}
+}
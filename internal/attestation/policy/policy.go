@@ -0,0 +1,110 @@
+/*
+Copyright (c) Edgeless Systems GmbH
+
+SPDX-License-Identifier: AGPL-3.0-only
+*/
+
+/*
+Package policy implements declarative attestation policies.
+
+A policy is a CEL expression (https://github.com/google/cel-spec) evaluating to a bool.
+It is evaluated against the claims of a node, available as the variable "claims":
+
+	claims.measurements          map(int, string)  PCR values (or MRTD and RTMRs for TDX), hex encoded
+	claims.eventLog              list(map)         TPM event log entries: pcrIndex, type, digest, data
+	claims.instanceInfo          map(string, dyn)  CSP specific instance information
+	claims.instanceID            string            ID of the attested instance, empty if not bound by the attestation
+	claims.attestationKeyDigest  string            SHA-256 digest of the TPM attestation key, hex encoded, empty without TPM
+	claims.snp                   map(string, dyn)  AMD SEV-SNP report fields, e.g. reportedTCB.snp, idKeyDigest
+	claims.tdx                   map(string, dyn)  Intel TDX quote fields, e.g. tcbStatus, fmspc
+
+A node is only trusted if the policy evaluates to true.
+Policies are evaluated in addition to the expected and enforced measurements.
+
+Example: accept two versions of PCR 4 and require a minimum SNP firmware version.
+
+	claims.measurements[4] in ["aaaa...", "bbbb..."] && has(claims.snp) && claims.snp.reportedTCB.snp >= 8
+*/
+package policy
+
+import (
+	"errors"
+	"fmt"
+
+	"github.com/google/cel-go/cel"
+	"github.com/google/cel-go/checker/decls"
+	"github.com/google/cel-go/common/types"
+	"google.golang.org/protobuf/proto"
+)
+
+const (
+	claimsVariable = "claims"
+	// costLimit bounds the runtime of a single policy evaluation.
+	costLimit = 1000000
+)
+
+// Policy is a compiled attestation policy.
+type Policy struct {
+	source  string
+	program cel.Program
+}
+
+// New compiles the given CEL expression into a policy.
+func New(source string) (*Policy, error) {
+	env, err := cel.NewEnv(
+		cel.Declarations(decls.NewVar(claimsVariable, decls.NewMapType(decls.String, decls.Dyn))),
+	)
+	if err != nil {
+		return nil, fmt.Errorf("creating CEL environment: %w", err)
+	}
+
+	ast, issues := env.Compile(source)
+	if issues != nil && issues.Err() != nil {
+		return nil, fmt.Errorf("compiling policy: %w", issues.Err())
+	}
+	if !proto.Equal(ast.ResultType(), decls.Bool) && !proto.Equal(ast.ResultType(), decls.Dyn) {
+		return nil, fmt.Errorf("policy must evaluate to bool, got %s", cel.FormatType(ast.ResultType()))
+	}
+
+	program, err := env.Program(ast, cel.CostLimit(costLimit))
+	if err != nil {
+		return nil, fmt.Errorf("creating policy program: %w", err)
+	}
+
+	return &Policy{source: source, program: program}, nil
+}
+
+// Evaluate returns an error if the claims do not satisfy the policy.
+func (p *Policy) Evaluate(claims Claims) error {
+	result, _, err := p.program.Eval(claims.activation())
+	if err != nil {
+		return &evaluationError{err}
+	}
+	if result.Type() != types.BoolType {
+		return &evaluationError{fmt.Errorf("policy evaluated to %s, expected bool", result.Type().TypeName())}
+	}
+	if result != types.True {
+		return ErrNotSatisfied
+	}
+	return nil
+}
+
+// String returns the source of the policy.
+func (p *Policy) String() string {
+	return p.source
+}
+
+// ErrNotSatisfied is returned if claims do not satisfy a policy.
+var ErrNotSatisfied = errors.New("attestation policy not satisfied")
+
+type evaluationError struct {
+	innerError error
+}
+
+func (e *evaluationError) Unwrap() error {
+	return e.innerError
+}
+
+func (e *evaluationError) Error() string {
+	return fmt.Sprintf("evaluating attestation policy: %v", e.innerError)
+}
@@ -0,0 +1,156 @@
+/*
+Copyright (c) Edgeless Systems GmbH
+
+SPDX-License-Identifier: AGPL-3.0-only
+*/
+
+package policy
+
+import (
+	"bytes"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestNew(t *testing.T) {
+	testCases := map[string]struct {
+		source  string
+		wantErr bool
+	}{
+		"bool expression": {
+			source: `claims.measurements[4] == "00"`,
+		},
+		"dynamic expression": {
+			source: `claims.instanceInfo.projectId`,
+		},
+		"syntax error": {
+			source:  `claims.measurements[4] ==`,
+			wantErr: true,
+		},
+		"undeclared variable": {
+			source:  `pcrs[4] == "00"`,
+			wantErr: true,
+		},
+		"non bool result": {
+			source:  `1 + 2`,
+			wantErr: true,
+		},
+	}
+
+	for name, tc := range testCases {
+		t.Run(name, func(t *testing.T) {
+			assert := assert.New(t)
+
+			policy, err := New(tc.source)
+			if tc.wantErr {
+				assert.Error(err)
+				return
+			}
+			assert.NoError(err)
+			assert.Equal(tc.source, policy.String())
+		})
+	}
+}
+
+func TestEvaluate(t *testing.T) {
+	pcr4A := bytes.Repeat([]byte{0xAA}, 32)
+	pcr4B := bytes.Repeat([]byte{0xBB}, 32)
+	snpClaims := func(pcr4 []byte, snpVersion uint8) Claims {
+		return Claims{
+			Measurements: map[uint32][]byte{4: pcr4},
+			SNP:          &SNPReport{ReportedTCB: TCBVersion{SNP: snpVersion}},
+		}
+	}
+	pcr4Policy := `claims.measurements[4] in ["` +
+		"aaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaa" + `", "` +
+		"bbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbb" + `"] && claims.snp.reportedTCB.snp >= 8`
+
+	testCases := map[string]struct {
+		source           string
+		claims           Claims
+		wantErr          bool
+		wantNotSatisfied bool
+	}{
+		"first PCR value and TCB satisfied": {
+			source: pcr4Policy,
+			claims: snpClaims(pcr4A, 8),
+		},
+		"second PCR value and TCB satisfied": {
+			source: pcr4Policy,
+			claims: snpClaims(pcr4B, 9),
+		},
+		"PCR value not allowed": {
+			source:           pcr4Policy,
+			claims:           snpClaims(bytes.Repeat([]byte{0xCC}, 32), 8),
+			wantErr:          true,
+			wantNotSatisfied: true,
+		},
+		"TCB too low": {
+			source:           pcr4Policy,
+			claims:           snpClaims(pcr4A, 7),
+			wantErr:          true,
+			wantNotSatisfied: true,
+		},
+		"missing SNP claims": {
+			source:  pcr4Policy,
+			claims:  Claims{Measurements: map[uint32][]byte{4: pcr4A}},
+			wantErr: true,
+		},
+		"has check for missing SNP claims": {
+			source: `!has(claims.snp) || claims.snp.debug == false`,
+			claims: Claims{},
+		},
+		"event log": {
+			source: `claims.eventLog.exists(e, e.pcrIndex == 4 && e.type == 3)`,
+			claims: Claims{EventLog: []Event{{PCRIndex: 4, Type: 3}}},
+		},
+		"instance info": {
+			source: `claims.instanceInfo.projectId == "constellation"`,
+			claims: Claims{InstanceInfo: []byte(`{"projectId":"constellation"}`)},
+		},
+		"instance info not an object": {
+			source:  `claims.instanceInfo.projectId == "constellation"`,
+			claims:  Claims{InstanceInfo: []byte("not json")},
+			wantErr: true,
+		},
+		"instance ID": {
+			source: `claims.instanceID.startsWith("projects/constellation/")`,
+			claims: Claims{InstanceID: "projects/constellation/zones/europe-west3-b/instances/worker-0"},
+		},
+		"attestation key digest": {
+			source: `claims.attestationKeyDigest == "00ff"`,
+			claims: Claims{AttestationKeyDigest: []byte{0x00, 0xff}},
+		},
+		"tdx claims": {
+			source: `claims.tdx.tcbStatus == "UpToDate" && claims.tdx.fmspc == "00806f050000"`,
+			claims: Claims{TDX: &TDXReport{TCBStatus: "UpToDate", FMSPC: []byte{0x00, 0x80, 0x6f, 0x05, 0x00, 0x00}}},
+		},
+		"dynamic result is not bool": {
+			source:  `claims.instanceInfo.projectId`,
+			claims:  Claims{InstanceInfo: []byte(`{"projectId":"constellation"}`)},
+			wantErr: true,
+		},
+	}
+
+	for name, tc := range testCases {
+		t.Run(name, func(t *testing.T) {
+			assert := assert.New(t)
+			require := require.New(t)
+
+			policy, err := New(tc.source)
+			require.NoError(err)
+
+			err = policy.Evaluate(tc.claims)
+			if tc.wantErr {
+				assert.Error(err)
+				if tc.wantNotSatisfied {
+					assert.ErrorIs(err, ErrNotSatisfied)
+				}
+				return
+			}
+			assert.NoError(err)
+		})
+	}
+}
@@ -0,0 +1,289 @@
+/*
+Copyright (c) Edgeless Systems GmbH
+
+SPDX-License-Identifier: AGPL-3.0-only
+*/
+
+package qemu
+
+import (
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/subtle"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/asn1"
+	"encoding/base64"
+	"encoding/pem"
+	"errors"
+	"fmt"
+	"math/big"
+	"net/url"
+	"sync"
+	"time"
+
+	"github.com/google/go-tpm/tpm2"
+	"github.com/google/go-tpm/tpm2/credactivation"
+)
+
+const (
+	// AKChallengePath is the path of the QEMU metadata API issuing credential activation challenges for attestation keys.
+	AKChallengePath = "/attestation-key/challenge"
+	// AKCertificatePath is the path of the QEMU metadata API issuing certificates for attestation keys.
+	AKCertificatePath = "/attestation-key/certificate"
+
+	// akChallengeValidity is the time a VM has to activate the credential of a challenge.
+	akChallengeValidity = time.Minute
+	// akCertificateValidity is the validity of an attestation key certificate.
+	akCertificateValidity = 24 * time.Hour
+	// akSecretLength is the length of the secret protected by a credential activation challenge.
+	akSecretLength = 32
+	// ekSymmetricBlockSize is the block size of the symmetric cipher of the default RSA EK (AES-128).
+	ekSymmetricBlockSize = 16
+)
+
+// oidAKCertificate is the extended key usage tcg-kp-AIKCertificate,
+// marking a certificate as issued for a TPM attestation key.
+var oidAKCertificate = asn1.ObjectIdentifier{2, 23, 133, 8, 3}
+
+// AKChallengeRequest requests a credential activation challenge for an attestation key.
+type AKChallengeRequest struct {
+	// EKCertificate is the DER encoded EK certificate of the TPM.
+	EKCertificate []byte `json:"ekCertificate"`
+	// AttestationKey is the encoded TPMT_PUBLIC of the attestation key.
+	AttestationKey []byte `json:"attestationKey"`
+}
+
+// AKChallengeResponse is a credential activation challenge for an attestation key.
+type AKChallengeResponse struct {
+	// ID identifies the challenge when requesting the certificate.
+	ID string `json:"id"`
+	// Credential is the TPM2B_ID_OBJECT to pass to TPM2_ActivateCredential.
+	Credential []byte `json:"credential"`
+	// EncryptedSecret is the TPM2B_ENCRYPTED_SECRET to pass to TPM2_ActivateCredential.
+	EncryptedSecret []byte `json:"encryptedSecret"`
+}
+
+// AKCertificateRequest requests a certificate for an attestation key by answering a challenge.
+type AKCertificateRequest struct {
+	// ID identifies the challenge.
+	ID string `json:"id"`
+	// Secret is the secret recovered using TPM2_ActivateCredential.
+	Secret []byte `json:"secret"`
+}
+
+// AKCertificateResponse contains the certificate chain of an attestation key.
+type AKCertificateResponse struct {
+	// Chain is the DER encoded certificate of the attestation key, followed by the certificate of the issuing CA.
+	Chain [][]byte `json:"chain"`
+}
+
+// AKCA certifies attestation keys of VMs using the CA issuing their EK certificates.
+// The certificates contain the provider ID of the VM as URI SAN.
+//
+// An attestation key is only certified if it resides in the same TPM as an EK certified by the CA.
+// This is proven using TPM2_MakeCredential and TPM2_ActivateCredential:
+// the TPM only releases the secret of a challenge if the EK can decrypt it,
+// and the attestation key named in the challenge is loaded in the TPM.
+type AKCA struct {
+	cert *x509.Certificate
+	key  crypto.Signer
+	now  func() time.Time
+
+	mux        sync.Mutex
+	challenges map[string]akChallenge
+}
+
+type akChallenge struct {
+	secret     []byte
+	akPub      crypto.PublicKey
+	instanceID *url.URL
+	expiry     time.Time
+}
+
+// NewAKCA creates a new attestation key CA from the PEM encoded certificate and private key of the EK CA,
+// e.g., the issuer certificate and signing key of swtpm-localca.
+func NewAKCA(certPEM, keyPEM []byte) (*AKCA, error) {
+	certBlock, _ := pem.Decode(certPEM)
+	if certBlock == nil || certBlock.Type != "CERTIFICATE" {
+		return nil, errors.New("no PEM encoded CA certificate found")
+	}
+	cert, err := x509.ParseCertificate(certBlock.Bytes)
+	if err != nil {
+		return nil, fmt.Errorf("parsing CA certificate: %w", err)
+	}
+
+	keyBlock, _ := pem.Decode(keyPEM)
+	if keyBlock == nil {
+		return nil, errors.New("no PEM encoded CA private key found")
+	}
+	key, err := parsePrivateKey(keyBlock)
+	if err != nil {
+		return nil, fmt.Errorf("parsing CA private key: %w", err)
+	}
+	if !publicKeysEqual(key.Public(), cert.PublicKey) {
+		return nil, errors.New("CA private key does not match CA certificate")
+	}
+
+	return &AKCA{
+		cert:       cert,
+		key:        key,
+		now:        time.Now,
+		challenges: make(map[string]akChallenge),
+	}, nil
+}
+
+// Challenge creates a credential activation challenge for the attestation key of a TPM.
+// The EK certificate of the TPM must be issued by the CA.
+// instanceID is the provider ID of the requesting VM, as determined by the caller. It is included in the certificate
+// of the attestation key, so that validators can bind the attestation to the VM.
+func (c *AKCA) Challenge(req AKChallengeRequest, instanceID string) (AKChallengeResponse, error) {
+	instanceURI, err := url.Parse(instanceID)
+	if err != nil || instanceURI.Scheme == "" {
+		return AKChallengeResponse{}, fmt.Errorf("invalid instance ID %q", instanceID)
+	}
+
+	ekCert, err := x509.ParseCertificate(req.EKCertificate)
+	if err != nil {
+		return AKChallengeResponse{}, fmt.Errorf("parsing EK certificate: %w", err)
+	}
+	roots := x509.NewCertPool()
+	roots.AddCert(c.cert)
+	if _, err := ekCert.Verify(x509.VerifyOptions{
+		Roots:       roots,
+		CurrentTime: c.now(),
+		KeyUsages:   []x509.ExtKeyUsage{x509.ExtKeyUsageAny},
+	}); err != nil {
+		return AKChallengeResponse{}, fmt.Errorf("verifying EK certificate: %w", err)
+	}
+
+	pubArea, err := parseAttestationKey(req.AttestationKey)
+	if err != nil {
+		return AKChallengeResponse{}, err
+	}
+	akPub, err := pubArea.Key()
+	if err != nil {
+		return AKChallengeResponse{}, fmt.Errorf("decoding attestation key: %w", err)
+	}
+	name, err := pubArea.Name()
+	if err != nil {
+		return AKChallengeResponse{}, fmt.Errorf("computing attestation key name: %w", err)
+	}
+
+	secret := make([]byte, akSecretLength)
+	if _, err := rand.Read(secret); err != nil {
+		return AKChallengeResponse{}, fmt.Errorf("generating secret: %w", err)
+	}
+	credential, encryptedSecret, err := credactivation.Generate(name.Digest, ekCert.PublicKey, ekSymmetricBlockSize, secret)
+	if err != nil {
+		return AKChallengeResponse{}, fmt.Errorf("generating challenge: %w", err)
+	}
+	id := make([]byte, 16)
+	if _, err := rand.Read(id); err != nil {
+		return AKChallengeResponse{}, fmt.Errorf("generating challenge ID: %w", err)
+	}
+	challengeID := base64.RawURLEncoding.EncodeToString(id)
+
+	c.mux.Lock()
+	defer c.mux.Unlock()
+	c.removeExpired()
+	c.challenges[challengeID] = akChallenge{
+		secret:     secret,
+		akPub:      akPub,
+		instanceID: instanceURI,
+		expiry:     c.now().Add(akChallengeValidity),
+	}
+
+	return AKChallengeResponse{
+		ID:              challengeID,
+		Credential:      credential,
+		EncryptedSecret: encryptedSecret,
+	}, nil
+}
+
+// Certify issues a certificate for the attestation key of a challenge if the secret of the challenge was recovered.
+// Each challenge can only be answered once.
+func (c *AKCA) Certify(req AKCertificateRequest) (AKCertificateResponse, error) {
+	c.mux.Lock()
+	c.removeExpired()
+	challenge, ok := c.challenges[req.ID]
+	delete(c.challenges, req.ID)
+	c.mux.Unlock()
+
+	if !ok {
+		return AKCertificateResponse{}, errors.New("unknown or expired challenge")
+	}
+	if subtle.ConstantTimeCompare(challenge.secret, req.Secret) != 1 {
+		return AKCertificateResponse{}, errors.New("secret does not match challenge")
+	}
+
+	serial, err := rand.Int(rand.Reader, new(big.Int).Lsh(big.NewInt(1), 128))
+	if err != nil {
+		return AKCertificateResponse{}, fmt.Errorf("generating serial number: %w", err)
+	}
+	now := c.now()
+	template := &x509.Certificate{
+		SerialNumber:       serial,
+		Subject:            pkix.Name{CommonName: "Constellation QEMU attestation key"},
+		NotBefore:          now.Add(-time.Minute),
+		NotAfter:           now.Add(akCertificateValidity),
+		KeyUsage:           x509.KeyUsageDigitalSignature,
+		UnknownExtKeyUsage: []asn1.ObjectIdentifier{oidAKCertificate},
+		URIs:               []*url.URL{challenge.instanceID},
+	}
+	akCert, err := x509.CreateCertificate(rand.Reader, template, c.cert, challenge.akPub, c.key)
+	if err != nil {
+		return AKCertificateResponse{}, fmt.Errorf("creating attestation key certificate: %w", err)
+	}
+
+	return AKCertificateResponse{Chain: [][]byte{akCert, c.cert.Raw}}, nil
+}
+
+// removeExpired removes expired challenges. The caller must hold the lock.
+func (c *AKCA) removeExpired() {
+	now := c.now()
+	for id, challenge := range c.challenges {
+		if now.After(challenge.expiry) {
+			delete(c.challenges, id)
+		}
+	}
+}
+
+// parseAttestationKey decodes an attestation key and checks it is a restricted signing key fixed to its TPM.
+func parseAttestationKey(akPub []byte) (tpm2.Public, error) {
+	pubArea, err := tpm2.DecodePublic(akPub)
+	if err != nil {
+		return tpm2.Public{}, fmt.Errorf("decoding attestation key: %w", err)
+	}
+	// a key loaded into the TPM from outside can't have fixedTPM set
+	if pubArea.Attributes&akAttributes != akAttributes {
+		return tpm2.Public{}, fmt.Errorf("attestation key has invalid attributes 0x%x", pubArea.Attributes)
+	}
+	return pubArea, nil
+}
+
+func parsePrivateKey(block *pem.Block) (crypto.Signer, error) {
+	switch block.Type {
+	case "RSA PRIVATE KEY":
+		return x509.ParsePKCS1PrivateKey(block.Bytes)
+	case "EC PRIVATE KEY":
+		return x509.ParseECPrivateKey(block.Bytes)
+	case "PRIVATE KEY":
+		key, err := x509.ParsePKCS8PrivateKey(block.Bytes)
+		if err != nil {
+			return nil, err
+		}
+		switch key := key.(type) {
+		case *rsa.PrivateKey:
+			return key, nil
+		case *ecdsa.PrivateKey:
+			return key, nil
+		default:
+			return nil, fmt.Errorf("unsupported private key type %T", key)
+		}
+	default:
+		return nil, fmt.Errorf("unexpected PEM block type %q", block.Type)
+	}
+}
@@ -0,0 +1,221 @@
+/*
+Copyright (c) Edgeless Systems GmbH
+
+SPDX-License-Identifier: AGPL-3.0-only
+*/
+
+package qemu
+
+import (
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/x509"
+	"encoding/pem"
+	"testing"
+	"time"
+
+	"github.com/edgelesssys/constellation/v2/internal/attestation/simulator"
+	tpmclient "github.com/google/go-tpm-tools/client"
+	"github.com/google/go-tpm/tpm2"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestNewAKCA(t *testing.T) {
+	ca, caKey := newTestCA(t)
+	caPEM := pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: ca.Raw})
+	sec1, err := x509.MarshalECPrivateKey(caKey)
+	require.NoError(t, err)
+	pkcs8, err := x509.MarshalPKCS8PrivateKey(caKey)
+	require.NoError(t, err)
+	otherKey, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	require.NoError(t, err)
+	otherSEC1, err := x509.MarshalECPrivateKey(otherKey)
+	require.NoError(t, err)
+
+	testCases := map[string]struct {
+		certPEM []byte
+		keyPEM  []byte
+		wantErr bool
+	}{
+		"EC private key": {
+			certPEM: caPEM,
+			keyPEM:  pem.EncodeToMemory(&pem.Block{Type: "EC PRIVATE KEY", Bytes: sec1}),
+		},
+		"PKCS #8 private key": {
+			certPEM: caPEM,
+			keyPEM:  pem.EncodeToMemory(&pem.Block{Type: "PRIVATE KEY", Bytes: pkcs8}),
+		},
+		"key does not match certificate": {
+			certPEM: caPEM,
+			keyPEM:  pem.EncodeToMemory(&pem.Block{Type: "EC PRIVATE KEY", Bytes: otherSEC1}),
+			wantErr: true,
+		},
+		"missing certificate": {
+			keyPEM:  pem.EncodeToMemory(&pem.Block{Type: "EC PRIVATE KEY", Bytes: sec1}),
+			wantErr: true,
+		},
+		"missing key": {
+			certPEM: caPEM,
+			wantErr: true,
+		},
+		"unexpected key type": {
+			certPEM: caPEM,
+			keyPEM:  pem.EncodeToMemory(&pem.Block{Type: "PUBLIC KEY", Bytes: sec1}),
+			wantErr: true,
+		},
+	}
+
+	for name, tc := range testCases {
+		t.Run(name, func(t *testing.T) {
+			assert := assert.New(t)
+
+			akCA, err := NewAKCA(tc.certPEM, tc.keyPEM)
+			if tc.wantErr {
+				assert.Error(err)
+			} else {
+				assert.NoError(err)
+				assert.NotNil(akCA)
+			}
+		})
+	}
+}
+
+func TestAKCA(t *testing.T) {
+	ca, caKey := newTestCA(t)
+	otherCA, otherCAKey := newTestCA(t)
+
+	testCases := map[string]struct {
+		ca               *AKCA
+		instanceID       string
+		modifyRequest    func(t *testing.T, req *AKChallengeRequest)
+		modifySecret     func(secret []byte) []byte
+		delay            time.Duration
+		wantChallengeErr bool
+		wantActivateErr  bool
+		wantCertifyErr   bool
+	}{
+		"success": {
+			ca: newTestAKCA(t, ca, caKey),
+		},
+		"invalid instance ID": {
+			ca:               newTestAKCA(t, ca, caKey),
+			instanceID:       "worker-0",
+			wantChallengeErr: true,
+		},
+		"EK certificate from other CA": {
+			ca:               newTestAKCA(t, otherCA, otherCAKey),
+			wantChallengeErr: true,
+		},
+		"invalid EK certificate": {
+			ca: newTestAKCA(t, ca, caKey),
+			modifyRequest: func(t *testing.T, req *AKChallengeRequest) {
+				req.EKCertificate = []byte("invalid")
+			},
+			wantChallengeErr: true,
+		},
+		"attestation key not fixed to TPM": {
+			ca: newTestAKCA(t, ca, caKey),
+			modifyRequest: func(t *testing.T, req *AKChallengeRequest) {
+				template := tpmclient.AKTemplateRSA()
+				template.Attributes &^= tpm2.FlagFixedTPM | tpm2.FlagFixedParent
+				pub, err := template.Encode()
+				require.NoError(t, err)
+				req.AttestationKey = pub
+			},
+			wantChallengeErr: true,
+		},
+		"EK of other TPM": {
+			ca: newTestAKCA(t, ca, caKey),
+			modifyRequest: func(t *testing.T, req *AKChallengeRequest) {
+				key, err := rsa.GenerateKey(rand.Reader, 2048)
+				require.NoError(t, err)
+				req.EKCertificate = newTestCertificate(t, ca, caKey, &key.PublicKey)
+			},
+			wantActivateErr: true,
+		},
+		"wrong secret": {
+			ca: newTestAKCA(t, ca, caKey),
+			modifySecret: func(secret []byte) []byte {
+				secret[0] ^= 0xFF
+				return secret
+			},
+			wantCertifyErr: true,
+		},
+		"expired challenge": {
+			ca:             newTestAKCA(t, ca, caKey),
+			delay:          2 * akChallengeValidity,
+			wantCertifyErr: true,
+		},
+	}
+
+	for name, tc := range testCases {
+		t.Run(name, func(t *testing.T) {
+			assert := assert.New(t)
+			require := require.New(t)
+
+			tpm, err := simulator.OpenSimulatedTPM()
+			require.NoError(err)
+			defer tpm.Close()
+			provisionEKCertificate(t, tpm, ca, caKey)
+
+			ek, err := tpmclient.EndorsementKeyRSA(tpm)
+			require.NoError(err)
+			defer ek.Close()
+			ak, err := tpmclient.AttestationKeyRSA(tpm)
+			require.NoError(err)
+			defer ak.Close()
+			ekCert, err := readEKCertificate(tpm)
+			require.NoError(err)
+
+			req := AKChallengeRequest{EKCertificate: ekCert.Raw, AttestationKey: attestationKeyPub(t, tpm)}
+			if tc.modifyRequest != nil {
+				tc.modifyRequest(t, &req)
+			}
+			instanceID := testInstanceID
+			if tc.instanceID != "" {
+				instanceID = tc.instanceID
+			}
+			challenge, err := tc.ca.Challenge(req, instanceID)
+			if tc.wantChallengeErr {
+				assert.Error(err)
+				return
+			}
+			require.NoError(err)
+
+			secret, err := activateCredential(tpm, ak.Handle(), ek.Handle(), challenge.Credential, challenge.EncryptedSecret)
+			if tc.wantActivateErr {
+				assert.Error(err)
+				return
+			}
+			require.NoError(err)
+			if tc.modifySecret != nil {
+				secret = tc.modifySecret(secret)
+			}
+
+			tc.ca.now = func() time.Time { return time.Now().Add(tc.delay) }
+			certReq := AKCertificateRequest{ID: challenge.ID, Secret: secret}
+			resp, err := tc.ca.Certify(certReq)
+			if tc.wantCertifyErr {
+				assert.Error(err)
+				return
+			}
+			require.NoError(err)
+
+			require.Len(resp.Chain, 2)
+			akCert, err := x509.ParseCertificate(resp.Chain[0])
+			require.NoError(err)
+			assert.True(isAKCertificate(akCert))
+			assert.True(publicKeysEqual(ak.PublicKey(), akCert.PublicKey))
+			assert.NoError(akCert.CheckSignatureFrom(ca))
+			require.Len(akCert.URIs, 1)
+			assert.Equal(testInstanceID, akCert.URIs[0].String())
+
+			// a challenge can only be answered once
+			_, err = tc.ca.Certify(certReq)
+			assert.Error(err)
+		})
+	}
+}
@@ -7,11 +7,32 @@ SPDX-License-Identifier: AGPL-3.0-only
 package qemu
 
 import (
+	"bytes"
+	"crypto"
+	"crypto/x509"
+	"encoding/asn1"
+	"encoding/json"
+	"errors"
+	"fmt"
 	"io"
+	"net/http"
+	"net/url"
+	"sync"
+	"time"
 
 	"github.com/edgelesssys/constellation/v2/internal/attestation/vtpm"
+	"github.com/edgelesssys/constellation/v2/internal/constants"
 	"github.com/edgelesssys/constellation/v2/internal/oid"
 	tpmclient "github.com/google/go-tpm-tools/client"
+	"github.com/google/go-tpm/tpm2"
+	"github.com/google/go-tpm/tpmutil"
+)
+
+const (
+	// akCertificateRenewal is the remaining validity of the attestation key certificate at which a new one is requested.
+	akCertificateRenewal = time.Hour
+	// akCARequestTimeout is the timeout for requests to the attestation key CA.
+	akCARequestTimeout = 30 * time.Second
 )
 
 // Issuer for qemu TPM attestation.
@@ -20,13 +41,214 @@ type Issuer struct {
 	*vtpm.Issuer
 }
 
-// NewIssuer initializes a new Azure Issuer.
+// NewIssuer initializes a new QEMU Issuer.
+// The attestation key is certified by the CA of the QEMU metadata API.
 func NewIssuer() *Issuer {
+	certifier := newAKCertifier(&metadataAKCA{
+		client:   &http.Client{Timeout: akCARequestTimeout},
+		endpoint: constants.QEMUMetadataEndpoint,
+	})
 	return &Issuer{
 		Issuer: vtpm.NewIssuer(
 			vtpm.OpenVTPM,
 			tpmclient.AttestationKeyRSA,
-			func(tpm io.ReadWriteCloser) ([]byte, error) { return nil, nil },
+			certifier.getInstanceInfo,
 		),
 	}
 }
+
+// instanceInfo proves the attestation key resides in a TPM with an EK certified by the EK CA.
+type instanceInfo struct {
+	// AKCertificateChain is the DER encoded certificate of the attestation key, followed by intermediate CA certificates.
+	AKCertificateChain [][]byte `json:"akCertificateChain"`
+}
+
+// akCA issues certificates for attestation keys.
+type akCA interface {
+	Challenge(req AKChallengeRequest) (AKChallengeResponse, error)
+	Certify(req AKCertificateRequest) (AKCertificateResponse, error)
+}
+
+// akCertifier requests a certificate for the attestation key of the TPM,
+// and reuses it until it is about to expire.
+type akCertifier struct {
+	ca  akCA
+	now func() time.Time
+
+	mux      sync.Mutex
+	chain    [][]byte
+	akPub    crypto.PublicKey
+	notAfter time.Time
+}
+
+func newAKCertifier(ca akCA) *akCertifier {
+	return &akCertifier{ca: ca, now: time.Now}
+}
+
+// getInstanceInfo returns the certificate chain of the attestation key.
+func (c *akCertifier) getInstanceInfo(tpm io.ReadWriteCloser) ([]byte, error) {
+	ak, err := tpmclient.AttestationKeyRSA(tpm)
+	if err != nil {
+		return nil, fmt.Errorf("loading attestation key: %w", err)
+	}
+	defer ak.Close()
+
+	c.mux.Lock()
+	defer c.mux.Unlock()
+	if c.chain == nil || !publicKeysEqual(ak.PublicKey(), c.akPub) || c.now().Add(akCertificateRenewal).After(c.notAfter) {
+		chain, err := c.certify(tpm, ak)
+		if err != nil {
+			return nil, fmt.Errorf("certifying attestation key: %w", err)
+		}
+		akCert, err := x509.ParseCertificate(chain[0])
+		if err != nil {
+			return nil, fmt.Errorf("parsing attestation key certificate: %w", err)
+		}
+		c.chain, c.akPub, c.notAfter = chain, ak.PublicKey(), akCert.NotAfter
+	}
+
+	return json.Marshal(instanceInfo{AKCertificateChain: c.chain})
+}
+
+// certify proves to the CA that the attestation key resides in the same TPM as the certified EK,
+// and returns the certificate chain issued by the CA.
+func (c *akCertifier) certify(tpm io.ReadWriter, ak *tpmclient.Key) ([][]byte, error) {
+	ekCert, err := readEKCertificate(tpm)
+	if err != nil {
+		return nil, err
+	}
+	ek, err := tpmclient.EndorsementKeyRSA(tpm)
+	if err != nil {
+		return nil, fmt.Errorf("loading EK: %w", err)
+	}
+	defer ek.Close()
+	if !publicKeysEqual(ek.PublicKey(), ekCert.PublicKey) {
+		return nil, errors.New("EK certificate does not match the default RSA EK")
+	}
+
+	akPub, err := ak.PublicArea().Encode()
+	if err != nil {
+		return nil, fmt.Errorf("encoding attestation key: %w", err)
+	}
+	challenge, err := c.ca.Challenge(AKChallengeRequest{
+		EKCertificate:  ekCert.Raw,
+		AttestationKey: akPub,
+	})
+	if err != nil {
+		return nil, fmt.Errorf("requesting challenge: %w", err)
+	}
+
+	secret, err := activateCredential(tpm, ak.Handle(), ek.Handle(), challenge.Credential, challenge.EncryptedSecret)
+	if err != nil {
+		return nil, fmt.Errorf("activating credential: %w", err)
+	}
+
+	resp, err := c.ca.Certify(AKCertificateRequest{ID: challenge.ID, Secret: secret})
+	if err != nil {
+		return nil, fmt.Errorf("requesting certificate: %w", err)
+	}
+	if len(resp.Chain) == 0 {
+		return nil, errors.New("CA returned an empty certificate chain")
+	}
+	return resp.Chain, nil
+}
+
+// readEKCertificate reads the RSA EK certificate provisioned by swtpm from NV memory.
+func readEKCertificate(tpm io.ReadWriter) (*x509.Certificate, error) {
+	ekCertRaw, err := tpm2.NVReadEx(tpm, tpmutil.Handle(tpmclient.EKCertNVIndexRSA), tpm2.HandleOwner, "", 0)
+	if err != nil {
+		return nil, fmt.Errorf("reading EK certificate from NV index 0x%x: %w", tpmclient.EKCertNVIndexRSA, err)
+	}
+	// the NV index may be larger than the certificate
+	var certDER asn1.RawValue
+	if _, err := asn1.Unmarshal(ekCertRaw, &certDER); err != nil {
+		return nil, fmt.Errorf("decoding EK certificate: %w", err)
+	}
+	ekCert, err := x509.ParseCertificate(certDER.FullBytes)
+	if err != nil {
+		return nil, fmt.Errorf("parsing EK certificate: %w", err)
+	}
+	return ekCert, nil
+}
+
+// activateCredential recovers the secret of a credential activation challenge using TPM2_ActivateCredential.
+// The EK may only be used in a policy session satisfying PolicySecret(TPM_RH_ENDORSEMENT).
+func activateCredential(tpm io.ReadWriter, ak, ek tpmutil.Handle, credential, encryptedSecret []byte) ([]byte, error) {
+	// the challenge contains TPM2B encoded values, while ActivateCredential adds the size prefix itself
+	if len(credential) < 2 || len(encryptedSecret) < 2 {
+		return nil, errors.New("malformed challenge")
+	}
+
+	session, _, err := tpm2.StartAuthSession(
+		tpm,
+		/*tpmKey=*/ tpm2.HandleNull,
+		/*bindKey=*/ tpm2.HandleNull,
+		/*nonceCaller=*/ make([]byte, 32),
+		/*encryptedSalt=*/ nil,
+		/*sessionType=*/ tpm2.SessionPolicy,
+		/*symmetric=*/ tpm2.AlgNull,
+		/*authHash=*/ tpm2.AlgSHA256,
+	)
+	if err != nil {
+		return nil, fmt.Errorf("starting policy session: %w", err)
+	}
+	defer tpm2.FlushContext(tpm, session)
+
+	nullAuth := tpm2.AuthCommand{Session: tpm2.HandlePasswordSession, Attributes: tpm2.AttrContinueSession}
+	if _, _, err := tpm2.PolicySecret(tpm, tpm2.HandleEndorsement, nullAuth, session, nil, nil, nil, 0); err != nil {
+		return nil, fmt.Errorf("satisfying EK policy: %w", err)
+	}
+
+	return tpm2.ActivateCredentialUsingAuth(tpm, []tpm2.AuthCommand{
+		nullAuth,
+		{Session: session, Attributes: tpm2.AttrContinueSession},
+	}, ak, ek, credential[2:], encryptedSecret[2:])
+}
+
+// metadataAKCA requests attestation key certificates from the QEMU metadata API.
+type metadataAKCA struct {
+	client   *http.Client
+	endpoint string
+}
+
+// Challenge requests a credential activation challenge.
+func (m *metadataAKCA) Challenge(req AKChallengeRequest) (AKChallengeResponse, error) {
+	var resp AKChallengeResponse
+	err := m.post(AKChallengePath, req, &resp)
+	return resp, err
+}
+
+// Certify requests a certificate for a solved challenge.
+func (m *metadataAKCA) Certify(req AKCertificateRequest) (AKCertificateResponse, error) {
+	var resp AKCertificateResponse
+	err := m.post(AKCertificatePath, req, &resp)
+	return resp, err
+}
+
+func (m *metadataAKCA) post(path string, req, resp any) error {
+	body, err := json.Marshal(req)
+	if err != nil {
+		return err
+	}
+	url := &url.URL{
+		Scheme: "http",
+		Host:   m.endpoint,
+		Path:   path,
+	}
+
+	res, err := m.client.Post(url.String(), "application/json", bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	defer res.Body.Close()
+	if res.StatusCode != http.StatusOK {
+		msg, _ := io.ReadAll(io.LimitReader(res.Body, 1024))
+		return fmt.Errorf("metadata API returned %s: %s", res.Status, bytes.TrimSpace(msg))
+	}
+	return json.NewDecoder(res.Body).Decode(resp)
+}
+
+func publicKeysEqual(a, b crypto.PublicKey) bool {
+	key, ok := a.(interface{ Equal(x crypto.PublicKey) bool })
+	return ok && key.Equal(b)
+}
@@ -0,0 +1,74 @@
+/*
+Copyright (c) Edgeless Systems GmbH
+
+SPDX-License-Identifier: AGPL-3.0-only
+*/
+
+package qemu
+
+import (
+	"encoding/json"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/edgelesssys/constellation/v2/internal/attestation/simulator"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestAKCertifier(t *testing.T) {
+	assert := assert.New(t)
+	require := require.New(t)
+
+	ca, caKey := newTestCA(t)
+	tpm, err := simulator.OpenSimulatedTPM()
+	require.NoError(err)
+	defer tpm.Close()
+	provisionEKCertificate(t, tpm, ca, caKey)
+
+	counter := &countingAKCA{akCA: newTestVMAKCA(t, ca, caKey)}
+	certifier := newAKCertifier(counter)
+	now := time.Now()
+	certifier.now = func() time.Time { return now }
+
+	infoRaw, err := certifier.getInstanceInfo(tpm)
+	require.NoError(err)
+	var info instanceInfo
+	require.NoError(json.Unmarshal(infoRaw, &info))
+	assert.Len(info.AKCertificateChain, 2)
+	assert.Equal(1, counter.certified)
+
+	// the certificate is reused while it is valid
+	_, err = certifier.getInstanceInfo(tpm)
+	require.NoError(err)
+	assert.Equal(1, counter.certified)
+
+	// a new certificate is requested before the certificate expires
+	now = now.Add(akCertificateValidity - akCertificateRenewal/2)
+	counter.err = errors.New("failed")
+	_, err = certifier.getInstanceInfo(tpm)
+	assert.Error(err)
+	counter.err = nil
+	_, err = certifier.getInstanceInfo(tpm)
+	require.NoError(err)
+	assert.Equal(2, counter.certified)
+}
+
+type countingAKCA struct {
+	akCA      akCA
+	certified int
+	err       error
+}
+
+func (c *countingAKCA) Challenge(req AKChallengeRequest) (AKChallengeResponse, error) {
+	if c.err != nil {
+		return AKChallengeResponse{}, c.err
+	}
+	return c.akCA.Challenge(req)
+}
+
+func (c *countingAKCA) Certify(req AKCertificateRequest) (AKCertificateResponse, error) {
+	c.certified++
+	return c.akCA.Certify(req)
+}
@@ -7,38 +7,158 @@ SPDX-License-Identifier: AGPL-3.0-only
 package qemu
 
 import (
+	"bytes"
 	"crypto"
+	"crypto/x509"
+	"encoding/json"
+	"encoding/pem"
+	"errors"
+	"fmt"
 
+	"github.com/edgelesssys/constellation/v2/internal/attestation/policy"
 	"github.com/edgelesssys/constellation/v2/internal/attestation/vtpm"
 	"github.com/edgelesssys/constellation/v2/internal/oid"
 	"github.com/google/go-tpm/tpm2"
 )
 
+// akAttributes are the attributes an attestation key must have to be trusted.
+const akAttributes = tpm2.FlagFixedTPM | tpm2.FlagFixedParent | tpm2.FlagSensitiveDataOrigin | tpm2.FlagRestricted | tpm2.FlagSign
+
 // Validator for QEMU VM attestation.
 type Validator struct {
 	oid.QEMU
 	*vtpm.Validator
 }
 
-// NewValidator initializes a new qemu validator with the provided PCR values.
-func NewValidator(pcrs map[uint32][]byte, enforcedPCRs []uint32, log vtpm.WarnLogger) *Validator {
+// NewValidator initializes a new QEMU validator with the provided PCR values and attestation policy.
+// ekRoots are the CA certificates trusted to issue swtpm EK certificates, and certify attestation keys.
+// If ekRoots is nil, all attestations are rejected.
+func NewValidator(pcrs map[uint32][]byte, enforcedPCRs []uint32, ekRoots *x509.CertPool, attestationPolicy *policy.Policy, log vtpm.WarnLogger) *Validator {
 	return &Validator{
 		Validator: vtpm.NewValidator(
 			pcrs,
 			enforcedPCRs,
-			unconditionalTrust,
-			func(attestation vtpm.AttestationDocument) error { return nil },
+			trustedKey(ekRoots),
+			validateVM,
 			vtpm.VerifyPKCS1v15,
+			attestationPolicy,
 			log,
 		),
 	}
 }
 
-// unconditionalTrust returns the given public key as the trusted attestation key.
-func unconditionalTrust(akPub, instanceInfo []byte) (crypto.PublicKey, error) {
-	pubArea, err := tpm2.DecodePublic(akPub)
+// ParseEKRoots parses PEM encoded EK CA certificates.
+func ParseEKRoots(pemCerts string) (*x509.CertPool, error) {
+	roots := x509.NewCertPool()
+	var count int
+	rest := []byte(pemCerts)
+	for {
+		var block *pem.Block
+		block, rest = pem.Decode(rest)
+		if block == nil {
+			break
+		}
+		if block.Type != "CERTIFICATE" {
+			return nil, fmt.Errorf("unexpected PEM block type %q", block.Type)
+		}
+		cert, err := x509.ParseCertificate(block.Bytes)
+		if err != nil {
+			return nil, fmt.Errorf("parsing EK CA certificate: %w", err)
+		}
+		roots.AddCert(cert)
+		count++
+	}
+	if len(bytes.TrimSpace(rest)) > 0 {
+		return nil, errors.New("EK CA certificates contain non-PEM data")
+	}
+	if count == 0 {
+		return nil, errors.New("no EK CA certificates found")
+	}
+	return roots, nil
+}
+
+// trustedKey returns a function verifying the attestation key using the certificate chain in the instance info.
+// The attestation key certificate must be issued by one of ekRoots for the key used to sign the attestation.
+func trustedKey(ekRoots *x509.CertPool) vtpm.GetTPMTrustedAttestationPublicKey {
+	return func(akPub, instanceInfoRaw []byte) (crypto.PublicKey, error) {
+		if ekRoots == nil {
+			return nil, errors.New("no EK CA configured to verify the attestation key")
+		}
+
+		pubArea, err := parseAttestationKey(akPub)
+		if err != nil {
+			return nil, err
+		}
+		key, err := pubArea.Key()
+		if err != nil {
+			return nil, fmt.Errorf("decoding attestation key: %w", err)
+		}
+
+		var info instanceInfo
+		if err := json.Unmarshal(instanceInfoRaw, &info); err != nil {
+			return nil, fmt.Errorf("unmarshaling instance info: %w", err)
+		}
+		if len(info.AKCertificateChain) == 0 {
+			return nil, errors.New("missing attestation key certificate")
+		}
+
+		akCert, err := x509.ParseCertificate(info.AKCertificateChain[0])
+		if err != nil {
+			return nil, fmt.Errorf("parsing attestation key certificate: %w", err)
+		}
+		intermediates := x509.NewCertPool()
+		for _, certDER := range info.AKCertificateChain[1:] {
+			cert, err := x509.ParseCertificate(certDER)
+			if err != nil {
+				return nil, fmt.Errorf("parsing intermediate certificate: %w", err)
+			}
+			intermediates.AddCert(cert)
+		}
+		if _, err := akCert.Verify(x509.VerifyOptions{
+			Roots:         ekRoots,
+			Intermediates: intermediates,
+			KeyUsages:     []x509.ExtKeyUsage{x509.ExtKeyUsageAny},
+		}); err != nil {
+			return nil, fmt.Errorf("verifying attestation key certificate: %w", err)
+		}
+		if !isAKCertificate(akCert) {
+			return nil, errors.New("certificate was not issued for an attestation key")
+		}
+		if !publicKeysEqual(key, akCert.PublicKey) {
+			return nil, errors.New("certificate does not match attestation key")
+		}
+
+		return key, nil
+	}
+}
+
+// validateVM sets the instance ID in claims to the provider ID of the VM recorded in the attestation key certificate.
+// The certificate is already verified in trustedKey().
+func validateVM(attestation vtpm.AttestationDocument, claims *policy.Claims) error {
+	var info instanceInfo
+	if err := json.Unmarshal(attestation.InstanceInfo, &info); err != nil {
+		return fmt.Errorf("unmarshaling instance info: %w", err)
+	}
+	if len(info.AKCertificateChain) == 0 {
+		return errors.New("missing attestation key certificate")
+	}
+	akCert, err := x509.ParseCertificate(info.AKCertificateChain[0])
 	if err != nil {
-		return nil, err
+		return fmt.Errorf("parsing attestation key certificate: %w", err)
+	}
+	if len(akCert.URIs) != 1 {
+		return fmt.Errorf("attestation key certificate must contain exactly one instance ID, got %d", len(akCert.URIs))
+	}
+	claims.InstanceID = akCert.URIs[0].String()
+	return nil
+}
+
+// isAKCertificate checks whether the certificate has the tcg-kp-AIKCertificate extended key usage.
+func isAKCertificate(cert *x509.Certificate) bool {
+	for _, usage := range cert.UnknownExtKeyUsage {
+		if usage.Equal(oidAKCertificate) {
+			return true
+		}
 	}
-	return pubArea.Key()
+	return false
 }
@@ -0,0 +1,393 @@
+/*
+Copyright (c) Edgeless Systems GmbH
+
+SPDX-License-Identifier: AGPL-3.0-only
+*/
+
+package qemu
+
+import (
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/json"
+	"encoding/pem"
+	"io"
+	"math/big"
+	"net/url"
+	"testing"
+	"time"
+
+	"github.com/edgelesssys/constellation/v2/internal/attestation/policy"
+	"github.com/edgelesssys/constellation/v2/internal/attestation/simulator"
+	"github.com/edgelesssys/constellation/v2/internal/attestation/vtpm"
+	tpmclient "github.com/google/go-tpm-tools/client"
+	"github.com/google/go-tpm/tpm2"
+	"github.com/google/go-tpm/tpmutil"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/goleak"
+)
+
+func TestMain(m *testing.M) {
+	goleak.VerifyTestMain(m)
+}
+
+func TestTrustedKey(t *testing.T) {
+	ca, caKey := newTestCA(t)
+	otherCA, _ := newTestCA(t)
+
+	testCases := map[string]struct {
+		roots   *x509.CertPool
+		akPub   func(t *testing.T, tpm io.ReadWriter) []byte
+		modify  func(t *testing.T, info *instanceInfo, akPub []byte)
+		wantErr bool
+	}{
+		"success": {
+			roots: certPool(ca),
+			akPub: attestationKeyPub,
+		},
+		"no EK CA configured": {
+			akPub:   attestationKeyPub,
+			wantErr: true,
+		},
+		"certificate from unknown CA": {
+			roots:   certPool(otherCA),
+			akPub:   attestationKeyPub,
+			wantErr: true,
+		},
+		"certificate of other key": {
+			roots: certPool(ca),
+			akPub: func(t *testing.T, tpm io.ReadWriter) []byte {
+				key, err := tpmclient.AttestationKeyECC(tpm)
+				require.NoError(t, err)
+				defer key.Close()
+				pub, err := key.PublicArea().Encode()
+				require.NoError(t, err)
+				return pub
+			},
+			wantErr: true,
+		},
+		"attestation key not fixed to TPM": {
+			roots: certPool(ca),
+			akPub: func(t *testing.T, tpm io.ReadWriter) []byte {
+				template := tpmclient.AKTemplateRSA()
+				template.Attributes &^= tpm2.FlagFixedTPM | tpm2.FlagFixedParent
+				pub, err := template.Encode()
+				require.NoError(t, err)
+				return pub
+			},
+			wantErr: true,
+		},
+		"missing certificate": {
+			roots: certPool(ca),
+			akPub: attestationKeyPub,
+			modify: func(t *testing.T, info *instanceInfo, _ []byte) {
+				info.AKCertificateChain = nil
+			},
+			wantErr: true,
+		},
+		"invalid certificate": {
+			roots: certPool(ca),
+			akPub: attestationKeyPub,
+			modify: func(t *testing.T, info *instanceInfo, _ []byte) {
+				info.AKCertificateChain[0] = []byte("invalid")
+			},
+			wantErr: true,
+		},
+		"certificate not issued for attestation key": {
+			roots: certPool(ca),
+			akPub: attestationKeyPub,
+			modify: func(t *testing.T, info *instanceInfo, akPub []byte) {
+				pubArea, err := tpm2.DecodePublic(akPub)
+				require.NoError(t, err)
+				key, err := pubArea.Key()
+				require.NoError(t, err)
+				info.AKCertificateChain[0] = newTestCertificate(t, ca, caKey, key)
+			},
+			wantErr: true,
+		},
+	}
+
+	for name, tc := range testCases {
+		t.Run(name, func(t *testing.T) {
+			assert := assert.New(t)
+			require := require.New(t)
+
+			tpm, err := simulator.OpenSimulatedTPM()
+			require.NoError(err)
+			defer tpm.Close()
+			provisionEKCertificate(t, tpm, ca, caKey)
+
+			infoRaw, err := newAKCertifier(newTestVMAKCA(t, ca, caKey)).getInstanceInfo(tpm)
+			require.NoError(err)
+			akPub := tc.akPub(t, tpm)
+			if tc.modify != nil {
+				var info instanceInfo
+				require.NoError(json.Unmarshal(infoRaw, &info))
+				tc.modify(t, &info, akPub)
+				infoRaw, err = json.Marshal(info)
+				require.NoError(err)
+			}
+
+			_, err = trustedKey(tc.roots)(akPub, infoRaw)
+			if tc.wantErr {
+				assert.Error(err)
+			} else {
+				assert.NoError(err)
+			}
+		})
+	}
+}
+
+func TestValidate(t *testing.T) {
+	assert := assert.New(t)
+	require := require.New(t)
+
+	ca, caKey := newTestCA(t)
+	openTPM, tpmCloser := simulator.NewSimulatedTPMOpenFunc()
+	defer tpmCloser.Close()
+
+	tpm, err := openTPM()
+	require.NoError(err)
+	provisionEKCertificate(t, tpm, ca, caKey)
+
+	openTPMWithEventLog := func() (io.ReadWriteCloser, error) {
+		return simTPMWithEventLog{tpm}, nil
+	}
+	certifier := newAKCertifier(newTestVMAKCA(t, ca, caKey))
+	issuer := &Issuer{Issuer: vtpm.NewIssuer(openTPMWithEventLog, tpmclient.AttestationKeyRSA, certifier.getInstanceInfo)}
+	attDoc, err := issuer.Issue([]byte("user data"), []byte("nonce"))
+	require.NoError(err)
+
+	validator := NewValidator(map[uint32][]byte{}, nil, certPool(ca), nil, nil)
+	userData, claims, err := validator.ValidateWithClaims(attDoc, []byte("nonce"))
+	require.NoError(err)
+	assert.Equal([]byte("user data"), userData)
+	assert.Equal(testInstanceID, claims.InstanceID)
+
+	otherCA, _ := newTestCA(t)
+	validator = NewValidator(map[uint32][]byte{}, nil, certPool(otherCA), nil, nil)
+	_, err = validator.Validate(attDoc, []byte("nonce"))
+	assert.Error(err)
+
+	validator = NewValidator(map[uint32][]byte{}, nil, nil, nil, nil)
+	_, err = validator.Validate(attDoc, []byte("nonce"))
+	assert.Error(err)
+}
+
+func TestValidateVM(t *testing.T) {
+	ca, caKey := newTestCA(t)
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	require.NoError(t, err)
+
+	testCases := map[string]struct {
+		chain          [][]byte
+		wantInstanceID string
+		wantErr        bool
+	}{
+		"success": {
+			chain:          [][]byte{newTestCertificate(t, ca, caKey, &key.PublicKey, testInstanceID), ca.Raw},
+			wantInstanceID: testInstanceID,
+		},
+		"certificate without instance ID": {
+			chain:   [][]byte{newTestCertificate(t, ca, caKey, &key.PublicKey), ca.Raw},
+			wantErr: true,
+		},
+		"certificate with multiple instance IDs": {
+			chain:   [][]byte{newTestCertificate(t, ca, caKey, &key.PublicKey, testInstanceID, "qemu:///hostname/worker-1"), ca.Raw},
+			wantErr: true,
+		},
+		"missing certificate": {
+			wantErr: true,
+		},
+	}
+
+	for name, tc := range testCases {
+		t.Run(name, func(t *testing.T) {
+			assert := assert.New(t)
+			require := require.New(t)
+
+			infoRaw, err := json.Marshal(instanceInfo{AKCertificateChain: tc.chain})
+			require.NoError(err)
+
+			var claims policy.Claims
+			err = validateVM(vtpm.AttestationDocument{InstanceInfo: infoRaw}, &claims)
+			if tc.wantErr {
+				assert.Error(err)
+				return
+			}
+			require.NoError(err)
+			assert.Equal(tc.wantInstanceID, claims.InstanceID)
+		})
+	}
+}
+
+func TestParseEKRoots(t *testing.T) {
+	ca, _ := newTestCA(t)
+	caPEM := string(pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: ca.Raw}))
+
+	testCases := map[string]struct {
+		pem     string
+		wantErr bool
+	}{
+		"single certificate": {
+			pem: caPEM,
+		},
+		"multiple certificates": {
+			pem: caPEM + caPEM,
+		},
+		"empty": {
+			wantErr: true,
+		},
+		"invalid PEM": {
+			pem:     "not a certificate",
+			wantErr: true,
+		},
+		"trailing data": {
+			pem:     caPEM + "garbage",
+			wantErr: true,
+		},
+		"private key": {
+			pem:     string(pem.EncodeToMemory(&pem.Block{Type: "PRIVATE KEY", Bytes: []byte{0x1}})),
+			wantErr: true,
+		},
+	}
+
+	for name, tc := range testCases {
+		t.Run(name, func(t *testing.T) {
+			assert := assert.New(t)
+
+			roots, err := ParseEKRoots(tc.pem)
+			if tc.wantErr {
+				assert.Error(err)
+			} else {
+				assert.NoError(err)
+				assert.NotNil(roots)
+			}
+		})
+	}
+}
+
+type simTPMWithEventLog struct {
+	io.ReadWriteCloser
+}
+
+// EventLog returns an empty event log.
+func (s simTPMWithEventLog) EventLog() ([]byte, error) {
+	// event log header for successful parsing of event log
+	header := []byte{
+		0x00, 0x00, 0x00, 0x00, 0x03, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00,
+		0x00, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00, 0x25, 0x00, 0x00, 0x00, 0x53, 0x70, 0x65,
+		0x63, 0x20, 0x49, 0x44, 0x20, 0x45, 0x76, 0x65, 0x6E, 0x74, 0x30, 0x33, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00,
+		0x02, 0x00, 0x02, 0x02, 0x00, 0x00, 0x00, 0x04, 0x00, 0x14, 0x00, 0x0B, 0x00, 0x20, 0x00, 0x00,
+	}
+	return header, nil
+}
+
+func attestationKeyPub(t *testing.T, tpm io.ReadWriter) []byte {
+	key, err := tpmclient.AttestationKeyRSA(tpm)
+	require.NoError(t, err)
+	defer key.Close()
+	pub, err := key.PublicArea().Encode()
+	require.NoError(t, err)
+	return pub
+}
+
+func newTestCA(t *testing.T) (*x509.Certificate, *ecdsa.PrivateKey) {
+	t.Helper()
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	require.NoError(t, err)
+	template := &x509.Certificate{
+		SerialNumber:          big.NewInt(1),
+		Subject:               pkix.Name{CommonName: "swtpm-localca"},
+		NotBefore:             time.Now().Add(-time.Hour),
+		NotAfter:              time.Now().Add(time.Hour),
+		KeyUsage:              x509.KeyUsageCertSign,
+		BasicConstraintsValid: true,
+		IsCA:                  true,
+	}
+	der, err := x509.CreateCertificate(rand.Reader, template, template, &key.PublicKey, key)
+	require.NoError(t, err)
+	cert, err := x509.ParseCertificate(der)
+	require.NoError(t, err)
+	return cert, key
+}
+
+// provisionEKCertificate writes an EK certificate for the default RSA EK to the TPM, like swtpm_setup does.
+func provisionEKCertificate(t *testing.T, tpm io.ReadWriter, ca *x509.Certificate, caKey *ecdsa.PrivateKey) {
+	t.Helper()
+	require := require.New(t)
+
+	ek, err := tpmclient.EndorsementKeyRSA(tpm)
+	require.NoError(err)
+	defer ek.Close()
+
+	der := newTestCertificate(t, ca, caKey, ek.PublicKey())
+	index := tpmutil.Handle(tpmclient.EKCertNVIndexRSA)
+	require.NoError(tpm2.NVDefineSpace(tpm, tpm2.HandleOwner, index, "", "", nil, tpm2.AttrOwnerRead|tpm2.AttrOwnerWrite, uint16(len(der))))
+	require.NoError(tpm2.NVWrite(tpm, tpm2.HandleOwner, index, "", der, 0))
+}
+
+// newTestCertificate issues a leaf certificate for pub, with the given URI SANs.
+func newTestCertificate(t *testing.T, ca *x509.Certificate, caKey *ecdsa.PrivateKey, pub crypto.PublicKey, uris ...string) []byte {
+	t.Helper()
+	template := &x509.Certificate{
+		SerialNumber: big.NewInt(2),
+		NotBefore:    time.Now().Add(-time.Hour),
+		NotAfter:     time.Now().Add(time.Hour),
+		KeyUsage:     x509.KeyUsageKeyEncipherment | x509.KeyUsageDigitalSignature,
+	}
+	for _, uri := range uris {
+		parsed, err := url.Parse(uri)
+		require.NoError(t, err)
+		template.URIs = append(template.URIs, parsed)
+	}
+	der, err := x509.CreateCertificate(rand.Reader, template, ca, pub, caKey)
+	require.NoError(t, err)
+	return der
+}
+
+func newTestAKCA(t *testing.T, ca *x509.Certificate, caKey *ecdsa.PrivateKey) *AKCA {
+	t.Helper()
+	keyDER, err := x509.MarshalECPrivateKey(caKey)
+	require.NoError(t, err)
+	akCA, err := NewAKCA(
+		pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: ca.Raw}),
+		pem.EncodeToMemory(&pem.Block{Type: "EC PRIVATE KEY", Bytes: keyDER}),
+	)
+	require.NoError(t, err)
+	return akCA
+}
+
+// testInstanceID is the provider ID of the VM requesting certificates from the CAs returned by newTestVMAKCA.
+const testInstanceID = "qemu:///hostname/worker-0"
+
+// vmAKCA is an attestation key CA serving requests of a single VM.
+type vmAKCA struct {
+	ca         *AKCA
+	instanceID string
+}
+
+func newTestVMAKCA(t *testing.T, ca *x509.Certificate, caKey *ecdsa.PrivateKey) *vmAKCA {
+	return &vmAKCA{ca: newTestAKCA(t, ca, caKey), instanceID: testInstanceID}
+}
+
+func (v *vmAKCA) Challenge(req AKChallengeRequest) (AKChallengeResponse, error) {
+	return v.ca.Challenge(req, v.instanceID)
+}
+
+func (v *vmAKCA) Certify(req AKCertificateRequest) (AKCertificateResponse, error) {
+	return v.ca.Certify(req)
+}
+
+func certPool(certs ...*x509.Certificate) *x509.CertPool {
+	pool := x509.NewCertPool()
+	for _, cert := range certs {
+		pool.AddCert(cert)
+	}
+	return pool
+}
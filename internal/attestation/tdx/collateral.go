@@ -0,0 +1,574 @@
+/*
+Copyright (c) Edgeless Systems GmbH
+
+SPDX-License-Identifier: AGPL-3.0-only
+*/
+
+package tdx
+
+import (
+	"bytes"
+	"context"
+	"crypto/ecdsa"
+	"crypto/sha256"
+	"crypto/x509"
+	"encoding/asn1"
+	"encoding/binary"
+	"encoding/hex"
+	"encoding/json"
+	"encoding/pem"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+)
+
+const (
+	// pcsTCBInfoURL is the Intel Provisioning Certification Service endpoint for TDX TCB info.
+	pcsTCBInfoURL = "https://api.trustedservices.intel.com/tdx/certification/v4/tcb"
+	// pcsTCBInfoIssuerChainHeader is the response header holding the TCB info signing certificate chain.
+	pcsTCBInfoIssuerChainHeader = "TCB-Info-Issuer-Chain"
+	// pcsQEIdentityURL is the Intel Provisioning Certification Service endpoint for the identity of the TD quoting enclave.
+	pcsQEIdentityURL = "https://api.trustedservices.intel.com/tdx/certification/v4/qe/identity"
+	// pcsQEIdentityIssuerChainHeader is the response header holding the QE identity signing certificate chain.
+	pcsQEIdentityIssuerChainHeader = "SGX-Enclave-Identity-Issuer-Chain"
+	// pcsPCKCRLURL is the Intel Provisioning Certification Service endpoint for the CRLs of the PCK issuing CAs.
+	pcsPCKCRLURL = "https://api.trustedservices.intel.com/sgx/certification/v4/pckcrl"
+	// pcsPCKCRLIssuerChainHeader is the response header holding the PCK CRL signing certificate chain.
+	pcsPCKCRLIssuerChainHeader = "SGX-PCK-CRL-Issuer-Chain"
+
+	// pckPlatformCA and pckProcessorCA are the two CAs issuing PCK certificates, as named by the PCS API.
+	pckPlatformCA  = "platform"
+	pckProcessorCA = "processor"
+
+	tcbStatusUpToDate          = "UpToDate"
+	tcbStatusSWHardeningNeeded = "SWHardeningNeeded"
+
+	// qeIdentityID is the ID of the TD quoting enclave in its identity.
+	qeIdentityID = "TD_QE"
+)
+
+var (
+	oidSGXExtensions = asn1.ObjectIdentifier{1, 2, 840, 113741, 1, 13, 1}
+	oidTCB           = asn1.ObjectIdentifier{1, 2, 840, 113741, 1, 13, 1, 2}
+	oidPCESVN        = asn1.ObjectIdentifier{1, 2, 840, 113741, 1, 13, 1, 2, 17}
+	oidFMSPC         = asn1.ObjectIdentifier{1, 2, 840, 113741, 1, 13, 1, 4}
+)
+
+// Collateral holds the endorsements needed to appraise the TCB level of a TDX quote.
+type Collateral struct {
+	// TCBInfo is the raw response body of the Intel PCS TCB info endpoint.
+	TCBInfo []byte
+	// TCBInfoIssuerChain is the PEM encoded certificate chain of the TCB info signing key.
+	TCBInfoIssuerChain []byte
+	// QEIdentity is the raw response body of the Intel PCS QE identity endpoint.
+	QEIdentity []byte
+	// QEIdentityIssuerChain is the PEM encoded certificate chain of the QE identity signing key.
+	QEIdentityIssuerChain []byte
+	// PCKCRL is the DER encoded revocation list of the CA issuing the PCK certificate.
+	PCKCRL []byte
+	// PCKCRLIssuerChain is the PEM encoded certificate chain of the PCK CRL signing key.
+	PCKCRLIssuerChain []byte
+}
+
+type tcbInfoResponse struct {
+	// TCBInfo is kept raw, since the signature is calculated over the exact bytes of the JSON object.
+	TCBInfo   json.RawMessage `json:"tcbInfo"`
+	Signature string          `json:"signature"`
+}
+
+type tcbInfo struct {
+	ID                  string              `json:"id"`
+	Version             int                 `json:"version"`
+	IssueDate           time.Time           `json:"issueDate"`
+	NextUpdate          time.Time           `json:"nextUpdate"`
+	FMSPC               string              `json:"fmspc"`
+	PCEID               string              `json:"pceId"`
+	TDXModule           tdxModule           `json:"tdxModule"`
+	TDXModuleIdentities []tdxModuleIdentity `json:"tdxModuleIdentities"`
+	TCBLevels           []tcbLevel          `json:"tcbLevels"`
+}
+
+// tdxModule is the expected identity of the Intel TDX module, i.e. the SEAM.
+type tdxModule struct {
+	MRSigner       string `json:"mrsigner"`
+	Attributes     string `json:"attributes"`
+	AttributesMask string `json:"attributesMask"`
+}
+
+// tdxModuleIdentity is the expected identity and the TCB levels of a major version of the Intel TDX module.
+type tdxModuleIdentity struct {
+	ID string `json:"id"`
+	tdxModule
+	TCBLevels []isvTCBLevel `json:"tcbLevels"`
+}
+
+type qeIdentityResponse struct {
+	// EnclaveIdentity is kept raw, since the signature is calculated over the exact bytes of the JSON object.
+	EnclaveIdentity json.RawMessage `json:"enclaveIdentity"`
+	Signature       string          `json:"signature"`
+}
+
+// qeIdentity is the expected identity and the TCB levels of the TD quoting enclave.
+type qeIdentity struct {
+	ID             string        `json:"id"`
+	Version        int           `json:"version"`
+	IssueDate      time.Time     `json:"issueDate"`
+	NextUpdate     time.Time     `json:"nextUpdate"`
+	MiscSelect     string        `json:"miscselect"`
+	MiscSelectMask string        `json:"miscselectMask"`
+	Attributes     string        `json:"attributes"`
+	AttributesMask string        `json:"attributesMask"`
+	MRSigner       string        `json:"mrsigner"`
+	ISVProdID      uint16        `json:"isvprodid"`
+	TCBLevels      []isvTCBLevel `json:"tcbLevels"`
+}
+
+// isvTCBLevel is a TCB level of an enclave or the TDX module, identified by its security version number.
+type isvTCBLevel struct {
+	TCB struct {
+		ISVSVN uint16 `json:"isvsvn"`
+	} `json:"tcb"`
+	TCBDate   string `json:"tcbDate"`
+	TCBStatus string `json:"tcbStatus"`
+}
+
+type tcbLevel struct {
+	TCB struct {
+		SGXTCBComponents []tcbComponent `json:"sgxtcbcomponents"`
+		PCESVN           uint16         `json:"pcesvn"`
+		TDXTCBComponents []tcbComponent `json:"tdxtcbcomponents"`
+	} `json:"tcb"`
+	TCBDate   string `json:"tcbDate"`
+	TCBStatus string `json:"tcbStatus"`
+}
+
+type tcbComponent struct {
+	SVN uint8 `json:"svn"`
+}
+
+// pckExtensions holds the platform's TCB as certified in the Intel SGX extensions of a PCK certificate.
+type pckExtensions struct {
+	fmspc            []byte
+	pceSVN           uint16
+	sgxTCBComponents [16]uint8
+}
+
+type sgxExtension struct {
+	ID    asn1.ObjectIdentifier
+	Value asn1.RawValue
+}
+
+// parsePCKExtensions reads FMSPC and TCB values from the Intel SGX extensions of a PCK certificate.
+func parsePCKExtensions(cert *x509.Certificate) (pckExtensions, error) {
+	var ext pckExtensions
+	var sgxExtensions []sgxExtension
+	for _, e := range cert.Extensions {
+		if e.Id.Equal(oidSGXExtensions) {
+			if _, err := asn1.Unmarshal(e.Value, &sgxExtensions); err != nil {
+				return pckExtensions{}, fmt.Errorf("unmarshalling SGX extensions: %w", err)
+			}
+		}
+	}
+	if sgxExtensions == nil {
+		return pckExtensions{}, errors.New("PCK certificate does not contain SGX extensions")
+	}
+
+	var foundFMSPC, foundTCB bool
+	for _, e := range sgxExtensions {
+		switch {
+		case e.ID.Equal(oidFMSPC):
+			if _, err := asn1.Unmarshal(e.Value.FullBytes, &ext.fmspc); err != nil {
+				return pckExtensions{}, fmt.Errorf("unmarshalling FMSPC: %w", err)
+			}
+			foundFMSPC = true
+		case e.ID.Equal(oidTCB):
+			var tcb []sgxExtension
+			if _, err := asn1.Unmarshal(e.Value.FullBytes, &tcb); err != nil {
+				return pckExtensions{}, fmt.Errorf("unmarshalling TCB: %w", err)
+			}
+			for _, comp := range tcb {
+				idx := comp.ID[len(comp.ID)-1]
+				switch {
+				case comp.ID.Equal(oidPCESVN):
+					var svn int
+					if _, err := asn1.Unmarshal(comp.Value.FullBytes, &svn); err != nil {
+						return pckExtensions{}, fmt.Errorf("unmarshalling PCESVN: %w", err)
+					}
+					ext.pceSVN = uint16(svn)
+				case idx >= 1 && idx <= 16:
+					var svn int
+					if _, err := asn1.Unmarshal(comp.Value.FullBytes, &svn); err != nil {
+						return pckExtensions{}, fmt.Errorf("unmarshalling SGX TCB component %d: %w", idx, err)
+					}
+					ext.sgxTCBComponents[idx-1] = uint8(svn)
+				}
+			}
+			foundTCB = true
+		}
+	}
+	if !foundFMSPC || !foundTCB {
+		return pckExtensions{}, errors.New("PCK certificate is missing FMSPC or TCB extension")
+	}
+
+	return ext, nil
+}
+
+// verifyTCBInfo verifies the signature of the TCB info against the trusted root and returns the parsed TCB info.
+func verifyTCBInfo(collateral Collateral, root *x509.Certificate, now time.Time) (*tcbInfo, error) {
+	var resp tcbInfoResponse
+	if err := json.Unmarshal(collateral.TCBInfo, &resp); err != nil {
+		return nil, fmt.Errorf("unmarshalling TCB info: %w", err)
+	}
+	if err := verifySignedCollateral(resp.TCBInfo, resp.Signature, collateral.TCBInfoIssuerChain, root, now); err != nil {
+		return nil, &tcbInfoError{err}
+	}
+
+	var info tcbInfo
+	if err := json.Unmarshal(resp.TCBInfo, &info); err != nil {
+		return nil, fmt.Errorf("unmarshalling TCB info body: %w", err)
+	}
+	if info.ID != "TDX" {
+		return nil, fmt.Errorf("unexpected TCB info ID: %q", info.ID)
+	}
+	if now.After(info.NextUpdate) {
+		return nil, &tcbInfoError{fmt.Errorf("TCB info expired at %s", info.NextUpdate)}
+	}
+
+	return &info, nil
+}
+
+// verifyQEIdentity verifies the signature of the QE identity against the trusted root and returns the parsed QE identity.
+func verifyQEIdentity(collateral Collateral, root *x509.Certificate, now time.Time) (*qeIdentity, error) {
+	var resp qeIdentityResponse
+	if err := json.Unmarshal(collateral.QEIdentity, &resp); err != nil {
+		return nil, &qeIdentityError{fmt.Errorf("unmarshalling QE identity: %w", err)}
+	}
+	if err := verifySignedCollateral(resp.EnclaveIdentity, resp.Signature, collateral.QEIdentityIssuerChain, root, now); err != nil {
+		return nil, &qeIdentityError{err}
+	}
+
+	var identity qeIdentity
+	if err := json.Unmarshal(resp.EnclaveIdentity, &identity); err != nil {
+		return nil, &qeIdentityError{fmt.Errorf("unmarshalling QE identity body: %w", err)}
+	}
+	if identity.ID != qeIdentityID {
+		return nil, &qeIdentityError{fmt.Errorf("unexpected QE identity ID: %q", identity.ID)}
+	}
+	if now.After(identity.NextUpdate) {
+		return nil, &qeIdentityError{fmt.Errorf("QE identity expired at %s", identity.NextUpdate)}
+	}
+
+	return &identity, nil
+}
+
+// verifySignedCollateral verifies the raw ECDSA signature over body, made by the leaf of issuerChain.
+func verifySignedCollateral(body []byte, signature string, issuerChain []byte, root *x509.Certificate, now time.Time) error {
+	chain, err := parsePEMChain(issuerChain)
+	if err != nil {
+		return fmt.Errorf("parsing issuer chain: %w", err)
+	}
+	signingCert, err := verifyChain(chain, root, now)
+	if err != nil {
+		return fmt.Errorf("verifying issuer chain: %w", err)
+	}
+	signingKey, ok := signingCert.PublicKey.(*ecdsa.PublicKey)
+	if !ok {
+		return errors.New("signing key is not an ECDSA key")
+	}
+
+	sig, err := hex.DecodeString(signature)
+	if err != nil {
+		return fmt.Errorf("decoding signature: %w", err)
+	}
+	digest := sha256.Sum256(body)
+	if !verifyRawECDSA(signingKey, digest[:], sig) {
+		return errors.New("invalid signature")
+	}
+	return nil
+}
+
+// verifyPCKRevocation checks that the PCK certificate is not revoked by the CRL of its issuing CA.
+func verifyPCKRevocation(pckCert *x509.Certificate, collateral Collateral, root *x509.Certificate, now time.Time) error {
+	chain, err := parsePEMChain(collateral.PCKCRLIssuerChain)
+	if err != nil {
+		return fmt.Errorf("parsing PCK CRL issuer chain: %w", err)
+	}
+	crlIssuer, err := verifyChain(chain, root, now)
+	if err != nil {
+		return fmt.Errorf("verifying PCK CRL issuer chain: %w", err)
+	}
+	if !bytes.Equal(crlIssuer.RawSubject, pckCert.RawIssuer) {
+		return errors.New("PCK CRL is not issued by the issuer of the PCK certificate")
+	}
+
+	crl, err := x509.ParseDERCRL(collateral.PCKCRL)
+	if err != nil {
+		return fmt.Errorf("parsing PCK CRL: %w", err)
+	}
+	if err := crlIssuer.CheckCRLSignature(crl); err != nil {
+		return fmt.Errorf("verifying PCK CRL signature: %w", err)
+	}
+	if crl.HasExpired(now) {
+		return fmt.Errorf("PCK CRL expired at %s", crl.TBSCertList.NextUpdate)
+	}
+
+	for _, revoked := range crl.TBSCertList.RevokedCertificates {
+		if revoked.SerialNumber.Cmp(pckCert.SerialNumber) == 0 {
+			return fmt.Errorf("PCK certificate %s is revoked", pckCert.SerialNumber)
+		}
+	}
+	return nil
+}
+
+// pckCAType returns the name of the CA issuing a PCK certificate, as used by the PCS API.
+func pckCAType(pckCert *x509.Certificate) (string, error) {
+	switch issuer := pckCert.Issuer.CommonName; {
+	case strings.Contains(issuer, "Platform"):
+		return pckPlatformCA, nil
+	case strings.Contains(issuer, "Processor"):
+		return pckProcessorCA, nil
+	default:
+		return "", fmt.Errorf("unknown PCK issuer: %q", issuer)
+	}
+}
+
+// tcbStatus returns the status of the first TCB level not higher than the platform's TCB.
+// TCB levels in the TCB info are sorted from the highest to the lowest level.
+func (t *tcbInfo) tcbStatus(pck pckExtensions, teeTCBSVN [16]byte) (string, error) {
+	for _, level := range t.TCBLevels {
+		if level.matches(pck, teeTCBSVN) {
+			return level.TCBStatus, nil
+		}
+	}
+	return "", errors.New("no matching TCB level found")
+}
+
+func (l *tcbLevel) matches(pck pckExtensions, teeTCBSVN [16]byte) bool {
+	if len(l.TCB.SGXTCBComponents) != len(pck.sgxTCBComponents) || len(l.TCB.TDXTCBComponents) != len(teeTCBSVN) {
+		return false
+	}
+	for i, comp := range l.TCB.SGXTCBComponents {
+		if pck.sgxTCBComponents[i] < comp.SVN {
+			return false
+		}
+	}
+	if pck.pceSVN < l.TCB.PCESVN {
+		return false
+	}
+	for i, comp := range l.TCB.TDXTCBComponents {
+		// the SVN and major version of TDX modules with a major version above 0
+		// are appraised using the TDX module identities instead
+		if i < 2 && teeTCBSVN[1] > 0 {
+			continue
+		}
+		if teeTCBSVN[i] < comp.SVN {
+			return false
+		}
+	}
+	return true
+}
+
+// moduleStatus verifies the identity of the TDX module against the TCB info and returns the status of its TCB level.
+// TEE_TCB_SVN[0] is the SVN and TEE_TCB_SVN[1] the major version of the TDX module.
+func (t *tcbInfo) moduleStatus(body tdQuoteBody) (string, error) {
+	version, svn := body.TEETCBSVN[1], body.TEETCBSVN[0]
+	if version == 0 {
+		if err := t.TDXModule.matches(body); err != nil {
+			return "", err
+		}
+		// the TCB level of the first major version is part of the TDX TCB components
+		return tcbStatusUpToDate, nil
+	}
+
+	id := fmt.Sprintf("TDX_%02X", version)
+	for _, identity := range t.TDXModuleIdentities {
+		if identity.ID != id {
+			continue
+		}
+		if err := identity.matches(body); err != nil {
+			return "", err
+		}
+		return isvTCBStatus(identity.TCBLevels, uint16(svn))
+	}
+	return "", fmt.Errorf("no identity found for TDX module %s", id)
+}
+
+// matches checks the signer and attributes of the TDX module.
+func (m tdxModule) matches(body tdQuoteBody) error {
+	mrSigner, err := hex.DecodeString(m.MRSigner)
+	if err != nil {
+		return fmt.Errorf("decoding TDX module MRSIGNER: %w", err)
+	}
+	if !bytes.Equal(mrSigner, body.MRSignerSEAM[:]) {
+		return fmt.Errorf("untrusted TDX module signer: %x", body.MRSignerSEAM)
+	}
+	if !maskedEqual(body.SEAMAttributes[:], m.Attributes, m.AttributesMask) {
+		return fmt.Errorf("untrusted TDX module attributes: %x", body.SEAMAttributes)
+	}
+	return nil
+}
+
+// matches checks the quoting enclave that signed a quote against the QE identity and returns the status of its TCB level.
+func (q *qeIdentity) matches(report qeReport) (string, error) {
+	mrSigner, err := hex.DecodeString(q.MRSigner)
+	if err != nil {
+		return "", fmt.Errorf("decoding QE MRSIGNER: %w", err)
+	}
+	if !bytes.Equal(mrSigner, report.MRSigner[:]) {
+		return "", fmt.Errorf("untrusted QE signer: %x", report.MRSigner)
+	}
+	if report.ISVProdID != q.ISVProdID {
+		return "", fmt.Errorf("untrusted QE product ID: %d", report.ISVProdID)
+	}
+	miscSelect := make([]byte, 4)
+	binary.BigEndian.PutUint32(miscSelect, report.MiscSelect)
+	if !maskedEqual(miscSelect, q.MiscSelect, q.MiscSelectMask) {
+		return "", fmt.Errorf("untrusted QE MISCSELECT: %x", miscSelect)
+	}
+	if !maskedEqual(report.Attributes[:], q.Attributes, q.AttributesMask) {
+		return "", fmt.Errorf("untrusted QE attributes: %x", report.Attributes)
+	}
+	return isvTCBStatus(q.TCBLevels, report.ISVSVN)
+}
+
+// isvTCBStatus returns the status of the first TCB level with an SVN not higher than svn.
+// TCB levels are sorted from the highest to the lowest level.
+func isvTCBStatus(levels []isvTCBLevel, svn uint16) (string, error) {
+	for _, level := range levels {
+		if svn >= level.TCB.ISVSVN {
+			return level.TCBStatus, nil
+		}
+	}
+	return "", fmt.Errorf("no matching TCB level found for SVN %d", svn)
+}
+
+// maskedEqual checks if value AND mask equals expected AND mask. expected and mask are hex encoded.
+func maskedEqual(value []byte, expected, mask string) bool {
+	expectedRaw, err := hex.DecodeString(expected)
+	if err != nil || len(expectedRaw) != len(value) {
+		return false
+	}
+	maskRaw, err := hex.DecodeString(mask)
+	if err != nil || len(maskRaw) != len(value) {
+		return false
+	}
+	for i := range value {
+		if value[i]&maskRaw[i] != expectedRaw[i]&maskRaw[i] {
+			return false
+		}
+	}
+	return true
+}
+
+// pcsClient fetches collateral from the Intel Provisioning Certification Service.
+type pcsClient struct {
+	client        httpClient
+	tcbInfoURL    string
+	qeIdentityURL string
+	pckCRLURL     string
+}
+
+func (c *pcsClient) getCollateral(ctx context.Context, fmspc []byte, pckCA string) (Collateral, error) {
+	tcbInfo, tcbInfoChain, err := c.get(ctx, c.tcbInfoURL, url.Values{"fmspc": []string{hex.EncodeToString(fmspc)}}, pcsTCBInfoIssuerChainHeader)
+	if err != nil {
+		return Collateral{}, fmt.Errorf("fetching TCB info: %w", err)
+	}
+	qeIdentity, qeIdentityChain, err := c.get(ctx, c.qeIdentityURL, nil, pcsQEIdentityIssuerChainHeader)
+	if err != nil {
+		return Collateral{}, fmt.Errorf("fetching QE identity: %w", err)
+	}
+	pckCRL, pckCRLChain, err := c.get(ctx, c.pckCRLURL, url.Values{"ca": []string{pckCA}, "encoding": []string{"der"}}, pcsPCKCRLIssuerChainHeader)
+	if err != nil {
+		return Collateral{}, fmt.Errorf("fetching PCK CRL: %w", err)
+	}
+
+	return Collateral{
+		TCBInfo:               tcbInfo,
+		TCBInfoIssuerChain:    tcbInfoChain,
+		QEIdentity:            qeIdentity,
+		QEIdentityIssuerChain: qeIdentityChain,
+		PCKCRL:                pckCRL,
+		PCKCRLIssuerChain:     pckCRLChain,
+	}, nil
+}
+
+// get fetches a collateral from the PCS and returns the response body and the issuer chain from chainHeader.
+func (c *pcsClient) get(ctx context.Context, rawURL string, query url.Values, chainHeader string) ([]byte, []byte, error) {
+	reqURL, err := url.Parse(rawURL)
+	if err != nil {
+		return nil, nil, err
+	}
+	reqURL.RawQuery = query.Encode()
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, reqURL.String(), http.NoBody)
+	if err != nil {
+		return nil, nil, err
+	}
+	resp, err := c.client.Do(req)
+	if err != nil {
+		return nil, nil, err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return nil, nil, fmt.Errorf("unexpected status code from PCS: %s", resp.Status)
+	}
+
+	body, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return nil, nil, err
+	}
+	chain, err := url.QueryUnescape(resp.Header.Get(chainHeader))
+	if err != nil {
+		return nil, nil, fmt.Errorf("decoding issuer chain: %w", err)
+	}
+	return body, []byte(chain), nil
+}
+
+// parsePEMChain parses all PEM encoded certificates in raw.
+// Trailing null bytes, as appended by the quoting enclave, are ignored.
+func parsePEMChain(raw []byte) ([]*x509.Certificate, error) {
+	var certs []*x509.Certificate
+	for {
+		var block *pem.Block
+		block, raw = pem.Decode(raw)
+		if block == nil {
+			break
+		}
+		cert, err := x509.ParseCertificate(block.Bytes)
+		if err != nil {
+			return nil, err
+		}
+		certs = append(certs, cert)
+	}
+	if len(certs) == 0 {
+		return nil, errors.New("no certificates found")
+	}
+	return certs, nil
+}
+
+// verifyChain verifies that chain[0] is issued by root, using the remaining certificates as intermediates.
+func verifyChain(chain []*x509.Certificate, root *x509.Certificate, now time.Time) (*x509.Certificate, error) {
+	roots := x509.NewCertPool()
+	roots.AddCert(root)
+	intermediates := x509.NewCertPool()
+	for _, cert := range chain[1:] {
+		intermediates.AddCert(cert)
+	}
+	if _, err := chain[0].Verify(x509.VerifyOptions{
+		Roots:         roots,
+		Intermediates: intermediates,
+		CurrentTime:   now,
+		KeyUsages:     []x509.ExtKeyUsage{x509.ExtKeyUsageAny},
+	}); err != nil {
+		return nil, err
+	}
+	return chain[0], nil
+}
+
+type httpClient interface {
+	Do(req *http.Request) (*http.Response, error)
+}
@@ -0,0 +1,86 @@
+/*
+Copyright (c) Edgeless Systems GmbH
+
+SPDX-License-Identifier: AGPL-3.0-only
+*/
+
+package tdx
+
+import (
+	"errors"
+	"fmt"
+)
+
+type signatureError struct {
+	innerError error
+}
+
+func (e *signatureError) Unwrap() error {
+	return e.innerError
+}
+
+func (e *signatureError) Error() string {
+	return fmt.Sprintf("signature validation failed: %v", e.innerError)
+}
+
+type pckError struct {
+	innerError error
+}
+
+func (e *pckError) Unwrap() error {
+	return e.innerError
+}
+
+func (e *pckError) Error() string {
+	return fmt.Sprintf("validating PCK certificate chain: %v", e.innerError)
+}
+
+type tcbInfoError struct {
+	innerError error
+}
+
+func (e *tcbInfoError) Unwrap() error {
+	return e.innerError
+}
+
+func (e *tcbInfoError) Error() string {
+	return fmt.Sprintf("validating TCB info: %v", e.innerError)
+}
+
+type qeIdentityError struct {
+	innerError error
+}
+
+func (e *qeIdentityError) Unwrap() error {
+	return e.innerError
+}
+
+func (e *qeIdentityError) Error() string {
+	return fmt.Sprintf("validating QE identity: %v", e.innerError)
+}
+
+type tcbStatusError struct {
+	component string
+	status    string
+}
+
+func (e *tcbStatusError) Error() string {
+	return fmt.Sprintf("unacceptable TCB status of the %s: %s", e.component, e.status)
+}
+
+type measurementError struct {
+	index uint32
+}
+
+func (e *measurementError) Error() string {
+	if e.index == MRTDIndex {
+		return "untrusted MRTD value"
+	}
+	return fmt.Sprintf("untrusted RTMR value at RTMR index %d", e.index-MRTDIndex-1)
+}
+
+var (
+	errDebugEnabled     = errors.New("TD attributes indicate debugging, expected no debugging")
+	errReportDataDigest = errors.New("report data does not match user data and nonce")
+	errInstanceID       = errors.New("instance ID is not bound to the TD by MRCONFIGID")
+)
@@ -0,0 +1,51 @@
+/*
+Copyright (c) Edgeless Systems GmbH
+
+SPDX-License-Identifier: AGPL-3.0-only
+*/
+
+package tdx
+
+import (
+	"crypto/sha512"
+	"fmt"
+	"os"
+)
+
+const (
+	// RTMRIndexClusterID is the index of RTMR[3] in a measurements map.
+	// RTMR[3] is not used by the firmware or the kernel, we extend it to mark the node as initialized.
+	// The value used to extend is a random generated 32 Byte value.
+	RTMRIndexClusterID = MRTDIndex + 1 + 3
+	// rtmrClusterIDPath is the interface of the TDX guest driver to read and extend RTMR[3].
+	rtmrClusterIDPath = "/sys/class/misc/tdx_guest/measurements/rtmr3:sha384"
+)
+
+// MarkNodeAsBootstrapped marks a node as initialized by extending RTMR[3] with the SHA384 digest of clusterID.
+func MarkNodeAsBootstrapped(clusterID []byte) error {
+	return markNodeAsBootstrapped(rtmrClusterIDPath, clusterID)
+}
+
+func markNodeAsBootstrapped(rtmrPath string, clusterID []byte) error {
+	digest := sha512.Sum384(clusterID)
+	rtmr, err := os.OpenFile(rtmrPath, os.O_WRONLY, 0)
+	if err != nil {
+		return fmt.Errorf("opening RTMR: %w", err)
+	}
+	defer rtmr.Close()
+	if _, err := rtmr.Write(digest[:]); err != nil {
+		return fmt.Errorf("extending RTMR: %w", err)
+	}
+	return nil
+}
+
+// ExtendMeasurement returns the value of an RTMR after extending it with the SHA384 digest of data.
+// An empty rtmr is treated as the initial, all zero value of the RTMR.
+func ExtendMeasurement(rtmr, data []byte) []byte {
+	if len(rtmr) == 0 {
+		rtmr = make([]byte, sha512.Size384)
+	}
+	digest := sha512.Sum384(data)
+	extended := sha512.Sum384(append(append([]byte{}, rtmr...), digest[:]...))
+	return extended[:]
+}
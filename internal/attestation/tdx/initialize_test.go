@@ -0,0 +1,48 @@
+/*
+Copyright (c) Edgeless Systems GmbH
+
+SPDX-License-Identifier: AGPL-3.0-only
+*/
+
+package tdx
+
+import (
+	"crypto/sha512"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestMarkNodeAsBootstrapped(t *testing.T) {
+	assert := assert.New(t)
+	require := require.New(t)
+
+	rtmrPath := filepath.Join(t.TempDir(), "rtmr3:sha384")
+	require.NoError(os.WriteFile(rtmrPath, nil, 0o600))
+
+	clusterID := []byte("cluster-id")
+	require.NoError(markNodeAsBootstrapped(rtmrPath, clusterID))
+
+	written, err := os.ReadFile(rtmrPath)
+	require.NoError(err)
+	digest := sha512.Sum384(clusterID)
+	assert.Equal(digest[:], written)
+
+	assert.Error(markNodeAsBootstrapped(filepath.Join(t.TempDir(), "missing"), clusterID))
+}
+
+func TestExtendMeasurement(t *testing.T) {
+	assert := assert.New(t)
+
+	data := []byte("cluster-id")
+	digest := sha512.Sum384(data)
+	zero := make([]byte, sha512.Size384)
+	want := sha512.Sum384(append(append([]byte{}, zero...), digest[:]...))
+
+	assert.Equal(want[:], ExtendMeasurement(nil, data))
+	assert.Equal(want[:], ExtendMeasurement(zero, data))
+	assert.NotEqual(want[:], ExtendMeasurement(want[:], data))
+}
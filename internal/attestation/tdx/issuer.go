@@ -0,0 +1,203 @@
+/*
+Copyright (c) Edgeless Systems GmbH
+
+SPDX-License-Identifier: AGPL-3.0-only
+*/
+
+package tdx
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/edgelesssys/constellation/v2/internal/cloud/metadata"
+	qemucloud "github.com/edgelesssys/constellation/v2/internal/cloud/qemu"
+	"github.com/edgelesssys/constellation/v2/internal/oid"
+)
+
+const (
+	// tdxGuestDevice is the device exposed by the Linux TDX guest driver.
+	tdxGuestDevice = "/dev/tdx_guest"
+	// tsmReportPath is the configfs-tsm interface used to request quotes from the quoting enclave.
+	tsmReportPath = "/sys/kernel/config/tsm/report"
+	// tsmProviderTDX is the configfs-tsm provider name of the TDX guest driver.
+	tsmProviderTDX = "tdx_guest"
+	// collateralTimeout bounds fetching the collateral from the Intel PCS.
+	collateralTimeout = 30 * time.Second
+)
+
+// AttestationDocument contains a TDX quote and the collateral needed to verify it.
+type AttestationDocument struct {
+	// Quote is a TD quote, signed by the quoting enclave.
+	// Its REPORTDATA binds UserData and the nonce of the requesting party.
+	Quote []byte
+	// Collateral is used to appraise the TCB level of the platform.
+	Collateral Collateral
+	// UserData is arbitrary data bound to the quote.
+	UserData []byte
+	// InstanceID is the provider ID of the TD, if the host set MRCONFIGID to its SHA-384 digest.
+	InstanceID string
+}
+
+// Available returns true if the system is running as an Intel TDX guest.
+func Available() bool {
+	_, err := os.Stat(tdxGuestDevice)
+	return err == nil
+}
+
+// GetSelectedMeasurements returns MRTD and RTMR[0-3] of the running TD.
+func GetSelectedMeasurements() (map[uint32][]byte, error) {
+	raw, err := (&tsmReport{path: tsmReportPath}).getQuote([64]byte{})
+	if err != nil {
+		return nil, err
+	}
+	quote, err := parseQuote(raw)
+	if err != nil {
+		return nil, err
+	}
+	return quote.body.measurements(), nil
+}
+
+// Issuer for Intel TDX attestation.
+type Issuer struct {
+	oid.QEMUTDX
+
+	quoter     quoteGetter
+	collateral collateralGetter
+	self       instanceSelfer
+}
+
+// NewIssuer initializes a new TDX Issuer.
+func NewIssuer() *Issuer {
+	return &Issuer{
+		quoter: &tsmReport{path: tsmReportPath},
+		collateral: &pcsClient{
+			client:        &http.Client{},
+			tcbInfoURL:    pcsTCBInfoURL,
+			qeIdentityURL: pcsQEIdentityURL,
+			pckCRLURL:     pcsPCKCRLURL,
+		},
+		self: &qemucloud.Metadata{},
+	}
+}
+
+// Issue generates a TDX quote with the hash of userData and nonce as REPORTDATA.
+// If the host bound an instance ID to the TD using MRCONFIGID, the provider ID of the TD is added to the attestation.
+func (i *Issuer) Issue(userData []byte, nonce []byte) ([]byte, error) {
+	rawQuote, err := i.quoter.getQuote(makeReportData(userData, nonce))
+	if err != nil {
+		return nil, fmt.Errorf("getting TD quote: %w", err)
+	}
+
+	quote, err := parseQuote(rawQuote)
+	if err != nil {
+		return nil, fmt.Errorf("parsing TD quote: %w", err)
+	}
+	pckChain, err := parsePEMChain(quote.pckCertChain)
+	if err != nil {
+		return nil, fmt.Errorf("parsing PCK certificate chain: %w", err)
+	}
+	pck, err := parsePCKExtensions(pckChain[0])
+	if err != nil {
+		return nil, err
+	}
+	pckCA, err := pckCAType(pckChain[0])
+	if err != nil {
+		return nil, err
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), collateralTimeout)
+	defer cancel()
+	collateral, err := i.collateral.getCollateral(ctx, pck.fmspc, pckCA)
+	if err != nil {
+		return nil, fmt.Errorf("fetching collateral: %w", err)
+	}
+
+	var instanceID string
+	if quote.body.MRConfigID != ([48]byte{}) {
+		self, err := i.self.Self(ctx)
+		if err != nil {
+			return nil, fmt.Errorf("retrieving provider ID: %w", err)
+		}
+		instanceID = self.ProviderID
+	}
+
+	attDoc := AttestationDocument{
+		Quote:      rawQuote,
+		Collateral: collateral,
+		UserData:   userData,
+		InstanceID: instanceID,
+	}
+	return json.Marshal(attDoc)
+}
+
+// makeReportData returns the REPORTDATA for a quote: SHA256(userData) || SHA256(nonce).
+func makeReportData(userData, nonce []byte) [64]byte {
+	var reportData [64]byte
+	userDataHash := sha256.Sum256(userData)
+	nonceHash := sha256.Sum256(nonce)
+	copy(reportData[:32], userDataHash[:])
+	copy(reportData[32:], nonceHash[:])
+	return reportData
+}
+
+// tsmReport requests quotes using the Linux configfs-tsm report interface.
+type tsmReport struct {
+	path string
+}
+
+func (t *tsmReport) getQuote(reportData [64]byte) ([]byte, error) {
+	entry, err := os.MkdirTemp(t.path, "constellation-")
+	if err != nil {
+		return nil, fmt.Errorf("creating report entry: %w", err)
+	}
+	defer os.Remove(entry)
+
+	provider, err := os.ReadFile(filepath.Join(entry, "provider"))
+	if err != nil {
+		return nil, fmt.Errorf("reading report provider: %w", err)
+	}
+	if p := strings.TrimSpace(string(provider)); p != tsmProviderTDX {
+		return nil, fmt.Errorf("unexpected report provider: %q", p)
+	}
+
+	if err := os.WriteFile(filepath.Join(entry, "inblob"), reportData[:], 0o600); err != nil {
+		return nil, fmt.Errorf("writing report data: %w", err)
+	}
+	quote, err := os.ReadFile(filepath.Join(entry, "outblob"))
+	if err != nil {
+		return nil, fmt.Errorf("reading quote: %w", err)
+	}
+
+	// the generation is incremented on every write to the entry,
+	// anything other than 1 means someone else wrote to our entry concurrently
+	generation, err := os.ReadFile(filepath.Join(entry, "generation"))
+	if err != nil {
+		return nil, fmt.Errorf("reading report generation: %w", err)
+	}
+	if strings.TrimSpace(string(generation)) != "1" {
+		return nil, errors.New("report entry was modified concurrently")
+	}
+
+	return quote, nil
+}
+
+type quoteGetter interface {
+	getQuote(reportData [64]byte) ([]byte, error)
+}
+
+type instanceSelfer interface {
+	Self(ctx context.Context) (metadata.InstanceMetadata, error)
+}
+
+type collateralGetter interface {
+	getCollateral(ctx context.Context, fmspc []byte, pckCA string) (Collateral, error)
+}
@@ -0,0 +1,141 @@
+/*
+Copyright (c) Edgeless Systems GmbH
+
+SPDX-License-Identifier: AGPL-3.0-only
+*/
+
+package tdx
+
+import (
+	"context"
+	"crypto/sha512"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"strings"
+	"testing"
+
+	"github.com/edgelesssys/constellation/v2/internal/cloud/metadata"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestIssue(t *testing.T) {
+	userData := []byte("user data")
+	nonce := []byte("nonce")
+
+	_, attDocRaw := newTestAttestation(t, newTestQuoteConfig(make([]byte, 48), make([]byte, 48)), userData, nonce)
+	var testDoc AttestationDocument
+	require.NoError(t, json.Unmarshal(attDocRaw, &testDoc))
+
+	boundCfg := newTestQuoteConfig(make([]byte, 48), make([]byte, 48))
+	boundCfg.mrConfigID = sha512.Sum384([]byte(testInstanceID))
+	_, attDocRaw = newTestAttestation(t, boundCfg, userData, nonce)
+	var boundDoc AttestationDocument
+	require.NoError(t, json.Unmarshal(attDocRaw, &boundDoc))
+
+	someErr := errors.New("failed")
+
+	testCases := map[string]struct {
+		quoter         *stubQuoteGetter
+		collateral     *stubCollateralGetter
+		self           *stubInstanceSelfer
+		wantInstanceID string
+		wantErr        bool
+	}{
+		"success": {
+			quoter:     &stubQuoteGetter{quote: testDoc.Quote},
+			collateral: &stubCollateralGetter{collateral: testDoc.Collateral},
+			self:       &stubInstanceSelfer{err: someErr},
+		},
+		"instance ID bound by MRCONFIGID": {
+			quoter:         &stubQuoteGetter{quote: boundDoc.Quote},
+			collateral:     &stubCollateralGetter{collateral: testDoc.Collateral},
+			self:           &stubInstanceSelfer{instance: metadata.InstanceMetadata{ProviderID: testInstanceID}},
+			wantInstanceID: testInstanceID,
+		},
+		"retrieving instance ID fails": {
+			quoter:     &stubQuoteGetter{quote: boundDoc.Quote},
+			collateral: &stubCollateralGetter{collateral: testDoc.Collateral},
+			self:       &stubInstanceSelfer{err: someErr},
+			wantErr:    true,
+		},
+		"getting quote fails": {
+			quoter:     &stubQuoteGetter{err: someErr},
+			collateral: &stubCollateralGetter{collateral: testDoc.Collateral},
+			wantErr:    true,
+		},
+		"invalid quote": {
+			quoter:     &stubQuoteGetter{quote: []byte("invalid")},
+			collateral: &stubCollateralGetter{collateral: testDoc.Collateral},
+			wantErr:    true,
+		},
+		"fetching collateral fails": {
+			quoter:     &stubQuoteGetter{quote: testDoc.Quote},
+			collateral: &stubCollateralGetter{err: someErr},
+			wantErr:    true,
+		},
+	}
+
+	for name, tc := range testCases {
+		t.Run(name, func(t *testing.T) {
+			assert := assert.New(t)
+			require := require.New(t)
+
+			self := tc.self
+			if self == nil {
+				self = &stubInstanceSelfer{}
+			}
+			issuer := &Issuer{quoter: tc.quoter, collateral: tc.collateral, self: self}
+
+			attDocRaw, err := issuer.Issue(userData, nonce)
+			if tc.wantErr {
+				assert.Error(err)
+				return
+			}
+			require.NoError(err)
+
+			var attDoc AttestationDocument
+			require.NoError(json.Unmarshal(attDocRaw, &attDoc))
+			assert.Equal(userData, attDoc.UserData)
+			assert.Equal(testDoc.Collateral, attDoc.Collateral)
+			assert.Equal(makeReportData(userData, nonce), tc.quoter.reportData)
+			assert.Equal(testFMSPC, tc.collateral.fmspc)
+			assert.Equal(pckPlatformCA, tc.collateral.pckCA)
+			assert.Equal(tc.wantInstanceID, attDoc.InstanceID)
+		})
+	}
+}
+
+type stubQuoteGetter struct {
+	quote      []byte
+	err        error
+	reportData [64]byte
+}
+
+func (s *stubQuoteGetter) getQuote(reportData [64]byte) ([]byte, error) {
+	s.reportData = reportData
+	return s.quote, s.err
+}
+
+type stubInstanceSelfer struct {
+	instance metadata.InstanceMetadata
+	err      error
+}
+
+func (s *stubInstanceSelfer) Self(context.Context) (metadata.InstanceMetadata, error) {
+	return s.instance, s.err
+}
+
+type stubCollateralGetter struct {
+	collateral Collateral
+	err        error
+	fmspc      string
+	pckCA      string
+}
+
+func (s *stubCollateralGetter) getCollateral(_ context.Context, fmspc []byte, pckCA string) (Collateral, error) {
+	s.fmspc = strings.ToUpper(hex.EncodeToString(fmspc))
+	s.pckCA = pckCA
+	return s.collateral, s.err
+}
@@ -0,0 +1,238 @@
+/*
+Copyright (c) Edgeless Systems GmbH
+
+SPDX-License-Identifier: AGPL-3.0-only
+*/
+
+package tdx
+
+import (
+	"bytes"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"math/big"
+)
+
+const (
+	quoteVersion                = 4
+	attestationKeyTypeECDSAP256 = 2
+	teeTypeTDX                  = 0x81
+
+	lenQuoteHeader  = 0x30
+	lenTDQuoteBody  = 0x248
+	lenQEReport     = 0x180
+	lenECDSASig     = 0x40
+	lenECDSAPubKey  = 0x40
+	lenCertDataHead = 0x6
+
+	certDataTypePCKCertChain = 5
+	certDataTypeQEReport     = 6
+
+	// tdAttributesDebug is set in TDATTRIBUTES if the TD is running in debug mode.
+	tdAttributesDebug = 0x1
+)
+
+// MRTDIndex is the index of MRTD in a measurements map.
+// The runtime measurement registers RTMR[0-3] are placed at the indices 1 to 4.
+const MRTDIndex = 0
+
+// Reference: Intel TDX DCAP Quoting Library API, Appendix A.3 (Version 4 Quote Format).
+type quoteHeader struct {
+	Version            uint16   // 0x000
+	AttestationKeyType uint16   // 0x002
+	TEEType            uint32   // 0x004
+	_                  [4]byte  // 0x008
+	QEVendorID         [16]byte // 0x00C
+	UserData           [20]byte // 0x01C
+}
+
+// Reference: Intel TDX DCAP Quoting Library API, Appendix A.3.2 (TD Quote Body).
+type tdQuoteBody struct {
+	TEETCBSVN      [16]byte    // 0x000
+	MRSEAM         [48]byte    // 0x010
+	MRSignerSEAM   [48]byte    // 0x040
+	SEAMAttributes [8]byte     // 0x070
+	TDAttributes   [8]byte     // 0x078
+	XFAM           [8]byte     // 0x080
+	MRTD           [48]byte    // 0x088
+	MRConfigID     [48]byte    // 0x0B8
+	MROwner        [48]byte    // 0x0E8
+	MROwnerConfig  [48]byte    // 0x118
+	RTMR           [4][48]byte // 0x148
+	ReportData     [64]byte    // 0x208
+}
+
+// measurements returns MRTD and RTMR[0-3] as a measurements map.
+func (b *tdQuoteBody) measurements() map[uint32][]byte {
+	m := map[uint32][]byte{MRTDIndex: b.MRTD[:]}
+	for i := range b.RTMR {
+		m[uint32(MRTDIndex+1+i)] = b.RTMR[i][:]
+	}
+	return m
+}
+
+func (b *tdQuoteBody) debug() bool {
+	return b.TDAttributes[0]&tdAttributesDebug != 0
+}
+
+// Reference: Intel SGX DCAP Quoting Library API, Appendix A.4 (Enclave Report Body).
+type qeReport struct {
+	CPUSVN     [16]byte // 0x000
+	MiscSelect uint32   // 0x010
+	_          [28]byte // 0x014
+	Attributes [16]byte // 0x030
+	MREnclave  [32]byte // 0x040
+	_          [32]byte // 0x060
+	MRSigner   [32]byte // 0x080
+	_          [96]byte // 0x0A0
+	ISVProdID  uint16   // 0x100
+	ISVSVN     uint16   // 0x102
+	_          [60]byte // 0x104
+	ReportData [64]byte // 0x140
+}
+
+// quote is a parsed TDX quote in version 4 format.
+type quote struct {
+	header            quoteHeader
+	body              tdQuoteBody
+	signature         []byte
+	attestationKey    []byte
+	qeReportRaw       []byte
+	qeReport          qeReport
+	qeReportSignature []byte
+	qeAuthData        []byte
+	pckCertChain      []byte
+	// signedData is the part of the quote covered by signature, i.e. header and TD quote body.
+	signedData []byte
+}
+
+// parseQuote parses a version 4 TDX quote with ECDSA-256-with-P-256 attestation key
+// and a PCK certificate chain as QE certification data.
+func parseQuote(raw []byte) (*quote, error) {
+	if len(raw) < lenQuoteHeader+lenTDQuoteBody+4 {
+		return nil, fmt.Errorf("quote is shorter than expected: %d bytes", len(raw))
+	}
+
+	var q quote
+	if err := binary.Read(bytes.NewReader(raw[:lenQuoteHeader]), binary.LittleEndian, &q.header); err != nil {
+		return nil, fmt.Errorf("reading quote header: %w", err)
+	}
+	if q.header.Version != quoteVersion {
+		return nil, fmt.Errorf("unsupported quote version: %d", q.header.Version)
+	}
+	if q.header.AttestationKeyType != attestationKeyTypeECDSAP256 {
+		return nil, fmt.Errorf("unsupported attestation key type: %d", q.header.AttestationKeyType)
+	}
+	if q.header.TEEType != teeTypeTDX {
+		return nil, fmt.Errorf("unexpected TEE type: %#x", q.header.TEEType)
+	}
+	if err := binary.Read(bytes.NewReader(raw[lenQuoteHeader:lenQuoteHeader+lenTDQuoteBody]), binary.LittleEndian, &q.body); err != nil {
+		return nil, fmt.Errorf("reading TD quote body: %w", err)
+	}
+	q.signedData = raw[:lenQuoteHeader+lenTDQuoteBody]
+
+	sigData := raw[lenQuoteHeader+lenTDQuoteBody:]
+	sigDataLen := binary.LittleEndian.Uint32(sigData)
+	sigData = sigData[4:]
+	if uint64(len(sigData)) < uint64(sigDataLen) {
+		return nil, errors.New("quote signature data is truncated")
+	}
+	sigData = sigData[:sigDataLen]
+
+	r := &reader{buf: sigData}
+	q.signature = r.next(lenECDSASig)
+	q.attestationKey = r.next(lenECDSAPubKey)
+
+	certDataType, certData := r.certData()
+	if r.err != nil {
+		return nil, fmt.Errorf("reading quote signature data: %w", r.err)
+	}
+	if certDataType != certDataTypeQEReport {
+		return nil, fmt.Errorf("unsupported QE certification data type: %d", certDataType)
+	}
+
+	r = &reader{buf: certData}
+	q.qeReportRaw = r.next(lenQEReport)
+	q.qeReportSignature = r.next(lenECDSASig)
+	q.qeAuthData = r.next(int(r.uint16()))
+	pckDataType, pckData := r.certData()
+	if r.err != nil {
+		return nil, fmt.Errorf("reading QE certification data: %w", r.err)
+	}
+	if pckDataType != certDataTypePCKCertChain {
+		return nil, fmt.Errorf("unsupported PCK certification data type: %d", pckDataType)
+	}
+	q.pckCertChain = pckData
+
+	if err := binary.Read(bytes.NewReader(q.qeReportRaw), binary.LittleEndian, &q.qeReport); err != nil {
+		return nil, fmt.Errorf("reading QE report: %w", err)
+	}
+
+	return &q, nil
+}
+
+// reader consumes length-delimited fields from the quote signature data.
+// After the first error, all subsequent reads return zero values.
+type reader struct {
+	buf []byte
+	err error
+}
+
+func (r *reader) next(n int) []byte {
+	if r.err != nil {
+		return nil
+	}
+	if len(r.buf) < n {
+		r.err = fmt.Errorf("expected %d more bytes, got %d", n, len(r.buf))
+		return nil
+	}
+	out := r.buf[:n]
+	r.buf = r.buf[n:]
+	return out
+}
+
+func (r *reader) uint16() uint16 {
+	b := r.next(2)
+	if b == nil {
+		return 0
+	}
+	return binary.LittleEndian.Uint16(b)
+}
+
+// certData reads a certification data structure: a 2 byte type, a 4 byte size and the data itself.
+func (r *reader) certData() (uint16, []byte) {
+	head := r.next(lenCertDataHead)
+	if head == nil {
+		return 0, nil
+	}
+	return binary.LittleEndian.Uint16(head[:2]), r.next(int(binary.LittleEndian.Uint32(head[2:])))
+}
+
+// rawECDSAPublicKey converts a raw P-256 public key (X || Y) to an ecdsa.PublicKey.
+func rawECDSAPublicKey(raw []byte) (*ecdsa.PublicKey, error) {
+	if len(raw) != lenECDSAPubKey {
+		return nil, fmt.Errorf("invalid public key length: %d", len(raw))
+	}
+	pub := &ecdsa.PublicKey{
+		Curve: elliptic.P256(),
+		X:     new(big.Int).SetBytes(raw[:lenECDSAPubKey/2]),
+		Y:     new(big.Int).SetBytes(raw[lenECDSAPubKey/2:]),
+	}
+	if !pub.Curve.IsOnCurve(pub.X, pub.Y) {
+		return nil, errors.New("public key is not on curve P-256")
+	}
+	return pub, nil
+}
+
+// verifyRawECDSA verifies a raw (R || S) ECDSA signature over digest.
+func verifyRawECDSA(pub *ecdsa.PublicKey, digest, sig []byte) bool {
+	if len(sig) != lenECDSASig {
+		return false
+	}
+	r := new(big.Int).SetBytes(sig[:lenECDSASig/2])
+	s := new(big.Int).SetBytes(sig[lenECDSASig/2:])
+	return ecdsa.Verify(pub, digest, r, s)
+}
@@ -0,0 +1,277 @@
+/*
+Copyright (c) Edgeless Systems GmbH
+
+SPDX-License-Identifier: AGPL-3.0-only
+*/
+
+package tdx
+
+import (
+	"bytes"
+	"crypto/ecdsa"
+	"crypto/sha256"
+	"crypto/sha512"
+	"crypto/x509"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/edgelesssys/constellation/v2/internal/attestation/policy"
+	"github.com/edgelesssys/constellation/v2/internal/attestation/vtpm"
+	internalCrypto "github.com/edgelesssys/constellation/v2/internal/crypto"
+	"github.com/edgelesssys/constellation/v2/internal/oid"
+)
+
+// Intel SGX root CA. Received from the Intel Provisioning Certification Service (PCS).
+// It is the root of trust for both PCK certificates and TCB info signing certificates.
+const intelRootCAPEM = "-----BEGIN CERTIFICATE-----\nMIICjzCCAjSgAwIBAgIUImUM1lqdNInzg7SVUr9QGzknBqwwCgYIKoZIzj0EAwIw\naDEaMBgGA1UEAwwRSW50ZWwgU0dYIFJvb3QgQ0ExGjAYBgNVBAoMEUludGVsIENv\ncnBvcmF0aW9uMRQwEgYDVQQHDAtTYW50YSBDbGFyYTELMAkGA1UECAwCQ0ExCzAJ\nBgNVBAYTAlVTMB4XDTE4MDUyMTEwNDUxMFoXDTQ5MTIzMTIzNTk1OVowaDEaMBgG\nA1UEAwwRSW50ZWwgU0dYIFJvb3QgQ0ExGjAYBgNVBAoMEUludGVsIENvcnBvcmF0\naW9uMRQwEgYDVQQHDAtTYW50YSBDbGFyYTELMAkGA1UECAwCQ0ExCzAJBgNVBAYT\nAlVTMFkwEwYHKoZIzj0CAQYIKoZIzj0DAQcDQgAEC6nEwMDIYZOj/iPWsCzaEKi7\n1OiOSLRFhWGjbnBVJfVnkY4u3IjkDYYL0MxO4mqsyYjlBalTVYxFP2sJBK5zlKOB\nuzCBuDAfBgNVHSMEGDAWgBQiZQzWWp00ifODtJVSv1AbOScGrDBSBgNVHR8ESzBJ\nMEegRaBDhkFodHRwczovL2NlcnRpZmljYXRlcy50cnVzdGVkc2VydmljZXMuaW50\nZWwuY29tL0ludGVsU0dYUm9vdENBLmRlcjAdBgNVHQ4EFgQUImUM1lqdNInzg7SV\nUr9QGzknBqwwDgYDVR0PAQH/BAQDAgEGMBIGA1UdEwEB/wQIMAYBAf8CAQEwCgYI\nKoZIzj0EAwIDSQAwRgIhAOW/5QkR+S9CiSDcNoowLuPRLsWGf/Yi7GSX94BgwTwg\nAiEA4J0lrHoMs+Xo5o/sX6O9QWxHRAvZUGOdRQ7cvqRXaqI=\n-----END CERTIFICATE-----\n"
+
+// Validator for Intel TDX attestation.
+type Validator struct {
+	oid.QEMUTDX
+
+	expectedMeasurements map[uint32][]byte
+	enforcedMeasurements map[uint32]struct{}
+	rootCA               *x509.Certificate
+	now                  func() time.Time
+	policy               *policy.Policy
+
+	log vtpm.WarnLogger
+}
+
+// NewValidator initializes a new TDX validator with the provided measurements.
+// Index 0 of measurements is the expected MRTD, indices 1 to 4 are the expected values of RTMR[0-3].
+// Mismatching values at indices not listed in enforced only result in a warning.
+// If attestationPolicy is not nil, it is evaluated against the claims of every validated quote.
+func NewValidator(measurements map[uint32][]byte, enforced []uint32, attestationPolicy *policy.Policy, log vtpm.WarnLogger) *Validator {
+	enforcedMap := make(map[uint32]struct{})
+	for _, idx := range enforced {
+		enforcedMap[idx] = struct{}{}
+	}
+
+	// the root CA is a compile time constant, failing to parse it is a programming error
+	rootCA, err := internalCrypto.PemToX509Cert([]byte(intelRootCAPEM))
+	if err != nil {
+		panic(fmt.Sprintf("parsing Intel root CA: %s", err))
+	}
+
+	return &Validator{
+		expectedMeasurements: measurements,
+		enforcedMeasurements: enforcedMap,
+		rootCA:               rootCA,
+		now:                  time.Now,
+		policy:               attestationPolicy,
+		log:                  log,
+	}
+}
+
+// Validate a TDX based attestation.
+func (v *Validator) Validate(attDocRaw []byte, nonce []byte) ([]byte, error) {
+	userData, _, err := v.ValidateWithClaims(attDocRaw, nonce)
+	return userData, err
+}
+
+// ValidateWithClaims validates a TDX based attestation.
+// In addition to the signed user data, it returns the claims established by the attestation.
+func (v *Validator) ValidateWithClaims(attDocRaw []byte, nonce []byte) ([]byte, policy.Claims, error) {
+	var attDoc AttestationDocument
+	if err := json.Unmarshal(attDocRaw, &attDoc); err != nil {
+		return nil, policy.Claims{}, fmt.Errorf("unmarshaling TDX attestation document: %w", err)
+	}
+
+	quote, err := parseQuote(attDoc.Quote)
+	if err != nil {
+		return nil, policy.Claims{}, fmt.Errorf("parsing TD quote: %w", err)
+	}
+	now := v.now()
+
+	// Verify the PCK certificate chain and the quote signatures
+	pckCert, err := v.validatePCK(quote.pckCertChain, attDoc.Collateral, now)
+	if err != nil {
+		return nil, policy.Claims{}, err
+	}
+	if err := validateQuoteSignature(quote, pckCert); err != nil {
+		return nil, policy.Claims{}, err
+	}
+
+	// Verify the quote was signed by a genuine and up to date quoting enclave
+	if err := v.validateQE(quote, attDoc.Collateral, now); err != nil {
+		return nil, policy.Claims{}, err
+	}
+
+	// Appraise the platform's TCB level
+	tdxClaims, err := v.validateTCB(quote, pckCert, attDoc.Collateral, now)
+	if err != nil {
+		return nil, policy.Claims{}, err
+	}
+
+	if quote.body.debug() {
+		return nil, policy.Claims{}, errDebugEnabled
+	}
+
+	// Verify measurements
+	actual := quote.body.measurements()
+	for idx, expected := range v.expectedMeasurements {
+		if !bytes.Equal(expected, actual[idx]) {
+			if _, ok := v.enforcedMeasurements[idx]; ok {
+				return nil, policy.Claims{}, &measurementError{idx}
+			}
+			if v.log != nil {
+				v.log.Warnf("Encountered untrusted measurement value at index %d", idx)
+			}
+		}
+	}
+
+	// Verify user data and nonce are bound to the quote
+	if reportData := makeReportData(attDoc.UserData, nonce); !bytes.Equal(reportData[:], quote.body.ReportData[:]) {
+		return nil, policy.Claims{}, errReportDataDigest
+	}
+
+	instanceID, err := verifyInstanceID(attDoc.InstanceID, quote.body.MRConfigID)
+	if err != nil {
+		return nil, policy.Claims{}, err
+	}
+
+	claims := policy.Claims{Measurements: actual, InstanceID: instanceID, TDX: tdxClaims}
+	if v.policy != nil {
+		if err := v.policy.Evaluate(claims); err != nil {
+			return nil, policy.Claims{}, err
+		}
+	}
+
+	return attDoc.UserData, claims, nil
+}
+
+// validatePCK verifies the PCK certificate chain embedded in the quote against the Intel root CA
+// and checks that the PCK certificate is not revoked.
+func (v *Validator) validatePCK(chainRaw []byte, collateral Collateral, now time.Time) (*x509.Certificate, error) {
+	chain, err := parsePEMChain(chainRaw)
+	if err != nil {
+		return nil, &pckError{err}
+	}
+	pckCert, err := verifyChain(chain, v.rootCA, now)
+	if err != nil {
+		return nil, &pckError{err}
+	}
+	if err := verifyPCKRevocation(pckCert, collateral, v.rootCA, now); err != nil {
+		return nil, &pckError{err}
+	}
+	return pckCert, nil
+}
+
+// validateQE checks the identity and TCB level of the quoting enclave against the signed QE identity.
+func (v *Validator) validateQE(quote *quote, collateral Collateral, now time.Time) error {
+	identity, err := verifyQEIdentity(collateral, v.rootCA, now)
+	if err != nil {
+		return err
+	}
+	status, err := identity.matches(quote.qeReport)
+	if err != nil {
+		return &qeIdentityError{err}
+	}
+	return v.checkTCBStatus("quoting enclave", status)
+}
+
+// validateQuoteSignature verifies the signature chain of the quote:
+// the PCK signs the QE report, the QE report binds the attestation key, the attestation key signs the quote.
+func validateQuoteSignature(quote *quote, pckCert *x509.Certificate) error {
+	pckKey, ok := pckCert.PublicKey.(*ecdsa.PublicKey)
+	if !ok {
+		return &pckError{errors.New("PCK is not an ECDSA key")}
+	}
+	qeReportDigest := sha256.Sum256(quote.qeReportRaw)
+	if !verifyRawECDSA(pckKey, qeReportDigest[:], quote.qeReportSignature) {
+		return &signatureError{errors.New("invalid QE report signature")}
+	}
+
+	// QE report data is SHA256(attestation key || QE authentication data) padded with zeros
+	expectedReportData := make([]byte, 64)
+	keyHash := sha256.Sum256(append(append([]byte{}, quote.attestationKey...), quote.qeAuthData...))
+	copy(expectedReportData, keyHash[:])
+	if !bytes.Equal(expectedReportData, quote.qeReport.ReportData[:]) {
+		return &signatureError{errors.New("attestation key is not bound to QE report")}
+	}
+
+	attestationKey, err := rawECDSAPublicKey(quote.attestationKey)
+	if err != nil {
+		return &signatureError{fmt.Errorf("parsing attestation key: %w", err)}
+	}
+	quoteDigest := sha256.Sum256(quote.signedData)
+	if !verifyRawECDSA(attestationKey, quoteDigest[:], quote.signature) {
+		return &signatureError{errors.New("invalid quote signature")}
+	}
+
+	return nil
+}
+
+// verifyInstanceID returns the instance ID of the TD if it is bound to the quote.
+// The host binds the instance ID to the TD by setting MRCONFIGID to its SHA-384 digest when launching the TD.
+// An empty string is returned if MRCONFIGID is not set, in which case the attestation doesn't identify the TD.
+func verifyInstanceID(instanceID string, mrConfigID [48]byte) (string, error) {
+	if mrConfigID == ([48]byte{}) {
+		if instanceID != "" {
+			return "", errInstanceID
+		}
+		return "", nil
+	}
+	if sha512.Sum384([]byte(instanceID)) != mrConfigID {
+		return "", errInstanceID
+	}
+	return instanceID, nil
+}
+
+// validateTCB checks the TCB level of the platform against the signed TCB info.
+// It returns the verified TCB properties of the platform as policy claims.
+func (v *Validator) validateTCB(quote *quote, pckCert *x509.Certificate, collateral Collateral, now time.Time) (*policy.TDXReport, error) {
+	info, err := verifyTCBInfo(collateral, v.rootCA, now)
+	if err != nil {
+		return nil, err
+	}
+
+	pck, err := parsePCKExtensions(pckCert)
+	if err != nil {
+		return nil, &pckError{err}
+	}
+	if fmspc := hex.EncodeToString(pck.fmspc); !strings.EqualFold(fmspc, info.FMSPC) {
+		return nil, &tcbInfoError{fmt.Errorf("TCB info FMSPC %s does not match PCK FMSPC %s", info.FMSPC, fmspc)}
+	}
+
+	status, err := info.tcbStatus(pck, quote.body.TEETCBSVN)
+	if err != nil {
+		return nil, &tcbInfoError{err}
+	}
+	if err := v.checkTCBStatus("platform", status); err != nil {
+		return nil, err
+	}
+	moduleStatus, err := info.moduleStatus(quote.body)
+	if err != nil {
+		return nil, &tcbInfoError{err}
+	}
+	if err := v.checkTCBStatus("TDX module", moduleStatus); err != nil {
+		return nil, err
+	}
+
+	return &policy.TDXReport{
+		TCBStatus:    status,
+		TEETCBSVN:    quote.body.TEETCBSVN[:],
+		TDAttributes: quote.body.TDAttributes[:],
+		FMSPC:        pck.fmspc,
+	}, nil
+}
+
+// checkTCBStatus accepts up to date TCB levels and only warns about levels needing software hardening.
+func (v *Validator) checkTCBStatus(component, status string) error {
+	switch status {
+	case tcbStatusUpToDate:
+	case tcbStatusSWHardeningNeeded:
+		if v.log != nil {
+			v.log.Warnf("TCB status of the %s is %s", component, status)
+		}
+	default:
+		return &tcbStatusError{component: component, status: status}
+	}
+	return nil
+}
@@ -0,0 +1,717 @@
+/*
+Copyright (c) Edgeless Systems GmbH
+
+SPDX-License-Identifier: AGPL-3.0-only
+*/
+
+package tdx
+
+import (
+	"bytes"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/sha256"
+	"crypto/sha512"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/asn1"
+	"encoding/binary"
+	"encoding/hex"
+	"encoding/json"
+	"encoding/pem"
+	"fmt"
+	"math/big"
+	"testing"
+	"time"
+
+	"github.com/edgelesssys/constellation/v2/internal/attestation/policy"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestValidate(t *testing.T) {
+	userData := []byte("user data")
+	nonce := []byte("nonce")
+	mrtd := bytes.Repeat([]byte{0x11}, 48)
+	rtmr0 := bytes.Repeat([]byte{0x22}, 48)
+
+	testCases := map[string]struct {
+		modify         func(*testQuoteConfig)
+		measurements   map[uint32][]byte
+		enforced       []uint32
+		policy         string
+		nonce          []byte
+		wantInstanceID string
+		wantErr        bool
+		assertErr      func(error)
+	}{
+		"success": {
+			measurements: map[uint32][]byte{MRTDIndex: mrtd, 1: rtmr0},
+			enforced:     []uint32{MRTDIndex, 1},
+			nonce:        nonce,
+		},
+		"instance ID bound by MRCONFIGID": {
+			modify: func(c *testQuoteConfig) {
+				c.mrConfigID = sha512.Sum384([]byte(testInstanceID))
+				c.instanceID = testInstanceID
+			},
+			measurements:   map[uint32][]byte{MRTDIndex: mrtd},
+			nonce:          nonce,
+			wantInstanceID: testInstanceID,
+		},
+		"instance ID not matching MRCONFIGID": {
+			modify: func(c *testQuoteConfig) {
+				c.mrConfigID = sha512.Sum384([]byte("qemu:///hostname/worker-1"))
+				c.instanceID = testInstanceID
+			},
+			measurements: map[uint32][]byte{MRTDIndex: mrtd},
+			nonce:        nonce,
+			wantErr:      true,
+			assertErr: func(err error) {
+				assert.ErrorIs(t, err, errInstanceID)
+			},
+		},
+		"instance ID without MRCONFIGID": {
+			modify:       func(c *testQuoteConfig) { c.instanceID = testInstanceID },
+			measurements: map[uint32][]byte{MRTDIndex: mrtd},
+			nonce:        nonce,
+			wantErr:      true,
+			assertErr: func(err error) {
+				assert.ErrorIs(t, err, errInstanceID)
+			},
+		},
+		"non-enforced measurement mismatch": {
+			measurements: map[uint32][]byte{MRTDIndex: mrtd, 2: bytes.Repeat([]byte{0xFF}, 48)},
+			enforced:     []uint32{MRTDIndex},
+			nonce:        nonce,
+		},
+		"policy satisfied": {
+			measurements: map[uint32][]byte{MRTDIndex: mrtd},
+			enforced:     []uint32{MRTDIndex},
+			policy:       `claims.tdx.tcbStatus == "UpToDate" && claims.tdx.fmspc == "00806f050000" && claims.measurements[0] == "111111111111111111111111111111111111111111111111111111111111111111111111111111111111111111111111"`,
+			nonce:        nonce,
+		},
+		"policy not satisfied": {
+			measurements: map[uint32][]byte{MRTDIndex: mrtd},
+			enforced:     []uint32{MRTDIndex},
+			policy:       `claims.tdx.tcbStatus != "UpToDate"`,
+			nonce:        nonce,
+			wantErr:      true,
+			assertErr: func(err error) {
+				assert.ErrorIs(t, err, policy.ErrNotSatisfied)
+			},
+		},
+		"enforced MRTD mismatch": {
+			measurements: map[uint32][]byte{MRTDIndex: bytes.Repeat([]byte{0xFF}, 48)},
+			enforced:     []uint32{MRTDIndex},
+			nonce:        nonce,
+			wantErr:      true,
+			assertErr: func(err error) {
+				target := &measurementError{}
+				assert.ErrorAs(t, err, &target)
+			},
+		},
+		"wrong nonce": {
+			measurements: map[uint32][]byte{MRTDIndex: mrtd},
+			nonce:        []byte("other nonce"),
+			wantErr:      true,
+			assertErr: func(err error) {
+				assert.ErrorIs(t, err, errReportDataDigest)
+			},
+		},
+		"debug enabled": {
+			modify:       func(c *testQuoteConfig) { c.tdAttributes[0] = tdAttributesDebug },
+			measurements: map[uint32][]byte{MRTDIndex: mrtd},
+			nonce:        nonce,
+			wantErr:      true,
+			assertErr: func(err error) {
+				assert.ErrorIs(t, err, errDebugEnabled)
+			},
+		},
+		"invalid quote signature": {
+			modify:       func(c *testQuoteConfig) { c.corruptQuoteSignature = true },
+			measurements: map[uint32][]byte{MRTDIndex: mrtd},
+			nonce:        nonce,
+			wantErr:      true,
+			assertErr: func(err error) {
+				target := &signatureError{}
+				assert.ErrorAs(t, err, &target)
+			},
+		},
+		"invalid QE report signature": {
+			modify:       func(c *testQuoteConfig) { c.corruptQEReportSignature = true },
+			measurements: map[uint32][]byte{MRTDIndex: mrtd},
+			nonce:        nonce,
+			wantErr:      true,
+			assertErr: func(err error) {
+				target := &signatureError{}
+				assert.ErrorAs(t, err, &target)
+			},
+		},
+		"untrusted PCK chain": {
+			modify:       func(c *testQuoteConfig) { c.untrustedPCK = true },
+			measurements: map[uint32][]byte{MRTDIndex: mrtd},
+			nonce:        nonce,
+			wantErr:      true,
+			assertErr: func(err error) {
+				target := &pckError{}
+				assert.ErrorAs(t, err, &target)
+			},
+		},
+		"invalid TCB info signature": {
+			modify:       func(c *testQuoteConfig) { c.corruptTCBInfoSignature = true },
+			measurements: map[uint32][]byte{MRTDIndex: mrtd},
+			nonce:        nonce,
+			wantErr:      true,
+			assertErr: func(err error) {
+				target := &tcbInfoError{}
+				assert.ErrorAs(t, err, &target)
+			},
+		},
+		"out of date TCB": {
+			modify:       func(c *testQuoteConfig) { c.teeTCBSVN[0] = 1 },
+			measurements: map[uint32][]byte{MRTDIndex: mrtd},
+			nonce:        nonce,
+			wantErr:      true,
+			assertErr: func(err error) {
+				target := &tcbStatusError{}
+				assert.ErrorAs(t, err, &target)
+			},
+		},
+		"revoked PCK": {
+			modify:       func(c *testQuoteConfig) { c.revokedPCK = true },
+			measurements: map[uint32][]byte{MRTDIndex: mrtd},
+			nonce:        nonce,
+			wantErr:      true,
+			assertErr: func(err error) {
+				target := &pckError{}
+				assert.ErrorAs(t, err, &target)
+			},
+		},
+		"expired PCK CRL": {
+			modify:       func(c *testQuoteConfig) { c.crlNextUpdate = time.Now().Add(-time.Minute) },
+			measurements: map[uint32][]byte{MRTDIndex: mrtd},
+			nonce:        nonce,
+			wantErr:      true,
+			assertErr: func(err error) {
+				target := &pckError{}
+				assert.ErrorAs(t, err, &target)
+			},
+		},
+		"untrusted QE signer": {
+			modify:       func(c *testQuoteConfig) { c.qeMRSigner[0] ^= 0xFF },
+			measurements: map[uint32][]byte{MRTDIndex: mrtd},
+			nonce:        nonce,
+			wantErr:      true,
+			assertErr: func(err error) {
+				target := &qeIdentityError{}
+				assert.ErrorAs(t, err, &target)
+			},
+		},
+		"invalid QE identity signature": {
+			modify:       func(c *testQuoteConfig) { c.corruptQEIdentitySignature = true },
+			measurements: map[uint32][]byte{MRTDIndex: mrtd},
+			nonce:        nonce,
+			wantErr:      true,
+			assertErr: func(err error) {
+				target := &qeIdentityError{}
+				assert.ErrorAs(t, err, &target)
+			},
+		},
+		"out of date QE": {
+			modify:       func(c *testQuoteConfig) { c.qeISVSVN = 1 },
+			measurements: map[uint32][]byte{MRTDIndex: mrtd},
+			nonce:        nonce,
+			wantErr:      true,
+			assertErr: func(err error) {
+				target := &tcbStatusError{}
+				assert.ErrorAs(t, err, &target)
+			},
+		},
+		"untrusted TDX module signer": {
+			modify:       func(c *testQuoteConfig) { c.seamMRSigner[0] ^= 0xFF },
+			measurements: map[uint32][]byte{MRTDIndex: mrtd},
+			nonce:        nonce,
+			wantErr:      true,
+			assertErr: func(err error) {
+				target := &tcbInfoError{}
+				assert.ErrorAs(t, err, &target)
+			},
+		},
+		"TDX module identity": {
+			modify: func(c *testQuoteConfig) {
+				c.teeTCBSVN[0] = 2
+				c.teeTCBSVN[1] = 1
+			},
+			measurements: map[uint32][]byte{MRTDIndex: mrtd},
+			nonce:        nonce,
+		},
+		"out of date TDX module": {
+			modify: func(c *testQuoteConfig) {
+				c.teeTCBSVN[0] = 1
+				c.teeTCBSVN[1] = 1
+			},
+			measurements: map[uint32][]byte{MRTDIndex: mrtd},
+			nonce:        nonce,
+			wantErr:      true,
+			assertErr: func(err error) {
+				target := &tcbStatusError{}
+				assert.ErrorAs(t, err, &target)
+			},
+		},
+		"unknown TDX module version": {
+			modify:       func(c *testQuoteConfig) { c.teeTCBSVN[1] = 2 },
+			measurements: map[uint32][]byte{MRTDIndex: mrtd},
+			nonce:        nonce,
+			wantErr:      true,
+			assertErr: func(err error) {
+				target := &tcbInfoError{}
+				assert.ErrorAs(t, err, &target)
+			},
+		},
+		"FMSPC mismatch": {
+			modify:       func(c *testQuoteConfig) { c.tcbInfoFMSPC = "00906ED50000" },
+			measurements: map[uint32][]byte{MRTDIndex: mrtd},
+			nonce:        nonce,
+			wantErr:      true,
+			assertErr: func(err error) {
+				target := &tcbInfoError{}
+				assert.ErrorAs(t, err, &target)
+			},
+		},
+	}
+
+	for name, tc := range testCases {
+		t.Run(name, func(t *testing.T) {
+			assert := assert.New(t)
+			require := require.New(t)
+
+			cfg := newTestQuoteConfig(mrtd, rtmr0)
+			if tc.modify != nil {
+				tc.modify(&cfg)
+			}
+			root, attDoc := newTestAttestation(t, cfg, userData, nonce)
+
+			var attestationPolicy *policy.Policy
+			if tc.policy != "" {
+				var err error
+				attestationPolicy, err = policy.New(tc.policy)
+				require.NoError(err)
+			}
+
+			validator := NewValidator(tc.measurements, tc.enforced, attestationPolicy, nil)
+			validator.rootCA = root
+
+			out, claims, err := validator.ValidateWithClaims(attDoc, tc.nonce)
+			if tc.wantErr {
+				assert.Error(err)
+				if tc.assertErr != nil {
+					tc.assertErr(err)
+				}
+				return
+			}
+			require.NoError(err)
+			assert.Equal(userData, out)
+			assert.Equal(mrtd, claims.Measurements[MRTDIndex])
+			require.NotNil(claims.TDX)
+			assert.Equal("00806f050000", hex.EncodeToString(claims.TDX.FMSPC))
+			assert.Equal(tc.wantInstanceID, claims.InstanceID)
+		})
+	}
+}
+
+func TestIntelRootCA(t *testing.T) {
+	assert := assert.New(t)
+
+	validator := NewValidator(nil, nil, nil, nil)
+	assert.Equal("Intel SGX Root CA", validator.rootCA.Subject.CommonName)
+	assert.NoError(validator.rootCA.CheckSignatureFrom(validator.rootCA))
+}
+
+// testQuoteConfig controls the generation of test quotes and collateral.
+type testQuoteConfig struct {
+	mrtd          []byte
+	rtmr0         []byte
+	tdAttributes  [8]byte
+	teeTCBSVN     [16]byte
+	seamMRSigner  [48]byte
+	qeMRSigner    [32]byte
+	qeISVSVN      uint16
+	tcbInfoFMSPC  string
+	crlNextUpdate time.Time
+	mrConfigID    [48]byte
+	instanceID    string
+
+	corruptQuoteSignature      bool
+	corruptQEReportSignature   bool
+	corruptTCBInfoSignature    bool
+	corruptQEIdentitySignature bool
+	untrustedPCK               bool
+	revokedPCK                 bool
+}
+
+const (
+	testInstanceID = "qemu:///hostname/worker-0"
+	testFMSPC      = "00806F050000"
+	testQEProdID   = 2
+	testPCKSerial  = 2
+)
+
+var (
+	testSEAMMRSigner = bytes.Repeat([]byte{0x33}, 48)
+	testQEMRSigner   = bytes.Repeat([]byte{0x44}, 32)
+)
+
+func newTestQuoteConfig(mrtd, rtmr0 []byte) testQuoteConfig {
+	cfg := testQuoteConfig{
+		mrtd:          mrtd,
+		rtmr0:         rtmr0,
+		qeISVSVN:      4,
+		tcbInfoFMSPC:  testFMSPC,
+		crlNextUpdate: time.Now().Add(time.Hour),
+	}
+	cfg.teeTCBSVN[0] = 3
+	copy(cfg.seamMRSigner[:], testSEAMMRSigner)
+	copy(cfg.qeMRSigner[:], testQEMRSigner)
+	return cfg
+}
+
+// newTestAttestation creates a root CA and an attestation document signed by a PCK chained to that root.
+func newTestAttestation(t *testing.T, cfg testQuoteConfig, userData, nonce []byte) (*x509.Certificate, []byte) {
+	t.Helper()
+	require := require.New(t)
+
+	rootKey, rootCert := newTestCA(t, "Test Root CA", nil, nil)
+	pckCAKey, pckCACert := newTestCA(t, "Test SGX PCK Platform CA", rootKey, rootCert)
+	pckKey, pckCert := newTestPCK(t, pckCAKey, pckCACert)
+	tcbSigningKey, tcbSigningCert := newTestCA(t, "Test TCB Signing", rootKey, rootCert)
+	if cfg.untrustedPCK {
+		otherRootKey, otherRootCert := newTestCA(t, "Other Root CA", nil, nil)
+		pckKey, pckCert = newTestPCK(t, otherRootKey, otherRootCert)
+	}
+
+	attestationKey, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	require.NoError(err)
+	rawAttestationKey := make([]byte, lenECDSAPubKey)
+	attestationKey.X.FillBytes(rawAttestationKey[:32])
+	attestationKey.Y.FillBytes(rawAttestationKey[32:])
+
+	// header and body
+	header := quoteHeader{Version: quoteVersion, AttestationKeyType: attestationKeyTypeECDSAP256, TEEType: teeTypeTDX}
+	body := tdQuoteBody{
+		TDAttributes: cfg.tdAttributes,
+		TEETCBSVN:    cfg.teeTCBSVN,
+		MRSignerSEAM: cfg.seamMRSigner,
+		MRConfigID:   cfg.mrConfigID,
+		ReportData:   makeReportData(userData, nonce),
+	}
+	copy(body.MRTD[:], cfg.mrtd)
+	copy(body.RTMR[0][:], cfg.rtmr0)
+	signed := new(bytes.Buffer)
+	require.NoError(binary.Write(signed, binary.LittleEndian, header))
+	require.NoError(binary.Write(signed, binary.LittleEndian, body))
+	quoteSig := signRaw(t, attestationKey, signed.Bytes())
+	if cfg.corruptQuoteSignature {
+		quoteSig[0] ^= 0xFF
+	}
+
+	// QE report binding the attestation key
+	qeAuthData := []byte("qe auth data")
+	report := qeReport{MRSigner: cfg.qeMRSigner, ISVProdID: testQEProdID, ISVSVN: cfg.qeISVSVN}
+	keyHash := sha256.Sum256(append(append([]byte{}, rawAttestationKey...), qeAuthData...))
+	copy(report.ReportData[:], keyHash[:])
+	reportRaw := new(bytes.Buffer)
+	require.NoError(binary.Write(reportRaw, binary.LittleEndian, report))
+	qeReportSig := signRaw(t, pckKey, reportRaw.Bytes())
+	if cfg.corruptQEReportSignature {
+		qeReportSig[0] ^= 0xFF
+	}
+
+	// certification data
+	pckChain := append(append(pemCert(pckCert), pemCert(pckCACert)...), pemCert(rootCert)...)
+	qeCertData := new(bytes.Buffer)
+	qeCertData.Write(reportRaw.Bytes())
+	qeCertData.Write(qeReportSig)
+	require.NoError(binary.Write(qeCertData, binary.LittleEndian, uint16(len(qeAuthData))))
+	qeCertData.Write(qeAuthData)
+	writeCertData(t, qeCertData, certDataTypePCKCertChain, pckChain)
+
+	sigData := new(bytes.Buffer)
+	sigData.Write(quoteSig)
+	sigData.Write(rawAttestationKey)
+	writeCertData(t, sigData, certDataTypeQEReport, qeCertData.Bytes())
+
+	rawQuote := new(bytes.Buffer)
+	rawQuote.Write(signed.Bytes())
+	require.NoError(binary.Write(rawQuote, binary.LittleEndian, uint32(sigData.Len())))
+	rawQuote.Write(sigData.Bytes())
+
+	// collateral
+	collateral := newTestCollateral(t, cfg, tcbSigningKey, tcbSigningCert, rootCert)
+	collateral.PCKCRL, collateral.PCKCRLIssuerChain = newTestPCKCRL(t, cfg, pckCAKey, pckCACert, rootCert)
+	attDoc, err := json.Marshal(AttestationDocument{
+		Quote:      rawQuote.Bytes(),
+		Collateral: collateral,
+		UserData:   userData,
+		InstanceID: cfg.instanceID,
+	})
+	require.NoError(err)
+
+	return rootCert, attDoc
+}
+
+func newTestCollateral(t *testing.T, cfg testQuoteConfig, key *ecdsa.PrivateKey, cert, root *x509.Certificate) Collateral {
+	t.Helper()
+	require := require.New(t)
+
+	newLevel := func(tdxSVN uint8, status string) tcbLevel {
+		var level tcbLevel
+		level.TCB.SGXTCBComponents = make([]tcbComponent, 16)
+		level.TCB.TDXTCBComponents = make([]tcbComponent, 16)
+		level.TCB.TDXTCBComponents[0].SVN = tdxSVN
+		level.TCB.PCESVN = 11
+		level.TCBStatus = status
+		return level
+	}
+	newISVLevel := func(svn uint16, status string) isvTCBLevel {
+		var level isvTCBLevel
+		level.TCB.ISVSVN = svn
+		level.TCBStatus = status
+		return level
+	}
+	module := tdxModule{
+		MRSigner:       hex.EncodeToString(testSEAMMRSigner),
+		Attributes:     "0000000000000000",
+		AttributesMask: "FFFFFFFFFFFFFFFF",
+	}
+	info := tcbInfo{
+		ID:         "TDX",
+		Version:    3,
+		IssueDate:  time.Now().Add(-time.Hour).UTC(),
+		NextUpdate: time.Now().Add(time.Hour).UTC(),
+		FMSPC:      cfg.tcbInfoFMSPC,
+		TDXModule:  module,
+		TDXModuleIdentities: []tdxModuleIdentity{
+			{
+				ID:        "TDX_01",
+				tdxModule: module,
+				TCBLevels: []isvTCBLevel{newISVLevel(2, tcbStatusUpToDate), newISVLevel(0, "OutOfDate")},
+			},
+		},
+		TCBLevels: []tcbLevel{
+			newLevel(3, tcbStatusUpToDate),
+			newLevel(0, "OutOfDate"),
+		},
+	}
+	infoRaw, err := json.Marshal(info)
+	require.NoError(err)
+
+	sig := signRaw(t, key, infoRaw)
+	if cfg.corruptTCBInfoSignature {
+		sig[0] ^= 0xFF
+	}
+	resp, err := json.Marshal(tcbInfoResponse{TCBInfo: infoRaw, Signature: hex.EncodeToString(sig)})
+	require.NoError(err)
+
+	identity := qeIdentity{
+		ID:             qeIdentityID,
+		Version:        2,
+		IssueDate:      time.Now().Add(-time.Hour).UTC(),
+		NextUpdate:     time.Now().Add(time.Hour).UTC(),
+		MiscSelect:     "00000000",
+		MiscSelectMask: "FFFFFFFF",
+		Attributes:     "00000000000000000000000000000000",
+		AttributesMask: "FBFFFFFFFFFFFFFF0000000000000000",
+		MRSigner:       hex.EncodeToString(testQEMRSigner),
+		ISVProdID:      testQEProdID,
+		TCBLevels:      []isvTCBLevel{newISVLevel(4, tcbStatusUpToDate), newISVLevel(0, "OutOfDate")},
+	}
+	identityRaw, err := json.Marshal(identity)
+	require.NoError(err)
+	identitySig := signRaw(t, key, identityRaw)
+	if cfg.corruptQEIdentitySignature {
+		identitySig[0] ^= 0xFF
+	}
+	identityResp, err := json.Marshal(qeIdentityResponse{EnclaveIdentity: identityRaw, Signature: hex.EncodeToString(identitySig)})
+	require.NoError(err)
+
+	return Collateral{
+		TCBInfo:               resp,
+		TCBInfoIssuerChain:    append(pemCert(cert), pemCert(root)...),
+		QEIdentity:            identityResp,
+		QEIdentityIssuerChain: append(pemCert(cert), pemCert(root)...),
+	}
+}
+
+// newTestPCKCRL creates a CRL of the PCK CA, revoking the test PCK certificate if configured.
+func newTestPCKCRL(t *testing.T, cfg testQuoteConfig, key *ecdsa.PrivateKey, cert, root *x509.Certificate) ([]byte, []byte) {
+	t.Helper()
+
+	revoked := []pkix.RevokedCertificate{{SerialNumber: big.NewInt(1234), RevocationTime: time.Now()}}
+	if cfg.revokedPCK {
+		revoked = append(revoked, pkix.RevokedCertificate{SerialNumber: big.NewInt(testPCKSerial), RevocationTime: time.Now()})
+	}
+	crl, err := cert.CreateCRL(rand.Reader, key, revoked, time.Now().Add(-time.Hour), cfg.crlNextUpdate)
+	require.NoError(t, err)
+	return crl, append(pemCert(cert), pemCert(root)...)
+}
+
+func newTestCA(t *testing.T, name string, parentKey *ecdsa.PrivateKey, parent *x509.Certificate) (*ecdsa.PrivateKey, *x509.Certificate) {
+	t.Helper()
+	template := &x509.Certificate{
+		SerialNumber:          big.NewInt(1),
+		Subject:               pkix.Name{CommonName: name},
+		NotBefore:             time.Now().Add(-time.Hour),
+		NotAfter:              time.Now().Add(time.Hour),
+		IsCA:                  true,
+		BasicConstraintsValid: true,
+		KeyUsage:              x509.KeyUsageCertSign | x509.KeyUsageCRLSign | x509.KeyUsageDigitalSignature,
+	}
+	return newTestCert(t, template, parentKey, parent)
+}
+
+func newTestPCK(t *testing.T, parentKey *ecdsa.PrivateKey, parent *x509.Certificate) (*ecdsa.PrivateKey, *x509.Certificate) {
+	t.Helper()
+	require := require.New(t)
+
+	fmspc, err := hex.DecodeString(testFMSPC)
+	require.NoError(err)
+	var tcb []sgxExtension
+	for i := 1; i <= 16; i++ {
+		tcb = append(tcb, sgxExtension{ID: appendOID(oidTCB, i), Value: asn1Int(t, 0)})
+	}
+	tcb = append(tcb, sgxExtension{ID: oidPCESVN, Value: asn1Int(t, 11)})
+	tcbRaw, err := asn1.Marshal(tcb)
+	require.NoError(err)
+	fmspcRaw, err := asn1.Marshal(fmspc)
+	require.NoError(err)
+	extensions, err := asn1.Marshal([]sgxExtension{
+		{ID: oidTCB, Value: asn1.RawValue{FullBytes: tcbRaw}},
+		{ID: oidFMSPC, Value: asn1.RawValue{FullBytes: fmspcRaw}},
+	})
+	require.NoError(err)
+
+	template := &x509.Certificate{
+		SerialNumber:    big.NewInt(testPCKSerial),
+		Subject:         pkix.Name{CommonName: "Test PCK Certificate"},
+		NotBefore:       time.Now().Add(-time.Hour),
+		NotAfter:        time.Now().Add(time.Hour),
+		KeyUsage:        x509.KeyUsageDigitalSignature,
+		ExtraExtensions: []pkix.Extension{{Id: oidSGXExtensions, Value: extensions}},
+	}
+	return newTestCert(t, template, parentKey, parent)
+}
+
+func newTestCert(t *testing.T, template *x509.Certificate, parentKey *ecdsa.PrivateKey, parent *x509.Certificate) (*ecdsa.PrivateKey, *x509.Certificate) {
+	t.Helper()
+	require := require.New(t)
+
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	require.NoError(err)
+	if parent == nil {
+		parent, parentKey = template, key
+	}
+	der, err := x509.CreateCertificate(rand.Reader, template, parent, &key.PublicKey, parentKey)
+	require.NoError(err)
+	cert, err := x509.ParseCertificate(der)
+	require.NoError(err)
+	return key, cert
+}
+
+func signRaw(t *testing.T, key *ecdsa.PrivateKey, data []byte) []byte {
+	t.Helper()
+	digest := sha256.Sum256(data)
+	r, s, err := ecdsa.Sign(rand.Reader, key, digest[:])
+	require.NoError(t, err)
+	sig := make([]byte, lenECDSASig)
+	r.FillBytes(sig[:32])
+	s.FillBytes(sig[32:])
+	return sig
+}
+
+func writeCertData(t *testing.T, buf *bytes.Buffer, certDataType uint16, data []byte) {
+	t.Helper()
+	require.NoError(t, binary.Write(buf, binary.LittleEndian, certDataType))
+	require.NoError(t, binary.Write(buf, binary.LittleEndian, uint32(len(data))))
+	buf.Write(data)
+}
+
+func pemCert(cert *x509.Certificate) []byte {
+	return pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: cert.Raw})
+}
+
+func asn1Int(t *testing.T, i int) asn1.RawValue {
+	t.Helper()
+	raw, err := asn1.Marshal(i)
+	require.NoError(t, err)
+	return asn1.RawValue{FullBytes: raw}
+}
+
+func appendOID(base asn1.ObjectIdentifier, i int) asn1.ObjectIdentifier {
+	oid := make(asn1.ObjectIdentifier, len(base), len(base)+1)
+	copy(oid, base)
+	return append(oid, i)
+}
+
+func TestMaskedEqual(t *testing.T) {
+	testCases := map[string]struct {
+		value    []byte
+		expected string
+		mask     string
+		want     bool
+	}{
+		"equal":                {value: []byte{0x12, 0x34}, expected: "1234", mask: "FFFF", want: true},
+		"masked bits differ":   {value: []byte{0x12, 0x34}, expected: "1200", mask: "FF00", want: true},
+		"unmasked bits differ": {value: []byte{0x12, 0x34}, expected: "1235", mask: "FFFF"},
+		"length mismatch":      {value: []byte{0x12}, expected: "1234", mask: "FFFF"},
+		"invalid hex":          {value: []byte{0x12}, expected: "zz", mask: "FF"},
+	}
+
+	for name, tc := range testCases {
+		t.Run(name, func(t *testing.T) {
+			assert.Equal(t, tc.want, maskedEqual(tc.value, tc.expected, tc.mask))
+		})
+	}
+}
+
+func TestTCBLevelMatches(t *testing.T) {
+	newLevel := func(sgx, tdx uint8, pce uint16) tcbLevel {
+		var level tcbLevel
+		level.TCB.SGXTCBComponents = make([]tcbComponent, 16)
+		level.TCB.TDXTCBComponents = make([]tcbComponent, 16)
+		level.TCB.SGXTCBComponents[0].SVN = sgx
+		level.TCB.TDXTCBComponents[0].SVN = tdx
+		level.TCB.PCESVN = pce
+		return level
+	}
+	pck := pckExtensions{pceSVN: 5}
+	pck.sgxTCBComponents[0] = 2
+	var teeTCBSVN [16]byte
+	teeTCBSVN[0] = 2
+
+	testCases := map[string]struct {
+		level tcbLevel
+		want  bool
+	}{
+		"equal":              {level: newLevel(2, 2, 5), want: true},
+		"lower":              {level: newLevel(1, 1, 4), want: true},
+		"higher sgx":         {level: newLevel(3, 2, 5)},
+		"higher tdx":         {level: newLevel(2, 3, 5)},
+		"higher pcesvn":      {level: newLevel(2, 2, 6)},
+		"missing components": {level: tcbLevel{}},
+	}
+
+	for name, tc := range testCases {
+		t.Run(name, func(t *testing.T) {
+			assert.Equal(t, tc.want, tc.level.matches(pck, teeTCBSVN), fmt.Sprintf("%+v", tc.level))
+		})
+	}
+
+	t.Run("TDX module SVN is ignored for major versions above 0", func(t *testing.T) {
+		teeTCBSVN := teeTCBSVN
+		teeTCBSVN[1] = 1
+		level := newLevel(2, 3, 5)
+		assert.True(t, level.matches(pck, teeTCBSVN))
+	})
+}
@@ -16,6 +16,7 @@ import (
 	"fmt"
 	"io"
 
+	"github.com/edgelesssys/constellation/v2/internal/attestation/policy"
 	tpmClient "github.com/google/go-tpm-tools/client"
 	"github.com/google/go-tpm-tools/proto/attest"
 	tpmProto "github.com/google/go-tpm-tools/proto/tpm"
@@ -52,7 +53,8 @@ type (
 	// GetInstanceInfo returns VM metdata.
 	GetInstanceInfo func(tpm io.ReadWriteCloser) ([]byte, error)
 	// ValidateCVM validates confidential computing capabilities of the instance issuing the attestation.
-	ValidateCVM func(attestation AttestationDocument) error
+	// CVM specific claims, e.g. fields of a hardware attestation report, are added to claims.
+	ValidateCVM func(attestation AttestationDocument, claims *policy.Claims) error
 	// VerifyUserData verifies signed user data.
 	VerifyUserData func(pub crypto.PublicKey, hash crypto.Hash, hashed, sig []byte) error
 )
@@ -138,13 +140,16 @@ type Validator struct {
 	getTrustedKey  GetTPMTrustedAttestationPublicKey
 	validateCVM    ValidateCVM
 	verifyUserData VerifyUserData
+	policy         *policy.Policy
 
 	log WarnLogger
 }
 
 // NewValidator returns a new Validator.
+// If attestationPolicy is not nil, it is evaluated against the claims of every validated attestation.
 func NewValidator(expectedPCRs map[uint32][]byte, enforcedPCRs []uint32,
-	getTrustedKey GetTPMTrustedAttestationPublicKey, validateCVM ValidateCVM, verifyUserData VerifyUserData, log WarnLogger,
+	getTrustedKey GetTPMTrustedAttestationPublicKey, validateCVM ValidateCVM, verifyUserData VerifyUserData,
+	attestationPolicy *policy.Policy, log WarnLogger,
 ) *Validator {
 	// Convert the enforced PCR list to a map for convenient and fast lookup
 	enforcedMap := make(map[uint32]struct{})
@@ -158,49 +163,60 @@ func NewValidator(expectedPCRs map[uint32][]byte, enforcedPCRs []uint32,
 		getTrustedKey:  getTrustedKey,
 		validateCVM:    validateCVM,
 		verifyUserData: verifyUserData,
+		policy:         attestationPolicy,
 		log:            log,
 	}
 }
 
 // Validate a TPM based attestation.
 func (v *Validator) Validate(attDocRaw []byte, nonce []byte) ([]byte, error) {
+	userData, _, err := v.ValidateWithClaims(attDocRaw, nonce)
+	return userData, err
+}
+
+// ValidateWithClaims validates a TPM based attestation.
+// In addition to the signed user data, it returns the claims established by the attestation.
+func (v *Validator) ValidateWithClaims(attDocRaw []byte, nonce []byte) ([]byte, policy.Claims, error) {
 	var attDoc AttestationDocument
 	if err := json.Unmarshal(attDocRaw, &attDoc); err != nil {
-		return nil, fmt.Errorf("unmarshaling TPM attestation document: %w", err)
+		return nil, policy.Claims{}, fmt.Errorf("unmarshaling TPM attestation document: %w", err)
 	}
 
 	// Verify and retrieve the trusted attestation public key using the provided instance info
 	aKP, err := v.getTrustedKey(attDoc.Attestation.AkPub, attDoc.InstanceInfo)
 	if err != nil {
-		return nil, fmt.Errorf("validating attestation public key: %w", err)
+		return nil, policy.Claims{}, fmt.Errorf("validating attestation public key: %w", err)
 	}
 
 	// Validate confidential computing capabilities of the VM
-	if err := v.validateCVM(attDoc); err != nil {
-		return nil, fmt.Errorf("verifying VM confidential computing capabilities: %w", err)
+	akDigest := sha256.Sum256(attDoc.Attestation.AkPub)
+	claims := policy.Claims{InstanceInfo: attDoc.InstanceInfo, AttestationKeyDigest: akDigest[:]}
+	if err := v.validateCVM(attDoc, &claims); err != nil {
+		return nil, policy.Claims{}, fmt.Errorf("verifying VM confidential computing capabilities: %w", err)
 	}
 
 	// Verify the TPM attestation
-	if _, err := tpmServer.VerifyAttestation(
+	machineState, err := tpmServer.VerifyAttestation(
 		attDoc.Attestation,
 		tpmServer.VerifyOpts{
 			Nonce:      nonce,
 			TrustedAKs: []crypto.PublicKey{aKP},
 			AllowSHA1:  false,
 		},
-	); err != nil {
-		return nil, fmt.Errorf("verifying attestation document: %w", err)
+	)
+	if err != nil {
+		return nil, policy.Claims{}, fmt.Errorf("verifying attestation document: %w", err)
 	}
 
 	// Verify PCRs
 	quoteIdx, err := GetSHA256QuoteIndex(attDoc.Attestation.Quotes)
 	if err != nil {
-		return nil, err
+		return nil, policy.Claims{}, err
 	}
 	for idx, pcr := range v.expectedPCRs {
 		if !bytes.Equal(pcr, attDoc.Attestation.Quotes[quoteIdx].Pcrs.Pcrs[idx]) {
 			if _, ok := v.enforcedPCRs[idx]; ok {
-				return nil, fmt.Errorf("untrusted PCR value at PCR index %d", idx)
+				return nil, policy.Claims{}, fmt.Errorf("untrusted PCR value at PCR index %d", idx)
 			}
 			if v.log != nil {
 				v.log.Warnf("Encountered untrusted PCR value at index %d", idx)
@@ -211,9 +227,27 @@ func (v *Validator) Validate(attDocRaw []byte, nonce []byte) ([]byte, error) {
 	// Verify signed user data
 	digest := sha256.Sum256(attDoc.UserData)
 	if err = v.verifyUserData(aKP, crypto.SHA256, digest[:], attDoc.UserDataSignature); err != nil {
-		return nil, fmt.Errorf("verifying signed user data: %w", err)
+		return nil, policy.Claims{}, fmt.Errorf("verifying signed user data: %w", err)
+	}
+
+	claims.Measurements = attDoc.Attestation.Quotes[quoteIdx].Pcrs.Pcrs
+	for _, event := range machineState.GetRawEvents() {
+		claims.EventLog = append(claims.EventLog, policy.Event{
+			PCRIndex: event.GetPcrIndex(),
+			Type:     event.GetUntrustedType(),
+			Digest:   event.GetDigest(),
+			Data:     event.GetData(),
+		})
 	}
-	return attDoc.UserData, nil
+
+	// Evaluate the attestation policy against the verified claims
+	if v.policy != nil {
+		if err := v.policy.Evaluate(claims); err != nil {
+			return nil, policy.Claims{}, err
+		}
+	}
+
+	return attDoc.UserData, claims, nil
 }
 
 // GetSHA256QuoteIndex performs safety checks and returns the index for SHA256 PCR quotes.
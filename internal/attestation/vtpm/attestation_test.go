@@ -14,6 +14,7 @@ import (
 	"io"
 	"testing"
 
+	"github.com/edgelesssys/constellation/v2/internal/attestation/policy"
 	tpmsim "github.com/edgelesssys/constellation/v2/internal/attestation/simulator"
 	tpmclient "github.com/google/go-tpm-tools/client"
 	"github.com/google/go-tpm-tools/proto/attest"
@@ -55,7 +56,7 @@ func fakeGetInstanceInfo(tpm io.ReadWriteCloser) ([]byte, error) {
 func TestValidate(t *testing.T) {
 	require := require.New(t)
 
-	fakeValidateCVM := func(AttestationDocument) error { return nil }
+	fakeValidateCVM := func(AttestationDocument, *policy.Claims) error { return nil }
 	fakeGetTrustedKey := func(aKPub, instanceInfo []byte) (crypto.PublicKey, error) {
 		pubArea, err := tpm2.DecodePublic(aKPub)
 		if err != nil {
@@ -71,7 +72,7 @@ func TestValidate(t *testing.T) {
 	warnLog := &testWarnLog{}
 
 	issuer := NewIssuer(newSimTPMWithEventLog, tpmclient.AttestationKeyRSA, fakeGetInstanceInfo)
-	validator := NewValidator(testExpectedPCRs, []uint32{0, 1}, fakeGetTrustedKey, fakeValidateCVM, VerifyPKCS1v15, warnLog)
+	validator := NewValidator(testExpectedPCRs, []uint32{0, 1}, fakeGetTrustedKey, fakeValidateCVM, VerifyPKCS1v15, nil, warnLog)
 
 	nonce := []byte{1, 2, 3, 4}
 	challenge := []byte("Constellation")
@@ -89,6 +90,12 @@ func TestValidate(t *testing.T) {
 	require.NoError(err)
 	require.Equal(challenge, out)
 
+	out, claims, err := validator.ValidateWithClaims(attDocRaw, nonce)
+	require.NoError(err)
+	require.Equal(challenge, out)
+	assert.Equal(t, testExpectedPCRs[0], claims.Measurements[0])
+	assert.Equal(t, attDoc.InstanceInfo, claims.InstanceInfo)
+
 	enforcedPCRs := []uint32{0, 1}
 	expectedPCRs := map[uint32][]byte{
 		0: {0x00, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00},
@@ -104,6 +111,7 @@ func TestValidate(t *testing.T) {
 		fakeGetTrustedKey,
 		fakeValidateCVM,
 		VerifyPKCS1v15,
+		nil,
 		warnLog,
 	)
 	out, err = warningValidator.Validate(attDocRaw, nonce)
@@ -118,13 +126,13 @@ func TestValidate(t *testing.T) {
 		wantErr   bool
 	}{
 		"invalid nonce": {
-			validator: NewValidator(testExpectedPCRs, []uint32{0, 1}, fakeGetTrustedKey, fakeValidateCVM, VerifyPKCS1v15, warnLog),
+			validator: NewValidator(testExpectedPCRs, []uint32{0, 1}, fakeGetTrustedKey, fakeValidateCVM, VerifyPKCS1v15, nil, warnLog),
 			attDoc:    mustMarshalAttestation(attDoc, require),
 			nonce:     []byte{4, 3, 2, 1},
 			wantErr:   true,
 		},
 		"invalid signature": {
-			validator: NewValidator(testExpectedPCRs, []uint32{0, 1}, fakeGetTrustedKey, fakeValidateCVM, VerifyPKCS1v15, warnLog),
+			validator: NewValidator(testExpectedPCRs, []uint32{0, 1}, fakeGetTrustedKey, fakeValidateCVM, VerifyPKCS1v15, nil, warnLog),
 			attDoc: mustMarshalAttestation(AttestationDocument{
 				Attestation:       attDoc.Attestation,
 				InstanceInfo:      attDoc.InstanceInfo,
@@ -141,7 +149,7 @@ func TestValidate(t *testing.T) {
 				func(akPub, instanceInfo []byte) (crypto.PublicKey, error) {
 					return nil, errors.New("untrusted")
 				},
-				fakeValidateCVM, VerifyPKCS1v15, warnLog),
+				fakeValidateCVM, VerifyPKCS1v15, nil, warnLog),
 			attDoc:  mustMarshalAttestation(attDoc, require),
 			nonce:   nonce,
 			wantErr: true,
@@ -151,10 +159,10 @@ func TestValidate(t *testing.T) {
 				testExpectedPCRs,
 				[]uint32{0, 1},
 				fakeGetTrustedKey,
-				func(attestation AttestationDocument) error {
+				func(attestation AttestationDocument, claims *policy.Claims) error {
 					return errors.New("untrusted")
 				},
-				VerifyPKCS1v15, warnLog),
+				VerifyPKCS1v15, nil, warnLog),
 			attDoc:  mustMarshalAttestation(attDoc, require),
 			nonce:   nonce,
 			wantErr: true,
@@ -167,13 +175,13 @@ func TestValidate(t *testing.T) {
 				[]uint32{0},
 				fakeGetTrustedKey,
 				fakeValidateCVM,
-				VerifyPKCS1v15, warnLog),
+				VerifyPKCS1v15, nil, warnLog),
 			attDoc:  mustMarshalAttestation(attDoc, require),
 			nonce:   nonce,
 			wantErr: true,
 		},
 		"no sha256 quote": {
-			validator: NewValidator(testExpectedPCRs, []uint32{0, 1}, fakeGetTrustedKey, fakeValidateCVM, VerifyPKCS1v15, warnLog),
+			validator: NewValidator(testExpectedPCRs, []uint32{0, 1}, fakeGetTrustedKey, fakeValidateCVM, VerifyPKCS1v15, nil, warnLog),
 			attDoc: mustMarshalAttestation(AttestationDocument{
 				Attestation: &attest.Attestation{
 					AkPub: attDoc.Attestation.AkPub,
@@ -190,8 +198,21 @@ func TestValidate(t *testing.T) {
 			nonce:   nonce,
 			wantErr: true,
 		},
+		"policy satisfied": {
+			validator: NewValidator(testExpectedPCRs, []uint32{0, 1}, fakeGetTrustedKey, fakeValidateCVM, VerifyPKCS1v15,
+				mustNewPolicy(`claims.measurements[0] == "0000000000000000000000000000000000000000000000000000000000000000" && size(claims.eventLog) >= 0`, require), warnLog),
+			attDoc: mustMarshalAttestation(attDoc, require),
+			nonce:  nonce,
+		},
+		"policy not satisfied": {
+			validator: NewValidator(testExpectedPCRs, []uint32{0, 1}, fakeGetTrustedKey, fakeValidateCVM, VerifyPKCS1v15,
+				mustNewPolicy(`claims.measurements[0] == "ff"`, require), warnLog),
+			attDoc:  mustMarshalAttestation(attDoc, require),
+			nonce:   nonce,
+			wantErr: true,
+		},
 		"invalid attestation document": {
-			validator: NewValidator(testExpectedPCRs, []uint32{0, 1}, fakeGetTrustedKey, fakeValidateCVM, VerifyPKCS1v15, warnLog),
+			validator: NewValidator(testExpectedPCRs, []uint32{0, 1}, fakeGetTrustedKey, fakeValidateCVM, VerifyPKCS1v15, nil, warnLog),
 			attDoc:    []byte("invalid attestation"),
 			nonce:     nonce,
 			wantErr:   true,
@@ -212,6 +233,12 @@ func TestValidate(t *testing.T) {
 	}
 }
 
+func mustNewPolicy(source string, require *require.Assertions) *policy.Policy {
+	p, err := policy.New(source)
+	require.NoError(err)
+	return p
+}
+
 func mustMarshalAttestation(attDoc AttestationDocument, require *require.Assertions) []byte {
 	out, err := json.Marshal(attDoc)
 	require.NoError(err)
@@ -0,0 +1,78 @@
+/*
+Copyright (c) Edgeless Systems GmbH
+
+SPDX-License-Identifier: AGPL-3.0-only
+*/
+
+package vtpm
+
+import (
+	"crypto"
+	"crypto/sha256"
+	"sync"
+	"time"
+)
+
+// EnableTrustedKeyCache caches verified attestation keys for up to maxAge.
+// Repeated attestations of the same instance skip the verification of its attestation key,
+// e.g. fetching and verifying the AK certificate or the VCEK chain and SNP report.
+// Must be called before the Validator is used.
+func (v *Validator) EnableTrustedKeyCache(maxAge time.Duration) {
+	if maxAge <= 0 {
+		return
+	}
+	cache := &trustedKeyCache{
+		getTrustedKey: v.getTrustedKey,
+		maxAge:        maxAge,
+		now:           time.Now,
+		keys:          make(map[[sha256.Size]byte]trustedKey),
+	}
+	v.getTrustedKey = cache.get
+}
+
+// trustedKeyCache caches the results of a GetTPMTrustedAttestationPublicKey.
+type trustedKeyCache struct {
+	getTrustedKey GetTPMTrustedAttestationPublicKey
+	maxAge        time.Duration
+	now           func() time.Time
+
+	mux  sync.Mutex
+	keys map[[sha256.Size]byte]trustedKey
+}
+
+type trustedKey struct {
+	key        crypto.PublicKey
+	verifiedAt time.Time
+}
+
+// get returns the trusted key for akPub and instanceInfo, verifying it only if it is not cached.
+func (c *trustedKeyCache) get(akPub, instanceInfo []byte) (crypto.PublicKey, error) {
+	hash := sha256.New()
+	hash.Write(akPub)
+	hash.Write(instanceInfo)
+	var id [sha256.Size]byte
+	copy(id[:], hash.Sum(nil))
+
+	now := c.now()
+	c.mux.Lock()
+	cached, ok := c.keys[id]
+	c.mux.Unlock()
+	if ok && now.Sub(cached.verifiedAt) < c.maxAge {
+		return cached.key, nil
+	}
+
+	key, err := c.getTrustedKey(akPub, instanceInfo)
+	if err != nil {
+		return nil, err
+	}
+
+	c.mux.Lock()
+	defer c.mux.Unlock()
+	for id, cached := range c.keys {
+		if now.Sub(cached.verifiedAt) >= c.maxAge {
+			delete(c.keys, id)
+		}
+	}
+	c.keys[id] = trustedKey{key: key, verifiedAt: now}
+	return key, nil
+}
@@ -0,0 +1,92 @@
+/*
+Copyright (c) Edgeless Systems GmbH
+
+SPDX-License-Identifier: AGPL-3.0-only
+*/
+
+package vtpm
+
+import (
+	"crypto"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestTrustedKeyCache(t *testing.T) {
+	testCases := map[string]struct {
+		firstInfo   []byte
+		secondInfo  []byte
+		elapsed     time.Duration
+		getErr      error
+		wantLookups int
+		wantErr     bool
+	}{
+		"cached": {
+			firstInfo:   []byte("instance-1"),
+			secondInfo:  []byte("instance-1"),
+			elapsed:     time.Minute,
+			wantLookups: 1,
+		},
+		"different instance": {
+			firstInfo:   []byte("instance-1"),
+			secondInfo:  []byte("instance-2"),
+			elapsed:     time.Minute,
+			wantLookups: 2,
+		},
+		"expired": {
+			firstInfo:   []byte("instance-1"),
+			secondInfo:  []byte("instance-1"),
+			elapsed:     time.Hour,
+			wantLookups: 2,
+		},
+		"errors are not cached": {
+			firstInfo:   []byte("instance-1"),
+			secondInfo:  []byte("instance-1"),
+			elapsed:     time.Minute,
+			getErr:      errors.New("failed"),
+			wantLookups: 2,
+			wantErr:     true,
+		},
+	}
+
+	for name, tc := range testCases {
+		t.Run(name, func(t *testing.T) {
+			assert := assert.New(t)
+			require := require.New(t)
+
+			lookups := 0
+			getTrustedKey := func(akPub, instanceInfo []byte) (crypto.PublicKey, error) {
+				lookups++
+				return akPub, tc.getErr
+			}
+			now := time.Unix(1000, 0)
+			cache := &trustedKeyCache{
+				getTrustedKey: getTrustedKey,
+				maxAge:        10 * time.Minute,
+				now:           func() time.Time { return now },
+				keys:          make(map[[32]byte]trustedKey),
+			}
+
+			_, err := cache.get([]byte("ak"), tc.firstInfo)
+			if tc.wantErr {
+				assert.Error(err)
+			} else {
+				require.NoError(err)
+			}
+
+			now = now.Add(tc.elapsed)
+			key, err := cache.get([]byte("ak"), tc.secondInfo)
+			if tc.wantErr {
+				assert.Error(err)
+			} else {
+				require.NoError(err)
+				assert.Equal([]byte("ak"), key)
+			}
+			assert.Equal(tc.wantLookups, lookups)
+		})
+	}
+}
@@ -18,12 +18,13 @@ import (
 	"github.com/Azure/azure-sdk-for-go/sdk/resourcemanager/network/armnetwork"
 	"github.com/edgelesssys/constellation/v2/internal/azureshared"
 	"github.com/edgelesssys/constellation/v2/internal/cloud/metadata"
+	"github.com/edgelesssys/constellation/v2/internal/constants"
 	"github.com/edgelesssys/constellation/v2/internal/role"
 )
 
 var (
 	controlPlaneScaleSetRegexp = regexp.MustCompile(`constellation-scale-set-controlplanes-[0-9a-zA-Z]+$`)
-	workerScaleSetRegexp       = regexp.MustCompile(`constellation-scale-set-workers-[0-9a-zA-Z]+$`)
+	workerScaleSetRegexp       = regexp.MustCompile(`constellation-scale-set-workers-[0-9a-zA-Z]+(-[0-9a-z]+)?$`)
 )
 
 // getScaleSetVM tries to get an azure vm belonging to a scale set.
@@ -101,16 +102,32 @@ func convertScaleSetVMToCoreInstance(scaleSet string, vm armcomputev2.VirtualMac
 	} else {
 		sshKeys = extractSSHKeys(*vm.Properties.OSProfile.LinuxConfiguration.SSH)
 	}
+	var vmID string
+	if vm.Properties.VMID != nil {
+		vmID = strings.ToLower(*vm.Properties.VMID)
+	}
 	return metadata.InstanceMetadata{
 		Name:       *vm.Properties.OSProfile.ComputerName,
 		ProviderID: "azure://" + *vm.ID,
+		VMID:       vmID,
 		Role:       extractScaleSetVMRole(scaleSet),
 		VPCIP:      extractVPCIP(networkInterfaces),
 		PublicIP:   publicIPAddress,
 		SSHKeys:    sshKeys,
+		// tags of scale set VMs are inherited from the scale set
+		NodeLabels: tagValue(vm.Tags, constants.WorkerGroupLabelsTag),
+		NodeTaints: tagValue(vm.Tags, constants.WorkerGroupTaintsTag),
 	}, nil
 }
 
+// tagValue returns the value of a tag, or an empty string if it is not set.
+func tagValue(tags map[string]*string, key string) string {
+	if value, ok := tags[key]; ok && value != nil {
+		return *value
+	}
+	return ""
+}
+
 // extractScaleSetVMRole extracts the constellation role of a scale set using its name.
 func extractScaleSetVMRole(scaleSet string) role.Role {
 	if controlPlaneScaleSetRegexp.MatchString(scaleSet) {
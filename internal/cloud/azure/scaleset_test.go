@@ -15,6 +15,7 @@ import (
 	armcomputev2 "github.com/Azure/azure-sdk-for-go/sdk/resourcemanager/compute/armcompute/v2"
 	"github.com/Azure/azure-sdk-for-go/sdk/resourcemanager/network/armnetwork"
 	"github.com/edgelesssys/constellation/v2/internal/cloud/metadata"
+	"github.com/edgelesssys/constellation/v2/internal/constants"
 	"github.com/edgelesssys/constellation/v2/internal/role"
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/require"
@@ -165,6 +166,7 @@ func TestConvertScaleSetVMToCoreInstance(t *testing.T) {
 					OSProfile: &armcomputev2.OSProfile{
 						ComputerName: to.Ptr("scale-set-name-instance-id"),
 					},
+					VMID: to.Ptr("B6C98C3B-4EC7-4DA6-BD2F-7D98D20D7B75"),
 				},
 			},
 			inInterface: []armnetwork.Interface{
@@ -187,11 +189,33 @@ func TestConvertScaleSetVMToCoreInstance(t *testing.T) {
 			wantInstance: metadata.InstanceMetadata{
 				Name:       "scale-set-name-instance-id",
 				ProviderID: "azure:///subscriptions/subscription-id/resourceGroups/resource-group/providers/Microsoft.Compute/virtualMachineScaleSets/scale-set-name/virtualMachines/instance-id",
+				VMID:       "b6c98c3b-4ec7-4da6-bd2f-7d98d20d7b75",
 				VPCIP:      "192.0.2.0",
 				PublicIP:   "192.0.2.100",
 				SSHKeys:    map[string][]string{},
 			},
 		},
+		"worker group labels and taints are converted": {
+			inVM: armcomputev2.VirtualMachineScaleSetVM{
+				ID: to.Ptr("/subscriptions/subscription-id/resourceGroups/resource-group/providers/Microsoft.Compute/virtualMachineScaleSets/scale-set-name/virtualMachines/instance-id"),
+				Tags: map[string]*string{
+					constants.WorkerGroupLabelsTag: to.Ptr("pool=highmem"),
+					constants.WorkerGroupTaintsTag: to.Ptr("pool=highmem:NoSchedule"),
+				},
+				Properties: &armcomputev2.VirtualMachineScaleSetVMProperties{
+					OSProfile: &armcomputev2.OSProfile{
+						ComputerName: to.Ptr("scale-set-name-instance-id"),
+					},
+				},
+			},
+			wantInstance: metadata.InstanceMetadata{
+				Name:       "scale-set-name-instance-id",
+				ProviderID: "azure:///subscriptions/subscription-id/resourceGroups/resource-group/providers/Microsoft.Compute/virtualMachineScaleSets/scale-set-name/virtualMachines/instance-id",
+				SSHKeys:    map[string][]string{},
+				NodeLabels: "pool=highmem",
+				NodeTaints: "pool=highmem:NoSchedule",
+			},
+		},
 		"invalid instance": {
 			inVM:    armcomputev2.VirtualMachineScaleSetVM{},
 			wantErr: true,
@@ -228,6 +252,10 @@ func TestExtractScaleSetVMRole(t *testing.T) {
 			scaleSet: "constellation-scale-set-workers-abcd123",
 			wantRole: role.Worker,
 		},
+		"worker group node role": {
+			scaleSet: "constellation-scale-set-workers-abcd123-highmem",
+			wantRole: role.Worker,
+		},
 		"unknown role": {
 			scaleSet: "unknown",
 			wantRole: role.Unknown,
@@ -0,0 +1,44 @@
+/*
+Copyright (c) Edgeless Systems GmbH
+
+SPDX-License-Identifier: AGPL-3.0-only
+*/
+
+package cloudtypes
+
+import (
+	"sort"
+	"strings"
+)
+
+// WorkerGroup is a named group of worker nodes that is created as one scaling group.
+type WorkerGroup struct {
+	// Name is the name of the group. The default worker group has an empty name.
+	Name string
+	// Count is the number of nodes the group is created with.
+	Count int
+	// Min is the minimum number of nodes the cluster autoscaler keeps in the group.
+	Min int
+	// Max is the maximum number of nodes the cluster autoscaler scales the group to.
+	Max             int
+	InstanceType    string
+	StateDiskSizeGB int
+	StateDiskType   string
+	Labels          map[string]string
+	Taints          []string
+}
+
+// LabelsString returns the labels of the group as sorted, comma separated list of key=value pairs.
+func (g WorkerGroup) LabelsString() string {
+	labels := make([]string, 0, len(g.Labels))
+	for key, value := range g.Labels {
+		labels = append(labels, key+"="+value)
+	}
+	sort.Strings(labels)
+	return strings.Join(labels, ",")
+}
+
+// TaintsString returns the taints of the group as comma separated list.
+func (g WorkerGroup) TaintsString() string {
+	return strings.Join(g.Taints, ",")
+}
@@ -0,0 +1,44 @@
+/*
+Copyright (c) Edgeless Systems GmbH
+
+SPDX-License-Identifier: AGPL-3.0-only
+*/
+
+package cloudtypes
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestWorkerGroupLabelsString(t *testing.T) {
+	testCases := map[string]struct {
+		labels map[string]string
+		want   string
+	}{
+		"no labels": {},
+		"one label": {
+			labels: map[string]string{"pool": "highmem"},
+			want:   "pool=highmem",
+		},
+		"labels are sorted": {
+			labels: map[string]string{"zone": "a", "example.com/pool": "spot", "pool": ""},
+			want:   "example.com/pool=spot,pool=,zone=a",
+		},
+	}
+
+	for name, tc := range testCases {
+		t.Run(name, func(t *testing.T) {
+			assert := assert.New(t)
+			assert.Equal(tc.want, WorkerGroup{Labels: tc.labels}.LabelsString())
+		})
+	}
+}
+
+func TestWorkerGroupTaintsString(t *testing.T) {
+	assert := assert.New(t)
+	assert.Empty(WorkerGroup{}.TaintsString())
+	group := WorkerGroup{Taints: []string{"pool=spot:NoSchedule", "dedicated:NoExecute"}}
+	assert.Equal("pool=spot:NoSchedule,dedicated:NoExecute", group.TaintsString())
+}
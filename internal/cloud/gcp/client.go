@@ -16,6 +16,7 @@ import (
 
 	compute "cloud.google.com/go/compute/apiv1"
 	"github.com/edgelesssys/constellation/v2/internal/cloud/metadata"
+	"github.com/edgelesssys/constellation/v2/internal/constants"
 	"github.com/edgelesssys/constellation/v2/internal/gcpshared"
 	"google.golang.org/api/iterator"
 	computepb "google.golang.org/genproto/googleapis/cloud/compute/v1"
@@ -390,6 +391,8 @@ func convertToCoreInstance(in *computepb.Instance, project string, zone string)
 		PublicIP:      extractPublicIP(in.NetworkInterfaces),
 		AliasIPRanges: extractAliasIPRanges(in.NetworkInterfaces),
 		SSHKeys:       extractSSHKeys(mdata),
+		NodeLabels:    mdata[constants.WorkerGroupLabelsTag],
+		NodeTaints:    mdata[constants.WorkerGroupTaintsTag],
 	}, nil
 }
 
@@ -13,6 +13,7 @@ import (
 
 	compute "cloud.google.com/go/compute/apiv1"
 	"github.com/edgelesssys/constellation/v2/internal/cloud/metadata"
+	"github.com/edgelesssys/constellation/v2/internal/constants"
 	"github.com/edgelesssys/constellation/v2/internal/role"
 	gax "github.com/googleapis/gax-go/v2"
 	"github.com/stretchr/testify/assert"
@@ -279,6 +280,26 @@ func TestRetrieveInstance(t *testing.T) {
 				SSHKeys:       map[string][]string{},
 			},
 		},
+		"retrieve with worker group labels and taints works": {
+			client:         stubInstancesClient{},
+			clientInstance: newTestInstance(),
+			clientInstanceMutator: func(i *computepb.Instance) {
+				i.Metadata.Items[0].Key = proto.String(constants.WorkerGroupLabelsTag)
+				i.Metadata.Items[0].Value = proto.String("pool=highmem")
+				i.Metadata.Items[1].Key = proto.String(constants.WorkerGroupTaintsTag)
+				i.Metadata.Items[1].Value = proto.String("pool=highmem:NoSchedule")
+			},
+			wantInstance: metadata.InstanceMetadata{
+				Name:          "someInstance",
+				ProviderID:    "gce://someProject/someZone/someInstance",
+				AliasIPRanges: []string{"192.0.2.0/16"},
+				PublicIP:      "192.0.2.1",
+				VPCIP:         "192.0.2.0",
+				SSHKeys:       map[string][]string{},
+				NodeLabels:    "pool=highmem",
+				NodeTaints:    "pool=highmem:NoSchedule",
+			},
+		},
 		"retrieve fails": {
 			client: stubInstancesClient{
 				GetErr: errors.New("retrieve error"),
@@ -20,7 +20,10 @@ import (
 type InstanceMetadata struct {
 	Name       string
 	ProviderID string
-	Role       role.Role
+	// VMID is the unique ID assigned to the VM by the CSP, if it is not part of the provider ID.
+	// It is only set on Azure, where it is the vmId of the VM.
+	VMID string
+	Role role.Role
 	// VPCIP is the primary IP address of the instance in the VPC.
 	VPCIP string
 	// PublicIP is the primary public IP of the instance, if available, empty string otherwise.
@@ -28,6 +31,12 @@ type InstanceMetadata struct {
 	AliasIPRanges []string
 	// SSHKeys maps usernames to ssh public keys.
 	SSHKeys map[string][]string
+	// NodeLabels are the labels the Kubernetes node of the instance registers with,
+	// as comma separated list of key=value pairs. Only set for instances of worker groups.
+	NodeLabels string
+	// NodeTaints are the taints the Kubernetes node of the instance registers with,
+	// as comma separated list in the format key[=value]:effect. Only set for instances of worker groups.
+	NodeTaints string
 }
 
 type InstanceSelfer interface {
@@ -11,6 +11,8 @@ import (
 	"net/http"
 	"net/url"
 	"strings"
+
+	"github.com/edgelesssys/constellation/v2/internal/constants"
 )
 
 // Logger is a Cloud Logger for QEMU.
@@ -26,7 +28,7 @@ func NewLogger() *Logger {
 func (l *Logger) Disclose(msg string) {
 	url := &url.URL{
 		Scheme: "http",
-		Host:   qemuMetadataEndpoint,
+		Host:   constants.QEMUMetadataEndpoint,
 		Path:   "/log",
 	}
 
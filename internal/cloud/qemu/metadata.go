@@ -15,10 +15,9 @@ import (
 	"net/url"
 
 	"github.com/edgelesssys/constellation/v2/internal/cloud/metadata"
+	"github.com/edgelesssys/constellation/v2/internal/constants"
 )
 
-const qemuMetadataEndpoint = "10.42.0.1:8080"
-
 // Metadata implements core.ProviderMetadata interface for QEMU.
 type Metadata struct{}
 
@@ -91,7 +90,7 @@ func (m Metadata) GetSubnetworkCIDR(ctx context.Context) (string, error) {
 func (m Metadata) retrieveMetadata(ctx context.Context, uri string) ([]byte, error) {
 	url := &url.URL{
 		Scheme: "http",
-		Host:   qemuMetadataEndpoint,
+		Host:   constants.QEMUMetadataEndpoint,
 		Path:   uri,
 	}
 
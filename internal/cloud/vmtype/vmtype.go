@@ -17,6 +17,7 @@ const (
 	Unknown VMType = iota
 	AzureCVM
 	AzureTrustedLaunch
+	QEMUTDX
 )
 
 // FromString returns a VMType from a string.
@@ -27,6 +28,8 @@ func FromString(s string) VMType {
 		return AzureCVM
 	case "azuretrustedlaunch":
 		return AzureTrustedLaunch
+	case "qemutdx":
+		return QEMUTDX
 	default:
 		return Unknown
 	}
@@ -11,11 +11,12 @@ func _() {
 	_ = x[Unknown-0]
 	_ = x[AzureCVM-1]
 	_ = x[AzureTrustedLaunch-2]
+	_ = x[QEMUTDX-3]
 }
 
-const _VMType_name = "UnknownAzureCVMAzureTrustedLaunch"
+const _VMType_name = "UnknownAzureCVMAzureTrustedLaunchQEMUTDX"
 
-var _VMType_index = [...]uint8{0, 7, 15, 33}
+var _VMType_index = [...]uint8{0, 7, 15, 33, 40}
 
 func (i VMType) String() string {
 	if i >= VMType(len(_VMType_index)-1) {
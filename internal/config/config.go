@@ -17,6 +17,8 @@ import (
 	"regexp"
 	"strings"
 
+	"github.com/edgelesssys/constellation/v2/internal/attestation/policy"
+	"github.com/edgelesssys/constellation/v2/internal/attestation/qemu"
 	"github.com/edgelesssys/constellation/v2/internal/cloud/cloudprovider"
 	"github.com/edgelesssys/constellation/v2/internal/config/instancetypes"
 	"github.com/edgelesssys/constellation/v2/internal/file"
@@ -25,12 +27,22 @@ import (
 	ut "github.com/go-playground/universal-translator"
 	"github.com/go-playground/validator/v10"
 	en_translations "github.com/go-playground/validator/v10/translations/en"
+	"k8s.io/apimachinery/pkg/util/validation"
 )
 
 const (
 	Version1 = "v1"
 )
 
+var (
+	// taintRegexp matches node taints in the format key[=value]:effect.
+	taintRegexp = regexp.MustCompile(`^([a-z0-9A-Z.-]+/)?[a-z0-9A-Z]([-a-z0-9A-Z_.]*[a-z0-9A-Z])?(=[-a-z0-9A-Z_.]*)?:(NoSchedule|PreferNoSchedule|NoExecute)$`)
+	// azureStateDiskTypes are the supported types of state disks on Azure.
+	azureStateDiskTypes = []string{"Premium_LRS", "Premium_ZRS", "Standard_LRS", "StandardSSD_LRS", "StandardSSD_ZRS"}
+	// gcpStateDiskTypes are the supported types of state disks on GCP.
+	gcpStateDiskTypes = []string{"pd-standard", "pd-balanced", "pd-ssd"}
+)
+
 // Config defines configuration used by CLI.
 type Config struct {
 	// description: |
@@ -58,6 +70,19 @@ type Config struct {
 	// examples:
 	//   - value: 'UpgradeConfig{ Image: "", Measurements: Measurements{} }'
 	Upgrade UpgradeConfig `yaml:"upgrade,omitempty"`
+	// description: |
+	//   Attestation policy as CEL expression, evaluated in addition to the expected measurements. Nodes are only trusted if the policy evaluates to true. For usage, see: https://github.com/google/cel-spec
+	// examples:
+	//   - value: '"has(claims.snp) && claims.snp.reportedTCB.snp >= 8"'
+	AttestationPolicy string `yaml:"attestationPolicy,omitempty" validate:"omitempty,attestation_policy"`
+	// description: |
+	//   Named groups of worker nodes. Each group is created as a separate instance group (GCP) or scale set (Azure) and is scaled independently by the cluster autoscaler. If empty, a single worker group using the provider's instance type is created.
+	// examples:
+	//   - value: '[]WorkerGroup{ { Name: "highmem", InstanceType: "n2d-highmem-4", Min: 1, Max: 5, Labels: map[string]string{"pool": "highmem"}, Taints: []string{"pool=highmem:NoSchedule"} } }'
+	WorkerGroups []WorkerGroup `yaml:"workerGroups,omitempty" validate:"dive"`
+	// description: |
+	//   Validity of the kubelet certificates issued to nodes, in days. Nodes renew their certificate before it expires. Defaults to 365 days if unset.
+	KubeletCertificateValidityDays int `yaml:"kubeletCertificateValidityDays,omitempty" validate:"min=0"`
 }
 
 // UpgradeConfig defines configuration used during constellation upgrade.
@@ -68,6 +93,9 @@ type UpgradeConfig struct {
 	// description: |
 	//   Measurements of the updated image.
 	Measurements Measurements `yaml:"measurements"`
+	// description: |
+	//   Kubernetes version to upgrade all nodes to. Leave empty to keep the current version.
+	KubernetesVersion string `yaml:"kubernetesVersion,omitempty" validate:"omitempty,supported_k8s_version"`
 }
 
 // UserKey describes a user that should be created with corresponding public SSH key.
@@ -80,6 +108,34 @@ type UserKey struct {
 	PublicKey string `yaml:"publicKey" validate:"required"`
 }
 
+// WorkerGroup is a named group of worker nodes sharing an instance type and scaling limits.
+type WorkerGroup struct {
+	// description: |
+	//   Name of the worker group. Must consist of at most 10 lowercase alphanumeric characters.
+	Name string `yaml:"name" validate:"required,lowercase,alphanum,max=10"`
+	// description: |
+	//   VM instance type to use for the group's nodes.
+	InstanceType string `yaml:"instanceType" validate:"required"`
+	// description: |
+	//   Size (in GB) of a node's disk to store the non-volatile state. Defaults to stateDiskSizeGB.
+	StateDiskSizeGB int `yaml:"stateDiskSizeGB,omitempty" validate:"min=0"`
+	// description: |
+	//   Type of a node's state disk. Defaults to the stateDiskType of the provider.
+	StateDiskType string `yaml:"stateDiskType,omitempty"`
+	// description: |
+	//   Minimum number of nodes in the group. The group is created with this number of nodes.
+	Min int `yaml:"min" validate:"min=0"`
+	// description: |
+	//   Maximum number of nodes in the group.
+	Max int `yaml:"max" validate:"min=1,gtefield=Min"`
+	// description: |
+	//   Labels to set on the group's nodes. Keys in the kubernetes.io and k8s.io namespaces are reserved.
+	Labels map[string]string `yaml:"labels,omitempty" validate:"dive,keys,node_label_key,endkeys,node_label_value"`
+	// description: |
+	//   Taints to set on the group's nodes, in the format key[=value]:effect.
+	Taints []string `yaml:"taints,omitempty" validate:"dive,taint"`
+}
+
 // ProviderConfig are cloud-provider specific configuration values used by the CLI.
 // Fields should remain pointer-types so custom specific configs can nil them
 // if not required.
@@ -140,7 +196,7 @@ type AzureConfig struct {
 	//   Enforce the specified idKeyDigest value during remote attestation.
 	EnforceIdKeyDigest *bool `yaml:"enforceIdKeyDigest" validate:"required"`
 	// description: |
-	//   Use Confidential VMs. If set to false, Trusted Launch VMs are used instead. See: https://docs.microsoft.com/en-us/azure/confidential-computing/confidential-vm-overview
+	//   Use Confidential VMs. Must be true, Trusted Launch VMs are currently not supported. See: https://docs.microsoft.com/en-us/azure/confidential-computing/confidential-vm-overview
 	ConfidentialVM *bool `yaml:"confidentialVM" validate:"required"`
 }
 
@@ -200,6 +256,12 @@ type QEMUConfig struct {
 	// description: |
 	//   List of values that should be enforced to be equal to the ones from the measurement list. Any non-equal values not in this list will only result in a warning.
 	EnforcedMeasurements []uint32 `yaml:"enforcedMeasurements"`
+	// description: |
+	//   Use Intel TDX guests. If set to true, measurements index 0 holds the expected MRTD and indices 1 to 4 the expected RTMR[0-3] values instead of PCRs. Launch the guests with MRCONFIGID set to the SHA-384 digest of their provider ID (qemu:///hostname/<name>), otherwise their attestation doesn't report an instance ID and the join service rejects them.
+	TDX *bool `yaml:"tdx"`
+	// description: |
+	//   PEM encoded certificates of the local CA issuing the swtpm EK certificates, e.g., the swtpm-localca root certificate. The QEMU metadata API certifies the attestation keys of the VMs using this CA after proving they reside in a TPM with a certified EK. Attestation keys without such a certificate are rejected. Required to initialize and verify the cluster unless TDX is used.
+	EKCertificateAuthority string `yaml:"ekCertificateAuthority,omitempty" validate:"omitempty,ek_ca"`
 }
 
 // Default returns a struct with the default config.
@@ -243,6 +305,7 @@ func Default() *Config {
 				Measurements:         copyPCRMap(qemuPCRs),
 				MetadataAPIImage:     "ghcr.io/edgelesssys/constellation/qemu-metadata-api:v2.1.0-pre.0.20220922072347-abb78344bc2a",
 				EnforcedMeasurements: []uint32{11, 12},
+				TDX:                  func() *bool { b := false; return &b }(),
 			},
 		},
 		KubernetesVersion: string(versions.Default),
@@ -268,6 +331,44 @@ func validateGCPInstanceType(fl validator.FieldLevel) bool {
 	return validInstanceTypeForProvider(fl.Field().String(), false, cloudprovider.GCP)
 }
 
+func validateTaint(fl validator.FieldLevel) bool {
+	return taintRegexp.MatchString(fl.Field().String())
+}
+
+// validateNodeLabelKey checks that a node label key is a qualified name outside of the
+// kubernetes.io and k8s.io namespaces, which are reserved for Kubernetes and Constellation.
+func validateNodeLabelKey(fl validator.FieldLevel) bool {
+	key := fl.Field().String()
+	if len(validation.IsQualifiedName(key)) > 0 || strings.Contains(key, ",") {
+		return false
+	}
+	prefix, _, found := strings.Cut(key, "/")
+	if !found {
+		return true
+	}
+	for _, reserved := range []string{"kubernetes.io", "k8s.io"} {
+		if prefix == reserved || strings.HasSuffix(prefix, "."+reserved) {
+			return false
+		}
+	}
+	return true
+}
+
+func validateNodeLabelValue(fl validator.FieldLevel) bool {
+	value := fl.Field().String()
+	return len(validation.IsValidLabelValue(value)) == 0 && !strings.Contains(value, ",")
+}
+
+func validateEKCertificateAuthority(fl validator.FieldLevel) bool {
+	_, err := qemu.ParseEKRoots(fl.Field().String())
+	return err == nil
+}
+
+func validateAttestationPolicy(fl validator.FieldLevel) bool {
+	_, err := policy.New(fl.Field().String())
+	return err == nil
+}
+
 // validateProvider checks if zero or more than one providers are defined in the config.
 func validateProvider(sl validator.StructLevel) {
 	provider := sl.Current().Interface().(ProviderConfig)
@@ -290,6 +391,40 @@ func validateProvider(sl validator.StructLevel) {
 	}
 }
 
+// validateWorkerGroups checks that worker group names are unique and that instance and disk types match the provider.
+func validateWorkerGroups(sl validator.StructLevel) {
+	c := sl.Current().Interface().(Config)
+	if len(c.WorkerGroups) == 0 {
+		return
+	}
+
+	provider := c.GetProvider()
+	if provider != cloudprovider.Azure && provider != cloudprovider.GCP {
+		sl.ReportError(c.WorkerGroups, "WorkerGroups", "WorkerGroups", "worker_groups_unsupported", provider.String())
+		return
+	}
+	acceptNonCVM := c.IsAzureNonCVM()
+	diskTypes := gcpStateDiskTypes
+	if provider == cloudprovider.Azure {
+		diskTypes = azureStateDiskTypes
+	}
+
+	names := make(map[string]struct{}, len(c.WorkerGroups))
+	for i, group := range c.WorkerGroups {
+		fieldName := fmt.Sprintf("WorkerGroups[%d]", i)
+		if _, ok := names[group.Name]; ok {
+			sl.ReportError(group.Name, fieldName+".Name", "Name", "worker_group_duplicate_name", group.Name)
+		}
+		names[group.Name] = struct{}{}
+		if !validInstanceTypeForProvider(group.InstanceType, acceptNonCVM, provider) {
+			sl.ReportError(group.InstanceType, fieldName+".InstanceType", "InstanceType", "worker_group_instance_type", provider.String())
+		}
+		if group.StateDiskType != "" && !containsString(diskTypes, group.StateDiskType) {
+			sl.ReportError(group.StateDiskType, fieldName+".StateDiskType", "StateDiskType", "worker_group_disk_type", strings.Join(diskTypes, " "))
+		}
+	}
+}
+
 // Validate checks the config values and returns validation error messages.
 // The function only returns an error if the validation itself fails.
 func (c *Config) Validate() ([]string, error) {
@@ -308,6 +443,43 @@ func (c *Config) Validate() ([]string, error) {
 		return nil, err
 	}
 
+	if err := validate.RegisterTranslation("attestation_policy", trans, registerTranslateAttestationPolicyError, translateAttestationPolicyError); err != nil {
+		return nil, err
+	}
+
+	if err := validate.RegisterTranslation("ek_ca", trans, registerTranslateEKCertificateAuthorityError, translateEKCertificateAuthorityError); err != nil {
+		return nil, err
+	}
+
+	if err := validate.RegisterTranslation("taint", trans, registerTranslateTaintError, translateTaintError); err != nil {
+		return nil, err
+	}
+
+	if err := validate.RegisterTranslation("node_label_key", trans, registerTranslateNodeLabelKeyError, translateNodeLabelError("node_label_key")); err != nil {
+		return nil, err
+	}
+
+	if err := validate.RegisterTranslation("node_label_value", trans, registerTranslateNodeLabelValueError, translateNodeLabelError("node_label_value")); err != nil {
+		return nil, err
+	}
+
+	// Register worker group validation error types
+	if err := validate.RegisterTranslation("worker_groups_unsupported", trans, registerWorkerGroupsUnsupportedError, translateWorkerGroupError("worker_groups_unsupported")); err != nil {
+		return nil, err
+	}
+
+	if err := validate.RegisterTranslation("worker_group_duplicate_name", trans, registerWorkerGroupDuplicateNameError, translateWorkerGroupError("worker_group_duplicate_name")); err != nil {
+		return nil, err
+	}
+
+	if err := validate.RegisterTranslation("worker_group_instance_type", trans, registerWorkerGroupInstanceTypeError, c.translateWorkerGroupInstanceTypeError); err != nil {
+		return nil, err
+	}
+
+	if err := validate.RegisterTranslation("worker_group_disk_type", trans, registerWorkerGroupDiskTypeError, translateWorkerGroupError("worker_group_disk_type")); err != nil {
+		return nil, err
+	}
+
 	// Register Provider validation error types
 	if err := validate.RegisterTranslation("no_provider", trans, registerNoProviderError, translateNoProviderError); err != nil {
 		return nil, err
@@ -332,9 +504,36 @@ func (c *Config) Validate() ([]string, error) {
 		return nil, err
 	}
 
+	// register custom validator with label attestation_policy to validate the policy compiles.
+	if err := validate.RegisterValidation("attestation_policy", validateAttestationPolicy); err != nil {
+		return nil, err
+	}
+
+	// register custom validator with label ek_ca to validate the EK CA certificates can be parsed.
+	if err := validate.RegisterValidation("ek_ca", validateEKCertificateAuthority); err != nil {
+		return nil, err
+	}
+
+	// register custom validator with label taint to validate the format of node taints.
+	if err := validate.RegisterValidation("taint", validateTaint); err != nil {
+		return nil, err
+	}
+
+	// register custom validators with labels node_label_key and node_label_value to validate node labels.
+	if err := validate.RegisterValidation("node_label_key", validateNodeLabelKey); err != nil {
+		return nil, err
+	}
+
+	if err := validate.RegisterValidation("node_label_value", validateNodeLabelValue); err != nil {
+		return nil, err
+	}
+
 	// Register provider validation
 	validate.RegisterStructValidation(validateProvider, ProviderConfig{})
 
+	// Register worker group validation
+	validate.RegisterStructValidation(validateWorkerGroups, Config{})
+
 	err := validate.Struct(c)
 	if err == nil {
 		return nil, nil
@@ -379,6 +578,102 @@ func translateGCPInstanceTypeError(ut ut.Translator, fe validator.FieldError) st
 	return t
 }
 
+// Validation translation functions for attestation policy errors.
+func registerTranslateAttestationPolicyError(ut ut.Translator) error {
+	return ut.Add("attestation_policy", "{0} is not a valid attestation policy: {1}", true)
+}
+
+func translateAttestationPolicyError(ut ut.Translator, fe validator.FieldError) string {
+	var reason string
+	if _, err := policy.New(fe.Value().(string)); err != nil {
+		reason = err.Error()
+	}
+	t, _ := ut.T("attestation_policy", fe.Field(), reason)
+
+	return t
+}
+
+// Validation translation functions for EK CA errors.
+func registerTranslateEKCertificateAuthorityError(ut ut.Translator) error {
+	return ut.Add("ek_ca", "{0} must contain PEM encoded CA certificates: {1}", true)
+}
+
+func translateEKCertificateAuthorityError(ut ut.Translator, fe validator.FieldError) string {
+	var reason string
+	if _, err := qemu.ParseEKRoots(fe.Value().(string)); err != nil {
+		reason = err.Error()
+	}
+	t, _ := ut.T("ek_ca", fe.Field(), reason)
+
+	return t
+}
+
+// Validation translation functions for taint errors.
+func registerTranslateTaintError(ut ut.Translator) error {
+	return ut.Add("taint", "{0} must have the format key[=value]:effect with effect one of NoSchedule, PreferNoSchedule, NoExecute", true)
+}
+
+func translateTaintError(ut ut.Translator, fe validator.FieldError) string {
+	t, _ := ut.T("taint", fe.Field())
+
+	return t
+}
+
+// Validation translation functions for node label errors.
+func registerTranslateNodeLabelKeyError(ut ut.Translator) error {
+	return ut.Add("node_label_key", "{0}: label key {1} must be a qualified name without commas and outside of the kubernetes.io and k8s.io namespaces", true)
+}
+
+func registerTranslateNodeLabelValueError(ut ut.Translator) error {
+	return ut.Add("node_label_value", "{0}: label value {1} must be a valid label value without commas", true)
+}
+
+func translateNodeLabelError(tag string) validator.TranslationFunc {
+	return func(ut ut.Translator, fe validator.FieldError) string {
+		t, _ := ut.T(tag, fe.Field(), fmt.Sprint(fe.Value()))
+
+		return t
+	}
+}
+
+// Validation translation functions for worker group errors.
+func registerWorkerGroupsUnsupportedError(ut ut.Translator) error {
+	return ut.Add("worker_groups_unsupported", "{0}: Worker groups are not supported for provider {1}", true)
+}
+
+func registerWorkerGroupDuplicateNameError(ut ut.Translator) error {
+	return ut.Add("worker_group_duplicate_name", "{0}: Worker group name {1} is used more than once", true)
+}
+
+func registerWorkerGroupInstanceTypeError(ut ut.Translator) error {
+	return ut.Add("worker_group_instance_type", "{0} must be one of {1}", true)
+}
+
+func registerWorkerGroupDiskTypeError(ut ut.Translator) error {
+	return ut.Add("worker_group_disk_type", "{0} must be one of [{1}]", true)
+}
+
+func translateWorkerGroupError(tag string) validator.TranslationFunc {
+	return func(ut ut.Translator, fe validator.FieldError) string {
+		t, _ := ut.T(tag, fe.Field(), fe.Param())
+
+		return t
+	}
+}
+
+func (c *Config) translateWorkerGroupInstanceTypeError(ut ut.Translator, fe validator.FieldError) string {
+	instanceTypes := instancetypes.GCPInstanceTypes
+	if c.GetProvider() == cloudprovider.Azure {
+		instanceTypes = instancetypes.AzureCVMInstanceTypes
+		if c.IsAzureNonCVM() {
+			instanceTypes = instancetypes.AzureTrustedLaunchInstanceTypes
+		}
+	}
+	t, _ := ut.T("worker_group_instance_type", fe.Field(), fmt.Sprintf("%v", instanceTypes))
+
+	return t
+}
+
 // Validation translation functions for Provider errors.
 func registerNoProviderError(ut ut.Translator) error {
 	return ut.Add("no_provider", "{0}: No provider has been defined (requires either Azure, GCP or QEMU)", true)
@@ -522,6 +817,15 @@ func FromFile(fileHandler file.Handler, name string) (*Config, error) {
 	return &conf, nil
 }
 
+func containsString(list []string, s string) bool {
+	for _, item := range list {
+		if item == s {
+			return true
+		}
+	}
+	return false
+}
+
 func copyPCRMap(m map[uint32][]byte) map[uint32][]byte {
 	res := make(Measurements)
 	res.CopyFrom(m)
@@ -14,6 +14,7 @@ var (
 	ConfigDoc         encoder.Doc
 	UpgradeConfigDoc  encoder.Doc
 	UserKeyDoc        encoder.Doc
+	WorkerGroupDoc    encoder.Doc
 	ProviderConfigDoc encoder.Doc
 	AzureConfigDoc    encoder.Doc
 	GCPConfigDoc      encoder.Doc
@@ -24,7 +25,7 @@ func init() {
 	ConfigDoc.Type = "Config"
 	ConfigDoc.Comments[encoder.LineComment] = "Config defines configuration used by CLI."
 	ConfigDoc.Description = "Config defines configuration used by CLI."
-	ConfigDoc.Fields = make([]encoder.Doc, 7)
+	ConfigDoc.Fields = make([]encoder.Doc, 10)
 	ConfigDoc.Fields[0].Name = "version"
 	ConfigDoc.Fields[0].Type = "string"
 	ConfigDoc.Fields[0].Note = ""
@@ -64,6 +65,25 @@ func init() {
 	ConfigDoc.Fields[6].Comments[encoder.LineComment] = "Configuration to apply during constellation upgrade."
 
 	ConfigDoc.Fields[6].AddExample("", UpgradeConfig{Image: "", Measurements: Measurements{}})
+	ConfigDoc.Fields[7].Name = "attestationPolicy"
+	ConfigDoc.Fields[7].Type = "string"
+	ConfigDoc.Fields[7].Note = ""
+	ConfigDoc.Fields[7].Description = "Attestation policy as CEL expression, evaluated in addition to the expected measurements. Nodes are only trusted if the policy evaluates to true. For usage, see: https://github.com/google/cel-spec"
+	ConfigDoc.Fields[7].Comments[encoder.LineComment] = "Attestation policy as CEL expression, evaluated in addition to the expected measurements. Nodes are only trusted if the policy evaluates to true. For usage, see: https://github.com/google/cel-spec"
+
+	ConfigDoc.Fields[7].AddExample("", "has(claims.snp) && claims.snp.reportedTCB.snp >= 8")
+	ConfigDoc.Fields[8].Name = "workerGroups"
+	ConfigDoc.Fields[8].Type = "[]WorkerGroup"
+	ConfigDoc.Fields[8].Note = ""
+	ConfigDoc.Fields[8].Description = "Named groups of worker nodes. Each group is created as a separate instance group (GCP) or scale set (Azure) and is scaled independently by the cluster autoscaler. If empty, a single worker group using the provider's instance type is created."
+	ConfigDoc.Fields[8].Comments[encoder.LineComment] = "Named groups of worker nodes. Each group is created as a separate instance group (GCP) or scale set (Azure) and is scaled independently by the cluster autoscaler. If empty, a single worker group using the provider's instance type is created."
+
+	ConfigDoc.Fields[8].AddExample("", []WorkerGroup{{Name: "highmem", InstanceType: "n2d-highmem-4", Min: 1, Max: 5, Labels: map[string]string{"pool": "highmem"}, Taints: []string{"pool=highmem:NoSchedule"}}})
+	ConfigDoc.Fields[9].Name = "kubeletCertificateValidityDays"
+	ConfigDoc.Fields[9].Type = "int"
+	ConfigDoc.Fields[9].Note = ""
+	ConfigDoc.Fields[9].Description = "Validity of the kubelet certificates issued to nodes, in days. Nodes renew their certificate before it expires. Defaults to 365 days if unset."
+	ConfigDoc.Fields[9].Comments[encoder.LineComment] = "Validity of the kubelet certificates issued to nodes, in days. Nodes renew their certificate before it expires. Defaults to 365 days if unset."
 
 	UpgradeConfigDoc.Type = "UpgradeConfig"
 	UpgradeConfigDoc.Comments[encoder.LineComment] = "UpgradeConfig defines configuration used during constellation upgrade."
@@ -76,7 +96,7 @@ func init() {
 			FieldName: "upgrade",
 		},
 	}
-	UpgradeConfigDoc.Fields = make([]encoder.Doc, 2)
+	UpgradeConfigDoc.Fields = make([]encoder.Doc, 3)
 	UpgradeConfigDoc.Fields[0].Name = "image"
 	UpgradeConfigDoc.Fields[0].Type = "string"
 	UpgradeConfigDoc.Fields[0].Note = ""
@@ -87,6 +107,11 @@ func init() {
 	UpgradeConfigDoc.Fields[1].Note = ""
 	UpgradeConfigDoc.Fields[1].Description = "Measurements of the updated image."
 	UpgradeConfigDoc.Fields[1].Comments[encoder.LineComment] = "Measurements of the updated image."
+	UpgradeConfigDoc.Fields[2].Name = "kubernetesVersion"
+	UpgradeConfigDoc.Fields[2].Type = "string"
+	UpgradeConfigDoc.Fields[2].Note = ""
+	UpgradeConfigDoc.Fields[2].Description = "Kubernetes version to upgrade all nodes to. Leave empty to keep the current version."
+	UpgradeConfigDoc.Fields[2].Comments[encoder.LineComment] = "Kubernetes version to upgrade all nodes to. Leave empty to keep the current version."
 
 	UserKeyDoc.Type = "UserKey"
 	UserKeyDoc.Comments[encoder.LineComment] = "UserKey describes a user that should be created with corresponding public SSH key."
@@ -111,6 +136,59 @@ func init() {
 	UserKeyDoc.Fields[1].Description = "Public key of new SSH user."
 	UserKeyDoc.Fields[1].Comments[encoder.LineComment] = "Public key of new SSH user."
 
+	WorkerGroupDoc.Type = "WorkerGroup"
+	WorkerGroupDoc.Comments[encoder.LineComment] = "WorkerGroup is a named group of worker nodes sharing an instance type and scaling limits."
+	WorkerGroupDoc.Description = "WorkerGroup is a named group of worker nodes sharing an instance type and scaling limits."
+
+	WorkerGroupDoc.AddExample("", []WorkerGroup{{Name: "highmem", InstanceType: "n2d-highmem-4", Min: 1, Max: 5, Labels: map[string]string{"pool": "highmem"}, Taints: []string{"pool=highmem:NoSchedule"}}})
+	WorkerGroupDoc.AppearsIn = []encoder.Appearance{
+		{
+			TypeName:  "Config",
+			FieldName: "workerGroups",
+		},
+	}
+	WorkerGroupDoc.Fields = make([]encoder.Doc, 8)
+	WorkerGroupDoc.Fields[0].Name = "name"
+	WorkerGroupDoc.Fields[0].Type = "string"
+	WorkerGroupDoc.Fields[0].Note = ""
+	WorkerGroupDoc.Fields[0].Description = "Name of the worker group. Must consist of at most 10 lowercase alphanumeric characters."
+	WorkerGroupDoc.Fields[0].Comments[encoder.LineComment] = "Name of the worker group. Must consist of at most 10 lowercase alphanumeric characters."
+	WorkerGroupDoc.Fields[1].Name = "instanceType"
+	WorkerGroupDoc.Fields[1].Type = "string"
+	WorkerGroupDoc.Fields[1].Note = ""
+	WorkerGroupDoc.Fields[1].Description = "VM instance type to use for the group's nodes."
+	WorkerGroupDoc.Fields[1].Comments[encoder.LineComment] = "VM instance type to use for the group's nodes."
+	WorkerGroupDoc.Fields[2].Name = "stateDiskSizeGB"
+	WorkerGroupDoc.Fields[2].Type = "int"
+	WorkerGroupDoc.Fields[2].Note = ""
+	WorkerGroupDoc.Fields[2].Description = "Size (in GB) of a node's disk to store the non-volatile state. Defaults to stateDiskSizeGB."
+	WorkerGroupDoc.Fields[2].Comments[encoder.LineComment] = "Size (in GB) of a node's disk to store the non-volatile state. Defaults to stateDiskSizeGB."
+	WorkerGroupDoc.Fields[3].Name = "stateDiskType"
+	WorkerGroupDoc.Fields[3].Type = "string"
+	WorkerGroupDoc.Fields[3].Note = ""
+	WorkerGroupDoc.Fields[3].Description = "Type of a node's state disk. Defaults to the stateDiskType of the provider."
+	WorkerGroupDoc.Fields[3].Comments[encoder.LineComment] = "Type of a node's state disk. Defaults to the stateDiskType of the provider."
+	WorkerGroupDoc.Fields[4].Name = "min"
+	WorkerGroupDoc.Fields[4].Type = "int"
+	WorkerGroupDoc.Fields[4].Note = ""
+	WorkerGroupDoc.Fields[4].Description = "Minimum number of nodes in the group. The group is created with this number of nodes."
+	WorkerGroupDoc.Fields[4].Comments[encoder.LineComment] = "Minimum number of nodes in the group. The group is created with this number of nodes."
+	WorkerGroupDoc.Fields[5].Name = "max"
+	WorkerGroupDoc.Fields[5].Type = "int"
+	WorkerGroupDoc.Fields[5].Note = ""
+	WorkerGroupDoc.Fields[5].Description = "Maximum number of nodes in the group."
+	WorkerGroupDoc.Fields[5].Comments[encoder.LineComment] = "Maximum number of nodes in the group."
+	WorkerGroupDoc.Fields[6].Name = "labels"
+	WorkerGroupDoc.Fields[6].Type = "map[string]string"
+	WorkerGroupDoc.Fields[6].Note = ""
+	WorkerGroupDoc.Fields[6].Description = "Labels to set on the group's nodes. Keys in the kubernetes.io and k8s.io namespaces are reserved."
+	WorkerGroupDoc.Fields[6].Comments[encoder.LineComment] = "Labels to set on the group's nodes. Keys in the kubernetes.io and k8s.io namespaces are reserved."
+	WorkerGroupDoc.Fields[7].Name = "taints"
+	WorkerGroupDoc.Fields[7].Type = "[]string"
+	WorkerGroupDoc.Fields[7].Note = ""
+	WorkerGroupDoc.Fields[7].Description = "Taints to set on the group's nodes, in the format key[=value]:effect."
+	WorkerGroupDoc.Fields[7].Comments[encoder.LineComment] = "Taints to set on the group's nodes, in the format key[=value]:effect."
+
 	ProviderConfigDoc.Type = "ProviderConfig"
 	ProviderConfigDoc.Comments[encoder.LineComment] = "ProviderConfig are cloud-provider specific configuration values used by the CLI."
 	ProviderConfigDoc.Description = "ProviderConfig are cloud-provider specific configuration values used by the CLI.\nFields should remain pointer-types so custom specific configs can nil them\nif not required.\n"
@@ -220,8 +298,8 @@ func init() {
 	AzureConfigDoc.Fields[14].Name = "confidentialVM"
 	AzureConfigDoc.Fields[14].Type = "bool"
 	AzureConfigDoc.Fields[14].Note = ""
-	AzureConfigDoc.Fields[14].Description = "Use Confidential VMs. If set to false, Trusted Launch VMs are used instead. See: https://docs.microsoft.com/en-us/azure/confidential-computing/confidential-vm-overview"
-	AzureConfigDoc.Fields[14].Comments[encoder.LineComment] = "Use Confidential VMs. If set to false, Trusted Launch VMs are used instead. See: https://docs.microsoft.com/en-us/azure/confidential-computing/confidential-vm-overview"
+	AzureConfigDoc.Fields[14].Description = "Use Confidential VMs. Must be true, Trusted Launch VMs are currently not supported. See: https://docs.microsoft.com/en-us/azure/confidential-computing/confidential-vm-overview"
+	AzureConfigDoc.Fields[14].Comments[encoder.LineComment] = "Use Confidential VMs. Must be true, Trusted Launch VMs are currently not supported. See: https://docs.microsoft.com/en-us/azure/confidential-computing/confidential-vm-overview"
 
 	GCPConfigDoc.Type = "GCPConfig"
 	GCPConfigDoc.Comments[encoder.LineComment] = "GCPConfig are GCP specific configuration values used by the CLI."
@@ -288,7 +366,7 @@ func init() {
 			FieldName: "qemu",
 		},
 	}
-	QEMUConfigDoc.Fields = make([]encoder.Doc, 8)
+	QEMUConfigDoc.Fields = make([]encoder.Doc, 10)
 	QEMUConfigDoc.Fields[0].Name = "image"
 	QEMUConfigDoc.Fields[0].Type = "string"
 	QEMUConfigDoc.Fields[0].Note = ""
@@ -329,6 +407,16 @@ func init() {
 	QEMUConfigDoc.Fields[7].Note = ""
 	QEMUConfigDoc.Fields[7].Description = "List of values that should be enforced to be equal to the ones from the measurement list. Any non-equal values not in this list will only result in a warning."
 	QEMUConfigDoc.Fields[7].Comments[encoder.LineComment] = "List of values that should be enforced to be equal to the ones from the measurement list. Any non-equal values not in this list will only result in a warning."
+	QEMUConfigDoc.Fields[8].Name = "tdx"
+	QEMUConfigDoc.Fields[8].Type = "bool"
+	QEMUConfigDoc.Fields[8].Note = ""
+	QEMUConfigDoc.Fields[8].Description = "Use Intel TDX guests. If set to true, measurements index 0 holds the expected MRTD and indices 1 to 4 the expected RTMR[0-3] values instead of PCRs. Launch the guests with MRCONFIGID set to the SHA-384 digest of their provider ID (qemu:///hostname/<name>), otherwise their attestation doesn't report an instance ID and the join service rejects them."
+	QEMUConfigDoc.Fields[8].Comments[encoder.LineComment] = "Use Intel TDX guests. If set to true, measurements index 0 holds the expected MRTD and indices 1 to 4 the expected RTMR[0-3] values instead of PCRs. Launch the guests with MRCONFIGID set to the SHA-384 digest of their provider ID (qemu:///hostname/<name>), otherwise their attestation doesn't report an instance ID and the join service rejects them."
+	QEMUConfigDoc.Fields[9].Name = "ekCertificateAuthority"
+	QEMUConfigDoc.Fields[9].Type = "string"
+	QEMUConfigDoc.Fields[9].Note = ""
+	QEMUConfigDoc.Fields[9].Description = "PEM encoded certificates of the local CA issuing the swtpm EK certificates, e.g., the swtpm-localca root certificate. The QEMU metadata API certifies the attestation keys of the VMs using this CA after proving they reside in a TPM with a certified EK. Attestation keys without such a certificate are rejected. Required to initialize and verify the cluster unless TDX is used."
+	QEMUConfigDoc.Fields[9].Comments[encoder.LineComment] = "PEM encoded certificates of the local CA issuing the swtpm EK certificates, e.g., the swtpm-localca root certificate. The QEMU metadata API certifies the attestation keys of the VMs using this CA after proving they reside in a TPM with a certified EK. Attestation keys without such a certificate are rejected. Required to initialize and verify the cluster unless TDX is used."
 }
 
 func (_ Config) Doc() *encoder.Doc {
@@ -343,6 +431,10 @@ func (_ UserKey) Doc() *encoder.Doc {
 	return &UserKeyDoc
 }
 
+func (_ WorkerGroup) Doc() *encoder.Doc {
+	return &WorkerGroupDoc
+}
+
 func (_ ProviderConfig) Doc() *encoder.Doc {
 	return &ProviderConfigDoc
 }
@@ -368,6 +460,7 @@ func GetConfigurationDoc() *encoder.FileDoc {
 			&ConfigDoc,
 			&UpgradeConfigDoc,
 			&UserKeyDoc,
+			&WorkerGroupDoc,
 			&ProviderConfigDoc,
 			&AzureConfigDoc,
 			&GCPConfigDoc,
@@ -182,6 +182,87 @@ func TestValidate(t *testing.T) {
 			}(),
 			wantMsgCount: defaultMsgCount + 2,
 		},
+		"valid attestation policy": {
+			cnf: func() *Config {
+				cnf := Default()
+				cnf.AttestationPolicy = `claims.measurements[4] == "00"`
+				return cnf
+			}(),
+			wantMsgCount: defaultMsgCount,
+		},
+		"invalid attestation policy": {
+			cnf: func() *Config {
+				cnf := Default()
+				cnf.AttestationPolicy = "claims.measurements["
+				return cnf
+			}(),
+			wantMsgCount: defaultMsgCount + 1,
+		},
+		"valid worker groups": {
+			cnf: func() *Config {
+				cnf := Default()
+				cnf.WorkerGroups = []WorkerGroup{
+					{Name: "general", InstanceType: "Standard_DC4as_v5", Min: 1, Max: 3},
+					{
+						Name: "highmem", InstanceType: "Standard_DC8as_v5", StateDiskType: "StandardSSD_LRS", Min: 0, Max: 5,
+						Labels: map[string]string{"pool": "highmem"}, Taints: []string{"pool=highmem:NoSchedule", "example.com/spot:NoExecute"},
+					},
+				}
+				return cnf
+			}(),
+			wantMsgCount: defaultMsgCount,
+		},
+		"invalid worker groups": {
+			cnf: func() *Config {
+				cnf := Default()
+				cnf.WorkerGroups = []WorkerGroup{
+					{Name: "general", InstanceType: "n2d-standard-4", Min: 1, Max: 3},
+					{Name: "general", InstanceType: "Standard_DC4as_v5", StateDiskType: "pd-ssd", Min: 3, Max: 1},
+					{Name: "Spot-Nodes", InstanceType: "Standard_DC4as_v5", Max: 1, Taints: []string{"spot"}},
+				}
+				return cnf
+			}(),
+			// wrong instance type, duplicate name, wrong disk type, max < min, invalid name, invalid taint
+			wantMsgCount: defaultMsgCount + 6,
+		},
+		"invalid worker group labels": {
+			cnf: func() *Config {
+				cnf := Default()
+				cnf.WorkerGroups = []WorkerGroup{
+					{
+						Name: "general", InstanceType: "Standard_DC4as_v5", Min: 1, Max: 3,
+						Labels: map[string]string{
+							"node-role.kubernetes.io/worker": "",
+							"k8s.io/pool":                    "general",
+							"pool,zone":                      "general",
+							"-pool":                          "general",
+							"pool":                           "a,b",
+							"example.com/pool":               "general",
+						},
+					},
+				}
+				return cnf
+			}(),
+			// reserved namespaces (2), comma in key, invalid key, comma in value
+			wantMsgCount: defaultMsgCount + 5,
+		},
+		"worker groups on QEMU": {
+			cnf: func() *Config {
+				cnf := Default()
+				cnf.RemoveProviderExcept(cloudprovider.QEMU)
+				cnf.WorkerGroups = []WorkerGroup{{Name: "general", InstanceType: "2-vCPU", Min: 1, Max: 3}}
+				return cnf
+			}(),
+			wantMsgCount: 2, // image is not set by default, worker groups are unsupported
+		},
+		"invalid EK CA": {
+			cnf: func() *Config {
+				cnf := Default()
+				cnf.Provider.QEMU.EKCertificateAuthority = "not a certificate"
+				return cnf
+			}(),
+			wantMsgCount: defaultMsgCount + 1,
+		},
 	}
 
 	for name, tc := range testCases {
@@ -288,6 +369,7 @@ func TestConfigGeneratedDocsFresh(t *testing.T) {
 	assert.Len(ConfigDoc.Fields, reflect.ValueOf(Config{}).NumField(), updateMsg)
 	assert.Len(UpgradeConfigDoc.Fields, reflect.ValueOf(UpgradeConfig{}).NumField(), updateMsg)
 	assert.Len(UserKeyDoc.Fields, reflect.ValueOf(UserKey{}).NumField(), updateMsg)
+	assert.Len(WorkerGroupDoc.Fields, reflect.ValueOf(WorkerGroup{}).NumField(), updateMsg)
 	assert.Len(ProviderConfigDoc.Fields, reflect.ValueOf(ProviderConfig{}).NumField(), updateMsg)
 	assert.Len(AzureConfigDoc.Fields, reflect.ValueOf(AzureConfig{}).NumField(), updateMsg)
 	assert.Len(GCPConfigDoc.Fields, reflect.ValueOf(GCPConfig{}).NumField(), updateMsg)
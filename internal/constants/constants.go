@@ -25,6 +25,12 @@ const (
 	ConstellationMasterSecretKey = "mastersecret"
 	// ConstellationMasterSecretSalt is the name of the key for salt in the master secret store secret.
 	ConstellationMasterSecretSalt = "salt"
+	// EtcdBackupKeyID is the KMS key ID used by the node operator to encrypt etcd snapshots.
+	EtcdBackupKeyID = "etcd-backup"
+	// EtcdBackupKeyLength is the length of the etcd snapshot encryption key in bytes.
+	EtcdBackupKeyLength = 32
+	// QEMUMetadataEndpoint is the endpoint of the QEMU metadata API, reachable from all VMs of a QEMU cluster.
+	QEMUMetadataEndpoint = "10.42.0.1:8080"
 
 	//
 	// Ports.
@@ -33,7 +39,11 @@ const (
 	// JoinServicePort is the port for reaching the join service within Kubernetes.
 	JoinServicePort = 9090
 	// JoinServiceNodePort is the port for reaching the join service outside of Kubernetes.
-	JoinServiceNodePort       = 30090
+	JoinServiceNodePort = 30090
+	// AttestationVerifierPort is the port of the optional in-cluster attestation verifier of the join service.
+	AttestationVerifierPort = 9091
+	// JoinServiceMetricsPort is the port the join service serves Prometheus metrics on.
+	JoinServiceMetricsPort    = 9092
 	VerifyServicePortHTTP     = 8080
 	VerifyServicePortGRPC     = 9090
 	VerifyServiceNodePortHTTP = 30080
@@ -63,6 +73,7 @@ const (
 	DebugdConfigFilename    = "cdbg-conf.yaml"
 	AdminConfFilename       = "constellation-admin.conf"
 	MasterSecretFilename    = "constellation-mastersecret.json"
+	OwnerKeyFilename        = "cosign.key"
 	WGQuickConfigFilename   = "wg0.conf"
 	CoreOSAdminConfFilename = "/etc/kubernetes/admin.conf"
 	KubeadmCertificateDir   = "/etc/kubernetes/pki"
@@ -85,10 +96,25 @@ const (
 	IdKeyDigestFilename = "idkeydigest"
 	// EnforceIdKeyDigestFilename is the name of the file configuring whether idkeydigest is enforced or not.
 	EnforceIdKeyDigestFilename = "enforceIdKeyDigest"
+	// AttestationPolicyFilename is the name of the file holding the attestation policy evaluated in addition to the measurements.
+	AttestationPolicyFilename = "attestationPolicy"
+	// EKCertificateAuthorityFilename is the name of the file holding the CA certificates trusted to issue swtpm EK certificates.
+	EKCertificateAuthorityFilename = "ekCertificateAuthority"
+	// JoinConfigVersionFilename is the name of the file holding the version of the signed join-config, incremented with every signature.
+	JoinConfigVersionFilename = "joinConfigVersion"
+	// MeasurementsSignatureFilename is the name of the file holding the cluster owner's signature of the measurements and related attestation config.
+	MeasurementsSignatureFilename = "measurementsSignature"
+	// OwnerPublicKeyFilename is the name of the file holding the PEM encoded public key of the cluster owner, pinned at initialization.
+	OwnerPublicKeyFilename = "ownerPublicKey"
 	// AzureCVM is the name of the file indicating whether the cluster is expected to run on CVMs or not.
 	AzureCVM = "azureCVM"
+	// QEMUTDX is the name of the file indicating whether the cluster is expected to run on QEMU Intel TDX guests or not.
+	QEMUTDX = "qemuTDX"
 	// K8sVersion is the filename of the mapped "k8s-version" configMap file.
 	K8sVersion = "k8s-version"
+	// K8sComponents is the filename of the JSON encoded Kubernetes components in the mapped "k8s-version" configMap,
+	// set by the node operator after a Kubernetes upgrade.
+	K8sComponents = "k8s-components"
 
 	//
 	// CLI.
@@ -97,14 +123,62 @@ const (
 	MinControllerCount = 1
 	MinWorkerCount     = 1
 
+	//
+	// Worker groups.
+	//
+
+	// WorkerGroupTag is the key of the tag (Azure) or metadata item (GCP) holding the name of a worker group.
+	WorkerGroupTag = "constellation-worker-group"
+	// WorkerGroupMinTag is the key of the tag or metadata item holding the minimum size of a worker group.
+	WorkerGroupMinTag = "constellation-min-count"
+	// WorkerGroupMaxTag is the key of the tag or metadata item holding the maximum size of a worker group.
+	WorkerGroupMaxTag = "constellation-max-count"
+	// WorkerGroupLabelsTag is the key of the tag or metadata item holding the node labels of a worker group.
+	WorkerGroupLabelsTag = "constellation-node-labels"
+	// WorkerGroupTaintsTag is the key of the tag or metadata item holding the node taints of a worker group.
+	WorkerGroupTaintsTag = "constellation-node-taints"
+
 	//
 	// Kubernetes.
 	//
 
 	KubernetesJoinTokenTTL = 15 * time.Minute
-	ConstellationNamespace = "kube-system"
-	JoinConfigMap          = "join-config"
-	InternalConfigMap      = "internal-config"
+	// KubeletCertificateValidity is the default validity of kubelet certificates issued to nodes.
+	KubeletCertificateValidity = 365 * 24 * time.Hour
+	ConstellationNamespace     = "kube-system"
+	JoinConfigMap              = "join-config"
+	InternalConfigMap          = "internal-config"
+	// OwnerPublicKeyConfigMap is the immutable ConfigMap holding the public key of the cluster owner.
+	OwnerPublicKeyConfigMap = "owner-public-key"
+	// NodeAttestationGroup is the API group of the NodeAttestation custom resource.
+	NodeAttestationGroup = "attestation.edgeless.systems"
+	// NodeAttestationVersion is the API version of the NodeAttestation custom resource.
+	NodeAttestationVersion = "v1alpha1"
+	// NodeAttestationResource is the plural resource name of the NodeAttestation custom resource.
+	NodeAttestationResource = "nodeattestations"
+	// RevokedNodeResource is the plural resource name of the RevokedNode custom resource, which shares the API group of NodeAttestation.
+	RevokedNodeResource = "revokednodes"
+	// JoinRecordResource is the plural resource name of the JoinRecord custom resource, which shares the API group of NodeAttestation.
+	JoinRecordResource = "joinrecords"
+	// AttestationFailedTaintKey is the key of the taint applied to nodes quarantined after failing re-attestation.
+	AttestationFailedTaintKey = "constellation.edgeless.systems/attestation-failed"
+	// AttestationQuarantineCordonAnnotation marks nodes cordoned as quarantine after failing re-attestation.
+	// Only nodes with this annotation are uncordoned when released from quarantine.
+	AttestationQuarantineCordonAnnotation = "constellation.edgeless.systems/attestation-quarantine-cordon"
+	// NodeKubernetesVersionName is the name of the NodeKubernetesVersion custom resource holding the desired Kubernetes version of all nodes.
+	NodeKubernetesVersionName = "constellation-kubernetes"
+	// DiskOwnersConfigMap maps the UUIDs of state disks to the attested identity of the node they belong to.
+	DiskOwnersConfigMap = "disk-owners"
+	// DiskOwnersNodeKeySuffix is appended to a disk UUID to form the key of the disk owners ConfigMap holding the name of the node using the disk.
+	DiskOwnersNodeKeySuffix = ".node"
+	// DiskOwnerRetired is recorded as owner of disks whose node was deleted. Keys of retired disks are never released again.
+	DiskOwnerRetired = "<retired>"
+	// AttestationTokenKeySecret is the name of the Secret holding the signing key of the join service's attestation verifier.
+	AttestationTokenKeySecret = "attestation-token-key"
+	// AttestationTokenKeyFilename is the key of the PEM encoded signing key in AttestationTokenKeySecret.
+	AttestationTokenKeyFilename = "attestationTokenKey"
+	// AttestationTokenKeyPath is the path AttestationTokenKeySecret is mounted to in the join service.
+	AttestationTokenKeyPath = "/var/secrets/attestation-token"
 
 	//
 	// Helm.
@@ -13,11 +13,34 @@ import (
 
 	"github.com/edgelesssys/constellation/v2/internal/atls"
 	"google.golang.org/grpc/credentials"
+	"google.golang.org/grpc/peer"
 )
 
+// AuthInfo is the gRPC AuthInfo of aTLS connections.
+type AuthInfo struct {
+	credentials.TLSInfo
+	// PeerClaims are the claims of the attested peer, or nil if the peer was not attested.
+	PeerClaims *atls.PeerClaims
+}
+
+// PeerClaimsFromContext returns the claims of the attested peer of a gRPC call.
+// The second return value is false if the peer did not connect using aTLS, or was not attested.
+func PeerClaimsFromContext(ctx context.Context) (*atls.PeerClaims, bool) {
+	p, ok := peer.FromContext(ctx)
+	if !ok {
+		return nil, false
+	}
+	authInfo, ok := p.AuthInfo.(AuthInfo)
+	if !ok || authInfo.PeerClaims == nil {
+		return nil, false
+	}
+	return authInfo.PeerClaims, true
+}
+
 type Credentials struct {
 	issuer     atls.Issuer
 	validators []atls.Validator
+	cache      *atls.Cache
 }
 
 func New(issuer atls.Issuer, validators []atls.Validator) *Credentials {
@@ -27,22 +50,52 @@ func New(issuer atls.Issuer, validators []atls.Validator) *Credentials {
 	}
 }
 
+// NewWithCache creates aTLS credentials caching attestations using cache.
+// Clients resume TLS sessions with servers they connected to before, using the authority as key.
+func NewWithCache(issuer atls.Issuer, validators []atls.Validator, cache *atls.Cache) *Credentials {
+	return &Credentials{
+		issuer:     issuer,
+		validators: validators,
+		cache:      cache,
+	}
+}
+
 func (c *Credentials) ClientHandshake(ctx context.Context, authority string, rawConn net.Conn) (net.Conn, credentials.AuthInfo, error) {
-	clientCfg, err := atls.CreateAttestationClientTLSConfig(c.issuer, c.validators)
+	var opts []atls.Option
+	if c.cache != nil {
+		opts = append(opts, atls.WithCache(c.cache), atls.WithSessionKey(authority))
+	}
+
+	var attestedPeer atls.Peer
+	clientCfg, err := atls.CreateAttestationClientTLSConfigForPeer(c.issuer, c.validators, &attestedPeer, opts...)
 	if err != nil {
 		return nil, nil, err
 	}
 
-	return credentials.NewTLS(clientCfg).ClientHandshake(ctx, authority, rawConn)
+	conn, authInfo, err := credentials.NewTLS(clientCfg).ClientHandshake(ctx, authority, rawConn)
+	if err != nil {
+		return nil, nil, err
+	}
+	return conn, newAuthInfo(authInfo, &attestedPeer), nil
 }
 
 func (c *Credentials) ServerHandshake(rawConn net.Conn) (net.Conn, credentials.AuthInfo, error) {
-	serverCfg, err := atls.CreateAttestationServerTLSConfig(c.issuer, c.validators)
+	var opts []atls.Option
+	if c.cache != nil {
+		opts = append(opts, atls.WithCache(c.cache))
+	}
+
+	var attestedPeer atls.Peer
+	serverCfg, err := atls.CreateAttestationServerTLSConfigForPeer(c.issuer, c.validators, &attestedPeer, opts...)
 	if err != nil {
 		return nil, nil, err
 	}
 
-	return credentials.NewTLS(serverCfg).ServerHandshake(rawConn)
+	conn, authInfo, err := credentials.NewTLS(serverCfg).ServerHandshake(rawConn)
+	if err != nil {
+		return nil, nil, err
+	}
+	return conn, newAuthInfo(authInfo, &attestedPeer), nil
 }
 
 func (c *Credentials) Info() credentials.ProtocolInfo {
@@ -54,6 +107,11 @@ func (c *Credentials) Clone() credentials.TransportCredentials {
 	return &cloned
 }
 
+func newAuthInfo(tlsAuthInfo credentials.AuthInfo, attestedPeer *atls.Peer) AuthInfo {
+	tlsInfo, _ := tlsAuthInfo.(credentials.TLSInfo)
+	return AuthInfo{TLSInfo: tlsInfo, PeerClaims: attestedPeer.Claims()}
+}
+
 func (c *Credentials) OverrideServerName(s string) error {
 	return errors.New("cannot override server name")
 }
@@ -14,6 +14,7 @@ import (
 	"errors"
 	"net"
 	"testing"
+	"time"
 
 	"github.com/edgelesssys/constellation/v2/bootstrapper/initproto"
 	"github.com/edgelesssys/constellation/v2/internal/atls"
@@ -21,6 +22,7 @@ import (
 	"github.com/stretchr/testify/require"
 	"go.uber.org/goleak"
 	"google.golang.org/grpc"
+	"google.golang.org/grpc/peer"
 	"google.golang.org/grpc/test/bufconn"
 )
 
@@ -82,6 +84,94 @@ func TestATLSCredentials(t *testing.T) {
 	}
 }
 
+func TestPeerClaimsFromContext(t *testing.T) {
+	assert := assert.New(t)
+	require := require.New(t)
+
+	clientOID := fakeOID{1, 3, 9900, 1}
+	serverOID := fakeOID{1, 3, 9900, 2}
+
+	api := &fakeAPI{}
+	server := grpc.NewServer(grpc.Creds(New(fakeIssuer{fakeOID: serverOID}, []atls.Validator{fakeValidator{fakeOID: clientOID}})))
+	initproto.RegisterAPIServer(server, api)
+	listener := bufconn.Listen(1024)
+	defer server.GracefulStop()
+	go server.Serve(listener)
+
+	clientCreds := New(fakeIssuer{fakeOID: clientOID}, []atls.Validator{fakeValidator{fakeOID: serverOID}})
+	conn, err := grpc.DialContext(context.Background(), "", grpc.WithContextDialer(func(ctx context.Context, addr string) (net.Conn, error) {
+		return listener.Dial()
+	}), grpc.WithTransportCredentials(clientCreds))
+	require.NoError(err)
+	defer conn.Close()
+
+	var serverPeer peer.Peer
+	_, err = initproto.NewAPIClient(conn).Init(context.Background(), &initproto.InitRequest{}, grpc.Peer(&serverPeer))
+	require.NoError(err)
+
+	// the server sees the claims of the client
+	require.NotNil(api.peerClaims)
+	assert.Equal(asn1.ObjectIdentifier(clientOID), api.peerClaims.Variant)
+
+	// the client sees the claims of the server
+	authInfo, ok := serverPeer.AuthInfo.(AuthInfo)
+	require.True(ok)
+	require.NotNil(authInfo.PeerClaims)
+	assert.Equal(asn1.ObjectIdentifier(serverOID), authInfo.PeerClaims.Variant)
+	assert.Equal("tls", authInfo.AuthType())
+}
+
+func TestCachedCredentials(t *testing.T) {
+	assert := assert.New(t)
+	require := require.New(t)
+
+	clientOID := fakeOID{1, 3, 9900, 1}
+	serverOID := fakeOID{1, 3, 9900, 2}
+
+	api := &fakeAPI{}
+	serverCreds := NewWithCache(fakeIssuer{fakeOID: serverOID}, []atls.Validator{fakeValidator{fakeOID: clientOID}}, atls.NewCache(time.Hour))
+	server := grpc.NewServer(grpc.Creds(serverCreds))
+	initproto.RegisterAPIServer(server, api)
+	listener := bufconn.Listen(1024)
+	defer server.GracefulStop()
+	go server.Serve(listener)
+
+	clientCreds := NewWithCache(fakeIssuer{fakeOID: clientOID}, []atls.Validator{fakeValidator{fakeOID: serverOID}}, atls.NewCache(time.Hour))
+
+	// the second connection resumes the session of the first one, and keeps the claims of both peers
+	for i := 0; i < 2; i++ {
+		api.peerClaims = nil
+		conn, err := grpc.DialContext(context.Background(), "passthrough:///server", grpc.WithContextDialer(func(ctx context.Context, addr string) (net.Conn, error) {
+			return listener.Dial()
+		}), grpc.WithTransportCredentials(clientCreds))
+		require.NoError(err)
+
+		var serverPeer peer.Peer
+		_, err = initproto.NewAPIClient(conn).Init(context.Background(), &initproto.InitRequest{}, grpc.Peer(&serverPeer))
+		require.NoError(err)
+		require.NoError(conn.Close())
+
+		require.NotNil(api.peerClaims)
+		assert.Equal(asn1.ObjectIdentifier(clientOID), api.peerClaims.Variant)
+		authInfo, ok := serverPeer.AuthInfo.(AuthInfo)
+		require.True(ok)
+		require.NotNil(authInfo.PeerClaims)
+		assert.Equal(asn1.ObjectIdentifier(serverOID), authInfo.PeerClaims.Variant)
+		assert.Equal(i == 1, authInfo.State.DidResume)
+	}
+}
+
+func TestPeerClaimsFromContextNoPeer(t *testing.T) {
+	assert := assert.New(t)
+
+	_, ok := PeerClaimsFromContext(context.Background())
+	assert.False(ok)
+
+	ctx := peer.NewContext(context.Background(), &peer.Peer{AuthInfo: AuthInfo{}})
+	_, ok = PeerClaimsFromContext(ctx)
+	assert.False(ok)
+}
+
 type fakeIssuer struct {
 	fakeOID
 }
@@ -118,9 +208,11 @@ type fakeDoc struct {
 }
 
 type fakeAPI struct {
+	peerClaims *atls.PeerClaims
 	initproto.UnimplementedAPIServer
 }
 
 func (f *fakeAPI) Init(ctx context.Context, in *initproto.InitRequest) (*initproto.InitResponse, error) {
+	f.peerClaims, _ = PeerClaimsFromContext(ctx)
 	return &initproto.InitResponse{}, nil
 }
@@ -0,0 +1,96 @@
+/*
+Copyright (c) Edgeless Systems GmbH
+
+SPDX-License-Identifier: AGPL-3.0-only
+*/
+
+/*
+Package joinconfig signs and verifies the attestation configuration stored in the join-config ConfigMap.
+
+The configuration is signed with the cluster owner's cosign key.
+The public key is pinned when the cluster is initialized, and the join service only trusts
+values signed with the matching private key, which never leaves the owner's machine.
+Write access to the join-config ConfigMap is therefore not sufficient to change the values trusted by the join service.
+
+Every signature covers a version, which the owner increments when signing.
+The join service never goes back to a lower version, so previously signed values can't be replayed.
+*/
+package joinconfig
+
+import (
+	"crypto"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strconv"
+
+	"github.com/edgelesssys/constellation/v2/internal/constants"
+	"github.com/edgelesssys/constellation/v2/internal/sigstore"
+)
+
+// ErrUnsigned is returned when verifying a join config without signature.
+var ErrUnsigned = errors.New("join config is not signed")
+
+// SignedKeys are the keys of the join-config ConfigMap covered by the signature.
+var SignedKeys = []string{
+	constants.MeasurementsFilename,
+	constants.EnforcedPCRsFilename,
+	constants.IdKeyDigestFilename,
+	constants.EnforceIdKeyDigestFilename,
+	constants.AttestationPolicyFilename,
+	constants.EKCertificateAuthorityFilename,
+	constants.JoinConfigVersionFilename,
+}
+
+// Sign signs the values of the SignedKeys in data.
+// The returned signature is base64 encoded and is stored under constants.MeasurementsSignatureFilename.
+func Sign(data map[string]string, key crypto.Signer) ([]byte, error) {
+	payload, err := Payload(data)
+	if err != nil {
+		return nil, err
+	}
+	return sigstore.SignContent(payload, key)
+}
+
+// Verify checks that signature is a valid signature of the values of the SignedKeys in data.
+// publicKey is expected to be PEM encoded.
+func Verify(data map[string]string, signature, publicKey []byte) error {
+	if len(signature) == 0 {
+		return ErrUnsigned
+	}
+	payload, err := Payload(data)
+	if err != nil {
+		return err
+	}
+	if err := sigstore.VerifySignature(payload, signature, publicKey); err != nil {
+		return fmt.Errorf("verifying join config signature: %w", err)
+	}
+	return nil
+}
+
+// Payload returns the content signed for data.
+// Only the SignedKeys are included, so a missing key changes the payload as much as a changed value.
+func Payload(data map[string]string) ([]byte, error) {
+	signed := make(map[string]string, len(SignedKeys))
+	for _, key := range SignedKeys {
+		if value, ok := data[key]; ok {
+			signed[key] = value
+		}
+	}
+	// map keys are sorted by encoding/json, making the payload deterministic
+	return json.Marshal(signed)
+}
+
+// Version returns the version of the join-config in data.
+// A join-config without version, as written at initialization, has version 0.
+func Version(data map[string]string) (uint64, error) {
+	versionRaw, ok := data[constants.JoinConfigVersionFilename]
+	if !ok {
+		return 0, nil
+	}
+	version, err := strconv.ParseUint(versionRaw, 10, 64)
+	if err != nil {
+		return 0, fmt.Errorf("parsing join-config version %q: %w", versionRaw, err)
+	}
+	return version, nil
+}
@@ -0,0 +1,147 @@
+/*
+Copyright (c) Edgeless Systems GmbH
+
+SPDX-License-Identifier: AGPL-3.0-only
+*/
+
+package joinconfig
+
+import (
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"testing"
+
+	"github.com/edgelesssys/constellation/v2/internal/constants"
+	"github.com/sigstore/sigstore/pkg/cryptoutils"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/goleak"
+)
+
+func TestMain(m *testing.M) {
+	goleak.VerifyTestMain(m)
+}
+
+func TestSignVerify(t *testing.T) {
+	ownerKey, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	require.NoError(t, err)
+	otherKey, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	require.NoError(t, err)
+	signed := map[string]string{
+		constants.MeasurementsFilename: `{"11":"AAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAA="}`,
+		constants.EnforcedPCRsFilename: "[11]",
+	}
+
+	testCases := map[string]struct {
+		data        map[string]string
+		verifyKey   *ecdsa.PrivateKey
+		noSignature bool
+		wantErr     bool
+	}{
+		"valid signature": {
+			data:      signed,
+			verifyKey: ownerKey,
+		},
+		"unsigned keys may change": {
+			data: map[string]string{
+				constants.MeasurementsFilename: signed[constants.MeasurementsFilename],
+				constants.EnforcedPCRsFilename: signed[constants.EnforcedPCRsFilename],
+				constants.AzureCVM:             "false",
+			},
+			verifyKey: ownerKey,
+		},
+		"changed measurements": {
+			data: map[string]string{
+				constants.MeasurementsFilename: `{"11":"AQAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAA="}`,
+				constants.EnforcedPCRsFilename: signed[constants.EnforcedPCRsFilename],
+			},
+			verifyKey: ownerKey,
+			wantErr:   true,
+		},
+		"removed key": {
+			data: map[string]string{
+				constants.MeasurementsFilename: signed[constants.MeasurementsFilename],
+			},
+			verifyKey: ownerKey,
+			wantErr:   true,
+		},
+		"added key": {
+			data: map[string]string{
+				constants.MeasurementsFilename:           signed[constants.MeasurementsFilename],
+				constants.EnforcedPCRsFilename:           signed[constants.EnforcedPCRsFilename],
+				constants.EKCertificateAuthorityFilename: "",
+			},
+			verifyKey: ownerKey,
+			wantErr:   true,
+		},
+		"signed by other key": {
+			data:      signed,
+			verifyKey: otherKey,
+			wantErr:   true,
+		},
+		"missing signature": {
+			data:        signed,
+			verifyKey:   ownerKey,
+			noSignature: true,
+			wantErr:     true,
+		},
+	}
+
+	for name, tc := range testCases {
+		t.Run(name, func(t *testing.T) {
+			assert := assert.New(t)
+			require := require.New(t)
+
+			signature, err := Sign(signed, ownerKey)
+			require.NoError(err)
+			if tc.noSignature {
+				signature = nil
+			}
+
+			publicKey, err := cryptoutils.MarshalPublicKeyToPEM(tc.verifyKey.Public())
+			require.NoError(err)
+
+			err = Verify(tc.data, signature, publicKey)
+			if tc.wantErr {
+				assert.Error(err)
+				return
+			}
+			assert.NoError(err)
+		})
+	}
+}
+
+func TestVersion(t *testing.T) {
+	testCases := map[string]struct {
+		data        map[string]string
+		wantVersion uint64
+		wantErr     bool
+	}{
+		"no version": {
+			data: map[string]string{constants.MeasurementsFilename: "{}"},
+		},
+		"version": {
+			data:        map[string]string{constants.JoinConfigVersionFilename: "42"},
+			wantVersion: 42,
+		},
+		"invalid version": {
+			data:    map[string]string{constants.JoinConfigVersionFilename: "-1"},
+			wantErr: true,
+		},
+	}
+
+	for name, tc := range testCases {
+		t.Run(name, func(t *testing.T) {
+			assert := assert.New(t)
+
+			version, err := Version(tc.data)
+			if tc.wantErr {
+				assert.Error(err)
+				return
+			}
+			assert.NoError(err)
+			assert.Equal(tc.wantVersion, version)
+		})
+	}
+}
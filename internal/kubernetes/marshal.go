@@ -14,12 +14,19 @@ import (
 	"reflect"
 
 	"gopkg.in/yaml.v3"
+	apiextensionsv1 "k8s.io/apiextensions-apiserver/pkg/apis/apiextensions/v1"
 	"k8s.io/apimachinery/pkg/runtime"
 	"k8s.io/apimachinery/pkg/runtime/serializer"
 	"k8s.io/apimachinery/pkg/runtime/serializer/json"
+	utilruntime "k8s.io/apimachinery/pkg/util/runtime"
 	"k8s.io/client-go/kubernetes/scheme"
 )
 
+func init() {
+	// CustomResourceDefinitions are deployed alongside the built-in resources
+	utilruntime.Must(apiextensionsv1.AddToScheme(scheme.Scheme))
+}
+
 // Marshaler is used by all k8s resources that can be marshaled to YAML.
 type Marshaler interface {
 	Marshal() ([]byte, error)
@@ -16,6 +16,8 @@ OIDs beginning with 1.3.9900 are reserved and can be used without registration.
 
 * The 1.3.9900.5 branch is reserved for QEMU.
 
+* The 1.3.9900.6 branch is reserved for aTLS protocol extensions.
+
 Deprecated OIDs should never be reused for different purposes.
 Instead, new OIDs should be added in the appropriate branch at the next available index.
 */
@@ -77,3 +79,20 @@ type QEMU struct{}
 func (QEMU) OID() asn1.ObjectIdentifier {
 	return asn1.ObjectIdentifier{1, 3, 9900, 5, 1}
 }
+
+// QEMUTDX holds the OID for QEMU Intel TDX guests.
+type QEMUTDX struct{}
+
+// OID returns the struct's object identifier.
+func (QEMUTDX) OID() asn1.ObjectIdentifier {
+	return asn1.ObjectIdentifier{1, 3, 9900, 5, 2}
+}
+
+// CachedAttestationNonce is the OID of the certificate extension holding the nonce of a reused attestation.
+// See internal/atls for details.
+type CachedAttestationNonce struct{}
+
+// OID returns the struct's object identifier.
+func (CachedAttestationNonce) OID() asn1.ObjectIdentifier {
+	return asn1.ObjectIdentifier{1, 3, 9900, 6, 1}
+}
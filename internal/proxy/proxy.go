@@ -0,0 +1,239 @@
+/*
+Copyright (c) Edgeless Systems GmbH
+
+SPDX-License-Identifier: AGPL-3.0-only
+*/
+
+/*
+Package proxy tunnels TCP connections through HTTP(S) and SOCKS5 proxies.
+
+HTTP and HTTPS proxies are used with the CONNECT method, SOCKS5 proxies with the CONNECT command.
+Credentials in the user info of the proxy URL are used for proxy authentication.
+
+Only the TCP connection is tunneled. Protocols on top of it, like aTLS, are end-to-end between client and target,
+so the proxy can neither read nor modify the connection.
+*/
+package proxy
+
+import (
+	"bufio"
+	"context"
+	"crypto/tls"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"net"
+	"net/http"
+	"net/url"
+	"os"
+	"strings"
+	"time"
+
+	"golang.org/x/net/http/httpproxy"
+	netproxy "golang.org/x/net/proxy"
+)
+
+// Dialer dials TCP connections through the proxy applying to the target address.
+type Dialer struct {
+	proxyFor func(*url.URL) (*url.URL, error)
+	forward  NetDialer
+}
+
+// New returns a Dialer tunneling connections through proxy, using forward to connect to the proxy.
+// If proxy is empty, it is taken from the environment: HTTPS_PROXY, or ALL_PROXY if the former is unset,
+// except for targets matching NO_PROXY.
+// Connections are dialed directly if no proxy applies.
+func New(proxy string, forward NetDialer) (*Dialer, error) {
+	if proxy == "" {
+		return &Dialer{proxyFor: fromEnvironment(), forward: forward}, nil
+	}
+
+	proxyURL, err := parse(proxy)
+	if err != nil {
+		return nil, err
+	}
+	return &Dialer{
+		proxyFor: func(*url.URL) (*url.URL, error) { return proxyURL, nil },
+		forward:  forward,
+	}, nil
+}
+
+// DialContext connects to addr through the proxy applying to it.
+func (d *Dialer) DialContext(ctx context.Context, network, addr string) (net.Conn, error) {
+	proxyURL, err := d.proxyFor(&url.URL{Scheme: "https", Host: addr})
+	if err != nil {
+		return nil, fmt.Errorf("selecting proxy for %s: %w", addr, err)
+	}
+	if proxyURL == nil {
+		return d.forward.DialContext(ctx, network, addr)
+	}
+
+	var conn net.Conn
+	switch proxyURL.Scheme {
+	case "http", "https":
+		conn, err = d.dialConnect(ctx, proxyURL, addr)
+	case "socks5", "socks5h":
+		conn, err = d.dialSOCKS5(ctx, proxyURL, network, addr)
+	default:
+		err = fmt.Errorf("unsupported proxy scheme %q", proxyURL.Scheme)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("connecting to %s through proxy %s: %w", addr, proxyURL.Redacted(), err)
+	}
+	return conn, nil
+}
+
+// HTTPProxy returns the proxy for an HTTP request, for use as http.Transport.Proxy.
+func (d *Dialer) HTTPProxy(req *http.Request) (*url.URL, error) {
+	return d.proxyFor(req.URL)
+}
+
+// dialConnect opens a tunnel to addr using an HTTP CONNECT request.
+func (d *Dialer) dialConnect(ctx context.Context, proxyURL *url.URL, addr string) (net.Conn, error) {
+	conn, err := d.forward.DialContext(ctx, "tcp", proxyAddress(proxyURL))
+	if err != nil {
+		return nil, err
+	}
+	if deadline, ok := ctx.Deadline(); ok {
+		_ = conn.SetDeadline(deadline)
+	}
+
+	if proxyURL.Scheme == "https" {
+		tlsConn := tls.Client(conn, &tls.Config{ServerName: proxyURL.Hostname(), MinVersion: tls.VersionTLS12})
+		if err := tlsConn.HandshakeContext(ctx); err != nil {
+			conn.Close()
+			return nil, fmt.Errorf("TLS handshake with proxy: %w", err)
+		}
+		conn = tlsConn
+	}
+
+	req := &http.Request{
+		Method: http.MethodConnect,
+		URL:    &url.URL{Opaque: addr},
+		Host:   addr,
+		Header: make(http.Header),
+	}
+	if proxyURL.User != nil {
+		password, _ := proxyURL.User.Password()
+		credentials := base64.StdEncoding.EncodeToString([]byte(proxyURL.User.Username() + ":" + password))
+		req.Header.Set("Proxy-Authorization", "Basic "+credentials)
+	}
+	if err := req.Write(conn); err != nil {
+		conn.Close()
+		return nil, fmt.Errorf("sending CONNECT request: %w", err)
+	}
+
+	reader := bufio.NewReader(conn)
+	resp, err := http.ReadResponse(reader, req)
+	if err != nil {
+		conn.Close()
+		return nil, fmt.Errorf("reading CONNECT response: %w", err)
+	}
+	resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		conn.Close()
+		return nil, fmt.Errorf("proxy refused CONNECT: %s", resp.Status)
+	}
+
+	_ = conn.SetDeadline(time.Time{})
+	if reader.Buffered() > 0 {
+		// the target may start talking before the client, e.g. with protocols other than TLS
+		return &bufferedConn{Conn: conn, reader: reader}, nil
+	}
+	return conn, nil
+}
+
+// dialSOCKS5 opens a tunnel to addr through a SOCKS5 proxy.
+func (d *Dialer) dialSOCKS5(ctx context.Context, proxyURL *url.URL, network, addr string) (net.Conn, error) {
+	var auth *netproxy.Auth
+	if proxyURL.User != nil {
+		password, _ := proxyURL.User.Password()
+		auth = &netproxy.Auth{User: proxyURL.User.Username(), Password: password}
+	}
+	socksDialer, err := netproxy.SOCKS5("tcp", proxyAddress(proxyURL), auth, contextDialer{d.forward})
+	if err != nil {
+		return nil, err
+	}
+	contextDialer, ok := socksDialer.(netproxy.ContextDialer)
+	if !ok {
+		return nil, errors.New("SOCKS5 dialer does not support contexts")
+	}
+	return contextDialer.DialContext(ctx, network, addr)
+}
+
+// fromEnvironment returns a function selecting the proxy for a URL based on the environment.
+func fromEnvironment() func(*url.URL) (*url.URL, error) {
+	cfg := httpproxy.FromEnvironment()
+	if cfg.HTTPSProxy == "" {
+		cfg.HTTPSProxy = getEnvAny("ALL_PROXY", "all_proxy")
+	}
+	return cfg.ProxyFunc()
+}
+
+// parse parses a proxy URL. URLs without scheme are treated as HTTP proxies.
+func parse(proxy string) (*url.URL, error) {
+	if !strings.Contains(proxy, "://") {
+		proxy = "http://" + proxy
+	}
+	proxyURL, err := url.Parse(proxy)
+	if err != nil {
+		return nil, fmt.Errorf("parsing proxy URL: %w", err)
+	}
+	switch proxyURL.Scheme {
+	case "http", "https", "socks5", "socks5h":
+	default:
+		return nil, fmt.Errorf("unsupported proxy scheme %q, must be one of http, https, socks5", proxyURL.Scheme)
+	}
+	if proxyURL.Host == "" {
+		return nil, fmt.Errorf("proxy URL %q has no host", proxyURL.Redacted())
+	}
+	return proxyURL, nil
+}
+
+// proxyAddress returns the address of the proxy, adding the default port of its scheme if none is set.
+func proxyAddress(proxyURL *url.URL) string {
+	if proxyURL.Port() != "" {
+		return proxyURL.Host
+	}
+	port := "80"
+	switch proxyURL.Scheme {
+	case "https":
+		port = "443"
+	case "socks5", "socks5h":
+		port = "1080"
+	}
+	return net.JoinHostPort(proxyURL.Hostname(), port)
+}
+
+func getEnvAny(names ...string) string {
+	for _, name := range names {
+		if val := os.Getenv(name); val != "" {
+			return val
+		}
+	}
+	return ""
+}
+
+// bufferedConn is a net.Conn returning data buffered while reading the CONNECT response first.
+type bufferedConn struct {
+	net.Conn
+	reader *bufio.Reader
+}
+
+func (c *bufferedConn) Read(b []byte) (int, error) {
+	return c.reader.Read(b)
+}
+
+// contextDialer adapts a NetDialer to the interfaces of golang.org/x/net/proxy.
+type contextDialer struct {
+	NetDialer
+}
+
+func (d contextDialer) Dial(network, addr string) (net.Conn, error) {
+	return d.DialContext(context.Background(), network, addr)
+}
+
+// NetDialer implements the net Dialer interface.
+type NetDialer interface {
+	DialContext(ctx context.Context, network, address string) (net.Conn, error)
+}
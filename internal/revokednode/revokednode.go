@@ -0,0 +1,52 @@
+/*
+Copyright (c) Edgeless Systems GmbH
+
+SPDX-License-Identifier: AGPL-3.0-only
+*/
+
+/*
+Package revokednode defines the RevokedNode resource shared by the CLI and the join service.
+
+A RevokedNode lists the identifiers of a revoked node. The CLI creates it when revoking a node,
+and the join service denies nodes matching any of its identifiers.
+*/
+package revokednode
+
+import (
+	"github.com/edgelesssys/constellation/v2/internal/constants"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+)
+
+// Kind is the kind of RevokedNode resources.
+const Kind = "RevokedNode"
+
+// GroupVersionResource is the resource of RevokedNode objects.
+var GroupVersionResource = schema.GroupVersionResource{
+	Group:    constants.NodeAttestationGroup,
+	Version:  constants.NodeAttestationVersion,
+	Resource: constants.RevokedNodeResource,
+}
+
+// RevokedNode denies a node to join or rejoin the cluster.
+type RevokedNode struct {
+	metav1.TypeMeta   `json:",inline"`
+	metav1.ObjectMeta `json:"metadata,omitempty"`
+
+	Spec Spec `json:"spec,omitempty"`
+}
+
+// Spec lists the identifiers of a revoked node.
+type Spec struct {
+	// NodeName is the Kubernetes name of the revoked node.
+	NodeName string `json:"nodeName,omitempty"`
+	// ProviderID is the cloud provider ID of the revoked instance.
+	ProviderID string `json:"providerID,omitempty"`
+	// AttestationIdentities are the identities reported by the node's attestation,
+	// i.e. the instance ID or the digest of the attestation key prefixed with "ak:".
+	AttestationIdentities []string `json:"attestationIdentities,omitempty"`
+	// DiskUUIDs are the UUIDs of the state disks used by the node.
+	DiskUUIDs []string `json:"diskUUIDs,omitempty"`
+	// Reason describes why the node was revoked.
+	Reason string `json:"reason,omitempty"`
+}
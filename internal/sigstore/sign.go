@@ -0,0 +1,35 @@
+/*
+Copyright (c) Edgeless Systems GmbH
+
+SPDX-License-Identifier: AGPL-3.0-only
+*/
+
+package sigstore
+
+import (
+	"bytes"
+	"crypto"
+	"encoding/base64"
+	"fmt"
+
+	sigsig "github.com/sigstore/sigstore/pkg/signature"
+)
+
+// SignContent signs content using privateKey.
+// The returned signature is base64 encoded, the same format as produced by cosign sign-blob,
+// and can be verified using VerifySignature.
+func SignContent(content []byte, privateKey crypto.PrivateKey) ([]byte, error) {
+	signer, err := sigsig.LoadSigner(privateKey, crypto.SHA256)
+	if err != nil {
+		return nil, fmt.Errorf("unable to load signer: %w", err)
+	}
+
+	sig, err := signer.SignMessage(bytes.NewReader(content))
+	if err != nil {
+		return nil, fmt.Errorf("unable to sign content: %w", err)
+	}
+
+	encoded := make([]byte, base64.StdEncoding.EncodedLen(len(sig)))
+	base64.StdEncoding.Encode(encoded, sig)
+	return encoded, nil
+}
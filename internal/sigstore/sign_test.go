@@ -0,0 +1,43 @@
+/*
+Copyright (c) Edgeless Systems GmbH
+
+SPDX-License-Identifier: AGPL-3.0-only
+*/
+
+package sigstore
+
+import (
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"testing"
+
+	"github.com/sigstore/sigstore/pkg/cryptoutils"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestSignContent(t *testing.T) {
+	assert := assert.New(t)
+	require := require.New(t)
+
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	require.NoError(err)
+	publicKey, err := cryptoutils.MarshalPublicKeyToPEM(key.Public())
+	require.NoError(err)
+	otherKey, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	require.NoError(err)
+	otherPublicKey, err := cryptoutils.MarshalPublicKeyToPEM(otherKey.Public())
+	require.NoError(err)
+
+	content := []byte("This is some content to be signed!\n")
+	signature, err := SignContent(content, key)
+	require.NoError(err)
+
+	assert.NoError(VerifySignature(content, signature, publicKey))
+	assert.Error(VerifySignature([]byte("This is some completely different content!\n"), signature, publicKey))
+	assert.Error(VerifySignature(content, signature, otherPublicKey))
+
+	_, err = SignContent(content, "not a key")
+	assert.Error(err)
+}
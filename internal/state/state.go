@@ -23,6 +23,8 @@ type ConstellationState struct {
 	GCPControlPlaneInstanceGroup    string               `json:"gcpcontrolplaneinstancegroup,omitempty"`
 	GCPWorkerInstanceTemplate       string               `json:"gcpworkerinstancetemplate,omitempty"`
 	GCPControlPlaneInstanceTemplate string               `json:"gcpcontrolplaneinstancetemplate,omitempty"`
+	GCPWorkerGroupInstanceGroups    []string             `json:"gcpworkergroupinstancegroups,omitempty"`
+	GCPWorkerGroupInstanceTemplates []string             `json:"gcpworkergroupinstancetemplates,omitempty"`
 	GCPNetwork                      string               `json:"gcpnetwork,omitempty"`
 	GCPSubnetwork                   string               `json:"gcpsubnetwork,omitempty"`
 	GCPFirewalls                    []string             `json:"gcpfirewalls,omitempty"`
@@ -42,6 +44,7 @@ type ConstellationState struct {
 	AzureNetworkSecurityGroup  string               `json:"azurenetworksecuritygroup,omitempty"`
 	AzureWorkerScaleSet        string               `json:"azureworkersscaleset,omitempty"`
 	AzureControlPlaneScaleSet  string               `json:"azurecontrolplanesscaleset,omitempty"`
+	AzureWorkerGroupScaleSets  []string             `json:"azureworkergroupscalesets,omitempty"`
 	AzureADAppObjectID         string               `json:"azureadappobjectid,omitempty"`
 
 	QEMUWorkerInstances       cloudtypes.Instances `json:"qemuworkers,omitempty"`
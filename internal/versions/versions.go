@@ -52,10 +52,9 @@ const (
 	GcpGuestImage            = "ghcr.io/edgelesssys/gcp-guest-agent:20220713.00"
 	NodeOperatorCatalogImage = "ghcr.io/edgelesssys/constellation/node-operator-catalog"
 	NodeOperatorVersion      = "v0.0.1-0.20220920083838-788cfd9bd98a"
-	// TODO: switch node maintenance operator catalog back to upstream quay.io/medik8s/node-maintenance-operator-catalog
-	// once https://github.com/medik8s/node-maintenance-operator/issues/49 is resolved.
-	NodeMaintenanceOperatorCatalogImage = "ghcr.io/edgelesssys/constellation/node-maintenance-operator-catalog"
-	NodeMaintenanceOperatorVersion      = "v0.13.1-alpha1"
+	// BusyboxImage is used for file operations on nodes, e.g. when restoring etcd snapshots or upgrading Kubernetes components.
+	// It runs privileged on every node, including control-plane nodes, and is therefore pinned by digest.
+	BusyboxImage = "ghcr.io/edgelesssys/constellation/busybox:1.35.0@sha256:8c40df61d40166f5791f44b3d90b77b4c7f59ed39a992fd9046886d3126ffa68"
 
 	// currently supported versions.
 	V1_22   ValidK8sVersion = "1.22"
@@ -160,3 +159,12 @@ type KubernetesVersion struct {
 	CloudNodeManagerImageAzure       string // k8s version dependency. Same version as above.
 	ClusterAutoscalerImage           string // Matches k8s versioning scheme.
 }
+
+// Component is a Kubernetes component pinned by the SHA256 checksum of its download.
+// The node operator sets these components when upgrading a cluster, and joining nodes install them on top of their KubernetesVersion.
+type Component struct {
+	URL         string `json:"url"`
+	Hash        string `json:"sha256"`
+	InstallPath string `json:"installPath"`
+	Extract     bool   `json:"extract,omitempty"`
+}
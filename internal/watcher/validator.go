@@ -7,21 +7,30 @@ SPDX-License-Identifier: AGPL-3.0-only
 package watcher
 
 import (
+	"crypto/x509"
 	"encoding/asn1"
 	"encoding/hex"
+	"encoding/json"
+	"errors"
 	"fmt"
+	"io/fs"
 	"path/filepath"
 	"strconv"
 	"sync"
+	"time"
 
 	"github.com/edgelesssys/constellation/v2/internal/atls"
 	"github.com/edgelesssys/constellation/v2/internal/attestation/azure/snp"
 	"github.com/edgelesssys/constellation/v2/internal/attestation/azure/trustedlaunch"
 	"github.com/edgelesssys/constellation/v2/internal/attestation/gcp"
+	"github.com/edgelesssys/constellation/v2/internal/attestation/policy"
 	"github.com/edgelesssys/constellation/v2/internal/attestation/qemu"
+	"github.com/edgelesssys/constellation/v2/internal/attestation/tdx"
 	"github.com/edgelesssys/constellation/v2/internal/cloud/cloudprovider"
+	"github.com/edgelesssys/constellation/v2/internal/cloud/vmtype"
 	"github.com/edgelesssys/constellation/v2/internal/constants"
 	"github.com/edgelesssys/constellation/v2/internal/file"
+	"github.com/edgelesssys/constellation/v2/internal/joinconfig"
 	"github.com/edgelesssys/constellation/v2/internal/logger"
 )
 
@@ -32,31 +41,44 @@ type Updatable struct {
 	newValidator newValidatorFunc
 	fileHandler  file.Handler
 	csp          cloudprovider.Provider
-	azureCVM     bool
+	vmType       vmtype.VMType
+	publicKey    []byte
+	version      uint64
+	policy       string
+	keyCacheTTL  time.Duration
+	caches       []cacheInvalidator
 	atls.Validator
 }
 
 // NewValidator initializes a new updatable validator.
-func NewValidator(log *logger.Logger, csp string, fileHandler file.Handler, azureCVM bool) (*Updatable, error) {
+// vmType selects the attestation variant for CSPs supporting more than one.
+// Measurements are only accepted if they are signed by publicKey, which is expected to be PEM encoded.
+func NewValidator(log *logger.Logger, csp string, fileHandler file.Handler, vmType vmtype.VMType, publicKey []byte) (*Updatable, error) {
 	var newValidator newValidatorFunc
 	switch cloudprovider.FromString(csp) {
 	case cloudprovider.Azure:
-		if azureCVM {
-			newValidator = func(m map[uint32][]byte, e []uint32, idkeydigest []byte, enforceIdKeyDigest bool, log *logger.Logger) atls.Validator {
-				return snp.NewValidator(m, e, idkeydigest, enforceIdKeyDigest, log)
+		if vmType == vmtype.AzureCVM {
+			newValidator = func(m map[uint32][]byte, e []uint32, idkeydigest []byte, enforceIdKeyDigest bool, _ *x509.CertPool, p *policy.Policy, log *logger.Logger) atls.Validator {
+				return snp.NewValidator(m, e, idkeydigest, enforceIdKeyDigest, p, log)
 			}
 		} else {
-			newValidator = func(m map[uint32][]byte, e []uint32, idkeydigest []byte, enforceIdKeyDigest bool, log *logger.Logger) atls.Validator {
-				return trustedlaunch.NewValidator(m, e, log)
+			newValidator = func(m map[uint32][]byte, e []uint32, idkeydigest []byte, enforceIdKeyDigest bool, _ *x509.CertPool, p *policy.Policy, log *logger.Logger) atls.Validator {
+				return trustedlaunch.NewValidator(m, e, p, log)
 			}
 		}
 	case cloudprovider.GCP:
-		newValidator = func(m map[uint32][]byte, e []uint32, _ []byte, _ bool, log *logger.Logger) atls.Validator {
-			return gcp.NewValidator(m, e, log)
+		newValidator = func(m map[uint32][]byte, e []uint32, _ []byte, _ bool, _ *x509.CertPool, p *policy.Policy, log *logger.Logger) atls.Validator {
+			return gcp.NewValidator(m, e, p, log)
 		}
 	case cloudprovider.QEMU:
-		newValidator = func(m map[uint32][]byte, e []uint32, _ []byte, _ bool, log *logger.Logger) atls.Validator {
-			return qemu.NewValidator(m, e, log)
+		if vmType == vmtype.QEMUTDX {
+			newValidator = func(m map[uint32][]byte, e []uint32, _ []byte, _ bool, _ *x509.CertPool, p *policy.Policy, log *logger.Logger) atls.Validator {
+				return tdx.NewValidator(m, e, p, log)
+			}
+		} else {
+			newValidator = func(m map[uint32][]byte, e []uint32, _ []byte, _ bool, ekRoots *x509.CertPool, p *policy.Policy, log *logger.Logger) atls.Validator {
+				return qemu.NewValidator(m, e, ekRoots, p, log)
+			}
 		}
 	default:
 		return nil, fmt.Errorf("unknown cloud service provider: %q", csp)
@@ -67,7 +89,8 @@ func NewValidator(log *logger.Logger, csp string, fileHandler file.Handler, azur
 		newValidator: newValidator,
 		fileHandler:  fileHandler,
 		csp:          cloudprovider.FromString(csp),
-		azureCVM:     azureCVM,
+		vmType:       vmType,
+		publicKey:    publicKey,
 	}
 
 	if err := u.Update(); err != nil {
@@ -83,59 +106,195 @@ func (u *Updatable) Validate(attDoc []byte, nonce []byte) ([]byte, error) {
 	return u.Validator.Validate(attDoc, nonce)
 }
 
+// ValidateWithClaims calls the validators ValidateWithClaims method, and prevents any updates during the call.
+// If the validator does not report claims, the attestation is validated and empty claims are returned.
+// Validators without claims can't evaluate an attestation policy, so validation fails if a policy is configured.
+func (u *Updatable) ValidateWithClaims(attDoc []byte, nonce []byte) ([]byte, policy.Claims, error) {
+	u.mux.Lock()
+	defer u.mux.Unlock()
+	return u.validateWithClaims(attDoc, nonce)
+}
+
+// ValidateWithPolicy calls ValidateWithClaims, and also returns the source of the attestation policy enforced by the call.
+// An empty policy is returned if no policy is enforced.
+func (u *Updatable) ValidateWithPolicy(attDoc []byte, nonce []byte) ([]byte, policy.Claims, string, error) {
+	u.mux.Lock()
+	defer u.mux.Unlock()
+	userData, claims, err := u.validateWithClaims(attDoc, nonce)
+	return userData, claims, u.policy, err
+}
+
+// validateWithClaims validates the attestation with the current validator. The caller must hold u.mux.
+func (u *Updatable) validateWithClaims(attDoc []byte, nonce []byte) ([]byte, policy.Claims, error) {
+	validator, ok := u.Validator.(claimsValidator)
+	if !ok {
+		if u.policy != "" {
+			return nil, policy.Claims{}, errors.New("attestation policy is configured, but the validator does not support attestation policies")
+		}
+		userData, err := u.Validator.Validate(attDoc, nonce)
+		return userData, policy.Claims{}, err
+	}
+	return validator.ValidateWithClaims(attDoc, nonce)
+}
+
+// EnableTrustedKeyCache caches verified attestation keys for up to maxAge,
+// for the current and all future validators, if the attestation variant supports it.
+func (u *Updatable) EnableTrustedKeyCache(maxAge time.Duration) {
+	u.mux.Lock()
+	defer u.mux.Unlock()
+	u.keyCacheTTL = maxAge
+	u.enableTrustedKeyCache()
+}
+
+func (u *Updatable) enableTrustedKeyCache() {
+	if validator, ok := u.Validator.(trustedKeyCacher); ok && u.keyCacheTTL > 0 {
+		validator.EnableTrustedKeyCache(u.keyCacheTTL)
+	}
+}
+
+// InvalidateOnUpdate invalidates cache whenever the validator is updated,
+// so that attestations validated using previous measurements are validated again.
+func (u *Updatable) InvalidateOnUpdate(cache cacheInvalidator) {
+	u.mux.Lock()
+	defer u.mux.Unlock()
+	u.caches = append(u.caches, cache)
+}
+
 // OID returns the validators Object Identifier.
 func (u *Updatable) OID() asn1.ObjectIdentifier {
 	return u.Validator.OID()
 }
 
 // Update switches out the underlying validator.
+// The update is rejected, and the previous validator kept, if the attestation config
+// is not signed by the cluster owner, or if its version is lower than the version of the current config.
 func (u *Updatable) Update() error {
 	u.mux.Lock()
 	defer u.mux.Unlock()
 
 	u.log.Infof("Updating expected measurements")
 
-	var measurements map[uint32][]byte
-	if err := u.fileHandler.ReadJSON(filepath.Join(constants.ServiceBasePath, constants.MeasurementsFilename), &measurements); err != nil {
+	joinConfig, err := u.readJoinConfig()
+	if err != nil {
 		return err
 	}
+	signature, err := u.fileHandler.Read(filepath.Join(constants.ServiceBasePath, constants.MeasurementsSignatureFilename))
+	if err != nil && !errors.Is(err, fs.ErrNotExist) {
+		return err
+	}
+	if err := joinconfig.Verify(joinConfig, signature, u.publicKey); err != nil {
+		return fmt.Errorf("rejecting measurements update: %w", err)
+	}
+	version, err := joinconfig.Version(joinConfig)
+	if err != nil {
+		return fmt.Errorf("rejecting measurements update: %w", err)
+	}
+	if version < u.version {
+		return fmt.Errorf("rejecting measurements update: join-config version %d is older than the current version %d", version, u.version)
+	}
+
+	var measurements map[uint32][]byte
+	if err := json.Unmarshal([]byte(joinConfig[constants.MeasurementsFilename]), &measurements); err != nil {
+		return fmt.Errorf("parsing measurements: %w", err)
+	}
 	u.log.Debugf("New measurements: %v", measurements)
 
 	var enforced []uint32
-	if err := u.fileHandler.ReadJSON(filepath.Join(constants.ServiceBasePath, constants.EnforcedPCRsFilename), &enforced); err != nil {
-		return err
+	if err := json.Unmarshal([]byte(joinConfig[constants.EnforcedPCRsFilename]), &enforced); err != nil {
+		return fmt.Errorf("parsing enforced PCRs: %w", err)
 	}
 	u.log.Debugf("Enforced PCRs: %v", enforced)
 
 	var idkeydigest []byte
 	var enforceIdKeyDigest bool
-	if u.csp == cloudprovider.Azure && u.azureCVM {
+	if u.csp == cloudprovider.Azure && u.vmType == vmtype.AzureCVM {
 		u.log.Infof("Updating encforceIdKeyDigest value")
-		enforceRaw, err := u.fileHandler.Read(filepath.Join(constants.ServiceBasePath, constants.EnforceIdKeyDigestFilename))
-		if err != nil {
-			return err
+		enforceRaw, ok := joinConfig[constants.EnforceIdKeyDigestFilename]
+		if !ok {
+			return fmt.Errorf("missing %s", constants.EnforceIdKeyDigestFilename)
 		}
-		enforceIdKeyDigest, err = strconv.ParseBool(string(enforceRaw))
+		enforceIdKeyDigest, err = strconv.ParseBool(enforceRaw)
 		if err != nil {
 			return fmt.Errorf("parsing content of EnforceIdKeyDigestFilename: %s: %w", enforceRaw, err)
 		}
 		u.log.Debugf("New encforceIdKeyDigest value: %v", enforceIdKeyDigest)
 
 		u.log.Infof("Updating expected idkeydigest")
-		idkeydigestRaw, err := u.fileHandler.Read(filepath.Join(constants.ServiceBasePath, constants.IdKeyDigestFilename))
-		if err != nil {
-			return err
+		idkeydigestRaw, ok := joinConfig[constants.IdKeyDigestFilename]
+		if !ok {
+			return fmt.Errorf("missing %s", constants.IdKeyDigestFilename)
 		}
-		idkeydigest, err = hex.DecodeString(string(idkeydigestRaw))
+		idkeydigest, err = hex.DecodeString(idkeydigestRaw)
 		if err != nil {
 			return fmt.Errorf("parsing hexstring: %s: %w", idkeydigestRaw, err)
 		}
 		u.log.Debugf("New idkeydigest: %x", idkeydigest)
 	}
 
-	u.Validator = u.newValidator(measurements, enforced, idkeydigest, enforceIdKeyDigest, u.log)
+	// attestation keys of QEMU VMs are only trusted if certified by the EK CA
+	var ekRoots *x509.CertPool
+	if u.csp == cloudprovider.QEMU && u.vmType != vmtype.QEMUTDX {
+		u.log.Infof("Updating EK CA certificates")
+		ekCA, ok := joinConfig[constants.EKCertificateAuthorityFilename]
+		if !ok {
+			return fmt.Errorf("missing %s", constants.EKCertificateAuthorityFilename)
+		}
+		ekRoots, err = qemu.ParseEKRoots(ekCA)
+		if err != nil {
+			return fmt.Errorf("parsing EK CA certificates: %w", err)
+		}
+	}
+
+	// the attestation policy is optional, clusters without one only verify measurements
+	var attestationPolicy *policy.Policy
+	policyRaw := joinConfig[constants.AttestationPolicyFilename]
+	if len(policyRaw) > 0 {
+		u.log.Infof("Updating attestation policy")
+		attestationPolicy, err = policy.New(policyRaw)
+		if err != nil {
+			return fmt.Errorf("parsing attestation policy: %w", err)
+		}
+		u.log.Debugf("New attestation policy: %s", attestationPolicy)
+	}
+
+	u.Validator = u.newValidator(measurements, enforced, idkeydigest, enforceIdKeyDigest, ekRoots, attestationPolicy, u.log)
+	u.version = version
+	u.policy = policyRaw
+	u.enableTrustedKeyCache()
+	for _, cache := range u.caches {
+		cache.Invalidate()
+	}
 
 	return nil
 }
 
-type newValidatorFunc func(measurements map[uint32][]byte, enforcedPCRs []uint32, idkeydigest []byte, enforceIdKeyDigest bool, log *logger.Logger) atls.Validator
+// readJoinConfig reads the signed values of the join-config.
+// Measurements and enforced PCRs are required, all other values are optional.
+func (u *Updatable) readJoinConfig() (map[string]string, error) {
+	joinConfig := make(map[string]string, len(joinconfig.SignedKeys))
+	for _, key := range joinconfig.SignedKeys {
+		value, err := u.fileHandler.Read(filepath.Join(constants.ServiceBasePath, key))
+		if errors.Is(err, fs.ErrNotExist) && key != constants.MeasurementsFilename && key != constants.EnforcedPCRsFilename {
+			continue
+		}
+		if err != nil {
+			return nil, err
+		}
+		joinConfig[key] = string(value)
+	}
+	return joinConfig, nil
+}
+
+type trustedKeyCacher interface {
+	EnableTrustedKeyCache(maxAge time.Duration)
+}
+
+type cacheInvalidator interface {
+	Invalidate()
+}
+
+type claimsValidator interface {
+	ValidateWithClaims(attDoc []byte, nonce []byte) ([]byte, policy.Claims, error)
+}
+
+type newValidatorFunc func(measurements map[uint32][]byte, enforcedPCRs []uint32, idkeydigest []byte, enforceIdKeyDigest bool, ekRoots *x509.CertPool, attestationPolicy *policy.Policy, log *logger.Logger) atls.Validator
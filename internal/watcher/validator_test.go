@@ -9,20 +9,33 @@ package watcher
 import (
 	"bytes"
 	"context"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/x509"
 	"encoding/asn1"
 	"encoding/json"
+	"encoding/pem"
 	"errors"
 	"io"
+	"io/fs"
+	"math/big"
 	"net/http"
 	"net/http/httptest"
 	"path/filepath"
 	"sync"
 	"testing"
+	"time"
 
 	"github.com/edgelesssys/constellation/v2/internal/atls"
+	"github.com/edgelesssys/constellation/v2/internal/attestation/policy"
+	"github.com/edgelesssys/constellation/v2/internal/cloud/cloudprovider"
+	"github.com/edgelesssys/constellation/v2/internal/cloud/vmtype"
 	"github.com/edgelesssys/constellation/v2/internal/constants"
 	"github.com/edgelesssys/constellation/v2/internal/file"
+	"github.com/edgelesssys/constellation/v2/internal/joinconfig"
 	"github.com/edgelesssys/constellation/v2/internal/logger"
+	"github.com/sigstore/sigstore/pkg/cryptoutils"
 	"github.com/spf13/afero"
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/require"
@@ -39,13 +52,20 @@ func TestMain(m *testing.M) {
 func TestNewUpdateableValidator(t *testing.T) {
 	testCases := map[string]struct {
 		provider  string
+		vmType    vmtype.VMType
 		writeFile bool
+		unsigned  bool
 		wantErr   bool
 	}{
 		"azure": {
 			provider:  "azure",
 			writeFile: true,
 		},
+		"azure cvm": {
+			provider:  "azure",
+			vmType:    vmtype.AzureCVM,
+			writeFile: true,
+		},
 		"gcp": {
 			provider:  "gcp",
 			writeFile: true,
@@ -54,11 +74,22 @@ func TestNewUpdateableValidator(t *testing.T) {
 			provider:  "qemu",
 			writeFile: true,
 		},
+		"qemu tdx": {
+			provider:  "qemu",
+			vmType:    vmtype.QEMUTDX,
+			writeFile: true,
+		},
 		"no file": {
 			provider:  "azure",
 			writeFile: false,
 			wantErr:   true,
 		},
+		"unsigned": {
+			provider:  "gcp",
+			writeFile: true,
+			unsigned:  true,
+			wantErr:   true,
+		},
 		"invalid provider": {
 			provider:  "invalid",
 			writeFile: true,
@@ -95,13 +126,22 @@ func TestNewUpdateableValidator(t *testing.T) {
 					filepath.Join(constants.ServiceBasePath, constants.AzureCVM),
 					[]byte("true"),
 				))
+				require.NoError(handler.Write(
+					filepath.Join(constants.ServiceBasePath, constants.EKCertificateAuthorityFilename),
+					newTestCACertificate(t),
+				))
+			}
+			var publicKey []byte
+			if tc.writeFile && !tc.unsigned {
+				publicKey = writeSignature(t, handler)
 			}
 
 			_, err := NewValidator(
 				logger.NewTest(t),
 				tc.provider,
 				handler,
-				false,
+				tc.vmType,
+				publicKey,
 			)
 			if tc.wantErr {
 				assert.Error(err)
@@ -117,7 +157,7 @@ func TestUpdate(t *testing.T) {
 	require := require.New(t)
 
 	oid := fakeOID{1, 3, 9900, 1}
-	newValidator := func(m map[uint32][]byte, e []uint32, idkeydigest []byte, enforceIdKeyDigest bool, _ *logger.Logger) atls.Validator {
+	newValidator := func(m map[uint32][]byte, e []uint32, idkeydigest []byte, enforceIdKeyDigest bool, _ *x509.CertPool, _ *policy.Policy, _ *logger.Logger) atls.Validator {
 		return fakeValidator{fakeOID: oid}
 	}
 	handler := file.NewHandler(afero.NewMemMapFs())
@@ -156,6 +196,7 @@ func TestUpdate(t *testing.T) {
 		filepath.Join(constants.ServiceBasePath, constants.AzureCVM),
 		[]byte("true"),
 	))
+	validator.publicKey = writeSignature(t, handler)
 
 	// call update once to initialize the server's validator
 	require.NoError(validator.Update())
@@ -199,7 +240,7 @@ func TestUpdateConcurrency(t *testing.T) {
 	validator := &Updatable{
 		log:         logger.NewTest(t),
 		fileHandler: handler,
-		newValidator: func(m map[uint32][]byte, e []uint32, idkeydigest []byte, enforceIdKeyDigest bool, _ *logger.Logger) atls.Validator {
+		newValidator: func(m map[uint32][]byte, e []uint32, idkeydigest []byte, enforceIdKeyDigest bool, _ *x509.CertPool, _ *policy.Policy, _ *logger.Logger) atls.Validator {
 			return fakeValidator{fakeOID: fakeOID{1, 3, 9900, 1}}
 		},
 	}
@@ -226,6 +267,7 @@ func TestUpdateConcurrency(t *testing.T) {
 		filepath.Join(constants.ServiceBasePath, constants.AzureCVM),
 		[]byte("true"),
 	))
+	validator.publicKey = writeSignature(t, handler)
 
 	var wg sync.WaitGroup
 
@@ -240,6 +282,425 @@ func TestUpdateConcurrency(t *testing.T) {
 	wg.Wait()
 }
 
+func TestEnableTrustedKeyCache(t *testing.T) {
+	assert := assert.New(t)
+	require := require.New(t)
+
+	var validators []*keyCachingValidator
+	handler := file.NewHandler(afero.NewMemMapFs())
+	validator := &Updatable{
+		log:         logger.NewTest(t),
+		fileHandler: handler,
+		newValidator: func(m map[uint32][]byte, e []uint32, idkeydigest []byte, enforceIdKeyDigest bool, _ *x509.CertPool, _ *policy.Policy, _ *logger.Logger) atls.Validator {
+			v := &keyCachingValidator{fakeValidator: fakeValidator{fakeOID: fakeOID{1, 3, 9900, 1}}}
+			validators = append(validators, v)
+			return v
+		},
+	}
+	require.NoError(handler.WriteJSON(
+		filepath.Join(constants.ServiceBasePath, constants.MeasurementsFilename),
+		map[uint32][]byte{11: make([]byte, 32)},
+		file.OptNone,
+	))
+	require.NoError(handler.WriteJSON(
+		filepath.Join(constants.ServiceBasePath, constants.EnforcedPCRsFilename),
+		[]uint32{11},
+	))
+	validator.publicKey = writeSignature(t, handler)
+
+	require.NoError(validator.Update())
+	validator.EnableTrustedKeyCache(time.Minute)
+
+	// the cache is enabled for validators created by future updates
+	require.NoError(validator.Update())
+	require.Len(validators, 2)
+	assert.Equal(time.Minute, validators[0].maxAge)
+	assert.Equal(time.Minute, validators[1].maxAge)
+}
+
+func TestInvalidateOnUpdate(t *testing.T) {
+	assert := assert.New(t)
+	require := require.New(t)
+
+	handler := file.NewHandler(afero.NewMemMapFs())
+	validator := &Updatable{
+		log:         logger.NewTest(t),
+		fileHandler: handler,
+		newValidator: func(m map[uint32][]byte, e []uint32, idkeydigest []byte, enforceIdKeyDigest bool, _ *x509.CertPool, _ *policy.Policy, _ *logger.Logger) atls.Validator {
+			return fakeValidator{fakeOID: fakeOID{1, 3, 9900, 1}}
+		},
+	}
+	require.NoError(handler.WriteJSON(
+		filepath.Join(constants.ServiceBasePath, constants.MeasurementsFilename),
+		map[uint32][]byte{11: make([]byte, 32)},
+		file.OptNone,
+	))
+	require.NoError(handler.WriteJSON(
+		filepath.Join(constants.ServiceBasePath, constants.EnforcedPCRsFilename),
+		[]uint32{11},
+	))
+	validator.publicKey = writeSignature(t, handler)
+
+	cache := &countingInvalidator{}
+	validator.InvalidateOnUpdate(cache)
+	require.NoError(validator.Update())
+	assert.Equal(1, cache.invalidated)
+
+	// rejected updates keep the previous validator, and its cached results
+	require.NoError(handler.Write(filepath.Join(constants.ServiceBasePath, constants.MeasurementsSignatureFilename), []byte("invalid"), file.OptOverwrite))
+	assert.Error(validator.Update())
+	assert.Equal(1, cache.invalidated)
+}
+
+type countingInvalidator struct {
+	invalidated int
+}
+
+func (c *countingInvalidator) Invalidate() {
+	c.invalidated++
+}
+
+func TestUpdatePolicy(t *testing.T) {
+	testCases := map[string]struct {
+		policy     []byte
+		wantPolicy bool
+		wantErr    bool
+	}{
+		"no policy": {},
+		"empty policy": {
+			policy: []byte{},
+		},
+		"valid policy": {
+			policy:     []byte(`claims.measurements[11] == "00"`),
+			wantPolicy: true,
+		},
+		"invalid policy": {
+			policy:  []byte("claims.measurements["),
+			wantErr: true,
+		},
+	}
+
+	for name, tc := range testCases {
+		t.Run(name, func(t *testing.T) {
+			assert := assert.New(t)
+			require := require.New(t)
+
+			handler := file.NewHandler(afero.NewMemMapFs())
+			var gotPolicy *policy.Policy
+			validator := &Updatable{
+				log:         logger.NewTest(t),
+				fileHandler: handler,
+				newValidator: func(m map[uint32][]byte, e []uint32, idkeydigest []byte, enforceIdKeyDigest bool, _ *x509.CertPool, p *policy.Policy, _ *logger.Logger) atls.Validator {
+					gotPolicy = p
+					return fakeValidator{fakeOID: fakeOID{1, 3, 9900, 1}}
+				},
+			}
+			require.NoError(handler.WriteJSON(
+				filepath.Join(constants.ServiceBasePath, constants.MeasurementsFilename),
+				map[uint32][]byte{11: {0x0}},
+			))
+			require.NoError(handler.WriteJSON(
+				filepath.Join(constants.ServiceBasePath, constants.EnforcedPCRsFilename),
+				[]uint32{11},
+			))
+			if tc.policy != nil {
+				require.NoError(handler.Write(
+					filepath.Join(constants.ServiceBasePath, constants.AttestationPolicyFilename),
+					tc.policy,
+				))
+			}
+			validator.publicKey = writeSignature(t, handler)
+
+			err := validator.Update()
+			if tc.wantErr {
+				assert.Error(err)
+				return
+			}
+			require.NoError(err)
+			assert.Equal(tc.wantPolicy, gotPolicy != nil)
+			assert.Equal(string(tc.policy), validator.policy)
+
+			// fakeValidator does not report claims, so a configured policy can't be enforced
+			attDoc, err := json.Marshal(fakeDoc{UserData: []byte("data"), Nonce: []byte("nonce")})
+			require.NoError(err)
+			_, _, enforcedPolicy, err := validator.ValidateWithPolicy(attDoc, []byte("nonce"))
+			if tc.wantPolicy {
+				assert.Error(err)
+			} else {
+				assert.NoError(err)
+			}
+			assert.Equal(string(tc.policy), enforcedPolicy)
+		})
+	}
+}
+
+func TestUpdateEKCertificateAuthority(t *testing.T) {
+	testCases := map[string]struct {
+		csp         cloudprovider.Provider
+		vmType      vmtype.VMType
+		ekCA        []byte
+		wantEKRoots bool
+		wantErr     bool
+	}{
+		"no EK CA": {
+			csp:     cloudprovider.QEMU,
+			wantErr: true,
+		},
+		"no EK CA for TDX": {
+			csp:    cloudprovider.QEMU,
+			vmType: vmtype.QEMUTDX,
+		},
+		"valid EK CA": {
+			csp:         cloudprovider.QEMU,
+			ekCA:        newTestCACertificate(t),
+			wantEKRoots: true,
+		},
+		"invalid EK CA": {
+			csp:     cloudprovider.QEMU,
+			ekCA:    []byte("not a certificate"),
+			wantErr: true,
+		},
+		"ignored for TDX": {
+			csp:    cloudprovider.QEMU,
+			vmType: vmtype.QEMUTDX,
+			ekCA:   []byte("not a certificate"),
+		},
+		"ignored for GCP": {
+			csp:  cloudprovider.GCP,
+			ekCA: []byte("not a certificate"),
+		},
+	}
+
+	for name, tc := range testCases {
+		t.Run(name, func(t *testing.T) {
+			assert := assert.New(t)
+			require := require.New(t)
+
+			handler := file.NewHandler(afero.NewMemMapFs())
+			var gotEKRoots *x509.CertPool
+			validator := &Updatable{
+				log:         logger.NewTest(t),
+				fileHandler: handler,
+				csp:         tc.csp,
+				vmType:      tc.vmType,
+				newValidator: func(m map[uint32][]byte, e []uint32, idkeydigest []byte, enforceIdKeyDigest bool, ekRoots *x509.CertPool, _ *policy.Policy, _ *logger.Logger) atls.Validator {
+					gotEKRoots = ekRoots
+					return fakeValidator{fakeOID: fakeOID{1, 3, 9900, 1}}
+				},
+			}
+			require.NoError(handler.WriteJSON(
+				filepath.Join(constants.ServiceBasePath, constants.MeasurementsFilename),
+				map[uint32][]byte{11: {0x0}},
+			))
+			require.NoError(handler.WriteJSON(
+				filepath.Join(constants.ServiceBasePath, constants.EnforcedPCRsFilename),
+				[]uint32{11},
+			))
+			if tc.ekCA != nil {
+				require.NoError(handler.Write(
+					filepath.Join(constants.ServiceBasePath, constants.EKCertificateAuthorityFilename),
+					tc.ekCA,
+				))
+			}
+			validator.publicKey = writeSignature(t, handler)
+
+			err := validator.Update()
+			if tc.wantErr {
+				assert.Error(err)
+				return
+			}
+			require.NoError(err)
+			assert.Equal(tc.wantEKRoots, gotEKRoots != nil)
+		})
+	}
+}
+
+func TestUpdateSignature(t *testing.T) {
+	testCases := map[string]struct {
+		measurements []byte
+		signature    func(publicKey []byte) []byte
+		wantErr      bool
+	}{
+		"signed update": {
+			measurements: []byte(`{"11":"AQ=="}`),
+		},
+		"unsigned update": {
+			measurements: []byte(`{"11":"AQ=="}`),
+			signature:    func([]byte) []byte { return nil },
+			wantErr:      true,
+		},
+		"signature of previous measurements": {
+			measurements: []byte(`{"11":"AQ=="}`),
+			signature:    func(previous []byte) []byte { return previous },
+			wantErr:      true,
+		},
+		"signed by different key": {
+			measurements: []byte(`{"11":"AQ=="}`),
+			signature: func([]byte) []byte {
+				handler := file.NewHandler(afero.NewMemMapFs())
+				require.NoError(t, handler.Write(filepath.Join(constants.ServiceBasePath, constants.MeasurementsFilename), []byte(`{"11":"AQ=="}`)))
+				require.NoError(t, handler.Write(filepath.Join(constants.ServiceBasePath, constants.EnforcedPCRsFilename), []byte("[11]")))
+				writeSignature(t, handler)
+				signature, err := handler.Read(filepath.Join(constants.ServiceBasePath, constants.MeasurementsSignatureFilename))
+				require.NoError(t, err)
+				return signature
+			},
+			wantErr: true,
+		},
+	}
+
+	for name, tc := range testCases {
+		t.Run(name, func(t *testing.T) {
+			assert := assert.New(t)
+			require := require.New(t)
+
+			handler := file.NewHandler(afero.NewMemMapFs())
+			var gotMeasurements []map[uint32][]byte
+			validator := &Updatable{
+				log:         logger.NewTest(t),
+				fileHandler: handler,
+				newValidator: func(m map[uint32][]byte, e []uint32, idkeydigest []byte, enforceIdKeyDigest bool, _ *x509.CertPool, _ *policy.Policy, _ *logger.Logger) atls.Validator {
+					gotMeasurements = append(gotMeasurements, m)
+					return fakeValidator{fakeOID: fakeOID{1, 3, 9900, 1}}
+				},
+			}
+			require.NoError(handler.Write(filepath.Join(constants.ServiceBasePath, constants.MeasurementsFilename), []byte(`{"11":"AA=="}`)))
+			require.NoError(handler.Write(filepath.Join(constants.ServiceBasePath, constants.EnforcedPCRsFilename), []byte("[11]")))
+			validator.publicKey = writeSignature(t, handler)
+			require.NoError(validator.Update())
+			previousSignature, err := handler.Read(filepath.Join(constants.ServiceBasePath, constants.MeasurementsSignatureFilename))
+			require.NoError(err)
+
+			require.NoError(handler.Write(filepath.Join(constants.ServiceBasePath, constants.MeasurementsFilename), tc.measurements, file.OptOverwrite))
+			if tc.signature != nil {
+				require.NoError(handler.Write(filepath.Join(constants.ServiceBasePath, constants.MeasurementsSignatureFilename), tc.signature(previousSignature), file.OptOverwrite))
+			} else {
+				signJoinConfig(t, handler, validator.publicKey)
+			}
+
+			err = validator.Update()
+			if tc.wantErr {
+				assert.Error(err)
+				// the previous validator is kept
+				assert.Len(gotMeasurements, 1)
+				assert.NotNil(validator.Validator)
+				return
+			}
+			require.NoError(err)
+			require.Len(gotMeasurements, 2)
+			assert.Equal(map[uint32][]byte{11: {0x1}}, gotMeasurements[1])
+		})
+	}
+}
+
+func TestUpdateVersion(t *testing.T) {
+	testCases := map[string]struct {
+		version string
+		wantErr bool
+	}{
+		"newer version": {
+			version: "3",
+		},
+		"same version": {
+			version: "2",
+		},
+		"older version": {
+			version: "1",
+			wantErr: true,
+		},
+		"no version": {
+			wantErr: true,
+		},
+	}
+
+	for name, tc := range testCases {
+		t.Run(name, func(t *testing.T) {
+			assert := assert.New(t)
+			require := require.New(t)
+
+			handler := file.NewHandler(afero.NewMemMapFs())
+			var gotMeasurements []map[uint32][]byte
+			validator := &Updatable{
+				log:         logger.NewTest(t),
+				fileHandler: handler,
+				newValidator: func(m map[uint32][]byte, e []uint32, idkeydigest []byte, enforceIdKeyDigest bool, _ *x509.CertPool, _ *policy.Policy, _ *logger.Logger) atls.Validator {
+					gotMeasurements = append(gotMeasurements, m)
+					return fakeValidator{fakeOID: fakeOID{1, 3, 9900, 1}}
+				},
+			}
+			require.NoError(handler.Write(filepath.Join(constants.ServiceBasePath, constants.MeasurementsFilename), []byte(`{"11":"AA=="}`)))
+			require.NoError(handler.Write(filepath.Join(constants.ServiceBasePath, constants.EnforcedPCRsFilename), []byte("[11]")))
+			require.NoError(handler.Write(filepath.Join(constants.ServiceBasePath, constants.JoinConfigVersionFilename), []byte("2")))
+			validator.publicKey = writeSignature(t, handler)
+			require.NoError(validator.Update())
+
+			// the owner signed every version, an older one is replayed by writing it to the join-config
+			require.NoError(handler.Write(filepath.Join(constants.ServiceBasePath, constants.MeasurementsFilename), []byte(`{"11":"AQ=="}`), file.OptOverwrite))
+			require.NoError(handler.Remove(filepath.Join(constants.ServiceBasePath, constants.JoinConfigVersionFilename)))
+			if tc.version != "" {
+				require.NoError(handler.Write(filepath.Join(constants.ServiceBasePath, constants.JoinConfigVersionFilename), []byte(tc.version)))
+			}
+			signJoinConfig(t, handler, validator.publicKey)
+
+			err := validator.Update()
+			if tc.wantErr {
+				assert.Error(err)
+				// the previous validator is kept
+				assert.Len(gotMeasurements, 1)
+				return
+			}
+			require.NoError(err)
+			require.Len(gotMeasurements, 2)
+			assert.Equal(map[uint32][]byte{11: {0x1}}, gotMeasurements[1])
+		})
+	}
+}
+
+func newTestCACertificate(t *testing.T) []byte {
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	require.NoError(t, err)
+	template := &x509.Certificate{
+		SerialNumber:          big.NewInt(1),
+		NotBefore:             time.Now(),
+		NotAfter:              time.Now().Add(time.Hour),
+		BasicConstraintsValid: true,
+		IsCA:                  true,
+	}
+	der, err := x509.CreateCertificate(rand.Reader, template, template, &key.PublicKey, key)
+	require.NoError(t, err)
+	return pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: der})
+}
+
+// testSigningKeys holds the signing keys created by writeSignature, indexed by their PEM encoded public key.
+var testSigningKeys = map[string]*ecdsa.PrivateKey{}
+
+// writeSignature signs the join-config written to handler using a new key and returns the PEM encoded public key.
+func writeSignature(t *testing.T, handler file.Handler) []byte {
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	require.NoError(t, err)
+	publicKey, err := cryptoutils.MarshalPublicKeyToPEM(key.Public())
+	require.NoError(t, err)
+	testSigningKeys[string(publicKey)] = key
+	signJoinConfig(t, handler, publicKey)
+	return publicKey
+}
+
+// signJoinConfig signs the join-config written to handler using the key previously created for publicKey.
+func signJoinConfig(t *testing.T, handler file.Handler, publicKey []byte) {
+	joinConfig := map[string]string{}
+	for _, key := range joinconfig.SignedKeys {
+		value, err := handler.Read(filepath.Join(constants.ServiceBasePath, key))
+		if errors.Is(err, fs.ErrNotExist) {
+			continue
+		}
+		require.NoError(t, err)
+		joinConfig[key] = string(value)
+	}
+	signature, err := joinconfig.Sign(joinConfig, testSigningKeys[string(publicKey)])
+	require.NoError(t, err)
+	require.NoError(t, handler.Write(filepath.Join(constants.ServiceBasePath, constants.MeasurementsSignatureFilename), signature, file.OptOverwrite))
+}
+
 func testConnection(require *require.Assertions, url string, oid fakeOID) (*http.Response, error) {
 	clientConfig, err := atls.CreateAttestationClientTLSConfig(fakeIssuer{fakeOID: oid}, nil)
 	require.NoError(err)
@@ -274,6 +735,15 @@ func (v fakeValidator) Validate(attDoc []byte, nonce []byte) ([]byte, error) {
 	return doc.UserData, v.err
 }
 
+type keyCachingValidator struct {
+	fakeValidator
+	maxAge time.Duration
+}
+
+func (v *keyCachingValidator) EnableTrustedKeyCache(maxAge time.Duration) {
+	v.maxAge = maxAge
+}
+
 type fakeOID asn1.ObjectIdentifier
 
 func (o fakeOID) OID() asn1.ObjectIdentifier {
@@ -11,25 +11,37 @@ import (
 	"errors"
 	"flag"
 	"net"
+	"net/http"
+	"os"
 	"path/filepath"
 	"strconv"
 	"time"
 
 	"github.com/edgelesssys/constellation/v2/internal/atls"
+	"github.com/edgelesssys/constellation/v2/internal/attestation/eat"
 	azurecloud "github.com/edgelesssys/constellation/v2/internal/cloud/azure"
 	"github.com/edgelesssys/constellation/v2/internal/cloud/cloudprovider"
 	gcpcloud "github.com/edgelesssys/constellation/v2/internal/cloud/gcp"
 	"github.com/edgelesssys/constellation/v2/internal/cloud/metadata"
 	qemucloud "github.com/edgelesssys/constellation/v2/internal/cloud/qemu"
+	"github.com/edgelesssys/constellation/v2/internal/cloud/vmtype"
 	"github.com/edgelesssys/constellation/v2/internal/constants"
 	"github.com/edgelesssys/constellation/v2/internal/file"
 	"github.com/edgelesssys/constellation/v2/internal/grpc/atlscredentials"
+	"github.com/edgelesssys/constellation/v2/internal/joinconfig"
 	"github.com/edgelesssys/constellation/v2/internal/logger"
 	"github.com/edgelesssys/constellation/v2/internal/watcher"
+	"github.com/edgelesssys/constellation/v2/joinservice/internal/diskowner"
+	"github.com/edgelesssys/constellation/v2/joinservice/internal/joinrecord"
 	"github.com/edgelesssys/constellation/v2/joinservice/internal/kms"
 	"github.com/edgelesssys/constellation/v2/joinservice/internal/kubeadm"
 	"github.com/edgelesssys/constellation/v2/joinservice/internal/kubernetesca"
+	"github.com/edgelesssys/constellation/v2/joinservice/internal/membership"
+	"github.com/edgelesssys/constellation/v2/joinservice/internal/reattestation"
+	"github.com/edgelesssys/constellation/v2/joinservice/internal/revocation"
 	"github.com/edgelesssys/constellation/v2/joinservice/internal/server"
+	"github.com/edgelesssys/constellation/v2/joinservice/internal/verifier"
+	"github.com/prometheus/client_golang/prometheus/promhttp"
 	"github.com/spf13/afero"
 	"go.uber.org/zap"
 )
@@ -40,6 +52,19 @@ const vpcIPTimeout = 30 * time.Second
 func main() {
 	provider := flag.String("cloud-provider", "", "cloud service provider this binary is running on")
 	kmsEndpoint := flag.String("kms-endpoint", "", "endpoint of Constellations key management service")
+	attestationVerifier := flag.Bool("attestation-verifier", false,
+		"serve signed attestation result tokens for attested nodes on port "+strconv.Itoa(constants.AttestationVerifierPort))
+	reattestationInterval := flag.Duration("reattestation-interval", 10*time.Minute,
+		"interval for re-attesting all nodes of the cluster, 0 disables re-attestation")
+	quarantineAction := flag.String("quarantine-action", string(reattestation.ActionNone),
+		"action applied to nodes failing re-attestation: none, cordon or taint")
+	quarantineThreshold := flag.Int("quarantine-threshold", 3, "number of consecutive failed re-attestations before a node is quarantined")
+	attestationCacheTTL := flag.Duration("attestation-cache-ttl", 0,
+		"duration for which verified attestation keys and attestation results are reused, 0 disables caching")
+	kubeletCertValidity := flag.Duration("kubelet-cert-validity", constants.KubeletCertificateValidity,
+		"validity period of kubelet certificates issued to joining and renewing nodes")
+	joinRecordRetention := flag.Duration("join-record-retention", 30*24*time.Hour,
+		"duration for which join records are kept, 0 keeps join records forever")
 	verbosity := flag.Int("v", 0, logger.CmdLineVerbosityDescription)
 	flag.Parse()
 
@@ -57,22 +82,61 @@ func main() {
 	if err != nil {
 		log.With(zap.Error(err)).Fatalf("Failed to parse content of AzureCVM: %s", cvmRaw)
 	}
+	// clusters created before TDX support do not have the qemuTDX key
+	var qemuTDX bool
+	if tdxRaw, err := handler.Read(filepath.Join(constants.ServiceBasePath, constants.QEMUTDX)); err == nil {
+		qemuTDX, err = strconv.ParseBool(string(tdxRaw))
+		if err != nil {
+			log.With(zap.Error(err)).Fatalf("Failed to parse content of QEMUTDX: %s", tdxRaw)
+		}
+	}
+
+	vmType := vmtype.Unknown
+	switch {
+	case azureCVM:
+		vmType = vmtype.AzureCVM
+	case qemuTDX:
+		vmType = vmtype.QEMUTDX
+	}
+
+	// the owner's public key is pinned at initialization and read only once,
+	// the join-config is only trusted if it is signed with the matching private key
+	ownerPublicKey, err := handler.Read(filepath.Join(constants.ServiceBasePath, constants.OwnerPublicKeyFilename))
+	if err != nil {
+		log.With(zap.Error(err)).Fatalf("Failed to read owner public key")
+	}
 
-	validator, err := watcher.NewValidator(log.Named("validator"), *provider, handler, azureCVM)
+	// the CLI signs the join-config right after initialization
+	validator, err := watcher.NewValidator(log.Named("validator"), *provider, handler, vmType, ownerPublicKey)
+	for errors.Is(err, joinconfig.ErrUnsigned) {
+		log.Infof("Waiting for the cluster owner to sign the join-config")
+		time.Sleep(10 * time.Second)
+		validator, err = watcher.NewValidator(log.Named("validator"), *provider, handler, vmType, ownerPublicKey)
+	}
 	if err != nil {
 		flag.Usage()
 		log.With(zap.Error(err)).Fatalf("Failed to create validator")
 	}
 
 	creds := atlscredentials.New(nil, []atls.Validator{validator})
+	if *attestationCacheTTL > 0 {
+		validator.EnableTrustedKeyCache(*attestationCacheTTL)
+		cache := atls.NewCache(*attestationCacheTTL)
+		validator.InvalidateOnUpdate(cache)
+		creds = atlscredentials.NewWithCache(nil, []atls.Validator{validator}, cache)
+	}
 
 	ctx, cancel := context.WithTimeout(context.Background(), vpcIPTimeout)
 	defer cancel()
-	vpcIP, err := getVPCIP(ctx, *provider)
+	metadataAPI, err := newMetadata(ctx, *provider)
+	if err != nil {
+		log.With(zap.Error(err)).Fatalf("Failed to create metadata API")
+	}
+	self, err := metadataAPI.Self(ctx)
 	if err != nil {
 		log.With(zap.Error(err)).Fatalf("Failed to get IP in VPC")
 	}
-	apiServerEndpoint := net.JoinHostPort(vpcIP, strconv.Itoa(constants.KubernetesPort))
+	apiServerEndpoint := net.JoinHostPort(self.VPCIP, strconv.Itoa(constants.KubernetesPort))
 	kubeadm, err := kubeadm.New(apiServerEndpoint, log.Named("kubeadm"))
 	if err != nil {
 		log.With(zap.Error(err)).Fatalf("Failed to create kubeadm")
@@ -84,15 +148,103 @@ func main() {
 		log.With(zap.Error(err)).Fatalf("Failed to read measurement salt")
 	}
 
+	diskOwnerClient, err := diskowner.NewClient()
+	if err != nil {
+		log.With(zap.Error(err)).Fatalf("Failed to create Kubernetes client for disk owners")
+	}
+
+	revocationClient, err := revocation.NewClient()
+	if err != nil {
+		log.With(zap.Error(err)).Fatalf("Failed to create Kubernetes client for revocation list")
+	}
+
+	joinRecordClient, err := joinrecord.NewClient()
+	if err != nil {
+		log.With(zap.Error(err)).Fatalf("Failed to create Kubernetes client for join records")
+	}
+	joinRecords := joinrecord.New(joinRecordClient, *joinRecordRetention, log.Named("joinRecords"))
+	go joinRecords.RunPruning(context.Background(), time.Hour)
+	handshakeRecords := joinrecord.NewAggregator(joinRecords, joinrecord.DefaultAggregationInterval, log.Named("handshakeRecords"))
+	go handshakeRecords.Run(context.Background())
+
+	diskOwners := diskowner.New(diskOwnerClient, log.Named("diskOwners"))
+	go diskOwners.RunCollection(context.Background(), metadataAPI, time.Hour)
+	certificateAuthority := kubernetesca.New(log.Named("certificateAuthority"), handler, *kubeletCertValidity)
+	go certificateAuthority.RunCollection(context.Background(), metadataAPI, time.Hour)
+
 	server := server.New(
 		measurementSalt,
 		handler,
-		kubernetesca.New(log.Named("certificateAuthority"), handler),
+		certificateAuthority,
 		kubeadm,
 		kms,
+		diskOwners,
+		membership.New(metadataAPI, log.Named("membership")),
+		revocation.New(revocationClient, log.Named("revocation")),
+		joinRecords,
+		handshakeRecords,
 		log.Named("server"),
 	)
 
+	go func() {
+		mux := http.NewServeMux()
+		mux.Handle("/metrics", promhttp.Handler())
+		if err := http.ListenAndServe(net.JoinHostPort("", strconv.Itoa(constants.JoinServiceMetricsPort)), mux); err != nil {
+			log.With(zap.Error(err)).Fatalf("Failed to serve metrics")
+		}
+	}()
+
+	if *attestationVerifier {
+		signingKey, err := verifier.ReadSigningKey(handler)
+		if err != nil {
+			log.With(zap.Error(err)).Fatalf("Failed to read attestation result token signing key")
+		}
+		attestationVerifier, err := verifier.New(*provider, validator, signingKey, eat.DefaultValidity, log.Named("attestationVerifier"))
+		if err != nil {
+			log.With(zap.Error(err)).Fatalf("Failed to create attestation verifier")
+		}
+		lis, err := net.Listen("tcp", net.JoinHostPort("", strconv.Itoa(constants.AttestationVerifierPort)))
+		if err != nil {
+			log.With(zap.Error(err)).Fatalf("Failed to listen for attestation verifier")
+		}
+		go func() {
+			if err := attestationVerifier.Run(lis); err != nil {
+				log.With(zap.Error(err)).Fatalf("Failed to run attestation verifier")
+			}
+		}()
+	}
+
+	if *reattestationInterval > 0 {
+		action, err := reattestation.ParseAction(*quarantineAction)
+		if err != nil {
+			log.With(zap.Error(err)).Fatalf("Invalid quarantine action")
+		}
+		kubeClient, err := reattestation.NewClient()
+		if err != nil {
+			log.With(zap.Error(err)).Fatalf("Failed to create Kubernetes client for re-attestation")
+		}
+		identity, err := os.Hostname()
+		if err != nil {
+			log.With(zap.Error(err)).Fatalf("Failed to get hostname")
+		}
+		controller := reattestation.New(
+			validator,
+			reattestation.NewVerificationClient(),
+			kubeClient,
+			metadataAPI,
+			reattestation.Policy{Action: action, FailureThreshold: *quarantineThreshold},
+			log.Named("reattestation"),
+		)
+		// only one join service instance re-attests the nodes
+		go func() {
+			if err := kubeClient.RunLeaderElected(context.Background(), identity, func(ctx context.Context) {
+				controller.Run(ctx, *reattestationInterval)
+			}); err != nil {
+				log.With(zap.Error(err)).Fatalf("Failed to run re-attestation")
+			}
+		}()
+	}
+
 	watcher, err := watcher.New(log.Named("fileWatcher"), validator)
 	if err != nil {
 		log.With(zap.Error(err)).Fatalf("Failed to create watcher for measurements updates")
@@ -111,35 +263,24 @@ func main() {
 	}
 }
 
-func getVPCIP(ctx context.Context, provider string) (string, error) {
-	var metadata metadataAPI
-	var err error
-
+func newMetadata(ctx context.Context, provider string) (metadataAPI, error) {
 	switch cloudprovider.FromString(provider) {
 	case cloudprovider.Azure:
-		metadata, err = azurecloud.NewMetadata(ctx)
-		if err != nil {
-			return "", err
-		}
+		return azurecloud.NewMetadata(ctx)
 	case cloudprovider.GCP:
 		gcpClient, err := gcpcloud.NewClient(ctx)
 		if err != nil {
-			return "", err
+			return nil, err
 		}
-		metadata = gcpcloud.New(gcpClient)
+		return gcpcloud.New(gcpClient), nil
 	case cloudprovider.QEMU:
-		metadata = &qemucloud.Metadata{}
+		return &qemucloud.Metadata{}, nil
 	default:
-		return "", errors.New("unsupported cloud provider")
-	}
-
-	self, err := metadata.Self(ctx)
-	if err != nil {
-		return "", err
+		return nil, errors.New("unsupported cloud provider")
 	}
-	return self.VPCIP, nil
 }
 
 type metadataAPI interface {
-	Self(ctx context.Context) (metadata.InstanceMetadata, error)
+	metadata.InstanceSelfer
+	metadata.InstanceLister
 }
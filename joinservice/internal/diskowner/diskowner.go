@@ -0,0 +1,281 @@
+/*
+Copyright (c) Edgeless Systems GmbH
+
+SPDX-License-Identifier: AGPL-3.0-only
+*/
+
+/*
+Package diskowner binds state disks to the attested nodes they belong to.
+
+The key of a state disk is derived from its UUID. Without binding, any attested node could request the key of another node's disk.
+The first node requesting the key of a disk becomes its owner, and only the owner may request the key again.
+Owners are identified by their attested identity, e.g. the instance ID, and are recorded in a ConfigMap.
+The name of the node using a disk is recorded alongside, under the key "<disk UUID>.node", to look up the disks and identity of a node.
+
+The disk of the first control-plane node is registered during cluster initialization, before the join service is running.
+Its owner is recorded as empty, and the first node with the recorded name requesting the key becomes the owner.
+
+Disks of nodes whose instance no longer exists are retired by Collect: the owner is replaced by a tombstone and the node name is removed.
+The key of a retired disk is never released again, since a copy of the disk would otherwise be accepted as a new disk by any attested node.
+Tombstones are only removed by an administrator editing the ConfigMap, after all copies of the disk are known to be destroyed.
+*/
+package diskowner
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"regexp"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/edgelesssys/constellation/v2/internal/attestation"
+	"github.com/edgelesssys/constellation/v2/internal/cloud/metadata"
+	"github.com/edgelesssys/constellation/v2/internal/constants"
+	"github.com/edgelesssys/constellation/v2/internal/logger"
+	"go.uber.org/zap"
+	corev1 "k8s.io/api/core/v1"
+	k8serrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/util/retry"
+)
+
+var (
+	// ErrForeignDisk is returned if a node requests the key of a disk bound to a different node.
+	ErrForeignDisk = errors.New("disk belongs to a different node")
+	// ErrInvalidDiskUUID is returned if a disk UUID is malformed, or is reserved for keys other than disk keys.
+	ErrInvalidDiskUUID = errors.New("invalid disk UUID")
+)
+
+// diskUUIDRegexp matches canonical, lower case disk UUIDs, as reported by the disk mapper.
+var diskUUIDRegexp = regexp.MustCompile(`^[0-9a-f]{8}-[0-9a-f]{4}-[0-9a-f]{4}-[0-9a-f]{4}-[0-9a-f]{12}$`)
+
+// reservedKeyIDs are the IDs of keys derived from the KMS for purposes other than disk encryption.
+// None of them is a UUID, but they are rejected explicitly, so that a change of the UUID format can't expose them.
+var reservedKeyIDs = map[string]struct{}{
+	"joinConfigSigningKey":               {}, // used by previous versions to sign the join-config
+	"attestationTokenSigningKey":         {}, // used by previous versions to sign attestation tokens
+	attestation.MeasurementSecretContext: {},
+	constants.EtcdBackupKeyID:            {},
+}
+
+// Registry records the owner of every state disk.
+type Registry struct {
+	kube kubeClient
+	log  *logger.Logger
+
+	collectMux sync.Mutex
+	// orphans are disks whose node had no instance during the last collection.
+	orphans map[string]struct{}
+}
+
+// New creates a new Registry.
+func New(kube kubeClient, log *logger.Logger) *Registry {
+	return &Registry{kube: kube, log: log}
+}
+
+// Bind binds the disk to owner when its key is requested for the first time, and verifies the owner on later requests.
+// If the disk belongs to a different node, an audit event is recorded and ErrForeignDisk is returned.
+// If nodeName is not empty, it is recorded as the name of the node using the disk.
+// A disk registered during cluster initialization is bound to the first owner requesting it with the registered node name.
+func (r *Registry) Bind(ctx context.Context, diskUUID, owner, nodeName string) error {
+	if err := validateDiskUUID(diskUUID); err != nil {
+		return err
+	}
+	if owner == "" {
+		return errors.New("owner of disk must not be empty")
+	}
+
+	var recordedOwner string
+	err := retry.OnError(retry.DefaultRetry, isConflict, func() error {
+		owners, err := r.kube.GetConfigMap(ctx, constants.DiskOwnersConfigMap)
+		if k8serrors.IsNotFound(err) {
+			recordedOwner = owner
+			data := map[string]string{diskUUID: owner}
+			if nodeName != "" {
+				data[diskUUID+constants.DiskOwnersNodeKeySuffix] = nodeName
+			}
+			return r.kube.CreateConfigMap(ctx, &corev1.ConfigMap{
+				ObjectMeta: metav1.ObjectMeta{Name: constants.DiskOwnersConfigMap, Namespace: constants.ConstellationNamespace},
+				Data:       data,
+			})
+		}
+		if err != nil {
+			return err
+		}
+		if owners.Data == nil {
+			owners.Data = make(map[string]string)
+		}
+		current, ok := owners.Data[diskUUID]
+		recordedNode := owners.Data[diskUUID+constants.DiskOwnersNodeKeySuffix]
+		switch {
+		case ok && current == constants.DiskOwnerRetired:
+			// the node of the disk was deleted, a node requesting its key uses a copy of the disk
+			recordedOwner = current
+			return nil
+		case ok && current == "":
+			// registered during cluster initialization, only the registered node may claim the disk
+			if nodeName == "" || nodeName != recordedNode {
+				recordedOwner = fmt.Sprintf("registered node %q", recordedNode)
+				return nil
+			}
+		case ok && current != owner:
+			recordedOwner = current
+			return nil
+		case ok && (nodeName == "" || recordedNode == nodeName):
+			recordedOwner = owner
+			return nil
+		}
+		recordedOwner = owner
+		owners.Data[diskUUID] = owner
+		if nodeName != "" {
+			owners.Data[diskUUID+constants.DiskOwnersNodeKeySuffix] = nodeName
+		}
+		return r.kube.UpdateConfigMap(ctx, owners)
+	})
+	if err != nil {
+		return fmt.Errorf("recording owner of disk %s: %w", diskUUID, err)
+	}
+	if recordedOwner != owner {
+		r.recordRejection(ctx, diskUUID, owner, recordedOwner)
+		return ErrForeignDisk
+	}
+	return nil
+}
+
+// Collect retires the disks whose node has no instance in the cluster anymore.
+// The owner of a retired disk is replaced by constants.DiskOwnerRetired, so no node can bind the disk again,
+// and its node name is removed.
+// Disks are only retired if their node was missing in two consecutive collections,
+// so that disks of nodes joining while the instances are listed are kept.
+func (r *Registry) Collect(ctx context.Context, instances []metadata.InstanceMetadata) error {
+	// an empty list is more likely a failure of the metadata API than a cluster without nodes
+	if len(instances) == 0 {
+		return errors.New("no instances listed, not collecting disk owners")
+	}
+	names := make(map[string]struct{}, len(instances))
+	for _, instance := range instances {
+		names[instance.Name] = struct{}{}
+	}
+
+	r.collectMux.Lock()
+	defer r.collectMux.Unlock()
+
+	var orphans map[string]struct{}
+	var retired []string
+	err := retry.RetryOnConflict(retry.DefaultRetry, func() error {
+		orphans = make(map[string]struct{})
+		retired = nil
+		owners, err := r.kube.GetConfigMap(ctx, constants.DiskOwnersConfigMap)
+		if k8serrors.IsNotFound(err) {
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+
+		for key, nodeName := range owners.Data {
+			diskUUID := strings.TrimSuffix(key, constants.DiskOwnersNodeKeySuffix)
+			if diskUUID == key {
+				continue
+			}
+			if _, ok := names[nodeName]; ok {
+				continue
+			}
+			if _, ok := r.orphans[diskUUID]; !ok {
+				orphans[diskUUID] = struct{}{}
+				continue
+			}
+			owners.Data[diskUUID] = constants.DiskOwnerRetired
+			delete(owners.Data, key)
+			retired = append(retired, diskUUID)
+		}
+		if len(retired) == 0 {
+			return nil
+		}
+		return r.kube.UpdateConfigMap(ctx, owners)
+	})
+	if err != nil {
+		return fmt.Errorf("retiring disks of deleted nodes: %w", err)
+	}
+	r.orphans = orphans
+	for _, diskUUID := range retired {
+		r.log.With(zap.String("diskUUID", diskUUID)).Infof("Retired disk of deleted node")
+	}
+	return nil
+}
+
+// RunCollection calls Collect every interval with the instances listed by lister, until ctx is done.
+func (r *Registry) RunCollection(ctx context.Context, lister metadata.InstanceLister, interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+
+		instances, err := lister.List(ctx)
+		if err != nil {
+			r.log.With(zap.Error(err)).Errorf("Failed to list instances for collecting disk owners")
+			continue
+		}
+		if err := r.Collect(ctx, instances); err != nil {
+			r.log.With(zap.Error(err)).Errorf("Failed to collect disk owners")
+		}
+	}
+}
+
+// recordRejection records an audit event for a rejected request for the key of a foreign disk.
+func (r *Registry) recordRejection(ctx context.Context, diskUUID, requester, owner string) {
+	log := r.log.With(zap.Bool("audit", true), zap.String("diskUUID", diskUUID), zap.String("requester", requester), zap.String("owner", owner))
+	log.Warnf("Rejected request for the key of a disk belonging to a different node")
+
+	now := metav1.Now()
+	event := &corev1.Event{
+		ObjectMeta: metav1.ObjectMeta{
+			GenerateName: constants.DiskOwnersConfigMap + ".",
+			Namespace:    constants.ConstellationNamespace,
+		},
+		InvolvedObject: corev1.ObjectReference{
+			APIVersion: "v1",
+			Kind:       "ConfigMap",
+			Name:       constants.DiskOwnersConfigMap,
+			Namespace:  constants.ConstellationNamespace,
+		},
+		Reason:         "ForeignDiskKeyRequested",
+		Message:        fmt.Sprintf("Rejected request of %s for the key of disk %s, which belongs to %s", requester, diskUUID, owner),
+		Type:           corev1.EventTypeWarning,
+		Source:         corev1.EventSource{Component: "join-service"},
+		FirstTimestamp: now,
+		LastTimestamp:  now,
+		Count:          1,
+	}
+	if err := r.kube.CreateEvent(ctx, event); err != nil {
+		log.With(zap.Error(err)).Errorf("Failed to record audit event")
+	}
+}
+
+// validateDiskUUID checks that diskUUID is a canonical UUID, and not the ID of a reserved key.
+func validateDiskUUID(diskUUID string) error {
+	if _, ok := reservedKeyIDs[diskUUID]; ok {
+		return fmt.Errorf("%w: %q is reserved", ErrInvalidDiskUUID, diskUUID)
+	}
+	if !diskUUIDRegexp.MatchString(diskUUID) {
+		return fmt.Errorf("%w: %q", ErrInvalidDiskUUID, diskUUID)
+	}
+	return nil
+}
+
+func isConflict(err error) bool {
+	return k8serrors.IsConflict(err) || k8serrors.IsAlreadyExists(err)
+}
+
+type kubeClient interface {
+	GetConfigMap(ctx context.Context, name string) (*corev1.ConfigMap, error)
+	CreateConfigMap(ctx context.Context, configMap *corev1.ConfigMap) error
+	UpdateConfigMap(ctx context.Context, configMap *corev1.ConfigMap) error
+	CreateEvent(ctx context.Context, event *corev1.Event) error
+}
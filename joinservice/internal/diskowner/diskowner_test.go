@@ -0,0 +1,365 @@
+/*
+Copyright (c) Edgeless Systems GmbH
+
+SPDX-License-Identifier: AGPL-3.0-only
+*/
+
+package diskowner
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/edgelesssys/constellation/v2/internal/attestation"
+	"github.com/edgelesssys/constellation/v2/internal/cloud/metadata"
+	"github.com/edgelesssys/constellation/v2/internal/constants"
+	"github.com/edgelesssys/constellation/v2/internal/logger"
+	"github.com/stretchr/testify/assert"
+	"go.uber.org/goleak"
+	corev1 "k8s.io/api/core/v1"
+	k8serrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+)
+
+func TestMain(m *testing.M) {
+	goleak.VerifyTestMain(m)
+}
+
+func TestBind(t *testing.T) {
+	someErr := errors.New("failed")
+	gr := schema.GroupResource{Resource: "configmaps"}
+	diskA := "5c2d9f0e-7a4b-4d1c-9e3f-0a1b2c3d4e5f"
+	diskB := "8e7f6a5b-4c3d-4e2f-8a1b-9c0d1e2f3a4b"
+
+	testCases := map[string]struct {
+		kube        *stubKubeClient
+		diskUUID    string
+		nodeName    string
+		noOwner     bool
+		wantOwners  map[string]string
+		wantEvent   bool
+		wantForeign bool
+		wantErr     bool
+	}{
+		"first disk": {
+			kube:       &stubKubeClient{},
+			diskUUID:   diskA,
+			wantOwners: map[string]string{diskA: "node-a"},
+		},
+		"first disk with node name": {
+			kube:       &stubKubeClient{},
+			diskUUID:   diskA,
+			nodeName:   "node-a-0",
+			wantOwners: map[string]string{diskA: "node-a", diskA + ".node": "node-a-0"},
+		},
+		"node name of owned disk is updated": {
+			kube:       &stubKubeClient{owners: map[string]string{diskA: "node-a", diskA + ".node": "old-name"}},
+			diskUUID:   diskA,
+			nodeName:   "node-a-0",
+			wantOwners: map[string]string{diskA: "node-a", diskA + ".node": "node-a-0"},
+		},
+		"node name of foreign disk is kept": {
+			kube:        &stubKubeClient{owners: map[string]string{diskA: "node-b", diskA + ".node": "node-b-0"}},
+			diskUUID:    diskA,
+			nodeName:    "node-a-0",
+			wantOwners:  map[string]string{diskA: "node-b", diskA + ".node": "node-b-0"},
+			wantEvent:   true,
+			wantForeign: true,
+			wantErr:     true,
+		},
+		"new disk": {
+			kube:       &stubKubeClient{owners: map[string]string{diskB: "node-b"}},
+			diskUUID:   diskA,
+			wantOwners: map[string]string{diskA: "node-a", diskB: "node-b"},
+		},
+		"disk owned by node": {
+			kube:       &stubKubeClient{owners: map[string]string{diskA: "node-a"}},
+			diskUUID:   diskA,
+			wantOwners: map[string]string{diskA: "node-a"},
+		},
+		"disk owned by different node": {
+			kube:        &stubKubeClient{owners: map[string]string{diskA: "node-b"}},
+			diskUUID:    diskA,
+			wantOwners:  map[string]string{diskA: "node-b"},
+			wantEvent:   true,
+			wantForeign: true,
+			wantErr:     true,
+		},
+		"retired disk": {
+			kube:        &stubKubeClient{owners: map[string]string{diskA: constants.DiskOwnerRetired}},
+			diskUUID:    diskA,
+			nodeName:    "node-a-0",
+			wantOwners:  map[string]string{diskA: constants.DiskOwnerRetired},
+			wantEvent:   true,
+			wantForeign: true,
+			wantErr:     true,
+		},
+		"creating event fails": {
+			kube:        &stubKubeClient{owners: map[string]string{diskA: "node-b"}, createEventErr: someErr},
+			diskUUID:    diskA,
+			wantOwners:  map[string]string{diskA: "node-b"},
+			wantForeign: true,
+			wantErr:     true,
+		},
+		"conflicting update is retried": {
+			kube: &stubKubeClient{
+				owners:    map[string]string{},
+				updateErr: []error{k8serrors.NewConflict(gr, constants.DiskOwnersConfigMap, someErr)},
+			},
+			diskUUID:   diskA,
+			wantOwners: map[string]string{diskA: "node-a"},
+		},
+		"concurrent create is retried": {
+			kube: &stubKubeClient{
+				createErr: []error{k8serrors.NewAlreadyExists(gr, constants.DiskOwnersConfigMap)},
+			},
+			diskUUID:   diskA,
+			wantOwners: map[string]string{diskA: "node-a"},
+		},
+		"get fails": {
+			kube:     &stubKubeClient{getErr: someErr},
+			diskUUID: diskA,
+			wantErr:  true,
+		},
+		"pre-registered disk is claimed by registered node": {
+			kube:       &stubKubeClient{owners: map[string]string{diskA: "", diskA + ".node": "node-a-0"}},
+			diskUUID:   diskA,
+			nodeName:   "node-a-0",
+			wantOwners: map[string]string{diskA: "node-a", diskA + ".node": "node-a-0"},
+		},
+		"pre-registered disk of different node": {
+			kube:        &stubKubeClient{owners: map[string]string{diskA: "", diskA + ".node": "node-b-0"}},
+			diskUUID:    diskA,
+			nodeName:    "node-a-0",
+			wantOwners:  map[string]string{diskA: "", diskA + ".node": "node-b-0"},
+			wantEvent:   true,
+			wantForeign: true,
+			wantErr:     true,
+		},
+		"pre-registered disk requested without node name": {
+			kube:        &stubKubeClient{owners: map[string]string{diskA: "", diskA + ".node": "node-a-0"}},
+			diskUUID:    diskA,
+			wantOwners:  map[string]string{diskA: "", diskA + ".node": "node-a-0"},
+			wantEvent:   true,
+			wantForeign: true,
+			wantErr:     true,
+		},
+		"invalid disk UUID": {
+			kube:     &stubKubeClient{},
+			diskUUID: "../disk",
+			wantErr:  true,
+		},
+		"upper case disk UUID": {
+			kube:     &stubKubeClient{},
+			diskUUID: "5C2D9F0E-7A4B-4D1C-9E3F-0A1B2C3D4E5F",
+			wantErr:  true,
+		},
+		"reserved key ID": {
+			kube:     &stubKubeClient{},
+			diskUUID: constants.EtcdBackupKeyID,
+			wantErr:  true,
+		},
+		"measurement secret": {
+			kube:     &stubKubeClient{},
+			diskUUID: attestation.MeasurementSecretContext,
+			wantErr:  true,
+		},
+		"no owner": {
+			kube:     &stubKubeClient{},
+			diskUUID: diskA,
+			noOwner:  true,
+			wantErr:  true,
+		},
+	}
+
+	for name, tc := range testCases {
+		t.Run(name, func(t *testing.T) {
+			assert := assert.New(t)
+
+			registry := New(tc.kube, logger.NewTest(t))
+
+			owner := "node-a"
+			if tc.noOwner {
+				owner = ""
+			}
+			err := registry.Bind(context.Background(), tc.diskUUID, owner, tc.nodeName)
+			if tc.wantErr {
+				assert.Error(err)
+			} else {
+				assert.NoError(err)
+			}
+			assert.Equal(tc.wantForeign, errors.Is(err, ErrForeignDisk))
+			assert.Equal(tc.wantOwners, tc.kube.owners)
+			if tc.wantEvent {
+				assert.Len(tc.kube.events, 1)
+				assert.Equal(corev1.EventTypeWarning, tc.kube.events[0].Type)
+				assert.Equal(constants.DiskOwnersConfigMap, tc.kube.events[0].InvolvedObject.Name)
+			} else {
+				assert.Empty(tc.kube.events)
+			}
+		})
+	}
+}
+
+func TestCollect(t *testing.T) {
+	diskA := "5c2d9f0e-7a4b-4d1c-9e3f-0a1b2c3d4e5f"
+	diskB := "8e7f6a5b-4c3d-4e2f-8a1b-9c0d1e2f3a4b"
+	diskC := "1a2b3c4d-5e6f-4a7b-8c9d-0e1f2a3b4c5d"
+
+	testCases := map[string]struct {
+		kube       *stubKubeClient
+		rounds     [][]metadata.InstanceMetadata
+		wantOwners map[string]string
+		wantErr    bool
+	}{
+		"disks of existing nodes are kept": {
+			kube: &stubKubeClient{owners: map[string]string{diskA: "a", diskA + ".node": "node-a"}},
+			rounds: [][]metadata.InstanceMetadata{
+				{{Name: "node-a"}},
+				{{Name: "node-a"}},
+			},
+			wantOwners: map[string]string{diskA: "a", diskA + ".node": "node-a"},
+		},
+		"disk of deleted node is kept after one round": {
+			kube: &stubKubeClient{owners: map[string]string{
+				diskA: "a", diskA + ".node": "node-a",
+				diskB: "b", diskB + ".node": "node-b",
+			}},
+			rounds: [][]metadata.InstanceMetadata{
+				{{Name: "node-a"}},
+			},
+			wantOwners: map[string]string{
+				diskA: "a", diskA + ".node": "node-a",
+				diskB: "b", diskB + ".node": "node-b",
+			},
+		},
+		"disk of deleted node is retired after two rounds": {
+			kube: &stubKubeClient{owners: map[string]string{
+				diskA: "a", diskA + ".node": "node-a",
+				diskB: "b", diskB + ".node": "node-b",
+				diskC: "c",
+			}},
+			rounds: [][]metadata.InstanceMetadata{
+				{{Name: "node-a"}},
+				{{Name: "node-a"}},
+			},
+			wantOwners: map[string]string{
+				diskA: "a", diskA + ".node": "node-a",
+				diskB: constants.DiskOwnerRetired,
+				diskC: "c",
+			},
+		},
+		"retired disk is kept": {
+			kube: &stubKubeClient{owners: map[string]string{diskB: "b", diskB + ".node": "node-b"}},
+			rounds: [][]metadata.InstanceMetadata{
+				{{Name: "node-a"}},
+				{{Name: "node-a"}},
+				{{Name: "node-a"}},
+				{{Name: "node-a"}},
+			},
+			wantOwners: map[string]string{diskB: constants.DiskOwnerRetired},
+		},
+		"node reappearing in between is kept": {
+			kube: &stubKubeClient{owners: map[string]string{diskB: "b", diskB + ".node": "node-b"}},
+			rounds: [][]metadata.InstanceMetadata{
+				{{Name: "node-a"}},
+				{{Name: "node-a"}, {Name: "node-b"}},
+				{{Name: "node-a"}},
+			},
+			wantOwners: map[string]string{diskB: "b", diskB + ".node": "node-b"},
+		},
+		"no ConfigMap": {
+			kube: &stubKubeClient{},
+			rounds: [][]metadata.InstanceMetadata{
+				{{Name: "node-a"}},
+			},
+		},
+		"no instances": {
+			kube:       &stubKubeClient{owners: map[string]string{diskA: "a", diskA + ".node": "node-a"}},
+			rounds:     [][]metadata.InstanceMetadata{{}},
+			wantOwners: map[string]string{diskA: "a", diskA + ".node": "node-a"},
+			wantErr:    true,
+		},
+		"get fails": {
+			kube: &stubKubeClient{getErr: errors.New("failed")},
+			rounds: [][]metadata.InstanceMetadata{
+				{{Name: "node-a"}},
+			},
+			wantErr: true,
+		},
+	}
+
+	for name, tc := range testCases {
+		t.Run(name, func(t *testing.T) {
+			assert := assert.New(t)
+
+			registry := New(tc.kube, logger.NewTest(t))
+
+			var err error
+			for _, instances := range tc.rounds {
+				err = registry.Collect(context.Background(), instances)
+			}
+			if tc.wantErr {
+				assert.Error(err)
+			} else {
+				assert.NoError(err)
+			}
+			assert.Equal(tc.wantOwners, tc.kube.owners)
+		})
+	}
+}
+
+// stubKubeClient stores the owners ConfigMap in memory.
+// Errors in createErr and updateErr are returned by consecutive calls, before the ConfigMap is modified.
+type stubKubeClient struct {
+	owners         map[string]string
+	getErr         error
+	createErr      []error
+	updateErr      []error
+	createEventErr error
+	events         []*corev1.Event
+}
+
+func (s *stubKubeClient) GetConfigMap(_ context.Context, name string) (*corev1.ConfigMap, error) {
+	if s.getErr != nil {
+		return nil, s.getErr
+	}
+	if s.owners == nil {
+		return nil, k8serrors.NewNotFound(schema.GroupResource{Resource: "configmaps"}, name)
+	}
+	data := make(map[string]string, len(s.owners))
+	for k, v := range s.owners {
+		data[k] = v
+	}
+	return &corev1.ConfigMap{ObjectMeta: metav1.ObjectMeta{Name: name}, Data: data}, nil
+}
+
+func (s *stubKubeClient) CreateConfigMap(_ context.Context, configMap *corev1.ConfigMap) error {
+	if len(s.createErr) > 0 {
+		err := s.createErr[0]
+		s.createErr = s.createErr[1:]
+		return err
+	}
+	s.owners = configMap.Data
+	return nil
+}
+
+func (s *stubKubeClient) UpdateConfigMap(_ context.Context, configMap *corev1.ConfigMap) error {
+	if len(s.updateErr) > 0 {
+		err := s.updateErr[0]
+		s.updateErr = s.updateErr[1:]
+		return err
+	}
+	s.owners = configMap.Data
+	return nil
+}
+
+func (s *stubKubeClient) CreateEvent(_ context.Context, event *corev1.Event) error {
+	if s.createEventErr != nil {
+		return s.createEventErr
+	}
+	s.events = append(s.events, event)
+	return nil
+}
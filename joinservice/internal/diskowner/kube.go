@@ -0,0 +1,59 @@
+/*
+Copyright (c) Edgeless Systems GmbH
+
+SPDX-License-Identifier: AGPL-3.0-only
+*/
+
+package diskowner
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/edgelesssys/constellation/v2/internal/constants"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	clientset "k8s.io/client-go/kubernetes"
+	"k8s.io/client-go/rest"
+)
+
+// Client is a Kubernetes client for the disk owner registry.
+type Client struct {
+	client clientset.Interface
+}
+
+// NewClient creates a new Client using the in-cluster configuration.
+func NewClient() (*Client, error) {
+	config, err := rest.InClusterConfig()
+	if err != nil {
+		return nil, fmt.Errorf("failed to get in-cluster config: %w", err)
+	}
+	client, err := clientset.NewForConfig(config)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create client: %w", err)
+	}
+	return &Client{client: client}, nil
+}
+
+// GetConfigMap returns the ConfigMap with the given name in the Constellation namespace.
+func (c *Client) GetConfigMap(ctx context.Context, name string) (*corev1.ConfigMap, error) {
+	return c.client.CoreV1().ConfigMaps(constants.ConstellationNamespace).Get(ctx, name, metav1.GetOptions{})
+}
+
+// CreateConfigMap creates a ConfigMap.
+func (c *Client) CreateConfigMap(ctx context.Context, configMap *corev1.ConfigMap) error {
+	_, err := c.client.CoreV1().ConfigMaps(configMap.Namespace).Create(ctx, configMap, metav1.CreateOptions{})
+	return err
+}
+
+// UpdateConfigMap updates a ConfigMap. It fails with a conflict if the ConfigMap was modified since it was read.
+func (c *Client) UpdateConfigMap(ctx context.Context, configMap *corev1.ConfigMap) error {
+	_, err := c.client.CoreV1().ConfigMaps(configMap.Namespace).Update(ctx, configMap, metav1.UpdateOptions{})
+	return err
+}
+
+// CreateEvent creates a Kubernetes Event.
+func (c *Client) CreateEvent(ctx context.Context, event *corev1.Event) error {
+	_, err := c.client.CoreV1().Events(event.Namespace).Create(ctx, event, metav1.CreateOptions{})
+	return err
+}
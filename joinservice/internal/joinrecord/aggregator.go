@@ -0,0 +1,108 @@
+/*
+Copyright (c) Edgeless Systems GmbH
+
+SPDX-License-Identifier: AGPL-3.0-only
+*/
+
+package joinrecord
+
+import (
+	"context"
+	"net"
+	"sync"
+	"time"
+
+	"github.com/edgelesssys/constellation/v2/internal/logger"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/utils/clock"
+)
+
+// DefaultAggregationInterval is the interval in which requests of the same source are aggregated into a single JoinRecord.
+const DefaultAggregationInterval = 10 * time.Minute
+
+// maxAggregatedSources is the maximum number of sources recorded individually per interval.
+// Requests of further sources are aggregated into a single record without peer address.
+const maxAggregatedSources = 10
+
+// Aggregator records requests of unauthenticated peers, e.g. failed aTLS handshakes.
+// Such requests can be sent at an arbitrary rate, e.g. by port scanners,
+// so they are aggregated per source address and recorded at most once per interval.
+// Adding a request never waits for the Kubernetes API.
+type Aggregator struct {
+	recorder recorder
+	interval time.Duration
+	clock    clock.WithTicker
+	log      *logger.Logger
+
+	mux     sync.Mutex
+	pending map[string]*JoinRecordSpec
+}
+
+// NewAggregator creates a new Aggregator, which records aggregated requests through recorder.
+func NewAggregator(recorder recorder, interval time.Duration, log *logger.Logger) *Aggregator {
+	return &Aggregator{
+		recorder: recorder,
+		interval: interval,
+		clock:    clock.RealClock{},
+		log:      log,
+		pending:  make(map[string]*JoinRecordSpec),
+	}
+}
+
+// Add adds a request to the records of the current interval.
+// The record of a source keeps the time of its first request and the reason of its latest request.
+func (a *Aggregator) Add(spec JoinRecordSpec) {
+	source := spec.PeerAddress
+	if host, _, err := net.SplitHostPort(source); err == nil {
+		source = host
+	}
+
+	a.mux.Lock()
+	defer a.mux.Unlock()
+	if _, ok := a.pending[source]; !ok && len(a.pending) >= maxAggregatedSources {
+		source = ""
+	}
+	if pending, ok := a.pending[source]; ok {
+		pending.Count++
+		pending.Reason = spec.Reason
+		return
+	}
+	if spec.Time.IsZero() {
+		spec.Time = metav1.NewTime(a.clock.Now())
+	}
+	spec.PeerAddress = source
+	spec.Count = 1
+	a.pending[source] = &spec
+}
+
+// Flush records all pending requests.
+func (a *Aggregator) Flush(ctx context.Context) {
+	a.mux.Lock()
+	pending := a.pending
+	a.pending = make(map[string]*JoinRecordSpec)
+	a.mux.Unlock()
+
+	for _, spec := range pending {
+		a.recorder.Record(ctx, *spec)
+	}
+}
+
+// Run records the pending requests once per interval until the context is canceled.
+func (a *Aggregator) Run(ctx context.Context) {
+	ticker := a.clock.NewTicker(a.interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C():
+		}
+		flushCtx, cancel := context.WithTimeout(ctx, a.interval)
+		a.Flush(flushCtx)
+		cancel()
+	}
+}
+
+type recorder interface {
+	Record(ctx context.Context, spec JoinRecordSpec)
+}
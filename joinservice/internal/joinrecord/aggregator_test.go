@@ -0,0 +1,101 @@
+/*
+Copyright (c) Edgeless Systems GmbH
+
+SPDX-License-Identifier: AGPL-3.0-only
+*/
+
+package joinrecord
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"testing"
+	"time"
+
+	"github.com/edgelesssys/constellation/v2/internal/logger"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	testclock "k8s.io/utils/clock/testing"
+)
+
+func TestAggregator(t *testing.T) {
+	now := time.Date(2022, 10, 1, 12, 0, 0, 0, time.UTC)
+
+	testCases := map[string]struct {
+		peers       []string
+		wantRecords []JoinRecordSpec
+	}{
+		"requests of a source are aggregated": {
+			peers: []string{"192.0.2.1:1000", "192.0.2.1:1001", "192.0.2.2:1000", "192.0.2.1:1002"},
+			wantRecords: []JoinRecordSpec{
+				{PeerAddress: "192.0.2.1", Count: 3, Reason: "reason 3"},
+				{PeerAddress: "192.0.2.2", Count: 1, Reason: "reason 2"},
+			},
+		},
+		"requests of further sources are aggregated without address": {
+			peers: func() []string {
+				var peers []string
+				for i := 0; i < maxAggregatedSources+2; i++ {
+					peers = append(peers, fmt.Sprintf("192.0.2.%d:1000", i))
+				}
+				return append(peers, "192.0.2.0:1001")
+			}(),
+			wantRecords: func() []JoinRecordSpec {
+				records := []JoinRecordSpec{
+					{PeerAddress: "", Count: 2, Reason: fmt.Sprintf("reason %d", maxAggregatedSources+1)},
+					{PeerAddress: "192.0.2.0", Count: 2, Reason: fmt.Sprintf("reason %d", maxAggregatedSources+2)},
+				}
+				for i := 1; i < maxAggregatedSources; i++ {
+					records = append(records, JoinRecordSpec{PeerAddress: fmt.Sprintf("192.0.2.%d", i), Count: 1, Reason: fmt.Sprintf("reason %d", i)})
+				}
+				return records
+			}(),
+		},
+		"no requests": {},
+	}
+
+	for name, tc := range testCases {
+		t.Run(name, func(t *testing.T) {
+			assert := assert.New(t)
+			require := require.New(t)
+
+			recorder := &stubRecorder{}
+			aggregator := NewAggregator(recorder, time.Minute, logger.NewTest(t))
+			aggregator.clock = testclock.NewFakeClock(now)
+
+			for i, peer := range tc.peers {
+				aggregator.Add(JoinRecordSpec{Request: "Handshake", PeerAddress: peer, Result: ResultDenied, Reason: fmt.Sprintf("reason %d", i)})
+			}
+			aggregator.Flush(context.Background())
+
+			require.Len(recorder.records, len(tc.wantRecords))
+			sort.Slice(recorder.records, func(i, j int) bool {
+				return recorder.records[i].PeerAddress < recorder.records[j].PeerAddress
+			})
+			sort.Slice(tc.wantRecords, func(i, j int) bool {
+				return tc.wantRecords[i].PeerAddress < tc.wantRecords[j].PeerAddress
+			})
+			for i, record := range recorder.records {
+				assert.Equal(tc.wantRecords[i].PeerAddress, record.PeerAddress)
+				assert.Equal(tc.wantRecords[i].Count, record.Count)
+				assert.Equal(tc.wantRecords[i].Reason, record.Reason)
+				assert.Equal("Handshake", record.Request)
+				assert.Equal(ResultDenied, record.Result)
+				assert.True(now.Equal(record.Time.Time))
+			}
+
+			// pending requests are only recorded once
+			aggregator.Flush(context.Background())
+			assert.Len(recorder.records, len(tc.wantRecords))
+		})
+	}
+}
+
+type stubRecorder struct {
+	records []JoinRecordSpec
+}
+
+func (s *stubRecorder) Record(_ context.Context, spec JoinRecordSpec) {
+	s.records = append(s.records, spec)
+}
@@ -0,0 +1,173 @@
+/*
+Copyright (c) Edgeless Systems GmbH
+
+SPDX-License-Identifier: AGPL-3.0-only
+*/
+
+/*
+Package joinrecord records the decisions of the join service in JoinRecord resources.
+
+Every join, rejoin and certificate renewal request results in a new cluster scoped JoinRecord.
+Failed aTLS handshakes are aggregated per source address by an Aggregator and recorded at most once per interval.
+JoinRecords are never updated. They are deleted once they are older than the retention period.
+The records can be viewed using "constellation join-log" or "kubectl get joinrecords".
+*/
+package joinrecord
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/edgelesssys/constellation/v2/internal/constants"
+	"github.com/edgelesssys/constellation/v2/internal/logger"
+	"go.uber.org/zap"
+	k8serrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/utils/clock"
+)
+
+// Results of requests recorded in JoinRecords.
+const (
+	// ResultAccepted marks a request that was granted.
+	ResultAccepted = "Accepted"
+	// ResultDenied marks a request that was denied, e.g. because the node is not a member of the cluster.
+	ResultDenied = "Denied"
+	// ResultFailed marks a request that failed due to an internal error.
+	ResultFailed = "Failed"
+)
+
+// joinRecordGVR is the resource of JoinRecord objects.
+var joinRecordGVR = schema.GroupVersionResource{
+	Group:    constants.NodeAttestationGroup,
+	Version:  constants.NodeAttestationVersion,
+	Resource: constants.JoinRecordResource,
+}
+
+// JoinRecord records the decision of the join service on a request of a node.
+type JoinRecord struct {
+	metav1.TypeMeta   `json:",inline"`
+	metav1.ObjectMeta `json:"metadata,omitempty"`
+
+	Spec JoinRecordSpec `json:"spec,omitempty"`
+}
+
+// JoinRecordSpec describes a request of a node and its result.
+type JoinRecordSpec struct {
+	// Time is the time the request was decided.
+	Time metav1.Time `json:"time"`
+	// Request is the name of the RPC called by the node, e.g. IssueJoinTicket.
+	Request string `json:"request"`
+	// PeerAddress is the network address of the node.
+	PeerAddress string `json:"peerAddress,omitempty"`
+	// AttestationVariant is the attestation variant of the node.
+	AttestationVariant string `json:"attestationVariant,omitempty"`
+	// InstanceID is the instance ID reported by the node's attestation.
+	InstanceID string `json:"instanceID,omitempty"`
+	// AttestationIdentity is the attested identity of the node,
+	// i.e. the instance ID or the digest of the attestation key prefixed with "ak:".
+	AttestationIdentity string `json:"attestationIdentity,omitempty"`
+	// NodeName is the name of the node, if the node passed the membership verification.
+	NodeName string `json:"nodeName,omitempty"`
+	// DiskUUID is the UUID of the state disk whose key was requested.
+	DiskUUID string `json:"diskUUID,omitempty"`
+	// IsControlPlane is true if the node requested to join as control-plane node.
+	IsControlPlane bool `json:"isControlPlane,omitempty"`
+	// Result is the decision on the request: Accepted, Denied or Failed.
+	Result string `json:"result"`
+	// Reason explains why the request was denied or failed.
+	Reason string `json:"reason,omitempty"`
+	// Count is the number of requests aggregated in the record.
+	// It is only set for records of an Aggregator, Time is the time of the first of these requests.
+	Count int `json:"count,omitempty"`
+}
+
+// Recorder records requests of nodes in JoinRecords.
+type Recorder struct {
+	kube      kubeClient
+	retention time.Duration
+	clock     clock.WithTicker
+	log       *logger.Logger
+}
+
+// New creates a new Recorder.
+// JoinRecords older than retention are deleted by Prune. A retention of 0 keeps records forever.
+func New(kube kubeClient, retention time.Duration, log *logger.Logger) *Recorder {
+	return &Recorder{
+		kube:      kube,
+		retention: retention,
+		clock:     clock.RealClock{},
+		log:       log,
+	}
+}
+
+// Record creates a JoinRecord for the given request.
+// Failing to record a request is logged, but doesn't affect the request.
+func (r *Recorder) Record(ctx context.Context, spec JoinRecordSpec) {
+	if spec.Time.IsZero() {
+		spec.Time = metav1.NewTime(r.clock.Now())
+	}
+	record := &JoinRecord{
+		TypeMeta: metav1.TypeMeta{
+			APIVersion: joinRecordGVR.GroupVersion().String(),
+			Kind:       "JoinRecord",
+		},
+		ObjectMeta: metav1.ObjectMeta{
+			GenerateName: strings.ToLower(spec.Request) + "-",
+		},
+		Spec: spec,
+	}
+	if err := r.kube.CreateJoinRecord(ctx, record); err != nil {
+		r.log.With(zap.Error(err), zap.Any("record", spec)).Errorf("Failed to create join record")
+	}
+}
+
+// Prune deletes JoinRecords older than the retention period.
+func (r *Recorder) Prune(ctx context.Context) error {
+	if r.retention <= 0 {
+		return nil
+	}
+	records, err := r.kube.ListJoinRecords(ctx)
+	if err != nil {
+		return fmt.Errorf("listing join records: %w", err)
+	}
+	cutoff := r.clock.Now().Add(-r.retention)
+	var pruned int
+	for _, record := range records {
+		if !record.Spec.Time.Time.Before(cutoff) {
+			continue
+		}
+		if err := r.kube.DeleteJoinRecord(ctx, record.Name); err != nil && !k8serrors.IsNotFound(err) {
+			return fmt.Errorf("deleting join record %q: %w", record.Name, err)
+		}
+		pruned++
+	}
+	if pruned > 0 {
+		r.log.Infof("Deleted %d join records older than %s", pruned, r.retention)
+	}
+	return nil
+}
+
+// RunPruning prunes old JoinRecords in the given interval until the context is canceled.
+func (r *Recorder) RunPruning(ctx context.Context, interval time.Duration) {
+	ticker := r.clock.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		if err := r.Prune(ctx); err != nil {
+			r.log.With(zap.Error(err)).Errorf("Failed to prune join records")
+		}
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C():
+		}
+	}
+}
+
+type kubeClient interface {
+	CreateJoinRecord(ctx context.Context, record *JoinRecord) error
+	ListJoinRecords(ctx context.Context) ([]JoinRecord, error)
+	DeleteJoinRecord(ctx context.Context, name string) error
+}
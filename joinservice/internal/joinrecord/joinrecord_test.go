@@ -0,0 +1,154 @@
+/*
+Copyright (c) Edgeless Systems GmbH
+
+SPDX-License-Identifier: AGPL-3.0-only
+*/
+
+package joinrecord
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/edgelesssys/constellation/v2/internal/logger"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/goleak"
+	k8serrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	testclock "k8s.io/utils/clock/testing"
+)
+
+func TestMain(m *testing.M) {
+	goleak.VerifyTestMain(m)
+}
+
+func TestRecord(t *testing.T) {
+	now := time.Date(2022, 10, 1, 12, 0, 0, 0, time.UTC)
+
+	testCases := map[string]struct {
+		kube     stubKubeClient
+		spec     JoinRecordSpec
+		wantTime time.Time
+	}{
+		"time is set": {
+			spec:     JoinRecordSpec{Request: "IssueJoinTicket", DiskUUID: "disk-0", Result: ResultAccepted},
+			wantTime: now,
+		},
+		"time is kept": {
+			spec:     JoinRecordSpec{Time: metav1.NewTime(now.Add(-time.Hour)), Request: "IssueJoinTicket", Result: ResultDenied},
+			wantTime: now.Add(-time.Hour),
+		},
+		"create fails": {
+			kube:     stubKubeClient{createErr: errors.New("failed")},
+			spec:     JoinRecordSpec{Request: "IssueRejoinTicket", Result: ResultFailed},
+			wantTime: now,
+		},
+	}
+
+	for name, tc := range testCases {
+		t.Run(name, func(t *testing.T) {
+			assert := assert.New(t)
+			require := require.New(t)
+
+			recorder := &Recorder{kube: &tc.kube, clock: testclock.NewFakeClock(now), log: logger.NewTest(t)}
+			recorder.Record(context.Background(), tc.spec)
+
+			require.Len(tc.kube.created, 1)
+			record := tc.kube.created[0]
+			assert.Equal("JoinRecord", record.Kind)
+			assert.Equal("attestation.edgeless.systems/v1alpha1", record.APIVersion)
+			assert.Equal(tc.spec.Request, record.Spec.Request)
+			assert.Equal(tc.spec.Result, record.Spec.Result)
+			assert.NotEmpty(record.GenerateName)
+			assert.True(tc.wantTime.Equal(record.Spec.Time.Time))
+		})
+	}
+}
+
+func TestPrune(t *testing.T) {
+	now := time.Date(2022, 10, 1, 12, 0, 0, 0, time.UTC)
+	records := []JoinRecord{
+		{ObjectMeta: metav1.ObjectMeta{Name: "old"}, Spec: JoinRecordSpec{Time: metav1.NewTime(now.Add(-48 * time.Hour))}},
+		{ObjectMeta: metav1.ObjectMeta{Name: "recent"}, Spec: JoinRecordSpec{Time: metav1.NewTime(now.Add(-time.Hour))}},
+		{ObjectMeta: metav1.ObjectMeta{Name: "older"}, Spec: JoinRecordSpec{Time: metav1.NewTime(now.Add(-72 * time.Hour))}},
+	}
+
+	testCases := map[string]struct {
+		kube        stubKubeClient
+		retention   time.Duration
+		wantDeleted []string
+		wantErr     bool
+	}{
+		"old records are deleted": {
+			kube:        stubKubeClient{records: records},
+			retention:   24 * time.Hour,
+			wantDeleted: []string{"old", "older"},
+		},
+		"nothing to delete": {
+			kube:      stubKubeClient{records: records},
+			retention: 100 * time.Hour,
+		},
+		"retention disabled": {
+			kube:      stubKubeClient{records: records},
+			retention: 0,
+		},
+		"already deleted records are ignored": {
+			kube:        stubKubeClient{records: records, deleteErr: k8serrors.NewNotFound(schema.GroupResource{}, "")},
+			retention:   24 * time.Hour,
+			wantDeleted: []string{"old", "older"},
+		},
+		"list fails": {
+			kube:      stubKubeClient{listErr: errors.New("failed")},
+			retention: 24 * time.Hour,
+			wantErr:   true,
+		},
+		"delete fails": {
+			kube:        stubKubeClient{records: records, deleteErr: errors.New("failed")},
+			retention:   24 * time.Hour,
+			wantDeleted: []string{"old"},
+			wantErr:     true,
+		},
+	}
+
+	for name, tc := range testCases {
+		t.Run(name, func(t *testing.T) {
+			assert := assert.New(t)
+
+			recorder := &Recorder{kube: &tc.kube, retention: tc.retention, clock: testclock.NewFakeClock(now), log: logger.NewTest(t)}
+			err := recorder.Prune(context.Background())
+			if tc.wantErr {
+				assert.Error(err)
+			} else {
+				assert.NoError(err)
+			}
+			assert.Equal(tc.wantDeleted, tc.kube.deleted)
+		})
+	}
+}
+
+type stubKubeClient struct {
+	records   []JoinRecord
+	created   []*JoinRecord
+	deleted   []string
+	createErr error
+	listErr   error
+	deleteErr error
+}
+
+func (s *stubKubeClient) CreateJoinRecord(_ context.Context, record *JoinRecord) error {
+	s.created = append(s.created, record)
+	return s.createErr
+}
+
+func (s *stubKubeClient) ListJoinRecords(context.Context) ([]JoinRecord, error) {
+	return s.records, s.listErr
+}
+
+func (s *stubKubeClient) DeleteJoinRecord(_ context.Context, name string) error {
+	s.deleted = append(s.deleted, name)
+	return s.deleteErr
+}
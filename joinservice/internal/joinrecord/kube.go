@@ -0,0 +1,68 @@
+/*
+Copyright (c) Edgeless Systems GmbH
+
+SPDX-License-Identifier: AGPL-3.0-only
+*/
+
+package joinrecord
+
+import (
+	"context"
+	"fmt"
+
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/client-go/dynamic"
+	"k8s.io/client-go/rest"
+)
+
+// Client is a Kubernetes client for JoinRecord resources.
+type Client struct {
+	dynamic dynamic.Interface
+}
+
+// NewClient creates a new Client using the in-cluster configuration.
+func NewClient() (*Client, error) {
+	config, err := rest.InClusterConfig()
+	if err != nil {
+		return nil, fmt.Errorf("failed to get in-cluster config: %w", err)
+	}
+	dynamicClient, err := dynamic.NewForConfig(config)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create dynamic client: %w", err)
+	}
+	return &Client{dynamic: dynamicClient}, nil
+}
+
+// CreateJoinRecord creates a JoinRecord resource.
+func (c *Client) CreateJoinRecord(ctx context.Context, record *JoinRecord) error {
+	obj, err := runtime.DefaultUnstructuredConverter.ToUnstructured(record)
+	if err != nil {
+		return fmt.Errorf("converting join record: %w", err)
+	}
+	_, err = c.dynamic.Resource(joinRecordGVR).Create(ctx, &unstructured.Unstructured{Object: obj}, metav1.CreateOptions{})
+	return err
+}
+
+// ListJoinRecords returns all JoinRecord resources.
+func (c *Client) ListJoinRecords(ctx context.Context) ([]JoinRecord, error) {
+	list, err := c.dynamic.Resource(joinRecordGVR).List(ctx, metav1.ListOptions{})
+	if err != nil {
+		return nil, err
+	}
+	records := make([]JoinRecord, 0, len(list.Items))
+	for _, item := range list.Items {
+		var record JoinRecord
+		if err := runtime.DefaultUnstructuredConverter.FromUnstructured(item.Object, &record); err != nil {
+			return nil, fmt.Errorf("converting join record %q: %w", item.GetName(), err)
+		}
+		records = append(records, record)
+	}
+	return records, nil
+}
+
+// DeleteJoinRecord deletes the JoinRecord with the given name.
+func (c *Client) DeleteJoinRecord(ctx context.Context, name string) error {
+	return c.dynamic.Resource(joinRecordGVR).Delete(ctx, name, metav1.DeleteOptions{})
+}
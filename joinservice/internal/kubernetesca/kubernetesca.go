@@ -7,17 +7,23 @@ SPDX-License-Identifier: AGPL-3.0-only
 package kubernetesca
 
 import (
+	"context"
 	"crypto/rand"
 	"crypto/x509"
 	"encoding/pem"
 	"errors"
 	"fmt"
 	"strings"
+	"sync"
 	"time"
 
+	"github.com/edgelesssys/constellation/v2/internal/cloud/metadata"
 	"github.com/edgelesssys/constellation/v2/internal/crypto"
 	"github.com/edgelesssys/constellation/v2/internal/file"
 	"github.com/edgelesssys/constellation/v2/internal/logger"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promauto"
+	"go.uber.org/zap"
 	kubeconstants "k8s.io/kubernetes/cmd/kubeadm/app/constants"
 )
 
@@ -26,22 +32,52 @@ const (
 	caKeyFilename  = "/etc/kubernetes/pki/ca.key"
 )
 
+var (
+	certificateValidity = promauto.NewGauge(prometheus.GaugeOpts{
+		Namespace: "constellation",
+		Subsystem: "join_service",
+		Name:      "kubelet_certificate_validity_seconds",
+		Help:      "Validity period of kubelet certificates issued by the join service.",
+	})
+	certificateExpiry = promauto.NewGaugeVec(prometheus.GaugeOpts{
+		Namespace: "constellation",
+		Subsystem: "join_service",
+		Name:      "kubelet_certificate_expiry_timestamp_seconds",
+		Help:      "Expiry of the latest kubelet certificate issued to a node, as Unix timestamp. Removed once the node is deleted.",
+	}, []string{"node"})
+	certificatesIssued = promauto.NewCounter(prometheus.CounterOpts{
+		Namespace: "constellation",
+		Subsystem: "join_service",
+		Name:      "kubelet_certificates_issued_total",
+		Help:      "Number of kubelet certificates issued by the join service.",
+	})
+)
+
 // KubernetesCA handles signing of certificates using the Kubernetes root CA.
 type KubernetesCA struct {
-	log  *logger.Logger
-	file file.Handler
+	log      *logger.Logger
+	file     file.Handler
+	validity time.Duration
+
+	expiryMux sync.Mutex
+	// expiryNodes are the nodes with a series of the certificate expiry metric.
+	expiryNodes map[string]struct{}
 }
 
-// New creates a new KubernetesCA.
-func New(log *logger.Logger, fileHandler file.Handler) *KubernetesCA {
+// New creates a new KubernetesCA issuing certificates valid for the given duration.
+func New(log *logger.Logger, fileHandler file.Handler, validity time.Duration) *KubernetesCA {
+	certificateValidity.Set(validity.Seconds())
 	return &KubernetesCA{
-		log:  log,
-		file: fileHandler,
+		log:      log,
+		file:     fileHandler,
+		validity: validity,
+
+		expiryNodes: make(map[string]struct{}),
 	}
 }
 
 // GetCertificate creates a certificate for a node and signs it using the Kubernetes root CA.
-func (c KubernetesCA) GetCertificate(csr []byte) (cert []byte, err error) {
+func (c *KubernetesCA) GetCertificate(csr []byte) (cert []byte, err error) {
 	c.log.Debugf("Loading Kubernetes CA certificate")
 	parentCertRaw, err := c.file.Read(caCertFilename)
 	if err != nil {
@@ -103,7 +139,7 @@ func (c KubernetesCA) GetCertificate(csr []byte) (cert []byte, err error) {
 	certTmpl := &x509.Certificate{
 		SerialNumber: serialNumber,
 		NotBefore:    now.Add(-2 * time.Hour),
-		NotAfter:     now.Add(24 * 365 * time.Hour),
+		NotAfter:     now.Add(c.validity),
 		Subject:      certRequest.Subject,
 		KeyUsage:     x509.KeyUsageKeyEncipherment | x509.KeyUsageDigitalSignature,
 		ExtKeyUsage: []x509.ExtKeyUsage{
@@ -124,5 +160,61 @@ func (c KubernetesCA) GetCertificate(csr []byte) (cert []byte, err error) {
 		Bytes: certRaw,
 	})
 
+	certificatesIssued.Inc()
+	c.recordExpiry(strings.TrimPrefix(certRequest.Subject.CommonName, kubeconstants.NodesUserPrefix), certTmpl.NotAfter)
 	return kubeletCert, nil
 }
+
+// Collect deletes the certificate expiry of nodes that are not among the given instances.
+// Otherwise, the metric would keep a series for every node that was ever issued a certificate.
+func (c *KubernetesCA) Collect(instances []metadata.InstanceMetadata) error {
+	// an empty list is more likely a failure of the metadata API than a cluster without nodes
+	if len(instances) == 0 {
+		return errors.New("no instances listed, not collecting certificate expiries")
+	}
+	names := make(map[string]struct{}, len(instances))
+	for _, instance := range instances {
+		names[instance.Name] = struct{}{}
+	}
+
+	c.expiryMux.Lock()
+	defer c.expiryMux.Unlock()
+	for nodeName := range c.expiryNodes {
+		if _, ok := names[nodeName]; ok {
+			continue
+		}
+		certificateExpiry.DeleteLabelValues(nodeName)
+		delete(c.expiryNodes, nodeName)
+	}
+	return nil
+}
+
+// RunCollection calls Collect every interval with the instances listed by lister, until ctx is done.
+func (c *KubernetesCA) RunCollection(ctx context.Context, lister metadata.InstanceLister, interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+
+		instances, err := lister.List(ctx)
+		if err != nil {
+			c.log.With(zap.Error(err)).Errorf("Failed to list instances for collecting certificate expiries")
+			continue
+		}
+		if err := c.Collect(instances); err != nil {
+			c.log.With(zap.Error(err)).Errorf("Failed to collect certificate expiries")
+		}
+	}
+}
+
+// recordExpiry sets the certificate expiry of a node.
+func (c *KubernetesCA) recordExpiry(nodeName string, notAfter time.Time) {
+	c.expiryMux.Lock()
+	defer c.expiryMux.Unlock()
+	certificateExpiry.WithLabelValues(nodeName).Set(float64(notAfter.Unix()))
+	c.expiryNodes[nodeName] = struct{}{}
+}
@@ -20,8 +20,10 @@ import (
 	"testing"
 	"time"
 
+	"github.com/edgelesssys/constellation/v2/internal/cloud/metadata"
 	"github.com/edgelesssys/constellation/v2/internal/file"
 	"github.com/edgelesssys/constellation/v2/internal/logger"
+	"github.com/prometheus/client_golang/prometheus/testutil"
 	"github.com/spf13/afero"
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/require"
@@ -187,6 +189,7 @@ Q29uc3RlbGxhdGlvbg==
 			ca := New(
 				logger.NewTest(t),
 				fileHandler,
+				48*time.Hour,
 			)
 
 			signingRequest, err := tc.createSigningRequest()
@@ -208,6 +211,7 @@ Q29uc3RlbGxhdGlvbg==
 			assert.Equal(x509.ExtKeyUsageClientAuth, cert.ExtKeyUsage[0])
 			assert.False(cert.IsCA)
 			assert.True(cert.BasicConstraintsValid)
+			assert.WithinDuration(time.Now().Add(48*time.Hour), cert.NotAfter, time.Minute)
 		})
 	}
 }
@@ -287,3 +291,44 @@ func publicKey(priv crypto.PrivateKey) any {
 		return nil
 	}
 }
+
+func TestCollect(t *testing.T) {
+	testCases := map[string]struct {
+		instances     []metadata.InstanceMetadata
+		wantRemaining []string
+		wantErr       bool
+	}{
+		"expiry of deleted nodes is removed": {
+			instances:     []metadata.InstanceMetadata{{Name: "node-1"}, {Name: "node-3"}},
+			wantRemaining: []string{"node-1"},
+		},
+		"no instances listed": {
+			wantRemaining: []string{"node-1", "node-2"},
+			wantErr:       true,
+		},
+	}
+
+	for name, tc := range testCases {
+		t.Run(name, func(t *testing.T) {
+			assert := assert.New(t)
+
+			certificateExpiry.Reset()
+			ca := New(logger.NewTest(t), file.NewHandler(afero.NewMemMapFs()), time.Hour)
+			ca.recordExpiry("node-1", time.Now())
+			ca.recordExpiry("node-2", time.Now())
+
+			err := ca.Collect(tc.instances)
+			if tc.wantErr {
+				assert.Error(err)
+			} else {
+				assert.NoError(err)
+			}
+
+			assert.Equal(len(tc.wantRemaining), testutil.CollectAndCount(certificateExpiry))
+			assert.Len(ca.expiryNodes, len(tc.wantRemaining))
+			for _, nodeName := range tc.wantRemaining {
+				assert.Contains(ca.expiryNodes, nodeName)
+			}
+		})
+	}
+}
@@ -0,0 +1,152 @@
+/*
+Copyright (c) Edgeless Systems GmbH
+
+SPDX-License-Identifier: AGPL-3.0-only
+*/
+
+/*
+Package membership verifies that joining nodes are members of the cluster's scaling groups.
+
+A valid attestation only proves that a node runs the expected software, not that it belongs to this cluster.
+Nodes are therefore looked up in the cloud provider's metadata API, which only lists instances of the cluster's scaling groups.
+The node must have the role it requests, and its kubelet certificate request must match the name and IPs of the instance.
+
+The instance is looked up by the instance ID reported by the node's attestation.
+Nodes whose attestation doesn't report an instance ID, and instance IDs of an unknown format, are rejected.
+The node name of the certificate request is never used for the lookup, since it is chosen by the node.
+*/
+package membership
+
+import (
+	"context"
+	"crypto/x509"
+	"errors"
+	"fmt"
+	"net"
+	"strings"
+
+	"github.com/edgelesssys/constellation/v2/internal/attestation/policy"
+	"github.com/edgelesssys/constellation/v2/internal/cloud/metadata"
+	"github.com/edgelesssys/constellation/v2/internal/gcpshared"
+	"github.com/edgelesssys/constellation/v2/internal/logger"
+	"github.com/edgelesssys/constellation/v2/internal/role"
+	kubeconstants "k8s.io/kubernetes/cmd/kubeadm/app/constants"
+)
+
+// qemuProviderIDPrefix is the prefix of the provider IDs of QEMU VMs, followed by their name.
+const qemuProviderIDPrefix = "qemu:///hostname/"
+
+var (
+	// ErrNotMember is returned if the node is not a member of the cluster's scaling groups with the requested role.
+	ErrNotMember = errors.New("node is not a member of the cluster")
+	// ErrInvalidCertificateRequest is returned if the kubelet certificate request is malformed or does not match the node's instance.
+	ErrInvalidCertificateRequest = errors.New("invalid certificate request")
+)
+
+// Verifier verifies the membership of joining nodes.
+type Verifier struct {
+	lister metadata.InstanceLister
+	log    *logger.Logger
+}
+
+// New creates a new Verifier.
+func New(lister metadata.InstanceLister, log *logger.Logger) *Verifier {
+	return &Verifier{lister: lister, log: log}
+}
+
+// Verify checks that the node identified by instanceID is a member of the cluster with the given role,
+// and that the node name and IP addresses of its kubelet certificate request belong to the instance.
+// instanceID is the instance ID reported by the node's attestation. If it is empty, ErrNotMember is returned.
+// The metadata of the verified instance is returned.
+func (v *Verifier) Verify(ctx context.Context, instanceID string, certificateRequest []byte, wantRole role.Role) (metadata.InstanceMetadata, error) {
+	csr, err := x509.ParseCertificateRequest(certificateRequest)
+	if err != nil {
+		return metadata.InstanceMetadata{}, fmt.Errorf("%w: %s", ErrInvalidCertificateRequest, err)
+	}
+	nodeName := strings.TrimPrefix(csr.Subject.CommonName, kubeconstants.NodesUserPrefix)
+	if nodeName == csr.Subject.CommonName {
+		return metadata.InstanceMetadata{}, fmt.Errorf("%w: common name %q is missing prefix %q", ErrInvalidCertificateRequest, csr.Subject.CommonName, kubeconstants.NodesUserPrefix)
+	}
+
+	instance, err := v.Lookup(ctx, instanceID)
+	if err != nil {
+		return metadata.InstanceMetadata{}, err
+	}
+
+	if instance.Role != wantRole {
+		return metadata.InstanceMetadata{}, fmt.Errorf("%w: instance %q has role %s, but requested role %s", ErrNotMember, instance.Name, instance.Role, wantRole)
+	}
+	if instance.Name != nodeName {
+		return metadata.InstanceMetadata{}, fmt.Errorf("%w: node name %q differs from instance name %q", ErrInvalidCertificateRequest, nodeName, instance.Name)
+	}
+	for _, ip := range csr.IPAddresses {
+		if !ip.Equal(net.ParseIP(instance.VPCIP)) && !ip.Equal(net.ParseIP(instance.PublicIP)) {
+			return metadata.InstanceMetadata{}, fmt.Errorf("%w: IP address %s does not belong to instance %q", ErrInvalidCertificateRequest, ip, instance.Name)
+		}
+	}
+	if len(csr.DNSNames) > 0 || len(csr.EmailAddresses) > 0 || len(csr.URIs) > 0 {
+		return metadata.InstanceMetadata{}, fmt.Errorf("%w: only IP address SANs are allowed", ErrInvalidCertificateRequest)
+	}
+
+	return instance, nil
+}
+
+// Lookup returns the instance identified by an attested instance ID.
+// ErrNotMember is returned if the instance is not a member of the cluster, or if instanceID is empty.
+func (v *Verifier) Lookup(ctx context.Context, instanceID string) (metadata.InstanceMetadata, error) {
+	if instanceID == "" {
+		v.log.Warnf("Attestation does not report an instance ID")
+		return metadata.InstanceMetadata{}, fmt.Errorf("%w: attestation does not report an instance ID", ErrNotMember)
+	}
+	instances, err := v.lister.List(ctx)
+	if err != nil {
+		return metadata.InstanceMetadata{}, fmt.Errorf("listing instances: %w", err)
+	}
+	return FindInstance(instances, instanceID)
+}
+
+// FindInstance returns the instance identified by an attested instance ID.
+// ErrNotMember is returned if none of instances matches, or if the instance ID has an unknown format.
+func FindInstance(instances []metadata.InstanceMetadata, instanceID string) (metadata.InstanceMetadata, error) {
+	match, err := instanceMatcher(instanceID)
+	if err != nil {
+		return metadata.InstanceMetadata{}, fmt.Errorf("%w: %s", ErrNotMember, err)
+	}
+	instance, found := findInstance(instances, match)
+	if !found {
+		return metadata.InstanceMetadata{}, fmt.Errorf("%w: no instance with ID %q", ErrNotMember, instanceID)
+	}
+	return instance, nil
+}
+
+// instanceMatcher returns a function matching the metadata of the instance identified by an attested instance ID.
+// See policy.Claims for the formats of the instance IDs.
+func instanceMatcher(instanceID string) (func(metadata.InstanceMetadata) bool, error) {
+	// GCP: projects/<project>/zones/<zone>/instances/<name>
+	parts := strings.Split(instanceID, "/")
+	if len(parts) == 6 && parts[0] == "projects" && parts[2] == "zones" && parts[4] == "instances" {
+		providerID := gcpshared.JoinProviderID(parts[1], parts[3], parts[5])
+		return func(i metadata.InstanceMetadata) bool { return i.ProviderID == providerID }, nil
+	}
+
+	// Azure: azure/vms/<vmId>
+	if vmID := strings.TrimPrefix(instanceID, policy.AzureInstanceIDPrefix); vmID != instanceID && vmID != "" {
+		return func(i metadata.InstanceMetadata) bool { return i.VMID != "" && strings.EqualFold(i.VMID, vmID) }, nil
+	}
+
+	// QEMU: qemu:///hostname/<name>, the provider ID of the instance
+	if name := strings.TrimPrefix(instanceID, qemuProviderIDPrefix); name != instanceID && name != "" {
+		return func(i metadata.InstanceMetadata) bool { return i.ProviderID == instanceID }, nil
+	}
+
+	return nil, fmt.Errorf("unknown format of instance ID %q", instanceID)
+}
+
+func findInstance(instances []metadata.InstanceMetadata, match func(metadata.InstanceMetadata) bool) (metadata.InstanceMetadata, bool) {
+	for _, instance := range instances {
+		if match(instance) {
+			return instance, true
+		}
+	}
+	return metadata.InstanceMetadata{}, false
+}
@@ -0,0 +1,271 @@
+/*
+Copyright (c) Edgeless Systems GmbH
+
+SPDX-License-Identifier: AGPL-3.0-only
+*/
+
+package membership
+
+import (
+	"context"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"errors"
+	"net"
+	"testing"
+
+	"github.com/edgelesssys/constellation/v2/internal/cloud/metadata"
+	"github.com/edgelesssys/constellation/v2/internal/logger"
+	"github.com/edgelesssys/constellation/v2/internal/role"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/goleak"
+)
+
+func TestMain(m *testing.M) {
+	goleak.VerifyTestMain(m)
+}
+
+func TestVerify(t *testing.T) {
+	instances := []metadata.InstanceMetadata{
+		{
+			Name:       "worker-0",
+			ProviderID: "gce://project/zone/worker-0",
+			Role:       role.Worker,
+			VPCIP:      "192.0.2.1",
+			PublicIP:   "203.0.113.1",
+		},
+		{
+			Name:       "control-plane-0",
+			ProviderID: "gce://project/zone/control-plane-0",
+			Role:       role.ControlPlane,
+			VPCIP:      "192.0.2.2",
+		},
+		{
+			Name:       "worker-azure",
+			ProviderID: "azure:///subscriptions/subscription/resourceGroups/rg/providers/Microsoft.Compute/virtualMachineScaleSets/workers/virtualMachines/0",
+			VMID:       "b6c98c3b-4ec7-4da6-bd2f-7d98d20d7b75",
+			Role:       role.Worker,
+			VPCIP:      "192.0.2.3",
+		},
+		{
+			Name:       "worker-qemu",
+			ProviderID: "qemu:///hostname/worker-qemu",
+			Role:       role.Worker,
+			VPCIP:      "192.0.2.4",
+		},
+	}
+
+	testCases := map[string]struct {
+		lister      stubInstanceLister
+		instanceID  string
+		csr         *x509.CertificateRequest
+		role        role.Role
+		wantErr     bool
+		wantErrType error
+	}{
+		"worker": {
+			lister:     stubInstanceLister{instances: instances},
+			instanceID: "projects/project/zones/zone/instances/worker-0",
+			csr:        newCSR("system:node:worker-0", "192.0.2.1", "203.0.113.1"),
+			role:       role.Worker,
+		},
+		"control plane": {
+			lister:     stubInstanceLister{instances: instances},
+			instanceID: "projects/project/zones/zone/instances/control-plane-0",
+			csr:        newCSR("system:node:control-plane-0", "192.0.2.2"),
+			role:       role.ControlPlane,
+		},
+		"no instance ID": {
+			lister:      stubInstanceLister{instances: instances},
+			csr:         newCSR("system:node:worker-0", "192.0.2.1"),
+			role:        role.Worker,
+			wantErr:     true,
+			wantErrType: ErrNotMember,
+		},
+		"Azure VM ID": {
+			lister:     stubInstanceLister{instances: instances},
+			instanceID: "azure/vms/b6c98c3b-4ec7-4da6-bd2f-7d98d20d7b75",
+			csr:        newCSR("system:node:worker-azure", "192.0.2.3"),
+			role:       role.Worker,
+		},
+		"Azure VM ID of other instance": {
+			lister:      stubInstanceLister{instances: instances},
+			instanceID:  "azure/vms/00000000-0000-0000-0000-000000000000",
+			csr:         newCSR("system:node:worker-azure", "192.0.2.3"),
+			role:        role.Worker,
+			wantErr:     true,
+			wantErrType: ErrNotMember,
+		},
+		"QEMU provider ID": {
+			lister:     stubInstanceLister{instances: instances},
+			instanceID: "qemu:///hostname/worker-qemu",
+			csr:        newCSR("system:node:worker-qemu", "192.0.2.4"),
+			role:       role.Worker,
+		},
+		"unknown instance ID format": {
+			lister:      stubInstanceLister{instances: instances},
+			instanceID:  "some-id",
+			csr:         newCSR("system:node:worker-0", "192.0.2.1"),
+			role:        role.Worker,
+			wantErr:     true,
+			wantErrType: ErrNotMember,
+		},
+		"instance not in scaling groups": {
+			lister:      stubInstanceLister{instances: instances},
+			instanceID:  "projects/project/zones/zone/instances/worker-1",
+			csr:         newCSR("system:node:worker-0", "192.0.2.1"),
+			role:        role.Worker,
+			wantErr:     true,
+			wantErrType: ErrNotMember,
+		},
+		"worker requests control plane role": {
+			lister:      stubInstanceLister{instances: instances},
+			instanceID:  "projects/project/zones/zone/instances/worker-0",
+			csr:         newCSR("system:node:worker-0", "192.0.2.1"),
+			role:        role.ControlPlane,
+			wantErr:     true,
+			wantErrType: ErrNotMember,
+		},
+		"node name of other instance": {
+			lister:      stubInstanceLister{instances: instances},
+			instanceID:  "projects/project/zones/zone/instances/worker-0",
+			csr:         newCSR("system:node:control-plane-0", "192.0.2.1"),
+			role:        role.Worker,
+			wantErr:     true,
+			wantErrType: ErrInvalidCertificateRequest,
+		},
+		"IP of other instance": {
+			lister:      stubInstanceLister{instances: instances},
+			instanceID:  "projects/project/zones/zone/instances/worker-0",
+			csr:         newCSR("system:node:worker-0", "192.0.2.1", "192.0.2.2"),
+			role:        role.Worker,
+			wantErr:     true,
+			wantErrType: ErrInvalidCertificateRequest,
+		},
+		"DNS SAN": {
+			lister:     stubInstanceLister{instances: instances},
+			instanceID: "projects/project/zones/zone/instances/worker-0",
+			csr: func() *x509.CertificateRequest {
+				csr := newCSR("system:node:worker-0", "192.0.2.1")
+				csr.DNSNames = []string{"kubernetes.default"}
+				return csr
+			}(),
+			role:        role.Worker,
+			wantErr:     true,
+			wantErrType: ErrInvalidCertificateRequest,
+		},
+		"missing node prefix": {
+			lister:      stubInstanceLister{instances: instances},
+			instanceID:  "projects/project/zones/zone/instances/worker-0",
+			csr:         newCSR("worker-0", "192.0.2.1"),
+			role:        role.Worker,
+			wantErr:     true,
+			wantErrType: ErrInvalidCertificateRequest,
+		},
+		"listing instances fails": {
+			lister:     stubInstanceLister{listErr: errors.New("failed")},
+			instanceID: "projects/project/zones/zone/instances/worker-0",
+			csr:        newCSR("system:node:worker-0", "192.0.2.1"),
+			role:       role.Worker,
+			wantErr:    true,
+		},
+	}
+
+	for name, tc := range testCases {
+		t.Run(name, func(t *testing.T) {
+			assert := assert.New(t)
+			require := require.New(t)
+
+			priv, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+			require.NoError(err)
+			csr, err := x509.CreateCertificateRequest(rand.Reader, tc.csr, priv)
+			require.NoError(err)
+
+			verifier := New(tc.lister, logger.NewTest(t))
+			instance, err := verifier.Verify(context.Background(), tc.instanceID, csr, tc.role)
+			if tc.wantErr {
+				assert.Error(err)
+				if tc.wantErrType != nil {
+					assert.ErrorIs(err, tc.wantErrType)
+				}
+				return
+			}
+			assert.NoError(err)
+			assert.Equal(tc.role, instance.Role)
+			assert.Equal(tc.csr.Subject.CommonName, "system:node:"+instance.Name)
+		})
+	}
+}
+
+func TestLookup(t *testing.T) {
+	instances := []metadata.InstanceMetadata{
+		{Name: "worker-0", ProviderID: "gce://project/zone/worker-0", Role: role.Worker},
+	}
+
+	testCases := map[string]struct {
+		lister     stubInstanceLister
+		instanceID string
+		wantErr    bool
+	}{
+		"instance found": {
+			lister:     stubInstanceLister{instances: instances},
+			instanceID: "projects/project/zones/zone/instances/worker-0",
+		},
+		"no instance ID": {
+			lister:  stubInstanceLister{instances: instances},
+			wantErr: true,
+		},
+		"instance not found": {
+			lister:     stubInstanceLister{instances: instances},
+			instanceID: "projects/project/zones/zone/instances/worker-1",
+			wantErr:    true,
+		},
+	}
+
+	for name, tc := range testCases {
+		t.Run(name, func(t *testing.T) {
+			assert := assert.New(t)
+
+			verifier := New(tc.lister, logger.NewTest(t))
+			instance, err := verifier.Lookup(context.Background(), tc.instanceID)
+			if tc.wantErr {
+				assert.ErrorIs(err, ErrNotMember)
+				return
+			}
+			assert.NoError(err)
+			assert.Equal("worker-0", instance.Name)
+		})
+	}
+}
+
+func TestVerifyMalformedCertificateRequest(t *testing.T) {
+	verifier := New(stubInstanceLister{}, logger.NewTest(t))
+	_, err := verifier.Verify(context.Background(), "", []byte("invalid"), role.Worker)
+	assert.ErrorIs(t, err, ErrInvalidCertificateRequest)
+}
+
+func newCSR(commonName string, ips ...string) *x509.CertificateRequest {
+	csr := &x509.CertificateRequest{
+		Subject: pkix.Name{
+			Organization: []string{"system:nodes"},
+			CommonName:   commonName,
+		},
+	}
+	for _, ip := range ips {
+		csr.IPAddresses = append(csr.IPAddresses, net.ParseIP(ip))
+	}
+	return csr
+}
+
+type stubInstanceLister struct {
+	instances []metadata.InstanceMetadata
+	listErr   error
+}
+
+func (s stubInstanceLister) List(context.Context) ([]metadata.InstanceMetadata, error) {
+	return s.instances, s.listErr
+}
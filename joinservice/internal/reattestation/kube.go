@@ -0,0 +1,322 @@
+/*
+Copyright (c) Edgeless Systems GmbH
+
+SPDX-License-Identifier: AGPL-3.0-only
+*/
+
+package reattestation
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net"
+	"strconv"
+	"time"
+
+	"github.com/edgelesssys/constellation/v2/internal/constants"
+	"github.com/edgelesssys/constellation/v2/internal/grpc/dialer"
+	"github.com/edgelesssys/constellation/v2/verify/verifyproto"
+	corev1 "k8s.io/api/core/v1"
+	k8serrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/client-go/dynamic"
+	clientset "k8s.io/client-go/kubernetes"
+	"k8s.io/client-go/rest"
+	"k8s.io/client-go/tools/leaderelection"
+	"k8s.io/client-go/tools/leaderelection/resourcelock"
+)
+
+const (
+	// leaseName is the name of the lease used to elect the join service instance running re-attestation.
+	leaseName = "join-service-reattestation"
+	// verificationServiceSelector selects the pods of the verification service.
+	verificationServiceSelector = "k8s-app=verification-service"
+)
+
+// Client is a Kubernetes client for re-attestation.
+type Client struct {
+	client  clientset.Interface
+	dynamic dynamic.Interface
+}
+
+// NewClient creates a new Client using the in-cluster configuration.
+func NewClient() (*Client, error) {
+	config, err := rest.InClusterConfig()
+	if err != nil {
+		return nil, fmt.Errorf("failed to get in-cluster config: %w", err)
+	}
+	client, err := clientset.NewForConfig(config)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create client: %w", err)
+	}
+	dynamicClient, err := dynamic.NewForConfig(config)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create dynamic client: %w", err)
+	}
+	return &Client{client: client, dynamic: dynamicClient}, nil
+}
+
+// ListNodes returns all nodes of the cluster.
+func (c *Client) ListNodes(ctx context.Context) ([]corev1.Node, error) {
+	nodes, err := c.client.CoreV1().Nodes().List(ctx, metav1.ListOptions{})
+	if err != nil {
+		return nil, err
+	}
+	return nodes.Items, nil
+}
+
+// ListVerificationEndpoints returns the gRPC endpoints of running verification service pods, keyed by node name.
+func (c *Client) ListVerificationEndpoints(ctx context.Context) (map[string]string, error) {
+	pods, err := c.client.CoreV1().Pods("kube-system").List(ctx, metav1.ListOptions{LabelSelector: verificationServiceSelector})
+	if err != nil {
+		return nil, err
+	}
+	endpoints := make(map[string]string, len(pods.Items))
+	for _, pod := range pods.Items {
+		if pod.Status.Phase != corev1.PodRunning || pod.Status.PodIP == "" {
+			continue
+		}
+		endpoints[pod.Spec.NodeName] = net.JoinHostPort(pod.Status.PodIP, strconv.Itoa(constants.VerifyServicePortGRPC))
+	}
+	return endpoints, nil
+}
+
+// GetNodeAttestation returns the NodeAttestation with the given name, or nil if it does not exist.
+func (c *Client) GetNodeAttestation(ctx context.Context, name string) (*NodeAttestation, error) {
+	obj, err := c.dynamic.Resource(nodeAttestationGVR).Get(ctx, name, metav1.GetOptions{})
+	if k8serrors.IsNotFound(err) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("getting NodeAttestation %s: %w", name, err)
+	}
+	return fromUnstructured(obj)
+}
+
+// CreateNodeAttestation creates a NodeAttestation.
+func (c *Client) CreateNodeAttestation(ctx context.Context, attestation *NodeAttestation) (*NodeAttestation, error) {
+	obj, err := toUnstructured(attestation)
+	if err != nil {
+		return nil, err
+	}
+	created, err := c.dynamic.Resource(nodeAttestationGVR).Create(ctx, obj, metav1.CreateOptions{})
+	if err != nil {
+		return nil, fmt.Errorf("creating NodeAttestation %s: %w", attestation.Name, err)
+	}
+	return fromUnstructured(created)
+}
+
+// UpdateNodeAttestationStatus updates the status subresource of a NodeAttestation.
+func (c *Client) UpdateNodeAttestationStatus(ctx context.Context, attestation *NodeAttestation) error {
+	obj, err := toUnstructured(attestation)
+	if err != nil {
+		return err
+	}
+	if _, err := c.dynamic.Resource(nodeAttestationGVR).UpdateStatus(ctx, obj, metav1.UpdateOptions{}); err != nil {
+		return fmt.Errorf("updating status of NodeAttestation %s: %w", attestation.Name, err)
+	}
+	return nil
+}
+
+// Quarantine applies the quarantine action to a node.
+func (c *Client) Quarantine(ctx context.Context, nodeName string, action Action) error {
+	switch action {
+	case ActionCordon:
+		return c.cordon(ctx, nodeName)
+	case ActionTaint:
+		return c.patchTaints(ctx, nodeName, func(taints []corev1.Taint) []corev1.Taint {
+			for _, taint := range taints {
+				if taint.Key == constants.AttestationFailedTaintKey {
+					return taints
+				}
+			}
+			now := metav1.Now()
+			return append(taints, corev1.Taint{
+				Key:       constants.AttestationFailedTaintKey,
+				Effect:    corev1.TaintEffectNoExecute,
+				TimeAdded: &now,
+			})
+		})
+	default:
+		return nil
+	}
+}
+
+// Release reverts the quarantine action applied to a node.
+func (c *Client) Release(ctx context.Context, nodeName string, action Action) error {
+	switch action {
+	case ActionCordon:
+		return c.uncordon(ctx, nodeName)
+	case ActionTaint:
+		return c.patchTaints(ctx, nodeName, func(taints []corev1.Taint) []corev1.Taint {
+			kept := make([]corev1.Taint, 0, len(taints))
+			for _, taint := range taints {
+				if taint.Key != constants.AttestationFailedTaintKey {
+					kept = append(kept, taint)
+				}
+			}
+			return kept
+		})
+	default:
+		return nil
+	}
+}
+
+// cordon marks a node as unschedulable and annotates it as cordoned by the quarantine.
+// Nodes that are already cordoned, e.g., by an administrator or a node drain, are left unchanged,
+// so releasing the quarantine doesn't uncordon them.
+func (c *Client) cordon(ctx context.Context, nodeName string) error {
+	node, err := c.client.CoreV1().Nodes().Get(ctx, nodeName, metav1.GetOptions{})
+	if err != nil {
+		return fmt.Errorf("getting node %s: %w", nodeName, err)
+	}
+	if node.Spec.Unschedulable {
+		return nil
+	}
+	return c.patchCordon(ctx, node, true)
+}
+
+// uncordon marks a node as schedulable if it was cordoned by the quarantine.
+func (c *Client) uncordon(ctx context.Context, nodeName string) error {
+	node, err := c.client.CoreV1().Nodes().Get(ctx, nodeName, metav1.GetOptions{})
+	if err != nil {
+		return fmt.Errorf("getting node %s: %w", nodeName, err)
+	}
+	if _, ok := node.Annotations[constants.AttestationQuarantineCordonAnnotation]; !ok {
+		return nil
+	}
+	return c.patchCordon(ctx, node, false)
+}
+
+// patchCordon sets the unschedulable field of a node together with the quarantine cordon annotation.
+// The patch fails if the node was modified concurrently.
+func (c *Client) patchCordon(ctx context.Context, node *corev1.Node, cordoned bool) error {
+	var annotation any
+	if cordoned {
+		annotation = "true"
+	}
+	patch, err := json.Marshal(map[string]any{
+		"metadata": map[string]any{
+			"resourceVersion": node.ResourceVersion,
+			"annotations":     map[string]any{constants.AttestationQuarantineCordonAnnotation: annotation},
+		},
+		"spec": map[string]any{"unschedulable": cordoned},
+	})
+	if err != nil {
+		return err
+	}
+	if _, err := c.client.CoreV1().Nodes().Patch(ctx, node.Name, types.MergePatchType, patch, metav1.PatchOptions{}); err != nil {
+		return fmt.Errorf("patching node %s: %w", node.Name, err)
+	}
+	return nil
+}
+
+// patchTaints replaces the taints of a node with the result of mutate.
+// The join service may only patch nodes, so the taints are replaced using a JSON patch,
+// which fails if the node was modified concurrently.
+func (c *Client) patchTaints(ctx context.Context, nodeName string, mutate func([]corev1.Taint) []corev1.Taint) error {
+	node, err := c.client.CoreV1().Nodes().Get(ctx, nodeName, metav1.GetOptions{})
+	if err != nil {
+		return fmt.Errorf("getting node %s: %w", nodeName, err)
+	}
+	patch, err := json.Marshal([]jsonPatchOperation{
+		{Op: "test", Path: "/metadata/resourceVersion", Value: node.ResourceVersion},
+		{Op: "add", Path: "/spec/taints", Value: mutate(node.Spec.Taints)},
+	})
+	if err != nil {
+		return err
+	}
+	if _, err := c.client.CoreV1().Nodes().Patch(ctx, nodeName, types.JSONPatchType, patch, metav1.PatchOptions{}); err != nil {
+		return fmt.Errorf("patching node %s: %w", nodeName, err)
+	}
+	return nil
+}
+
+type jsonPatchOperation struct {
+	Op    string `json:"op"`
+	Path  string `json:"path"`
+	Value any    `json:"value"`
+}
+
+// RunLeaderElected calls run once identity is elected leader among all join service instances.
+// It returns when ctx is done.
+func (c *Client) RunLeaderElected(ctx context.Context, identity string, run func(ctx context.Context)) error {
+	lock, err := resourcelock.New(
+		resourcelock.LeasesResourceLock,
+		"kube-system",
+		leaseName,
+		c.client.CoreV1(),
+		c.client.CoordinationV1(),
+		resourcelock.ResourceLockConfig{Identity: identity},
+	)
+	if err != nil {
+		return fmt.Errorf("creating leader election lock: %w", err)
+	}
+
+	elector, err := leaderelection.NewLeaderElector(leaderelection.LeaderElectionConfig{
+		Lock:            lock,
+		ReleaseOnCancel: true,
+		LeaseDuration:   15 * time.Second,
+		RenewDeadline:   10 * time.Second,
+		RetryPeriod:     2 * time.Second,
+		Callbacks: leaderelection.LeaderCallbacks{
+			OnStartedLeading: run,
+			OnStoppedLeading: func() {},
+		},
+	})
+	if err != nil {
+		return fmt.Errorf("creating leader elector: %w", err)
+	}
+
+	// Run returns once leadership is lost, so keep competing until ctx is done
+	for ctx.Err() == nil {
+		elector.Run(ctx)
+	}
+	return nil
+}
+
+func toUnstructured(attestation *NodeAttestation) (*unstructured.Unstructured, error) {
+	obj, err := runtime.DefaultUnstructuredConverter.ToUnstructured(attestation)
+	if err != nil {
+		return nil, fmt.Errorf("converting NodeAttestation: %w", err)
+	}
+	return &unstructured.Unstructured{Object: obj}, nil
+}
+
+func fromUnstructured(obj *unstructured.Unstructured) (*NodeAttestation, error) {
+	var attestation NodeAttestation
+	if err := runtime.DefaultUnstructuredConverter.FromUnstructured(obj.Object, &attestation); err != nil {
+		return nil, fmt.Errorf("converting NodeAttestation: %w", err)
+	}
+	return &attestation, nil
+}
+
+// VerificationClient requests attestations from the verification service of nodes.
+type VerificationClient struct {
+	dialer *dialer.Dialer
+}
+
+// NewVerificationClient creates a new VerificationClient.
+func NewVerificationClient() *VerificationClient {
+	return &VerificationClient{dialer: dialer.New(nil, nil, &net.Dialer{})}
+}
+
+// GetAttestation requests an attestation from the verification service at endpoint.
+// The connection is not secured, integrity is provided by the attestation itself.
+func (v *VerificationClient) GetAttestation(ctx context.Context, endpoint string, req *verifyproto.GetAttestationRequest) ([]byte, error) {
+	conn, err := v.dialer.DialInsecure(ctx, endpoint)
+	if err != nil {
+		return nil, fmt.Errorf("dialing verification service: %w", err)
+	}
+	defer conn.Close()
+
+	resp, err := verifyproto.NewAPIClient(conn).GetAttestation(ctx, req)
+	if err != nil {
+		return nil, err
+	}
+	return resp.Attestation, nil
+}
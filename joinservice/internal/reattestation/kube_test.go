@@ -0,0 +1,66 @@
+/*
+Copyright (c) Edgeless Systems GmbH
+
+SPDX-License-Identifier: AGPL-3.0-only
+*/
+
+package reattestation
+
+import (
+	"context"
+	"testing"
+
+	"github.com/edgelesssys/constellation/v2/internal/constants"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/kubernetes/fake"
+)
+
+func TestQuarantineCordon(t *testing.T) {
+	testCases := map[string]struct {
+		unschedulable     bool
+		wantAnnotated     bool
+		wantUnschedulable bool
+	}{
+		"schedulable node is cordoned and uncordoned": {
+			wantAnnotated:     true,
+			wantUnschedulable: false,
+		},
+		"node cordoned by someone else stays cordoned": {
+			unschedulable:     true,
+			wantAnnotated:     false,
+			wantUnschedulable: true,
+		},
+	}
+
+	for name, tc := range testCases {
+		t.Run(name, func(t *testing.T) {
+			assert := assert.New(t)
+			require := require.New(t)
+
+			clientset := fake.NewSimpleClientset(&corev1.Node{
+				ObjectMeta: metav1.ObjectMeta{Name: "node"},
+				Spec:       corev1.NodeSpec{Unschedulable: tc.unschedulable},
+			})
+			client := &Client{client: clientset}
+			getNode := func() *corev1.Node {
+				node, err := clientset.CoreV1().Nodes().Get(context.Background(), "node", metav1.GetOptions{})
+				require.NoError(err)
+				return node
+			}
+
+			require.NoError(client.Quarantine(context.Background(), "node", ActionCordon))
+			node := getNode()
+			assert.True(node.Spec.Unschedulable)
+			_, annotated := node.Annotations[constants.AttestationQuarantineCordonAnnotation]
+			assert.Equal(tc.wantAnnotated, annotated)
+
+			require.NoError(client.Release(context.Background(), "node", ActionCordon))
+			node = getNode()
+			assert.Equal(tc.wantUnschedulable, node.Spec.Unschedulable)
+			assert.NotContains(node.Annotations, constants.AttestationQuarantineCordonAnnotation)
+		})
+	}
+}
@@ -0,0 +1,91 @@
+/*
+Copyright (c) Edgeless Systems GmbH
+
+SPDX-License-Identifier: AGPL-3.0-only
+*/
+
+package reattestation
+
+import (
+	"fmt"
+
+	"github.com/edgelesssys/constellation/v2/internal/constants"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+)
+
+// nodeAttestationGVR is the resource of NodeAttestation objects.
+var nodeAttestationGVR = schema.GroupVersionResource{
+	Group:    constants.NodeAttestationGroup,
+	Version:  constants.NodeAttestationVersion,
+	Resource: constants.NodeAttestationResource,
+}
+
+// State is the attestation state of a node.
+type State string
+
+const (
+	// StateTrusted means the last attestation of the node was successful.
+	StateTrusted State = "Trusted"
+	// StateFailed means the last attestation of the node failed.
+	StateFailed State = "Failed"
+	// StateQuarantined means the node failed re-attestation repeatedly and the quarantine action was applied.
+	StateQuarantined State = "Quarantined"
+)
+
+// Action is the action applied to nodes failing re-attestation.
+type Action string
+
+const (
+	// ActionNone only records failures.
+	ActionNone Action = "none"
+	// ActionCordon marks failing nodes as unschedulable.
+	ActionCordon Action = "cordon"
+	// ActionTaint adds a NoExecute taint to failing nodes, evicting workloads not tolerating it.
+	ActionTaint Action = "taint"
+)
+
+// ParseAction parses a quarantine action.
+func ParseAction(action string) (Action, error) {
+	switch Action(action) {
+	case ActionNone, ActionCordon, ActionTaint:
+		return Action(action), nil
+	default:
+		return "", fmt.Errorf("unknown quarantine action %q: must be one of %q, %q or %q", action, ActionNone, ActionCordon, ActionTaint)
+	}
+}
+
+// NodeAttestation records the result of periodic re-attestation of a node.
+// NodeAttestations are cluster scoped, named after their node, and owned by it.
+type NodeAttestation struct {
+	metav1.TypeMeta   `json:",inline"`
+	metav1.ObjectMeta `json:"metadata,omitempty"`
+
+	Spec   NodeAttestationSpec   `json:"spec,omitempty"`
+	Status NodeAttestationStatus `json:"status,omitempty"`
+}
+
+// NodeAttestationSpec defines the node a NodeAttestation belongs to.
+type NodeAttestationSpec struct {
+	// NodeName is the name of the attested node.
+	NodeName string `json:"nodeName"`
+}
+
+// NodeAttestationStatus is the re-attestation status of a node.
+type NodeAttestationStatus struct {
+	// State is the attestation state of the node.
+	State State `json:"state,omitempty"`
+	// LastAttestationTime is the time of the last attestation attempt.
+	LastAttestationTime *metav1.Time `json:"lastAttestationTime,omitempty"`
+	// LastSuccessfulAttestationTime is the time the node was last attested successfully.
+	LastSuccessfulAttestationTime *metav1.Time `json:"lastSuccessfulAttestationTime,omitempty"`
+	// ConsecutiveFailures is the number of failed attestations since the last successful one.
+	ConsecutiveFailures int `json:"consecutiveFailures,omitempty"`
+	// Message describes why the last attestation failed.
+	Message string `json:"message,omitempty"`
+	// QuarantineAction is the action applied to the node, to be reverted once it attests successfully again.
+	QuarantineAction Action `json:"quarantineAction,omitempty"`
+	// AttestedIdentity is the identity reported by the node's attestations.
+	// It is set on the first successful attestation, and later attestations must report the same identity.
+	AttestedIdentity string `json:"attestedIdentity,omitempty"`
+}
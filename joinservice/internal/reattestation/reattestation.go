@@ -0,0 +1,303 @@
+/*
+Copyright (c) Edgeless Systems GmbH
+
+SPDX-License-Identifier: AGPL-3.0-only
+*/
+
+/*
+Package reattestation periodically re-attests the nodes of a cluster.
+
+Nodes are attested when joining the cluster. Afterwards, their measurements may change at runtime,
+or the expected measurements may be tightened. The controller in this package therefore regularly requests
+a fresh attestation from the verification service of every node, using a new nonce, and validates it
+with the join service's current validator.
+
+The attestation must identify the node: if it reports an instance ID, the instance listed by the
+metadata API for that ID must be the one backing the Node object. Otherwise, the attested identity, e.g., the digest of the attestation key,
+is pinned on the first successful re-attestation and must not change afterwards.
+Nodes without a running verification service fail re-attestation.
+
+Results are recorded in NodeAttestation objects. Nodes failing attestation repeatedly are
+quarantined according to the configured Policy, and released once they attest successfully again.
+*/
+package reattestation
+
+import (
+	"bytes"
+	"context"
+	"crypto/rand"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/edgelesssys/constellation/v2/internal/atls"
+	"github.com/edgelesssys/constellation/v2/internal/attestation/policy"
+	"github.com/edgelesssys/constellation/v2/internal/cloud/metadata"
+	"github.com/edgelesssys/constellation/v2/internal/logger"
+	"github.com/edgelesssys/constellation/v2/joinservice/internal/membership"
+	"github.com/edgelesssys/constellation/v2/verify/verifyproto"
+	"go.uber.org/multierr"
+	"go.uber.org/zap"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+const (
+	// nonceLength is the length of the nonce and user data sent with attestation requests.
+	nonceLength = 32
+	// attestationTimeout is the maximum time to wait for the attestation of a single node.
+	attestationTimeout = 30 * time.Second
+)
+
+// Policy configures how nodes failing re-attestation are handled.
+type Policy struct {
+	// Action is applied to nodes after FailureThreshold consecutive failed attestations.
+	Action Action
+	// FailureThreshold is the number of consecutive failed attestations before the node is quarantined.
+	FailureThreshold int
+}
+
+// Controller re-attests all nodes of the cluster.
+type Controller struct {
+	validator atls.ClaimsValidator
+	attester  attester
+	kube      kubeClient
+	instances metadata.InstanceLister
+	policy    Policy
+	now       func() time.Time
+	log       *logger.Logger
+}
+
+// New initializes a new Controller.
+// instances lists the instances of the cluster, to look up the instance IDs reported by attestations.
+func New(validator atls.ClaimsValidator, attester attester, kube kubeClient, instances metadata.InstanceLister, policy Policy, log *logger.Logger) *Controller {
+	return &Controller{
+		validator: validator,
+		attester:  attester,
+		kube:      kube,
+		instances: instances,
+		policy:    policy,
+		now:       time.Now,
+		log:       log,
+	}
+}
+
+// Run re-attests all nodes every interval, until ctx is done.
+func (c *Controller) Run(ctx context.Context, interval time.Duration) {
+	c.log.Infof("Starting re-attestation of nodes every %s", interval)
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		if err := c.Reattest(ctx); err != nil {
+			c.log.With(zap.Error(err)).Errorf("Failed to re-attest nodes")
+		}
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// Reattest attests all nodes once, and records the results.
+func (c *Controller) Reattest(ctx context.Context) error {
+	nodes, err := c.kube.ListNodes(ctx)
+	if err != nil {
+		return fmt.Errorf("listing nodes: %w", err)
+	}
+	endpoints, err := c.kube.ListVerificationEndpoints(ctx)
+	if err != nil {
+		return fmt.Errorf("listing verification service endpoints: %w", err)
+	}
+	instances, err := c.instances.List(ctx)
+	if err != nil {
+		return fmt.Errorf("listing instances: %w", err)
+	}
+
+	var errs error
+	for _, node := range nodes {
+		if err := c.reattest(ctx, node, endpoints[node.Name], instances); err != nil {
+			errs = multierr.Append(errs, fmt.Errorf("recording attestation of node %s: %w", node.Name, err))
+		}
+	}
+	return errs
+}
+
+// reattest attests node using the verification service at endpoint, and records the result.
+func (c *Controller) reattest(ctx context.Context, node corev1.Node, endpoint string, instances []metadata.InstanceMetadata) error {
+	log := c.log.With(zap.String("node", node.Name))
+
+	attestation, err := c.kube.GetNodeAttestation(ctx, node.Name)
+	if err != nil {
+		return err
+	}
+	if attestation == nil {
+		attestation, err = c.kube.CreateNodeAttestation(ctx, newNodeAttestation(node))
+		if err != nil {
+			return err
+		}
+	}
+
+	var identity string
+	var attestationErr error
+	if endpoint == "" {
+		// nodes may only run workloads if they can be attested, so a missing verification service is a failure
+		attestationErr = errors.New("no verification service running on node")
+	} else {
+		identity, attestationErr = c.attest(ctx, node, endpoint, attestation.Status.AttestedIdentity, instances)
+	}
+	if attestationErr != nil {
+		log.With(zap.Error(attestationErr)).Warnf("Node failed re-attestation")
+	} else {
+		log.Debugf("Node re-attested successfully")
+	}
+
+	return c.record(ctx, node.Name, attestation, identity, attestationErr)
+}
+
+// attest requests a fresh attestation from the verification service at endpoint and validates it.
+// The attestation must identify node, and match pinnedIdentity if set. The attested identity is returned.
+func (c *Controller) attest(ctx context.Context, node corev1.Node, endpoint, pinnedIdentity string, instances []metadata.InstanceMetadata) (string, error) {
+	ctx, cancel := context.WithTimeout(ctx, attestationTimeout)
+	defer cancel()
+
+	nonce, err := randomBytes()
+	if err != nil {
+		return "", err
+	}
+	userData, err := randomBytes()
+	if err != nil {
+		return "", err
+	}
+
+	attDoc, err := c.attester.GetAttestation(ctx, endpoint, &verifyproto.GetAttestationRequest{
+		Nonce:    nonce,
+		UserData: userData,
+	})
+	if err != nil {
+		return "", fmt.Errorf("getting attestation: %w", err)
+	}
+
+	signedData, claims, err := c.validator.ValidateWithClaims(attDoc, nonce)
+	if err != nil {
+		return "", fmt.Errorf("validating attestation: %w", err)
+	}
+	if !bytes.Equal(signedData, userData) {
+		return "", errors.New("signed data in attestation does not match requested user data")
+	}
+	return verifyIdentity(node, claims, pinnedIdentity, instances)
+}
+
+// verifyIdentity checks that the attestation claims belong to node.
+func verifyIdentity(node corev1.Node, claims policy.Claims, pinnedIdentity string, instances []metadata.InstanceMetadata) (string, error) {
+	identity := claims.Identity()
+	if identity == "" {
+		return "", errors.New("attestation does not identify the node")
+	}
+	if claims.InstanceID != "" {
+		instance, err := membership.FindInstance(instances, claims.InstanceID)
+		if err != nil {
+			return "", fmt.Errorf("looking up attested instance: %w", err)
+		}
+		if instance.ProviderID != node.Spec.ProviderID {
+			return "", fmt.Errorf("attested instance %q does not match provider ID %q of node", instance.ProviderID, node.Spec.ProviderID)
+		}
+	}
+	if pinnedIdentity != "" && identity != pinnedIdentity {
+		return "", fmt.Errorf("attested identity %q differs from identity %q of previous attestations", identity, pinnedIdentity)
+	}
+	return identity, nil
+}
+
+// record updates the status of the node's NodeAttestation, and applies or reverts the quarantine action.
+func (c *Controller) record(ctx context.Context, nodeName string, attestation *NodeAttestation, identity string, attestationErr error) error {
+	now := metav1.NewTime(c.now())
+	status := &attestation.Status
+	status.LastAttestationTime = &now
+
+	if attestationErr == nil {
+		if status.QuarantineAction != "" {
+			c.log.With(zap.String("node", nodeName)).Infof("Releasing node from quarantine")
+			if err := c.kube.Release(ctx, nodeName, status.QuarantineAction); err != nil {
+				return fmt.Errorf("releasing node from quarantine: %w", err)
+			}
+		}
+		status.State = StateTrusted
+		status.LastSuccessfulAttestationTime = &now
+		status.ConsecutiveFailures = 0
+		status.Message = ""
+		status.QuarantineAction = ""
+		status.AttestedIdentity = identity
+		return c.kube.UpdateNodeAttestationStatus(ctx, attestation)
+	}
+
+	status.State = StateFailed
+	status.ConsecutiveFailures++
+	status.Message = attestationErr.Error()
+	if status.QuarantineAction != "" {
+		status.State = StateQuarantined
+	} else if c.policy.Action != ActionNone && status.ConsecutiveFailures >= c.policy.FailureThreshold {
+		c.log.With(zap.String("node", nodeName), zap.String("action", string(c.policy.Action))).Warnf("Quarantining node")
+		if err := c.kube.Quarantine(ctx, nodeName, c.policy.Action); err != nil {
+			return fmt.Errorf("quarantining node: %w", err)
+		}
+		status.State = StateQuarantined
+		status.QuarantineAction = c.policy.Action
+	}
+	return c.kube.UpdateNodeAttestationStatus(ctx, attestation)
+}
+
+// newNodeAttestation returns a NodeAttestation for node, owned by the node so it is deleted along with it.
+func newNodeAttestation(node corev1.Node) *NodeAttestation {
+	return &NodeAttestation{
+		TypeMeta: metav1.TypeMeta{
+			APIVersion: nodeAttestationGVR.GroupVersion().String(),
+			Kind:       "NodeAttestation",
+		},
+		ObjectMeta: metav1.ObjectMeta{
+			Name: node.Name,
+			OwnerReferences: []metav1.OwnerReference{
+				{
+					APIVersion: "v1",
+					Kind:       "Node",
+					Name:       node.Name,
+					UID:        node.UID,
+				},
+			},
+		},
+		Spec: NodeAttestationSpec{
+			NodeName: node.Name,
+		},
+	}
+}
+
+func randomBytes() ([]byte, error) {
+	b := make([]byte, nonceLength)
+	if _, err := rand.Read(b); err != nil {
+		return nil, fmt.Errorf("generating random bytes: %w", err)
+	}
+	return b, nil
+}
+
+type attester interface {
+	// GetAttestation requests an attestation from the verification service at endpoint.
+	GetAttestation(ctx context.Context, endpoint string, req *verifyproto.GetAttestationRequest) ([]byte, error)
+}
+
+type kubeClient interface {
+	// ListNodes returns all nodes of the cluster.
+	ListNodes(ctx context.Context) ([]corev1.Node, error)
+	// ListVerificationEndpoints returns the endpoints of running verification service pods, keyed by node name.
+	ListVerificationEndpoints(ctx context.Context) (map[string]string, error)
+	// GetNodeAttestation returns the NodeAttestation of a node, or nil if it does not exist.
+	GetNodeAttestation(ctx context.Context, name string) (*NodeAttestation, error)
+	// CreateNodeAttestation creates a NodeAttestation.
+	CreateNodeAttestation(ctx context.Context, attestation *NodeAttestation) (*NodeAttestation, error)
+	// UpdateNodeAttestationStatus updates the status of a NodeAttestation.
+	UpdateNodeAttestationStatus(ctx context.Context, attestation *NodeAttestation) error
+	// Quarantine applies the quarantine action to a node.
+	Quarantine(ctx context.Context, nodeName string, action Action) error
+	// Release reverts the quarantine action applied to a node.
+	Release(ctx context.Context, nodeName string, action Action) error
+}
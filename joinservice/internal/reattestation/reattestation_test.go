@@ -0,0 +1,441 @@
+/*
+Copyright (c) Edgeless Systems GmbH
+
+SPDX-License-Identifier: AGPL-3.0-only
+*/
+
+package reattestation
+
+import (
+	"context"
+	"encoding/asn1"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/edgelesssys/constellation/v2/internal/attestation/policy"
+	"github.com/edgelesssys/constellation/v2/internal/cloud/metadata"
+	"github.com/edgelesssys/constellation/v2/internal/logger"
+	"github.com/edgelesssys/constellation/v2/verify/verifyproto"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/goleak"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+func TestMain(m *testing.M) {
+	goleak.VerifyTestMain(m)
+}
+
+func TestReattest(t *testing.T) {
+	someErr := errors.New("failed")
+	node := corev1.Node{ObjectMeta: metav1.ObjectMeta{Name: "node", UID: "uid"}}
+	gcpNode := corev1.Node{ObjectMeta: metav1.ObjectMeta{Name: "node", UID: "uid"}, Spec: corev1.NodeSpec{ProviderID: "gce://project/zone/node"}}
+	azureProviderID := "azure:///subscriptions/subscription/resourceGroups/rg/providers/Microsoft.Compute/virtualMachineScaleSets/workers/virtualMachines/0"
+	azureNode := corev1.Node{ObjectMeta: metav1.ObjectMeta{Name: "node", UID: "uid"}, Spec: corev1.NodeSpec{ProviderID: azureProviderID}}
+	instances := []metadata.InstanceMetadata{
+		{Name: "node", ProviderID: "gce://project/zone/node"},
+		{Name: "other", ProviderID: "gce://project/zone/other"},
+		{Name: "node", ProviderID: azureProviderID, VMID: "b6c98c3b-4ec7-4da6-bd2f-7d98d20d7b75"},
+	}
+	endpoints := map[string]string{"node": "192.0.2.1:9090"}
+	taintPolicy := Policy{Action: ActionTaint, FailureThreshold: 2}
+
+	testCases := map[string]struct {
+		kube             *stubKubeClient
+		attester         *stubAttester
+		validator        *stubValidator
+		policy           Policy
+		listInstancesErr error
+		wantErr          bool
+		wantState        State
+		wantFailures     int
+		wantQuarantine   Action
+		wantQuarantined  []string
+		wantReleased     []string
+		wantIdentity     string
+	}{
+		"first successful attestation creates status": {
+			kube:         &stubKubeClient{nodes: []corev1.Node{node}, endpoints: endpoints},
+			attester:     &stubAttester{},
+			validator:    &stubValidator{},
+			policy:       taintPolicy,
+			wantState:    StateTrusted,
+			wantIdentity: "ak:01",
+		},
+		"success resets failures": {
+			kube: &stubKubeClient{
+				nodes: []corev1.Node{node}, endpoints: endpoints,
+				attestation: &NodeAttestation{Status: NodeAttestationStatus{State: StateFailed, ConsecutiveFailures: 1}},
+			},
+			attester:  &stubAttester{},
+			validator: &stubValidator{},
+			policy:    taintPolicy,
+			wantState: StateTrusted,
+		},
+		"success releases quarantined node": {
+			kube: &stubKubeClient{
+				nodes: []corev1.Node{node}, endpoints: endpoints,
+				attestation: &NodeAttestation{Status: NodeAttestationStatus{
+					State: StateQuarantined, ConsecutiveFailures: 5, QuarantineAction: ActionCordon,
+				}},
+			},
+			attester:     &stubAttester{},
+			validator:    &stubValidator{},
+			policy:       taintPolicy,
+			wantState:    StateTrusted,
+			wantReleased: []string{"node"},
+		},
+		"failure below threshold": {
+			kube:         &stubKubeClient{nodes: []corev1.Node{node}, endpoints: endpoints},
+			attester:     &stubAttester{},
+			validator:    &stubValidator{err: someErr},
+			policy:       taintPolicy,
+			wantState:    StateFailed,
+			wantFailures: 1,
+		},
+		"failure reaching threshold quarantines node": {
+			kube: &stubKubeClient{
+				nodes: []corev1.Node{node}, endpoints: endpoints,
+				attestation: &NodeAttestation{Status: NodeAttestationStatus{State: StateFailed, ConsecutiveFailures: 1}},
+			},
+			attester:        &stubAttester{},
+			validator:       &stubValidator{err: someErr},
+			policy:          taintPolicy,
+			wantState:       StateQuarantined,
+			wantFailures:    2,
+			wantQuarantine:  ActionTaint,
+			wantQuarantined: []string{"node"},
+		},
+		"already quarantined node is not quarantined again": {
+			kube: &stubKubeClient{
+				nodes: []corev1.Node{node}, endpoints: endpoints,
+				attestation: &NodeAttestation{Status: NodeAttestationStatus{
+					State: StateQuarantined, ConsecutiveFailures: 2, QuarantineAction: ActionTaint,
+				}},
+			},
+			attester:       &stubAttester{},
+			validator:      &stubValidator{err: someErr},
+			policy:         taintPolicy,
+			wantState:      StateQuarantined,
+			wantFailures:   3,
+			wantQuarantine: ActionTaint,
+		},
+		"action none only records failures": {
+			kube: &stubKubeClient{
+				nodes: []corev1.Node{node}, endpoints: endpoints,
+				attestation: &NodeAttestation{Status: NodeAttestationStatus{State: StateFailed, ConsecutiveFailures: 9}},
+			},
+			attester:     &stubAttester{},
+			validator:    &stubValidator{err: someErr},
+			policy:       Policy{Action: ActionNone, FailureThreshold: 1},
+			wantState:    StateFailed,
+			wantFailures: 10,
+		},
+		"unreachable verification service counts as failure": {
+			kube:         &stubKubeClient{nodes: []corev1.Node{node}, endpoints: endpoints},
+			attester:     &stubAttester{err: someErr},
+			validator:    &stubValidator{},
+			policy:       taintPolicy,
+			wantState:    StateFailed,
+			wantFailures: 1,
+		},
+		"mismatching user data counts as failure": {
+			kube:         &stubKubeClient{nodes: []corev1.Node{node}, endpoints: endpoints},
+			attester:     &stubAttester{},
+			validator:    &stubValidator{signedData: []byte("replayed")},
+			policy:       taintPolicy,
+			wantState:    StateFailed,
+			wantFailures: 1,
+		},
+		"node without verification service counts as failure": {
+			kube:         &stubKubeClient{nodes: []corev1.Node{node}, endpoints: map[string]string{}},
+			attester:     &stubAttester{},
+			validator:    &stubValidator{},
+			policy:       taintPolicy,
+			wantState:    StateFailed,
+			wantFailures: 1,
+		},
+		"attested instance matches node": {
+			kube:         &stubKubeClient{nodes: []corev1.Node{gcpNode}, endpoints: endpoints},
+			attester:     &stubAttester{},
+			validator:    &stubValidator{claims: &policy.Claims{InstanceID: "projects/project/zones/zone/instances/node"}},
+			policy:       taintPolicy,
+			wantState:    StateTrusted,
+			wantIdentity: "projects/project/zones/zone/instances/node",
+		},
+		"attested instance differs from node": {
+			kube:         &stubKubeClient{nodes: []corev1.Node{gcpNode}, endpoints: endpoints},
+			attester:     &stubAttester{},
+			validator:    &stubValidator{claims: &policy.Claims{InstanceID: "projects/project/zones/zone/instances/other"}},
+			policy:       taintPolicy,
+			wantState:    StateFailed,
+			wantFailures: 1,
+		},
+		"attested Azure VM matches node": {
+			kube:         &stubKubeClient{nodes: []corev1.Node{azureNode}, endpoints: endpoints},
+			attester:     &stubAttester{},
+			validator:    &stubValidator{claims: &policy.Claims{InstanceID: "azure/vms/b6c98c3b-4ec7-4da6-bd2f-7d98d20d7b75"}},
+			policy:       taintPolicy,
+			wantState:    StateTrusted,
+			wantIdentity: "azure/vms/b6c98c3b-4ec7-4da6-bd2f-7d98d20d7b75",
+		},
+		"attested instance not in scaling groups": {
+			kube:         &stubKubeClient{nodes: []corev1.Node{gcpNode}, endpoints: endpoints},
+			attester:     &stubAttester{},
+			validator:    &stubValidator{claims: &policy.Claims{InstanceID: "projects/project/zones/zone/instances/unknown"}},
+			policy:       taintPolicy,
+			wantState:    StateFailed,
+			wantFailures: 1,
+		},
+		"attested instance ID with unknown format": {
+			kube:         &stubKubeClient{nodes: []corev1.Node{gcpNode}, endpoints: endpoints},
+			attester:     &stubAttester{},
+			validator:    &stubValidator{claims: &policy.Claims{InstanceID: "node"}},
+			policy:       taintPolicy,
+			wantState:    StateFailed,
+			wantFailures: 1,
+		},
+		"attestation without identity counts as failure": {
+			kube:         &stubKubeClient{nodes: []corev1.Node{node}, endpoints: endpoints},
+			attester:     &stubAttester{},
+			validator:    &stubValidator{claims: &policy.Claims{}},
+			policy:       taintPolicy,
+			wantState:    StateFailed,
+			wantFailures: 1,
+		},
+		"identity differing from previous attestations counts as failure": {
+			kube: &stubKubeClient{
+				nodes: []corev1.Node{node}, endpoints: endpoints,
+				attestation: &NodeAttestation{Status: NodeAttestationStatus{State: StateTrusted, AttestedIdentity: "ak:02"}},
+			},
+			attester:     &stubAttester{},
+			validator:    &stubValidator{},
+			policy:       taintPolicy,
+			wantState:    StateFailed,
+			wantFailures: 1,
+			wantIdentity: "ak:02",
+		},
+		"identity matching previous attestations": {
+			kube: &stubKubeClient{
+				nodes: []corev1.Node{node}, endpoints: endpoints,
+				attestation: &NodeAttestation{Status: NodeAttestationStatus{State: StateTrusted, AttestedIdentity: "ak:01"}},
+			},
+			attester:     &stubAttester{},
+			validator:    &stubValidator{},
+			policy:       taintPolicy,
+			wantState:    StateTrusted,
+			wantIdentity: "ak:01",
+		},
+		"listing nodes fails": {
+			kube:      &stubKubeClient{listNodesErr: someErr},
+			attester:  &stubAttester{},
+			validator: &stubValidator{},
+			policy:    taintPolicy,
+			wantErr:   true,
+		},
+		"listing instances fails": {
+			kube:             &stubKubeClient{nodes: []corev1.Node{node}, endpoints: endpoints},
+			attester:         &stubAttester{},
+			validator:        &stubValidator{},
+			policy:           taintPolicy,
+			listInstancesErr: someErr,
+			wantErr:          true,
+		},
+		"updating status fails": {
+			kube:      &stubKubeClient{nodes: []corev1.Node{node}, endpoints: endpoints, updateErr: someErr},
+			attester:  &stubAttester{},
+			validator: &stubValidator{},
+			policy:    taintPolicy,
+			wantErr:   true,
+		},
+		"quarantining fails": {
+			kube:      &stubKubeClient{nodes: []corev1.Node{node}, endpoints: endpoints, quarantineErr: someErr},
+			attester:  &stubAttester{},
+			validator: &stubValidator{err: someErr},
+			policy:    Policy{Action: ActionCordon, FailureThreshold: 1},
+			wantErr:   true,
+		},
+	}
+
+	for name, tc := range testCases {
+		t.Run(name, func(t *testing.T) {
+			assert := assert.New(t)
+			require := require.New(t)
+
+			now := time.Unix(1660000000, 0)
+			lister := &stubInstanceLister{instances: instances, err: tc.listInstancesErr}
+			controller := New(tc.validator, tc.attester, tc.kube, lister, tc.policy, logger.NewTest(t))
+			controller.now = func() time.Time { return now }
+
+			err := controller.Reattest(context.Background())
+			if tc.wantErr {
+				assert.Error(err)
+				return
+			}
+			require.NoError(err)
+
+			require.NotNil(tc.kube.attestation)
+			status := tc.kube.attestation.Status
+			assert.Equal(tc.wantState, status.State)
+			assert.Equal(tc.wantFailures, status.ConsecutiveFailures)
+			assert.Equal(tc.wantQuarantine, status.QuarantineAction)
+			assert.Equal(tc.wantQuarantined, tc.kube.quarantined)
+			assert.Equal(tc.wantReleased, tc.kube.released)
+			if tc.wantIdentity != "" {
+				assert.Equal(tc.wantIdentity, status.AttestedIdentity)
+			}
+			assert.True(now.Equal(status.LastAttestationTime.Time))
+			if tc.wantState == StateTrusted {
+				assert.Empty(status.Message)
+				require.NotNil(status.LastSuccessfulAttestationTime)
+				assert.True(now.Equal(status.LastSuccessfulAttestationTime.Time))
+			} else {
+				assert.NotEmpty(status.Message)
+			}
+		})
+	}
+}
+
+func TestNewNodeAttestation(t *testing.T) {
+	assert := assert.New(t)
+
+	attestation := newNodeAttestation(corev1.Node{ObjectMeta: metav1.ObjectMeta{Name: "node", UID: "uid"}})
+	assert.Equal("node", attestation.Name)
+	assert.Equal("node", attestation.Spec.NodeName)
+	assert.Equal("attestation.edgeless.systems/v1alpha1", attestation.APIVersion)
+	assert.Len(attestation.OwnerReferences, 1)
+	assert.Equal("Node", attestation.OwnerReferences[0].Kind)
+	assert.EqualValues("uid", attestation.OwnerReferences[0].UID)
+
+	obj, err := toUnstructured(attestation)
+	assert.NoError(err)
+	converted, err := fromUnstructured(obj)
+	assert.NoError(err)
+	assert.Equal(attestation, converted)
+}
+
+func TestParseAction(t *testing.T) {
+	testCases := map[string]struct {
+		action  string
+		want    Action
+		wantErr bool
+	}{
+		"none":    {action: "none", want: ActionNone},
+		"cordon":  {action: "cordon", want: ActionCordon},
+		"taint":   {action: "taint", want: ActionTaint},
+		"unknown": {action: "drain", wantErr: true},
+		"empty":   {action: "", wantErr: true},
+	}
+
+	for name, tc := range testCases {
+		t.Run(name, func(t *testing.T) {
+			assert := assert.New(t)
+
+			action, err := ParseAction(tc.action)
+			if tc.wantErr {
+				assert.Error(err)
+				return
+			}
+			assert.NoError(err)
+			assert.Equal(tc.want, action)
+		})
+	}
+}
+
+// stubAttester returns the requested user data as attestation document.
+type stubAttester struct {
+	err error
+}
+
+func (a *stubAttester) GetAttestation(_ context.Context, _ string, req *verifyproto.GetAttestationRequest) ([]byte, error) {
+	return req.UserData, a.err
+}
+
+// stubValidator returns the attestation document as signed data, unless signedData is set.
+// Unless claims is set, the attestation is identified by an attestation key digest.
+type stubValidator struct {
+	signedData []byte
+	claims     *policy.Claims
+	err        error
+}
+
+func (v *stubValidator) Validate(attDoc []byte, nonce []byte) ([]byte, error) {
+	signedData, _, err := v.ValidateWithClaims(attDoc, nonce)
+	return signedData, err
+}
+
+func (v *stubValidator) ValidateWithClaims(attDoc []byte, _ []byte) ([]byte, policy.Claims, error) {
+	claims := policy.Claims{AttestationKeyDigest: []byte{0x01}}
+	if v.claims != nil {
+		claims = *v.claims
+	}
+	if v.signedData != nil {
+		return v.signedData, claims, v.err
+	}
+	return attDoc, claims, v.err
+}
+
+func (v *stubValidator) OID() asn1.ObjectIdentifier {
+	return asn1.ObjectIdentifier{1, 3, 9900, 1}
+}
+
+type stubInstanceLister struct {
+	instances []metadata.InstanceMetadata
+	err       error
+}
+
+func (l *stubInstanceLister) List(context.Context) ([]metadata.InstanceMetadata, error) {
+	return l.instances, l.err
+}
+
+type stubKubeClient struct {
+	nodes        []corev1.Node
+	listNodesErr error
+	endpoints    map[string]string
+	attestation  *NodeAttestation
+	updateErr    error
+
+	quarantineErr error
+	quarantined   []string
+	released      []string
+}
+
+func (k *stubKubeClient) ListNodes(context.Context) ([]corev1.Node, error) {
+	return k.nodes, k.listNodesErr
+}
+
+func (k *stubKubeClient) ListVerificationEndpoints(context.Context) (map[string]string, error) {
+	return k.endpoints, nil
+}
+
+func (k *stubKubeClient) GetNodeAttestation(context.Context, string) (*NodeAttestation, error) {
+	return k.attestation, nil
+}
+
+func (k *stubKubeClient) CreateNodeAttestation(_ context.Context, attestation *NodeAttestation) (*NodeAttestation, error) {
+	k.attestation = attestation
+	return attestation, nil
+}
+
+func (k *stubKubeClient) UpdateNodeAttestationStatus(_ context.Context, attestation *NodeAttestation) error {
+	if k.updateErr != nil {
+		return k.updateErr
+	}
+	k.attestation = attestation
+	return nil
+}
+
+func (k *stubKubeClient) Quarantine(_ context.Context, nodeName string, _ Action) error {
+	if k.quarantineErr != nil {
+		return k.quarantineErr
+	}
+	k.quarantined = append(k.quarantined, nodeName)
+	return nil
+}
+
+func (k *stubKubeClient) Release(_ context.Context, nodeName string, _ Action) error {
+	k.released = append(k.released, nodeName)
+	return nil
+}
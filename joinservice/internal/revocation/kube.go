@@ -0,0 +1,53 @@
+/*
+Copyright (c) Edgeless Systems GmbH
+
+SPDX-License-Identifier: AGPL-3.0-only
+*/
+
+package revocation
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/edgelesssys/constellation/v2/internal/revokednode"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/client-go/dynamic"
+	"k8s.io/client-go/rest"
+)
+
+// Client is a Kubernetes client for RevokedNode resources.
+type Client struct {
+	dynamic dynamic.Interface
+}
+
+// NewClient creates a new Client using the in-cluster configuration.
+func NewClient() (*Client, error) {
+	config, err := rest.InClusterConfig()
+	if err != nil {
+		return nil, fmt.Errorf("failed to get in-cluster config: %w", err)
+	}
+	dynamicClient, err := dynamic.NewForConfig(config)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create dynamic client: %w", err)
+	}
+	return &Client{dynamic: dynamicClient}, nil
+}
+
+// ListRevokedNodes returns all RevokedNode resources.
+func (c *Client) ListRevokedNodes(ctx context.Context) ([]revokednode.RevokedNode, error) {
+	list, err := c.dynamic.Resource(revokednode.GroupVersionResource).List(ctx, metav1.ListOptions{})
+	if err != nil {
+		return nil, err
+	}
+	revoked := make([]revokednode.RevokedNode, 0, len(list.Items))
+	for _, item := range list.Items {
+		var node revokednode.RevokedNode
+		if err := runtime.DefaultUnstructuredConverter.FromUnstructured(item.Object, &node); err != nil {
+			return nil, fmt.Errorf("converting revoked node %q: %w", item.GetName(), err)
+		}
+		revoked = append(revoked, node)
+	}
+	return revoked, nil
+}
@@ -0,0 +1,101 @@
+/*
+Copyright (c) Edgeless Systems GmbH
+
+SPDX-License-Identifier: AGPL-3.0-only
+*/
+
+/*
+Package revocation denies revoked nodes to join or rejoin the cluster.
+
+Nodes are revoked by creating a cluster scoped RevokedNode resource, e.g. using "constellation node revoke".
+A RevokedNode lists the identifiers of the revoked node: its name, provider ID, attested identities and state disks.
+A node matching any of these identifiers is denied.
+Since disks and attested identities are revoked as well, neither a restarted node nor a clone of its disk can rejoin.
+*/
+package revocation
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	"github.com/edgelesssys/constellation/v2/internal/logger"
+	"github.com/edgelesssys/constellation/v2/internal/revokednode"
+	"go.uber.org/zap"
+)
+
+// ErrRevoked is returned if a node was revoked.
+var ErrRevoked = errors.New("node was revoked")
+
+// Node holds the identifiers of a node requesting to join or rejoin.
+// Empty identifiers are ignored.
+type Node struct {
+	Name string
+	// ProviderID is the cloud provider ID of the node's instance.
+	ProviderID string
+	// Identity is the attested identity of the node.
+	Identity string
+	// DiskUUID is the UUID of the node's state disk.
+	DiskUUID string
+}
+
+// Checker checks nodes against the revocation list.
+type Checker struct {
+	kube kubeClient
+	log  *logger.Logger
+}
+
+// New creates a new Checker.
+func New(kube kubeClient, log *logger.Logger) *Checker {
+	return &Checker{kube: kube, log: log}
+}
+
+// Check returns ErrRevoked if any identifier of the node was revoked.
+func (c *Checker) Check(ctx context.Context, node Node) error {
+	revoked, err := c.kube.ListRevokedNodes(ctx)
+	if err != nil {
+		return fmt.Errorf("listing revoked nodes: %w", err)
+	}
+
+	for _, revokedNode := range revoked {
+		match := matches(revokedNode.Spec, node)
+		if match == "" {
+			continue
+		}
+		c.log.With(
+			zap.Bool("audit", true), zap.String("revokedNode", revokedNode.Name), zap.String("match", match),
+			zap.String("nodeName", node.Name), zap.String("providerID", node.ProviderID),
+			zap.String("identity", node.Identity), zap.String("diskUUID", node.DiskUUID),
+		).Warnf("Denying revoked node")
+		return fmt.Errorf("%w: %s matches revoked node %q", ErrRevoked, match, revokedNode.Name)
+	}
+	return nil
+}
+
+// matches returns the name of the identifier of node revoked by spec, or an empty string if none is revoked.
+func matches(spec revokednode.Spec, node Node) string {
+	switch {
+	case node.Name != "" && node.Name == spec.NodeName:
+		return "node name"
+	case node.ProviderID != "" && node.ProviderID == spec.ProviderID:
+		return "provider ID"
+	case node.Identity != "" && contains(spec.AttestationIdentities, node.Identity):
+		return "attestation identity"
+	case node.DiskUUID != "" && contains(spec.DiskUUIDs, node.DiskUUID):
+		return "disk UUID"
+	}
+	return ""
+}
+
+func contains(list []string, s string) bool {
+	for _, item := range list {
+		if item == s {
+			return true
+		}
+	}
+	return false
+}
+
+type kubeClient interface {
+	ListRevokedNodes(ctx context.Context) ([]revokednode.RevokedNode, error)
+}
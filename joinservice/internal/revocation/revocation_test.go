@@ -0,0 +1,115 @@
+/*
+Copyright (c) Edgeless Systems GmbH
+
+SPDX-License-Identifier: AGPL-3.0-only
+*/
+
+package revocation
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/edgelesssys/constellation/v2/internal/logger"
+	"github.com/edgelesssys/constellation/v2/internal/revokednode"
+	"github.com/stretchr/testify/assert"
+	"go.uber.org/goleak"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+func TestMain(m *testing.M) {
+	goleak.VerifyTestMain(m)
+}
+
+func TestCheck(t *testing.T) {
+	revoked := []revokednode.RevokedNode{
+		{
+			ObjectMeta: metav1.ObjectMeta{Name: "worker-0"},
+			Spec: revokednode.Spec{
+				NodeName:              "worker-0",
+				ProviderID:            "gce://project/zone/worker-0",
+				AttestationIdentities: []string{"projects/project/zones/zone/instances/worker-0"},
+				DiskUUIDs:             []string{"disk-0"},
+			},
+		},
+		{
+			ObjectMeta: metav1.ObjectMeta{Name: "worker-1"},
+			Spec: revokednode.Spec{
+				AttestationIdentities: []string{"ak:abcd"},
+			},
+		},
+	}
+
+	testCases := map[string]struct {
+		kube        stubKubeClient
+		node        Node
+		wantErr     bool
+		wantRevoked bool
+	}{
+		"no revoked nodes": {
+			node: Node{Name: "worker-0", Identity: "ak:abcd", DiskUUID: "disk-0"},
+		},
+		"node not revoked": {
+			kube: stubKubeClient{revoked: revoked},
+			node: Node{Name: "worker-2", ProviderID: "gce://project/zone/worker-2", Identity: "ak:1234", DiskUUID: "disk-2"},
+		},
+		"node name revoked": {
+			kube:        stubKubeClient{revoked: revoked},
+			node:        Node{Name: "worker-0"},
+			wantErr:     true,
+			wantRevoked: true,
+		},
+		"provider ID revoked": {
+			kube:        stubKubeClient{revoked: revoked},
+			node:        Node{ProviderID: "gce://project/zone/worker-0"},
+			wantErr:     true,
+			wantRevoked: true,
+		},
+		"attestation identity revoked": {
+			kube:        stubKubeClient{revoked: revoked},
+			node:        Node{Name: "worker-2", Identity: "ak:abcd", DiskUUID: "disk-2"},
+			wantErr:     true,
+			wantRevoked: true,
+		},
+		"disk revoked": {
+			kube:        stubKubeClient{revoked: revoked},
+			node:        Node{Identity: "ak:1234", DiskUUID: "disk-0"},
+			wantErr:     true,
+			wantRevoked: true,
+		},
+		"empty identifiers are ignored": {
+			kube: stubKubeClient{revoked: []revokednode.RevokedNode{{ObjectMeta: metav1.ObjectMeta{Name: "empty"}}}},
+			node: Node{},
+		},
+		"listing revoked nodes fails": {
+			kube:    stubKubeClient{listErr: errors.New("failed")},
+			node:    Node{Name: "worker-0"},
+			wantErr: true,
+		},
+	}
+
+	for name, tc := range testCases {
+		t.Run(name, func(t *testing.T) {
+			assert := assert.New(t)
+
+			checker := New(tc.kube, logger.NewTest(t))
+			err := checker.Check(context.Background(), tc.node)
+			if tc.wantErr {
+				assert.Error(err)
+			} else {
+				assert.NoError(err)
+			}
+			assert.Equal(tc.wantRevoked, errors.Is(err, ErrRevoked))
+		})
+	}
+}
+
+type stubKubeClient struct {
+	revoked []revokednode.RevokedNode
+	listErr error
+}
+
+func (s stubKubeClient) ListRevokedNodes(context.Context) ([]revokednode.RevokedNode, error) {
+	return s.revoked, s.listErr
+}
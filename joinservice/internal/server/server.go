@@ -8,18 +8,31 @@ package server
 
 import (
 	"context"
+	"errors"
 	"fmt"
+	"io"
+	"io/fs"
 	"net"
 	"path/filepath"
 	"time"
 
 	"github.com/edgelesssys/constellation/v2/internal/attestation"
+	"github.com/edgelesssys/constellation/v2/internal/cloud/metadata"
 	"github.com/edgelesssys/constellation/v2/internal/constants"
 	"github.com/edgelesssys/constellation/v2/internal/crypto"
 	"github.com/edgelesssys/constellation/v2/internal/file"
+	"github.com/edgelesssys/constellation/v2/internal/grpc/atlscredentials"
 	"github.com/edgelesssys/constellation/v2/internal/grpc/grpclog"
 	"github.com/edgelesssys/constellation/v2/internal/logger"
+	"github.com/edgelesssys/constellation/v2/internal/role"
+	"github.com/edgelesssys/constellation/v2/internal/versions"
+	"github.com/edgelesssys/constellation/v2/joinservice/internal/diskowner"
+	"github.com/edgelesssys/constellation/v2/joinservice/internal/joinrecord"
+	"github.com/edgelesssys/constellation/v2/joinservice/internal/membership"
+	"github.com/edgelesssys/constellation/v2/joinservice/internal/revocation"
 	"github.com/edgelesssys/constellation/v2/joinservice/joinproto"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promauto"
 	"go.uber.org/zap"
 	"google.golang.org/grpc"
 	"google.golang.org/grpc/codes"
@@ -29,30 +42,57 @@ import (
 	kubeadmv1 "k8s.io/kubernetes/cmd/kubeadm/app/apis/kubeadm/v1beta3"
 )
 
+// recordTimeout is the timeout for recording the result of a request in the join log.
+// Results are recorded independently of the request context, which is canceled once the RPC returns.
+const recordTimeout = 10 * time.Second
+
+// handshakeRequest is the request name of join log records for failed aTLS handshakes.
+const handshakeRequest = "Handshake"
+
+// certificateRenewals counts the kubelet certificates renewed through RenewKubeletCertificate.
+var certificateRenewals = promauto.NewCounter(prometheus.CounterOpts{
+	Namespace: "constellation",
+	Subsystem: "join_service",
+	Name:      "kubelet_certificate_renewals_total",
+	Help:      "Number of kubelet certificates renewed by nodes of the cluster.",
+})
+
 // Server implements the core logic of Constellation's node join service.
 type Server struct {
 	measurementSalt []byte
 
-	log             *logger.Logger
-	file            file.Handler
-	joinTokenGetter joinTokenGetter
-	dataKeyGetter   dataKeyGetter
-	ca              certificateAuthority
+	log              *logger.Logger
+	file             file.Handler
+	joinTokenGetter  joinTokenGetter
+	dataKeyGetter    dataKeyGetter
+	ca               certificateAuthority
+	diskOwners       diskOwnerBinder
+	membership       membershipVerifier
+	revocations      revocationChecker
+	joinRecords      joinRecorder
+	handshakeRecords handshakeRecorder
 	joinproto.UnimplementedAPIServer
 }
 
 // New initializes a new Server.
 func New(
 	measurementSalt []byte, fileHandler file.Handler, ca certificateAuthority,
-	joinTokenGetter joinTokenGetter, dataKeyGetter dataKeyGetter, log *logger.Logger,
+	joinTokenGetter joinTokenGetter, dataKeyGetter dataKeyGetter,
+	diskOwners diskOwnerBinder, members membershipVerifier, revocations revocationChecker,
+	joinRecords joinRecorder, handshakeRecords handshakeRecorder, log *logger.Logger,
 ) *Server {
 	return &Server{
-		measurementSalt: measurementSalt,
-		log:             log,
-		file:            fileHandler,
-		joinTokenGetter: joinTokenGetter,
-		dataKeyGetter:   dataKeyGetter,
-		ca:              ca,
+		measurementSalt:  measurementSalt,
+		log:              log,
+		file:             fileHandler,
+		joinTokenGetter:  joinTokenGetter,
+		dataKeyGetter:    dataKeyGetter,
+		ca:               ca,
+		diskOwners:       diskOwners,
+		membership:       members,
+		revocations:      revocations,
+		joinRecords:      joinRecords,
+		handshakeRecords: handshakeRecords,
 	}
 }
 
@@ -60,7 +100,7 @@ func New(
 func (s *Server) Run(creds credentials.TransportCredentials, port string) error {
 	s.log.WithIncreasedLevel(zap.WarnLevel).Named("gRPC").ReplaceGRPCLogger()
 	grpcServer := grpc.NewServer(
-		grpc.Creds(creds),
+		grpc.Creds(&recordingCredentials{TransportCredentials: creds, record: s.recordHandshake}),
 		s.log.Named("gRPC").GetServerUnaryInterceptor(),
 	)
 
@@ -81,10 +121,32 @@ func (s *Server) Run(creds credentials.TransportCredentials, port string) error
 // - measurement salt and secret, to mark the node as initialized.
 // In addition, control plane nodes receive:
 // - a decryption key for CA certificates uploaded to the Kubernetes cluster.
-func (s *Server) IssueJoinTicket(ctx context.Context, req *joinproto.IssueJoinTicketRequest) (*joinproto.IssueJoinTicketResponse, error) {
-	log := s.log.With(zap.String("peerAddress", grpclog.PeerAddrFromContext(ctx)))
+func (s *Server) IssueJoinTicket(ctx context.Context, req *joinproto.IssueJoinTicketRequest) (resp *joinproto.IssueJoinTicketResponse, retErr error) {
+	log := s.peerLogger(ctx)
 	log.Infof("IssueJoinTicket called")
 
+	record := newJoinRecord(ctx, "IssueJoinTicket")
+	record.DiskUUID = req.DiskUuid
+	record.IsControlPlane = req.IsControlPlane
+	defer func() { s.recordResult(record, retErr) }()
+
+	instance, err := s.verifyMembership(ctx, log, req.CertificateRequest, req.IsControlPlane)
+	if err != nil {
+		return nil, err
+	}
+	record.NodeName = instance.Name
+	if err := s.checkRevocation(ctx, log, revocation.Node{
+		Name:       instance.Name,
+		ProviderID: instance.ProviderID,
+		Identity:   peerIdentity(ctx),
+		DiskUUID:   req.DiskUuid,
+	}); err != nil {
+		return nil, err
+	}
+	if err := s.bindDisk(ctx, log, req.DiskUuid, instance.Name); err != nil {
+		return nil, err
+	}
+
 	log.Infof("Requesting measurement secret")
 	measurementSecret, err := s.dataKeyGetter.GetDataKey(ctx, attestation.MeasurementSecretContext, crypto.DerivedKeyLengthDefault)
 	if err != nil {
@@ -111,6 +173,10 @@ func (s *Server) IssueJoinTicket(ctx context.Context, req *joinproto.IssueJoinTi
 	if err != nil {
 		return nil, status.Errorf(codes.Internal, "unable to get k8s version: %s", err)
 	}
+	k8sComponents, err := s.getK8sComponents()
+	if err != nil {
+		return nil, status.Errorf(codes.Internal, "unable to get k8s components: %s", err)
+	}
 
 	log.Infof("Creating signed kubelet certificate")
 	kubeletCert, err := s.ca.GetCertificate(req.CertificateRequest)
@@ -146,13 +212,35 @@ func (s *Server) IssueJoinTicket(ctx context.Context, req *joinproto.IssueJoinTi
 		KubeletCert:              kubeletCert,
 		ControlPlaneFiles:        controlPlaneFiles,
 		KubernetesVersion:        k8sVersion,
+		KubernetesComponents:     k8sComponents,
 	}, nil
 }
 
-func (s *Server) IssueRejoinTicket(ctx context.Context, req *joinproto.IssueRejoinTicketRequest) (*joinproto.IssueRejoinTicketResponse, error) {
-	log := s.log.With(zap.String("peerAddress", grpclog.PeerAddrFromContext(ctx)))
+func (s *Server) IssueRejoinTicket(ctx context.Context, req *joinproto.IssueRejoinTicketRequest) (resp *joinproto.IssueRejoinTicketResponse, retErr error) {
+	log := s.peerLogger(ctx)
 	log.Infof("IssueRejoinTicket called")
 
+	record := newJoinRecord(ctx, "IssueRejoinTicket")
+	record.DiskUUID = req.DiskUuid
+	defer func() { s.recordResult(record, retErr) }()
+
+	instance, err := s.lookupInstance(ctx, log)
+	if err != nil {
+		return nil, err
+	}
+	record.NodeName = instance.Name
+	if err := s.checkRevocation(ctx, log, revocation.Node{
+		Name:       instance.Name,
+		ProviderID: instance.ProviderID,
+		Identity:   peerIdentity(ctx),
+		DiskUUID:   req.DiskUuid,
+	}); err != nil {
+		return nil, err
+	}
+	if err := s.bindDisk(ctx, log, req.DiskUuid, instance.Name); err != nil {
+		return nil, err
+	}
+
 	log.Infof("Requesting measurement secret")
 	measurementSecret, err := s.dataKeyGetter.GetDataKey(ctx, attestation.MeasurementSecretContext, crypto.DerivedKeyLengthDefault)
 	if err != nil {
@@ -173,6 +261,214 @@ func (s *Server) IssueRejoinTicket(ctx context.Context, req *joinproto.IssueRejo
 	}, nil
 }
 
+// RenewKubeletCertificate issues a new kubelet certificate to a node of the cluster.
+// The node is attested again during the aTLS handshake, and has to pass the same membership
+// and revocation checks as a joining node.
+func (s *Server) RenewKubeletCertificate(ctx context.Context, req *joinproto.RenewKubeletCertificateRequest) (resp *joinproto.RenewKubeletCertificateResponse, retErr error) {
+	log := s.peerLogger(ctx)
+	log.Infof("RenewKubeletCertificate called")
+
+	record := newJoinRecord(ctx, "RenewKubeletCertificate")
+	record.IsControlPlane = req.IsControlPlane
+	defer func() { s.recordResult(record, retErr) }()
+
+	instance, err := s.verifyMembership(ctx, log, req.CertificateRequest, req.IsControlPlane)
+	if err != nil {
+		return nil, err
+	}
+	record.NodeName = instance.Name
+	if err := s.checkRevocation(ctx, log, revocation.Node{
+		Name:       instance.Name,
+		ProviderID: instance.ProviderID,
+		Identity:   peerIdentity(ctx),
+	}); err != nil {
+		return nil, err
+	}
+
+	log.Infof("Creating signed kubelet certificate")
+	kubeletCert, err := s.ca.GetCertificate(req.CertificateRequest)
+	if err != nil {
+		log.With(zap.Error(err)).Errorf("Unable to generate kubelet certificate")
+		return nil, status.Errorf(codes.Internal, "unable to generate kubelet certificate: %s", err)
+	}
+	certificateRenewals.Inc()
+
+	log.Infof("RenewKubeletCertificate successful")
+	return &joinproto.RenewKubeletCertificateResponse{KubeletCert: kubeletCert}, nil
+}
+
+// verifyMembership verifies that the calling node is a member of the cluster's scaling groups with the requested role,
+// and that its kubelet certificate request matches its instance.
+func (s *Server) verifyMembership(ctx context.Context, log *logger.Logger, certificateRequest []byte, isControlPlane bool) (metadata.InstanceMetadata, error) {
+	nodeRole := role.Worker
+	if isControlPlane {
+		nodeRole = role.ControlPlane
+	}
+	var instanceID string
+	if claims, ok := atlscredentials.PeerClaimsFromContext(ctx); ok {
+		instanceID = claims.InstanceID
+	}
+
+	log.Infof("Verifying cluster membership of node")
+	instance, err := s.membership.Verify(ctx, instanceID, certificateRequest, nodeRole)
+	switch {
+	case errors.Is(err, membership.ErrNotMember), errors.Is(err, membership.ErrInvalidCertificateRequest):
+		log.With(zap.Error(err)).Warnf("Rejecting node")
+		return metadata.InstanceMetadata{}, status.Errorf(codes.PermissionDenied, "verifying cluster membership: %s", err)
+	case err != nil:
+		log.With(zap.Error(err)).Errorf("Unable to verify cluster membership")
+		return metadata.InstanceMetadata{}, status.Errorf(codes.Internal, "unable to verify cluster membership: %s", err)
+	}
+	return instance, nil
+}
+
+// lookupInstance returns the instance of a rejoining node, identified by the instance ID reported by its attestation.
+// Empty metadata is returned if the attestation does not report an instance ID.
+func (s *Server) lookupInstance(ctx context.Context, log *logger.Logger) (metadata.InstanceMetadata, error) {
+	var instanceID string
+	if claims, ok := atlscredentials.PeerClaimsFromContext(ctx); ok {
+		instanceID = claims.InstanceID
+	}
+
+	log.Infof("Looking up instance of node")
+	instance, err := s.membership.Lookup(ctx, instanceID)
+	switch {
+	case errors.Is(err, membership.ErrNotMember):
+		log.With(zap.Error(err)).Warnf("Rejecting node")
+		return metadata.InstanceMetadata{}, status.Errorf(codes.PermissionDenied, "looking up instance: %s", err)
+	case err != nil:
+		log.With(zap.Error(err)).Errorf("Unable to look up instance")
+		return metadata.InstanceMetadata{}, status.Errorf(codes.Internal, "unable to look up instance: %s", err)
+	}
+	return instance, nil
+}
+
+// checkRevocation denies nodes matching an entry of the revocation list.
+func (s *Server) checkRevocation(ctx context.Context, log *logger.Logger, node revocation.Node) error {
+	log.Infof("Checking revocation list")
+	err := s.revocations.Check(ctx, node)
+	switch {
+	case errors.Is(err, revocation.ErrRevoked):
+		return status.Errorf(codes.PermissionDenied, "%s", err)
+	case err != nil:
+		log.With(zap.Error(err)).Errorf("Unable to check revocation list")
+		return status.Errorf(codes.Internal, "unable to check revocation list: %s", err)
+	}
+	return nil
+}
+
+// bindDisk verifies that the state disk belongs to the calling node, binding the disk to the node on its first request.
+// Nodes whose attestation doesn't identify them are rejected, since their disks can't be bound.
+// nodeName is recorded as the name of the node using the disk, unless it is empty.
+func (s *Server) bindDisk(ctx context.Context, log *logger.Logger, diskUUID, nodeName string) error {
+	owner := peerIdentity(ctx)
+	if owner == "" {
+		log.Warnf("Rejecting node: attestation does not report an identity to bind disk %s to", diskUUID)
+		return status.Error(codes.PermissionDenied, "attestation of node does not report an identity")
+	}
+
+	log.Infof("Verifying owner of disk")
+	err := s.diskOwners.Bind(ctx, diskUUID, owner, nodeName)
+	switch {
+	case errors.Is(err, diskowner.ErrForeignDisk):
+		return status.Errorf(codes.PermissionDenied, "disk %s belongs to a different node", diskUUID)
+	case errors.Is(err, diskowner.ErrInvalidDiskUUID):
+		log.With(zap.Error(err)).Warnf("Rejecting node")
+		return status.Errorf(codes.InvalidArgument, "%s", err)
+	case err != nil:
+		log.With(zap.Error(err)).Errorf("Unable to verify owner of disk")
+		return status.Errorf(codes.Internal, "unable to verify owner of disk: %s", err)
+	}
+	return nil
+}
+
+// peerIdentity returns the attested identity of the calling node.
+// An empty string is returned if the attestation does not identify the node.
+func peerIdentity(ctx context.Context) string {
+	claims, ok := atlscredentials.PeerClaimsFromContext(ctx)
+	if !ok {
+		return ""
+	}
+	return claims.Identity()
+}
+
+// newJoinRecord returns a join record for a request of the calling node, holding the node's address and attestation claims.
+func newJoinRecord(ctx context.Context, request string) joinrecord.JoinRecordSpec {
+	record := joinrecord.JoinRecordSpec{
+		Request:             request,
+		PeerAddress:         grpclog.PeerAddrFromContext(ctx),
+		AttestationIdentity: peerIdentity(ctx),
+	}
+	if claims, ok := atlscredentials.PeerClaimsFromContext(ctx); ok {
+		record.AttestationVariant = claims.Variant.String()
+		record.InstanceID = claims.InstanceID
+	}
+	return record
+}
+
+// recordResult records the result of a request in the join log.
+func (s *Server) recordResult(record joinrecord.JoinRecordSpec, err error) {
+	ctx, cancel := context.WithTimeout(context.Background(), recordTimeout)
+	defer cancel()
+	s.joinRecords.Record(ctx, withResult(record, err))
+}
+
+// recordHandshake records a failed aTLS handshake in the join log.
+// Handshakes are unauthenticated, so they are aggregated per peer and recorded asynchronously.
+func (s *Server) recordHandshake(record joinrecord.JoinRecordSpec, err error) {
+	s.handshakeRecords.Add(withResult(record, err))
+}
+
+// withResult sets the result of record according to err.
+// Requests denied by the checks of the join service are distinguished from requests failing due to internal errors.
+func withResult(record joinrecord.JoinRecordSpec, err error) joinrecord.JoinRecordSpec {
+	switch {
+	case err == nil:
+		record.Result = joinrecord.ResultAccepted
+	case status.Code(err) == codes.PermissionDenied:
+		record.Result = joinrecord.ResultDenied
+		record.Reason = status.Convert(err).Message()
+	default:
+		record.Result = joinrecord.ResultFailed
+		record.Reason = status.Convert(err).Message()
+	}
+	return record
+}
+
+// recordingCredentials records failed aTLS handshakes in the join log.
+// Nodes failing attestation are rejected during the handshake and never reach the RPC handlers.
+type recordingCredentials struct {
+	credentials.TransportCredentials
+	record func(record joinrecord.JoinRecordSpec, err error)
+}
+
+// ServerHandshake performs the aTLS handshake and records it if it failed.
+// Connections closed before the handshake started, e.g., by TCP health checks, are not recorded.
+func (c *recordingCredentials) ServerHandshake(rawConn net.Conn) (net.Conn, credentials.AuthInfo, error) {
+	conn, authInfo, err := c.TransportCredentials.ServerHandshake(rawConn)
+	if err != nil && !errors.Is(err, io.EOF) {
+		c.record(joinrecord.JoinRecordSpec{
+			Request:     handshakeRequest,
+			PeerAddress: rawConn.RemoteAddr().String(),
+		}, status.Errorf(codes.PermissionDenied, "aTLS handshake failed: %s", err))
+	}
+	return conn, authInfo, err
+}
+
+// Clone returns a copy of the credentials.
+func (c *recordingCredentials) Clone() credentials.TransportCredentials {
+	return &recordingCredentials{TransportCredentials: c.TransportCredentials.Clone(), record: c.record}
+}
+
+// peerLogger returns a logger annotated with the address and the attested identity of the calling node.
+func (s *Server) peerLogger(ctx context.Context) *logger.Logger {
+	log := s.log.With(zap.String("peerAddress", grpclog.PeerAddrFromContext(ctx)))
+	if claims, ok := atlscredentials.PeerClaimsFromContext(ctx); ok {
+		log = log.With(zap.String("attestationVariant", claims.Variant.String()), zap.String("instanceID", claims.InstanceID))
+	}
+	return log
+}
+
 // getK8sVersion reads the k8s version from a VolumeMount that is backed by the k8s-version ConfigMap.
 func (s *Server) getK8sVersion() (string, error) {
 	fileContent, err := s.file.Read(filepath.Join(constants.ServiceBasePath, constants.K8sVersion))
@@ -184,6 +480,28 @@ func (s *Server) getK8sVersion() (string, error) {
 	return k8sVersion, nil
 }
 
+// getK8sComponents reads the Kubernetes components pinned by the node operator from a VolumeMount that is backed by the k8s-version ConfigMap.
+// The file only exists after the node operator upgraded the cluster's Kubernetes version.
+func (s *Server) getK8sComponents() ([]*joinproto.KubernetesComponent, error) {
+	var components []versions.Component
+	if err := s.file.ReadJSON(filepath.Join(constants.ServiceBasePath, constants.K8sComponents), &components); errors.Is(err, fs.ErrNotExist) {
+		return nil, nil
+	} else if err != nil {
+		return nil, fmt.Errorf("could not read k8s components file: %w", err)
+	}
+
+	var k8sComponents []*joinproto.KubernetesComponent
+	for _, component := range components {
+		k8sComponents = append(k8sComponents, &joinproto.KubernetesComponent{
+			Url:         component.URL,
+			Hash:        component.Hash,
+			InstallPath: component.InstallPath,
+			Extract:     component.Extract,
+		})
+	}
+	return k8sComponents, nil
+}
+
 // joinTokenGetter returns Kubernetes bootstrap (join) tokens.
 type joinTokenGetter interface {
 	// GetJoinToken returns a bootstrap (join) token.
@@ -197,6 +515,37 @@ type dataKeyGetter interface {
 	GetDataKey(ctx context.Context, uuid string, length int) ([]byte, error)
 }
 
+// diskOwnerBinder binds state disks to the nodes they belong to.
+type diskOwnerBinder interface {
+	// Bind binds the disk to owner, or returns diskowner.ErrForeignDisk if it belongs to a different node.
+	Bind(ctx context.Context, diskUUID, owner, nodeName string) error
+}
+
+// membershipVerifier verifies that joining nodes belong to the cluster.
+type membershipVerifier interface {
+	// Verify checks that the node is a member of the cluster with the given role, and that the certificate request matches its instance.
+	Verify(ctx context.Context, instanceID string, certificateRequest []byte, nodeRole role.Role) (metadata.InstanceMetadata, error)
+	// Lookup returns the instance identified by an attested instance ID, or membership.ErrNotMember if it is not a member of the cluster.
+	Lookup(ctx context.Context, instanceID string) (metadata.InstanceMetadata, error)
+}
+
+// revocationChecker checks nodes against the revocation list.
+type revocationChecker interface {
+	// Check returns revocation.ErrRevoked if the node was revoked.
+	Check(ctx context.Context, node revocation.Node) error
+}
+
+// joinRecorder records the requests of nodes.
+type joinRecorder interface {
+	// Record records the request described by record.
+	Record(ctx context.Context, record joinrecord.JoinRecordSpec)
+}
+
+type handshakeRecorder interface {
+	// Add adds the request described by record to the aggregated records of its peer.
+	Add(record joinrecord.JoinRecordSpec)
+}
+
 type certificateAuthority interface {
 	// GetCertificate returns a certificate and private key, signed by the issuer.
 	GetCertificate(certificateRequest []byte) (kubeletCert []byte, err error)
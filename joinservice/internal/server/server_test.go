@@ -9,20 +9,35 @@ package server
 import (
 	"context"
 	"errors"
+	"io"
+	"net"
 	"path/filepath"
 	"testing"
 	"time"
 
+	"github.com/edgelesssys/constellation/v2/internal/atls"
 	"github.com/edgelesssys/constellation/v2/internal/attestation"
+	"github.com/edgelesssys/constellation/v2/internal/attestation/policy"
+	"github.com/edgelesssys/constellation/v2/internal/cloud/metadata"
 	"github.com/edgelesssys/constellation/v2/internal/constants"
 	"github.com/edgelesssys/constellation/v2/internal/file"
+	"github.com/edgelesssys/constellation/v2/internal/grpc/atlscredentials"
 	"github.com/edgelesssys/constellation/v2/internal/logger"
+	"github.com/edgelesssys/constellation/v2/internal/role"
 	"github.com/edgelesssys/constellation/v2/internal/versions"
+	"github.com/edgelesssys/constellation/v2/joinservice/internal/diskowner"
+	"github.com/edgelesssys/constellation/v2/joinservice/internal/joinrecord"
+	"github.com/edgelesssys/constellation/v2/joinservice/internal/membership"
+	"github.com/edgelesssys/constellation/v2/joinservice/internal/revocation"
 	"github.com/edgelesssys/constellation/v2/joinservice/joinproto"
 	"github.com/spf13/afero"
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/require"
 	"go.uber.org/goleak"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/credentials"
+	"google.golang.org/grpc/peer"
+	"google.golang.org/grpc/status"
 	kubeadmv1 "k8s.io/kubernetes/cmd/kubeadm/app/apis/kubeadm/v1beta3"
 )
 
@@ -30,6 +45,50 @@ func TestMain(m *testing.M) {
 	goleak.VerifyTestMain(m)
 }
 
+func TestRecordingCredentials(t *testing.T) {
+	testCases := map[string]struct {
+		handshakeErr error
+		wantRecord   bool
+	}{
+		"handshake succeeds": {},
+		"attestation fails": {
+			handshakeErr: errors.New("validation failed"),
+			wantRecord:   true,
+		},
+		"connection closed before handshake": {
+			handshakeErr: io.EOF,
+		},
+	}
+
+	for name, tc := range testCases {
+		t.Run(name, func(t *testing.T) {
+			assert := assert.New(t)
+			require := require.New(t)
+
+			handshakeRecords := &stubHandshakeRecorder{}
+			api := New(nil, file.Handler{}, stubCA{}, stubTokenGetter{}, stubKeyGetter{}, &stubDiskOwners{},
+				&stubMembership{}, &stubRevocations{}, &stubJoinRecorder{}, handshakeRecords, logger.NewTest(t))
+			creds := &recordingCredentials{
+				TransportCredentials: stubTransportCredentials{handshakeErr: tc.handshakeErr},
+				record:               api.recordHandshake,
+			}
+
+			_, _, err := creds.ServerHandshake(stubConn{})
+			assert.ErrorIs(err, tc.handshakeErr)
+			if !tc.wantRecord {
+				assert.Empty(handshakeRecords.records)
+				return
+			}
+			require.Len(handshakeRecords.records, 1)
+			record := handshakeRecords.records[0]
+			assert.Equal(handshakeRequest, record.Request)
+			assert.Equal("192.0.2.1:1234", record.PeerAddress)
+			assert.Equal(joinrecord.ResultDenied, record.Result)
+			assert.Contains(record.Reason, "validation failed")
+		})
+	}
+}
+
 func TestIssueJoinTicket(t *testing.T) {
 	someErr := errors.New("error")
 	testKey := []byte{0x1, 0x2, 0x3}
@@ -49,21 +108,186 @@ func TestIssueJoinTicket(t *testing.T) {
 		kubeadm        stubTokenGetter
 		kms            stubKeyGetter
 		ca             stubCA
+		diskOwners     stubDiskOwners
+		membership     stubMembership
+		revocations    stubRevocations
+		peerClaims     *atls.PeerClaims
+		noIdentity     bool
+		k8sComponents  string
+		wantOwner      string
+		wantRole       role.Role
+		wantComponents []versions.Component
+		wantCode       codes.Code
 		wantErr        bool
 	}{
+		"worker node with pinned Kubernetes components": {
+			kubeadm: stubTokenGetter{token: testJoinToken},
+			kms: stubKeyGetter{dataKeys: map[string][]byte{
+				uuid:                                 testKey,
+				attestation.MeasurementSecretContext: measurementSecret,
+			}},
+			ca:             stubCA{cert: testCert},
+			k8sComponents:  `[{"url":"https://example.com/cni.tgz","sha256":"aaaa","installPath":"/opt/cni/bin","extract":true}]`,
+			wantRole:       role.Worker,
+			wantOwner:      "ak:ab",
+			wantComponents: []versions.Component{{URL: "https://example.com/cni.tgz", Hash: "aaaa", InstallPath: "/opt/cni/bin", Extract: true}},
+		},
+		"invalid pinned Kubernetes components": {
+			kubeadm: stubTokenGetter{token: testJoinToken},
+			kms: stubKeyGetter{dataKeys: map[string][]byte{
+				uuid:                                 testKey,
+				attestation.MeasurementSecretContext: measurementSecret,
+			}},
+			ca:            stubCA{cert: testCert},
+			k8sComponents: "{",
+			wantOwner:     "ak:ab",
+			wantCode:      codes.Internal,
+			wantErr:       true,
+		},
 		"worker node": {
 			kubeadm: stubTokenGetter{token: testJoinToken},
 			kms: stubKeyGetter{dataKeys: map[string][]byte{
 				uuid:                                 testKey,
 				attestation.MeasurementSecretContext: measurementSecret,
 			}},
-			ca: stubCA{cert: testCert},
+			ca:        stubCA{cert: testCert},
+			wantRole:  role.Worker,
+			wantOwner: "ak:ab",
 		},
-		"GetDataKey fails": {
+		"node is not a member": {
 			kubeadm: stubTokenGetter{token: testJoinToken},
-			kms:     stubKeyGetter{dataKeys: make(map[string][]byte), getDataKeyErr: someErr},
-			ca:      stubCA{cert: testCert},
-			wantErr: true,
+			kms: stubKeyGetter{dataKeys: map[string][]byte{
+				uuid:                                 testKey,
+				attestation.MeasurementSecretContext: measurementSecret,
+			}},
+			ca:         stubCA{cert: testCert},
+			membership: stubMembership{verifyErr: membership.ErrNotMember},
+			wantRole:   role.Worker,
+			wantCode:   codes.PermissionDenied,
+			wantErr:    true,
+		},
+		"certificate request does not match instance": {
+			kubeadm: stubTokenGetter{token: testJoinToken},
+			kms: stubKeyGetter{dataKeys: map[string][]byte{
+				uuid:                                 testKey,
+				attestation.MeasurementSecretContext: measurementSecret,
+			}},
+			ca:         stubCA{cert: testCert},
+			membership: stubMembership{verifyErr: membership.ErrInvalidCertificateRequest},
+			wantRole:   role.Worker,
+			wantCode:   codes.PermissionDenied,
+			wantErr:    true,
+		},
+		"verifying membership fails": {
+			kubeadm: stubTokenGetter{token: testJoinToken},
+			kms: stubKeyGetter{dataKeys: map[string][]byte{
+				uuid:                                 testKey,
+				attestation.MeasurementSecretContext: measurementSecret,
+			}},
+			ca:         stubCA{cert: testCert},
+			membership: stubMembership{verifyErr: someErr},
+			wantRole:   role.Worker,
+			wantCode:   codes.Internal,
+			wantErr:    true,
+		},
+		"node revoked": {
+			kubeadm: stubTokenGetter{token: testJoinToken},
+			kms: stubKeyGetter{dataKeys: map[string][]byte{
+				uuid:                                 testKey,
+				attestation.MeasurementSecretContext: measurementSecret,
+			}},
+			ca:          stubCA{cert: testCert},
+			revocations: stubRevocations{checkErr: revocation.ErrRevoked},
+			wantCode:    codes.PermissionDenied,
+			wantErr:     true,
+		},
+		"checking revocation list fails": {
+			kubeadm: stubTokenGetter{token: testJoinToken},
+			kms: stubKeyGetter{dataKeys: map[string][]byte{
+				uuid:                                 testKey,
+				attestation.MeasurementSecretContext: measurementSecret,
+			}},
+			ca:          stubCA{cert: testCert},
+			revocations: stubRevocations{checkErr: someErr},
+			wantCode:    codes.Internal,
+			wantErr:     true,
+		},
+		"disk bound to instance ID": {
+			kubeadm: stubTokenGetter{token: testJoinToken},
+			kms: stubKeyGetter{dataKeys: map[string][]byte{
+				uuid:                                 testKey,
+				attestation.MeasurementSecretContext: measurementSecret,
+			}},
+			ca:         stubCA{cert: testCert},
+			membership: stubMembership{instance: metadata.InstanceMetadata{Name: "worker-0", ProviderID: "gce://project/zone/worker-0"}},
+			peerClaims: &atls.PeerClaims{Claims: policy.Claims{InstanceID: "instance", AttestationKeyDigest: []byte{0xAB}}},
+			wantOwner:  "instance",
+		},
+		"disk bound to attestation key": {
+			kubeadm: stubTokenGetter{token: testJoinToken},
+			kms: stubKeyGetter{dataKeys: map[string][]byte{
+				uuid:                                 testKey,
+				attestation.MeasurementSecretContext: measurementSecret,
+			}},
+			ca:         stubCA{cert: testCert},
+			peerClaims: &atls.PeerClaims{Claims: policy.Claims{AttestationKeyDigest: []byte{0xAB}}},
+			wantOwner:  "ak:ab",
+		},
+		"disk belongs to different node": {
+			kubeadm: stubTokenGetter{token: testJoinToken},
+			kms: stubKeyGetter{dataKeys: map[string][]byte{
+				uuid:                                 testKey,
+				attestation.MeasurementSecretContext: measurementSecret,
+			}},
+			ca:         stubCA{cert: testCert},
+			diskOwners: stubDiskOwners{bindErr: diskowner.ErrForeignDisk},
+			peerClaims: &atls.PeerClaims{Claims: policy.Claims{InstanceID: "instance"}},
+			wantOwner:  "instance",
+			wantCode:   codes.PermissionDenied,
+			wantErr:    true,
+		},
+		"attestation does not identify node": {
+			kubeadm: stubTokenGetter{token: testJoinToken},
+			kms: stubKeyGetter{dataKeys: map[string][]byte{
+				uuid:                                 testKey,
+				attestation.MeasurementSecretContext: measurementSecret,
+			}},
+			ca:         stubCA{cert: testCert},
+			noIdentity: true,
+			wantCode:   codes.PermissionDenied,
+			wantErr:    true,
+		},
+		"invalid disk UUID": {
+			kubeadm: stubTokenGetter{token: testJoinToken},
+			kms: stubKeyGetter{dataKeys: map[string][]byte{
+				uuid:                                 testKey,
+				attestation.MeasurementSecretContext: measurementSecret,
+			}},
+			ca:         stubCA{cert: testCert},
+			diskOwners: stubDiskOwners{bindErr: diskowner.ErrInvalidDiskUUID},
+			wantOwner:  "ak:ab",
+			wantCode:   codes.InvalidArgument,
+			wantErr:    true,
+		},
+		"binding disk fails": {
+			kubeadm: stubTokenGetter{token: testJoinToken},
+			kms: stubKeyGetter{dataKeys: map[string][]byte{
+				uuid:                                 testKey,
+				attestation.MeasurementSecretContext: measurementSecret,
+			}},
+			ca:         stubCA{cert: testCert},
+			diskOwners: stubDiskOwners{bindErr: someErr},
+			peerClaims: &atls.PeerClaims{Claims: policy.Claims{InstanceID: "instance"}},
+			wantOwner:  "instance",
+			wantCode:   codes.Internal,
+			wantErr:    true,
+		},
+		"GetDataKey fails": {
+			kubeadm:   stubTokenGetter{token: testJoinToken},
+			kms:       stubKeyGetter{dataKeys: make(map[string][]byte), getDataKeyErr: someErr},
+			ca:        stubCA{cert: testCert},
+			wantErr:   true,
+			wantOwner: "ak:ab",
 		},
 		"GetJoinToken fails": {
 			kubeadm: stubTokenGetter{getJoinTokenErr: someErr},
@@ -71,8 +295,9 @@ func TestIssueJoinTicket(t *testing.T) {
 				uuid:                                 testKey,
 				attestation.MeasurementSecretContext: measurementSecret,
 			}},
-			ca:      stubCA{cert: testCert},
-			wantErr: true,
+			ca:        stubCA{cert: testCert},
+			wantErr:   true,
+			wantOwner: "ak:ab",
 		},
 		"GetCertificate fails": {
 			kubeadm: stubTokenGetter{token: testJoinToken},
@@ -80,8 +305,9 @@ func TestIssueJoinTicket(t *testing.T) {
 				uuid:                                 testKey,
 				attestation.MeasurementSecretContext: measurementSecret,
 			}},
-			ca:      stubCA{getCertErr: someErr},
-			wantErr: true,
+			ca:        stubCA{getCertErr: someErr},
+			wantErr:   true,
+			wantOwner: "ak:ab",
 		},
 		"control plane": {
 			isControlPlane: true,
@@ -93,7 +319,9 @@ func TestIssueJoinTicket(t *testing.T) {
 				uuid:                                 testKey,
 				attestation.MeasurementSecretContext: measurementSecret,
 			}},
-			ca: stubCA{cert: testCert},
+			ca:        stubCA{cert: testCert},
+			wantRole:  role.ControlPlane,
+			wantOwner: "ak:ab",
 		},
 		"GetControlPlaneCertificateKey fails": {
 			isControlPlane: true,
@@ -102,8 +330,9 @@ func TestIssueJoinTicket(t *testing.T) {
 				uuid:                                 testKey,
 				attestation.MeasurementSecretContext: measurementSecret,
 			}},
-			ca:      stubCA{cert: testCert},
-			wantErr: true,
+			ca:        stubCA{cert: testCert},
+			wantErr:   true,
+			wantOwner: "ak:ab",
 		},
 	}
 
@@ -115,7 +344,11 @@ func TestIssueJoinTicket(t *testing.T) {
 			handler := file.NewHandler(afero.NewMemMapFs())
 			// IssueJoinTicket tries to read the k8s-version ConfigMap from a mounted file.
 			require.NoError(handler.Write(filepath.Join(constants.ServiceBasePath, constants.K8sVersion), []byte(testK8sVersion), file.OptNone))
+			if tc.k8sComponents != "" {
+				require.NoError(handler.Write(filepath.Join(constants.ServiceBasePath, constants.K8sComponents), []byte(tc.k8sComponents), file.OptNone))
+			}
 			salt := []byte{0xA, 0xB, 0xC}
+			joinRecords := &stubJoinRecorder{}
 
 			api := New(
 				salt,
@@ -123,20 +356,66 @@ func TestIssueJoinTicket(t *testing.T) {
 				tc.ca,
 				tc.kubeadm,
 				tc.kms,
+				&tc.diskOwners,
+				&tc.membership,
+				&tc.revocations,
+				joinRecords,
+				&stubHandshakeRecorder{},
 				logger.NewTest(t),
 			)
 
+			ctx := context.Background()
+			claims := tc.peerClaims
+			if claims == nil && !tc.noIdentity {
+				claims = &atls.PeerClaims{Claims: policy.Claims{AttestationKeyDigest: []byte{0xAB}}}
+			}
+			if claims != nil {
+				ctx = peer.NewContext(ctx, &peer.Peer{Addr: &net.TCPAddr{}, AuthInfo: atlscredentials.AuthInfo{PeerClaims: claims}})
+			}
 			req := &joinproto.IssueJoinTicketRequest{
 				DiskUuid:       "uuid",
 				IsControlPlane: tc.isControlPlane,
 			}
-			resp, err := api.IssueJoinTicket(context.Background(), req)
+			resp, err := api.IssueJoinTicket(ctx, req)
+			require.Len(joinRecords.records, 1)
+			assert.Equal("IssueJoinTicket", joinRecords.records[0].Request)
+			assert.Equal(uuid, joinRecords.records[0].DiskUUID)
+			assert.Equal(tc.isControlPlane, joinRecords.records[0].IsControlPlane)
+			assert.Equal(tc.wantOwner, tc.diskOwners.owner)
+			if tc.wantRole != role.Unknown {
+				assert.Equal(tc.wantRole, tc.membership.role)
+			}
+			var identity string
+			if claims != nil {
+				assert.Equal(claims.InstanceID, tc.membership.instanceID)
+				identity = claims.Identity()
+			}
+			if tc.membership.verifyErr == nil {
+				assert.Equal(revocation.Node{
+					Name:       tc.membership.instance.Name,
+					ProviderID: tc.membership.instance.ProviderID,
+					Identity:   identity,
+					DiskUUID:   uuid,
+				}, tc.revocations.node)
+			}
 			if tc.wantErr {
 				assert.Error(err)
+				if tc.wantCode != codes.OK {
+					assert.Equal(tc.wantCode, status.Code(err))
+				}
+				if status.Code(err) == codes.PermissionDenied {
+					assert.Equal(joinrecord.ResultDenied, joinRecords.records[0].Result)
+				} else {
+					assert.Equal(joinrecord.ResultFailed, joinRecords.records[0].Result)
+				}
+				assert.NotEmpty(joinRecords.records[0].Reason)
 				return
 			}
 
 			require.NoError(err)
+			assert.Equal(joinrecord.ResultAccepted, joinRecords.records[0].Result)
+			assert.Equal(tc.membership.instance.Name, joinRecords.records[0].NodeName)
+			assert.Equal(tc.membership.instance.Name, tc.diskOwners.nodeName)
 			assert.Equal(tc.kms.dataKeys[uuid], resp.StateDiskKey)
 			assert.Equal(salt, resp.MeasurementSalt)
 			assert.Equal(tc.kms.dataKeys[attestation.MeasurementSecretContext], resp.MeasurementSecret)
@@ -144,6 +423,17 @@ func TestIssueJoinTicket(t *testing.T) {
 			assert.Equal(tc.kubeadm.token.CACertHashes[0], resp.DiscoveryTokenCaCertHash)
 			assert.Equal(tc.kubeadm.token.Token, resp.Token)
 			assert.Equal(tc.ca.cert, resp.KubeletCert)
+			assert.Equal(string(testK8sVersion), resp.KubernetesVersion)
+			var components []versions.Component
+			for _, component := range resp.KubernetesComponents {
+				components = append(components, versions.Component{
+					URL:         component.Url,
+					Hash:        component.Hash,
+					InstallPath: component.InstallPath,
+					Extract:     component.Extract,
+				})
+			}
+			assert.Equal(tc.wantComponents, components)
 
 			if tc.isControlPlane {
 				assert.Len(resp.ControlPlaneFiles, len(tc.kubeadm.files))
@@ -154,12 +444,17 @@ func TestIssueJoinTicket(t *testing.T) {
 
 func TestIssueRejoinTicker(t *testing.T) {
 	uuid := "uuid"
+	worker := metadata.InstanceMetadata{Name: "worker-0", ProviderID: "gce://project/zone/worker-0"}
 
 	testCases := map[string]struct {
-		keyGetter stubKeyGetter
-		wantErr   bool
+		keyGetter   stubKeyGetter
+		diskOwners  stubDiskOwners
+		membership  stubMembership
+		revocations stubRevocations
+		wantErr     bool
 	}{
 		"success": {
+			membership: stubMembership{instance: worker},
 			keyGetter: stubKeyGetter{
 				dataKeys: map[string][]byte{
 					uuid:                                 {0x1, 0x2, 0x3},
@@ -168,12 +463,45 @@ func TestIssueRejoinTicker(t *testing.T) {
 			},
 		},
 		"failure": {
+			membership: stubMembership{instance: worker},
 			keyGetter: stubKeyGetter{
 				dataKeys:      make(map[string][]byte),
 				getDataKeyErr: errors.New("error"),
 			},
 			wantErr: true,
 		},
+		"disk belongs to different node": {
+			membership: stubMembership{instance: worker},
+			keyGetter: stubKeyGetter{
+				dataKeys: map[string][]byte{
+					uuid:                                 {0x1, 0x2, 0x3},
+					attestation.MeasurementSecretContext: {0x4, 0x5, 0x6},
+				},
+			},
+			diskOwners: stubDiskOwners{bindErr: diskowner.ErrForeignDisk},
+			wantErr:    true,
+		},
+		"node revoked": {
+			membership: stubMembership{instance: worker},
+			keyGetter: stubKeyGetter{
+				dataKeys: map[string][]byte{
+					uuid:                                 {0x1, 0x2, 0x3},
+					attestation.MeasurementSecretContext: {0x4, 0x5, 0x6},
+				},
+			},
+			revocations: stubRevocations{checkErr: revocation.ErrRevoked},
+			wantErr:     true,
+		},
+		"instance is not a member": {
+			membership: stubMembership{lookupErr: membership.ErrNotMember},
+			keyGetter: stubKeyGetter{
+				dataKeys: map[string][]byte{
+					uuid:                                 {0x1, 0x2, 0x3},
+					attestation.MeasurementSecretContext: {0x4, 0x5, 0x6},
+				},
+			},
+			wantErr: true,
+		},
 	}
 
 	for name, tc := range testCases {
@@ -181,19 +509,47 @@ func TestIssueRejoinTicker(t *testing.T) {
 			assert := assert.New(t)
 			require := require.New(t)
 
+			joinRecords := &stubJoinRecorder{}
 			api := New(
 				nil,
 				file.Handler{},
 				stubCA{},
 				stubTokenGetter{},
 				tc.keyGetter,
+				&tc.diskOwners,
+				&tc.membership,
+				&tc.revocations,
+				joinRecords,
+				&stubHandshakeRecorder{},
 				logger.NewTest(t),
 			)
 
+			ctx := peer.NewContext(context.Background(), &peer.Peer{Addr: &net.TCPAddr{}, AuthInfo: atlscredentials.AuthInfo{
+				PeerClaims: &atls.PeerClaims{Claims: policy.Claims{InstanceID: "instance"}},
+			}})
 			req := &joinproto.IssueRejoinTicketRequest{
 				DiskUuid: uuid,
 			}
-			resp, err := api.IssueRejoinTicket(context.Background(), req)
+			resp, err := api.IssueRejoinTicket(ctx, req)
+			require.Len(joinRecords.records, 1)
+			assert.Equal("instance", joinRecords.records[0].AttestationIdentity)
+			assert.Equal(uuid, joinRecords.records[0].DiskUUID)
+			assert.Equal("instance", tc.membership.instanceID)
+			if tc.membership.lookupErr != nil {
+				assert.Error(err)
+				return
+			}
+			assert.Equal(worker.Name, joinRecords.records[0].NodeName)
+			assert.Equal(revocation.Node{
+				Name:       worker.Name,
+				ProviderID: worker.ProviderID,
+				Identity:   "instance",
+				DiskUUID:   uuid,
+			}, tc.revocations.node)
+			if tc.revocations.checkErr == nil {
+				assert.Equal("instance", tc.diskOwners.owner)
+				assert.Equal(worker.Name, tc.diskOwners.nodeName)
+			}
 			if tc.wantErr {
 				assert.Error(err)
 				return
@@ -206,6 +562,92 @@ func TestIssueRejoinTicker(t *testing.T) {
 	}
 }
 
+func TestRenewKubeletCertificate(t *testing.T) {
+	someErr := errors.New("error")
+	testCert := []byte{0x1, 0x2, 0x3}
+	worker := metadata.InstanceMetadata{Name: "worker-0", ProviderID: "gce://project/zone/worker-0"}
+
+	testCases := map[string]struct {
+		isControlPlane bool
+		ca             stubCA
+		membership     stubMembership
+		revocations    stubRevocations
+		wantRole       role.Role
+		wantErr        bool
+	}{
+		"worker": {
+			ca:         stubCA{cert: testCert},
+			membership: stubMembership{instance: worker},
+			wantRole:   role.Worker,
+		},
+		"control plane": {
+			isControlPlane: true,
+			ca:             stubCA{cert: testCert},
+			membership:     stubMembership{instance: worker},
+			wantRole:       role.ControlPlane,
+		},
+		"not a member": {
+			ca:         stubCA{cert: testCert},
+			membership: stubMembership{verifyErr: membership.ErrNotMember},
+			wantRole:   role.Worker,
+			wantErr:    true,
+		},
+		"node revoked": {
+			ca:          stubCA{cert: testCert},
+			membership:  stubMembership{instance: worker},
+			revocations: stubRevocations{checkErr: revocation.ErrRevoked},
+			wantRole:    role.Worker,
+			wantErr:     true,
+		},
+		"signing fails": {
+			ca:         stubCA{getCertErr: someErr},
+			membership: stubMembership{instance: worker},
+			wantRole:   role.Worker,
+			wantErr:    true,
+		},
+	}
+
+	for name, tc := range testCases {
+		t.Run(name, func(t *testing.T) {
+			assert := assert.New(t)
+			require := require.New(t)
+
+			joinRecords := &stubJoinRecorder{}
+			api := New(
+				nil,
+				file.Handler{},
+				tc.ca,
+				stubTokenGetter{},
+				stubKeyGetter{},
+				&stubDiskOwners{},
+				&tc.membership,
+				&tc.revocations,
+				joinRecords,
+				&stubHandshakeRecorder{},
+				logger.NewTest(t),
+			)
+
+			ctx := peer.NewContext(context.Background(), &peer.Peer{Addr: &net.TCPAddr{}, AuthInfo: atlscredentials.AuthInfo{
+				PeerClaims: &atls.PeerClaims{Claims: policy.Claims{InstanceID: "instance"}},
+			}})
+			resp, err := api.RenewKubeletCertificate(ctx, &joinproto.RenewKubeletCertificateRequest{IsControlPlane: tc.isControlPlane})
+			require.Len(joinRecords.records, 1)
+			assert.Equal("RenewKubeletCertificate", joinRecords.records[0].Request)
+			assert.Equal("instance", joinRecords.records[0].InstanceID)
+			assert.Equal("instance", tc.membership.instanceID)
+			assert.Equal(tc.wantRole, tc.membership.role)
+			if tc.wantErr {
+				assert.Error(err)
+				return
+			}
+
+			require.NoError(err)
+			assert.Equal(revocation.Node{Name: worker.Name, ProviderID: worker.ProviderID, Identity: "instance"}, tc.revocations.node)
+			assert.Equal(testCert, resp.KubeletCert)
+		})
+	}
+}
+
 type stubTokenGetter struct {
 	token             *kubeadmv1.BootstrapTokenDiscovery
 	getJoinTokenErr   error
@@ -238,3 +680,82 @@ type stubCA struct {
 func (f stubCA) GetCertificate(csr []byte) ([]byte, error) {
 	return f.cert, f.getCertErr
 }
+
+type stubDiskOwners struct {
+	owner    string
+	nodeName string
+	bindErr  error
+}
+
+func (s *stubDiskOwners) Bind(_ context.Context, _, owner, nodeName string) error {
+	s.owner = owner
+	s.nodeName = nodeName
+	return s.bindErr
+}
+
+type stubMembership struct {
+	instanceID string
+	role       role.Role
+	instance   metadata.InstanceMetadata
+	verifyErr  error
+	lookupErr  error
+}
+
+func (s *stubMembership) Verify(_ context.Context, instanceID string, _ []byte, nodeRole role.Role) (metadata.InstanceMetadata, error) {
+	s.instanceID = instanceID
+	s.role = nodeRole
+	return s.instance, s.verifyErr
+}
+
+func (s *stubMembership) Lookup(_ context.Context, instanceID string) (metadata.InstanceMetadata, error) {
+	s.instanceID = instanceID
+	return s.instance, s.lookupErr
+}
+
+type stubRevocations struct {
+	node     revocation.Node
+	checkErr error
+}
+
+func (s *stubRevocations) Check(_ context.Context, node revocation.Node) error {
+	s.node = node
+	return s.checkErr
+}
+
+type stubJoinRecorder struct {
+	records []joinrecord.JoinRecordSpec
+	ctxErrs []error
+}
+
+func (s *stubJoinRecorder) Record(ctx context.Context, record joinrecord.JoinRecordSpec) {
+	s.records = append(s.records, record)
+	s.ctxErrs = append(s.ctxErrs, ctx.Err())
+}
+
+type stubHandshakeRecorder struct {
+	records []joinrecord.JoinRecordSpec
+}
+
+func (s *stubHandshakeRecorder) Add(record joinrecord.JoinRecordSpec) {
+	s.records = append(s.records, record)
+}
+
+type stubTransportCredentials struct {
+	credentials.TransportCredentials
+	handshakeErr error
+}
+
+func (c stubTransportCredentials) ServerHandshake(rawConn net.Conn) (net.Conn, credentials.AuthInfo, error) {
+	if c.handshakeErr != nil {
+		return nil, nil, c.handshakeErr
+	}
+	return rawConn, nil, nil
+}
+
+type stubConn struct {
+	net.Conn
+}
+
+func (stubConn) RemoteAddr() net.Addr {
+	return &net.TCPAddr{IP: net.IPv4(192, 0, 2, 1), Port: 1234}
+}
@@ -0,0 +1,98 @@
+/*
+Copyright (c) Edgeless Systems GmbH
+
+SPDX-License-Identifier: AGPL-3.0-only
+*/
+
+package verifier
+
+import (
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"sync"
+	"time"
+)
+
+const (
+	// nonceValidity is the time a client has to redeem a nonce.
+	nonceValidity = 2 * time.Minute
+	// nonceRandomLength is the number of random bytes of a nonce.
+	nonceRandomLength = 16
+	// nonceLength is the length of a nonce: timestamp, random bytes and MAC.
+	nonceLength = 8 + nonceRandomLength + sha256.Size
+)
+
+// nonces issues and redeems attestation nonces.
+//
+// Nonces are authenticated using a key shared by all replicas of the verifier,
+// so a nonce issued by one replica can be redeemed at any other replica.
+// Each replica only accepts a nonce once.
+type nonces struct {
+	macKey []byte
+	now    func() time.Time
+
+	mux      sync.Mutex
+	redeemed map[string]time.Time
+}
+
+// newNonces creates a new nonce issuer using macKey to authenticate nonces.
+func newNonces(macKey []byte) *nonces {
+	return &nonces{
+		macKey:   macKey,
+		now:      time.Now,
+		redeemed: make(map[string]time.Time),
+	}
+}
+
+// issue returns a new nonce.
+func (n *nonces) issue() ([]byte, error) {
+	nonce := make([]byte, 8, nonceLength)
+	binary.BigEndian.PutUint64(nonce, uint64(n.now().Unix()))
+	random := make([]byte, nonceRandomLength)
+	if _, err := rand.Read(random); err != nil {
+		return nil, fmt.Errorf("generating nonce: %w", err)
+	}
+	nonce = append(nonce, random...)
+	return append(nonce, n.mac(nonce)...), nil
+}
+
+// redeem checks that nonce was issued by the verifier, is not expired, and was not redeemed before.
+func (n *nonces) redeem(nonce []byte) error {
+	if len(nonce) != nonceLength {
+		return errors.New("nonce was not issued by the verifier")
+	}
+	payload, mac := nonce[:nonceLength-sha256.Size], nonce[nonceLength-sha256.Size:]
+	if !hmac.Equal(mac, n.mac(payload)) {
+		return errors.New("nonce was not issued by the verifier")
+	}
+
+	now := n.now()
+	issued := time.Unix(int64(binary.BigEndian.Uint64(payload)), 0)
+	expiry := issued.Add(nonceValidity)
+	if now.Before(issued.Add(-time.Minute)) || now.After(expiry) {
+		return errors.New("nonce is expired")
+	}
+
+	n.mux.Lock()
+	defer n.mux.Unlock()
+	for key, keyExpiry := range n.redeemed {
+		if now.After(keyExpiry) {
+			delete(n.redeemed, key)
+		}
+	}
+	if _, ok := n.redeemed[string(nonce)]; ok {
+		return errors.New("nonce was already used")
+	}
+	n.redeemed[string(nonce)] = expiry
+	return nil
+}
+
+func (n *nonces) mac(payload []byte) []byte {
+	mac := hmac.New(sha256.New, n.macKey)
+	mac.Write(payload)
+	return mac.Sum(nil)
+}
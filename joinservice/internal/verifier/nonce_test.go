@@ -0,0 +1,102 @@
+/*
+Copyright (c) Edgeless Systems GmbH
+
+SPDX-License-Identifier: AGPL-3.0-only
+*/
+
+package verifier
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestRedeemNonce(t *testing.T) {
+	issueTime := time.Date(2022, 10, 1, 12, 0, 0, 0, time.UTC)
+
+	testCases := map[string]struct {
+		modify     func(nonce []byte) []byte
+		redeemTime time.Time
+		wantErr    bool
+	}{
+		"success": {
+			redeemTime: issueTime.Add(time.Minute),
+		},
+		"expired": {
+			redeemTime: issueTime.Add(nonceValidity + time.Second),
+			wantErr:    true,
+		},
+		"issued in the future": {
+			redeemTime: issueTime.Add(-2 * time.Minute),
+			wantErr:    true,
+		},
+		"modified nonce": {
+			modify: func(nonce []byte) []byte {
+				nonce[10] ^= 0xFF
+				return nonce
+			},
+			redeemTime: issueTime,
+			wantErr:    true,
+		},
+		"truncated nonce": {
+			modify: func(nonce []byte) []byte {
+				return nonce[:nonceLength-1]
+			},
+			redeemTime: issueTime,
+			wantErr:    true,
+		},
+	}
+
+	for name, tc := range testCases {
+		t.Run(name, func(t *testing.T) {
+			assert := assert.New(t)
+			require := require.New(t)
+
+			n := newNonces([]byte("key"))
+			n.now = func() time.Time { return issueTime }
+			nonce, err := n.issue()
+			require.NoError(err)
+			if tc.modify != nil {
+				nonce = tc.modify(nonce)
+			}
+
+			n.now = func() time.Time { return tc.redeemTime }
+			err = n.redeem(nonce)
+			if tc.wantErr {
+				assert.Error(err)
+				return
+			}
+			require.NoError(err)
+			assert.Error(n.redeem(nonce), "nonce must only be accepted once")
+
+			// nonces issued with a different key are rejected
+			other := newNonces([]byte("other key"))
+			other.now = n.now
+			otherNonce, err := other.issue()
+			require.NoError(err)
+			assert.Error(n.redeem(otherNonce))
+		})
+	}
+}
+
+func TestRedeemedNoncesExpire(t *testing.T) {
+	assert := assert.New(t)
+	require := require.New(t)
+
+	now := time.Date(2022, 10, 1, 12, 0, 0, 0, time.UTC)
+	n := newNonces([]byte("key"))
+	n.now = func() time.Time { return now }
+
+	nonce, err := n.issue()
+	require.NoError(err)
+	require.NoError(n.redeem(nonce))
+
+	now = now.Add(nonceValidity + time.Second)
+	other, err := n.issue()
+	require.NoError(err)
+	require.NoError(n.redeem(other))
+	assert.Len(n.redeemed, 1)
+}
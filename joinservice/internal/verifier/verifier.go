@@ -0,0 +1,240 @@
+/*
+Copyright (c) Edgeless Systems GmbH
+
+SPDX-License-Identifier: AGPL-3.0-only
+*/
+
+/*
+Package verifier implements an in-cluster attestation verifier.
+
+The verifier appraises attestation statements of Constellation nodes using the join service's validator,
+and issues signed attestation result tokens (see package eat) for successfully verified nodes.
+Its public key is published as JSON Web Key Set, so relying parties can verify tokens without
+depending on Constellation's attestation formats.
+
+Attestation statements must be bound to a nonce previously issued by the verifier,
+and tokens are issued for the identity established by the attestation.
+Nonces are only tracked in memory, so each replica of the verifier accepts a nonce once:
+a nonce can be redeemed at most once per replica within its short validity.
+Relying parties requiring strict single use must track the nonces of accepted tokens themselves.
+The signing key is generated during cluster initialization and is independent of Constellation's KMS.
+*/
+package verifier
+
+import (
+	"crypto/ecdsa"
+	"crypto/sha256"
+	"encoding/json"
+	"fmt"
+	"net"
+	"net/http"
+	"path/filepath"
+	"time"
+
+	"github.com/edgelesssys/constellation/v2/internal/attestation/eat"
+	"github.com/edgelesssys/constellation/v2/internal/attestation/policy"
+	"github.com/edgelesssys/constellation/v2/internal/constants"
+	"github.com/edgelesssys/constellation/v2/internal/crypto"
+	"github.com/edgelesssys/constellation/v2/internal/file"
+	"github.com/edgelesssys/constellation/v2/internal/logger"
+	"go.uber.org/zap"
+)
+
+// nonceKeyInfo is the HKDF info used to derive the key authenticating nonces from the signing key.
+const nonceKeyInfo = "attestation-verifier-nonce"
+
+const (
+	// NoncePath is the HTTP path to request nonces for attestation statements from.
+	NoncePath = "/nonce"
+	// TokenPath is the HTTP path to request attestation result tokens from.
+	TokenPath = "/attestation-token"
+	// KeySetPath is the HTTP path serving the verifier's public key as JSON Web Key Set.
+	KeySetPath = "/.well-known/jwks.json"
+	// maxRequestSize limits the size of attestation requests.
+	maxRequestSize = 1 << 20
+)
+
+// Issuer is the issuer of attestation result tokens signed by the in-cluster verifier.
+const Issuer = "constellation-join-service"
+
+// NonceResponse is the response body of NoncePath.
+type NonceResponse struct {
+	// Nonce must be used to request the attestation statement of a node.
+	// It expires after a short time and can only be used once per replica of the verifier.
+	Nonce []byte `json:"nonce"`
+}
+
+// TokenRequest is the request body of TokenPath.
+type TokenRequest struct {
+	// Attestation is the attestation statement issued by the node.
+	Attestation []byte `json:"attestation"`
+	// Nonce is the nonce the attestation statement was requested with.
+	// It must have been issued by the verifier.
+	Nonce []byte `json:"nonce"`
+}
+
+// TokenResponse is the response body of TokenPath.
+type TokenResponse struct {
+	// Token is the signed attestation result token.
+	Token string `json:"token"`
+	// UserData is the user data signed by the attestation statement.
+	// Its SHA-256 digest is included in the token.
+	UserData []byte `json:"userData"`
+}
+
+// Server issues attestation result tokens for attested nodes.
+type Server struct {
+	provider  string
+	validator validator
+	signer    *eat.Signer
+	nonces    *nonces
+	log       *logger.Logger
+}
+
+// New initializes a new Server issuing tokens signed with key.
+func New(provider string, validator validator, key *ecdsa.PrivateKey, validity time.Duration, log *logger.Logger) (*Server, error) {
+	signer, err := eat.NewSigner(Issuer, key, validity)
+	if err != nil {
+		return nil, err
+	}
+	// all replicas share the signing key, so it is used to derive the key authenticating nonces
+	macKey, err := crypto.DeriveKey(key.D.Bytes(), nil, []byte(nonceKeyInfo), sha256.Size)
+	if err != nil {
+		return nil, fmt.Errorf("deriving nonce key: %w", err)
+	}
+	return &Server{
+		provider:  provider,
+		validator: validator,
+		signer:    signer,
+		nonces:    newNonces(macKey),
+		log:       log,
+	}, nil
+}
+
+// ReadSigningKey reads the signing key of the verifier from the mounted Secret.
+func ReadSigningKey(fileHandler file.Handler) (*ecdsa.PrivateKey, error) {
+	raw, err := fileHandler.Read(filepath.Join(constants.AttestationTokenKeyPath, constants.AttestationTokenKeyFilename))
+	if err != nil {
+		return nil, fmt.Errorf("reading signing key: %w", err)
+	}
+	return eat.ParseSigningKey(raw)
+}
+
+// Run starts the HTTP server on the given listener.
+func (s *Server) Run(lis net.Listener) error {
+	server := &http.Server{
+		Handler:           s.Handler(),
+		ReadHeaderTimeout: 10 * time.Second,
+	}
+	s.log.Infof("Starting attestation verifier on %s", lis.Addr().String())
+	return server.Serve(lis)
+}
+
+// Handler returns the HTTP handler of the verifier.
+func (s *Server) Handler() http.Handler {
+	mux := http.NewServeMux()
+	mux.HandleFunc(NoncePath, s.issueNonce)
+	mux.HandleFunc(TokenPath, s.issueToken)
+	mux.HandleFunc(KeySetPath, s.keySet)
+	return mux
+}
+
+// issueNonce returns a fresh nonce to request an attestation statement with.
+func (s *Server) issueNonce(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		http.Error(w, "only POST is supported", http.StatusMethodNotAllowed)
+		return
+	}
+	nonce, err := s.nonces.issue()
+	if err != nil {
+		s.log.With(zap.Error(err)).Errorf("Failed to issue nonce")
+		http.Error(w, fmt.Sprintf("issuing nonce: %v", err), http.StatusInternalServerError)
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	if err := json.NewEncoder(w).Encode(NonceResponse{Nonce: nonce}); err != nil {
+		s.log.With(zap.Error(err)).Errorf("Failed to write response")
+	}
+}
+
+// issueToken validates an attestation statement and returns a signed attestation result token.
+func (s *Server) issueToken(w http.ResponseWriter, r *http.Request) {
+	log := s.log.With(zap.String("peerAddress", r.RemoteAddr))
+
+	if r.Method != http.MethodPost {
+		http.Error(w, "only POST is supported", http.StatusMethodNotAllowed)
+		return
+	}
+
+	var req TokenRequest
+	if err := json.NewDecoder(http.MaxBytesReader(w, r.Body, maxRequestSize)).Decode(&req); err != nil {
+		log.With(zap.Error(err)).Errorf("Received invalid token request")
+		http.Error(w, fmt.Sprintf("decoding request: %v", err), http.StatusBadRequest)
+		return
+	}
+	if len(req.Nonce) == 0 {
+		http.Error(w, "nonce is required", http.StatusBadRequest)
+		return
+	}
+	if err := s.nonces.redeem(req.Nonce); err != nil {
+		log.With(zap.Error(err)).Warnf("Received token request with invalid nonce")
+		http.Error(w, fmt.Sprintf("invalid nonce: %v", err), http.StatusForbidden)
+		return
+	}
+
+	log.Infof("Validating attestation statement")
+	// the policy is returned by the validation, so the token reports the policy that was enforced
+	// even if the policy is updated concurrently
+	userData, claims, enforcedPolicy, err := s.validator.ValidateWithPolicy(req.Attestation, req.Nonce)
+	if err != nil {
+		log.With(zap.Error(err)).Warnf("Attestation statement is not valid")
+		http.Error(w, fmt.Sprintf("validating attestation: %v", err), http.StatusForbidden)
+		return
+	}
+	identity := claims.Identity()
+	if identity == "" {
+		log.Warnf("Attestation statement does not identify the node")
+		http.Error(w, "attestation does not identify the node", http.StatusForbidden)
+		return
+	}
+	log = log.With(zap.String("identity", identity))
+
+	token, err := s.signer.Sign(eat.Appraisal{
+		Provider: s.provider,
+		Variant:  s.validator.OID(),
+		Nonce:    req.Nonce,
+		UserData: userData,
+		Claims:   claims,
+		Policy:   enforcedPolicy,
+	})
+	if err != nil {
+		log.With(zap.Error(err)).Errorf("Failed to sign attestation result token")
+		http.Error(w, fmt.Sprintf("signing token: %v", err), http.StatusInternalServerError)
+		return
+	}
+
+	log.Infof("Issued attestation result token")
+	w.Header().Set("Content-Type", "application/json")
+	if err := json.NewEncoder(w).Encode(TokenResponse{Token: token, UserData: userData}); err != nil {
+		log.With(zap.Error(err)).Errorf("Failed to write response")
+	}
+}
+
+// keySet serves the public key of the verifier.
+func (s *Server) keySet(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		http.Error(w, "only GET is supported", http.StatusMethodNotAllowed)
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	if err := json.NewEncoder(w).Encode(s.signer.PublicKeySet()); err != nil {
+		s.log.With(zap.Error(err)).Errorf("Failed to write response")
+	}
+}
+
+type validator interface {
+	eat.Validator
+	// ValidateWithPolicy validates an attestation statement,
+	// and returns the source of the attestation policy enforced by the validation.
+	ValidateWithPolicy(attDoc []byte, nonce []byte) ([]byte, policy.Claims, string, error)
+}
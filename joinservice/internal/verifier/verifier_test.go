@@ -0,0 +1,274 @@
+/*
+Copyright (c) Edgeless Systems GmbH
+
+SPDX-License-Identifier: AGPL-3.0-only
+*/
+
+package verifier
+
+import (
+	"bytes"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/asn1"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"testing"
+
+	"github.com/edgelesssys/constellation/v2/internal/attestation/eat"
+	"github.com/edgelesssys/constellation/v2/internal/attestation/policy"
+	"github.com/edgelesssys/constellation/v2/internal/constants"
+	"github.com/edgelesssys/constellation/v2/internal/file"
+	"github.com/edgelesssys/constellation/v2/internal/logger"
+	"github.com/spf13/afero"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/goleak"
+	"gopkg.in/square/go-jose.v2"
+)
+
+func TestMain(m *testing.M) {
+	goleak.VerifyTestMain(m)
+}
+
+func TestIssueToken(t *testing.T) {
+	identityClaims := policy.Claims{InstanceID: "projects/p/zones/z/instances/i"}
+
+	testCases := map[string]struct {
+		method     string
+		request    func(nonce []byte) []byte
+		validator  *stubValidator
+		wantStatus int
+	}{
+		"success": {
+			method: http.MethodPost,
+			request: func(nonce []byte) []byte {
+				return mustMarshal(t, TokenRequest{Attestation: []byte("attestation"), Nonce: nonce})
+			},
+			validator:  &stubValidator{userData: []byte("user data"), claims: identityClaims, policy: "true"},
+			wantStatus: http.StatusOK,
+		},
+		"invalid attestation": {
+			method: http.MethodPost,
+			request: func(nonce []byte) []byte {
+				return mustMarshal(t, TokenRequest{Attestation: []byte("attestation"), Nonce: nonce})
+			},
+			validator:  &stubValidator{validateErr: errors.New("failed")},
+			wantStatus: http.StatusForbidden,
+		},
+		"attestation without identity": {
+			method: http.MethodPost,
+			request: func(nonce []byte) []byte {
+				return mustMarshal(t, TokenRequest{Attestation: []byte("attestation"), Nonce: nonce})
+			},
+			validator:  &stubValidator{userData: []byte("user data")},
+			wantStatus: http.StatusForbidden,
+		},
+		"missing nonce": {
+			method: http.MethodPost,
+			request: func([]byte) []byte {
+				return mustMarshal(t, TokenRequest{Attestation: []byte("attestation")})
+			},
+			validator:  &stubValidator{claims: identityClaims},
+			wantStatus: http.StatusBadRequest,
+		},
+		"nonce not issued by verifier": {
+			method: http.MethodPost,
+			request: func([]byte) []byte {
+				return mustMarshal(t, TokenRequest{Attestation: []byte("attestation"), Nonce: []byte("nonce")})
+			},
+			validator:  &stubValidator{claims: identityClaims},
+			wantStatus: http.StatusForbidden,
+		},
+		"invalid body": {
+			method:     http.MethodPost,
+			request:    func([]byte) []byte { return []byte("invalid") },
+			validator:  &stubValidator{},
+			wantStatus: http.StatusBadRequest,
+		},
+		"wrong method": {
+			method:     http.MethodGet,
+			request:    func([]byte) []byte { return nil },
+			validator:  &stubValidator{},
+			wantStatus: http.StatusMethodNotAllowed,
+		},
+	}
+
+	for name, tc := range testCases {
+		t.Run(name, func(t *testing.T) {
+			assert := assert.New(t)
+			require := require.New(t)
+
+			key := newTestKey(t)
+			server, err := New("gcp", tc.validator, key, eat.DefaultValidity, logger.NewTest(t))
+			require.NoError(err)
+			nonce := requestNonce(t, server)
+
+			req := httptest.NewRequest(tc.method, TokenPath, bytes.NewReader(tc.request(nonce)))
+			resp := httptest.NewRecorder()
+			server.Handler().ServeHTTP(resp, req)
+
+			assert.Equal(tc.wantStatus, resp.Code)
+			if tc.wantStatus != http.StatusOK {
+				return
+			}
+
+			var tokenResp TokenResponse
+			require.NoError(json.NewDecoder(resp.Body).Decode(&tokenResp))
+			assert.Equal(tc.validator.userData, tokenResp.UserData)
+
+			claims, err := eat.Verify(tokenResp.Token, &key.PublicKey, Issuer, nonce)
+			require.NoError(err)
+			assert.Equal("projects/p/zones/z/instances/i", claims.Subject)
+			assert.Equal("gcp", claims.Provider)
+			assert.Equal("1.3.9900.1", claims.Variant)
+			policyDigest := sha256.Sum256([]byte(tc.validator.policy))
+			assert.Equal(hex.EncodeToString(policyDigest[:]), claims.PolicyDigest)
+			userDataDigest := sha256.Sum256(tc.validator.userData)
+			assert.Equal(hex.EncodeToString(userDataDigest[:]), claims.UserDataDigest)
+
+			// nonces can only be used once
+			req = httptest.NewRequest(tc.method, TokenPath, bytes.NewReader(tc.request(nonce)))
+			resp = httptest.NewRecorder()
+			server.Handler().ServeHTTP(resp, req)
+			assert.Equal(http.StatusForbidden, resp.Code)
+		})
+	}
+}
+
+func TestIssueNonce(t *testing.T) {
+	assert := assert.New(t)
+	require := require.New(t)
+
+	key := newTestKey(t)
+	server, err := New("gcp", &stubValidator{}, key, eat.DefaultValidity, logger.NewTest(t))
+	require.NoError(err)
+	otherReplica, err := New("gcp", &stubValidator{}, key, eat.DefaultValidity, logger.NewTest(t))
+	require.NoError(err)
+
+	nonce := requestNonce(t, server)
+	assert.NotEqual(nonce, requestNonce(t, server))
+	// replicas share the signing key, so they accept nonces issued by each other
+	assert.NoError(otherReplica.nonces.redeem(nonce))
+
+	req := httptest.NewRequest(http.MethodGet, NoncePath, http.NoBody)
+	resp := httptest.NewRecorder()
+	server.Handler().ServeHTTP(resp, req)
+	assert.Equal(http.StatusMethodNotAllowed, resp.Code)
+}
+
+func TestKeySet(t *testing.T) {
+	assert := assert.New(t)
+	require := require.New(t)
+
+	key := newTestKey(t)
+	server, err := New("gcp", &stubValidator{}, key, eat.DefaultValidity, logger.NewTest(t))
+	require.NoError(err)
+
+	req := httptest.NewRequest(http.MethodGet, KeySetPath, http.NoBody)
+	resp := httptest.NewRecorder()
+	server.Handler().ServeHTTP(resp, req)
+	require.Equal(http.StatusOK, resp.Code)
+
+	var keySet jose.JSONWebKeySet
+	require.NoError(json.NewDecoder(resp.Body).Decode(&keySet))
+	require.Len(keySet.Keys, 1)
+	keyID, err := eat.KeyID(&key.PublicKey)
+	require.NoError(err)
+	assert.Equal(keyID, keySet.Keys[0].KeyID)
+}
+
+func TestReadSigningKey(t *testing.T) {
+	testCases := map[string]struct {
+		key     []byte
+		wantErr bool
+	}{
+		"success": {
+			key: func() []byte {
+				key, err := eat.GenerateSigningKey()
+				require.NoError(t, err)
+				return key
+			}(),
+		},
+		"no key": {
+			wantErr: true,
+		},
+		"invalid key": {
+			key:     []byte("invalid"),
+			wantErr: true,
+		},
+	}
+
+	for name, tc := range testCases {
+		t.Run(name, func(t *testing.T) {
+			assert := assert.New(t)
+			require := require.New(t)
+
+			handler := file.NewHandler(afero.NewMemMapFs())
+			if tc.key != nil {
+				require.NoError(handler.Write(filepath.Join(constants.AttestationTokenKeyPath, constants.AttestationTokenKeyFilename), tc.key))
+			}
+
+			_, err := ReadSigningKey(handler)
+			if tc.wantErr {
+				assert.Error(err)
+			} else {
+				assert.NoError(err)
+			}
+		})
+	}
+}
+
+func newTestKey(t *testing.T) *ecdsa.PrivateKey {
+	t.Helper()
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	require.NoError(t, err)
+	return key
+}
+
+func requestNonce(t *testing.T, server *Server) []byte {
+	t.Helper()
+	req := httptest.NewRequest(http.MethodPost, NoncePath, http.NoBody)
+	resp := httptest.NewRecorder()
+	server.Handler().ServeHTTP(resp, req)
+	require.Equal(t, http.StatusOK, resp.Code)
+	var nonceResp NonceResponse
+	require.NoError(t, json.NewDecoder(resp.Body).Decode(&nonceResp))
+	return nonceResp.Nonce
+}
+
+func mustMarshal(t *testing.T, v interface{}) []byte {
+	t.Helper()
+	out, err := json.Marshal(v)
+	require.NoError(t, err)
+	return out
+}
+
+type stubValidator struct {
+	userData    []byte
+	claims      policy.Claims
+	policy      string
+	validateErr error
+}
+
+func (v *stubValidator) Validate(attDoc []byte, nonce []byte) ([]byte, error) {
+	return v.userData, v.validateErr
+}
+
+func (v *stubValidator) ValidateWithClaims(attDoc []byte, nonce []byte) ([]byte, policy.Claims, error) {
+	return v.userData, v.claims, v.validateErr
+}
+
+func (v *stubValidator) OID() asn1.ObjectIdentifier {
+	return asn1.ObjectIdentifier{1, 3, 9900, 1}
+}
+
+func (v *stubValidator) ValidateWithPolicy(attDoc []byte, nonce []byte) ([]byte, policy.Claims, string, error) {
+	return v.userData, v.claims, v.policy, v.validateErr
+}
@@ -24,6 +24,7 @@ const _ = grpc.SupportPackageIsVersion7
 type APIClient interface {
 	IssueJoinTicket(ctx context.Context, in *IssueJoinTicketRequest, opts ...grpc.CallOption) (*IssueJoinTicketResponse, error)
 	IssueRejoinTicket(ctx context.Context, in *IssueRejoinTicketRequest, opts ...grpc.CallOption) (*IssueRejoinTicketResponse, error)
+	RenewKubeletCertificate(ctx context.Context, in *RenewKubeletCertificateRequest, opts ...grpc.CallOption) (*RenewKubeletCertificateResponse, error)
 }
 
 type aPIClient struct {
@@ -52,12 +53,22 @@ func (c *aPIClient) IssueRejoinTicket(ctx context.Context, in *IssueRejoinTicket
 	return out, nil
 }
 
+func (c *aPIClient) RenewKubeletCertificate(ctx context.Context, in *RenewKubeletCertificateRequest, opts ...grpc.CallOption) (*RenewKubeletCertificateResponse, error) {
+	out := new(RenewKubeletCertificateResponse)
+	err := c.cc.Invoke(ctx, "/join.API/RenewKubeletCertificate", in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
 // APIServer is the server API for API service.
 // All implementations must embed UnimplementedAPIServer
 // for forward compatibility
 type APIServer interface {
 	IssueJoinTicket(context.Context, *IssueJoinTicketRequest) (*IssueJoinTicketResponse, error)
 	IssueRejoinTicket(context.Context, *IssueRejoinTicketRequest) (*IssueRejoinTicketResponse, error)
+	RenewKubeletCertificate(context.Context, *RenewKubeletCertificateRequest) (*RenewKubeletCertificateResponse, error)
 	mustEmbedUnimplementedAPIServer()
 }
 
@@ -71,6 +82,9 @@ func (UnimplementedAPIServer) IssueJoinTicket(context.Context, *IssueJoinTicketR
 func (UnimplementedAPIServer) IssueRejoinTicket(context.Context, *IssueRejoinTicketRequest) (*IssueRejoinTicketResponse, error) {
 	return nil, status.Errorf(codes.Unimplemented, "method IssueRejoinTicket not implemented")
 }
+func (UnimplementedAPIServer) RenewKubeletCertificate(context.Context, *RenewKubeletCertificateRequest) (*RenewKubeletCertificateResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method RenewKubeletCertificate not implemented")
+}
 func (UnimplementedAPIServer) mustEmbedUnimplementedAPIServer() {}
 
 // UnsafeAPIServer may be embedded to opt out of forward compatibility for this service.
@@ -120,6 +134,24 @@ func _API_IssueRejoinTicket_Handler(srv interface{}, ctx context.Context, dec fu
 	return interceptor(ctx, in, info, handler)
 }
 
+func _API_RenewKubeletCertificate_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(RenewKubeletCertificateRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(APIServer).RenewKubeletCertificate(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: "/join.API/RenewKubeletCertificate",
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(APIServer).RenewKubeletCertificate(ctx, req.(*RenewKubeletCertificateRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
 // API_ServiceDesc is the grpc.ServiceDesc for API service.
 // It's only intended for direct use with grpc.RegisterService,
 // and not to be introspected or modified (even as a copy)
@@ -135,6 +167,10 @@ var API_ServiceDesc = grpc.ServiceDesc{
 			MethodName: "IssueRejoinTicket",
 			Handler:    _API_IssueRejoinTicket_Handler,
 		},
+		{
+			MethodName: "RenewKubeletCertificate",
+			Handler:    _API_RenewKubeletCertificate_Handler,
+		},
 	},
 	Streams:  []grpc.StreamDesc{},
 	Metadata: "join.proto",
@@ -0,0 +1,120 @@
+/*
+Copyright (c) Edgeless Systems GmbH
+
+SPDX-License-Identifier: AGPL-3.0-only
+*/
+
+package v1alpha1
+
+import (
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+const (
+	// ObjectStoreS3 stores snapshots in an S3-compatible bucket.
+	// The credentials secret must contain the keys "accessKeyID" and "secretAccessKey".
+	ObjectStoreS3 ObjectStoreType = "S3"
+	// ObjectStoreAzureBlob stores snapshots in an Azure Blob Storage container.
+	// The credentials secret must contain the key "connectionString".
+	ObjectStoreAzureBlob ObjectStoreType = "AzureBlob"
+	// ObjectStoreGCS stores snapshots in a Google Cloud Storage bucket.
+	// The credentials secret must contain the key "serviceAccountKey".
+	ObjectStoreGCS ObjectStoreType = "GCS"
+
+	// ConditionBackupSucceeded is true if the last scheduled etcd backup succeeded.
+	ConditionBackupSucceeded = "BackupSucceeded"
+)
+
+// ObjectStoreType is the type of object store used to store etcd snapshots.
+// Only one of the following types may be specified.
+// +kubebuilder:validation:Enum=S3;AzureBlob;GCS
+type ObjectStoreType string
+
+// EtcdBackupSpec defines the desired state of EtcdBackup.
+type EtcdBackupSpec struct {
+	// Schedule is a cron expression (minute, hour, day of month, month, day of week) in UTC defining when snapshots are taken.
+	Schedule string `json:"schedule"`
+	// Retention is the number of snapshots to keep in the object store.
+	// Older snapshots are deleted after a successful backup.
+	// +kubebuilder:validation:Minimum=1
+	// +kubebuilder:default=7
+	Retention int32 `json:"retention,omitempty"`
+	// Suspend stops scheduling new snapshots.
+	// +optional
+	Suspend bool `json:"suspend,omitempty"`
+	// Storage is the object store snapshots are uploaded to.
+	Storage ObjectStoreSpec `json:"storage"`
+}
+
+// ObjectStoreSpec describes the object store snapshots are uploaded to.
+type ObjectStoreSpec struct {
+	// Type is the type of the object store.
+	Type ObjectStoreType `json:"type"`
+	// Bucket is the name of the bucket (S3, GCS) or container (AzureBlob). It must already exist.
+	Bucket string `json:"bucket"`
+	// Prefix is prepended to the name of every snapshot.
+	// +optional
+	Prefix string `json:"prefix,omitempty"`
+	// Endpoint is the URL of an S3-compatible object store.
+	// If empty, the AWS S3 endpoint of the region is used.
+	// +optional
+	Endpoint string `json:"endpoint,omitempty"`
+	// Region is the region of the S3 bucket.
+	// +optional
+	Region string `json:"region,omitempty"`
+	// CredentialsSecret references the secret containing the credentials for the object store.
+	// The secret must be in the namespace of the node operator. If the namespace is empty, the namespace of the node operator is used.
+	CredentialsSecret corev1.SecretReference `json:"credentialsSecret"`
+}
+
+// EtcdSnapshot describes a snapshot stored in the object store.
+type EtcdSnapshot struct {
+	// Name is the name of the snapshot in the object store.
+	Name string `json:"name"`
+	// Time is the time the snapshot was taken.
+	Time metav1.Time `json:"time"`
+	// Size is the size of the encrypted snapshot in bytes.
+	Size int64 `json:"size"`
+}
+
+// EtcdBackupStatus defines the observed state of EtcdBackup.
+type EtcdBackupStatus struct {
+	// LastScheduleTime is the last time a backup was attempted.
+	// +optional
+	LastScheduleTime *metav1.Time `json:"lastScheduleTime,omitempty"`
+	// LastSuccessfulTime is the last time a backup succeeded.
+	// +optional
+	LastSuccessfulTime *metav1.Time `json:"lastSuccessfulTime,omitempty"`
+	// Snapshots are the snapshots currently kept in the object store, oldest first.
+	// +optional
+	Snapshots []EtcdSnapshot `json:"snapshots,omitempty"`
+	// Conditions represent the latest available observations of an object's state.
+	Conditions []metav1.Condition `json:"conditions"`
+}
+
+//+kubebuilder:object:root=true
+//+kubebuilder:subresource:status
+//+kubebuilder:resource:scope=Cluster
+
+// EtcdBackup is the Schema for the etcdbackups API.
+type EtcdBackup struct {
+	metav1.TypeMeta   `json:",inline"`
+	metav1.ObjectMeta `json:"metadata,omitempty"`
+
+	Spec   EtcdBackupSpec   `json:"spec,omitempty"`
+	Status EtcdBackupStatus `json:"status,omitempty"`
+}
+
+//+kubebuilder:object:root=true
+
+// EtcdBackupList contains a list of EtcdBackup.
+type EtcdBackupList struct {
+	metav1.TypeMeta `json:",inline"`
+	metav1.ListMeta `json:"metadata,omitempty"`
+	Items           []EtcdBackup `json:"items"`
+}
+
+func init() {
+	SchemeBuilder.Register(&EtcdBackup{}, &EtcdBackupList{})
+}
@@ -0,0 +1,193 @@
+/*
+Copyright (c) Edgeless Systems GmbH
+
+SPDX-License-Identifier: AGPL-3.0-only
+*/
+
+package v1alpha1
+
+import (
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+const (
+	// NodeDrainPhasePreDrain means the node is cordoned and the pre-drain hooks are running.
+	NodeDrainPhasePreDrain NodeDrainPhase = "PreDrain"
+	// NodeDrainPhaseEvicting means the pods of the node are being evicted.
+	NodeDrainPhaseEvicting NodeDrainPhase = "Evicting"
+	// NodeDrainPhasePostDrain means all pods are evicted and the post-drain hooks are running.
+	NodeDrainPhasePostDrain NodeDrainPhase = "PostDrain"
+	// NodeDrainPhaseSucceeded means the node is drained and can be removed.
+	NodeDrainPhaseSucceeded NodeDrainPhase = "Succeeded"
+	// NodeDrainPhaseFailed means the node could not be drained in time or a hook failed.
+	// The node stays cordoned until the NodeDrain is deleted or retried.
+	NodeDrainPhaseFailed NodeDrainPhase = "Failed"
+
+	// DrainFailurePolicyBlock keeps a node whose drain failed in the cluster until the NodeDrain is deleted.
+	DrainFailurePolicyBlock DrainFailurePolicy = "Block"
+	// DrainFailurePolicyRetry drains a node again once the retry interval passed after its drain failed.
+	DrainFailurePolicyRetry DrainFailurePolicy = "Retry"
+	// DrainFailurePolicyForce removes or upgrades a node even though its drain failed.
+	// Pods that were not evicted are terminated with the node.
+	DrainFailurePolicyForce DrainFailurePolicy = "Force"
+
+	// ConditionEvictionBlocked is used to signal that evictions are rejected, e.g. because of PodDisruptionBudgets.
+	ConditionEvictionBlocked = "EvictionBlocked"
+	// ConditionDrainFailed is used to signal why draining the node failed.
+	// On NodeImages, it lists the nodes that could not be drained for their replacement.
+	ConditionDrainFailed = "DrainFailed"
+)
+
+// NodeDrainPhase is the phase of draining a node.
+type NodeDrainPhase string
+
+// DrainFailurePolicy decides how to continue once draining a node failed.
+type DrainFailurePolicy string
+
+// NodeDrainSpec defines the desired state of NodeDrain.
+type NodeDrainSpec struct {
+	// NodeName is the name of the node to drain.
+	// +kubebuilder:validation:MinLength=1
+	NodeName string `json:"nodeName"`
+	// Reason is the reason the node is drained.
+	// +optional
+	Reason string `json:"reason,omitempty"`
+	// DrainConfig configures how the node is drained.
+	DrainConfig `json:",inline"`
+}
+
+// DrainConfig configures how nodes are drained before they are removed or upgraded.
+type DrainConfig struct {
+	// GracePeriodSeconds overrides the termination grace period of evicted pods.
+	// By default, the grace period of each pod is used.
+	// +kubebuilder:validation:Minimum=0
+	// +optional
+	GracePeriodSeconds *int64 `json:"gracePeriodSeconds,omitempty"`
+	// Timeout is the time limit for draining the node, including hooks.
+	// Once it is exceeded, draining fails and no further pods are evicted.
+	// By default, draining is retried until it succeeds.
+	// +optional
+	Timeout *metav1.Duration `json:"timeout,omitempty"`
+	// FailurePolicy decides how to continue once draining a node failed.
+	// "Block" keeps the node until the NodeDrain is deleted, "Retry" drains the node again after RetryInterval,
+	// and "Force" removes or upgrades the node regardless. Defaults to "Block".
+	// +kubebuilder:validation:Enum=Block;Retry;Force
+	// +optional
+	FailurePolicy DrainFailurePolicy `json:"failurePolicy,omitempty"`
+	// RetryInterval is the time to wait before a failed drain is retried if FailurePolicy is "Retry". Defaults to 5 minutes.
+	// +optional
+	RetryInterval *metav1.Duration `json:"retryInterval,omitempty"`
+	// PreDrainHooks are run one after another after cordoning the node and before evicting pods.
+	// +optional
+	PreDrainHooks []DrainHook `json:"preDrainHooks,omitempty"`
+	// PostDrainHooks are run one after another once all pods are evicted.
+	// +optional
+	PostDrainHooks []DrainHook `json:"postDrainHooks,omitempty"`
+}
+
+// DrainHook is an action run while draining a node. Exactly one of Webhook and Job has to be set.
+type DrainHook struct {
+	// Name identifies the hook.
+	// +kubebuilder:validation:Pattern=`^[a-z0-9]([-a-z0-9]*[a-z0-9])?$`
+	// +kubebuilder:validation:MaxLength=40
+	Name string `json:"name"`
+	// Webhook is called until it responds with a 2xx status code. Requests time out after 30 seconds.
+	// +optional
+	Webhook *WebhookDrainHook `json:"webhook,omitempty"`
+	// Job is run to completion. If the job fails, draining fails.
+	// +optional
+	Job *JobDrainHook `json:"job,omitempty"`
+}
+
+// WebhookDrainHook is an HTTP endpoint receiving a POST request with a JSON body
+// containing the fields "nodeName", "reason" and "phase".
+type WebhookDrainHook struct {
+	// URL is the endpoint of the webhook.
+	// +kubebuilder:validation:MinLength=1
+	URL string `json:"url"`
+}
+
+// JobDrainHook is a job run in the constellation-drain-hooks namespace, which enforces the restricted Pod Security Standard.
+// The environment variables NODE_NAME, DRAIN_REASON and DRAIN_PHASE are set for the container.
+type JobDrainHook struct {
+	// Image is the container image of the job.
+	// +kubebuilder:validation:MinLength=1
+	Image string `json:"image"`
+	// Command is the entrypoint of the container.
+	// +optional
+	Command []string `json:"command,omitempty"`
+	// Args are the arguments of the entrypoint.
+	// +optional
+	Args []string `json:"args,omitempty"`
+	// ServiceAccountName is the service account the job runs as. The service account has to exist in the
+	// constellation-drain-hooks namespace and carry the label update.edgeless.systems/drain-hook=allowed.
+	// By default, no service account token is mounted.
+	// +optional
+	ServiceAccountName string `json:"serviceAccountName,omitempty"`
+	// BackoffLimit is the number of retries before the job is considered failed. Defaults to 2.
+	// +kubebuilder:validation:Minimum=0
+	// +optional
+	BackoffLimit *int32 `json:"backoffLimit,omitempty"`
+}
+
+// BlockedEviction is a pod that cannot be evicted.
+type BlockedEviction struct {
+	// Namespace is the namespace of the pod.
+	Namespace string `json:"namespace"`
+	// Name is the name of the pod.
+	Name string `json:"name"`
+	// Message is the reason the eviction was rejected.
+	Message string `json:"message,omitempty"`
+}
+
+// NodeDrainStatus defines the observed state of NodeDrain.
+type NodeDrainStatus struct {
+	// Phase is the phase of draining the node.
+	Phase NodeDrainPhase `json:"phase,omitempty"`
+	// StartTime is the time draining the node started.
+	// +optional
+	StartTime *metav1.Time `json:"startTime,omitempty"`
+	// Retries is the number of times draining the node was retried after it failed.
+	// +optional
+	Retries int32 `json:"retries,omitempty"`
+	// CompletedHooks are the names of the hooks that completed, prefixed by their phase.
+	// +optional
+	CompletedHooks []string `json:"completedHooks,omitempty"`
+	// RemainingPods is the number of pods that still need to be evicted.
+	RemainingPods int32 `json:"remainingPods"`
+	// BlockedEvictions are pods whose eviction was rejected during the last attempt.
+	// +optional
+	BlockedEvictions []BlockedEviction `json:"blockedEvictions,omitempty"`
+	// Conditions represent the latest available observations of an object's state
+	Conditions []metav1.Condition `json:"conditions"`
+}
+
+//+kubebuilder:object:root=true
+//+kubebuilder:subresource:status
+//+kubebuilder:resource:scope=Cluster
+//+kubebuilder:printcolumn:name="Node",type=string,JSONPath=`.spec.nodeName`
+//+kubebuilder:printcolumn:name="Phase",type=string,JSONPath=`.status.phase`
+//+kubebuilder:printcolumn:name="Remaining",type=integer,JSONPath=`.status.remainingPods`
+
+// NodeDrain is the Schema for the nodedrains API.
+// A node is cordoned and drained while its NodeDrain exists and uncordoned once the NodeDrain is deleted.
+type NodeDrain struct {
+	metav1.TypeMeta   `json:",inline"`
+	metav1.ObjectMeta `json:"metadata,omitempty"`
+
+	Spec   NodeDrainSpec   `json:"spec,omitempty"`
+	Status NodeDrainStatus `json:"status,omitempty"`
+}
+
+//+kubebuilder:object:root=true
+
+// NodeDrainList contains a list of NodeDrain.
+type NodeDrainList struct {
+	metav1.TypeMeta `json:",inline"`
+	metav1.ListMeta `json:"metadata,omitempty"`
+	Items           []NodeDrain `json:"items"`
+}
+
+func init() {
+	SchemeBuilder.Register(&NodeDrain{}, &NodeDrainList{})
+}
@@ -0,0 +1,141 @@
+/*
+Copyright (c) Edgeless Systems GmbH
+
+SPDX-License-Identifier: AGPL-3.0-only
+*/
+
+package v1alpha1
+
+import (
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+const (
+	// ConditionRemediationAllowed is used to signal whether unhealthy nodes are remediated.
+	ConditionRemediationAllowed = "RemediationAllowed"
+)
+
+// NodeHealthCheckSpec defines the desired state of NodeHealthCheck.
+type NodeHealthCheckSpec struct {
+	// Selector selects the nodes that are checked. By default, all nodes are checked.
+	// +optional
+	Selector *metav1.LabelSelector `json:"selector,omitempty"`
+	// UnhealthyConditions are node conditions that mark a node as unhealthy once they persisted for their duration.
+	// By default, a node is unhealthy if its Ready condition is False or Unknown for 5 minutes.
+	// +optional
+	UnhealthyConditions []UnhealthyCondition `json:"unhealthyConditions,omitempty"`
+	// UnhealthyCSPStates are states of the node instance reported by the CSP that mark a node as unhealthy
+	// once they persisted for their duration.
+	// By default, a node is unhealthy if its instance is Failed or Stopped for 5 minutes.
+	// +optional
+	UnhealthyCSPStates []UnhealthyCSPState `json:"unhealthyCSPStates,omitempty"`
+	// MaxUnhealthy is the maximum number of unhealthy nodes that are remediated.
+	// If more nodes are unhealthy, remediation is paused, since the cause is likely not the nodes themselves.
+	// By default, remediation is paused if more than half of the checked nodes are unhealthy.
+	// +kubebuilder:validation:Minimum=0
+	// +optional
+	MaxUnhealthy *int32 `json:"maxUnhealthy,omitempty"`
+	// MaxConcurrentRemediations is the maximum number of nodes that are replaced at the same time.
+	// At most one control-plane node is replaced at a time.
+	// +kubebuilder:validation:Minimum=1
+	// +kubebuilder:default=1
+	// +optional
+	MaxConcurrentRemediations int32 `json:"maxConcurrentRemediations,omitempty"`
+	// Paused stops remediating unhealthy nodes.
+	// +optional
+	Paused bool `json:"paused,omitempty"`
+}
+
+// UnhealthyCondition is a node condition that marks a node as unhealthy.
+type UnhealthyCondition struct {
+	// Type is the type of the node condition.
+	// +kubebuilder:validation:MinLength=1
+	Type corev1.NodeConditionType `json:"type"`
+	// Status is the status of the node condition.
+	// +kubebuilder:validation:Enum=True;False;Unknown
+	Status corev1.ConditionStatus `json:"status"`
+	// Duration is the time the condition has to be present before the node is unhealthy.
+	Duration metav1.Duration `json:"duration"`
+}
+
+// UnhealthyCSPState is a state of the node instance that marks a node as unhealthy.
+type UnhealthyCSPState struct {
+	// State is the state of the node instance reported by the CSP.
+	State CSPNodeState `json:"state"`
+	// Duration is the time the state has to be observed before the node is unhealthy.
+	Duration metav1.Duration `json:"duration"`
+}
+
+// UnhealthyNode is a node that matches an unhealthy condition or CSP state.
+type UnhealthyNode struct {
+	// Name is the name of the node.
+	Name string `json:"name"`
+	// Reason is the condition or CSP state the node matches.
+	Reason string `json:"reason"`
+	// Since is the time the node started to match the condition or CSP state.
+	Since metav1.Time `json:"since"`
+}
+
+// Remediation is the replacement of an unhealthy node.
+type Remediation struct {
+	// NodeName is the name of the removed unhealthy node.
+	NodeName string `json:"nodeName"`
+	// ProviderID is the provider ID of the removed unhealthy node.
+	ProviderID string `json:"providerID,omitempty"`
+	// ScalingGroupID is the ID of the group that the replacement node is created in.
+	ScalingGroupID string `json:"groupID"`
+	// ControlPlane is true if the removed node was a control-plane node.
+	ControlPlane bool `json:"controlPlane,omitempty"`
+	// ReplacementNodeName is the name of the replacement node. Empty until the replacement node is created.
+	// +optional
+	ReplacementNodeName string `json:"replacementNodeName,omitempty"`
+	// StartTime is the time the remediation started.
+	StartTime metav1.Time `json:"startTime"`
+}
+
+// NodeHealthCheckStatus defines the observed state of NodeHealthCheck.
+type NodeHealthCheckStatus struct {
+	// ObservedNodes is the number of nodes selected by the health check.
+	ObservedNodes int32 `json:"observedNodes"`
+	// HealthyNodes is the number of selected nodes that are healthy.
+	HealthyNodes int32 `json:"healthyNodes"`
+	// UnhealthyNodes are the selected nodes that match an unhealthy condition or CSP state,
+	// including nodes that did not yet exceed the duration.
+	// +optional
+	UnhealthyNodes []UnhealthyNode `json:"unhealthyNodes,omitempty"`
+	// Remediations are the replacements of unhealthy nodes in progress.
+	// +optional
+	Remediations []Remediation `json:"remediations,omitempty"`
+	// Conditions represent the latest available observations of an object's state
+	Conditions []metav1.Condition `json:"conditions"`
+}
+
+//+kubebuilder:object:root=true
+//+kubebuilder:subresource:status
+//+kubebuilder:resource:scope=Cluster
+//+kubebuilder:printcolumn:name="Observed",type=integer,JSONPath=`.status.observedNodes`
+//+kubebuilder:printcolumn:name="Healthy",type=integer,JSONPath=`.status.healthyNodes`
+
+// NodeHealthCheck is the Schema for the nodehealthchecks API.
+// Nodes selected by a NodeHealthCheck are replaced once they are unhealthy.
+type NodeHealthCheck struct {
+	metav1.TypeMeta   `json:",inline"`
+	metav1.ObjectMeta `json:"metadata,omitempty"`
+
+	Spec   NodeHealthCheckSpec   `json:"spec,omitempty"`
+	Status NodeHealthCheckStatus `json:"status,omitempty"`
+}
+
+//+kubebuilder:object:root=true
+
+// NodeHealthCheckList contains a list of NodeHealthCheck.
+type NodeHealthCheckList struct {
+	metav1.TypeMeta `json:",inline"`
+	metav1.ListMeta `json:"metadata,omitempty"`
+	Items           []NodeHealthCheck `json:"items"`
+}
+
+func init() {
+	SchemeBuilder.Register(&NodeHealthCheck{}, &NodeHealthCheckList{})
+}
@@ -11,10 +11,119 @@ import (
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 )
 
+const (
+	// RolloutOrderNone replaces outdated nodes regardless of their role.
+	RolloutOrderNone RolloutOrder = ""
+	// RolloutOrderWorkersFirst replaces all worker nodes before replacing control plane nodes.
+	RolloutOrderWorkersFirst RolloutOrder = "WorkersFirst"
+	// RolloutOrderControlPlaneFirst replaces all control plane nodes before replacing worker nodes.
+	RolloutOrderControlPlaneFirst RolloutOrder = "ControlPlaneFirst"
+
+	// RolloutPhaseProgressing means outdated nodes are being replaced.
+	RolloutPhaseProgressing RolloutPhase = "Progressing"
+	// RolloutPhasePaused means no further outdated nodes are replaced until the rollout is resumed.
+	RolloutPhasePaused RolloutPhase = "Paused"
+	// RolloutPhaseCompleted means all nodes use the desired image.
+	RolloutPhaseCompleted RolloutPhase = "Completed"
+
+	// ConditionWaitingForMaintenanceWindow is used to signal that outdated nodes are not replaced until the next maintenance window opens.
+	ConditionWaitingForMaintenanceWindow = "WaitingForMaintenanceWindow"
+)
+
+// RolloutOrder defines which nodes are replaced first.
+type RolloutOrder string
+
+// RolloutPhase is the phase of a rollout.
+type RolloutPhase string
+
 // NodeImageSpec defines the desired state of NodeImage.
 type NodeImageSpec struct {
 	// ImageReference is the image to use for all nodes.
 	ImageReference string `json:"image,omitempty"`
+	// Strategy configures how outdated nodes are replaced.
+	// +optional
+	Strategy RolloutStrategy `json:"strategy,omitempty"`
+	// MaintenanceWindow restricts when replacements of outdated nodes are started.
+	// By default, outdated nodes are replaced at any time.
+	// +optional
+	MaintenanceWindow *MaintenanceWindow `json:"maintenanceWindow,omitempty"`
+	// Drain configures how outdated nodes are drained before they are removed.
+	// +optional
+	Drain *DrainConfig `json:"drain,omitempty"`
+}
+
+// MaintenanceWindow defines recurring time windows in which the replacement of outdated nodes is started.
+// Replacements that are already in progress when a window closes are finished.
+type MaintenanceWindow struct {
+	// Schedule is a cron expression (minute, hour, day of month, month, day of week) defining when a window opens.
+	// +kubebuilder:validation:MinLength=1
+	Schedule string `json:"schedule"`
+	// TimeZone is the IANA time zone the schedule is interpreted in. Defaults to UTC.
+	// +optional
+	TimeZone string `json:"timeZone,omitempty"`
+	// Duration is the maximum time after the window opens in which new replacements are started.
+	Duration metav1.Duration `json:"duration"`
+}
+
+// RolloutStrategy configures how outdated nodes are replaced by nodes using the desired image.
+type RolloutStrategy struct {
+	// MaxSurge is the maximum number of extra nodes created as replacements for outdated nodes at any point in time.
+	// Defaults to 1.
+	// +kubebuilder:validation:Minimum=1
+	// +optional
+	MaxSurge *int32 `json:"maxSurge,omitempty"`
+	// MaxUnavailable is the maximum number of outdated nodes being drained and removed at the same time.
+	// Defaults to 1.
+	// +kubebuilder:validation:Minimum=1
+	// +optional
+	MaxUnavailable *int32 `json:"maxUnavailable,omitempty"`
+	// Order defines whether worker nodes or control plane nodes are replaced first.
+	// By default, nodes are replaced regardless of their role.
+	// +kubebuilder:validation:Enum="";WorkersFirst;ControlPlaneFirst
+	// +optional
+	Order RolloutOrder `json:"order,omitempty"`
+	// Canary is the number of nodes replaced before the rollout is paused automatically.
+	// The rollout continues once Paused is set to false. 0 disables the canary phase.
+	// +kubebuilder:validation:Minimum=0
+	// +optional
+	Canary int32 `json:"canary,omitempty"`
+	// Paused stops the replacement of further outdated nodes.
+	// Nodes that are already being drained are still removed.
+	// +optional
+	Paused bool `json:"paused,omitempty"`
+	// HealthGate defines when a replacement node is considered healthy enough to remove the outdated node it replaces.
+	// +optional
+	HealthGate NodeHealthGate `json:"healthGate,omitempty"`
+	// NodeLeaveTimeout is the time a removed node has to leave the cluster after its termination was requested.
+	// Defaults to 1m.
+	// +optional
+	NodeLeaveTimeout *metav1.Duration `json:"nodeLeaveTimeout,omitempty"`
+}
+
+// NodeHealthGate defines the health requirements of replacement nodes.
+// A replacement node is always required to be ready.
+type NodeHealthGate struct {
+	// MinReadySeconds is the number of seconds a replacement node has to be ready.
+	// +kubebuilder:validation:Minimum=0
+	// +optional
+	MinReadySeconds int32 `json:"minReadySeconds,omitempty"`
+	// Conditions are additional node conditions that have to be true on a replacement node.
+	// +optional
+	Conditions []corev1.NodeConditionType `json:"conditions,omitempty"`
+}
+
+// RolloutStatus is the progress of replacing outdated nodes.
+type RolloutStatus struct {
+	// ImageReference is the image the progress refers to.
+	ImageReference string `json:"image,omitempty"`
+	// Phase is the phase of the rollout.
+	Phase RolloutPhase `json:"phase,omitempty"`
+	// Replaced is the number of nodes using the desired image.
+	Replaced int32 `json:"replaced"`
+	// Remaining is the number of nodes that still need to be replaced.
+	Remaining int32 `json:"remaining"`
+	// CanaryCompleted is set once the canary nodes of the rollout have been replaced.
+	CanaryCompleted bool `json:"canaryCompleted,omitempty"`
 }
 
 // NodeImageStatus defines the observed state of NodeImage.
@@ -37,6 +146,8 @@ type NodeImageStatus struct {
 	Invalid []corev1.ObjectReference `json:"invalid,omitempty"`
 	// Budget is the amount of extra nodes that can be created as replacements for outdated nodes.
 	Budget uint32 `json:"budget"`
+	// Rollout is the progress of replacing outdated nodes.
+	Rollout RolloutStatus `json:"rollout,omitempty"`
 	// Conditions represent the latest available observations of an object's state
 	Conditions []metav1.Condition `json:"conditions"`
 }
@@ -44,6 +155,10 @@ type NodeImageStatus struct {
 //+kubebuilder:object:root=true
 //+kubebuilder:subresource:status
 //+kubebuilder:resource:scope=Cluster
+//+kubebuilder:printcolumn:name="Image",type=string,JSONPath=`.spec.image`
+//+kubebuilder:printcolumn:name="Phase",type=string,JSONPath=`.status.rollout.phase`
+//+kubebuilder:printcolumn:name="Replaced",type=integer,JSONPath=`.status.rollout.replaced`
+//+kubebuilder:printcolumn:name="Remaining",type=integer,JSONPath=`.status.rollout.remaining`
 
 // NodeImage is the Schema for the nodeimages API.
 type NodeImage struct {
@@ -0,0 +1,104 @@
+/*
+Copyright (c) Edgeless Systems GmbH
+
+SPDX-License-Identifier: AGPL-3.0-only
+*/
+
+package v1alpha1
+
+import (
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+const (
+	// KubernetesUpgradePhaseControlPlane means control plane nodes are being upgraded.
+	KubernetesUpgradePhaseControlPlane KubernetesUpgradePhase = "UpgradingControlPlane"
+	// KubernetesUpgradePhaseWorkers means worker nodes are being upgraded.
+	KubernetesUpgradePhaseWorkers KubernetesUpgradePhase = "UpgradingWorkers"
+	// KubernetesUpgradePhaseCompleted means all nodes run the desired Kubernetes version.
+	KubernetesUpgradePhaseCompleted KubernetesUpgradePhase = "Completed"
+
+	// ConditionKubernetesUpgradeBlocked is used to signal that the upgrade to the desired Kubernetes version cannot progress.
+	ConditionKubernetesUpgradeBlocked = "UpgradeBlocked"
+)
+
+// KubernetesUpgradePhase is the phase of a Kubernetes version upgrade.
+type KubernetesUpgradePhase string
+
+// NodeKubernetesVersionSpec defines the desired state of NodeKubernetesVersion.
+type NodeKubernetesVersionSpec struct {
+	// Version is the Kubernetes version all nodes should run, e.g. "v1.24.3".
+	// +kubebuilder:validation:Pattern=`^v[0-9]+\.[0-9]+\.[0-9]+$`
+	Version string `json:"version"`
+	// Components are the Kubernetes binaries installed on every node when upgrading to Version.
+	// +optional
+	Components []KubernetesComponent `json:"components,omitempty"`
+	// MaxUnavailable is the maximum number of worker nodes being upgraded at the same time.
+	// Control plane nodes are always upgraded one after another. Defaults to 1.
+	// +kubebuilder:validation:Minimum=1
+	// +optional
+	MaxUnavailable *int32 `json:"maxUnavailable,omitempty"`
+	// Drain configures how nodes are drained before they are upgraded.
+	// +optional
+	Drain *DrainConfig `json:"drain,omitempty"`
+}
+
+// KubernetesComponent is a file downloaded and installed on the nodes.
+type KubernetesComponent struct {
+	// URL is the download location of the component.
+	// +kubebuilder:validation:MinLength=1
+	URL string `json:"url"`
+	// SHA256 is the hex encoded SHA256 checksum of the download. Components with a different checksum are not installed.
+	// +kubebuilder:validation:Pattern=`^[0-9a-f]{64}$`
+	SHA256 string `json:"sha256"`
+	// InstallPath is the path the component is installed to on the node.
+	// If Extract is set, the component is extracted into the directory at InstallPath.
+	// +kubebuilder:validation:MinLength=1
+	InstallPath string `json:"installPath"`
+	// Extract is set if the component is a gzip compressed tar archive.
+	// +optional
+	Extract bool `json:"extract,omitempty"`
+}
+
+// NodeKubernetesVersionStatus defines the observed state of NodeKubernetesVersion.
+type NodeKubernetesVersionStatus struct {
+	// Phase is the phase of the upgrade to the desired version.
+	Phase KubernetesUpgradePhase `json:"phase,omitempty"`
+	// Outdated is a list of nodes running an outdated Kubernetes version.
+	Outdated []corev1.ObjectReference `json:"outdated,omitempty"`
+	// UpToDate is a list of nodes running the desired Kubernetes version.
+	UpToDate []corev1.ObjectReference `json:"upToDate,omitempty"`
+	// Upgrading is a list of nodes that are being upgraded.
+	Upgrading []corev1.ObjectReference `json:"upgrading,omitempty"`
+	// Conditions represent the latest available observations of an object's state
+	Conditions []metav1.Condition `json:"conditions"`
+}
+
+//+kubebuilder:object:root=true
+//+kubebuilder:subresource:status
+//+kubebuilder:resource:scope=Cluster
+//+kubebuilder:printcolumn:name="Version",type=string,JSONPath=`.spec.version`
+//+kubebuilder:printcolumn:name="Phase",type=string,JSONPath=`.status.phase`
+
+// NodeKubernetesVersion is the Schema for the nodekubernetesversions API.
+type NodeKubernetesVersion struct {
+	metav1.TypeMeta   `json:",inline"`
+	metav1.ObjectMeta `json:"metadata,omitempty"`
+
+	Spec   NodeKubernetesVersionSpec   `json:"spec,omitempty"`
+	Status NodeKubernetesVersionStatus `json:"status,omitempty"`
+}
+
+//+kubebuilder:object:root=true
+
+// NodeKubernetesVersionList contains a list of NodeKubernetesVersion.
+type NodeKubernetesVersionList struct {
+	metav1.TypeMeta `json:",inline"`
+	metav1.ListMeta `json:"metadata,omitempty"`
+	Items           []NodeKubernetesVersion `json:"items"`
+}
+
+func init() {
+	SchemeBuilder.Register(&NodeKubernetesVersion{}, &NodeKubernetesVersionList{})
+}
@@ -7,6 +7,7 @@ SPDX-License-Identifier: AGPL-3.0-only
 package v1alpha1
 
 import (
+	corev1 "k8s.io/api/core/v1"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 )
 
@@ -36,6 +37,16 @@ type ScalingGroupSpec struct {
 	Max int32 `json:"max,omitempty"`
 	// Role is the role of the nodes in the scaling group.
 	Role NodeRole `json:"role,omitempty"`
+	// NodeTemplate defines labels and taints set on the nodes of the scaling group.
+	NodeTemplate NodeTemplate `json:"nodeTemplate,omitempty"`
+}
+
+// NodeTemplate defines labels and taints of the nodes in a scaling group.
+type NodeTemplate struct {
+	// Labels are set on every node of the scaling group.
+	Labels map[string]string `json:"labels,omitempty"`
+	// Taints are set on every node of the scaling group.
+	Taints []corev1.Taint `json:"taints,omitempty"`
 }
 
 // NodeRole is the role of a node.
@@ -0,0 +1,325 @@
+/*
+Copyright (c) Edgeless Systems GmbH
+
+SPDX-License-Identifier: AGPL-3.0-only
+*/
+
+package controllers
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/robfig/cron/v3"
+	"go.uber.org/multierr"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/meta"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/client-go/util/retry"
+	"k8s.io/utils/clock"
+	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/log"
+
+	updatev1alpha1 "github.com/edgelesssys/constellation/operators/constellation-node-operator/v2/api/v1alpha1"
+	"github.com/edgelesssys/constellation/operators/constellation-node-operator/v2/internal/constants"
+	"github.com/edgelesssys/constellation/operators/constellation-node-operator/v2/internal/objectstore"
+)
+
+const (
+	// etcdSnapshotPrefix is prepended to the name of every snapshot (after the user defined prefix).
+	etcdSnapshotPrefix = "etcd-snapshot-"
+	// etcdSnapshotSuffix marks snapshots as encrypted.
+	etcdSnapshotSuffix = ".enc"
+	// etcdSnapshotTimeFormat is the format of the timestamp in snapshot names. It sorts lexicographically.
+	etcdSnapshotTimeFormat = "20060102T150405Z"
+
+	conditionBackupSucceededReason       = "BackupSucceeded"
+	conditionBackupFailedReason          = "BackupFailed"
+	conditionBackupInvalidScheduleReason = "InvalidSchedule"
+)
+
+// EtcdBackupReconciler reconciles a EtcdBackup object.
+type EtcdBackupReconciler struct {
+	etcdSnapshotter
+	dataKeyGetter
+	newObjectStore objectStoreFactory
+	// secretReader reads the credentials secret without caching,
+	// so the operator doesn't need to list and watch secrets.
+	secretReader client.Reader
+	// namespace is the namespace of the operator. Only credentials secrets in this namespace are used.
+	namespace string
+	client.Client
+	Scheme *runtime.Scheme
+	clock.Clock
+}
+
+// NewEtcdBackupReconciler creates a new EtcdBackupReconciler.
+func NewEtcdBackupReconciler(snapshotter etcdSnapshotter, keyGetter dataKeyGetter, client client.Client, secretReader client.Reader, scheme *runtime.Scheme, namespace string) *EtcdBackupReconciler {
+	return &EtcdBackupReconciler{
+		etcdSnapshotter: snapshotter,
+		dataKeyGetter:   keyGetter,
+		newObjectStore:  newObjectStore,
+		secretReader:    secretReader,
+		namespace:       namespace,
+		Client:          client,
+		Scheme:          scheme,
+		Clock:           clock.RealClock{},
+	}
+}
+
+//+kubebuilder:rbac:groups=update.edgeless.systems,resources=etcdbackups,verbs=get;list;watch
+//+kubebuilder:rbac:groups=update.edgeless.systems,resources=etcdbackups/status,verbs=get;update;patch
+//+kubebuilder:rbac:groups="",namespace=system,resources=secrets,verbs=get
+
+// Reconcile takes an encrypted etcd snapshot whenever the schedule of an EtcdBackup is due.
+// Snapshots are encrypted with a key derived from the Constellation master secret and uploaded to the configured object store.
+// Snapshots exceeding the retention are deleted after a successful backup.
+func (r *EtcdBackupReconciler) Reconcile(ctx context.Context, req ctrl.Request) (ctrl.Result, error) {
+	logr := log.FromContext(ctx)
+
+	var desiredBackup updatev1alpha1.EtcdBackup
+	if err := r.Get(ctx, req.NamespacedName, &desiredBackup); err != nil {
+		logr.Error(err, "Unable to fetch EtcdBackup")
+		return ctrl.Result{}, client.IgnoreNotFound(err)
+	}
+
+	schedule, err := maintenanceWindowParser.Parse(desiredBackup.Spec.Schedule)
+	if err != nil {
+		logr.Error(err, "Invalid backup schedule", "schedule", desiredBackup.Spec.Schedule)
+		status := *desiredBackup.Status.DeepCopy()
+		meta.SetStatusCondition(&status.Conditions, metav1.Condition{
+			Type:    updatev1alpha1.ConditionBackupSucceeded,
+			Status:  metav1.ConditionFalse,
+			Reason:  conditionBackupInvalidScheduleReason,
+			Message: fmt.Sprintf("parsing schedule %q: %s", desiredBackup.Spec.Schedule, err),
+		})
+		return ctrl.Result{}, r.tryUpdateStatus(ctx, req.NamespacedName, status)
+	}
+	if desiredBackup.Spec.Suspend {
+		logr.Info("Backups are suspended")
+		return ctrl.Result{}, nil
+	}
+
+	now := r.Now()
+	if next := nextBackupTime(schedule, desiredBackup); now.Before(next) {
+		return ctrl.Result{RequeueAfter: next.Sub(now)}, nil
+	}
+
+	status := *desiredBackup.Status.DeepCopy()
+	status.LastScheduleTime = &metav1.Time{Time: now}
+	name := snapshotName(desiredBackup.Spec.Storage.Prefix, now)
+	snapshots, err := r.backup(ctx, desiredBackup.Spec, name)
+	if err != nil {
+		logr.Error(err, "Backup failed")
+		meta.SetStatusCondition(&status.Conditions, metav1.Condition{
+			Type:    updatev1alpha1.ConditionBackupSucceeded,
+			Status:  metav1.ConditionFalse,
+			Reason:  conditionBackupFailedReason,
+			Message: err.Error(),
+		})
+	} else {
+		logr.Info("Backup succeeded", "snapshot", name)
+		status.LastSuccessfulTime = &metav1.Time{Time: now}
+		status.Snapshots = snapshots
+		meta.SetStatusCondition(&status.Conditions, metav1.Condition{
+			Type:    updatev1alpha1.ConditionBackupSucceeded,
+			Status:  metav1.ConditionTrue,
+			Reason:  conditionBackupSucceededReason,
+			Message: fmt.Sprintf("Uploaded snapshot %s", name),
+		})
+	}
+	if err := r.tryUpdateStatus(ctx, req.NamespacedName, status); err != nil {
+		logr.Error(err, "Updating status")
+		return ctrl.Result{}, err
+	}
+
+	// failed backups are retried at the next scheduled time
+	return ctrl.Result{RequeueAfter: schedule.Next(now.UTC()).Sub(now)}, nil
+}
+
+// SetupWithManager sets up the controller with the Manager.
+func (r *EtcdBackupReconciler) SetupWithManager(mgr ctrl.Manager) error {
+	return ctrl.NewControllerManagedBy(mgr).
+		For(&updatev1alpha1.EtcdBackup{}).
+		Complete(r)
+}
+
+// backup takes an encrypted snapshot, uploads it to the object store under the given name and prunes old snapshots.
+// It returns the snapshots remaining in the object store, oldest first.
+func (r *EtcdBackupReconciler) backup(ctx context.Context, spec updatev1alpha1.EtcdBackupSpec, name string) ([]updatev1alpha1.EtcdSnapshot, error) {
+	// anyone allowed to edit EtcdBackups could otherwise read every secret of the cluster through the operator
+	secretNamespace := spec.Storage.CredentialsSecret.Namespace
+	if secretNamespace == "" {
+		secretNamespace = r.namespace
+	}
+	if secretNamespace != r.namespace {
+		return nil, fmt.Errorf("credentials secret must be in namespace %q of the operator, not %q", r.namespace, secretNamespace)
+	}
+	var credentials corev1.Secret
+	if err := r.secretReader.Get(ctx, types.NamespacedName{
+		Namespace: secretNamespace,
+		Name:      spec.Storage.CredentialsSecret.Name,
+	}, &credentials); err != nil {
+		return nil, fmt.Errorf("getting object store credentials: %w", err)
+	}
+	store, err := r.newObjectStore(ctx, spec.Storage, credentials.Data)
+	if err != nil {
+		return nil, fmt.Errorf("creating object store client: %w", err)
+	}
+	key, err := r.GetDataKey(ctx, constants.EtcdBackupKeyID, constants.EtcdBackupKeyLength)
+	if err != nil {
+		return nil, fmt.Errorf("getting snapshot encryption key: %w", err)
+	}
+
+	snapshot, err := r.Snapshot(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("taking etcd snapshot: %w", err)
+	}
+	defer snapshot.Close()
+
+	// the encrypted snapshot is spooled to disk, since snapshots can exceed the memory limit of the operator
+	// and uploads require knowing the size of the object in advance
+	encrypted, err := os.CreateTemp("", "etcd-snapshot-*")
+	if err != nil {
+		return nil, fmt.Errorf("creating temporary file: %w", err)
+	}
+	defer os.Remove(encrypted.Name())
+	defer encrypted.Close()
+	if err := encryptSnapshot(encrypted, snapshot, key, name); err != nil {
+		return nil, fmt.Errorf("encrypting etcd snapshot: %w", err)
+	}
+	if _, err := encrypted.Seek(0, io.SeekStart); err != nil {
+		return nil, fmt.Errorf("rewinding encrypted snapshot: %w", err)
+	}
+
+	if err := store.Put(ctx, name, encrypted); err != nil {
+		return nil, err
+	}
+	return pruneSnapshots(ctx, store, spec.Storage.Prefix, int(spec.Retention))
+}
+
+// tryUpdateStatus attempts to update the EtcdBackup status field in a retry loop.
+func (r *EtcdBackupReconciler) tryUpdateStatus(ctx context.Context, name types.NamespacedName, status updatev1alpha1.EtcdBackupStatus) error {
+	return retry.RetryOnConflict(retry.DefaultRetry, func() error {
+		var backup updatev1alpha1.EtcdBackup
+		if err := r.Get(ctx, name, &backup); err != nil {
+			return err
+		}
+		backup.Status = *status.DeepCopy()
+		return r.Status().Update(ctx, &backup)
+	})
+}
+
+// nextBackupTime returns the time the next backup is due.
+// Schedules are evaluated in UTC. The first backup is due at the first scheduled time after the EtcdBackup was created.
+func nextBackupTime(schedule cron.Schedule, backup updatev1alpha1.EtcdBackup) time.Time {
+	last := backup.CreationTimestamp.Time
+	if backup.Status.LastScheduleTime != nil {
+		last = backup.Status.LastScheduleTime.Time
+	}
+	return schedule.Next(last.UTC())
+}
+
+// snapshotName returns the object name of a snapshot taken at the given time.
+func snapshotName(prefix string, now time.Time) string {
+	return prefix + etcdSnapshotPrefix + now.UTC().Format(etcdSnapshotTimeFormat) + etcdSnapshotSuffix
+}
+
+// pruneSnapshots deletes the oldest snapshots until at most retention snapshots remain.
+// Objects not created by the operator are ignored.
+func pruneSnapshots(ctx context.Context, store objectStore, prefix string, retention int) ([]updatev1alpha1.EtcdSnapshot, error) {
+	objects, err := store.List(ctx, prefix)
+	if err != nil {
+		return nil, err
+	}
+	var snapshots []updatev1alpha1.EtcdSnapshot
+	for _, object := range objects {
+		timestamp := strings.TrimPrefix(object.Name, prefix+etcdSnapshotPrefix)
+		if timestamp == object.Name || !strings.HasSuffix(timestamp, etcdSnapshotSuffix) {
+			continue
+		}
+		takenAt, err := time.Parse(etcdSnapshotTimeFormat, strings.TrimSuffix(timestamp, etcdSnapshotSuffix))
+		if err != nil {
+			continue
+		}
+		snapshots = append(snapshots, updatev1alpha1.EtcdSnapshot{
+			Name: object.Name,
+			Time: metav1.Time{Time: takenAt},
+			Size: object.Size,
+		})
+	}
+	sort.Slice(snapshots, func(i, j int) bool {
+		return snapshots[i].Time.Before(&snapshots[j].Time)
+	})
+
+	if retention < 1 {
+		retention = 1
+	}
+	var errs error
+	for len(snapshots) > retention {
+		if err := store.Delete(ctx, snapshots[0].Name); err != nil {
+			errs = multierr.Append(errs, err)
+		}
+		snapshots = snapshots[1:]
+	}
+	if errs != nil {
+		return nil, fmt.Errorf("deleting old snapshots: %w", errs)
+	}
+	return snapshots, nil
+}
+
+// newObjectStore creates an object store client from an ObjectStoreSpec and the data of the credentials secret.
+func newObjectStore(_ context.Context, spec updatev1alpha1.ObjectStoreSpec, credentials map[string][]byte) (objectStore, error) {
+	switch spec.Type {
+	case updatev1alpha1.ObjectStoreS3:
+		accessKeyID, secretAccessKey := credentials["accessKeyID"], credentials["secretAccessKey"]
+		if len(accessKeyID) == 0 || len(secretAccessKey) == 0 {
+			return nil, errors.New("credentials secret must contain accessKeyID and secretAccessKey")
+		}
+		return objectstore.NewS3Store(spec.Bucket, spec.Region, spec.Endpoint, string(accessKeyID), string(secretAccessKey)), nil
+	case updatev1alpha1.ObjectStoreAzureBlob:
+		connectionString := credentials["connectionString"]
+		if len(connectionString) == 0 {
+			return nil, errors.New("credentials secret must contain connectionString")
+		}
+		return objectstore.NewAzureBlobStore(string(connectionString), spec.Bucket)
+	case updatev1alpha1.ObjectStoreGCS:
+		serviceAccountKey := credentials["serviceAccountKey"]
+		if len(serviceAccountKey) == 0 {
+			return nil, errors.New("credentials secret must contain serviceAccountKey")
+		}
+		return objectstore.NewGCSStore(spec.Bucket, serviceAccountKey), nil
+	default:
+		return nil, fmt.Errorf("unknown object store type %q", spec.Type)
+	}
+}
+
+type objectStoreFactory func(ctx context.Context, spec updatev1alpha1.ObjectStoreSpec, credentials map[string][]byte) (objectStore, error)
+
+type objectStore interface {
+	// Put uploads an object.
+	Put(ctx context.Context, name string, data io.ReadSeeker) error
+	// List returns all objects with the given prefix.
+	List(ctx context.Context, prefix string) ([]objectstore.Object, error)
+	// Delete removes an object.
+	Delete(ctx context.Context, name string) error
+}
+
+type etcdSnapshotter interface {
+	// Snapshot streams a snapshot of the etcd keyspace.
+	Snapshot(ctx context.Context) (io.ReadCloser, error)
+}
+
+type dataKeyGetter interface {
+	// GetDataKey returns a key derived from the Constellation master secret.
+	GetDataKey(ctx context.Context, keyID string, length int) ([]byte, error)
+}
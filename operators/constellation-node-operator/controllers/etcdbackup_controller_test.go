@@ -0,0 +1,447 @@
+/*
+Copyright (c) Edgeless Systems GmbH
+
+SPDX-License-Identifier: AGPL-3.0-only
+*/
+
+package controllers
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"io"
+	"sort"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+
+	updatev1alpha1 "github.com/edgelesssys/constellation/operators/constellation-node-operator/v2/api/v1alpha1"
+	"github.com/edgelesssys/constellation/operators/constellation-node-operator/v2/internal/constants"
+	"github.com/edgelesssys/constellation/operators/constellation-node-operator/v2/internal/objectstore"
+)
+
+func TestBackup(t *testing.T) {
+	someErr := errors.New("failed")
+	key := bytes.Repeat([]byte{0x42}, constants.EtcdBackupKeyLength)
+	now := time.Date(2022, time.August, 10, 3, 0, 0, 0, time.UTC)
+	secret := &corev1.Secret{
+		ObjectMeta: metav1.ObjectMeta{Name: "credentials", Namespace: "kube-system"},
+		Data:       map[string][]byte{"accessKeyID": []byte("id"), "secretAccessKey": []byte("secret")},
+	}
+	spec := updatev1alpha1.EtcdBackupSpec{
+		Schedule:  "@daily",
+		Retention: 2,
+		Storage: updatev1alpha1.ObjectStoreSpec{
+			Type:              updatev1alpha1.ObjectStoreS3,
+			Bucket:            "bucket",
+			Prefix:            "backups/",
+			CredentialsSecret: corev1.SecretReference{Name: "credentials", Namespace: "kube-system"},
+		},
+	}
+
+	testCases := map[string]struct {
+		secret         *corev1.Secret
+		secretRef      *corev1.SecretReference
+		getErr         error
+		newStoreErr    error
+		store          *stubObjectStore
+		keyGetter      *stubDataKeyGetter
+		snapshotter    *stubEtcdSnapshotter
+		wantSnapshots  []string
+		wantRemaining  []string
+		wantErr        bool
+		wantNoUploaded bool
+	}{
+		"first backup": {
+			secret:        secret,
+			store:         &stubObjectStore{objects: map[string][]byte{}},
+			keyGetter:     &stubDataKeyGetter{key: key},
+			snapshotter:   &stubEtcdSnapshotter{snapshot: []byte("snapshot")},
+			wantSnapshots: []string{"backups/etcd-snapshot-20220810T030000Z.enc"},
+			wantRemaining: []string{"backups/etcd-snapshot-20220810T030000Z.enc"},
+		},
+		"old snapshots are pruned": {
+			secret: secret,
+			store: &stubObjectStore{objects: map[string][]byte{
+				"backups/etcd-snapshot-20220808T030000Z.enc": {},
+				"backups/etcd-snapshot-20220809T030000Z.enc": {},
+				"backups/unrelated-file":                     {},
+			}},
+			keyGetter:   &stubDataKeyGetter{key: key},
+			snapshotter: &stubEtcdSnapshotter{snapshot: []byte("snapshot")},
+			wantSnapshots: []string{
+				"backups/etcd-snapshot-20220809T030000Z.enc",
+				"backups/etcd-snapshot-20220810T030000Z.enc",
+			},
+			wantRemaining: []string{
+				"backups/etcd-snapshot-20220809T030000Z.enc",
+				"backups/etcd-snapshot-20220810T030000Z.enc",
+				"backups/unrelated-file",
+			},
+		},
+		"getting credentials secret fails": {
+			secret:         secret,
+			getErr:         someErr,
+			store:          &stubObjectStore{objects: map[string][]byte{}},
+			keyGetter:      &stubDataKeyGetter{key: key},
+			snapshotter:    &stubEtcdSnapshotter{snapshot: []byte("snapshot")},
+			wantErr:        true,
+			wantNoUploaded: true,
+		},
+		"credentials secret without namespace": {
+			secret:        secret,
+			secretRef:     &corev1.SecretReference{Name: "credentials"},
+			store:         &stubObjectStore{objects: map[string][]byte{}},
+			keyGetter:     &stubDataKeyGetter{key: key},
+			snapshotter:   &stubEtcdSnapshotter{snapshot: []byte("snapshot")},
+			wantSnapshots: []string{"backups/etcd-snapshot-20220810T030000Z.enc"},
+			wantRemaining: []string{"backups/etcd-snapshot-20220810T030000Z.enc"},
+		},
+		"credentials secret in other namespace": {
+			secret: &corev1.Secret{
+				ObjectMeta: metav1.ObjectMeta{Name: "credentials", Namespace: "default"},
+				Data:       secret.Data,
+			},
+			secretRef:      &corev1.SecretReference{Name: "credentials", Namespace: "default"},
+			store:          &stubObjectStore{objects: map[string][]byte{}},
+			keyGetter:      &stubDataKeyGetter{key: key},
+			snapshotter:    &stubEtcdSnapshotter{snapshot: []byte("snapshot")},
+			wantErr:        true,
+			wantNoUploaded: true,
+		},
+		"creating object store fails": {
+			secret:         secret,
+			newStoreErr:    someErr,
+			store:          &stubObjectStore{objects: map[string][]byte{}},
+			keyGetter:      &stubDataKeyGetter{key: key},
+			snapshotter:    &stubEtcdSnapshotter{snapshot: []byte("snapshot")},
+			wantErr:        true,
+			wantNoUploaded: true,
+		},
+		"getting key fails": {
+			secret:         secret,
+			store:          &stubObjectStore{objects: map[string][]byte{}},
+			keyGetter:      &stubDataKeyGetter{err: someErr},
+			snapshotter:    &stubEtcdSnapshotter{snapshot: []byte("snapshot")},
+			wantErr:        true,
+			wantNoUploaded: true,
+		},
+		"taking snapshot fails": {
+			secret:         secret,
+			store:          &stubObjectStore{objects: map[string][]byte{}},
+			keyGetter:      &stubDataKeyGetter{key: key},
+			snapshotter:    &stubEtcdSnapshotter{err: someErr},
+			wantErr:        true,
+			wantNoUploaded: true,
+		},
+		"upload fails": {
+			secret:         secret,
+			store:          &stubObjectStore{objects: map[string][]byte{}, putErr: someErr},
+			keyGetter:      &stubDataKeyGetter{key: key},
+			snapshotter:    &stubEtcdSnapshotter{snapshot: []byte("snapshot")},
+			wantErr:        true,
+			wantNoUploaded: true,
+		},
+		"pruning fails": {
+			secret: secret,
+			store: &stubObjectStore{
+				objects: map[string][]byte{
+					"backups/etcd-snapshot-20220808T030000Z.enc": {},
+					"backups/etcd-snapshot-20220809T030000Z.enc": {},
+				},
+				deleteErr: someErr,
+			},
+			keyGetter:   &stubDataKeyGetter{key: key},
+			snapshotter: &stubEtcdSnapshotter{snapshot: []byte("snapshot")},
+			wantErr:     true,
+		},
+	}
+
+	for name, tc := range testCases {
+		t.Run(name, func(t *testing.T) {
+			assert := assert.New(t)
+			require := require.New(t)
+
+			reconciler := EtcdBackupReconciler{
+				etcdSnapshotter: tc.snapshotter,
+				dataKeyGetter:   tc.keyGetter,
+				newObjectStore: func(ctx context.Context, spec updatev1alpha1.ObjectStoreSpec, credentials map[string][]byte) (objectStore, error) {
+					return tc.store, tc.newStoreErr
+				},
+				secretReader: newStubReaderClient(t, []runtime.Object{tc.secret}, tc.getErr, nil),
+				namespace:    "kube-system",
+			}
+			spec := *spec.DeepCopy()
+			if tc.secretRef != nil {
+				spec.Storage.CredentialsSecret = *tc.secretRef
+			}
+			snapshotName := snapshotName(spec.Storage.Prefix, now)
+			snapshots, err := reconciler.backup(context.Background(), spec, snapshotName)
+			if tc.wantNoUploaded {
+				assert.NotContains(tc.store.objects, snapshotName)
+			}
+			if tc.wantErr {
+				assert.Error(err)
+				return
+			}
+			require.NoError(err)
+			var gotSnapshots []string
+			for _, snapshot := range snapshots {
+				gotSnapshots = append(gotSnapshots, snapshot.Name)
+			}
+			assert.Equal(tc.wantSnapshots, gotSnapshots)
+			assert.Equal(tc.wantRemaining, tc.store.names())
+			assert.Equal(constants.EtcdBackupKeyID, tc.keyGetter.keyID)
+			assert.True(tc.snapshotter.closed)
+
+			// the uploaded snapshot must be decryptable with the derived key
+			gotName, plaintext, err := decryptSnapshot(key, tc.store.objects[snapshotName])
+			require.NoError(err)
+			assert.Equal(snapshotName, gotName)
+			assert.Equal(tc.snapshotter.snapshot, plaintext)
+		})
+	}
+}
+
+func TestPruneSnapshots(t *testing.T) {
+	testCases := map[string]struct {
+		objects       []string
+		retention     int
+		listErr       error
+		wantSnapshots []string
+		wantErr       bool
+	}{
+		"fewer snapshots than retention": {
+			objects:       []string{"etcd-snapshot-20220809T030000Z.enc"},
+			retention:     3,
+			wantSnapshots: []string{"etcd-snapshot-20220809T030000Z.enc"},
+		},
+		"oldest snapshots are deleted": {
+			objects: []string{
+				"etcd-snapshot-20220810T030000Z.enc",
+				"etcd-snapshot-20220808T030000Z.enc",
+				"etcd-snapshot-20220809T030000Z.enc",
+			},
+			retention: 2,
+			wantSnapshots: []string{
+				"etcd-snapshot-20220809T030000Z.enc",
+				"etcd-snapshot-20220810T030000Z.enc",
+			},
+		},
+		"foreign objects are ignored": {
+			objects: []string{
+				"etcd-snapshot-20220810T030000Z.enc",
+				"etcd-snapshot-invalid.enc",
+				"etcd-snapshot-20220809T030000Z",
+				"notes.txt",
+			},
+			retention:     1,
+			wantSnapshots: []string{"etcd-snapshot-20220810T030000Z.enc"},
+		},
+		"retention below one keeps latest snapshot": {
+			objects: []string{
+				"etcd-snapshot-20220809T030000Z.enc",
+				"etcd-snapshot-20220810T030000Z.enc",
+			},
+			wantSnapshots: []string{"etcd-snapshot-20220810T030000Z.enc"},
+		},
+		"listing fails": {
+			listErr: errors.New("failed"),
+			wantErr: true,
+		},
+	}
+
+	for name, tc := range testCases {
+		t.Run(name, func(t *testing.T) {
+			assert := assert.New(t)
+
+			store := &stubObjectStore{objects: map[string][]byte{}, listErr: tc.listErr}
+			for _, object := range tc.objects {
+				store.objects[object] = []byte{}
+			}
+			snapshots, err := pruneSnapshots(context.Background(), store, "", tc.retention)
+			if tc.wantErr {
+				assert.Error(err)
+				return
+			}
+			assert.NoError(err)
+			var gotSnapshots []string
+			for _, snapshot := range snapshots {
+				gotSnapshots = append(gotSnapshots, snapshot.Name)
+				assert.Contains(store.objects, snapshot.Name)
+			}
+			assert.Equal(tc.wantSnapshots, gotSnapshots)
+		})
+	}
+}
+
+func TestNextBackupTime(t *testing.T) {
+	created := time.Date(2022, time.August, 10, 3, 30, 0, 0, time.UTC)
+	schedule, err := maintenanceWindowParser.Parse("0 * * * *")
+	require.NoError(t, err)
+
+	testCases := map[string]struct {
+		lastSchedule *metav1.Time
+		wantNext     time.Time
+	}{
+		"never scheduled": {
+			wantNext: time.Date(2022, time.August, 10, 4, 0, 0, 0, time.UTC),
+		},
+		"previously scheduled": {
+			lastSchedule: &metav1.Time{Time: time.Date(2022, time.August, 11, 7, 0, 0, 0, time.UTC)},
+			wantNext:     time.Date(2022, time.August, 11, 8, 0, 0, 0, time.UTC),
+		},
+	}
+
+	for name, tc := range testCases {
+		t.Run(name, func(t *testing.T) {
+			assert := assert.New(t)
+
+			backup := updatev1alpha1.EtcdBackup{
+				ObjectMeta: metav1.ObjectMeta{CreationTimestamp: metav1.Time{Time: created}},
+				Status:     updatev1alpha1.EtcdBackupStatus{LastScheduleTime: tc.lastSchedule},
+			}
+			assert.True(tc.wantNext.Equal(nextBackupTime(schedule, backup)))
+		})
+	}
+}
+
+func TestNewObjectStore(t *testing.T) {
+	testCases := map[string]struct {
+		storeType   updatev1alpha1.ObjectStoreType
+		credentials map[string][]byte
+		wantErr     bool
+	}{
+		"s3": {
+			storeType:   updatev1alpha1.ObjectStoreS3,
+			credentials: map[string][]byte{"accessKeyID": []byte("id"), "secretAccessKey": []byte("secret")},
+		},
+		"s3 missing credentials": {
+			storeType:   updatev1alpha1.ObjectStoreS3,
+			credentials: map[string][]byte{"accessKeyID": []byte("id")},
+			wantErr:     true,
+		},
+		"azure blob": {
+			storeType:   updatev1alpha1.ObjectStoreAzureBlob,
+			credentials: map[string][]byte{"connectionString": []byte("DefaultEndpointsProtocol=https;AccountName=account;AccountKey=a2V5;EndpointSuffix=core.windows.net")},
+		},
+		"azure blob missing credentials": {
+			storeType: updatev1alpha1.ObjectStoreAzureBlob,
+			wantErr:   true,
+		},
+		"gcs": {
+			storeType:   updatev1alpha1.ObjectStoreGCS,
+			credentials: map[string][]byte{"serviceAccountKey": []byte("{}")},
+		},
+		"gcs missing credentials": {
+			storeType: updatev1alpha1.ObjectStoreGCS,
+			wantErr:   true,
+		},
+		"unknown type": {
+			storeType: "FTP",
+			wantErr:   true,
+		},
+	}
+
+	for name, tc := range testCases {
+		t.Run(name, func(t *testing.T) {
+			assert := assert.New(t)
+
+			spec := updatev1alpha1.ObjectStoreSpec{Type: tc.storeType, Bucket: "bucket"}
+			store, err := newObjectStore(context.Background(), spec, tc.credentials)
+			if tc.wantErr {
+				assert.Error(err)
+				return
+			}
+			assert.NoError(err)
+			assert.NotNil(store)
+		})
+	}
+}
+
+type stubEtcdSnapshotter struct {
+	snapshot []byte
+	err      error
+	closed   bool
+}
+
+func (s *stubEtcdSnapshotter) Snapshot(ctx context.Context) (io.ReadCloser, error) {
+	if s.err != nil {
+		return nil, s.err
+	}
+	return &closeRecorder{Reader: bytes.NewReader(s.snapshot), closed: &s.closed}, nil
+}
+
+type closeRecorder struct {
+	io.Reader
+	closed *bool
+}
+
+func (c *closeRecorder) Close() error {
+	*c.closed = true
+	return nil
+}
+
+type stubDataKeyGetter struct {
+	key   []byte
+	err   error
+	keyID string
+}
+
+func (s *stubDataKeyGetter) GetDataKey(ctx context.Context, keyID string, length int) ([]byte, error) {
+	s.keyID = keyID
+	return s.key, s.err
+}
+
+type stubObjectStore struct {
+	objects   map[string][]byte
+	putErr    error
+	listErr   error
+	deleteErr error
+}
+
+func (s *stubObjectStore) Put(ctx context.Context, name string, data io.ReadSeeker) error {
+	if s.putErr != nil {
+		return s.putErr
+	}
+	object, err := io.ReadAll(data)
+	if err != nil {
+		return err
+	}
+	s.objects[name] = object
+	return nil
+}
+
+func (s *stubObjectStore) List(ctx context.Context, prefix string) ([]objectstore.Object, error) {
+	if s.listErr != nil {
+		return nil, s.listErr
+	}
+	var objects []objectstore.Object
+	for name, data := range s.objects {
+		objects = append(objects, objectstore.Object{Name: name, Size: int64(len(data))})
+	}
+	return objects, nil
+}
+
+func (s *stubObjectStore) Delete(ctx context.Context, name string) error {
+	if s.deleteErr != nil {
+		return s.deleteErr
+	}
+	delete(s.objects, name)
+	return nil
+}
+
+func (s *stubObjectStore) names() []string {
+	var names []string
+	for name := range s.objects {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
@@ -0,0 +1,116 @@
+/*
+Copyright (c) Edgeless Systems GmbH
+
+SPDX-License-Identifier: AGPL-3.0-only
+*/
+
+package controllers
+
+import (
+	"bufio"
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+	"math"
+
+	"golang.org/x/crypto/hkdf"
+)
+
+// Encrypted snapshots use a chunked AES-GCM stream, so snapshots never have to be held in memory as a whole.
+//
+// An encrypted snapshot starts with a header:
+//
+//	version (1 byte) | salt (32 bytes) | length of the snapshot name (2 bytes, big endian) | snapshot name
+//
+// The header is followed by the sealed chunks. Every chunk but the last holds exactly etcdSnapshotChunkSize bytes of plaintext.
+// Chunk i is sealed with the nonce 0x000000 | i (8 bytes, big endian) | last (1 byte), and the header as additional data.
+// This binds every chunk to its position, the end of the stream, and the snapshot name, which includes the time the snapshot was taken.
+// The chunk key is derived from the KMS data key using HKDF-SHA256 with the salt of the header and etcdSnapshotHKDFInfo,
+// so it is never used for anything else than snapshots, and every snapshot is encrypted with a fresh key.
+//
+// "constellation restore" implements the decryption of this format.
+const (
+	etcdSnapshotVersion   = 1
+	etcdSnapshotSaltSize  = 32
+	etcdSnapshotChunkSize = 64 * 1024
+	etcdSnapshotHKDFInfo  = "constellation etcd backup v1"
+)
+
+// encryptSnapshot encrypts a snapshot read from plaintext and writes it to ciphertext.
+// name is the object name of the snapshot and is bound to the ciphertext.
+func encryptSnapshot(ciphertext io.Writer, plaintext io.Reader, dataKey []byte, name string) error {
+	if len(name) > math.MaxUint16 {
+		return fmt.Errorf("snapshot name is too long: %d bytes", len(name))
+	}
+	salt := make([]byte, etcdSnapshotSaltSize)
+	if _, err := rand.Read(salt); err != nil {
+		return err
+	}
+	header := make([]byte, 1+etcdSnapshotSaltSize+2+len(name))
+	header[0] = etcdSnapshotVersion
+	copy(header[1:], salt)
+	binary.BigEndian.PutUint16(header[1+etcdSnapshotSaltSize:], uint16(len(name)))
+	copy(header[1+etcdSnapshotSaltSize+2:], name)
+
+	aead, err := newSnapshotAEAD(dataKey, salt)
+	if err != nil {
+		return err
+	}
+	if _, err := ciphertext.Write(header); err != nil {
+		return err
+	}
+
+	reader := bufio.NewReaderSize(plaintext, etcdSnapshotChunkSize)
+	chunk := make([]byte, etcdSnapshotChunkSize)
+	sealed := make([]byte, 0, etcdSnapshotChunkSize+aead.Overhead())
+	for counter := uint64(0); ; counter++ {
+		n, err := io.ReadFull(reader, chunk)
+		if err != nil && !errors.Is(err, io.EOF) && !errors.Is(err, io.ErrUnexpectedEOF) {
+			return fmt.Errorf("reading snapshot: %w", err)
+		}
+		last := n < etcdSnapshotChunkSize
+		if !last {
+			if _, err := reader.Peek(1); errors.Is(err, io.EOF) {
+				last = true
+			} else if err != nil {
+				return fmt.Errorf("reading snapshot: %w", err)
+			}
+		}
+
+		sealed = aead.Seal(sealed[:0], snapshotChunkNonce(counter, last), chunk[:n], header)
+		if _, err := ciphertext.Write(sealed); err != nil {
+			return err
+		}
+		if last {
+			return nil
+		}
+	}
+}
+
+// newSnapshotAEAD derives the key of a single snapshot and returns an AES-GCM cipher using it.
+func newSnapshotAEAD(dataKey, salt []byte) (cipher.AEAD, error) {
+	key := make([]byte, 32)
+	if _, err := io.ReadFull(hkdf.New(sha256.New, dataKey, salt, []byte(etcdSnapshotHKDFInfo)), key); err != nil {
+		return nil, err
+	}
+	block, err := aes.NewCipher(key)
+	if err != nil {
+		return nil, err
+	}
+	return cipher.NewGCM(block)
+}
+
+// snapshotChunkNonce returns the nonce of the chunk at the given position.
+func snapshotChunkNonce(counter uint64, last bool) []byte {
+	nonce := make([]byte, 12)
+	binary.BigEndian.PutUint64(nonce[3:11], counter)
+	if last {
+		nonce[11] = 1
+	}
+	return nonce
+}
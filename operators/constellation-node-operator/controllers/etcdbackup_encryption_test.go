@@ -0,0 +1,166 @@
+/*
+Copyright (c) Edgeless Systems GmbH
+
+SPDX-License-Identifier: AGPL-3.0-only
+*/
+
+package controllers
+
+import (
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/edgelesssys/constellation/operators/constellation-node-operator/v2/internal/constants"
+)
+
+func TestEncryptSnapshot(t *testing.T) {
+	key := bytes.Repeat([]byte{0x42}, constants.EtcdBackupKeyLength)
+	name := "backups/etcd-snapshot-20220810T030000Z.enc"
+
+	testCases := map[string]struct {
+		snapshot []byte
+		name     string
+		wantErr  bool
+	}{
+		"empty snapshot": {
+			snapshot: []byte{},
+			name:     name,
+		},
+		"single chunk": {
+			snapshot: []byte("snapshot"),
+			name:     name,
+		},
+		"exactly one chunk": {
+			snapshot: bytes.Repeat([]byte{0x01}, etcdSnapshotChunkSize),
+			name:     name,
+		},
+		"multiple chunks": {
+			snapshot: bytes.Repeat([]byte{0x01}, 3*etcdSnapshotChunkSize+17),
+			name:     name,
+		},
+		"name too long": {
+			snapshot: []byte("snapshot"),
+			name:     string(bytes.Repeat([]byte{'a'}, 1<<16)),
+			wantErr:  true,
+		},
+	}
+
+	for name, tc := range testCases {
+		t.Run(name, func(t *testing.T) {
+			assert := assert.New(t)
+			require := require.New(t)
+
+			var ciphertext bytes.Buffer
+			err := encryptSnapshot(&ciphertext, bytes.NewReader(tc.snapshot), key, tc.name)
+			if tc.wantErr {
+				assert.Error(err)
+				return
+			}
+			require.NoError(err)
+
+			gotName, plaintext, err := decryptSnapshot(key, ciphertext.Bytes())
+			require.NoError(err)
+			assert.Equal(tc.name, gotName)
+			assert.Equal(tc.snapshot, plaintext)
+		})
+	}
+}
+
+func TestEncryptSnapshotTampering(t *testing.T) {
+	key := bytes.Repeat([]byte{0x42}, constants.EtcdBackupKeyLength)
+	name := "etcd-snapshot-20220810T030000Z.enc"
+	snapshot := bytes.Repeat([]byte{0x01}, 2*etcdSnapshotChunkSize)
+	sealedChunkSize := etcdSnapshotChunkSize + 16
+	headerSize := 1 + etcdSnapshotSaltSize + 2 + len(name)
+
+	var buf bytes.Buffer
+	require.NoError(t, encryptSnapshot(&buf, bytes.NewReader(snapshot), key, name))
+	ciphertext := buf.Bytes()
+
+	testCases := map[string]struct {
+		key        []byte
+		ciphertext func() []byte
+	}{
+		"wrong key": {
+			key:        bytes.Repeat([]byte{0x43}, constants.EtcdBackupKeyLength),
+			ciphertext: func() []byte { return ciphertext },
+		},
+		"renamed snapshot": {
+			key: key,
+			ciphertext: func() []byte {
+				tampered := append([]byte{}, ciphertext...)
+				tampered[headerSize-1] = '1'
+				return tampered
+			},
+		},
+		"truncated at chunk boundary": {
+			key: key,
+			ciphertext: func() []byte {
+				return ciphertext[:headerSize+sealedChunkSize]
+			},
+		},
+		"chunks reordered": {
+			key: key,
+			ciphertext: func() []byte {
+				tampered := append([]byte{}, ciphertext[:headerSize]...)
+				tampered = append(tampered, ciphertext[headerSize+sealedChunkSize:headerSize+2*sealedChunkSize]...)
+				tampered = append(tampered, ciphertext[headerSize:headerSize+sealedChunkSize]...)
+				return append(tampered, ciphertext[headerSize+2*sealedChunkSize:]...)
+			},
+		},
+		"trailing data": {
+			key: key,
+			ciphertext: func() []byte {
+				return append(append([]byte{}, ciphertext...), 0x00)
+			},
+		},
+	}
+
+	for name, tc := range testCases {
+		t.Run(name, func(t *testing.T) {
+			_, _, err := decryptSnapshot(tc.key, tc.ciphertext())
+			assert.Error(t, err)
+		})
+	}
+}
+
+// decryptSnapshot mirrors the decryption done by "constellation restore".
+func decryptSnapshot(key, ciphertext []byte) (string, []byte, error) {
+	if len(ciphertext) < 1+etcdSnapshotSaltSize+2 || ciphertext[0] != etcdSnapshotVersion {
+		return "", nil, errors.New("invalid header")
+	}
+	salt := ciphertext[1 : 1+etcdSnapshotSaltSize]
+	nameLen := int(binary.BigEndian.Uint16(ciphertext[1+etcdSnapshotSaltSize:]))
+	headerSize := 1 + etcdSnapshotSaltSize + 2 + nameLen
+	if len(ciphertext) < headerSize {
+		return "", nil, errors.New("invalid header")
+	}
+	header, chunks := ciphertext[:headerSize], ciphertext[headerSize:]
+
+	aead, err := newSnapshotAEAD(key, salt)
+	if err != nil {
+		return "", nil, err
+	}
+	sealedChunkSize := etcdSnapshotChunkSize + aead.Overhead()
+	plaintext := []byte{}
+	for counter := uint64(0); ; counter++ {
+		last := len(chunks) <= sealedChunkSize
+		chunk := chunks
+		if !last {
+			chunk = chunks[:sealedChunkSize]
+		}
+		plaintext, err = aead.Open(plaintext, snapshotChunkNonce(counter, last), chunk, header)
+		if err != nil {
+			return "", nil, err
+		}
+		if last {
+			return string(header[1+etcdSnapshotSaltSize+2:]), plaintext, nil
+		}
+		chunks = chunks[sealedChunkSize:]
+	}
+}
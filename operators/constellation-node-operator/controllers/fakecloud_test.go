@@ -0,0 +1,535 @@
+/*
+Copyright (c) Edgeless Systems GmbH
+
+SPDX-License-Identifier: AGPL-3.0-only
+*/
+
+package controllers
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	corev1 "k8s.io/api/core/v1"
+	k8sErrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+
+	updatev1alpha1 "github.com/edgelesssys/constellation/operators/constellation-node-operator/v2/api/v1alpha1"
+)
+
+const (
+	controlPlaneRoleLabel = "node-role.kubernetes.io/control-plane"
+
+	fakeCloudTimeout  = 2 * time.Second
+	fakeCloudInterval = 5 * time.Millisecond
+)
+
+// errFakeCloud is returned by the fake cloud for injected failures.
+var errFakeCloud = errors.New("injected fake cloud failure")
+
+// fakeCloud is a programmable in-memory cloud provider.
+// It implements the cloud interfaces used by the NodeImage, PendingNode and ScalingGroup controllers.
+// Instances created using CreateNode boot after a configurable latency and register a Kubernetes node
+// with their provider ID. Terminated instances are removed from the cloud and their Kubernetes node is deleted.
+type fakeCloud struct {
+	mux sync.Mutex
+	// client is used to register and remove Kubernetes nodes. Nodes are not registered if it is nil.
+	client    client.Client
+	groups    map[string]*fakeScalingGroup
+	instances map[string]*fakeInstance
+	nextIndex int
+
+	bootLatency   time.Duration
+	deleteLatency time.Duration
+	// createFailures is the number of upcoming CreateNode calls that fail.
+	createFailures int
+	// joinFailures is the number of upcoming instances that boot but never join the cluster.
+	joinFailures int
+	// stuckDeletion prevents instances from terminating after DeleteNode was called.
+	stuckDeletion bool
+	// onStateChange is called without holding the lock whenever an instance changes its state.
+	onStateChange func(nodeName string)
+
+	peakInstances      int
+	removedEtcdMembers []string
+	errs               []error
+}
+
+type fakeScalingGroup struct {
+	id           string
+	image        string
+	controlPlane bool
+}
+
+type fakeInstance struct {
+	name         string
+	providerID   string
+	groupID      string
+	image        string
+	vpcIP        string
+	controlPlane bool
+	neverJoins   bool
+	state        updatev1alpha1.CSPNodeState
+}
+
+func newFakeCloud(client client.Client) *fakeCloud {
+	return &fakeCloud{
+		client:    client,
+		groups:    make(map[string]*fakeScalingGroup),
+		instances: make(map[string]*fakeInstance),
+	}
+}
+
+// GetNodeImage retrieves the image currently used by a node.
+func (c *fakeCloud) GetNodeImage(ctx context.Context, providerID string) (string, error) {
+	c.mux.Lock()
+	defer c.mux.Unlock()
+	instance, ok := c.instances[providerID]
+	if !ok {
+		return "", fmt.Errorf("instance %q not found", providerID)
+	}
+	return instance.image, nil
+}
+
+// GetScalingGroupID retrieves the scaling group that a node is part of.
+func (c *fakeCloud) GetScalingGroupID(ctx context.Context, providerID string) (string, error) {
+	c.mux.Lock()
+	defer c.mux.Unlock()
+	instance, ok := c.instances[providerID]
+	if !ok {
+		return "", fmt.Errorf("instance %q not found", providerID)
+	}
+	return instance.groupID, nil
+}
+
+// CreateNode creates a new instance using the current image of the scaling group.
+// The instance boots after the configured boot latency.
+func (c *fakeCloud) CreateNode(ctx context.Context, scalingGroupID string) (nodeName, providerID string, err error) {
+	c.mux.Lock()
+	defer c.mux.Unlock()
+	if c.createFailures > 0 {
+		c.createFailures--
+		return "", "", errFakeCloud
+	}
+	instance, err := c.newInstance(scalingGroupID)
+	if err != nil {
+		return "", "", err
+	}
+	if c.joinFailures > 0 {
+		c.joinFailures--
+		instance.neverJoins = true
+	}
+	time.AfterFunc(c.bootLatency, func() { c.boot(instance.providerID) })
+	return instance.name, instance.providerID, nil
+}
+
+// DeleteNode starts the termination of an instance.
+// The instance is terminated after the configured deletion latency, unless deletions are stuck.
+func (c *fakeCloud) DeleteNode(ctx context.Context, providerID string) error {
+	c.mux.Lock()
+	defer c.mux.Unlock()
+	instance, ok := c.instances[providerID]
+	if !ok || instance.state == updatev1alpha1.NodeStateTerminating {
+		return nil
+	}
+	instance.state = updatev1alpha1.NodeStateTerminating
+	if !c.stuckDeletion {
+		time.AfterFunc(c.deleteLatency, func() { c.terminate(providerID) })
+	}
+	return nil
+}
+
+// GetNodeState retrieves the state of an instance. Unknown instances are reported as terminated.
+func (c *fakeCloud) GetNodeState(ctx context.Context, providerID string) (updatev1alpha1.CSPNodeState, error) {
+	c.mux.Lock()
+	defer c.mux.Unlock()
+	instance, ok := c.instances[providerID]
+	if !ok {
+		return updatev1alpha1.NodeStateTerminated, nil
+	}
+	return instance.state, nil
+}
+
+// GetScalingGroupImage retrieves the image used for new instances of a scaling group.
+func (c *fakeCloud) GetScalingGroupImage(ctx context.Context, scalingGroupID string) (string, error) {
+	c.mux.Lock()
+	defer c.mux.Unlock()
+	group, ok := c.groups[strings.ToLower(scalingGroupID)]
+	if !ok {
+		return "", fmt.Errorf("scaling group %q not found", scalingGroupID)
+	}
+	return group.image, nil
+}
+
+// SetScalingGroupImage sets the image used for new instances of a scaling group.
+func (c *fakeCloud) SetScalingGroupImage(ctx context.Context, scalingGroupID, imageURI string) error {
+	c.mux.Lock()
+	defer c.mux.Unlock()
+	group, ok := c.groups[strings.ToLower(scalingGroupID)]
+	if !ok {
+		return fmt.Errorf("scaling group %q not found", scalingGroupID)
+	}
+	group.image = imageURI
+	return nil
+}
+
+// RemoveEtcdMemberFromCluster records the removal of the etcd member with the given VPC IP.
+func (c *fakeCloud) RemoveEtcdMemberFromCluster(ctx context.Context, vpcIP string) error {
+	c.mux.Lock()
+	defer c.mux.Unlock()
+	c.removedEtcdMembers = append(c.removedEtcdMembers, vpcIP)
+	return nil
+}
+
+// addScalingGroup adds a scaling group to the cloud.
+func (c *fakeCloud) addScalingGroup(id, image string, controlPlane bool) {
+	c.mux.Lock()
+	defer c.mux.Unlock()
+	c.groups[strings.ToLower(id)] = &fakeScalingGroup{id: id, image: image, controlPlane: controlPlane}
+}
+
+// addInstance adds a running instance to a scaling group and registers its Kubernetes node.
+func (c *fakeCloud) addInstance(ctx context.Context, scalingGroupID string) (*corev1.Node, error) {
+	c.mux.Lock()
+	instance, err := c.newInstance(scalingGroupID)
+	if err != nil {
+		c.mux.Unlock()
+		return nil, err
+	}
+	instance.state = updatev1alpha1.NodeStateReady
+	node := instance.node()
+	c.mux.Unlock()
+
+	if err := c.register(ctx, node); err != nil {
+		return nil, err
+	}
+	return node, nil
+}
+
+// failCreations makes the next n calls to CreateNode fail.
+func (c *fakeCloud) failCreations(n int) {
+	c.mux.Lock()
+	defer c.mux.Unlock()
+	c.createFailures = n
+}
+
+// failJoins makes the next n created instances boot without ever joining the cluster.
+func (c *fakeCloud) failJoins(n int) {
+	c.mux.Lock()
+	defer c.mux.Unlock()
+	c.joinFailures = n
+}
+
+// setLatency sets the time it takes for instances to boot and to terminate.
+func (c *fakeCloud) setLatency(boot, deletion time.Duration) {
+	c.mux.Lock()
+	defer c.mux.Unlock()
+	c.bootLatency = boot
+	c.deleteLatency = deletion
+}
+
+// setStuckDeletion stops instances from terminating.
+// Instances that got stuck terminate once deletion is unstuck.
+func (c *fakeCloud) setStuckDeletion(stuck bool) {
+	c.mux.Lock()
+	defer c.mux.Unlock()
+	c.stuckDeletion = stuck
+	if stuck {
+		return
+	}
+	for providerID, instance := range c.instances {
+		if instance.state == updatev1alpha1.NodeStateTerminating {
+			providerID := providerID
+			time.AfterFunc(c.deleteLatency, func() { c.terminate(providerID) })
+		}
+	}
+}
+
+// setOnStateChange sets a callback that is invoked whenever an instance changes its state.
+func (c *fakeCloud) setOnStateChange(onStateChange func(nodeName string)) {
+	c.mux.Lock()
+	defer c.mux.Unlock()
+	c.onStateChange = onStateChange
+}
+
+// instanceCount returns the number of instances that are not terminated.
+func (c *fakeCloud) instanceCount() int {
+	c.mux.Lock()
+	defer c.mux.Unlock()
+	return len(c.instances)
+}
+
+// peakInstanceCount returns the highest number of instances that existed at the same time.
+func (c *fakeCloud) peakInstanceCount() int {
+	c.mux.Lock()
+	defer c.mux.Unlock()
+	return c.peakInstances
+}
+
+// instanceImages returns the images of all instances by provider ID.
+func (c *fakeCloud) instanceImages() map[string]string {
+	c.mux.Lock()
+	defer c.mux.Unlock()
+	images := make(map[string]string, len(c.instances))
+	for providerID, instance := range c.instances {
+		images[providerID] = instance.image
+	}
+	return images
+}
+
+// etcdMembersRemoved returns the VPC IPs of all removed etcd members.
+func (c *fakeCloud) etcdMembersRemoved() []string {
+	c.mux.Lock()
+	defer c.mux.Unlock()
+	return append([]string{}, c.removedEtcdMembers...)
+}
+
+// errors returns the errors that occurred while registering or removing Kubernetes nodes in the background.
+func (c *fakeCloud) errors() []error {
+	c.mux.Lock()
+	defer c.mux.Unlock()
+	return append([]error{}, c.errs...)
+}
+
+// newInstance creates a new instance in the creating state. The caller must hold the lock.
+func (c *fakeCloud) newInstance(scalingGroupID string) (*fakeInstance, error) {
+	group, ok := c.groups[strings.ToLower(scalingGroupID)]
+	if !ok {
+		return nil, fmt.Errorf("scaling group %q not found", scalingGroupID)
+	}
+	c.nextIndex++
+	name := fmt.Sprintf("%s-%d", strings.ToLower(group.id), c.nextIndex)
+	instance := &fakeInstance{
+		name:         name,
+		providerID:   "fake://" + name,
+		groupID:      group.id,
+		image:        group.image,
+		vpcIP:        fmt.Sprintf("192.168.%d.%d", c.nextIndex/256, c.nextIndex%256),
+		controlPlane: group.controlPlane,
+		state:        updatev1alpha1.NodeStateCreating,
+	}
+	c.instances[instance.providerID] = instance
+	if len(c.instances) > c.peakInstances {
+		c.peakInstances = len(c.instances)
+	}
+	return instance, nil
+}
+
+// boot marks an instance as ready and registers its Kubernetes node.
+func (c *fakeCloud) boot(providerID string) {
+	c.mux.Lock()
+	instance, ok := c.instances[providerID]
+	if !ok || instance.state != updatev1alpha1.NodeStateCreating {
+		c.mux.Unlock()
+		return
+	}
+	instance.state = updatev1alpha1.NodeStateReady
+	node := instance.node()
+	neverJoins := instance.neverJoins
+	c.mux.Unlock()
+
+	if !neverJoins {
+		if err := c.register(context.Background(), node); err != nil {
+			c.recordErr(err)
+		}
+	}
+	c.notify(node.Name)
+}
+
+// terminate removes a terminating instance and its Kubernetes node.
+func (c *fakeCloud) terminate(providerID string) {
+	c.mux.Lock()
+	instance, ok := c.instances[providerID]
+	if !ok || instance.state != updatev1alpha1.NodeStateTerminating || c.stuckDeletion {
+		c.mux.Unlock()
+		return
+	}
+	delete(c.instances, providerID)
+	client := c.client
+	c.mux.Unlock()
+
+	if client != nil {
+		if err := client.Delete(context.Background(), &corev1.Node{ObjectMeta: metav1.ObjectMeta{Name: instance.name}}); err != nil && !k8sErrors.IsNotFound(err) {
+			c.recordErr(err)
+		}
+	}
+	c.notify(instance.name)
+}
+
+// register creates a ready Kubernetes node.
+func (c *fakeCloud) register(ctx context.Context, node *corev1.Node) error {
+	c.mux.Lock()
+	client := c.client
+	c.mux.Unlock()
+	if client == nil {
+		return nil
+	}
+	status := node.Status
+	if err := client.Create(ctx, node); err != nil {
+		return err
+	}
+	node.Status = status
+	return client.Status().Update(ctx, node)
+}
+
+func (c *fakeCloud) notify(nodeName string) {
+	c.mux.Lock()
+	onStateChange := c.onStateChange
+	c.mux.Unlock()
+	if onStateChange != nil {
+		onStateChange(nodeName)
+	}
+}
+
+func (c *fakeCloud) recordErr(err error) {
+	c.mux.Lock()
+	defer c.mux.Unlock()
+	c.errs = append(c.errs, err)
+}
+
+// node returns the Kubernetes node an instance registers.
+func (i *fakeInstance) node() *corev1.Node {
+	labels := map[string]string{}
+	if i.controlPlane {
+		labels[controlPlaneRoleLabel] = ""
+	}
+	return &corev1.Node{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:   i.name,
+			Labels: labels,
+		},
+		Spec: corev1.NodeSpec{
+			ProviderID: i.providerID,
+		},
+		Status: corev1.NodeStatus{
+			Conditions: []corev1.NodeCondition{
+				{
+					Type:               corev1.NodeReady,
+					Status:             corev1.ConditionTrue,
+					LastTransitionTime: metav1.Now(),
+				},
+			},
+			Addresses: []corev1.NodeAddress{
+				{Type: corev1.NodeInternalIP, Address: i.vpcIP},
+			},
+		},
+	}
+}
+
+func TestFakeCloudLifecycle(t *testing.T) {
+	assert := assert.New(t)
+	require := require.New(t)
+	ctx := context.Background()
+
+	cloud := newFakeCloud(&stubWriterClient{})
+	cloud.addScalingGroup("control-plane", "image-1", true)
+	cloud.setLatency(10*time.Millisecond, 10*time.Millisecond)
+
+	existing, err := cloud.addInstance(ctx, "control-plane")
+	require.NoError(err)
+	assert.Equal("fake://control-plane-1", existing.Spec.ProviderID)
+	assert.Contains(existing.Labels, controlPlaneRoleLabel)
+	state, err := cloud.GetNodeState(ctx, existing.Spec.ProviderID)
+	require.NoError(err)
+	assert.Equal(updatev1alpha1.NodeStateReady, state)
+
+	require.NoError(cloud.SetScalingGroupImage(ctx, "control-plane", "image-2"))
+	nodeName, providerID, err := cloud.CreateNode(ctx, "control-plane")
+	require.NoError(err)
+	assert.Equal("control-plane-2", nodeName)
+	image, err := cloud.GetNodeImage(ctx, providerID)
+	require.NoError(err)
+	assert.Equal("image-2", image)
+	groupID, err := cloud.GetScalingGroupID(ctx, providerID)
+	require.NoError(err)
+	assert.Equal("control-plane", groupID)
+	assert.Eventually(func() bool {
+		state, _ := cloud.GetNodeState(ctx, providerID)
+		return state == updatev1alpha1.NodeStateReady
+	}, fakeCloudTimeout, fakeCloudInterval)
+	assert.Equal(2, cloud.peakInstanceCount())
+
+	require.NoError(cloud.DeleteNode(ctx, existing.Spec.ProviderID))
+	assert.Eventually(func() bool {
+		state, _ := cloud.GetNodeState(ctx, existing.Spec.ProviderID)
+		return state == updatev1alpha1.NodeStateTerminated
+	}, fakeCloudTimeout, fakeCloudInterval)
+	assert.Equal(1, cloud.instanceCount())
+	assert.Equal(2, cloud.peakInstanceCount())
+
+	require.NoError(cloud.RemoveEtcdMemberFromCluster(ctx, "192.168.0.1"))
+	assert.Equal([]string{"192.168.0.1"}, cloud.etcdMembersRemoved())
+	assert.Empty(cloud.errors())
+}
+
+func TestFakeCloudFailures(t *testing.T) {
+	ctx := context.Background()
+
+	t.Run("creation fails", func(t *testing.T) {
+		assert := assert.New(t)
+		cloud := newFakeCloud(nil)
+		cloud.addScalingGroup("worker", "image", false)
+		cloud.failCreations(1)
+
+		_, _, err := cloud.CreateNode(ctx, "worker")
+		assert.ErrorIs(err, errFakeCloud)
+		_, _, err = cloud.CreateNode(ctx, "worker")
+		assert.NoError(err)
+	})
+
+	t.Run("unknown scaling group", func(t *testing.T) {
+		cloud := newFakeCloud(nil)
+		_, _, err := cloud.CreateNode(ctx, "worker")
+		assert.Error(t, err)
+	})
+
+	t.Run("node never joins", func(t *testing.T) {
+		assert := assert.New(t)
+		// registering a node fails with this client, so an instance that joins records an error
+		cloud := newFakeCloud(&stubWriterClient{createErr: errors.New("create error")})
+		cloud.addScalingGroup("worker", "image", false)
+		cloud.failJoins(1)
+		booted := make(chan string, 2)
+		cloud.setOnStateChange(func(nodeName string) { booted <- nodeName })
+
+		neverJoins, _, err := cloud.CreateNode(ctx, "worker")
+		assert.NoError(err)
+		assert.Equal(neverJoins, <-booted)
+		assert.Empty(cloud.errors())
+
+		joins, _, err := cloud.CreateNode(ctx, "worker")
+		assert.NoError(err)
+		assert.Equal(joins, <-booted)
+		assert.Len(cloud.errors(), 1)
+	})
+
+	t.Run("deletion is stuck", func(t *testing.T) {
+		assert := assert.New(t)
+		require := require.New(t)
+		cloud := newFakeCloud(nil)
+		cloud.addScalingGroup("worker", "image", false)
+		node, err := cloud.addInstance(ctx, "worker")
+		require.NoError(err)
+		cloud.setStuckDeletion(true)
+
+		require.NoError(cloud.DeleteNode(ctx, node.Spec.ProviderID))
+		assert.Never(func() bool {
+			state, _ := cloud.GetNodeState(ctx, node.Spec.ProviderID)
+			return state != updatev1alpha1.NodeStateTerminating
+		}, 50*time.Millisecond, fakeCloudInterval)
+
+		cloud.setStuckDeletion(false)
+		assert.Eventually(func() bool {
+			state, _ := cloud.GetNodeState(ctx, node.Spec.ProviderID)
+			return state == updatev1alpha1.NodeStateTerminated
+		}, fakeCloudTimeout, fakeCloudInterval)
+	})
+}
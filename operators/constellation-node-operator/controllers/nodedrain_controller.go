@@ -0,0 +1,586 @@
+/*
+Copyright (c) Edgeless Systems GmbH
+
+SPDX-License-Identifier: AGPL-3.0-only
+*/
+
+package controllers
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/edgelesssys/constellation/operators/constellation-node-operator/v2/internal/constants"
+	"github.com/edgelesssys/constellation/operators/constellation-node-operator/v2/internal/drain"
+	"go.uber.org/multierr"
+	batchv1 "k8s.io/api/batch/v1"
+	corev1 "k8s.io/api/core/v1"
+	k8serrors "k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/api/meta"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/client-go/util/retry"
+	"k8s.io/utils/clock"
+	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/builder"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/controller/controllerutil"
+	"sigs.k8s.io/controller-runtime/pkg/handler"
+	"sigs.k8s.io/controller-runtime/pkg/log"
+	"sigs.k8s.io/controller-runtime/pkg/source"
+
+	updatev1alpha1 "github.com/edgelesssys/constellation/operators/constellation-node-operator/v2/api/v1alpha1"
+)
+
+const (
+	// nodeDrainFinalizer makes sure a node is uncordoned before its NodeDrain is removed.
+	nodeDrainFinalizer = "update.edgeless.systems/uncordon"
+	// drainHookNamespace is the namespace of the jobs run as drain hooks.
+	drainHookNamespace = constants.DrainHookNamespace
+	// drainHookLabel is set on drain hook jobs to the phase and name of the hook.
+	drainHookLabel = "update.edgeless.systems/drain-hook"
+	// drainAttemptLabel is set on drain hook jobs to the number of retries of the drain that started them.
+	drainAttemptLabel = "update.edgeless.systems/drain-attempt"
+	// drainHookServiceAccountAllowed is the value of the label that allows drain hook jobs to run as a service account.
+	drainHookServiceAccountAllowed = "allowed"
+	// defaultDrainRetryInterval is the time to wait before retrying a failed drain if no retry interval is configured.
+	defaultDrainRetryInterval = 5 * time.Minute
+	// drainHookUserID is the unprivileged user drain hook jobs run as.
+	drainHookUserID = 65532
+	// drainHookBackoffLimit is the default number of retries of a failed drain hook job.
+	drainHookBackoffLimit = 2
+	// drainHookRetryInterval is the time to wait before checking a drain hook again.
+	drainHookRetryInterval = 10 * time.Second
+	// evictionRetryInterval is the time to wait before evicting the remaining pods of a node again.
+	evictionRetryInterval = 5 * time.Second
+
+	conditionDrainTimeoutReason          = "Timeout"
+	conditionDrainHookFailedReason       = "HookFailed"
+	conditionEvictionBlockedReason       = "EvictionRejected"
+	conditionEvictionAllowedReason       = "EvictionAllowed"
+	conditionEvictionAllowedMessage      = "No eviction is rejected"
+	conditionEvictionBlockedTemplate     = "Eviction of %d pods is rejected, e.g. because of a PodDisruptionBudget"
+	conditionDrainTimeoutMessageTemplate = "Node was not drained within %s"
+)
+
+var (
+	errDrainHookFailed = errors.New("drain hook failed")
+	// errNodeDrainFailed is returned by controllers waiting for a NodeDrain that failed.
+	errNodeDrainFailed = errors.New("draining node failed")
+)
+
+// NodeDrainReconciler reconciles a NodeDrain object.
+type NodeDrainReconciler struct {
+	nodeDrainer
+	client.Client
+	Scheme *runtime.Scheme
+	clock.Clock
+}
+
+// NewNodeDrainReconciler creates a new NodeDrainReconciler.
+func NewNodeDrainReconciler(nodeDrainer nodeDrainer, client client.Client, scheme *runtime.Scheme) *NodeDrainReconciler {
+	return &NodeDrainReconciler{
+		nodeDrainer: nodeDrainer,
+		Client:      client,
+		Scheme:      scheme,
+		Clock:       clock.RealClock{},
+	}
+}
+
+//+kubebuilder:rbac:groups=update.edgeless.systems,resources=nodedrains,verbs=get;list;watch;create;update;patch;delete
+//+kubebuilder:rbac:groups=update.edgeless.systems,resources=nodedrains/status,verbs=get;update;patch
+//+kubebuilder:rbac:groups=update.edgeless.systems,resources=nodedrains/finalizers,verbs=update
+//+kubebuilder:rbac:groups="",resources=nodes,verbs=get;list;watch;patch
+//+kubebuilder:rbac:groups="",resources=pods,verbs=get;list
+//+kubebuilder:rbac:groups="",resources=pods/eviction,verbs=create
+//+kubebuilder:rbac:groups=batch,resources=jobs,verbs=get;list;watch;create;delete
+//+kubebuilder:rbac:groups="",resources=serviceaccounts,verbs=get;list;watch
+
+// Reconcile cordons and drains the node referenced in the NodeDrain spec.
+//
+// After cordoning the node, the pre-drain hooks are run. Afterwards, pods are evicted using the eviction API,
+// so PodDisruptionBudgets are honoured. Rejected evictions are retried and reported in the status.
+// Once all pods are evicted, the post-drain hooks are run. The node is uncordoned when the NodeDrain is deleted.
+// A failed drain is started over after the retry interval if the failure policy is "Retry".
+func (r *NodeDrainReconciler) Reconcile(ctx context.Context, req ctrl.Request) (ctrl.Result, error) {
+	logr := log.FromContext(ctx)
+
+	var nodeDrain updatev1alpha1.NodeDrain
+	if err := r.Get(ctx, req.NamespacedName, &nodeDrain); err != nil {
+		return ctrl.Result{}, client.IgnoreNotFound(err)
+	}
+	if !nodeDrain.DeletionTimestamp.IsZero() {
+		return ctrl.Result{}, r.finalize(ctx, &nodeDrain)
+	}
+	if !controllerutil.ContainsFinalizer(&nodeDrain, nodeDrainFinalizer) {
+		controllerutil.AddFinalizer(&nodeDrain, nodeDrainFinalizer)
+		return ctrl.Result{}, r.Update(ctx, &nodeDrain)
+	}
+
+	if err := r.Get(ctx, types.NamespacedName{Name: nodeDrain.Spec.NodeName}, &corev1.Node{}); err != nil {
+		if !k8serrors.IsNotFound(err) {
+			logr.Error(err, "Unable to get node", "drainNode", nodeDrain.Spec.NodeName)
+			return ctrl.Result{}, err
+		}
+		// the node was removed from the cluster, so there is nothing left to drain
+		logr.Info("Removing NodeDrain of deleted node", "drainNode", nodeDrain.Spec.NodeName)
+		return ctrl.Result{}, client.IgnoreNotFound(r.Delete(ctx, &nodeDrain))
+	}
+
+	status := *nodeDrain.Status.DeepCopy()
+	result, drainErr := r.drain(ctx, &nodeDrain, &status)
+	if err := r.tryUpdateStatus(ctx, req.NamespacedName, status); err != nil {
+		logr.Error(err, "Updating status")
+		return ctrl.Result{}, client.IgnoreNotFound(err)
+	}
+	if drainErr != nil {
+		logr.Error(drainErr, "Draining node", "drainNode", nodeDrain.Spec.NodeName)
+	}
+	return result, drainErr
+}
+
+// SetupWithManager sets up the controller with the Manager.
+func (r *NodeDrainReconciler) SetupWithManager(mgr ctrl.Manager) error {
+	// index node drains by node name.
+	if err := mgr.GetFieldIndexer().IndexField(context.Background(), &updatev1alpha1.NodeDrain{}, nodeNameKey, func(rawObj client.Object) []string {
+		nodeDrain := rawObj.(*updatev1alpha1.NodeDrain)
+		return []string{nodeDrain.Spec.NodeName}
+	}); err != nil {
+		return err
+	}
+
+	return ctrl.NewControllerManagedBy(mgr).
+		For(&updatev1alpha1.NodeDrain{}).
+		Watches(
+			&source.Kind{Type: &corev1.Node{}},
+			handler.EnqueueRequestsFromMapFunc(r.findObjectsForNode),
+			builder.WithPredicates(nodeDeletedPredicate()),
+		).
+		Owns(&batchv1.Job{}).
+		Complete(r)
+}
+
+// drain advances draining the node through its phases until it has to wait for hooks or evictions.
+// The status is updated in place.
+func (r *NodeDrainReconciler) drain(ctx context.Context, nodeDrain *updatev1alpha1.NodeDrain, status *updatev1alpha1.NodeDrainStatus) (ctrl.Result, error) {
+	switch status.Phase {
+	case updatev1alpha1.NodeDrainPhaseSucceeded:
+		return ctrl.Result{}, nil
+	case updatev1alpha1.NodeDrainPhaseFailed:
+		if nodeDrain.Spec.FailurePolicy != updatev1alpha1.DrainFailurePolicyRetry {
+			return ctrl.Result{}, nil
+		}
+		if wait := r.drainRetryWait(nodeDrain, status); wait > 0 {
+			return ctrl.Result{RequeueAfter: wait}, nil
+		}
+		if err := r.deleteHookJobs(ctx, nodeDrain); err != nil {
+			return ctrl.Result{}, err
+		}
+		log.FromContext(ctx).Info("Retrying failed drain", "drainNode", nodeDrain.Spec.NodeName, "retries", status.Retries+1)
+		resetDrainStatus(status)
+		fallthrough
+	case "":
+		startTime := metav1.NewTime(r.Now())
+		status.StartTime = &startTime
+		status.Phase = updatev1alpha1.NodeDrainPhasePreDrain
+	}
+
+	if err := r.setUnschedulable(ctx, nodeDrain.Spec.NodeName, true); err != nil {
+		return ctrl.Result{}, err
+	}
+	if timeout := nodeDrain.Spec.Timeout; timeout != nil && status.StartTime != nil &&
+		!r.Now().Before(status.StartTime.Add(timeout.Duration)) {
+		setDrainFailed(status, r.Now(), conditionDrainTimeoutReason, fmt.Sprintf(conditionDrainTimeoutMessageTemplate, timeout.Duration))
+		return ctrl.Result{}, nil
+	}
+
+	for {
+		switch status.Phase {
+		case updatev1alpha1.NodeDrainPhasePreDrain:
+			done, err := r.runHooks(ctx, nodeDrain, status, updatev1alpha1.NodeDrainPhasePreDrain, nodeDrain.Spec.PreDrainHooks)
+			if errors.Is(err, errDrainHookFailed) {
+				setDrainFailed(status, r.Now(), conditionDrainHookFailedReason, err.Error())
+				return ctrl.Result{}, nil
+			}
+			if err != nil || !done {
+				return ctrl.Result{RequeueAfter: drainHookRetryInterval}, err
+			}
+			status.Phase = updatev1alpha1.NodeDrainPhaseEvicting
+		case updatev1alpha1.NodeDrainPhaseEvicting:
+			remaining, err := r.evictPods(ctx, nodeDrain, status)
+			if err != nil || remaining > 0 {
+				return ctrl.Result{RequeueAfter: evictionRetryInterval}, err
+			}
+			status.Phase = updatev1alpha1.NodeDrainPhasePostDrain
+		case updatev1alpha1.NodeDrainPhasePostDrain:
+			done, err := r.runHooks(ctx, nodeDrain, status, updatev1alpha1.NodeDrainPhasePostDrain, nodeDrain.Spec.PostDrainHooks)
+			if errors.Is(err, errDrainHookFailed) {
+				setDrainFailed(status, r.Now(), conditionDrainHookFailedReason, err.Error())
+				return ctrl.Result{}, nil
+			}
+			if err != nil || !done {
+				return ctrl.Result{RequeueAfter: drainHookRetryInterval}, err
+			}
+			status.Phase = updatev1alpha1.NodeDrainPhaseSucceeded
+		default:
+			return ctrl.Result{}, nil
+		}
+	}
+}
+
+// runHooks runs the hooks of a phase one after another and records completed hooks in the status.
+// It returns true once all hooks completed.
+func (r *NodeDrainReconciler) runHooks(
+	ctx context.Context, nodeDrain *updatev1alpha1.NodeDrain, status *updatev1alpha1.NodeDrainStatus,
+	phase updatev1alpha1.NodeDrainPhase, hooks []updatev1alpha1.DrainHook,
+) (bool, error) {
+	logr := log.FromContext(ctx)
+	for _, hook := range hooks {
+		key := drainHookKey(phase, hook.Name)
+		if hookCompleted(status.CompletedHooks, key) {
+			continue
+		}
+		var done bool
+		switch {
+		case hook.Webhook != nil:
+			request := drain.WebhookRequest{
+				NodeName: nodeDrain.Spec.NodeName,
+				Reason:   nodeDrain.Spec.Reason,
+				Phase:    string(phase),
+			}
+			if err := r.CallWebhook(ctx, hook.Webhook.URL, request); err != nil {
+				// webhooks are retried until they succeed or the drain times out
+				logr.Info("Drain webhook did not succeed", "drainHook", key, "error", err.Error())
+				return false, nil
+			}
+			done = true
+		case hook.Job != nil:
+			var err error
+			done, err = r.runJobHook(ctx, nodeDrain, status.Retries, phase, hook)
+			if err != nil {
+				return false, err
+			}
+		default:
+			return false, fmt.Errorf("%w: hook %s specifies neither a webhook nor a job", errDrainHookFailed, key)
+		}
+		if !done {
+			logr.Info("Waiting for drain hook", "drainHook", key)
+			return false, nil
+		}
+		logr.Info("Drain hook completed", "drainHook", key)
+		status.CompletedHooks = append(status.CompletedHooks, key)
+	}
+	return true, nil
+}
+
+// runJobHook starts the job of a drain hook and returns true once it completed.
+// Jobs started by earlier attempts of the drain are ignored.
+// An error wrapping errDrainHookFailed is returned if the job failed or its service account is not allowed to run drain hooks.
+func (r *NodeDrainReconciler) runJobHook(
+	ctx context.Context, nodeDrain *updatev1alpha1.NodeDrain, attempt int32,
+	phase updatev1alpha1.NodeDrainPhase, hook updatev1alpha1.DrainHook,
+) (bool, error) {
+	key := drainHookKey(phase, hook.Name)
+	var jobList batchv1.JobList
+	if err := r.List(ctx, &jobList, client.InNamespace(drainHookNamespace), client.MatchingLabels{
+		drainHookLabel:    key,
+		drainAttemptLabel: strconv.Itoa(int(attempt)),
+	}); err != nil {
+		return false, err
+	}
+	for i := range jobList.Items {
+		job := &jobList.Items[i]
+		if !metav1.IsControlledBy(job, nodeDrain) {
+			continue
+		}
+		if jobFailed(job) {
+			return false, fmt.Errorf("%w: job %s of hook %s failed", errDrainHookFailed, job.Name, key)
+		}
+		return jobComplete(job), nil
+	}
+
+	if err := r.checkHookServiceAccount(ctx, hook.Job.ServiceAccountName); err != nil {
+		return false, fmt.Errorf("hook %s: %w", key, err)
+	}
+	job := newDrainHookJob(nodeDrain, attempt, phase, hook)
+	if err := ctrl.SetControllerReference(nodeDrain, job, r.Scheme); err != nil {
+		return false, err
+	}
+	return false, r.Create(ctx, job)
+}
+
+// checkHookServiceAccount makes sure drain hook jobs only run as service accounts that were explicitly allowed to do so.
+// Otherwise, anyone able to configure drain hooks could use the permissions of any service account in the hook namespace.
+func (r *NodeDrainReconciler) checkHookServiceAccount(ctx context.Context, name string) error {
+	if name == "" {
+		return nil
+	}
+	var serviceAccount corev1.ServiceAccount
+	err := r.Get(ctx, types.NamespacedName{Namespace: drainHookNamespace, Name: name}, &serviceAccount)
+	if k8serrors.IsNotFound(err) {
+		return fmt.Errorf("%w: service account %s does not exist in namespace %s", errDrainHookFailed, name, drainHookNamespace)
+	}
+	if err != nil {
+		return err
+	}
+	if serviceAccount.Labels[constants.DrainHookServiceAccountLabel] != drainHookServiceAccountAllowed {
+		return fmt.Errorf("%w: service account %s is not allowed to run drain hooks, label it with %s=%s",
+			errDrainHookFailed, name, constants.DrainHookServiceAccountLabel, drainHookServiceAccountAllowed)
+	}
+	return nil
+}
+
+// deleteHookJobs deletes the drain hook jobs started for a NodeDrain.
+func (r *NodeDrainReconciler) deleteHookJobs(ctx context.Context, nodeDrain *updatev1alpha1.NodeDrain) error {
+	var jobList batchv1.JobList
+	if err := r.List(ctx, &jobList, client.InNamespace(drainHookNamespace), client.HasLabels{drainHookLabel}); err != nil {
+		return err
+	}
+	for i := range jobList.Items {
+		job := &jobList.Items[i]
+		if !metav1.IsControlledBy(job, nodeDrain) {
+			continue
+		}
+		if err := r.Delete(ctx, job, client.PropagationPolicy(metav1.DeletePropagationBackground)); client.IgnoreNotFound(err) != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// drainRetryWait returns the time left until a failed drain is retried.
+func (r *NodeDrainReconciler) drainRetryWait(nodeDrain *updatev1alpha1.NodeDrain, status *updatev1alpha1.NodeDrainStatus) time.Duration {
+	retryInterval := defaultDrainRetryInterval
+	if nodeDrain.Spec.RetryInterval != nil {
+		retryInterval = nodeDrain.Spec.RetryInterval.Duration
+	}
+	cond := meta.FindStatusCondition(status.Conditions, updatev1alpha1.ConditionDrainFailed)
+	if cond == nil {
+		return 0
+	}
+	return cond.LastTransitionTime.Add(retryInterval).Sub(r.Now())
+}
+
+// evictPods requests the eviction of all pods remaining on the node and records rejected evictions in the status.
+// It returns the number of pods remaining on the node.
+func (r *NodeDrainReconciler) evictPods(ctx context.Context, nodeDrain *updatev1alpha1.NodeDrain, status *updatev1alpha1.NodeDrainStatus) (int, error) {
+	pods, err := r.PodsToEvict(ctx, nodeDrain.Spec.NodeName)
+	if err != nil {
+		return 0, err
+	}
+	status.RemainingPods = int32(len(pods))
+	status.BlockedEvictions = nil
+
+	var evictErr error
+	for _, pod := range pods {
+		if pod.DeletionTimestamp != nil {
+			// pod is already terminating
+			continue
+		}
+		err := r.EvictPod(ctx, pod, nodeDrain.Spec.GracePeriodSeconds)
+		switch {
+		case errors.Is(err, drain.ErrEvictionBlocked):
+			status.BlockedEvictions = append(status.BlockedEvictions, updatev1alpha1.BlockedEviction{
+				Namespace: pod.Namespace,
+				Name:      pod.Name,
+				Message:   err.Error(),
+			})
+		case err != nil:
+			evictErr = multierr.Append(evictErr, fmt.Errorf("evicting pod %s/%s: %w", pod.Namespace, pod.Name, err))
+		}
+	}
+	meta.SetStatusCondition(&status.Conditions, evictionBlockedCondition(len(status.BlockedEvictions)))
+	return len(pods), evictErr
+}
+
+// finalize uncordons the node and removes the finalizer of a deleted NodeDrain.
+func (r *NodeDrainReconciler) finalize(ctx context.Context, nodeDrain *updatev1alpha1.NodeDrain) error {
+	if !controllerutil.ContainsFinalizer(nodeDrain, nodeDrainFinalizer) {
+		return nil
+	}
+	if err := r.setUnschedulable(ctx, nodeDrain.Spec.NodeName, false); client.IgnoreNotFound(err) != nil {
+		return err
+	}
+	controllerutil.RemoveFinalizer(nodeDrain, nodeDrainFinalizer)
+	return r.Update(ctx, nodeDrain)
+}
+
+// setUnschedulable cordons or uncordons a node.
+func (r *NodeDrainReconciler) setUnschedulable(ctx context.Context, nodeName string, unschedulable bool) error {
+	var node corev1.Node
+	if err := r.Get(ctx, types.NamespacedName{Name: nodeName}, &node); err != nil {
+		return err
+	}
+	if node.Spec.Unschedulable == unschedulable {
+		return nil
+	}
+	patchedNode := node.DeepCopy()
+	patchedNode.Spec.Unschedulable = unschedulable
+	return r.Patch(ctx, patchedNode, client.MergeFrom(&node))
+}
+
+// tryUpdateStatus attempts to update the NodeDrain status field in a retry loop.
+func (r *NodeDrainReconciler) tryUpdateStatus(ctx context.Context, name types.NamespacedName, status updatev1alpha1.NodeDrainStatus) error {
+	return retry.RetryOnConflict(retry.DefaultRetry, func() error {
+		var nodeDrain updatev1alpha1.NodeDrain
+		if err := r.Get(ctx, name, &nodeDrain); err != nil {
+			return err
+		}
+		nodeDrain.Status = *status.DeepCopy()
+		return r.Status().Update(ctx, &nodeDrain)
+	})
+}
+
+// newNodeDrain returns a NodeDrain for a node using the given drain configuration.
+func newNodeDrain(nodeName, reason string, config *updatev1alpha1.DrainConfig) *updatev1alpha1.NodeDrain {
+	nodeDrain := &updatev1alpha1.NodeDrain{
+		ObjectMeta: metav1.ObjectMeta{
+			Name: nodeName,
+		},
+		Spec: updatev1alpha1.NodeDrainSpec{
+			NodeName: nodeName,
+			Reason:   reason,
+		},
+	}
+	if config != nil {
+		nodeDrain.Spec.DrainConfig = *config.DeepCopy()
+	}
+	return nodeDrain
+}
+
+// newDrainHookJob returns the job of a drain hook.
+// The job complies with the restricted Pod Security Standard enforced in the hook namespace.
+// A service account token is only mounted if the hook runs as a dedicated service account.
+func newDrainHookJob(nodeDrain *updatev1alpha1.NodeDrain, attempt int32, phase updatev1alpha1.NodeDrainPhase, hook updatev1alpha1.DrainHook) *batchv1.Job {
+	key := drainHookKey(phase, hook.Name)
+	backoffLimit := int32(drainHookBackoffLimit)
+	if hook.Job.BackoffLimit != nil {
+		backoffLimit = *hook.Job.BackoffLimit
+	}
+	automountToken := hook.Job.ServiceAccountName != ""
+	runAsNonRoot := true
+	runAsUser := int64(drainHookUserID)
+	allowPrivilegeEscalation := false
+	return &batchv1.Job{
+		ObjectMeta: metav1.ObjectMeta{
+			GenerateName: key + "-",
+			Namespace:    drainHookNamespace,
+			Labels: map[string]string{
+				drainHookLabel:    key,
+				drainAttemptLabel: strconv.Itoa(int(attempt)),
+			},
+		},
+		Spec: batchv1.JobSpec{
+			BackoffLimit: &backoffLimit,
+			Template: corev1.PodTemplateSpec{
+				Spec: corev1.PodSpec{
+					RestartPolicy:                corev1.RestartPolicyNever,
+					ServiceAccountName:           hook.Job.ServiceAccountName,
+					AutomountServiceAccountToken: &automountToken,
+					SecurityContext: &corev1.PodSecurityContext{
+						RunAsNonRoot:   &runAsNonRoot,
+						RunAsUser:      &runAsUser,
+						RunAsGroup:     &runAsUser,
+						SeccompProfile: &corev1.SeccompProfile{Type: corev1.SeccompProfileTypeRuntimeDefault},
+					},
+					Containers: []corev1.Container{
+						{
+							Name:    "hook",
+							Image:   hook.Job.Image,
+							Command: hook.Job.Command,
+							Args:    hook.Job.Args,
+							Env: []corev1.EnvVar{
+								{Name: "NODE_NAME", Value: nodeDrain.Spec.NodeName},
+								{Name: "DRAIN_REASON", Value: nodeDrain.Spec.Reason},
+								{Name: "DRAIN_PHASE", Value: string(phase)},
+							},
+							SecurityContext: &corev1.SecurityContext{
+								AllowPrivilegeEscalation: &allowPrivilegeEscalation,
+								Capabilities:             &corev1.Capabilities{Drop: []corev1.Capability{"ALL"}},
+							},
+						},
+					},
+				},
+			},
+		},
+	}
+}
+
+// drainHookKey identifies a hook by its phase and name.
+// The key is used in the status of NodeDrains and as label value of hook jobs.
+func drainHookKey(phase updatev1alpha1.NodeDrainPhase, name string) string {
+	return strings.ToLower(string(phase)) + "-" + name
+}
+
+// hookCompleted checks if a hook is in the list of completed hooks.
+func hookCompleted(completedHooks []string, key string) bool {
+	for _, completed := range completedHooks {
+		if completed == key {
+			return true
+		}
+	}
+	return false
+}
+
+// jobComplete checks if a job completed successfully.
+func jobComplete(job *batchv1.Job) bool {
+	for _, cond := range job.Status.Conditions {
+		if cond.Type == batchv1.JobComplete && cond.Status == corev1.ConditionTrue {
+			return true
+		}
+	}
+	return false
+}
+
+// setDrainFailed marks draining the node as failed.
+func setDrainFailed(status *updatev1alpha1.NodeDrainStatus, now time.Time, reason, message string) {
+	status.Phase = updatev1alpha1.NodeDrainPhaseFailed
+	meta.SetStatusCondition(&status.Conditions, metav1.Condition{
+		Type:               updatev1alpha1.ConditionDrainFailed,
+		Status:             metav1.ConditionTrue,
+		LastTransitionTime: metav1.NewTime(now),
+		Reason:             reason,
+		Message:            message,
+	})
+}
+
+// resetDrainStatus prepares the status of a failed drain for another attempt.
+func resetDrainStatus(status *updatev1alpha1.NodeDrainStatus) {
+	status.Phase = ""
+	status.StartTime = nil
+	status.CompletedHooks = nil
+	status.Retries++
+	meta.RemoveStatusCondition(&status.Conditions, updatev1alpha1.ConditionDrainFailed)
+}
+
+// evictionBlockedCondition returns the EvictionBlocked condition given the number of rejected evictions.
+func evictionBlockedCondition(blocked int) metav1.Condition {
+	if blocked == 0 {
+		return metav1.Condition{
+			Type:    updatev1alpha1.ConditionEvictionBlocked,
+			Status:  metav1.ConditionFalse,
+			Reason:  conditionEvictionAllowedReason,
+			Message: conditionEvictionAllowedMessage,
+		}
+	}
+	return metav1.Condition{
+		Type:    updatev1alpha1.ConditionEvictionBlocked,
+		Status:  metav1.ConditionTrue,
+		Reason:  conditionEvictionBlockedReason,
+		Message: fmt.Sprintf(conditionEvictionBlockedTemplate, blocked),
+	}
+}
+
+type nodeDrainer interface {
+	// PodsToEvict returns the pods that have to be evicted from a node.
+	PodsToEvict(ctx context.Context, nodeName string) ([]corev1.Pod, error)
+	// EvictPod evicts a pod using the eviction API.
+	EvictPod(ctx context.Context, pod corev1.Pod, gracePeriodSeconds *int64) error
+	// CallWebhook calls a drain webhook.
+	CallWebhook(ctx context.Context, url string, request drain.WebhookRequest) error
+}
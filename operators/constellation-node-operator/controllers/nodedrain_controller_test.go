@@ -0,0 +1,444 @@
+/*
+Copyright (c) Edgeless Systems GmbH
+
+SPDX-License-Identifier: AGPL-3.0-only
+*/
+
+package controllers
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	batchv1 "k8s.io/api/batch/v1"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/meta"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	testclock "k8s.io/utils/clock/testing"
+	ctrl "sigs.k8s.io/controller-runtime"
+
+	updatev1alpha1 "github.com/edgelesssys/constellation/operators/constellation-node-operator/v2/api/v1alpha1"
+	"github.com/edgelesssys/constellation/operators/constellation-node-operator/v2/internal/constants"
+	"github.com/edgelesssys/constellation/operators/constellation-node-operator/v2/internal/drain"
+)
+
+func TestNodeDrainDrain(t *testing.T) {
+	someErr := errors.New("failed")
+	now := time.Date(2022, 9, 1, 12, 0, 0, 0, time.UTC)
+	nodeDrain := &updatev1alpha1.NodeDrain{
+		ObjectMeta: metav1.ObjectMeta{Name: "node", UID: "uid"},
+		Spec:       updatev1alpha1.NodeDrainSpec{NodeName: "node", Reason: "reason"},
+	}
+	failedJob := &batchv1.Job{
+		ObjectMeta: metav1.ObjectMeta{Name: "postdrain-backup-abcde", Namespace: drainHookNamespace},
+		Status: batchv1.JobStatus{Conditions: []batchv1.JobCondition{
+			{Type: batchv1.JobFailed, Status: corev1.ConditionTrue},
+		}},
+	}
+	require.NoError(t, ctrl.SetControllerReference(nodeDrain, failedJob, getScheme(t)))
+	allowedServiceAccount := &corev1.ServiceAccount{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      "backup",
+			Namespace: drainHookNamespace,
+			Labels:    map[string]string{constants.DrainHookServiceAccountLabel: drainHookServiceAccountAllowed},
+		},
+	}
+	failedStatus := func(failedAt time.Time) updatev1alpha1.NodeDrainStatus {
+		return updatev1alpha1.NodeDrainStatus{
+			Phase:          updatev1alpha1.NodeDrainPhaseFailed,
+			StartTime:      &metav1.Time{Time: failedAt.Add(-time.Hour)},
+			CompletedHooks: []string{"predrain-notify"},
+			Conditions: []metav1.Condition{{
+				Type:               updatev1alpha1.ConditionDrainFailed,
+				Status:             metav1.ConditionTrue,
+				LastTransitionTime: metav1.NewTime(failedAt),
+				Reason:             conditionDrainTimeoutReason,
+			}},
+		}
+	}
+	retryPolicy := updatev1alpha1.DrainConfig{
+		FailurePolicy: updatev1alpha1.DrainFailurePolicyRetry,
+		RetryInterval: &metav1.Duration{Duration: 10 * time.Minute},
+	}
+
+	testCases := map[string]struct {
+		spec         updatev1alpha1.DrainConfig
+		status       updatev1alpha1.NodeDrainStatus
+		pods         []corev1.Pod
+		webhookErr   error
+		jobs         []runtime.Object
+		patchErr     error
+		wantPhase    updatev1alpha1.NodeDrainPhase
+		wantRequeue  time.Duration
+		wantFailed   string
+		wantRetries  int32
+		wantErr      bool
+		wantEvicted  []string
+		wantComplete []string
+	}{
+		"node without pods is drained": {
+			wantPhase: updatev1alpha1.NodeDrainPhaseSucceeded,
+		},
+		"pods are evicted": {
+			pods:        []corev1.Pod{testPod("default", "a"), testPod("default", "b")},
+			wantPhase:   updatev1alpha1.NodeDrainPhaseEvicting,
+			wantRequeue: evictionRetryInterval,
+			wantEvicted: []string{"default/a", "default/b"},
+		},
+		"hooks are run": {
+			spec: updatev1alpha1.DrainConfig{
+				PreDrainHooks:  []updatev1alpha1.DrainHook{{Name: "notify", Webhook: &updatev1alpha1.WebhookDrainHook{URL: "http://hook"}}},
+				PostDrainHooks: []updatev1alpha1.DrainHook{{Name: "notify", Webhook: &updatev1alpha1.WebhookDrainHook{URL: "http://hook"}}},
+			},
+			wantPhase:    updatev1alpha1.NodeDrainPhaseSucceeded,
+			wantComplete: []string{"predrain-notify", "postdrain-notify"},
+		},
+		"webhook is retried": {
+			spec: updatev1alpha1.DrainConfig{
+				PreDrainHooks: []updatev1alpha1.DrainHook{{Name: "notify", Webhook: &updatev1alpha1.WebhookDrainHook{URL: "http://hook"}}},
+			},
+			webhookErr:  someErr,
+			wantPhase:   updatev1alpha1.NodeDrainPhasePreDrain,
+			wantRequeue: drainHookRetryInterval,
+		},
+		"job hook is started": {
+			spec: updatev1alpha1.DrainConfig{
+				PostDrainHooks: []updatev1alpha1.DrainHook{{Name: "backup", Job: &updatev1alpha1.JobDrainHook{Image: "image"}}},
+			},
+			wantPhase:   updatev1alpha1.NodeDrainPhasePostDrain,
+			wantRequeue: drainHookRetryInterval,
+		},
+		"job hook with allowed service account is started": {
+			spec: updatev1alpha1.DrainConfig{
+				PostDrainHooks: []updatev1alpha1.DrainHook{{Name: "backup", Job: &updatev1alpha1.JobDrainHook{Image: "image", ServiceAccountName: "backup"}}},
+			},
+			jobs:        []runtime.Object{allowedServiceAccount},
+			wantPhase:   updatev1alpha1.NodeDrainPhasePostDrain,
+			wantRequeue: drainHookRetryInterval,
+		},
+		"job hook with service account that is not allowed fails drain": {
+			spec: updatev1alpha1.DrainConfig{
+				PostDrainHooks: []updatev1alpha1.DrainHook{{Name: "backup", Job: &updatev1alpha1.JobDrainHook{Image: "image", ServiceAccountName: "other"}}},
+			},
+			jobs:       []runtime.Object{allowedServiceAccount},
+			wantPhase:  updatev1alpha1.NodeDrainPhaseFailed,
+			wantFailed: conditionDrainHookFailedReason,
+		},
+		"failed job hook fails drain": {
+			spec: updatev1alpha1.DrainConfig{
+				PostDrainHooks: []updatev1alpha1.DrainHook{{Name: "backup", Job: &updatev1alpha1.JobDrainHook{Image: "image"}}},
+			},
+			jobs:       []runtime.Object{failedJob},
+			wantPhase:  updatev1alpha1.NodeDrainPhaseFailed,
+			wantFailed: conditionDrainHookFailedReason,
+		},
+		"hook without action fails drain": {
+			spec: updatev1alpha1.DrainConfig{
+				PreDrainHooks: []updatev1alpha1.DrainHook{{Name: "empty"}},
+			},
+			wantPhase:  updatev1alpha1.NodeDrainPhaseFailed,
+			wantFailed: conditionDrainHookFailedReason,
+		},
+		"completed hooks are skipped": {
+			spec: updatev1alpha1.DrainConfig{
+				PreDrainHooks: []updatev1alpha1.DrainHook{{Name: "notify", Webhook: &updatev1alpha1.WebhookDrainHook{URL: "http://hook"}}},
+			},
+			status: updatev1alpha1.NodeDrainStatus{
+				Phase:          updatev1alpha1.NodeDrainPhasePreDrain,
+				StartTime:      &metav1.Time{Time: now},
+				CompletedHooks: []string{"predrain-notify"},
+			},
+			webhookErr:   someErr,
+			wantPhase:    updatev1alpha1.NodeDrainPhaseSucceeded,
+			wantComplete: []string{"predrain-notify"},
+		},
+		"timeout is exceeded": {
+			spec: updatev1alpha1.DrainConfig{Timeout: &metav1.Duration{Duration: time.Hour}},
+			status: updatev1alpha1.NodeDrainStatus{
+				Phase:     updatev1alpha1.NodeDrainPhaseEvicting,
+				StartTime: &metav1.Time{Time: now.Add(-2 * time.Hour)},
+			},
+			pods:       []corev1.Pod{testPod("default", "a")},
+			wantPhase:  updatev1alpha1.NodeDrainPhaseFailed,
+			wantFailed: conditionDrainTimeoutReason,
+		},
+		"failed drain is not retried": {
+			status: updatev1alpha1.NodeDrainStatus{
+				Phase:     updatev1alpha1.NodeDrainPhaseFailed,
+				StartTime: &metav1.Time{Time: now},
+			},
+			wantPhase: updatev1alpha1.NodeDrainPhaseFailed,
+		},
+		"failed drain waits for retry": {
+			spec:        retryPolicy,
+			status:      failedStatus(now.Add(-4 * time.Minute)),
+			wantPhase:   updatev1alpha1.NodeDrainPhaseFailed,
+			wantRequeue: 6 * time.Minute,
+			wantFailed:  conditionDrainTimeoutReason,
+		},
+		"failed drain is retried": {
+			spec:        retryPolicy,
+			status:      failedStatus(now.Add(-10 * time.Minute)),
+			wantPhase:   updatev1alpha1.NodeDrainPhaseSucceeded,
+			wantRetries: 1,
+		},
+		"failed drain is retried after default interval": {
+			spec:        updatev1alpha1.DrainConfig{FailurePolicy: updatev1alpha1.DrainFailurePolicyRetry},
+			status:      failedStatus(now.Add(-time.Minute)),
+			wantPhase:   updatev1alpha1.NodeDrainPhaseFailed,
+			wantRequeue: defaultDrainRetryInterval - time.Minute,
+			wantFailed:  conditionDrainTimeoutReason,
+		},
+		"forced failed drain is not retried": {
+			spec:      updatev1alpha1.DrainConfig{FailurePolicy: updatev1alpha1.DrainFailurePolicyForce},
+			status:    failedStatus(now.Add(-time.Hour)),
+			wantPhase: updatev1alpha1.NodeDrainPhaseFailed,
+		},
+		"cordoning fails": {
+			patchErr:  someErr,
+			wantPhase: updatev1alpha1.NodeDrainPhasePreDrain,
+			wantErr:   true,
+		},
+	}
+
+	for name, tc := range testCases {
+		t.Run(name, func(t *testing.T) {
+			assert := assert.New(t)
+
+			objects := append([]runtime.Object{&corev1.Node{ObjectMeta: metav1.ObjectMeta{Name: "node"}}}, tc.jobs...)
+			drainer := &stubNodeDrainer{pods: tc.pods, webhookErr: tc.webhookErr}
+			reconciler := NodeDrainReconciler{
+				nodeDrainer: drainer,
+				Client: &stubReadWriterClient{
+					stubReaderClient: *newStubReaderClient(t, objects, nil, nil),
+					stubWriterClient: stubWriterClient{patchErr: tc.patchErr},
+				},
+				Scheme: getScheme(t),
+				Clock:  testclock.NewFakeClock(now),
+			}
+			desired := nodeDrain.DeepCopy()
+			desired.Spec.DrainConfig = tc.spec
+			status := *tc.status.DeepCopy()
+
+			result, err := reconciler.drain(context.Background(), desired, &status)
+			if tc.wantErr {
+				assert.Error(err)
+			} else {
+				assert.NoError(err)
+			}
+			assert.Equal(tc.wantPhase, status.Phase)
+			assert.Equal(tc.wantRequeue, result.RequeueAfter)
+			assert.NotNil(status.StartTime)
+			assert.Equal(tc.wantEvicted, drainer.evicted)
+			assert.Equal(tc.wantRetries, status.Retries)
+			cond := meta.FindStatusCondition(status.Conditions, updatev1alpha1.ConditionDrainFailed)
+			if tc.wantFailed != "" {
+				if assert.NotNil(cond) {
+					assert.Equal(tc.wantFailed, cond.Reason)
+				}
+			} else if tc.wantPhase != updatev1alpha1.NodeDrainPhaseFailed {
+				assert.Nil(cond)
+			}
+		})
+	}
+}
+
+func TestEvictPods(t *testing.T) {
+	terminating := testPod("default", "terminating")
+	terminating.DeletionTimestamp = &metav1.Time{Time: time.Now()}
+
+	testCases := map[string]struct {
+		pods          []corev1.Pod
+		podsErr       error
+		evictErrs     map[string]error
+		wantRemaining int
+		wantBlocked   []updatev1alpha1.BlockedEviction
+		wantCondition metav1.ConditionStatus
+		wantEvicted   []string
+		wantErr       bool
+	}{
+		"no pods remaining": {
+			wantCondition: metav1.ConditionFalse,
+		},
+		"pods are evicted": {
+			pods:          []corev1.Pod{testPod("default", "a"), testPod("kube-system", "b")},
+			wantRemaining: 2,
+			wantCondition: metav1.ConditionFalse,
+			wantEvicted:   []string{"default/a", "kube-system/b"},
+		},
+		"terminating pods are skipped": {
+			pods:          []corev1.Pod{terminating},
+			wantRemaining: 1,
+			wantCondition: metav1.ConditionFalse,
+		},
+		"blocked evictions are reported": {
+			pods: []corev1.Pod{testPod("default", "a"), testPod("default", "b")},
+			evictErrs: map[string]error{
+				"default/a": fmt.Errorf("%w: disruption budget", drain.ErrEvictionBlocked),
+			},
+			wantRemaining: 2,
+			wantBlocked: []updatev1alpha1.BlockedEviction{
+				{Namespace: "default", Name: "a", Message: "eviction blocked: disruption budget"},
+			},
+			wantCondition: metav1.ConditionTrue,
+			wantEvicted:   []string{"default/a", "default/b"},
+		},
+		"eviction fails": {
+			pods: []corev1.Pod{testPod("default", "a"), testPod("default", "b")},
+			evictErrs: map[string]error{
+				"default/a": errors.New("failed"),
+			},
+			wantRemaining: 2,
+			wantCondition: metav1.ConditionFalse,
+			wantEvicted:   []string{"default/a", "default/b"},
+			wantErr:       true,
+		},
+		"listing pods fails": {
+			podsErr: errors.New("failed"),
+			wantErr: true,
+		},
+	}
+
+	for name, tc := range testCases {
+		t.Run(name, func(t *testing.T) {
+			assert := assert.New(t)
+
+			drainer := &stubNodeDrainer{pods: tc.pods, podsErr: tc.podsErr, evictErrs: tc.evictErrs}
+			reconciler := NodeDrainReconciler{nodeDrainer: drainer}
+			gracePeriod := int64(10)
+			nodeDrain := &updatev1alpha1.NodeDrain{
+				Spec: updatev1alpha1.NodeDrainSpec{
+					NodeName:    "node",
+					DrainConfig: updatev1alpha1.DrainConfig{GracePeriodSeconds: &gracePeriod},
+				},
+			}
+			status := updatev1alpha1.NodeDrainStatus{
+				BlockedEvictions: []updatev1alpha1.BlockedEviction{{Namespace: "default", Name: "old"}},
+			}
+
+			remaining, err := reconciler.evictPods(context.Background(), nodeDrain, &status)
+			if tc.wantErr {
+				assert.Error(err)
+			} else {
+				assert.NoError(err)
+			}
+			if tc.podsErr != nil {
+				return
+			}
+			assert.Equal(tc.wantRemaining, remaining)
+			assert.Equal(int32(tc.wantRemaining), status.RemainingPods)
+			assert.Equal(tc.wantBlocked, status.BlockedEvictions)
+			assert.Equal(tc.wantEvicted, drainer.evicted)
+			for _, gracePeriodSeconds := range drainer.gracePeriods {
+				assert.Equal(&gracePeriod, gracePeriodSeconds)
+			}
+			assert.True(meta.IsStatusConditionPresentAndEqual(status.Conditions, updatev1alpha1.ConditionEvictionBlocked, tc.wantCondition))
+		})
+	}
+}
+
+func TestNewNodeDrain(t *testing.T) {
+	assert := assert.New(t)
+
+	gracePeriod := int64(10)
+	config := &updatev1alpha1.DrainConfig{GracePeriodSeconds: &gracePeriod}
+
+	nodeDrain := newNodeDrain("node", "reason", config)
+	assert.Equal("node", nodeDrain.Name)
+	assert.Equal("node", nodeDrain.Spec.NodeName)
+	assert.Equal("reason", nodeDrain.Spec.Reason)
+	assert.Equal(*config, nodeDrain.Spec.DrainConfig)
+	assert.NotSame(config.GracePeriodSeconds, nodeDrain.Spec.GracePeriodSeconds)
+
+	nodeDrain = newNodeDrain("node", "reason", nil)
+	assert.Equal(updatev1alpha1.DrainConfig{}, nodeDrain.Spec.DrainConfig)
+}
+
+func TestNewDrainHookJob(t *testing.T) {
+	assert := assert.New(t)
+	require := require.New(t)
+
+	nodeDrain := &updatev1alpha1.NodeDrain{
+		Spec: updatev1alpha1.NodeDrainSpec{NodeName: "node", Reason: "reason"},
+	}
+	hook := updatev1alpha1.DrainHook{
+		Name: "backup",
+		Job: &updatev1alpha1.JobDrainHook{
+			Image:              "image",
+			Command:            []string{"backup"},
+			Args:               []string{"--all"},
+			ServiceAccountName: "backup",
+		},
+	}
+
+	job := newDrainHookJob(nodeDrain, 2, updatev1alpha1.NodeDrainPhasePreDrain, hook)
+
+	assert.Equal(drainHookNamespace, job.Namespace)
+	assert.Equal("predrain-backup-", job.GenerateName)
+	assert.Equal("predrain-backup", job.Labels[drainHookLabel])
+	assert.Equal("2", job.Labels[drainAttemptLabel])
+	assert.Equal(int32(drainHookBackoffLimit), *job.Spec.BackoffLimit)
+	podSpec := job.Spec.Template.Spec
+	assert.Equal(corev1.RestartPolicyNever, podSpec.RestartPolicy)
+	assert.Equal("backup", podSpec.ServiceAccountName)
+	assert.True(*podSpec.AutomountServiceAccountToken)
+	assert.True(*podSpec.SecurityContext.RunAsNonRoot)
+	assert.Equal(int64(drainHookUserID), *podSpec.SecurityContext.RunAsUser)
+	assert.Equal(corev1.SeccompProfileTypeRuntimeDefault, podSpec.SecurityContext.SeccompProfile.Type)
+	require.Len(podSpec.Containers, 1)
+	container := podSpec.Containers[0]
+	assert.False(*container.SecurityContext.AllowPrivilegeEscalation)
+	assert.Equal([]corev1.Capability{"ALL"}, container.SecurityContext.Capabilities.Drop)
+	assert.Equal("image", container.Image)
+	assert.Equal([]string{"backup"}, container.Command)
+	assert.Equal([]string{"--all"}, container.Args)
+	assert.Equal([]corev1.EnvVar{
+		{Name: "NODE_NAME", Value: "node"},
+		{Name: "DRAIN_REASON", Value: "reason"},
+		{Name: "DRAIN_PHASE", Value: "PreDrain"},
+	}, container.Env)
+
+	backoffLimit := int32(5)
+	hook.Job.BackoffLimit = &backoffLimit
+	hook.Job.ServiceAccountName = ""
+	job = newDrainHookJob(nodeDrain, 0, updatev1alpha1.NodeDrainPhasePostDrain, hook)
+	assert.Equal(backoffLimit, *job.Spec.BackoffLimit)
+	assert.Equal("postdrain-backup", job.Labels[drainHookLabel])
+	assert.Equal("0", job.Labels[drainAttemptLabel])
+	assert.False(*job.Spec.Template.Spec.AutomountServiceAccountToken)
+}
+
+func testPod(namespace, name string) corev1.Pod {
+	return corev1.Pod{ObjectMeta: metav1.ObjectMeta{Namespace: namespace, Name: name}}
+}
+
+type stubNodeDrainer struct {
+	pods       []corev1.Pod
+	podsErr    error
+	evictErrs  map[string]error
+	webhookErr error
+
+	evicted      []string
+	gracePeriods []*int64
+}
+
+func (d *stubNodeDrainer) PodsToEvict(_ context.Context, _ string) ([]corev1.Pod, error) {
+	return d.pods, d.podsErr
+}
+
+func (d *stubNodeDrainer) EvictPod(_ context.Context, pod corev1.Pod, gracePeriodSeconds *int64) error {
+	key := pod.Namespace + "/" + pod.Name
+	d.evicted = append(d.evicted, key)
+	d.gracePeriods = append(d.gracePeriods, gracePeriodSeconds)
+	return d.evictErrs[key]
+}
+
+func (d *stubNodeDrainer) CallWebhook(_ context.Context, _ string, _ drain.WebhookRequest) error {
+	return d.webhookErr
+}
@@ -0,0 +1,605 @@
+/*
+Copyright (c) Edgeless Systems GmbH
+
+SPDX-License-Identifier: AGPL-3.0-only
+*/
+
+package controllers
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	nodeutil "github.com/edgelesssys/constellation/operators/constellation-node-operator/v2/internal/node"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/meta"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/labels"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/client-go/util/retry"
+	"k8s.io/utils/clock"
+	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/builder"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/handler"
+	"sigs.k8s.io/controller-runtime/pkg/log"
+	"sigs.k8s.io/controller-runtime/pkg/source"
+
+	updatev1alpha1 "github.com/edgelesssys/constellation/operators/constellation-node-operator/v2/api/v1alpha1"
+)
+
+const (
+	// healthCheckInterval is the time between two checks of the CSP state of the selected nodes.
+	// CSP states are cached for this long, so node events do not cause additional CSP requests.
+	healthCheckInterval = time.Minute
+	// defaultUnhealthyDuration is the time a default unhealthy condition or CSP state has to persist.
+	defaultUnhealthyDuration = 5 * time.Minute
+
+	conditionRemediationAllowedReason        = "RemediationAllowed"
+	conditionRemediationAllowedMessage       = "Unhealthy nodes are remediated"
+	conditionRemediationPausedReason         = "Paused"
+	conditionRemediationPausedMessage        = "Remediation is paused"
+	conditionTooManyUnhealthyReason          = "TooManyUnhealthy"
+	conditionTooManyUnhealthyMessageTemplate = "%d of %d nodes are unhealthy, which exceeds the maximum of %d"
+)
+
+var (
+	defaultUnhealthyConditions = []updatev1alpha1.UnhealthyCondition{
+		{Type: corev1.NodeReady, Status: corev1.ConditionFalse, Duration: metav1.Duration{Duration: defaultUnhealthyDuration}},
+		{Type: corev1.NodeReady, Status: corev1.ConditionUnknown, Duration: metav1.Duration{Duration: defaultUnhealthyDuration}},
+	}
+	defaultUnhealthyCSPStates = []updatev1alpha1.UnhealthyCSPState{
+		{State: updatev1alpha1.NodeStateFailed, Duration: metav1.Duration{Duration: defaultUnhealthyDuration}},
+		{State: updatev1alpha1.NodeStateStopped, Duration: metav1.Duration{Duration: defaultUnhealthyDuration}},
+	}
+)
+
+// NodeHealthCheckReconciler reconciles a NodeHealthCheck object.
+type NodeHealthCheckReconciler struct {
+	nodeRemediator
+	etcdRemover
+	client.Client
+	Scheme *runtime.Scheme
+	clock.Clock
+	nodeStates nodeStateCache
+}
+
+// NewNodeHealthCheckReconciler creates a new NodeHealthCheckReconciler.
+func NewNodeHealthCheckReconciler(nodeRemediator nodeRemediator, etcdRemover etcdRemover, client client.Client, scheme *runtime.Scheme) *NodeHealthCheckReconciler {
+	return &NodeHealthCheckReconciler{
+		nodeRemediator: nodeRemediator,
+		etcdRemover:    etcdRemover,
+		Client:         client,
+		Scheme:         scheme,
+		Clock:          clock.RealClock{},
+	}
+}
+
+//+kubebuilder:rbac:groups=update.edgeless.systems,resources=nodehealthchecks,verbs=get;list;watch;create;update;patch;delete
+//+kubebuilder:rbac:groups=update.edgeless.systems,resources=nodehealthchecks/status,verbs=get;update;patch
+//+kubebuilder:rbac:groups=update.edgeless.systems,resources=nodehealthchecks/finalizers,verbs=update
+//+kubebuilder:rbac:groups=update.edgeless.systems,resources=pendingnodes,verbs=get;list;watch;create;update;patch;delete
+//+kubebuilder:rbac:groups=update.edgeless.systems,resources=scalinggroups,verbs=get;list;watch
+//+kubebuilder:rbac:groups=update.edgeless.systems,resources=nodedrains,verbs=get;list;watch
+//+kubebuilder:rbac:groups="",resources=nodes,verbs=get;list;watch;delete
+
+// Reconcile checks the health of the nodes selected by the NodeHealthCheck and replaces unhealthy nodes.
+//
+// A node is unhealthy if one of its conditions or the state of its instance reported by the CSP matches
+// the NodeHealthCheck spec for longer than the configured duration.
+// Unhealthy nodes are removed from the cluster (including their etcd membership) and their termination is
+// tracked by a leaving PendingNode. A replacement node is created in the same scaling group and tracked by
+// a joining PendingNode until it joined the cluster.
+func (r *NodeHealthCheckReconciler) Reconcile(ctx context.Context, req ctrl.Request) (ctrl.Result, error) {
+	logr := log.FromContext(ctx)
+
+	var healthCheck updatev1alpha1.NodeHealthCheck
+	if err := r.Get(ctx, req.NamespacedName, &healthCheck); err != nil {
+		return ctrl.Result{}, client.IgnoreNotFound(err)
+	}
+	selector := labels.Everything()
+	if healthCheck.Spec.Selector != nil {
+		var err error
+		selector, err = metav1.LabelSelectorAsSelector(healthCheck.Spec.Selector)
+		if err != nil {
+			logr.Error(err, "Invalid node selector")
+			return ctrl.Result{}, nil
+		}
+	}
+
+	var nodeList corev1.NodeList
+	if err := r.List(ctx, &nodeList); err != nil {
+		logr.Error(err, "Unable to list nodes")
+		return ctrl.Result{}, err
+	}
+	var pendingNodeList updatev1alpha1.PendingNodeList
+	if err := r.List(ctx, &pendingNodeList); err != nil {
+		logr.Error(err, "Unable to list pending nodes")
+		return ctrl.Result{}, err
+	}
+	var nodeDrainList updatev1alpha1.NodeDrainList
+	if err := r.List(ctx, &nodeDrainList); err != nil {
+		logr.Error(err, "Unable to list node drains")
+		return ctrl.Result{}, err
+	}
+	var scalingGroupList updatev1alpha1.ScalingGroupList
+	if err := r.List(ctx, &scalingGroupList); err != nil {
+		logr.Error(err, "Unable to list scaling groups")
+		return ctrl.Result{}, err
+	}
+	scalingGroupIDs := make(map[string]struct{}, len(scalingGroupList.Items))
+	for _, scalingGroup := range scalingGroupList.Items {
+		scalingGroupIDs[strings.ToLower(scalingGroup.Spec.GroupID)] = struct{}{}
+	}
+
+	now := r.Now()
+	remediations := r.progressRemediations(ctx, &healthCheck, pendingNodeList.Items)
+
+	var selectedNodes []corev1.Node
+	for _, node := range nodeList.Items {
+		if selector.Matches(labels.Set(node.Labels)) {
+			selectedNodes = append(selectedNodes, node)
+		}
+	}
+	unhealthyNodes := r.checkNodes(ctx, healthCheck.Spec, healthCheck.Status.UnhealthyNodes, selectedNodes, now)
+
+	status := updatev1alpha1.NodeHealthCheckStatus{
+		ObservedNodes: int32(len(selectedNodes)),
+		HealthyNodes:  int32(len(selectedNodes) - len(unhealthyNodes)),
+		Conditions:    healthCheck.Status.Conditions,
+	}
+	for _, unhealthy := range unhealthyNodes {
+		status.UnhealthyNodes = append(status.UnhealthyNodes, unhealthy.UnhealthyNode)
+	}
+	allowedCondition := remediationAllowedCondition(healthCheck.Spec, len(selectedNodes), len(unhealthyNodes))
+	meta.SetStatusCondition(&status.Conditions, allowedCondition)
+
+	if allowedCondition.Status == metav1.ConditionTrue {
+		busyNodes := nodesInProgress(pendingNodeList.Items, nodeDrainList.Items)
+		controlPlaneQuorum := hasControlPlaneQuorum(nodeList.Items)
+		for _, unhealthy := range unhealthyNodes {
+			if now.Before(unhealthy.deadline) {
+				continue
+			}
+			if int32(len(remediations)) >= maxConcurrentRemediations(healthCheck.Spec) {
+				logr.Info("Too many remediations in progress, not replacing node", "unhealthyNode", unhealthy.node.Name, "remediations", len(remediations))
+				break
+			}
+			if _, ok := busyNodes[unhealthy.node.Name]; ok {
+				logr.Info("Node is already being replaced or upgraded, not remediating", "unhealthyNode", unhealthy.node.Name)
+				continue
+			}
+			if nodeutil.IsControlPlaneNode(&unhealthy.node) {
+				if !controlPlaneQuorum || controlPlaneRemediationInProgress(remediations) {
+					logr.Info("Replacing control-plane node would risk etcd quorum, not remediating", "unhealthyNode", unhealthy.node.Name)
+					continue
+				}
+			}
+			remediation, err := r.startRemediation(ctx, &healthCheck, unhealthy.node, scalingGroupIDs)
+			if err != nil {
+				logr.Error(err, "Unable to remediate node", "unhealthyNode", unhealthy.node.Name)
+				continue
+			}
+			if remediation == nil {
+				continue
+			}
+			remediations = append(remediations, *remediation)
+		}
+	}
+	status.Remediations = remediations
+
+	if err := r.tryUpdateStatus(ctx, req.NamespacedName, status); err != nil {
+		logr.Error(err, "Updating status")
+		return ctrl.Result{}, client.IgnoreNotFound(err)
+	}
+	return ctrl.Result{RequeueAfter: requeueHealthCheck(unhealthyNodes, now)}, nil
+}
+
+// SetupWithManager sets up the controller with the Manager.
+func (r *NodeHealthCheckReconciler) SetupWithManager(mgr ctrl.Manager) error {
+	return ctrl.NewControllerManagedBy(mgr).
+		For(&updatev1alpha1.NodeHealthCheck{}).
+		Watches(
+			&source.Kind{Type: &corev1.Node{}},
+			handler.EnqueueRequestsFromMapFunc(r.findAllNodeHealthChecks),
+			builder.WithPredicates(nodeConditionsChangedPredicate()),
+		).
+		Owns(&updatev1alpha1.PendingNode{}).
+		Complete(r)
+}
+
+// progressRemediations checks the replacement nodes of remediations in progress and returns the remediations that are not finished.
+// Replacement nodes that joined the cluster finish their remediation. If a replacement node failed to join, another one is created.
+func (r *NodeHealthCheckReconciler) progressRemediations(ctx context.Context, healthCheck *updatev1alpha1.NodeHealthCheck, pendingNodes []updatev1alpha1.PendingNode) []updatev1alpha1.Remediation {
+	logr := log.FromContext(ctx)
+	pendingNodeByName := make(map[string]updatev1alpha1.PendingNode, len(pendingNodes))
+	for _, pendingNode := range pendingNodes {
+		pendingNodeByName[pendingNode.Name] = pendingNode
+		// replacement nodes that joined the cluster are no longer pending, even if their remediation was not recorded
+		if pendingNode.Spec.Goal == updatev1alpha1.NodeGoalJoin && pendingNode.Status.ReachedGoal && metav1.IsControlledBy(&pendingNode, healthCheck) {
+			if err := r.Delete(ctx, &pendingNode); client.IgnoreNotFound(err) != nil {
+				logr.Error(err, "Unable to delete pending node resource", "pendingNode", pendingNode.Name)
+				continue
+			}
+			delete(pendingNodeByName, pendingNode.Name)
+			logr.Info("Replacement node joined the cluster", "replacementNode", pendingNode.Spec.NodeName)
+		}
+	}
+
+	var remediations []updatev1alpha1.Remediation
+	for _, remediation := range healthCheck.Status.Remediations {
+		if remediation.ReplacementNodeName != "" {
+			pendingNode, ok := pendingNodeByName[remediation.ReplacementNodeName]
+			if !ok {
+				logr.Info("Remediation finished", "unhealthyNode", remediation.NodeName, "replacementNode", remediation.ReplacementNodeName)
+				continue
+			}
+			if pendingNode.Spec.Goal == updatev1alpha1.NodeGoalJoin {
+				remediations = append(remediations, remediation)
+				continue
+			}
+			logr.Info("Replacement node failed to join, creating another one", "unhealthyNode", remediation.NodeName, "replacementNode", remediation.ReplacementNodeName)
+		}
+		if err := r.createReplacement(ctx, healthCheck, &remediation); err != nil {
+			logr.Error(err, "Unable to create replacement node", "unhealthyNode", remediation.NodeName, "scalingGroup", remediation.ScalingGroupID)
+		}
+		remediations = append(remediations, remediation)
+	}
+	return remediations
+}
+
+// checkNodes returns the nodes that match an unhealthy condition or CSP state, ordered by the time they exceed the duration.
+func (r *NodeHealthCheckReconciler) checkNodes(ctx context.Context, spec updatev1alpha1.NodeHealthCheckSpec,
+	previous []updatev1alpha1.UnhealthyNode, nodes []corev1.Node, now time.Time,
+) []unhealthyNode {
+	logr := log.FromContext(ctx)
+	unhealthyConditions := spec.UnhealthyConditions
+	if len(unhealthyConditions) == 0 {
+		unhealthyConditions = defaultUnhealthyConditions
+	}
+	unhealthyCSPStates := spec.UnhealthyCSPStates
+	if len(unhealthyCSPStates) == 0 {
+		unhealthyCSPStates = defaultUnhealthyCSPStates
+	}
+	previouslySince := make(map[string]metav1.Time, len(previous))
+	for _, unhealthy := range previous {
+		previouslySince[unhealthy.Name+"/"+unhealthy.Reason] = unhealthy.Since
+	}
+
+	var unhealthyNodes []unhealthyNode
+	for _, node := range nodes {
+		var candidates []unhealthyNode
+		for _, unhealthyCondition := range unhealthyConditions {
+			for _, condition := range node.Status.Conditions {
+				if condition.Type != unhealthyCondition.Type || condition.Status != unhealthyCondition.Status {
+					continue
+				}
+				candidates = append(candidates, newUnhealthyNode(node,
+					fmt.Sprintf("%s=%s", condition.Type, condition.Status), condition.LastTransitionTime, unhealthyCondition.Duration.Duration))
+			}
+		}
+		if node.Spec.ProviderID != "" {
+			nodeState, err := r.cachedNodeState(ctx, node.Spec.ProviderID, now)
+			if err != nil {
+				logr.Error(err, "Unable to get node state", "node", node.Name)
+			}
+			for _, unhealthyState := range unhealthyCSPStates {
+				if err != nil || nodeState != unhealthyState.State {
+					continue
+				}
+				reason := fmt.Sprintf("CSPState=%s", nodeState)
+				since, ok := previouslySince[node.Name+"/"+reason]
+				if !ok {
+					since = metav1.NewTime(now)
+				}
+				candidates = append(candidates, newUnhealthyNode(node, reason, since, unhealthyState.Duration.Duration))
+			}
+		}
+		if len(candidates) == 0 {
+			continue
+		}
+		// the node is remediated as soon as the first match exceeds its duration
+		earliest := candidates[0]
+		for _, candidate := range candidates[1:] {
+			if candidate.deadline.Before(earliest.deadline) {
+				earliest = candidate
+			}
+		}
+		unhealthyNodes = append(unhealthyNodes, earliest)
+	}
+	sort.Slice(unhealthyNodes, func(i, j int) bool {
+		if unhealthyNodes[i].deadline.Equal(unhealthyNodes[j].deadline) {
+			return unhealthyNodes[i].Name < unhealthyNodes[j].Name
+		}
+		return unhealthyNodes[i].deadline.Before(unhealthyNodes[j].deadline)
+	})
+	return unhealthyNodes
+}
+
+// cachedNodeState returns the CSP state of a node. The CSP is queried at most once per healthCheckInterval and node.
+// Failed queries are not cached.
+func (r *NodeHealthCheckReconciler) cachedNodeState(ctx context.Context, providerID string, now time.Time) (updatev1alpha1.CSPNodeState, error) {
+	if state, ok := r.nodeStates.get(providerID, now); ok {
+		return state, nil
+	}
+	state, err := r.GetNodeState(ctx, providerID)
+	if err != nil {
+		return "", err
+	}
+	r.nodeStates.set(providerID, state, now)
+	return state, nil
+}
+
+// startRemediation removes an unhealthy node from the cluster and creates its replacement in the same scaling group.
+// If the node does not belong to a known scaling group, it is not remediated and nil is returned.
+func (r *NodeHealthCheckReconciler) startRemediation(ctx context.Context, healthCheck *updatev1alpha1.NodeHealthCheck,
+	node corev1.Node, scalingGroupIDs map[string]struct{},
+) (*updatev1alpha1.Remediation, error) {
+	logr := log.FromContext(ctx)
+	if node.Spec.ProviderID == "" {
+		logr.Info("Node has no provider ID, not remediating", "unhealthyNode", node.Name)
+		return nil, nil
+	}
+	scalingGroupID, err := r.GetScalingGroupID(ctx, node.Spec.ProviderID)
+	if err != nil {
+		return nil, fmt.Errorf("getting scaling group of node: %w", err)
+	}
+	if _, ok := scalingGroupIDs[strings.ToLower(scalingGroupID)]; !ok {
+		logr.Info("Scaling group does not have matching resource, not remediating", "unhealthyNode", node.Name, "scalingGroup", scalingGroupID)
+		return nil, nil
+	}
+
+	logr.Info("Remediating unhealthy node", "unhealthyNode", node.Name, "scalingGroup", scalingGroupID)
+	controlPlane := nodeutil.IsControlPlaneNode(&node)
+	if controlPlane {
+		nodeVPCIP, err := nodeutil.VPCIP(&node)
+		if err != nil {
+			return nil, fmt.Errorf("getting node VPC IP: %w", err)
+		}
+		if err := r.RemoveEtcdMemberFromCluster(ctx, nodeVPCIP); err != nil {
+			return nil, fmt.Errorf("removing etcd member from cluster: %w", err)
+		}
+	}
+	if err := r.Delete(ctx, &node); client.IgnoreNotFound(err) != nil {
+		return nil, fmt.Errorf("deleting node: %w", err)
+	}
+	if err := r.DeleteNode(ctx, node.Spec.ProviderID); err != nil {
+		logr.Error(err, "Scheduling CSP node deletion", "providerID", node.Spec.ProviderID)
+	}
+	deadline := metav1.NewTime(r.Now().Add(defaultNodeLeaveTimeout))
+	leavingNode := &updatev1alpha1.PendingNode{
+		ObjectMeta: metav1.ObjectMeta{Name: node.Name},
+		Spec: updatev1alpha1.PendingNodeSpec{
+			ProviderID:     node.Spec.ProviderID,
+			ScalingGroupID: scalingGroupID,
+			NodeName:       node.Name,
+			Goal:           updatev1alpha1.NodeGoalLeave,
+			Deadline:       &deadline,
+		},
+	}
+	if err := ctrl.SetControllerReference(healthCheck, leavingNode, r.Scheme); err != nil {
+		return nil, err
+	}
+	if err := r.Create(ctx, leavingNode); err != nil {
+		logr.Error(err, "Tracking CSP node deletion")
+	}
+
+	remediation := &updatev1alpha1.Remediation{
+		NodeName:       node.Name,
+		ProviderID:     node.Spec.ProviderID,
+		ScalingGroupID: scalingGroupID,
+		ControlPlane:   controlPlane,
+		StartTime:      metav1.NewTime(r.Now()),
+	}
+	// a failed creation of the replacement node is retried with the next reconcile call
+	if err := r.createReplacement(ctx, healthCheck, remediation); err != nil {
+		logr.Error(err, "Unable to create replacement node", "unhealthyNode", node.Name, "scalingGroup", scalingGroupID)
+	}
+	return remediation, nil
+}
+
+// createReplacement creates a replacement node for a remediation and tracks it using a joining PendingNode.
+func (r *NodeHealthCheckReconciler) createReplacement(ctx context.Context, healthCheck *updatev1alpha1.NodeHealthCheck, remediation *updatev1alpha1.Remediation) error {
+	nodeName, providerID, err := r.CreateNode(ctx, remediation.ScalingGroupID)
+	if err != nil {
+		return err
+	}
+	deadline := metav1.NewTime(r.Now().Add(nodeJoinTimeout))
+	pendingNode := &updatev1alpha1.PendingNode{
+		ObjectMeta: metav1.ObjectMeta{Name: nodeName},
+		Spec: updatev1alpha1.PendingNodeSpec{
+			ProviderID:     providerID,
+			ScalingGroupID: remediation.ScalingGroupID,
+			NodeName:       nodeName,
+			Goal:           updatev1alpha1.NodeGoalJoin,
+			Deadline:       &deadline,
+		},
+	}
+	if err := ctrl.SetControllerReference(healthCheck, pendingNode, r.Scheme); err != nil {
+		return err
+	}
+	if err := r.Create(ctx, pendingNode); err != nil {
+		return err
+	}
+	remediation.ReplacementNodeName = nodeName
+	log.FromContext(ctx).Info("Created replacement node", "unhealthyNode", remediation.NodeName, "replacementNode", nodeName)
+	return nil
+}
+
+// tryUpdateStatus attempts to update the NodeHealthCheck status field in a retry loop.
+func (r *NodeHealthCheckReconciler) tryUpdateStatus(ctx context.Context, name types.NamespacedName, status updatev1alpha1.NodeHealthCheckStatus) error {
+	return retry.RetryOnConflict(retry.DefaultRetry, func() error {
+		var healthCheck updatev1alpha1.NodeHealthCheck
+		if err := r.Get(ctx, name, &healthCheck); err != nil {
+			return err
+		}
+		healthCheck.Status = *status.DeepCopy()
+		return r.Status().Update(ctx, &healthCheck)
+	})
+}
+
+// unhealthyNode is a node matching an unhealthy condition or CSP state.
+type unhealthyNode struct {
+	updatev1alpha1.UnhealthyNode
+	node corev1.Node
+	// deadline is the time after which the node is remediated.
+	deadline time.Time
+}
+
+func newUnhealthyNode(node corev1.Node, reason string, since metav1.Time, duration time.Duration) unhealthyNode {
+	return unhealthyNode{
+		UnhealthyNode: updatev1alpha1.UnhealthyNode{
+			Name:   node.Name,
+			Reason: reason,
+			Since:  since,
+		},
+		node:     node,
+		deadline: since.Add(duration),
+	}
+}
+
+// nodeStateCache caches the CSP states of nodes by provider ID.
+type nodeStateCache struct {
+	mux    sync.Mutex
+	states map[string]cachedNodeState
+}
+
+type cachedNodeState struct {
+	state     updatev1alpha1.CSPNodeState
+	checkedAt time.Time
+}
+
+// get returns the state of a node if it was retrieved less than healthCheckInterval ago.
+func (c *nodeStateCache) get(providerID string, now time.Time) (updatev1alpha1.CSPNodeState, bool) {
+	c.mux.Lock()
+	defer c.mux.Unlock()
+	cached, ok := c.states[providerID]
+	if !ok || now.Sub(cached.checkedAt) >= healthCheckInterval || now.Before(cached.checkedAt) {
+		return "", false
+	}
+	return cached.state, true
+}
+
+// set stores the state of a node and removes expired states of other nodes.
+func (c *nodeStateCache) set(providerID string, state updatev1alpha1.CSPNodeState, now time.Time) {
+	c.mux.Lock()
+	defer c.mux.Unlock()
+	if c.states == nil {
+		c.states = make(map[string]cachedNodeState)
+	}
+	for id, cached := range c.states {
+		if now.Sub(cached.checkedAt) >= healthCheckInterval {
+			delete(c.states, id)
+		}
+	}
+	c.states[providerID] = cachedNodeState{state: state, checkedAt: now}
+}
+
+// remediationAllowedCondition returns whether unhealthy nodes may be remediated.
+func remediationAllowedCondition(spec updatev1alpha1.NodeHealthCheckSpec, observedNodes, unhealthyNodes int) metav1.Condition {
+	condition := metav1.Condition{
+		Type:    updatev1alpha1.ConditionRemediationAllowed,
+		Status:  metav1.ConditionTrue,
+		Reason:  conditionRemediationAllowedReason,
+		Message: conditionRemediationAllowedMessage,
+	}
+	if spec.Paused {
+		condition.Status = metav1.ConditionFalse
+		condition.Reason = conditionRemediationPausedReason
+		condition.Message = conditionRemediationPausedMessage
+		return condition
+	}
+	if limit := maxUnhealthy(spec, observedNodes); unhealthyNodes > limit {
+		condition.Status = metav1.ConditionFalse
+		condition.Reason = conditionTooManyUnhealthyReason
+		condition.Message = fmt.Sprintf(conditionTooManyUnhealthyMessageTemplate, unhealthyNodes, observedNodes, limit)
+	}
+	return condition
+}
+
+// maxUnhealthy returns the maximum number of unhealthy nodes that are remediated.
+func maxUnhealthy(spec updatev1alpha1.NodeHealthCheckSpec, observedNodes int) int {
+	if spec.MaxUnhealthy == nil {
+		return observedNodes / 2
+	}
+	return int(*spec.MaxUnhealthy)
+}
+
+// maxConcurrentRemediations returns the maximum number of remediations in progress.
+func maxConcurrentRemediations(spec updatev1alpha1.NodeHealthCheckSpec) int32 {
+	if spec.MaxConcurrentRemediations < 1 {
+		return 1
+	}
+	return spec.MaxConcurrentRemediations
+}
+
+// nodesInProgress returns the names of nodes that are joining, leaving or drained by other controllers.
+func nodesInProgress(pendingNodes []updatev1alpha1.PendingNode, nodeDrains []updatev1alpha1.NodeDrain) map[string]struct{} {
+	nodes := make(map[string]struct{}, len(pendingNodes)+len(nodeDrains))
+	for _, pendingNode := range pendingNodes {
+		nodes[pendingNode.Spec.NodeName] = struct{}{}
+	}
+	for _, nodeDrain := range nodeDrains {
+		nodes[nodeDrain.Spec.NodeName] = struct{}{}
+	}
+	return nodes
+}
+
+// hasControlPlaneQuorum returns true if a majority of the control-plane nodes is ready,
+// so an etcd member can be removed safely.
+func hasControlPlaneQuorum(nodes []corev1.Node) bool {
+	var controlPlaneNodes, readyControlPlaneNodes int
+	for _, node := range nodes {
+		if !nodeutil.IsControlPlaneNode(&node) {
+			continue
+		}
+		controlPlaneNodes++
+		if nodeutil.Ready(&node) {
+			readyControlPlaneNodes++
+		}
+	}
+	return readyControlPlaneNodes > controlPlaneNodes/2
+}
+
+// controlPlaneRemediationInProgress returns true if a control-plane node is being replaced.
+func controlPlaneRemediationInProgress(remediations []updatev1alpha1.Remediation) bool {
+	for _, remediation := range remediations {
+		if remediation.ControlPlane {
+			return true
+		}
+	}
+	return false
+}
+
+// requeueHealthCheck returns the time until the next unhealthy node exceeds its duration or the next periodic check is due.
+func requeueHealthCheck(unhealthyNodes []unhealthyNode, now time.Time) time.Duration {
+	requeueAfter := healthCheckInterval
+	for _, unhealthy := range unhealthyNodes {
+		if wait := unhealthy.deadline.Sub(now); wait > 0 && wait < requeueAfter {
+			requeueAfter = wait
+		}
+	}
+	return requeueAfter
+}
+
+// isRemediationReplacement returns true if a pending node was created to replace an unhealthy node.
+func isRemediationReplacement(pendingNode *updatev1alpha1.PendingNode) bool {
+	owner := metav1.GetControllerOf(pendingNode)
+	return owner != nil && owner.Kind == "NodeHealthCheck"
+}
+
+type nodeRemediator interface {
+	// GetNodeState retrieves the state of a node from a CSP.
+	GetNodeState(ctx context.Context, providerID string) (updatev1alpha1.CSPNodeState, error)
+	// GetScalingGroupID retrieves the scaling group that a node is part of.
+	GetScalingGroupID(ctx context.Context, providerID string) (string, error)
+	// CreateNode creates a new node inside a specified scaling group at the CSP and returns its future name and provider id.
+	CreateNode(ctx context.Context, scalingGroupID string) (nodeName, providerID string, err error)
+	// DeleteNode starts the termination of the node at the CSP.
+	DeleteNode(ctx context.Context, providerID string) error
+}
@@ -0,0 +1,650 @@
+/*
+Copyright (c) Edgeless Systems GmbH
+
+SPDX-License-Identifier: AGPL-3.0-only
+*/
+
+package controllers
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	testclock "k8s.io/utils/clock/testing"
+	"k8s.io/utils/pointer"
+	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+
+	updatev1alpha1 "github.com/edgelesssys/constellation/operators/constellation-node-operator/v2/api/v1alpha1"
+)
+
+func TestCheckNodes(t *testing.T) {
+	now := time.Date(2022, 9, 1, 12, 0, 0, 0, time.UTC)
+	someErr := errors.New("failed")
+	node := func(name, providerID string, ready corev1.ConditionStatus, since time.Time) corev1.Node {
+		return corev1.Node{
+			ObjectMeta: metav1.ObjectMeta{Name: name},
+			Spec:       corev1.NodeSpec{ProviderID: providerID},
+			Status: corev1.NodeStatus{Conditions: []corev1.NodeCondition{
+				{Type: corev1.NodeReady, Status: ready, LastTransitionTime: metav1.NewTime(since)},
+			}},
+		}
+	}
+
+	testCases := map[string]struct {
+		spec          updatev1alpha1.NodeHealthCheckSpec
+		previous      []updatev1alpha1.UnhealthyNode
+		nodes         []corev1.Node
+		nodeStates    map[string]updatev1alpha1.CSPNodeState
+		nodeStateErr  error
+		wantUnhealthy []updatev1alpha1.UnhealthyNode
+		wantDeadlines []time.Time
+	}{
+		"healthy nodes": {
+			nodes: []corev1.Node{
+				node("node-1", "provider://1", corev1.ConditionTrue, now.Add(-time.Hour)),
+				node("node-2", "provider://2", corev1.ConditionTrue, now.Add(-time.Hour)),
+			},
+			nodeStates: map[string]updatev1alpha1.CSPNodeState{
+				"provider://1": updatev1alpha1.NodeStateReady,
+				"provider://2": updatev1alpha1.NodeStateReady,
+			},
+		},
+		"not ready nodes are unhealthy": {
+			nodes: []corev1.Node{
+				node("node-1", "provider://1", corev1.ConditionFalse, now.Add(-time.Minute)),
+				node("node-2", "provider://2", corev1.ConditionUnknown, now.Add(-time.Hour)),
+			},
+			wantUnhealthy: []updatev1alpha1.UnhealthyNode{
+				{Name: "node-2", Reason: "Ready=Unknown", Since: metav1.NewTime(now.Add(-time.Hour))},
+				{Name: "node-1", Reason: "Ready=False", Since: metav1.NewTime(now.Add(-time.Minute))},
+			},
+			wantDeadlines: []time.Time{
+				now.Add(-time.Hour + defaultUnhealthyDuration),
+				now.Add(-time.Minute + defaultUnhealthyDuration),
+			},
+		},
+		"failed instance is unhealthy": {
+			nodes: []corev1.Node{
+				node("node-1", "provider://1", corev1.ConditionTrue, now.Add(-time.Hour)),
+			},
+			nodeStates: map[string]updatev1alpha1.CSPNodeState{
+				"provider://1": updatev1alpha1.NodeStateFailed,
+			},
+			wantUnhealthy: []updatev1alpha1.UnhealthyNode{
+				{Name: "node-1", Reason: "CSPState=Failed", Since: metav1.NewTime(now)},
+			},
+			wantDeadlines: []time.Time{now.Add(defaultUnhealthyDuration)},
+		},
+		"previously observed CSP state keeps its time": {
+			previous: []updatev1alpha1.UnhealthyNode{
+				{Name: "node-1", Reason: "CSPState=Stopped", Since: metav1.NewTime(now.Add(-time.Hour))},
+			},
+			nodes: []corev1.Node{
+				node("node-1", "provider://1", corev1.ConditionTrue, now.Add(-time.Hour)),
+			},
+			nodeStates: map[string]updatev1alpha1.CSPNodeState{
+				"provider://1": updatev1alpha1.NodeStateStopped,
+			},
+			wantUnhealthy: []updatev1alpha1.UnhealthyNode{
+				{Name: "node-1", Reason: "CSPState=Stopped", Since: metav1.NewTime(now.Add(-time.Hour))},
+			},
+			wantDeadlines: []time.Time{now.Add(-time.Hour + defaultUnhealthyDuration)},
+		},
+		"earliest deadline is used": {
+			nodes: []corev1.Node{
+				node("node-1", "provider://1", corev1.ConditionUnknown, now.Add(-time.Minute)),
+			},
+			nodeStates: map[string]updatev1alpha1.CSPNodeState{
+				"provider://1": updatev1alpha1.NodeStateStopped,
+			},
+			wantUnhealthy: []updatev1alpha1.UnhealthyNode{
+				{Name: "node-1", Reason: "Ready=Unknown", Since: metav1.NewTime(now.Add(-time.Minute))},
+			},
+			wantDeadlines: []time.Time{now.Add(-time.Minute + defaultUnhealthyDuration)},
+		},
+		"custom conditions and states replace defaults": {
+			spec: updatev1alpha1.NodeHealthCheckSpec{
+				UnhealthyConditions: []updatev1alpha1.UnhealthyCondition{
+					{Type: corev1.NodeDiskPressure, Status: corev1.ConditionTrue, Duration: metav1.Duration{Duration: time.Minute}},
+				},
+				UnhealthyCSPStates: []updatev1alpha1.UnhealthyCSPState{
+					{State: updatev1alpha1.NodeStateUnknown},
+				},
+			},
+			nodes: []corev1.Node{
+				node("node-1", "provider://1", corev1.ConditionFalse, now.Add(-time.Hour)),
+				{
+					ObjectMeta: metav1.ObjectMeta{Name: "node-2"},
+					Status: corev1.NodeStatus{Conditions: []corev1.NodeCondition{
+						{Type: corev1.NodeDiskPressure, Status: corev1.ConditionTrue, LastTransitionTime: metav1.NewTime(now)},
+					}},
+				},
+			},
+			nodeStates: map[string]updatev1alpha1.CSPNodeState{
+				"provider://1": updatev1alpha1.NodeStateFailed,
+			},
+			wantUnhealthy: []updatev1alpha1.UnhealthyNode{
+				{Name: "node-2", Reason: "DiskPressure=True", Since: metav1.NewTime(now)},
+			},
+			wantDeadlines: []time.Time{now.Add(time.Minute)},
+		},
+		"CSP state is ignored if it cannot be retrieved": {
+			nodes: []corev1.Node{
+				node("node-1", "provider://1", corev1.ConditionTrue, now.Add(-time.Hour)),
+			},
+			nodeStates: map[string]updatev1alpha1.CSPNodeState{
+				"provider://1": updatev1alpha1.NodeStateFailed,
+			},
+			nodeStateErr: someErr,
+		},
+	}
+
+	for name, tc := range testCases {
+		t.Run(name, func(t *testing.T) {
+			assert := assert.New(t)
+
+			reconciler := NodeHealthCheckReconciler{
+				nodeRemediator: &stubNodeRemediator{nodeStates: tc.nodeStates, nodeStateErr: tc.nodeStateErr},
+			}
+			unhealthyNodes := reconciler.checkNodes(context.Background(), tc.spec, tc.previous, tc.nodes, now)
+
+			var gotUnhealthy []updatev1alpha1.UnhealthyNode
+			var gotDeadlines []time.Time
+			for _, unhealthy := range unhealthyNodes {
+				gotUnhealthy = append(gotUnhealthy, unhealthy.UnhealthyNode)
+				gotDeadlines = append(gotDeadlines, unhealthy.deadline)
+			}
+			assert.Equal(tc.wantUnhealthy, gotUnhealthy)
+			assert.Equal(tc.wantDeadlines, gotDeadlines)
+		})
+	}
+}
+
+func TestCachedNodeState(t *testing.T) {
+	assert := assert.New(t)
+	require := require.New(t)
+
+	now := time.Date(2022, 9, 1, 12, 0, 0, 0, time.UTC)
+	remediator := &stubNodeRemediator{nodeStates: map[string]updatev1alpha1.CSPNodeState{
+		"provider://1": updatev1alpha1.NodeStateReady,
+		"provider://2": updatev1alpha1.NodeStateReady,
+	}}
+	reconciler := NodeHealthCheckReconciler{nodeRemediator: remediator}
+
+	state, err := reconciler.cachedNodeState(context.Background(), "provider://1", now)
+	require.NoError(err)
+	assert.Equal(updatev1alpha1.NodeStateReady, state)
+	assert.Equal([]string{"provider://1"}, remediator.nodeStateRequests)
+
+	// cached state is used within the health check interval
+	remediator.nodeStates["provider://1"] = updatev1alpha1.NodeStateFailed
+	state, err = reconciler.cachedNodeState(context.Background(), "provider://1", now.Add(healthCheckInterval/2))
+	require.NoError(err)
+	assert.Equal(updatev1alpha1.NodeStateReady, state)
+	assert.Equal([]string{"provider://1"}, remediator.nodeStateRequests)
+
+	// other nodes are queried
+	_, err = reconciler.cachedNodeState(context.Background(), "provider://2", now.Add(healthCheckInterval/2))
+	require.NoError(err)
+	assert.Equal([]string{"provider://1", "provider://2"}, remediator.nodeStateRequests)
+
+	// expired state is queried again
+	state, err = reconciler.cachedNodeState(context.Background(), "provider://1", now.Add(healthCheckInterval))
+	require.NoError(err)
+	assert.Equal(updatev1alpha1.NodeStateFailed, state)
+	assert.Equal([]string{"provider://1", "provider://2", "provider://1"}, remediator.nodeStateRequests)
+
+	// failed queries are not cached
+	remediator.nodeStateErr = errors.New("failed")
+	_, err = reconciler.cachedNodeState(context.Background(), "provider://3", now.Add(healthCheckInterval))
+	assert.Error(err)
+	remediator.nodeStateErr = nil
+	_, err = reconciler.cachedNodeState(context.Background(), "provider://3", now.Add(healthCheckInterval))
+	require.NoError(err)
+	assert.Equal([]string{"provider://1", "provider://2", "provider://1", "provider://3", "provider://3"}, remediator.nodeStateRequests)
+}
+
+func TestStartRemediation(t *testing.T) {
+	now := time.Date(2022, 9, 1, 12, 0, 0, 0, time.UTC)
+	someErr := errors.New("failed")
+	workerNode := corev1.Node{
+		ObjectMeta: metav1.ObjectMeta{Name: "worker"},
+		Spec:       corev1.NodeSpec{ProviderID: "provider://worker"},
+	}
+	controlPlaneNode := corev1.Node{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:   "control-plane",
+			Labels: map[string]string{controlPlaneRoleLabel: ""},
+		},
+		Spec: corev1.NodeSpec{ProviderID: "provider://control-plane"},
+		Status: corev1.NodeStatus{Addresses: []corev1.NodeAddress{
+			{Type: corev1.NodeInternalIP, Address: "192.0.2.1"},
+		}},
+	}
+	scalingGroupIDs := map[string]struct{}{"group": {}}
+
+	testCases := map[string]struct {
+		node                corev1.Node
+		remediator          *stubNodeRemediator
+		etcdErr             error
+		deleteErr           error
+		wantRemediation     *updatev1alpha1.Remediation
+		wantErr             bool
+		wantEtcdRemoved     []string
+		wantDeletedProvider []string
+	}{
+		"worker node is replaced": {
+			node:       workerNode,
+			remediator: &stubNodeRemediator{scalingGroupID: "Group", createNodeName: "replacement", createProviderID: "provider://replacement"},
+			wantRemediation: &updatev1alpha1.Remediation{
+				NodeName:            "worker",
+				ProviderID:          "provider://worker",
+				ScalingGroupID:      "Group",
+				ReplacementNodeName: "replacement",
+				StartTime:           metav1.NewTime(now),
+			},
+			wantDeletedProvider: []string{"provider://worker"},
+		},
+		"control-plane node is removed from etcd": {
+			node:       controlPlaneNode,
+			remediator: &stubNodeRemediator{scalingGroupID: "group", createNodeName: "replacement", createProviderID: "provider://replacement"},
+			wantRemediation: &updatev1alpha1.Remediation{
+				NodeName:            "control-plane",
+				ProviderID:          "provider://control-plane",
+				ScalingGroupID:      "group",
+				ControlPlane:        true,
+				ReplacementNodeName: "replacement",
+				StartTime:           metav1.NewTime(now),
+			},
+			wantEtcdRemoved:     []string{"192.0.2.1"},
+			wantDeletedProvider: []string{"provider://control-plane"},
+		},
+		"failed replacement creation is retried later": {
+			node:       workerNode,
+			remediator: &stubNodeRemediator{scalingGroupID: "group", createErr: someErr},
+			wantRemediation: &updatev1alpha1.Remediation{
+				NodeName:       "worker",
+				ProviderID:     "provider://worker",
+				ScalingGroupID: "group",
+				StartTime:      metav1.NewTime(now),
+			},
+			wantDeletedProvider: []string{"provider://worker"},
+		},
+		"unknown scaling group is skipped": {
+			node:       workerNode,
+			remediator: &stubNodeRemediator{scalingGroupID: "other-group"},
+		},
+		"node without provider ID is skipped": {
+			node:       corev1.Node{ObjectMeta: metav1.ObjectMeta{Name: "worker"}},
+			remediator: &stubNodeRemediator{scalingGroupID: "group"},
+		},
+		"getting scaling group fails": {
+			node:       workerNode,
+			remediator: &stubNodeRemediator{scalingGroupIDErr: someErr},
+			wantErr:    true,
+		},
+		"removing etcd member fails": {
+			node:       controlPlaneNode,
+			remediator: &stubNodeRemediator{scalingGroupID: "group"},
+			etcdErr:    someErr,
+			wantErr:    true,
+		},
+		"deleting node fails": {
+			node:       workerNode,
+			remediator: &stubNodeRemediator{scalingGroupID: "group"},
+			deleteErr:  someErr,
+			wantErr:    true,
+		},
+	}
+
+	for name, tc := range testCases {
+		t.Run(name, func(t *testing.T) {
+			assert := assert.New(t)
+
+			etcd := &stubEtcdRemover{removeErr: tc.etcdErr}
+			reconciler := NodeHealthCheckReconciler{
+				nodeRemediator: tc.remediator,
+				etcdRemover:    etcd,
+				Client: &stubReadWriterClient{
+					stubReaderClient: *newStubReaderClient(t, nil, nil, nil),
+					stubWriterClient: stubWriterClient{deleteErr: tc.deleteErr},
+				},
+				Scheme: getScheme(t),
+				Clock:  testclock.NewFakeClock(now),
+			}
+			healthCheck := &updatev1alpha1.NodeHealthCheck{ObjectMeta: metav1.ObjectMeta{Name: "health-check", UID: "uid"}}
+
+			remediation, err := reconciler.startRemediation(context.Background(), healthCheck, tc.node, scalingGroupIDs)
+			if tc.wantErr {
+				assert.Error(err)
+				return
+			}
+			assert.NoError(err)
+			assert.Equal(tc.wantRemediation, remediation)
+			assert.Equal(tc.wantEtcdRemoved, etcd.removed)
+			assert.Equal(tc.wantDeletedProvider, tc.remediator.deleted)
+		})
+	}
+}
+
+func TestProgressRemediations(t *testing.T) {
+	now := time.Date(2022, 9, 1, 12, 0, 0, 0, time.UTC)
+	healthCheck := &updatev1alpha1.NodeHealthCheck{ObjectMeta: metav1.ObjectMeta{Name: "health-check", UID: "uid"}}
+	pendingNode := func(name string, goal updatev1alpha1.PendingNodeGoal, reachedGoal bool) updatev1alpha1.PendingNode {
+		pendingNode := updatev1alpha1.PendingNode{
+			ObjectMeta: metav1.ObjectMeta{Name: name},
+			Spec:       updatev1alpha1.PendingNodeSpec{NodeName: name, Goal: goal},
+			Status:     updatev1alpha1.PendingNodeStatus{ReachedGoal: reachedGoal},
+		}
+		require.NoError(t, ctrl.SetControllerReference(healthCheck, &pendingNode, getScheme(t)))
+		return pendingNode
+	}
+	remediation := func(replacement string) updatev1alpha1.Remediation {
+		return updatev1alpha1.Remediation{
+			NodeName:            "unhealthy",
+			ScalingGroupID:      "group",
+			ReplacementNodeName: replacement,
+			StartTime:           metav1.NewTime(now),
+		}
+	}
+
+	testCases := map[string]struct {
+		remediations     []updatev1alpha1.Remediation
+		pendingNodes     []updatev1alpha1.PendingNode
+		createErr        error
+		wantRemediations []updatev1alpha1.Remediation
+	}{
+		"joining replacement is in progress": {
+			remediations:     []updatev1alpha1.Remediation{remediation("replacement")},
+			pendingNodes:     []updatev1alpha1.PendingNode{pendingNode("replacement", updatev1alpha1.NodeGoalJoin, false)},
+			wantRemediations: []updatev1alpha1.Remediation{remediation("replacement")},
+		},
+		"joined replacement finishes remediation": {
+			remediations: []updatev1alpha1.Remediation{remediation("replacement")},
+			pendingNodes: []updatev1alpha1.PendingNode{pendingNode("replacement", updatev1alpha1.NodeGoalJoin, true)},
+		},
+		"removed pending node finishes remediation": {
+			remediations: []updatev1alpha1.Remediation{remediation("replacement")},
+		},
+		"replacement that failed to join is replaced": {
+			remediations:     []updatev1alpha1.Remediation{remediation("replacement")},
+			pendingNodes:     []updatev1alpha1.PendingNode{pendingNode("replacement", updatev1alpha1.NodeGoalLeave, false)},
+			wantRemediations: []updatev1alpha1.Remediation{remediation("new-replacement")},
+		},
+		"missing replacement is created": {
+			remediations:     []updatev1alpha1.Remediation{remediation("")},
+			wantRemediations: []updatev1alpha1.Remediation{remediation("new-replacement")},
+		},
+		"failed creation is retried": {
+			remediations:     []updatev1alpha1.Remediation{remediation("")},
+			createErr:        errors.New("failed"),
+			wantRemediations: []updatev1alpha1.Remediation{remediation("")},
+		},
+	}
+
+	for name, tc := range testCases {
+		t.Run(name, func(t *testing.T) {
+			assert := assert.New(t)
+
+			reconciler := NodeHealthCheckReconciler{
+				nodeRemediator: &stubNodeRemediator{createNodeName: "new-replacement", createProviderID: "provider://new", createErr: tc.createErr},
+				Client: &stubReadWriterClient{
+					stubReaderClient: *newStubReaderClient(t, nil, nil, nil),
+				},
+				Scheme: getScheme(t),
+				Clock:  testclock.NewFakeClock(now),
+			}
+			healthCheck := healthCheck.DeepCopy()
+			healthCheck.Status.Remediations = tc.remediations
+
+			remediations := reconciler.progressRemediations(context.Background(), healthCheck, tc.pendingNodes)
+			assert.Equal(tc.wantRemediations, remediations)
+		})
+	}
+}
+
+func TestNodeHealthCheckReconcile(t *testing.T) {
+	now := time.Date(2022, 9, 1, 12, 0, 0, 0, time.UTC)
+	notReady := func(name string) *corev1.Node {
+		return &corev1.Node{
+			ObjectMeta: metav1.ObjectMeta{Name: name},
+			Spec:       corev1.NodeSpec{ProviderID: "provider://" + name},
+			Status: corev1.NodeStatus{Conditions: []corev1.NodeCondition{
+				{Type: corev1.NodeReady, Status: corev1.ConditionFalse, LastTransitionTime: metav1.NewTime(now.Add(-time.Hour))},
+			}},
+		}
+	}
+	ready := func(name string) *corev1.Node {
+		return &corev1.Node{
+			ObjectMeta: metav1.ObjectMeta{Name: name},
+			Spec:       corev1.NodeSpec{ProviderID: "provider://" + name},
+			Status: corev1.NodeStatus{Conditions: []corev1.NodeCondition{
+				{Type: corev1.NodeReady, Status: corev1.ConditionTrue},
+			}},
+		}
+	}
+	scalingGroup := &updatev1alpha1.ScalingGroup{
+		ObjectMeta: metav1.ObjectMeta{Name: "group"},
+		Spec:       updatev1alpha1.ScalingGroupSpec{GroupID: "group"},
+	}
+
+	testCases := map[string]struct {
+		spec        updatev1alpha1.NodeHealthCheckSpec
+		objects     []runtime.Object
+		wantDeleted []string
+	}{
+		"unhealthy node is replaced": {
+			objects:     []runtime.Object{notReady("node-1"), ready("node-2"), ready("node-3")},
+			wantDeleted: []string{"provider://node-1"},
+		},
+		"concurrent remediations are limited": {
+			spec:        updatev1alpha1.NodeHealthCheckSpec{MaxUnhealthy: pointer.Int32(2)},
+			objects:     []runtime.Object{notReady("node-1"), notReady("node-2"), ready("node-3")},
+			wantDeleted: []string{"provider://node-1"},
+		},
+		"concurrent remediations can be increased": {
+			spec:        updatev1alpha1.NodeHealthCheckSpec{MaxUnhealthy: pointer.Int32(2), MaxConcurrentRemediations: 2},
+			objects:     []runtime.Object{notReady("node-1"), notReady("node-2"), ready("node-3")},
+			wantDeleted: []string{"provider://node-1", "provider://node-2"},
+		},
+		"too many unhealthy nodes": {
+			objects: []runtime.Object{notReady("node-1"), notReady("node-2"), ready("node-3")},
+		},
+		"paused": {
+			spec:    updatev1alpha1.NodeHealthCheckSpec{Paused: true},
+			objects: []runtime.Object{notReady("node-1"), ready("node-2"), ready("node-3")},
+		},
+		"node being drained is not remediated": {
+			objects: []runtime.Object{
+				notReady("node-1"), ready("node-2"), ready("node-3"),
+				&updatev1alpha1.NodeDrain{ObjectMeta: metav1.ObjectMeta{Name: "node-1"}, Spec: updatev1alpha1.NodeDrainSpec{NodeName: "node-1"}},
+			},
+		},
+		"unselected node is not remediated": {
+			spec: updatev1alpha1.NodeHealthCheckSpec{
+				Selector: &metav1.LabelSelector{MatchLabels: map[string]string{"pool": "a"}},
+			},
+			objects: []runtime.Object{notReady("node-1"), ready("node-2"), ready("node-3")},
+		},
+	}
+
+	for name, tc := range testCases {
+		t.Run(name, func(t *testing.T) {
+			assert := assert.New(t)
+			require := require.New(t)
+
+			healthCheck := &updatev1alpha1.NodeHealthCheck{
+				ObjectMeta: metav1.ObjectMeta{Name: "health-check", UID: "uid"},
+				Spec:       tc.spec,
+			}
+			objects := append([]runtime.Object{healthCheck, scalingGroup}, tc.objects...)
+			remediator := &stubNodeRemediator{
+				scalingGroupID:   "group",
+				createNodeName:   "replacement",
+				createProviderID: "provider://replacement",
+			}
+			reconciler := NodeHealthCheckReconciler{
+				nodeRemediator: remediator,
+				etcdRemover:    &stubEtcdRemover{},
+				Client: &stubReadWriterClient{
+					stubReaderClient: *newStubReaderClient(t, objects, nil, nil),
+				},
+				Scheme: getScheme(t),
+				Clock:  testclock.NewFakeClock(now),
+			}
+
+			result, err := reconciler.Reconcile(context.Background(), ctrl.Request{NamespacedName: client.ObjectKeyFromObject(healthCheck)})
+			require.NoError(err)
+			assert.Equal(healthCheckInterval, result.RequeueAfter)
+			assert.ElementsMatch(tc.wantDeleted, remediator.deleted)
+		})
+	}
+}
+
+func TestRemediationAllowedCondition(t *testing.T) {
+	testCases := map[string]struct {
+		spec           updatev1alpha1.NodeHealthCheckSpec
+		observedNodes  int
+		unhealthyNodes int
+		wantStatus     metav1.ConditionStatus
+		wantReason     string
+	}{
+		"allowed": {
+			observedNodes:  3,
+			unhealthyNodes: 1,
+			wantStatus:     metav1.ConditionTrue,
+			wantReason:     conditionRemediationAllowedReason,
+		},
+		"more than half unhealthy": {
+			observedNodes:  3,
+			unhealthyNodes: 2,
+			wantStatus:     metav1.ConditionFalse,
+			wantReason:     conditionTooManyUnhealthyReason,
+		},
+		"custom maximum": {
+			spec:           updatev1alpha1.NodeHealthCheckSpec{MaxUnhealthy: pointer.Int32(2)},
+			observedNodes:  3,
+			unhealthyNodes: 2,
+			wantStatus:     metav1.ConditionTrue,
+			wantReason:     conditionRemediationAllowedReason,
+		},
+		"custom maximum exceeded": {
+			spec:           updatev1alpha1.NodeHealthCheckSpec{MaxUnhealthy: pointer.Int32(0)},
+			observedNodes:  10,
+			unhealthyNodes: 1,
+			wantStatus:     metav1.ConditionFalse,
+			wantReason:     conditionTooManyUnhealthyReason,
+		},
+		"paused": {
+			spec:          updatev1alpha1.NodeHealthCheckSpec{Paused: true},
+			observedNodes: 3,
+			wantStatus:    metav1.ConditionFalse,
+			wantReason:    conditionRemediationPausedReason,
+		},
+	}
+
+	for name, tc := range testCases {
+		t.Run(name, func(t *testing.T) {
+			assert := assert.New(t)
+
+			condition := remediationAllowedCondition(tc.spec, tc.observedNodes, tc.unhealthyNodes)
+			assert.Equal(updatev1alpha1.ConditionRemediationAllowed, condition.Type)
+			assert.Equal(tc.wantStatus, condition.Status)
+			assert.Equal(tc.wantReason, condition.Reason)
+		})
+	}
+}
+
+func TestHasControlPlaneQuorum(t *testing.T) {
+	controlPlane := func(ready bool) corev1.Node {
+		status := corev1.ConditionFalse
+		if ready {
+			status = corev1.ConditionTrue
+		}
+		return corev1.Node{
+			ObjectMeta: metav1.ObjectMeta{Labels: map[string]string{controlPlaneRoleLabel: ""}},
+			Status: corev1.NodeStatus{Conditions: []corev1.NodeCondition{
+				{Type: corev1.NodeReady, Status: status},
+			}},
+		}
+	}
+
+	testCases := map[string]struct {
+		nodes      []corev1.Node
+		wantQuorum bool
+	}{
+		"single unhealthy control-plane node": {
+			nodes: []corev1.Node{controlPlane(false)},
+		},
+		"one of two control-plane nodes unhealthy": {
+			nodes: []corev1.Node{controlPlane(true), controlPlane(false)},
+		},
+		"one of three control-plane nodes unhealthy": {
+			nodes:      []corev1.Node{controlPlane(true), controlPlane(true), controlPlane(false)},
+			wantQuorum: true,
+		},
+		"two of three control-plane nodes unhealthy": {
+			nodes: []corev1.Node{controlPlane(true), controlPlane(false), controlPlane(false)},
+		},
+		"worker nodes are ignored": {
+			nodes:      []corev1.Node{controlPlane(true), {}, {}},
+			wantQuorum: true,
+		},
+	}
+
+	for name, tc := range testCases {
+		t.Run(name, func(t *testing.T) {
+			assert.Equal(t, tc.wantQuorum, hasControlPlaneQuorum(tc.nodes))
+		})
+	}
+}
+
+type stubNodeRemediator struct {
+	nodeStates        map[string]updatev1alpha1.CSPNodeState
+	nodeStateErr      error
+	scalingGroupID    string
+	scalingGroupIDErr error
+	createNodeName    string
+	createProviderID  string
+	createErr         error
+	deleteErr         error
+
+	nodeStateRequests []string
+	deleted           []string
+}
+
+func (r *stubNodeRemediator) GetNodeState(_ context.Context, providerID string) (updatev1alpha1.CSPNodeState, error) {
+	r.nodeStateRequests = append(r.nodeStateRequests, providerID)
+	return r.nodeStates[providerID], r.nodeStateErr
+}
+
+func (r *stubNodeRemediator) GetScalingGroupID(_ context.Context, _ string) (string, error) {
+	return r.scalingGroupID, r.scalingGroupIDErr
+}
+
+func (r *stubNodeRemediator) CreateNode(_ context.Context, _ string) (nodeName, providerID string, err error) {
+	return r.createNodeName, r.createProviderID, r.createErr
+}
+
+func (r *stubNodeRemediator) DeleteNode(_ context.Context, providerID string) error {
+	r.deleted = append(r.deleted, providerID)
+	return r.deleteErr
+}
+
+type stubEtcdRemover struct {
+	removeErr error
+	removed   []string
+}
+
+func (r *stubEtcdRemover) RemoveEtcdMemberFromCluster(_ context.Context, vpcIP string) error {
+	r.removed = append(r.removed, vpcIP)
+	return r.removeErr
+}
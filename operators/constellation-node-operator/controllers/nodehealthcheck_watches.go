@@ -0,0 +1,79 @@
+/*
+Copyright (c) Edgeless Systems GmbH
+
+SPDX-License-Identifier: AGPL-3.0-only
+*/
+
+package controllers
+
+import (
+	"context"
+
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/types"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/event"
+	"sigs.k8s.io/controller-runtime/pkg/predicate"
+	"sigs.k8s.io/controller-runtime/pkg/reconcile"
+
+	updatev1alpha1 "github.com/edgelesssys/constellation/operators/constellation-node-operator/v2/api/v1alpha1"
+)
+
+// nodeConditionsChangedPredicate filters events on Node resources to those that change the status of a node condition.
+func nodeConditionsChangedPredicate() predicate.Predicate {
+	return predicate.Funcs{
+		CreateFunc: func(e event.CreateEvent) bool {
+			return true
+		},
+		UpdateFunc: func(e event.UpdateEvent) bool {
+			oldNode, ok := e.ObjectOld.(*corev1.Node)
+			if !ok {
+				return false
+			}
+			newNode, ok := e.ObjectNew.(*corev1.Node)
+			if !ok {
+				return false
+			}
+			return !equalConditionStatus(oldNode.Status.Conditions, newNode.Status.Conditions)
+		},
+		DeleteFunc: func(e event.DeleteEvent) bool {
+			return true
+		},
+		GenericFunc: func(e event.GenericEvent) bool {
+			return false
+		},
+	}
+}
+
+// equalConditionStatus returns true if both lists contain the same condition types with the same status.
+func equalConditionStatus(a, b []corev1.NodeCondition) bool {
+	if len(a) != len(b) {
+		return false
+	}
+	statusByType := make(map[corev1.NodeConditionType]corev1.ConditionStatus, len(a))
+	for _, condition := range a {
+		statusByType[condition.Type] = condition.Status
+	}
+	for _, condition := range b {
+		if status, ok := statusByType[condition.Type]; !ok || status != condition.Status {
+			return false
+		}
+	}
+	return true
+}
+
+// findAllNodeHealthChecks requests a reconcile call for all node health checks.
+func (r *NodeHealthCheckReconciler) findAllNodeHealthChecks(_ client.Object) []reconcile.Request {
+	var healthCheckList updatev1alpha1.NodeHealthCheckList
+	err := r.List(context.TODO(), &healthCheckList)
+	if err != nil {
+		return []reconcile.Request{}
+	}
+	requests := make([]reconcile.Request, len(healthCheckList.Items))
+	for i, item := range healthCheckList.Items {
+		requests[i] = reconcile.Request{
+			NamespacedName: types.NamespacedName{Name: item.GetName()},
+		}
+	}
+	return requests
+}
@@ -19,6 +19,7 @@ import (
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	"k8s.io/apimachinery/pkg/runtime"
 	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/client-go/tools/record"
 	ref "k8s.io/client-go/tools/reference"
 	"k8s.io/client-go/util/retry"
 	ctrl "sigs.k8s.io/controller-runtime"
@@ -29,16 +30,13 @@ import (
 	"sigs.k8s.io/controller-runtime/pkg/source"
 
 	updatev1alpha1 "github.com/edgelesssys/constellation/operators/constellation-node-operator/v2/api/v1alpha1"
-	nodemaintenancev1beta1 "github.com/medik8s/node-maintenance-operator/api/v1beta1"
 )
 
 const (
-	// nodeOverprovisionLimit is the maximum number of extra nodes created during the update procedure at any point in time.
-	nodeOverprovisionLimit = 1
 	// nodeJoinTimeout is the time limit pending nodes have to join the cluster before being terminated.
 	nodeJoinTimeout = time.Minute * 30
-	// nodeLeaveTimeout is the time limit pending nodes have to leave the cluster and being terminated.
-	nodeLeaveTimeout                   = time.Minute
+	// nodeReplacementReason is the reason of node drains before outdated nodes are removed.
+	nodeReplacementReason              = "node is replaced due to OS image update"
 	donorAnnotation                    = "constellation.edgeless.systems/donor"
 	heirAnnotation                     = "constellation.edgeless.systems/heir"
 	scalingGroupAnnotation             = "constellation.edgeless.systems/scaling-group-id"
@@ -48,6 +46,17 @@ const (
 	conditionNodeImageUpToDateMessage  = "Node image of every node is up to date"
 	conditionNodeImageOutOfDateReason  = "NodeImagesOutOfDate"
 	conditionNodeImageOutOfDateMessage = "Some node images are out of date"
+	conditionNodeDrainsFailedReason    = "DrainFailed"
+	conditionNodeDrainsFailedTemplate  = "Draining nodes failed, see their NodeDrains: %s"
+	conditionNodeDrainsHealthyReason   = "NoDrainFailed"
+	conditionNodeDrainsHealthyMessage  = "No node drain failed"
+	// event reasons of the node replacement steps.
+	eventReasonHeirCreated  = "HeirCreated"
+	eventReasonDrainStarted = "DrainStarted"
+	eventReasonDrainFailed  = "DrainFailed"
+	eventReasonDonorDrained = "DonorDrained"
+	eventReasonNodeDeleted  = "NodeDeleted"
+	eventReasonNodeReplaced = "NodeReplaced"
 )
 
 // NodeImageReconciler reconciles a NodeImage object.
@@ -55,25 +64,28 @@ type NodeImageReconciler struct {
 	nodeReplacer
 	etcdRemover
 	client.Client
-	Scheme *runtime.Scheme
+	Scheme   *runtime.Scheme
+	Recorder record.EventRecorder
 }
 
 // NewNodeImageReconciler creates a new NodeImageReconciler.
-func NewNodeImageReconciler(nodeReplacer nodeReplacer, etcdRemover etcdRemover, client client.Client, scheme *runtime.Scheme) *NodeImageReconciler {
+func NewNodeImageReconciler(nodeReplacer nodeReplacer, etcdRemover etcdRemover, client client.Client, scheme *runtime.Scheme, recorder record.EventRecorder) *NodeImageReconciler {
 	return &NodeImageReconciler{
 		nodeReplacer: nodeReplacer,
 		etcdRemover:  etcdRemover,
 		Client:       client,
 		Scheme:       scheme,
+		Recorder:     recorder,
 	}
 }
 
 //+kubebuilder:rbac:groups=update.edgeless.systems,resources=nodeimages,verbs=get;list;watch;create;update;patch;delete
 //+kubebuilder:rbac:groups=update.edgeless.systems,resources=nodeimages/status,verbs=get;update;patch
 //+kubebuilder:rbac:groups=update.edgeless.systems,resources=nodeimages/finalizers,verbs=update
-//+kubebuilder:rbac:groups=nodemaintenance.medik8s.io,resources=nodemaintenances,verbs=get;list;watch;create;update;patch;delete
+//+kubebuilder:rbac:groups=update.edgeless.systems,resources=nodedrains,verbs=get;list;watch;create;update;patch;delete
 //+kubebuilder:rbac:groups="",resources=nodes,verbs=get;list;watch;create;update;patch;delete
 //+kubebuilder:rbac:groups="",resources=nodes/status,verbs=get
+//+kubebuilder:rbac:groups="",resources=events,verbs=create;patch
 
 // Reconcile replaces outdated nodes (using an old image) with new nodes (using a new image) as specified in the NodeImage spec.
 func (r *NodeImageReconciler) Reconcile(ctx context.Context, req ctrl.Request) (ctrl.Result, error) {
@@ -133,6 +145,19 @@ func (r *NodeImageReconciler) Reconcile(ctx context.Context, req ctrl.Request) (
 		"pendingNodes", len(pendingNodeList.Items),
 		"obsoleteNodes", len(groups.Obsolete),
 		"invalidNodes", len(invalidNodes))
+	recordNodeMetrics(groups, pendingNodeList.Items, invalidNodes, scalingGroupByID)
+
+	strategy := desiredNodeImage.Spec.Strategy
+	rollout := rolloutProgress(desiredNodeImage.Status.Rollout, desiredNodeImage.Spec, groups, pendingNodeList.Items)
+	if canaryJustCompleted(desiredNodeImage.Status.Rollout, rollout) && rollout.Phase == updatev1alpha1.RolloutPhaseProgressing {
+		logr.Info("Canary nodes replaced, pausing rollout", "replacedNodes", rollout.Replaced)
+		if err := r.pauseRollout(ctx, req.NamespacedName); err != nil {
+			logr.Error(err, "Unable to pause rollout")
+			return ctrl.Result{}, err
+		}
+		strategy.Paused = true
+		rollout.Phase = updatev1alpha1.RolloutPhasePaused
+	}
 
 	// extraNodes are nodes that exist in the scaling group which cannot be used for regular workloads.
 	// consists of nodes that are
@@ -141,13 +166,32 @@ func (r *NodeImageReconciler) Reconcile(ctx context.Context, req ctrl.Request) (
 	// - heirs to outdated nodes
 	extraNodes := len(groups.Heirs) + len(pendingNodeList.Items)
 	// newNodesBudget is the maximum number of new nodes that can be created in this Reconcile call.
-	var newNodesBudget int
-	if extraNodes < nodeOverprovisionLimit {
-		newNodesBudget = nodeOverprovisionLimit - extraNodes
+	newNodesBudget := replacementBudget(strategy, rollout, extraNodes)
+	// replacements that are in progress are finished outside of maintenance windows, but no new nodes are created.
+	now := time.Now()
+	maintenanceWindow := checkMaintenanceWindow(desiredNodeImage.Spec.MaintenanceWindow, now)
+	if maintenanceWindow.err != nil {
+		logr.Error(maintenanceWindow.err, "Invalid maintenance window")
+	}
+	if !maintenanceWindow.open {
+		logr.Info("Maintenance window is closed, not creating new nodes", "nextWindow", maintenanceWindow.nextOpen)
+		newNodesBudget = 0
 	}
 	logr.Info("Budget for new nodes", "newNodesBudget", newNodesBudget)
 
+	// nodes under maintenance are already being drained and count towards the unavailable nodes
+	nodesUnderMaintenance, err := r.nodesUnderMaintenance(ctx)
+	if err != nil {
+		logr.Error(err, "Unable to list node maintenances")
+		return ctrl.Result{}, err
+	}
+
 	status := nodeImageStatus(r.Scheme, groups, pendingNodeList.Items, invalidNodes, newNodesBudget)
+	status.Rollout = rollout
+	if desiredNodeImage.Spec.MaintenanceWindow != nil {
+		meta.SetStatusCondition(&status.Conditions, maintenanceWindow.condition(len(groups.Outdated) > 0))
+	}
+	meta.SetStatusCondition(&status.Conditions, drainFailedCondition(nodesUnderMaintenance))
 	if err := r.tryUpdateStatus(ctx, req.NamespacedName, status); err != nil {
 		logr.Error(err, "Updating status")
 	}
@@ -165,12 +209,50 @@ func (r *NodeImageReconciler) Reconcile(ctx context.Context, req ctrl.Request) (
 
 	// should requeue is set if a node is deleted
 	var shouldRequeue bool
+	// requeueAfter is set if a replacement node has to be ready for longer before its donor is removed
+	// or if outdated nodes are waiting for the next maintenance window
+	requeueAfter := maintenanceWindow.requeueAfter(now)
 	// find pairs of mint nodes and outdated nodes in the same scaling group to become donor & heir
+	// new nodes are only created in maintenance windows, so mint nodes are paired and drained outside of windows as well.
+	// Otherwise, heirs created at the end of a window would wait for the next window with autoscaling disabled.
 	replacementPairs := r.pairDonorsAndHeirs(ctx, &desiredNodeImage, groups.Outdated, groups.Mint)
 	// extend replacement pairs to include existing pairs of donors and heirs
 	replacementPairs = r.matchDonorsAndHeirs(ctx, replacementPairs, groups.Donors, groups.Heirs)
+	unavailableNodes := 0
+	for _, pendingNode := range pendingNodeList.Items {
+		if pendingNode.Spec.Goal == updatev1alpha1.NodeGoalLeave {
+			unavailableNodes++
+		}
+	}
+	for _, pair := range replacementPairs {
+		if _, ok := nodesUnderMaintenance[pair.donor.Name]; ok {
+			unavailableNodes++
+		}
+	}
 	// replace donor nodes by heirs
 	for _, pair := range replacementPairs {
+		if _, draining := nodesUnderMaintenance[pair.donor.Name]; !draining {
+			if strategy.Paused {
+				logr.Info("Rollout is paused, not replacing node", "donorNode", pair.donor.Name, "heirNode", pair.heir.Name)
+				continue
+			}
+			if unavailableNodes >= maxUnavailable(strategy) {
+				logr.Info("Too many unavailable nodes, not replacing node", "donorNode", pair.donor.Name, "unavailableNodes", unavailableNodes)
+				continue
+			}
+			healthy, wait := passesHealthGate(&pair.heir, strategy.HealthGate, now)
+			if !healthy {
+				logr.Info("Heir did not pass health gate yet", "heirNode", pair.heir.Name)
+				if wait > 0 && (requeueAfter == 0 || wait < requeueAfter) {
+					requeueAfter = wait
+				}
+				if err := r.inheritLabels(ctx, pair); err != nil {
+					return ctrl.Result{}, err
+				}
+				continue
+			}
+			unavailableNodes++
+		}
 		logr.Info("Replacing node", "donorNode", pair.donor.Name, "heirNode", pair.heir.Name)
 		done, err := r.replaceNode(ctx, &desiredNodeImage, pair)
 		if err != nil {
@@ -179,6 +261,11 @@ func (r *NodeImageReconciler) Reconcile(ctx context.Context, req ctrl.Request) (
 		}
 		if done {
 			shouldRequeue = true
+			recordNodeReplacement(pair, scalingGroupByID, time.Now())
+			r.Recorder.Eventf(&desiredNodeImage, corev1.EventTypeNormal, eventReasonNodeReplaced, "Replaced node %s by %s", pair.donor.Name, pair.heir.Name)
+			if scalingGroup, ok := scalingGroupByID[strings.ToLower(pair.donor.Annotations[scalingGroupAnnotation])]; ok {
+				r.Recorder.Eventf(&scalingGroup, corev1.EventTypeNormal, eventReasonNodeReplaced, "Replaced node %s by %s", pair.donor.Name, pair.heir.Name)
+			}
 			// remove donor annotation from heir
 			if err := r.patchUnsetNodeAnnotations(ctx, pair.heir.Name, []string{donorAnnotation}); err != nil {
 				logr.Error(err, "Unable to remove donor annotation from heir", "heirNode", pair.heir.Name)
@@ -189,11 +276,16 @@ func (r *NodeImageReconciler) Reconcile(ctx context.Context, req ctrl.Request) (
 	// only create new nodes if the autoscaler is disabled.
 	// otherwise, new nodes will also be created by the autoscaler
 	if autoscalingEnabled {
-		return ctrl.Result{Requeue: shouldRequeue}, nil
+		return ctrl.Result{Requeue: shouldRequeue, RequeueAfter: requeueAfter}, nil
 	}
 
-	if err := r.createNewNodes(ctx, desiredNodeImage, groups.Outdated, pendingNodeList.Items, scalingGroupByID, newNodesBudget); err != nil {
-		return ctrl.Result{Requeue: shouldRequeue}, nil
+	// only replace nodes of the roles that are next in the rollout order
+	replaceControlPlane, replaceWorker := rolloutRoles(strategy.Order, append(append([]corev1.Node{}, groups.Outdated...), groups.Donors...))
+	outdatedNodes := filterByRole(groups.Outdated, replaceControlPlane, replaceWorker)
+	if err := r.createNewNodes(ctx, desiredNodeImage, outdatedNodes, pendingNodeList.Items, scalingGroupByID, newNodesBudget); err != nil {
+		// retry creating nodes with backoff. Nothing else triggers a Reconcile call if node creation failed.
+		logr.Error(err, "Unable to create new nodes")
+		return ctrl.Result{Requeue: true}, nil
 	}
 	// cleanup obsolete nodes
 	for _, node := range groups.Obsolete {
@@ -206,7 +298,7 @@ func (r *NodeImageReconciler) Reconcile(ctx context.Context, req ctrl.Request) (
 		}
 	}
 
-	return ctrl.Result{Requeue: shouldRequeue}, nil
+	return ctrl.Result{Requeue: shouldRequeue, RequeueAfter: requeueAfter}, nil
 }
 
 // SetupWithManager sets up the controller with the Manager.
@@ -229,9 +321,9 @@ func (r *NodeImageReconciler) SetupWithManager(mgr ctrl.Manager) error {
 			builder.WithPredicates(nodeReadyPredicate()),
 		).
 		Watches(
-			&source.Kind{Type: &nodemaintenancev1beta1.NodeMaintenance{}},
+			&source.Kind{Type: &updatev1alpha1.NodeDrain{}},
 			handler.EnqueueRequestsFromMapFunc(r.findAllNodeImages),
-			builder.WithPredicates(nodeMaintenanceSucceededPredicate()),
+			builder.WithPredicates(nodeDrainFinishedPredicate()),
 		).
 		Owns(&updatev1alpha1.PendingNode{}).
 		Complete(r)
@@ -284,7 +376,7 @@ func (r *NodeImageReconciler) annotateNodes(ctx context.Context, nodes []corev1.
 
 // pairDonorsAndHeirs takes a list of outdated nodes (that do not yet have a heir node) and a list of mint nodes (nodes using the latest image) and pairs matching nodes to become donor and heir.
 // outdatedNodes is also updated with heir annotations.
-func (r *NodeImageReconciler) pairDonorsAndHeirs(ctx context.Context, controller metav1.Object, outdatedNodes []corev1.Node, mintNodes []mintNode) []replacementPair {
+func (r *NodeImageReconciler) pairDonorsAndHeirs(ctx context.Context, desiredNodeImage *updatev1alpha1.NodeImage, outdatedNodes []corev1.Node, mintNodes []mintNode) []replacementPair {
 	logr := log.FromContext(ctx)
 	var pairs []replacementPair
 	for _, mintNode := range mintNodes {
@@ -332,7 +424,7 @@ func (r *NodeImageReconciler) pairDonorsAndHeirs(ctx context.Context, controller
 				logr.Error(err, "Unable to update mint node obsolete annotation", "mintNode", mintNode.node.Name)
 				break
 			}
-			if _, err := r.deleteNode(ctx, controller, mintNode.node); err != nil {
+			if _, err := r.deleteNode(ctx, desiredNodeImage, mintNode.node); err != nil {
 				logr.Error(err, "Unable to delete obsolete node", "obsoleteNode", mintNode.node.Name)
 				break
 			}
@@ -417,48 +509,61 @@ func (r *NodeImageReconciler) ensureAutoscaling(ctx context.Context, autoscaling
 // Labels are copied from the donor node to the heir node.
 // Readiness of the heir node is awaited.
 // Deletion of the donor node is scheduled.
-func (r *NodeImageReconciler) replaceNode(ctx context.Context, controller metav1.Object, pair replacementPair) (bool, error) {
+func (r *NodeImageReconciler) replaceNode(ctx context.Context, desiredNodeImage *updatev1alpha1.NodeImage, pair replacementPair) (bool, error) {
+	if err := r.inheritLabels(ctx, pair); err != nil {
+		return false, err
+	}
+	heirReady := nodeutil.Ready(&pair.heir)
+	if !heirReady {
+		return false, nil
+	}
+	return r.deleteNode(ctx, desiredNodeImage, pair.donor)
+}
+
+// inheritLabels copies the labels of the donor node to the heir node, if they differ.
+func (r *NodeImageReconciler) inheritLabels(ctx context.Context, pair replacementPair) error {
 	logr := log.FromContext(ctx)
 	if !reflect.DeepEqual(nodeutil.FilterLabels(pair.donor.Labels), nodeutil.FilterLabels(pair.heir.Labels)) {
 		if err := r.copyNodeLabels(ctx, pair.donor.Name, pair.heir.Name); err != nil {
 			logr.Error(err, "Copy node labels")
-			return false, err
+			return err
 		}
 	}
-	heirReady := nodeutil.Ready(&pair.heir)
-	if !heirReady {
-		return false, nil
-	}
-	return r.deleteNode(ctx, controller, pair.donor)
+	return nil
 }
 
 // deleteNode safely removes a node from the cluster and issues termination of the node by the CSP.
-func (r *NodeImageReconciler) deleteNode(ctx context.Context, controller metav1.Object, node corev1.Node) (bool, error) {
+// If draining the node failed, the node is only removed if the drain failure policy is "Force".
+func (r *NodeImageReconciler) deleteNode(ctx context.Context, desiredNodeImage *updatev1alpha1.NodeImage, node corev1.Node) (bool, error) {
 	logr := log.FromContext(ctx)
-	// cordon & drain node using node-maintenance-operator
-	var foundNodeMaintenance nodemaintenancev1beta1.NodeMaintenance
-	err := r.Get(ctx, types.NamespacedName{Name: node.Name}, &foundNodeMaintenance)
+	// cordon & drain node
+	var foundNodeDrain updatev1alpha1.NodeDrain
+	err := r.Get(ctx, types.NamespacedName{Name: node.Name}, &foundNodeDrain)
 	if client.IgnoreNotFound(err) != nil {
 		// unexpected error occurred
 		return false, err
 	}
 	if err != nil {
-		// NodeMaintenance resource does not exist yet
-		nodeMaintenance := nodemaintenancev1beta1.NodeMaintenance{
-			ObjectMeta: metav1.ObjectMeta{
-				Name: node.Name,
-			},
-			Spec: nodemaintenancev1beta1.NodeMaintenanceSpec{
-				NodeName: node.Name,
-				Reason:   "node is replaced due to OS image update",
-			},
-		}
-		return false, r.Create(ctx, &nodeMaintenance)
-	}
-
-	// NodeMaintenance resource already exists. Check cordon & drain status.
-	if foundNodeMaintenance.Status.Phase != nodemaintenancev1beta1.MaintenanceSucceeded {
-		logr.Info("Cordon & drain in progress", "maintenanceNode", node.Name, "nodeMaintenanceStatus", foundNodeMaintenance.Status.Phase)
+		// NodeDrain resource does not exist yet
+		if err := r.Create(ctx, newNodeDrain(node.Name, nodeReplacementReason, desiredNodeImage.Spec.Drain)); err != nil {
+			return false, err
+		}
+		r.recordNodeEvent(desiredNodeImage, &node, corev1.EventTypeNormal, eventReasonDrainStarted, "Draining node %s", node.Name)
+		return false, nil
+	}
+
+	// NodeDrain resource already exists. Check cordon & drain status.
+	switch {
+	case foundNodeDrain.Status.Phase == updatev1alpha1.NodeDrainPhaseSucceeded:
+		r.recordNodeEvent(desiredNodeImage, &node, corev1.EventTypeNormal, eventReasonDonorDrained, "Drained node %s", node.Name)
+	case foundNodeDrain.Status.Phase == updatev1alpha1.NodeDrainPhaseFailed && foundNodeDrain.Spec.FailurePolicy == updatev1alpha1.DrainFailurePolicyForce:
+		logr.Info("Draining node failed, forcing removal", "drainNode", node.Name)
+		r.recordNodeEvent(desiredNodeImage, &node, corev1.EventTypeWarning, eventReasonDrainFailed, "Draining node %s failed, removing it anyway", node.Name)
+	default:
+		logr.Info("Cordon & drain in progress", "drainNode", node.Name, "nodeDrainPhase", foundNodeDrain.Status.Phase)
+		if foundNodeDrain.Status.Phase == updatev1alpha1.NodeDrainPhaseFailed {
+			r.recordNodeEvent(desiredNodeImage, &node, corev1.EventTypeWarning, eventReasonDrainFailed, "Draining node %s failed, see NodeDrain %s", node.Name, foundNodeDrain.Name)
+		}
 		return false, nil
 	}
 
@@ -480,14 +585,15 @@ func (r *NodeImageReconciler) deleteNode(ctx context.Context, controller metav1.
 	}
 
 	logr.Info("Deleted node", "deletedNode", node.Name)
+	r.recordNodeEvent(desiredNodeImage, &node, corev1.EventTypeNormal, eventReasonNodeDeleted, "Deleted node %s", node.Name)
 	// schedule deletion of the node with the CSP
 	if err := r.DeleteNode(ctx, node.Spec.ProviderID); err != nil {
 		logr.Error(err, "Scheduling CSP node deletion", "providerID", node.Spec.ProviderID)
 	}
-	deadline := metav1.NewTime(time.Now().Add(nodeLeaveTimeout))
+	deadline := metav1.NewTime(time.Now().Add(nodeLeaveTimeout(desiredNodeImage.Spec.Strategy)))
 	pendingNode := updatev1alpha1.PendingNode{
 		ObjectMeta: metav1.ObjectMeta{
-			Namespace: controller.GetNamespace(),
+			Namespace: desiredNodeImage.GetNamespace(),
 			Name:      node.Name,
 		},
 		Spec: updatev1alpha1.PendingNodeSpec{
@@ -498,7 +604,7 @@ func (r *NodeImageReconciler) deleteNode(ctx context.Context, controller metav1.
 			Deadline:       &deadline,
 		},
 	}
-	if err := ctrl.SetControllerReference(controller, &pendingNode, r.Scheme); err != nil {
+	if err := ctrl.SetControllerReference(desiredNodeImage, &pendingNode, r.Scheme); err != nil {
 		return false, err
 	}
 	if err := r.Create(ctx, &pendingNode); err != nil {
@@ -583,6 +689,8 @@ func (r *NodeImageReconciler) createNewNodes(
 				return err
 			}
 			logr.Info("Created new node", "createdNode", nodeName, "scalingGroup", scalingGroupID)
+			r.Recorder.Eventf(&desiredNodeImage, corev1.EventTypeNormal, eventReasonHeirCreated, "Created node %s in scaling group %s", nodeName, scalingGroup.Name)
+			r.Recorder.Eventf(&scalingGroup, corev1.EventTypeNormal, eventReasonHeirCreated, "Created node %s", nodeName)
 			requiredNodesPerScalingGroup[scalingGroupID]--
 			newNodesBudget--
 		}
@@ -590,6 +698,12 @@ func (r *NodeImageReconciler) createNewNodes(
 	return nil
 }
 
+// recordNodeEvent emits an event for a node replacement step on both the NodeImage and the node.
+func (r *NodeImageReconciler) recordNodeEvent(desiredNodeImage *updatev1alpha1.NodeImage, node *corev1.Node, eventtype, reason, messageFmt string, args ...any) {
+	r.Recorder.Eventf(desiredNodeImage, eventtype, reason, messageFmt, args...)
+	r.Recorder.Eventf(node, eventtype, reason, messageFmt, args...)
+}
+
 // patchNodeAnnotations attempts to patch node annotations in a retry loop.
 func (r *NodeImageReconciler) patchNodeAnnotations(ctx context.Context, nodeName string, annotations map[string]string) error {
 	return retry.RetryOnConflict(retry.DefaultRetry, func() error {
@@ -790,7 +904,8 @@ func groupNodes(nodes []corev1.Node, pendingNodes []updatev1alpha1.PendingNode,
 			groups.Heirs = append(groups.Heirs, node)
 			continue
 		}
-		if pendingNode := nodeutil.FindPending(pendingNodes, &node); pendingNode != nil {
+		// replacements of unhealthy nodes are not heirs, they are tracked by their NodeHealthCheck
+		if pendingNode := nodeutil.FindPending(pendingNodes, &node); pendingNode != nil && !isRemediationReplacement(pendingNode) {
 			groups.Mint = append(groups.Mint, mintNode{
 				node:        node,
 				pendingNode: *pendingNode,
@@ -20,7 +20,6 @@ import (
 	"k8s.io/apimachinery/pkg/types"
 
 	updatev1alpha1 "github.com/edgelesssys/constellation/operators/constellation-node-operator/v2/api/v1alpha1"
-	nodemaintenancev1beta1 "github.com/medik8s/node-maintenance-operator/api/v1beta1"
 )
 
 var _ = Describe("NodeImage controller", func() {
@@ -43,7 +42,7 @@ var _ = Describe("NodeImage controller", func() {
 	nodeImageLookupKey := types.NamespacedName{Name: nodeImageResourceName}
 	scalingGroupLookupKey := types.NamespacedName{Name: scalingGroupID}
 	joiningPendingNodeLookupKey := types.NamespacedName{Name: secondNodeName}
-	nodeMaintenanceLookupKey := types.NamespacedName{Name: firstNodeName}
+	nodeDrainLookupKey := types.NamespacedName{Name: firstNodeName}
 
 	Context("When updating the cluster-wide node image", func() {
 		It("Should update every node in the cluster", func() {
@@ -247,18 +246,18 @@ var _ = Describe("NodeImage controller", func() {
 			}
 			Expect(k8sClient.Status().Update(ctx, secondNode)).Should(Succeed())
 
-			By("waiting for a NodeMaintenance resource to be created")
-			nodeMaintenance := &nodemaintenancev1beta1.NodeMaintenance{}
+			By("waiting for a NodeDrain resource to be created")
+			nodeDrain := &updatev1alpha1.NodeDrain{}
 			Eventually(func() error {
-				return k8sClient.Get(ctx, nodeMaintenanceLookupKey, nodeMaintenance)
+				return k8sClient.Get(ctx, nodeDrainLookupKey, nodeDrain)
 			}, timeout, interval).Should(Succeed())
 
-			By("marking the NodeMaintenance as successful")
+			By("marking the NodeDrain as successful")
 			fakes.nodeStateGetter.setNodeState(updatev1alpha1.NodeStateTerminated)
-			nodeMaintenance.Status.Phase = nodemaintenancev1beta1.MaintenanceSucceeded
-			Expect(k8sClient.Status().Update(ctx, nodeMaintenance)).Should(Succeed())
+			nodeDrain.Status.Phase = updatev1alpha1.NodeDrainPhaseSucceeded
+			Expect(k8sClient.Status().Update(ctx, nodeDrain)).Should(Succeed())
 			Eventually(func() error {
-				return k8sClient.Get(ctx, nodeMaintenanceLookupKey, nodeMaintenance)
+				return k8sClient.Get(ctx, nodeDrainLookupKey, nodeDrain)
 			}, timeout, interval).Should(Succeed())
 
 			By("checking that the outdated node is removed")
@@ -17,6 +17,10 @@ import (
 	corev1 "k8s.io/api/core/v1"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/client-go/tools/record"
+	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
 
 	updatev1alpha1 "github.com/edgelesssys/constellation/operators/constellation-node-operator/v2/api/v1alpha1"
 )
@@ -222,6 +226,7 @@ func TestPairDonorsAndHeirs(t *testing.T) {
 				Client: &stubReadWriterClient{
 					stubReaderClient: *newStubReaderClient(t, []runtime.Object{&tc.outdatedNode, &tc.mintNode.node}, nil, nil),
 				},
+				Recorder: &record.FakeRecorder{},
 			}
 			nodeImage := updatev1alpha1.NodeImage{}
 			pairs := reconciler.pairDonorsAndHeirs(context.Background(), &nodeImage, []corev1.Node{tc.outdatedNode}, []mintNode{tc.mintNode})
@@ -583,16 +588,20 @@ func TestCreateNewNodes(t *testing.T) {
 					ImageReference: "image",
 				},
 			}
+			recorder := record.NewFakeRecorder(100)
 			reconciler := NodeImageReconciler{
 				nodeReplacer: &stubNodeReplacerWriter{},
 				Client: &stubReadWriterClient{
 					stubReaderClient: *newStubReaderClient(t, []runtime.Object{}, nil, nil),
 				},
-				Scheme: getScheme(t),
+				Scheme:   getScheme(t),
+				Recorder: recorder,
 			}
 			err := reconciler.createNewNodes(context.Background(), desiredNodeImage, tc.outdatedNodes, tc.pendingNodes, tc.scalingGroupByID, tc.budget)
 			require.NoError(err)
 			assert.Equal(tc.wantCreateCalls, reconciler.nodeReplacer.(*stubNodeReplacerWriter).createCalls)
+			// every created node is reported on the NodeImage and the ScalingGroup
+			assert.Len(recorder.Events, 2*len(tc.wantCreateCalls))
 		})
 	}
 }
@@ -689,8 +698,33 @@ func TestGroupNodes(t *testing.T) {
 	nodes = append(nodes, wantNodeGroups.Heirs...)
 	nodes = append(nodes, wantNodeGroups.Obsolete...)
 	nodes = append(nodes, wantNodeGroups.Mint[0].node)
+	// replacements of unhealthy nodes are up to date and not mint nodes
+	replacement := corev1.Node{
+		ObjectMeta: metav1.ObjectMeta{
+			Name: "replacement",
+			Annotations: map[string]string{
+				scalingGroupAnnotation: scalingGroup,
+				nodeImageAnnotation:    latestImageReference,
+			},
+		},
+	}
+	wantNodeGroups.UpToDate = append(wantNodeGroups.UpToDate, replacement)
+	nodes = append(nodes, replacement)
+	isController := true
 	pendingNodes := []updatev1alpha1.PendingNode{
 		wantNodeGroups.Mint[0].pendingNode,
+		{
+			ObjectMeta: metav1.ObjectMeta{
+				OwnerReferences: []metav1.OwnerReference{{Kind: "NodeHealthCheck", Name: "health-check", Controller: &isController}},
+			},
+			Spec: updatev1alpha1.PendingNodeSpec{
+				NodeName: "replacement",
+				Goal:     updatev1alpha1.NodeGoalJoin,
+			},
+			Status: updatev1alpha1.PendingNodeStatus{
+				CSPNodeState: updatev1alpha1.NodeStateReady,
+			},
+		},
 	}
 
 	assert := assert.New(t)
@@ -817,3 +851,46 @@ func (*unimplementedNodeReplacer) CreateNode(ctx context.Context, scalingGroupID
 func (*unimplementedNodeReplacer) DeleteNode(ctx context.Context, providerID string) error {
 	panic("unimplemented")
 }
+
+func TestReconcileRetriesFailedNodeCreation(t *testing.T) {
+	assert := assert.New(t)
+	require := require.New(t)
+	ctx := context.Background()
+
+	nodeImage := &updatev1alpha1.NodeImage{
+		ObjectMeta: metav1.ObjectMeta{Name: "node-image"},
+		Spec:       updatev1alpha1.NodeImageSpec{ImageReference: "new-image"},
+	}
+	scalingGroup := &updatev1alpha1.ScalingGroup{
+		ObjectMeta: metav1.ObjectMeta{Name: "worker"},
+		Spec: updatev1alpha1.ScalingGroupSpec{
+			NodeImage: "node-image",
+			GroupID:   "worker",
+			Role:      updatev1alpha1.WorkerRole,
+		},
+		Status: updatev1alpha1.ScalingGroupStatus{ImageReference: "new-image"},
+	}
+	kubeClient := fake.NewClientBuilder().WithScheme(getScheme(t)).WithObjects(nodeImage, scalingGroup).Build()
+	cloud := newFakeCloud(kubeClient)
+	cloud.addScalingGroup("worker", "old-image", false)
+	_, err := cloud.addInstance(ctx, "worker")
+	require.NoError(err)
+	cloud.failCreations(1)
+
+	reconciler := NewNodeImageReconciler(cloud, cloud, kubeClient, getScheme(t), record.NewFakeRecorder(100))
+	req := ctrl.Request{NamespacedName: types.NamespacedName{Name: "node-image"}}
+
+	// nothing else triggers a reconciliation if creating the heir fails, so it's retried with backoff
+	result, err := reconciler.Reconcile(ctx, req)
+	require.NoError(err)
+	assert.True(result.Requeue)
+	var pendingNodes updatev1alpha1.PendingNodeList
+	require.NoError(kubeClient.List(ctx, &pendingNodes))
+	assert.Empty(pendingNodes.Items)
+
+	_, err = reconciler.Reconcile(ctx, req)
+	require.NoError(err)
+	require.NoError(kubeClient.List(ctx, &pendingNodes))
+	assert.Len(pendingNodes.Items, 1)
+	assert.Empty(cloud.errors())
+}
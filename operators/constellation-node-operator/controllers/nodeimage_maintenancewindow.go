@@ -0,0 +1,99 @@
+/*
+Copyright (c) Edgeless Systems GmbH
+
+SPDX-License-Identifier: AGPL-3.0-only
+*/
+
+package controllers
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/robfig/cron/v3"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+
+	updatev1alpha1 "github.com/edgelesssys/constellation/operators/constellation-node-operator/v2/api/v1alpha1"
+)
+
+const (
+	conditionMaintenanceWindowOpenReason     = "MaintenanceWindowOpen"
+	conditionMaintenanceWindowOpenMessage    = "Maintenance window is open"
+	conditionMaintenanceWindowClosedReason   = "MaintenanceWindowClosed"
+	conditionMaintenanceWindowInvalidReason  = "MaintenanceWindowInvalid"
+	conditionMaintenanceWindowClosedMessage  = "Waiting for maintenance window, next window opens at %s"
+	conditionMaintenanceWindowInvalidMessage = "Waiting for maintenance window, invalid maintenance window: %s"
+)
+
+// maintenanceWindowParser parses standard cron expressions (minute, hour, day of month, month, day of week).
+var maintenanceWindowParser = cron.NewParser(cron.Minute | cron.Hour | cron.Dom | cron.Month | cron.Dow | cron.Descriptor)
+
+// maintenanceWindowState is the state of a maintenance window at a point in time.
+type maintenanceWindowState struct {
+	// open is true if new replacements may be started.
+	open bool
+	// nextOpen is the time the next window opens if the window is closed.
+	nextOpen time.Time
+	// err is set if the maintenance window is invalid.
+	err error
+}
+
+// checkMaintenanceWindow checks if a maintenance window is open at the given time.
+// A window is open if the schedule activated less than the window duration ago.
+// A missing maintenance window is always open. An invalid maintenance window is never open.
+func checkMaintenanceWindow(window *updatev1alpha1.MaintenanceWindow, now time.Time) maintenanceWindowState {
+	if window == nil {
+		return maintenanceWindowState{open: true}
+	}
+	location, err := time.LoadLocation(window.TimeZone)
+	if err != nil {
+		return maintenanceWindowState{err: fmt.Errorf("loading time zone %q: %w", window.TimeZone, err)}
+	}
+	schedule, err := maintenanceWindowParser.Parse(window.Schedule)
+	if err != nil {
+		return maintenanceWindowState{err: fmt.Errorf("parsing schedule %q: %w", window.Schedule, err)}
+	}
+	if window.Duration.Duration <= 0 {
+		return maintenanceWindowState{err: fmt.Errorf("duration must be positive, got %s", window.Duration.Duration)}
+	}
+	now = now.In(location)
+	// the schedule activated at most one window duration ago if its next activation
+	// after (now - duration) is not in the future
+	if lastOpen := schedule.Next(now.Add(-window.Duration.Duration)); !lastOpen.After(now) {
+		return maintenanceWindowState{open: true}
+	}
+	return maintenanceWindowState{nextOpen: schedule.Next(now)}
+}
+
+// requeueAfter returns the time until the next window opens.
+func (s maintenanceWindowState) requeueAfter(now time.Time) time.Duration {
+	if s.open || s.err != nil || s.nextOpen.IsZero() {
+		return 0
+	}
+	return s.nextOpen.Sub(now)
+}
+
+// condition returns the WaitingForMaintenanceWindow condition.
+// The condition is only true if outdated nodes are waiting for the maintenance window.
+func (s maintenanceWindowState) condition(outdated bool) metav1.Condition {
+	condition := metav1.Condition{
+		Type:    updatev1alpha1.ConditionWaitingForMaintenanceWindow,
+		Status:  metav1.ConditionFalse,
+		Reason:  conditionMaintenanceWindowOpenReason,
+		Message: conditionMaintenanceWindowOpenMessage,
+	}
+	switch {
+	case s.err != nil:
+		condition.Reason = conditionMaintenanceWindowInvalidReason
+		condition.Message = fmt.Sprintf(conditionMaintenanceWindowInvalidMessage, s.err)
+	case !s.open:
+		condition.Reason = conditionMaintenanceWindowClosedReason
+		condition.Message = fmt.Sprintf(conditionMaintenanceWindowClosedMessage, s.nextOpen.Format(time.RFC3339))
+	default:
+		return condition
+	}
+	if outdated {
+		condition.Status = metav1.ConditionTrue
+	}
+	return condition
+}
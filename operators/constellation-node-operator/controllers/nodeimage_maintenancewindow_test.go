@@ -0,0 +1,180 @@
+/*
+Copyright (c) Edgeless Systems GmbH
+
+SPDX-License-Identifier: AGPL-3.0-only
+*/
+
+package controllers
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+
+	updatev1alpha1 "github.com/edgelesssys/constellation/operators/constellation-node-operator/v2/api/v1alpha1"
+)
+
+func TestCheckMaintenanceWindow(t *testing.T) {
+	// Wednesday
+	now := time.Date(2022, time.August, 10, 3, 30, 0, 0, time.UTC)
+	nightly := &updatev1alpha1.MaintenanceWindow{
+		Schedule: "0 2 * * *",
+		Duration: metav1.Duration{Duration: 2 * time.Hour},
+	}
+
+	testCases := map[string]struct {
+		window       *updatev1alpha1.MaintenanceWindow
+		wantOpen     bool
+		wantNextOpen time.Time
+		wantErr      bool
+	}{
+		"no maintenance window": {
+			wantOpen: true,
+		},
+		"inside window": {
+			window:   nightly,
+			wantOpen: true,
+		},
+		"window opens now": {
+			window: &updatev1alpha1.MaintenanceWindow{
+				Schedule: "30 3 * * *",
+				Duration: metav1.Duration{Duration: time.Minute},
+			},
+			wantOpen: true,
+		},
+		"window closed": {
+			window: &updatev1alpha1.MaintenanceWindow{
+				Schedule: "0 2 * * *",
+				Duration: metav1.Duration{Duration: time.Hour},
+			},
+			wantNextOpen: time.Date(2022, time.August, 11, 2, 0, 0, 0, time.UTC),
+		},
+		"window closes exactly now": {
+			window: &updatev1alpha1.MaintenanceWindow{
+				Schedule: "0 2 * * *",
+				Duration: metav1.Duration{Duration: 90 * time.Minute},
+			},
+			wantNextOpen: time.Date(2022, time.August, 11, 2, 0, 0, 0, time.UTC),
+		},
+		"weekend window": {
+			window: &updatev1alpha1.MaintenanceWindow{
+				Schedule: "0 1 * * SAT",
+				Duration: metav1.Duration{Duration: 4 * time.Hour},
+			},
+			wantNextOpen: time.Date(2022, time.August, 13, 1, 0, 0, 0, time.UTC),
+		},
+		"time zone": {
+			window: &updatev1alpha1.MaintenanceWindow{
+				Schedule: "0 5 * * *",
+				TimeZone: "Europe/Berlin",
+				Duration: metav1.Duration{Duration: time.Hour},
+			},
+			wantOpen: true,
+		},
+		"time zone closed": {
+			window: &updatev1alpha1.MaintenanceWindow{
+				Schedule: "0 2 * * *",
+				TimeZone: "America/New_York",
+				Duration: metav1.Duration{Duration: time.Hour},
+			},
+			wantNextOpen: time.Date(2022, time.August, 10, 6, 0, 0, 0, time.UTC),
+		},
+		"invalid schedule": {
+			window: &updatev1alpha1.MaintenanceWindow{
+				Schedule: "every night",
+				Duration: metav1.Duration{Duration: time.Hour},
+			},
+			wantErr: true,
+		},
+		"invalid time zone": {
+			window: &updatev1alpha1.MaintenanceWindow{
+				Schedule: "0 2 * * *",
+				TimeZone: "Mars/Olympus_Mons",
+				Duration: metav1.Duration{Duration: time.Hour},
+			},
+			wantErr: true,
+		},
+		"zero duration": {
+			window: &updatev1alpha1.MaintenanceWindow{
+				Schedule: "0 2 * * *",
+			},
+			wantErr: true,
+		},
+	}
+
+	for name, tc := range testCases {
+		t.Run(name, func(t *testing.T) {
+			assert := assert.New(t)
+
+			state := checkMaintenanceWindow(tc.window, now)
+			if tc.wantErr {
+				assert.Error(state.err)
+				assert.False(state.open)
+				assert.Zero(state.requeueAfter(now))
+				return
+			}
+			assert.NoError(state.err)
+			assert.Equal(tc.wantOpen, state.open)
+			if tc.wantOpen {
+				assert.Zero(state.requeueAfter(now))
+				return
+			}
+			assert.True(tc.wantNextOpen.Equal(state.nextOpen), "want next window at %s, got %s", tc.wantNextOpen, state.nextOpen)
+			assert.Equal(tc.wantNextOpen.Sub(now), state.requeueAfter(now))
+		})
+	}
+}
+
+func TestMaintenanceWindowCondition(t *testing.T) {
+	nextOpen := time.Date(2022, time.August, 11, 2, 0, 0, 0, time.UTC)
+	testCases := map[string]struct {
+		state       maintenanceWindowState
+		outdated    bool
+		wantStatus  metav1.ConditionStatus
+		wantReason  string
+		wantMessage string
+	}{
+		"open": {
+			state:       maintenanceWindowState{open: true},
+			outdated:    true,
+			wantStatus:  metav1.ConditionFalse,
+			wantReason:  conditionMaintenanceWindowOpenReason,
+			wantMessage: conditionMaintenanceWindowOpenMessage,
+		},
+		"closed with outdated nodes": {
+			state:       maintenanceWindowState{nextOpen: nextOpen},
+			outdated:    true,
+			wantStatus:  metav1.ConditionTrue,
+			wantReason:  conditionMaintenanceWindowClosedReason,
+			wantMessage: "Waiting for maintenance window, next window opens at 2022-08-11T02:00:00Z",
+		},
+		"closed without outdated nodes": {
+			state:       maintenanceWindowState{nextOpen: nextOpen},
+			wantStatus:  metav1.ConditionFalse,
+			wantReason:  conditionMaintenanceWindowClosedReason,
+			wantMessage: "Waiting for maintenance window, next window opens at 2022-08-11T02:00:00Z",
+		},
+		"invalid": {
+			state:      maintenanceWindowState{err: assert.AnError},
+			outdated:   true,
+			wantStatus: metav1.ConditionTrue,
+			wantReason: conditionMaintenanceWindowInvalidReason,
+			wantMessage: "Waiting for maintenance window, invalid maintenance window: " +
+				assert.AnError.Error(),
+		},
+	}
+
+	for name, tc := range testCases {
+		t.Run(name, func(t *testing.T) {
+			assert := assert.New(t)
+
+			condition := tc.state.condition(tc.outdated)
+			assert.Equal(updatev1alpha1.ConditionWaitingForMaintenanceWindow, condition.Type)
+			assert.Equal(tc.wantStatus, condition.Status)
+			assert.Equal(tc.wantReason, condition.Reason)
+			assert.Equal(tc.wantMessage, condition.Message)
+		})
+	}
+}
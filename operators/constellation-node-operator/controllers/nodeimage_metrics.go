@@ -0,0 +1,103 @@
+/*
+Copyright (c) Edgeless Systems GmbH
+
+SPDX-License-Identifier: AGPL-3.0-only
+*/
+
+package controllers
+
+import (
+	"strings"
+	"time"
+
+	corev1 "k8s.io/api/core/v1"
+
+	updatev1alpha1 "github.com/edgelesssys/constellation/operators/constellation-node-operator/v2/api/v1alpha1"
+	"github.com/edgelesssys/constellation/operators/constellation-node-operator/v2/internal/metrics"
+)
+
+// unknownScalingGroup is the scaling group label of nodes whose scaling group could not be determined.
+const unknownScalingGroup = "unknown"
+
+// recordNodeMetrics sets the node count metrics to the current node counts.
+func recordNodeMetrics(groups nodeGroups, pendingNodes []updatev1alpha1.PendingNode, invalidNodes []corev1.Node, scalingGroupByID map[string]updatev1alpha1.ScalingGroup) {
+	metrics.Nodes.Reset()
+	for scalingGroup, counts := range nodeCounts(groups, pendingNodes, invalidNodes, scalingGroupByID) {
+		for state, count := range counts {
+			metrics.Nodes.WithLabelValues(scalingGroup, state).Set(float64(count))
+		}
+	}
+}
+
+// recordNodeReplacement observes the duration of a finished node replacement.
+func recordNodeReplacement(pair replacementPair, scalingGroupByID map[string]updatev1alpha1.ScalingGroup, now time.Time) {
+	if pair.heir.CreationTimestamp.IsZero() {
+		return
+	}
+	scalingGroup := scalingGroupLabel(scalingGroupByID, pair.donor.Annotations[scalingGroupAnnotation])
+	metrics.NodeReplacementDuration.WithLabelValues(scalingGroup).Observe(now.Sub(pair.heir.CreationTimestamp.Time).Seconds())
+}
+
+// nodeCounts counts nodes per scaling group and state.
+// Every known scaling group reports every state, so that missing nodes are reported as zero.
+func nodeCounts(groups nodeGroups, pendingNodes []updatev1alpha1.PendingNode, invalidNodes []corev1.Node, scalingGroupByID map[string]updatev1alpha1.ScalingGroup) map[string]map[string]int {
+	counts := make(map[string]map[string]int)
+	add := func(scalingGroupID, state string) {
+		scalingGroup := scalingGroupLabel(scalingGroupByID, scalingGroupID)
+		if _, ok := counts[scalingGroup]; !ok {
+			counts[scalingGroup] = newStateCounts()
+		}
+		counts[scalingGroup][state]++
+	}
+	for _, scalingGroup := range scalingGroupByID {
+		counts[scalingGroupLabel(scalingGroupByID, scalingGroup.Spec.GroupID)] = newStateCounts()
+	}
+
+	for _, nodes := range [][]corev1.Node{groups.Outdated, groups.Donors} {
+		for _, node := range nodes {
+			add(node.Annotations[scalingGroupAnnotation], metrics.NodeStateOutdated)
+		}
+	}
+	for _, nodes := range [][]corev1.Node{groups.UpToDate, groups.Heirs} {
+		for _, node := range nodes {
+			add(node.Annotations[scalingGroupAnnotation], metrics.NodeStateUpToDate)
+		}
+	}
+	mintNodes := make(map[string]struct{}, len(groups.Mint))
+	for _, mintNode := range groups.Mint {
+		add(mintNode.pendingNode.Spec.ScalingGroupID, metrics.NodeStateUpToDate)
+		mintNodes[mintNode.pendingNode.Name] = struct{}{}
+	}
+	for _, pendingNode := range pendingNodes {
+		// mint nodes already joined and are counted as up to date
+		if _, ok := mintNodes[pendingNode.Name]; ok {
+			continue
+		}
+		add(pendingNode.Spec.ScalingGroupID, metrics.NodeStatePending)
+	}
+	for _, node := range invalidNodes {
+		add(node.Annotations[scalingGroupAnnotation], metrics.NodeStateInvalid)
+	}
+	return counts
+}
+
+// scalingGroupLabel returns the name of the ScalingGroup resource with the given scaling group ID.
+// The scaling group ID is returned if there is no matching resource.
+func scalingGroupLabel(scalingGroupByID map[string]updatev1alpha1.ScalingGroup, scalingGroupID string) string {
+	if scalingGroupID == "" {
+		return unknownScalingGroup
+	}
+	if scalingGroup, ok := scalingGroupByID[strings.ToLower(scalingGroupID)]; ok {
+		return scalingGroup.Name
+	}
+	return strings.ToLower(scalingGroupID)
+}
+
+func newStateCounts() map[string]int {
+	return map[string]int{
+		metrics.NodeStateOutdated: 0,
+		metrics.NodeStateUpToDate: 0,
+		metrics.NodeStatePending:  0,
+		metrics.NodeStateInvalid:  0,
+	}
+}
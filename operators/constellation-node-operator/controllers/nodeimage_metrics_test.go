@@ -0,0 +1,123 @@
+/*
+Copyright (c) Edgeless Systems GmbH
+
+SPDX-License-Identifier: AGPL-3.0-only
+*/
+
+package controllers
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+
+	updatev1alpha1 "github.com/edgelesssys/constellation/operators/constellation-node-operator/v2/api/v1alpha1"
+	"github.com/edgelesssys/constellation/operators/constellation-node-operator/v2/internal/metrics"
+)
+
+func TestNodeCounts(t *testing.T) {
+	nodeInGroup := func(name, scalingGroupID string) corev1.Node {
+		return corev1.Node{
+			ObjectMeta: metav1.ObjectMeta{
+				Name:        name,
+				Annotations: map[string]string{scalingGroupAnnotation: scalingGroupID},
+			},
+		}
+	}
+	pendingInGroup := func(name, scalingGroupID string) updatev1alpha1.PendingNode {
+		return updatev1alpha1.PendingNode{
+			ObjectMeta: metav1.ObjectMeta{Name: name},
+			Spec:       updatev1alpha1.PendingNodeSpec{ScalingGroupID: scalingGroupID},
+		}
+	}
+	scalingGroupByID := map[string]updatev1alpha1.ScalingGroup{
+		"control-plane-id": {
+			ObjectMeta: metav1.ObjectMeta{Name: "control-plane"},
+			Spec:       updatev1alpha1.ScalingGroupSpec{GroupID: "Control-Plane-ID"},
+		},
+		"worker-id": {
+			ObjectMeta: metav1.ObjectMeta{Name: "worker"},
+			Spec:       updatev1alpha1.ScalingGroupSpec{GroupID: "Worker-ID"},
+		},
+	}
+
+	testCases := map[string]struct {
+		groups       nodeGroups
+		pendingNodes []updatev1alpha1.PendingNode
+		invalidNodes []corev1.Node
+		wantCounts   map[string]map[string]int
+	}{
+		"no nodes": {
+			wantCounts: map[string]map[string]int{
+				"control-plane": newStateCounts(),
+				"worker":        newStateCounts(),
+			},
+		},
+		"nodes are counted per scaling group and state": {
+			groups: nodeGroups{
+				Outdated: []corev1.Node{nodeInGroup("outdated", "control-plane-id")},
+				Donors:   []corev1.Node{nodeInGroup("donor", "worker-id")},
+				Heirs:    []corev1.Node{nodeInGroup("heir", "worker-id")},
+				UpToDate: []corev1.Node{nodeInGroup("up-to-date-1", "WORKER-ID"), nodeInGroup("up-to-date-2", "control-plane-id")},
+				Mint: []mintNode{
+					{
+						node:        nodeInGroup("mint", "worker-id"),
+						pendingNode: pendingInGroup("mint", "worker-id"),
+					},
+				},
+				Obsolete: []corev1.Node{nodeInGroup("obsolete", "worker-id")},
+			},
+			pendingNodes: []updatev1alpha1.PendingNode{
+				pendingInGroup("mint", "worker-id"),
+				pendingInGroup("pending", "control-plane-id"),
+			},
+			invalidNodes: []corev1.Node{{ObjectMeta: metav1.ObjectMeta{Name: "invalid"}}},
+			wantCounts: map[string]map[string]int{
+				"control-plane": {
+					metrics.NodeStateOutdated: 1,
+					metrics.NodeStateUpToDate: 1,
+					metrics.NodeStatePending:  1,
+					metrics.NodeStateInvalid:  0,
+				},
+				"worker": {
+					metrics.NodeStateOutdated: 1,
+					metrics.NodeStateUpToDate: 3,
+					metrics.NodeStatePending:  0,
+					metrics.NodeStateInvalid:  0,
+				},
+				unknownScalingGroup: {
+					metrics.NodeStateOutdated: 0,
+					metrics.NodeStateUpToDate: 0,
+					metrics.NodeStatePending:  0,
+					metrics.NodeStateInvalid:  1,
+				},
+			},
+		},
+		"scaling groups without resource are labeled by ID": {
+			groups: nodeGroups{
+				Outdated: []corev1.Node{nodeInGroup("outdated", "Other-ID")},
+			},
+			wantCounts: map[string]map[string]int{
+				"control-plane": newStateCounts(),
+				"worker":        newStateCounts(),
+				"other-id": {
+					metrics.NodeStateOutdated: 1,
+					metrics.NodeStateUpToDate: 0,
+					metrics.NodeStatePending:  0,
+					metrics.NodeStateInvalid:  0,
+				},
+			},
+		},
+	}
+
+	for name, tc := range testCases {
+		t.Run(name, func(t *testing.T) {
+			assert := assert.New(t)
+
+			counts := nodeCounts(tc.groups, tc.pendingNodes, tc.invalidNodes, scalingGroupByID)
+			assert.Equal(tc.wantCounts, counts)
+		})
+	}
+}
@@ -0,0 +1,215 @@
+/*
+Copyright (c) Edgeless Systems GmbH
+
+SPDX-License-Identifier: AGPL-3.0-only
+*/
+
+package controllers
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+
+	nodeutil "github.com/edgelesssys/constellation/operators/constellation-node-operator/v2/internal/node"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/client-go/util/retry"
+
+	updatev1alpha1 "github.com/edgelesssys/constellation/operators/constellation-node-operator/v2/api/v1alpha1"
+)
+
+const (
+	// defaultMaxSurge is the maximum number of extra nodes created during the update procedure if the strategy does not specify it.
+	defaultMaxSurge = 1
+	// defaultMaxUnavailable is the maximum number of nodes drained at the same time if the strategy does not specify it.
+	defaultMaxUnavailable = 1
+	// defaultNodeLeaveTimeout is the time limit removed nodes have to leave the cluster if the strategy does not specify it.
+	defaultNodeLeaveTimeout = time.Minute
+)
+
+// maxSurge returns the maximum number of extra nodes created during the update procedure at any point in time.
+func maxSurge(strategy updatev1alpha1.RolloutStrategy) int {
+	if strategy.MaxSurge == nil || *strategy.MaxSurge < 1 {
+		return defaultMaxSurge
+	}
+	return int(*strategy.MaxSurge)
+}
+
+// maxUnavailable returns the maximum number of outdated nodes drained and removed at the same time.
+func maxUnavailable(strategy updatev1alpha1.RolloutStrategy) int {
+	if strategy.MaxUnavailable == nil || *strategy.MaxUnavailable < 1 {
+		return defaultMaxUnavailable
+	}
+	return int(*strategy.MaxUnavailable)
+}
+
+// nodeLeaveTimeout returns the time limit removed nodes have to leave the cluster and being terminated.
+func nodeLeaveTimeout(strategy updatev1alpha1.RolloutStrategy) time.Duration {
+	if strategy.NodeLeaveTimeout == nil || strategy.NodeLeaveTimeout.Duration <= 0 {
+		return defaultNodeLeaveTimeout
+	}
+	return strategy.NodeLeaveTimeout.Duration
+}
+
+// replacementBudget returns the maximum number of new nodes that can be created in a Reconcile call.
+// extraNodes are nodes that exist in the scaling groups but cannot be used for regular workloads.
+// During the canary phase, no more nodes are created than are needed to complete the canary.
+func replacementBudget(strategy updatev1alpha1.RolloutStrategy, rollout updatev1alpha1.RolloutStatus, extraNodes int) int {
+	if strategy.Paused {
+		return 0
+	}
+	budget := maxSurge(strategy) - extraNodes
+	if strategy.Canary > 0 && !rollout.CanaryCompleted {
+		if canaryBudget := int(strategy.Canary-rollout.Replaced) - extraNodes; canaryBudget < budget {
+			budget = canaryBudget
+		}
+	}
+	if budget < 0 {
+		return 0
+	}
+	return budget
+}
+
+// rolloutProgress computes the progress of replacing outdated nodes by nodes using the desired image.
+// The canary state is carried over from the previous progress if it refers to the same image.
+func rolloutProgress(previous updatev1alpha1.RolloutStatus, spec updatev1alpha1.NodeImageSpec, groups nodeGroups, pendingNodes []updatev1alpha1.PendingNode) updatev1alpha1.RolloutStatus {
+	progress := updatev1alpha1.RolloutStatus{
+		ImageReference: spec.ImageReference,
+		Replaced:       int32(len(groups.UpToDate)),
+		Remaining:      int32(len(groups.Outdated) + len(groups.Donors)),
+	}
+	if strings.EqualFold(previous.ImageReference, spec.ImageReference) {
+		progress.CanaryCompleted = previous.CanaryCompleted
+	}
+	if spec.Strategy.Canary > 0 && progress.Replaced >= spec.Strategy.Canary {
+		progress.CanaryCompleted = true
+	}
+
+	switch {
+	case len(groups.Outdated)+len(groups.Donors)+len(groups.Heirs)+len(pendingNodes)+len(groups.Obsolete) == 0:
+		progress.Phase = updatev1alpha1.RolloutPhaseCompleted
+	case spec.Strategy.Paused:
+		progress.Phase = updatev1alpha1.RolloutPhasePaused
+	default:
+		progress.Phase = updatev1alpha1.RolloutPhaseProgressing
+	}
+	return progress
+}
+
+// canaryJustCompleted reports whether the canary phase of the rollout completed since the previous progress was recorded.
+func canaryJustCompleted(previous, current updatev1alpha1.RolloutStatus) bool {
+	previouslyCompleted := previous.CanaryCompleted && strings.EqualFold(previous.ImageReference, current.ImageReference)
+	return current.CanaryCompleted && !previouslyCompleted
+}
+
+// rolloutRoles returns whether outdated control plane and worker nodes may be replaced,
+// given the nodes that still need to be replaced.
+func rolloutRoles(order updatev1alpha1.RolloutOrder, remainingNodes []corev1.Node) (controlPlane, worker bool) {
+	var remainingControlPlane, remainingWorker bool
+	for i := range remainingNodes {
+		if nodeutil.IsControlPlaneNode(&remainingNodes[i]) {
+			remainingControlPlane = true
+		} else {
+			remainingWorker = true
+		}
+	}
+	switch order {
+	case updatev1alpha1.RolloutOrderWorkersFirst:
+		return !remainingWorker, true
+	case updatev1alpha1.RolloutOrderControlPlaneFirst:
+		return true, !remainingControlPlane
+	default:
+		return true, true
+	}
+}
+
+// filterByRole returns the nodes with a role that may currently be replaced.
+func filterByRole(nodes []corev1.Node, controlPlane, worker bool) []corev1.Node {
+	var filtered []corev1.Node
+	for _, node := range nodes {
+		if nodeutil.IsControlPlaneNode(&node) && controlPlane || !nodeutil.IsControlPlaneNode(&node) && worker {
+			filtered = append(filtered, node)
+		}
+	}
+	return filtered
+}
+
+// passesHealthGate checks if a replacement node fulfills the health requirements
+// to remove the outdated node it replaces.
+// If the node is ready but has not been ready for long enough, the remaining time is returned.
+func passesHealthGate(node *corev1.Node, gate updatev1alpha1.NodeHealthGate, now time.Time) (bool, time.Duration) {
+	conditions := make(map[corev1.NodeConditionType]corev1.NodeCondition, len(node.Status.Conditions))
+	for _, cond := range node.Status.Conditions {
+		conditions[cond.Type] = cond
+	}
+	ready, ok := conditions[corev1.NodeReady]
+	if !ok || ready.Status != corev1.ConditionTrue {
+		return false, 0
+	}
+	for _, condType := range gate.Conditions {
+		if cond, ok := conditions[condType]; !ok || cond.Status != corev1.ConditionTrue {
+			return false, 0
+		}
+	}
+	readySince := ready.LastTransitionTime.Time
+	minReady := time.Duration(gate.MinReadySeconds) * time.Second
+	if readyFor := now.Sub(readySince); readyFor < minReady {
+		return false, minReady - readyFor
+	}
+	return true, 0
+}
+
+// nodesUnderMaintenance returns the NodeDrains of all nodes that are being cordoned and drained, by node name.
+func (r *NodeImageReconciler) nodesUnderMaintenance(ctx context.Context) (map[string]updatev1alpha1.NodeDrain, error) {
+	var nodeDrainList updatev1alpha1.NodeDrainList
+	if err := r.List(ctx, &nodeDrainList); err != nil {
+		return nil, err
+	}
+	nodes := make(map[string]updatev1alpha1.NodeDrain, len(nodeDrainList.Items))
+	for _, nodeDrain := range nodeDrainList.Items {
+		nodes[nodeDrain.Spec.NodeName] = nodeDrain
+	}
+	return nodes, nil
+}
+
+// drainFailedCondition returns the DrainFailed condition of a NodeImage.
+// It lists the nodes that could not be drained for their replacement.
+func drainFailedCondition(nodeDrains map[string]updatev1alpha1.NodeDrain) metav1.Condition {
+	var failed []string
+	for nodeName, nodeDrain := range nodeDrains {
+		if nodeDrain.Spec.Reason == nodeReplacementReason && nodeDrain.Status.Phase == updatev1alpha1.NodeDrainPhaseFailed {
+			failed = append(failed, nodeName)
+		}
+	}
+	if len(failed) == 0 {
+		return metav1.Condition{
+			Type:    updatev1alpha1.ConditionDrainFailed,
+			Status:  metav1.ConditionFalse,
+			Reason:  conditionNodeDrainsHealthyReason,
+			Message: conditionNodeDrainsHealthyMessage,
+		}
+	}
+	sort.Strings(failed)
+	return metav1.Condition{
+		Type:    updatev1alpha1.ConditionDrainFailed,
+		Status:  metav1.ConditionTrue,
+		Reason:  conditionNodeDrainsFailedReason,
+		Message: fmt.Sprintf(conditionNodeDrainsFailedTemplate, strings.Join(failed, ", ")),
+	}
+}
+
+// pauseRollout sets the paused flag of the NodeImage rollout strategy in a retry loop.
+func (r *NodeImageReconciler) pauseRollout(ctx context.Context, name types.NamespacedName) error {
+	return retry.RetryOnConflict(retry.DefaultRetry, func() error {
+		var nodeImage updatev1alpha1.NodeImage
+		if err := r.Get(ctx, name, &nodeImage); err != nil {
+			return err
+		}
+		nodeImage.Spec.Strategy.Paused = true
+		return r.Client.Update(ctx, &nodeImage)
+	})
+}
@@ -0,0 +1,418 @@
+/*
+Copyright (c) Edgeless Systems GmbH
+
+SPDX-License-Identifier: AGPL-3.0-only
+*/
+
+package controllers
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/types"
+
+	updatev1alpha1 "github.com/edgelesssys/constellation/operators/constellation-node-operator/v2/api/v1alpha1"
+)
+
+func TestReplacementBudget(t *testing.T) {
+	three := int32(3)
+	testCases := map[string]struct {
+		strategy   updatev1alpha1.RolloutStrategy
+		rollout    updatev1alpha1.RolloutStatus
+		extraNodes int
+		wantBudget int
+	}{
+		"default surge": {
+			wantBudget: 1,
+		},
+		"default surge used up": {
+			extraNodes: 1,
+			wantBudget: 0,
+		},
+		"custom surge": {
+			strategy:   updatev1alpha1.RolloutStrategy{MaxSurge: &three},
+			extraNodes: 1,
+			wantBudget: 2,
+		},
+		"more extra nodes than surge": {
+			strategy:   updatev1alpha1.RolloutStrategy{MaxSurge: &three},
+			extraNodes: 5,
+			wantBudget: 0,
+		},
+		"paused": {
+			strategy:   updatev1alpha1.RolloutStrategy{MaxSurge: &three, Paused: true},
+			wantBudget: 0,
+		},
+		"canary limits surge": {
+			strategy:   updatev1alpha1.RolloutStrategy{MaxSurge: &three, Canary: 2},
+			rollout:    updatev1alpha1.RolloutStatus{Replaced: 1},
+			wantBudget: 1,
+		},
+		"canary nodes in progress": {
+			strategy:   updatev1alpha1.RolloutStrategy{MaxSurge: &three, Canary: 2},
+			extraNodes: 2,
+			wantBudget: 0,
+		},
+		"canary completed": {
+			strategy:   updatev1alpha1.RolloutStrategy{MaxSurge: &three, Canary: 2},
+			rollout:    updatev1alpha1.RolloutStatus{Replaced: 2, CanaryCompleted: true},
+			wantBudget: 3,
+		},
+	}
+
+	for name, tc := range testCases {
+		t.Run(name, func(t *testing.T) {
+			assert.Equal(t, tc.wantBudget, replacementBudget(tc.strategy, tc.rollout, tc.extraNodes))
+		})
+	}
+}
+
+func TestRolloutProgress(t *testing.T) {
+	outdated := []corev1.Node{{ObjectMeta: metav1.ObjectMeta{Name: "outdated"}}}
+	upToDate := []corev1.Node{{ObjectMeta: metav1.ObjectMeta{Name: "up-to-date"}}}
+	testCases := map[string]struct {
+		previous     updatev1alpha1.RolloutStatus
+		spec         updatev1alpha1.NodeImageSpec
+		groups       nodeGroups
+		wantProgress updatev1alpha1.RolloutStatus
+	}{
+		"completed": {
+			spec:   updatev1alpha1.NodeImageSpec{ImageReference: "image"},
+			groups: nodeGroups{UpToDate: upToDate},
+			wantProgress: updatev1alpha1.RolloutStatus{
+				ImageReference: "image",
+				Phase:          updatev1alpha1.RolloutPhaseCompleted,
+				Replaced:       1,
+			},
+		},
+		"progressing": {
+			spec:   updatev1alpha1.NodeImageSpec{ImageReference: "image"},
+			groups: nodeGroups{Outdated: outdated, UpToDate: upToDate},
+			wantProgress: updatev1alpha1.RolloutStatus{
+				ImageReference: "image",
+				Phase:          updatev1alpha1.RolloutPhaseProgressing,
+				Replaced:       1,
+				Remaining:      1,
+			},
+		},
+		"paused": {
+			spec: updatev1alpha1.NodeImageSpec{
+				ImageReference: "image",
+				Strategy:       updatev1alpha1.RolloutStrategy{Paused: true},
+			},
+			groups: nodeGroups{Outdated: outdated, Donors: outdated},
+			wantProgress: updatev1alpha1.RolloutStatus{
+				ImageReference: "image",
+				Phase:          updatev1alpha1.RolloutPhasePaused,
+				Remaining:      2,
+			},
+		},
+		"canary completed": {
+			spec: updatev1alpha1.NodeImageSpec{
+				ImageReference: "image",
+				Strategy:       updatev1alpha1.RolloutStrategy{Canary: 1},
+			},
+			groups: nodeGroups{Outdated: outdated, UpToDate: upToDate},
+			wantProgress: updatev1alpha1.RolloutStatus{
+				ImageReference:  "image",
+				Phase:           updatev1alpha1.RolloutPhaseProgressing,
+				Replaced:        1,
+				Remaining:       1,
+				CanaryCompleted: true,
+			},
+		},
+		"canary completion is kept": {
+			previous: updatev1alpha1.RolloutStatus{ImageReference: "image", CanaryCompleted: true},
+			spec: updatev1alpha1.NodeImageSpec{
+				ImageReference: "image",
+				Strategy:       updatev1alpha1.RolloutStrategy{Canary: 2},
+			},
+			groups: nodeGroups{Outdated: outdated},
+			wantProgress: updatev1alpha1.RolloutStatus{
+				ImageReference:  "image",
+				Phase:           updatev1alpha1.RolloutPhaseProgressing,
+				Remaining:       1,
+				CanaryCompleted: true,
+			},
+		},
+		"canary completion is reset for new image": {
+			previous: updatev1alpha1.RolloutStatus{ImageReference: "old-image", CanaryCompleted: true},
+			spec: updatev1alpha1.NodeImageSpec{
+				ImageReference: "image",
+				Strategy:       updatev1alpha1.RolloutStrategy{Canary: 2},
+			},
+			groups: nodeGroups{Outdated: outdated},
+			wantProgress: updatev1alpha1.RolloutStatus{
+				ImageReference: "image",
+				Phase:          updatev1alpha1.RolloutPhaseProgressing,
+				Remaining:      1,
+			},
+		},
+	}
+
+	for name, tc := range testCases {
+		t.Run(name, func(t *testing.T) {
+			assert.Equal(t, tc.wantProgress, rolloutProgress(tc.previous, tc.spec, tc.groups, nil))
+		})
+	}
+}
+
+func TestCanaryJustCompleted(t *testing.T) {
+	assert := assert.New(t)
+
+	completed := updatev1alpha1.RolloutStatus{ImageReference: "image", CanaryCompleted: true}
+	assert.True(canaryJustCompleted(updatev1alpha1.RolloutStatus{ImageReference: "image"}, completed))
+	assert.True(canaryJustCompleted(updatev1alpha1.RolloutStatus{ImageReference: "old-image", CanaryCompleted: true}, completed))
+	assert.False(canaryJustCompleted(completed, completed))
+	assert.False(canaryJustCompleted(updatev1alpha1.RolloutStatus{}, updatev1alpha1.RolloutStatus{ImageReference: "image"}))
+}
+
+func TestRolloutRoles(t *testing.T) {
+	controlPlane := corev1.Node{ObjectMeta: metav1.ObjectMeta{
+		Name:   "control-plane",
+		Labels: map[string]string{"node-role.kubernetes.io/control-plane": ""},
+	}}
+	worker := corev1.Node{ObjectMeta: metav1.ObjectMeta{Name: "worker"}}
+
+	testCases := map[string]struct {
+		order            updatev1alpha1.RolloutOrder
+		remaining        []corev1.Node
+		wantControlPlane bool
+		wantWorker       bool
+	}{
+		"no order": {
+			remaining:        []corev1.Node{controlPlane, worker},
+			wantControlPlane: true,
+			wantWorker:       true,
+		},
+		"workers first with remaining workers": {
+			order:      updatev1alpha1.RolloutOrderWorkersFirst,
+			remaining:  []corev1.Node{controlPlane, worker},
+			wantWorker: true,
+		},
+		"workers first without remaining workers": {
+			order:            updatev1alpha1.RolloutOrderWorkersFirst,
+			remaining:        []corev1.Node{controlPlane},
+			wantControlPlane: true,
+			wantWorker:       true,
+		},
+		"control plane first with remaining control plane": {
+			order:            updatev1alpha1.RolloutOrderControlPlaneFirst,
+			remaining:        []corev1.Node{controlPlane, worker},
+			wantControlPlane: true,
+		},
+		"control plane first without remaining control plane": {
+			order:            updatev1alpha1.RolloutOrderControlPlaneFirst,
+			remaining:        []corev1.Node{worker},
+			wantControlPlane: true,
+			wantWorker:       true,
+		},
+	}
+
+	for name, tc := range testCases {
+		t.Run(name, func(t *testing.T) {
+			assert := assert.New(t)
+
+			gotControlPlane, gotWorker := rolloutRoles(tc.order, tc.remaining)
+			assert.Equal(tc.wantControlPlane, gotControlPlane)
+			assert.Equal(tc.wantWorker, gotWorker)
+
+			filtered := filterByRole([]corev1.Node{controlPlane, worker}, gotControlPlane, gotWorker)
+			var wantFiltered []corev1.Node
+			if tc.wantControlPlane {
+				wantFiltered = append(wantFiltered, controlPlane)
+			}
+			if tc.wantWorker {
+				wantFiltered = append(wantFiltered, worker)
+			}
+			assert.Equal(wantFiltered, filtered)
+		})
+	}
+}
+
+func TestPassesHealthGate(t *testing.T) {
+	now := time.Now()
+	readyCondition := func(status corev1.ConditionStatus, since time.Duration) corev1.NodeCondition {
+		return corev1.NodeCondition{
+			Type:               corev1.NodeReady,
+			Status:             status,
+			LastTransitionTime: metav1.NewTime(now.Add(-since)),
+		}
+	}
+
+	testCases := map[string]struct {
+		conditions  []corev1.NodeCondition
+		gate        updatev1alpha1.NodeHealthGate
+		wantHealthy bool
+		wantWait    time.Duration
+	}{
+		"ready": {
+			conditions:  []corev1.NodeCondition{readyCondition(corev1.ConditionTrue, 0)},
+			wantHealthy: true,
+		},
+		"not ready": {
+			conditions: []corev1.NodeCondition{readyCondition(corev1.ConditionFalse, time.Hour)},
+		},
+		"no conditions": {},
+		"ready for long enough": {
+			conditions:  []corev1.NodeCondition{readyCondition(corev1.ConditionTrue, time.Minute)},
+			gate:        updatev1alpha1.NodeHealthGate{MinReadySeconds: 30},
+			wantHealthy: true,
+		},
+		"ready for too short": {
+			conditions: []corev1.NodeCondition{readyCondition(corev1.ConditionTrue, 10*time.Second)},
+			gate:       updatev1alpha1.NodeHealthGate{MinReadySeconds: 30},
+			wantWait:   20 * time.Second,
+		},
+		"required condition true": {
+			conditions: []corev1.NodeCondition{
+				readyCondition(corev1.ConditionTrue, 0),
+				{Type: "NetworkReady", Status: corev1.ConditionTrue},
+			},
+			gate:        updatev1alpha1.NodeHealthGate{Conditions: []corev1.NodeConditionType{"NetworkReady"}},
+			wantHealthy: true,
+		},
+		"required condition false": {
+			conditions: []corev1.NodeCondition{
+				readyCondition(corev1.ConditionTrue, 0),
+				{Type: "NetworkReady", Status: corev1.ConditionFalse},
+			},
+			gate: updatev1alpha1.NodeHealthGate{Conditions: []corev1.NodeConditionType{"NetworkReady"}},
+		},
+		"required condition missing": {
+			conditions: []corev1.NodeCondition{readyCondition(corev1.ConditionTrue, 0)},
+			gate:       updatev1alpha1.NodeHealthGate{Conditions: []corev1.NodeConditionType{"NetworkReady"}},
+		},
+	}
+
+	for name, tc := range testCases {
+		t.Run(name, func(t *testing.T) {
+			assert := assert.New(t)
+
+			node := &corev1.Node{Status: corev1.NodeStatus{Conditions: tc.conditions}}
+			healthy, wait := passesHealthGate(node, tc.gate, now)
+			assert.Equal(tc.wantHealthy, healthy)
+			assert.Equal(tc.wantWait, wait)
+		})
+	}
+}
+
+func TestNodesUnderMaintenance(t *testing.T) {
+	assert := assert.New(t)
+	require := require.New(t)
+
+	reconciler := NodeImageReconciler{
+		Client: newStubReaderClient(t, []runtime.Object{
+			&updatev1alpha1.NodeDrain{
+				ObjectMeta: metav1.ObjectMeta{Name: "drain"},
+				Spec:       updatev1alpha1.NodeDrainSpec{NodeName: "node"},
+			},
+		}, nil, nil),
+	}
+	nodes, err := reconciler.nodesUnderMaintenance(context.Background())
+	require.NoError(err)
+	require.Len(nodes, 1)
+	assert.Equal("drain", nodes["node"].Name)
+}
+
+func TestDrainFailedCondition(t *testing.T) {
+	nodeDrain := func(reason string, phase updatev1alpha1.NodeDrainPhase) updatev1alpha1.NodeDrain {
+		return updatev1alpha1.NodeDrain{
+			Spec:   updatev1alpha1.NodeDrainSpec{Reason: reason},
+			Status: updatev1alpha1.NodeDrainStatus{Phase: phase},
+		}
+	}
+
+	testCases := map[string]struct {
+		nodeDrains  map[string]updatev1alpha1.NodeDrain
+		wantStatus  metav1.ConditionStatus
+		wantMessage string
+	}{
+		"no node drains": {
+			wantStatus:  metav1.ConditionFalse,
+			wantMessage: conditionNodeDrainsHealthyMessage,
+		},
+		"drains in progress": {
+			nodeDrains: map[string]updatev1alpha1.NodeDrain{
+				"a": nodeDrain(nodeReplacementReason, updatev1alpha1.NodeDrainPhaseEvicting),
+				"b": nodeDrain(nodeReplacementReason, updatev1alpha1.NodeDrainPhaseSucceeded),
+			},
+			wantStatus:  metav1.ConditionFalse,
+			wantMessage: conditionNodeDrainsHealthyMessage,
+		},
+		"drains failed": {
+			nodeDrains: map[string]updatev1alpha1.NodeDrain{
+				"b": nodeDrain(nodeReplacementReason, updatev1alpha1.NodeDrainPhaseFailed),
+				"a": nodeDrain(nodeReplacementReason, updatev1alpha1.NodeDrainPhaseFailed),
+				"c": nodeDrain(nodeReplacementReason, updatev1alpha1.NodeDrainPhaseEvicting),
+			},
+			wantStatus:  metav1.ConditionTrue,
+			wantMessage: "Draining nodes failed, see their NodeDrains: a, b",
+		},
+		"failed drains of other controllers are ignored": {
+			nodeDrains: map[string]updatev1alpha1.NodeDrain{
+				"a": nodeDrain(kubernetesUpgradeReason, updatev1alpha1.NodeDrainPhaseFailed),
+			},
+			wantStatus:  metav1.ConditionFalse,
+			wantMessage: conditionNodeDrainsHealthyMessage,
+		},
+	}
+
+	for name, tc := range testCases {
+		t.Run(name, func(t *testing.T) {
+			assert := assert.New(t)
+
+			cond := drainFailedCondition(tc.nodeDrains)
+			assert.Equal(updatev1alpha1.ConditionDrainFailed, cond.Type)
+			assert.Equal(tc.wantStatus, cond.Status)
+			assert.Equal(tc.wantMessage, cond.Message)
+		})
+	}
+}
+
+func TestPauseRollout(t *testing.T) {
+	testCases := map[string]struct {
+		getErr    error
+		updateErr error
+		wantErr   bool
+	}{
+		"pausing works": {},
+		"get fails": {
+			getErr:  errors.New("get failed"),
+			wantErr: true,
+		},
+		"update fails": {
+			updateErr: errors.New("update failed"),
+			wantErr:   true,
+		},
+	}
+
+	for name, tc := range testCases {
+		t.Run(name, func(t *testing.T) {
+			assert := assert.New(t)
+
+			reconciler := NodeImageReconciler{
+				Client: &stubReadWriterClient{
+					stubReaderClient: *newStubReaderClient(t, []runtime.Object{
+						&updatev1alpha1.NodeImage{ObjectMeta: metav1.ObjectMeta{Name: "nodeimage"}},
+					}, tc.getErr, nil),
+					stubWriterClient: stubWriterClient{updateErr: tc.updateErr},
+				},
+			}
+			err := reconciler.pauseRollout(context.Background(), types.NamespacedName{Name: "nodeimage"})
+			if tc.wantErr {
+				assert.Error(err)
+				return
+			}
+			assert.NoError(err)
+		})
+	}
+}
@@ -18,7 +18,6 @@ import (
 	"sigs.k8s.io/controller-runtime/pkg/reconcile"
 
 	updatev1alpha1 "github.com/edgelesssys/constellation/operators/constellation-node-operator/v2/api/v1alpha1"
-	nodemaintenancev1beta1 "github.com/medik8s/node-maintenance-operator/api/v1beta1"
 )
 
 // scalingGroupImageChangedPredicate checks if a scaling group has adopted a new node image for future nodes.
@@ -74,21 +73,21 @@ func nodeReadyPredicate() predicate.Predicate {
 	}
 }
 
-// nodeMaintenanceSucceededPredicate checks if a node maintenance resource switched its status to "maintenance succeeded".
-func nodeMaintenanceSucceededPredicate() predicate.Predicate {
+// nodeDrainFinishedPredicate checks if a node drain resource switched its phase to "succeeded" or "failed".
+func nodeDrainFinishedPredicate() predicate.Predicate {
 	return predicate.Funcs{
 		UpdateFunc: func(e event.UpdateEvent) bool {
-			oldNode, ok := e.ObjectOld.(*nodemaintenancev1beta1.NodeMaintenance)
+			oldDrain, ok := e.ObjectOld.(*updatev1alpha1.NodeDrain)
 			if !ok {
 				return false
 			}
-			newNode, ok := e.ObjectNew.(*nodemaintenancev1beta1.NodeMaintenance)
+			newDrain, ok := e.ObjectNew.(*updatev1alpha1.NodeDrain)
 			if !ok {
 				return false
 			}
-			succeeded := oldNode.Status.Phase != nodemaintenancev1beta1.MaintenanceSucceeded &&
-				newNode.Status.Phase == nodemaintenancev1beta1.MaintenanceSucceeded
-			return succeeded
+			finished := newDrain.Status.Phase == updatev1alpha1.NodeDrainPhaseSucceeded ||
+				newDrain.Status.Phase == updatev1alpha1.NodeDrainPhaseFailed
+			return finished && oldDrain.Status.Phase != newDrain.Status.Phase
 		},
 	}
 }
@@ -20,7 +20,6 @@ import (
 	"sigs.k8s.io/controller-runtime/pkg/reconcile"
 
 	updatev1alpha1 "github.com/edgelesssys/constellation/operators/constellation-node-operator/v2/api/v1alpha1"
-	nodemaintenancev1beta1 "github.com/medik8s/node-maintenance-operator/api/v1beta1"
 )
 
 func TestScalingGroupImageChangedPredicate(t *testing.T) {
@@ -179,56 +178,85 @@ func TestNodeReadyPredicate(t *testing.T) {
 	}
 }
 
-func TestNodeMaintenanceSucceededPredicate(t *testing.T) {
+func TestNodeDrainFinishedPredicate(t *testing.T) {
 	testCases := map[string]struct {
 		event          event.UpdateEvent
 		wantProcessing bool
 	}{
-		"old object is not a node maintenance resource": {
+		"old object is not a node drain resource": {
 			event: event.UpdateEvent{
-				ObjectNew: &nodemaintenancev1beta1.NodeMaintenance{},
+				ObjectNew: &updatev1alpha1.NodeDrain{},
 			},
 		},
-		"new object is not a node maintenance resource": {
+		"new object is not a node drain resource": {
 			event: event.UpdateEvent{
-				ObjectOld: &nodemaintenancev1beta1.NodeMaintenance{},
+				ObjectOld: &updatev1alpha1.NodeDrain{},
 			},
 		},
 		"status is unchanged": {
 			event: event.UpdateEvent{
-				ObjectOld: &nodemaintenancev1beta1.NodeMaintenance{
-					Status: nodemaintenancev1beta1.NodeMaintenanceStatus{
-						Phase: nodemaintenancev1beta1.MaintenanceRunning,
+				ObjectOld: &updatev1alpha1.NodeDrain{
+					Status: updatev1alpha1.NodeDrainStatus{
+						Phase: updatev1alpha1.NodeDrainPhaseEvicting,
 					},
 				},
-				ObjectNew: &nodemaintenancev1beta1.NodeMaintenance{
-					Status: nodemaintenancev1beta1.NodeMaintenanceStatus{
-						Phase: nodemaintenancev1beta1.MaintenanceRunning,
+				ObjectNew: &updatev1alpha1.NodeDrain{
+					Status: updatev1alpha1.NodeDrainStatus{
+						Phase: updatev1alpha1.NodeDrainPhaseEvicting,
 					},
 				},
 			},
 		},
 		"status has changed": {
 			event: event.UpdateEvent{
-				ObjectOld: &nodemaintenancev1beta1.NodeMaintenance{
-					Status: nodemaintenancev1beta1.NodeMaintenanceStatus{
-						Phase: nodemaintenancev1beta1.MaintenanceRunning,
+				ObjectOld: &updatev1alpha1.NodeDrain{
+					Status: updatev1alpha1.NodeDrainStatus{
+						Phase: updatev1alpha1.NodeDrainPhaseEvicting,
 					},
 				},
-				ObjectNew: &nodemaintenancev1beta1.NodeMaintenance{
-					Status: nodemaintenancev1beta1.NodeMaintenanceStatus{
-						Phase: nodemaintenancev1beta1.MaintenanceSucceeded,
+				ObjectNew: &updatev1alpha1.NodeDrain{
+					Status: updatev1alpha1.NodeDrainStatus{
+						Phase: updatev1alpha1.NodeDrainPhaseSucceeded,
 					},
 				},
 			},
 			wantProcessing: true,
 		},
+		"drain failed": {
+			event: event.UpdateEvent{
+				ObjectOld: &updatev1alpha1.NodeDrain{
+					Status: updatev1alpha1.NodeDrainStatus{
+						Phase: updatev1alpha1.NodeDrainPhasePostDrain,
+					},
+				},
+				ObjectNew: &updatev1alpha1.NodeDrain{
+					Status: updatev1alpha1.NodeDrainStatus{
+						Phase: updatev1alpha1.NodeDrainPhaseFailed,
+					},
+				},
+			},
+			wantProcessing: true,
+		},
+		"failed drain is retried": {
+			event: event.UpdateEvent{
+				ObjectOld: &updatev1alpha1.NodeDrain{
+					Status: updatev1alpha1.NodeDrainStatus{
+						Phase: updatev1alpha1.NodeDrainPhaseFailed,
+					},
+				},
+				ObjectNew: &updatev1alpha1.NodeDrain{
+					Status: updatev1alpha1.NodeDrainStatus{
+						Phase: updatev1alpha1.NodeDrainPhasePreDrain,
+					},
+				},
+			},
+		},
 	}
 
 	for name, tc := range testCases {
 		t.Run(name, func(t *testing.T) {
 			assert := assert.New(t)
-			predicate := nodeMaintenanceSucceededPredicate()
+			predicate := nodeDrainFinishedPredicate()
 			assert.Equal(tc.wantProcessing, predicate.Update(tc.event))
 		})
 	}
@@ -0,0 +1,637 @@
+/*
+Copyright (c) Edgeless Systems GmbH
+
+SPDX-License-Identifier: AGPL-3.0-only
+*/
+
+package controllers
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/edgelesssys/constellation/operators/constellation-node-operator/v2/internal/constants"
+	nodeutil "github.com/edgelesssys/constellation/operators/constellation-node-operator/v2/internal/node"
+	batchv1 "k8s.io/api/batch/v1"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/meta"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/apimachinery/pkg/util/version"
+	ref "k8s.io/client-go/tools/reference"
+	"k8s.io/client-go/util/retry"
+	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/builder"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/handler"
+	"sigs.k8s.io/controller-runtime/pkg/log"
+	"sigs.k8s.io/controller-runtime/pkg/source"
+
+	updatev1alpha1 "github.com/edgelesssys/constellation/operators/constellation-node-operator/v2/api/v1alpha1"
+)
+
+const (
+	// kubernetesUpgradeNamespace is the namespace of the jobs upgrading nodes.
+	kubernetesUpgradeNamespace = "kube-system"
+	// kubernetesUpgradeNodeLabel is set on upgrade jobs to the name of the node they upgrade.
+	kubernetesUpgradeNodeLabel = "constellation.edgeless.systems/kubernetes-upgrade-node"
+	// kubernetesUpgradeReason prefixes the reason of node drains before node upgrades.
+	kubernetesUpgradeReason = "node is upgraded to Kubernetes"
+	// kubernetesUpgradeBackoffLimit is the number of retries of a failed upgrade job.
+	kubernetesUpgradeBackoffLimit = 2
+	// kubeadmPath is the path of the kubeadm binary on the nodes.
+	kubeadmPath = "/run/state/bin/kubeadm"
+	// nodeImageRolloutRequeueInterval is the time to wait for node image rollouts to complete before upgrading nodes.
+	nodeImageRolloutRequeueInterval = time.Minute
+
+	conditionKubernetesUpgradingReason          = "Upgrading"
+	conditionKubernetesUpToDateReason           = "UpToDate"
+	conditionKubernetesVersionSkewReason        = "UnsupportedVersionSkew"
+	conditionKubernetesMissingComponentsReason  = "MissingComponents"
+	conditionKubernetesNodeImageRolloutReason   = "NodeImageRolloutInProgress"
+	conditionKubernetesUpgradeJobFailedReason   = "UpgradeJobFailed"
+	conditionKubernetesDrainFailedReason        = "DrainFailed"
+	conditionKubernetesMissingComponentsMessage = "No components to install on the nodes are specified"
+	conditionKubernetesNodeImageRolloutMessage  = "Waiting for the replacement of nodes with outdated images to complete"
+	conditionKubernetesUpgradeJobFailedMessage  = "Upgrade job failed, delete the job to retry"
+	conditionKubernetesDrainFailedMessage       = "Draining node failed, see its NodeDrain"
+	conditionKubernetesUpToDateMessage          = "All nodes run the desired Kubernetes version"
+	conditionKubernetesUpgradingMessageTemplate = "Upgrading %d outdated nodes"
+	conditionKubernetesVersionSkewMessagePrefix = "Upgrade violates the Kubernetes version skew policy: "
+)
+
+var errUpgradeJobFailed = errors.New("upgrade job failed")
+
+// NodeKubernetesVersionReconciler reconciles a NodeKubernetesVersion object.
+type NodeKubernetesVersionReconciler struct {
+	client.Client
+	Scheme       *runtime.Scheme
+	upgradeImage string
+}
+
+// NewNodeKubernetesVersionReconciler creates a new NodeKubernetesVersionReconciler.
+// upgradeImage is the image of the upgrade jobs. If it is empty, constants.DefaultKubernetesUpgradeImage is used.
+func NewNodeKubernetesVersionReconciler(client client.Client, scheme *runtime.Scheme, upgradeImage string) *NodeKubernetesVersionReconciler {
+	if upgradeImage == "" {
+		upgradeImage = constants.DefaultKubernetesUpgradeImage
+	}
+	return &NodeKubernetesVersionReconciler{
+		Client:       client,
+		Scheme:       scheme,
+		upgradeImage: upgradeImage,
+	}
+}
+
+//+kubebuilder:rbac:groups=update.edgeless.systems,resources=nodekubernetesversions,verbs=get;list;watch;create;update;patch;delete
+//+kubebuilder:rbac:groups=update.edgeless.systems,resources=nodekubernetesversions/status,verbs=get;update;patch
+//+kubebuilder:rbac:groups=update.edgeless.systems,resources=nodekubernetesversions/finalizers,verbs=update
+//+kubebuilder:rbac:groups=update.edgeless.systems,resources=nodeimages,verbs=get;list;watch
+//+kubebuilder:rbac:groups=update.edgeless.systems,resources=nodedrains,verbs=get;list;watch;create;update;patch;delete
+//+kubebuilder:rbac:groups=batch,resources=jobs,verbs=get;list;watch;create;delete
+//+kubebuilder:rbac:groups="",resources=configmaps,verbs=get;update
+//+kubebuilder:rbac:groups="",resources=nodes,verbs=get;list;watch
+
+// Reconcile upgrades the Kubernetes components of all nodes to the version specified in the NodeKubernetesVersion spec.
+//
+// Control plane nodes are upgraded first, one after another. The first control plane node runs "kubeadm upgrade apply",
+// all other nodes run "kubeadm upgrade node". Afterwards, the version installed on joining nodes is updated
+// and worker nodes are upgraded. Every node is drained before its upgrade job is started.
+func (r *NodeKubernetesVersionReconciler) Reconcile(ctx context.Context, req ctrl.Request) (ctrl.Result, error) {
+	logr := log.FromContext(ctx)
+	logr.Info("Reconciling NodeKubernetesVersion")
+
+	var desiredVersion updatev1alpha1.NodeKubernetesVersion
+	if err := r.Get(ctx, req.NamespacedName, &desiredVersion); err != nil {
+		return ctrl.Result{}, client.IgnoreNotFound(err)
+	}
+	target, err := version.ParseSemantic(desiredVersion.Spec.Version)
+	if err != nil {
+		logr.Error(err, "Invalid Kubernetes version", "version", desiredVersion.Spec.Version)
+		return ctrl.Result{}, nil
+	}
+
+	var nodeList corev1.NodeList
+	if err := r.List(ctx, &nodeList); err != nil {
+		logr.Error(err, "Unable to list nodes")
+		return ctrl.Result{}, err
+	}
+	var jobList batchv1.JobList
+	if err := r.List(ctx, &jobList, client.InNamespace(kubernetesUpgradeNamespace), client.HasLabels{kubernetesUpgradeNodeLabel}); err != nil {
+		logr.Error(err, "Unable to list upgrade jobs")
+		return ctrl.Result{}, err
+	}
+	var drainList updatev1alpha1.NodeDrainList
+	if err := r.List(ctx, &drainList); err != nil {
+		logr.Error(err, "Unable to list node drains")
+		return ctrl.Result{}, err
+	}
+	jobs := upgradeJobsByNode(jobList.Items)
+	drains := upgradeDrainsByNode(drainList.Items)
+	groups := groupNodesByKubernetesVersion(nodeList.Items, target)
+
+	logr.Info("Grouped nodes",
+		"outdatedControlPlaneNodes", len(groups.ControlPlaneOutdated),
+		"outdatedWorkerNodes", len(groups.WorkerOutdated),
+		"upToDateNodes", len(groups.UpToDate),
+		"invalidNodes", len(groups.Invalid))
+
+	for _, node := range groups.UpToDate {
+		if err := r.finishNodeUpgrade(ctx, node.Name, jobs, drains); err != nil {
+			logr.Error(err, "Unable to clean up after node upgrade", "node", node.Name)
+			return ctrl.Result{}, err
+		}
+	}
+
+	status := kubernetesVersionStatus(r.Scheme, desiredVersion.Status.Conditions, groups)
+	outdated := len(groups.ControlPlaneOutdated) + len(groups.WorkerOutdated)
+	if err := checkVersionSkew(nodeList.Items, target); err != nil {
+		logr.Info("Upgrade is blocked", "reason", err.Error())
+		setUpgradeBlocked(&status, conditionKubernetesVersionSkewReason, conditionKubernetesVersionSkewMessagePrefix+err.Error())
+		return ctrl.Result{}, r.tryUpdateStatus(ctx, req.NamespacedName, status)
+	}
+	if outdated > 0 && len(desiredVersion.Spec.Components) == 0 {
+		setUpgradeBlocked(&status, conditionKubernetesMissingComponentsReason, conditionKubernetesMissingComponentsMessage)
+		return ctrl.Result{}, r.tryUpdateStatus(ctx, req.NamespacedName, status)
+	}
+	if outdated > 0 {
+		inProgress, err := r.nodeImageRolloutInProgress(ctx)
+		if err != nil {
+			logr.Error(err, "Unable to list node images")
+			return ctrl.Result{}, err
+		}
+		if inProgress {
+			logr.Info("Node image rollout in progress, not upgrading nodes")
+			setUpgradeBlocked(&status, conditionKubernetesNodeImageRolloutReason, conditionKubernetesNodeImageRolloutMessage)
+			if err := r.tryUpdateStatus(ctx, req.NamespacedName, status); err != nil {
+				return ctrl.Result{}, err
+			}
+			return ctrl.Result{RequeueAfter: nodeImageRolloutRequeueInterval}, nil
+		}
+	}
+
+	var upgrading []corev1.Node
+	var kubeadmArgs []string
+	switch {
+	case len(groups.ControlPlaneOutdated) > 0:
+		status.Phase = updatev1alpha1.KubernetesUpgradePhaseControlPlane
+		// control plane nodes are upgraded one by one to keep etcd quorum
+		upgrading = selectNodesForUpgrade(groups.ControlPlaneOutdated, jobs, drains, 1)
+		kubeadmArgs = []string{"upgrade", "node"}
+		if len(groups.ControlPlaneUpToDate) == 0 {
+			// no control plane node runs the desired version yet, the cluster configuration has to be upgraded
+			kubeadmArgs = []string{"upgrade", "apply", "v" + target.String(), "--yes"}
+		}
+	case len(groups.WorkerOutdated) > 0:
+		status.Phase = updatev1alpha1.KubernetesUpgradePhaseWorkers
+		upgrading = selectNodesForUpgrade(groups.WorkerOutdated, jobs, drains, kubernetesMaxUnavailable(desiredVersion.Spec))
+		kubeadmArgs = []string{"upgrade", "node"}
+	default:
+		status.Phase = updatev1alpha1.KubernetesUpgradePhaseCompleted
+	}
+
+	// once the control plane is upgraded, new nodes join using the desired version
+	if len(groups.ControlPlaneOutdated) == 0 {
+		if err := r.ensureK8sVersionConfigMap(ctx, target, desiredVersion.Spec.Components); err != nil {
+			logr.Error(err, "Unable to update Kubernetes version of joining nodes")
+			return ctrl.Result{}, err
+		}
+	}
+
+	meta.SetStatusCondition(&status.Conditions, upgradeProgressCondition(outdated))
+	var upgradeErr error
+	for _, node := range upgrading {
+		nodeRef, err := ref.GetReference(r.Scheme, &node)
+		if err == nil {
+			status.Upgrading = append(status.Upgrading, *nodeRef)
+		}
+		logr.Info("Upgrading node", "node", node.Name, "kubeletVersion", node.Status.NodeInfo.KubeletVersion)
+		err = r.upgradeNode(ctx, &desiredVersion, node, kubeadmArgs, jobs[node.Name], drains[node.Name])
+		if errors.Is(err, errUpgradeJobFailed) {
+			logr.Info("Upgrade job failed", "node", node.Name)
+			setUpgradeBlocked(&status, conditionKubernetesUpgradeJobFailedReason, fmt.Sprintf("%s: %s", conditionKubernetesUpgradeJobFailedMessage, node.Name))
+			continue
+		}
+		if errors.Is(err, errNodeDrainFailed) {
+			logr.Info("Draining node failed", "node", node.Name)
+			setUpgradeBlocked(&status, conditionKubernetesDrainFailedReason, fmt.Sprintf("%s: %s", conditionKubernetesDrainFailedMessage, node.Name))
+			continue
+		}
+		if err != nil {
+			logr.Error(err, "Unable to upgrade node", "node", node.Name)
+			upgradeErr = err
+		}
+	}
+
+	if err := r.tryUpdateStatus(ctx, req.NamespacedName, status); err != nil {
+		logr.Error(err, "Updating status")
+		return ctrl.Result{}, err
+	}
+	return ctrl.Result{}, upgradeErr
+}
+
+// SetupWithManager sets up the controller with the Manager.
+func (r *NodeKubernetesVersionReconciler) SetupWithManager(mgr ctrl.Manager) error {
+	return ctrl.NewControllerManagedBy(mgr).
+		For(&updatev1alpha1.NodeKubernetesVersion{}).
+		Watches(
+			&source.Kind{Type: &corev1.Node{}},
+			handler.EnqueueRequestsFromMapFunc(r.findAllNodeKubernetesVersions),
+			builder.WithPredicates(kubeletVersionChangedPredicate()),
+		).
+		Watches(
+			&source.Kind{Type: &updatev1alpha1.NodeDrain{}},
+			handler.EnqueueRequestsFromMapFunc(r.findAllNodeKubernetesVersions),
+			builder.WithPredicates(nodeDrainFinishedPredicate()),
+		).
+		Owns(&batchv1.Job{}).
+		Complete(r)
+}
+
+// upgradeNode drains a node and starts the job upgrading its Kubernetes components.
+// The upgrade is finished by finishNodeUpgrade once the node's kubelet reports the desired version.
+// If draining the node failed, the node is only upgraded if the drain failure policy is "Force".
+func (r *NodeKubernetesVersionReconciler) upgradeNode(
+	ctx context.Context, desiredVersion *updatev1alpha1.NodeKubernetesVersion, node corev1.Node,
+	kubeadmArgs []string, job *batchv1.Job, nodeDrain *updatev1alpha1.NodeDrain,
+) error {
+	logr := log.FromContext(ctx)
+	// cordon & drain node
+	if nodeDrain == nil {
+		reason := fmt.Sprintf("%s %s", kubernetesUpgradeReason, desiredVersion.Spec.Version)
+		return r.Create(ctx, newNodeDrain(node.Name, reason, desiredVersion.Spec.Drain))
+	}
+	switch {
+	case nodeDrain.Status.Phase == updatev1alpha1.NodeDrainPhaseFailed && nodeDrain.Spec.FailurePolicy == updatev1alpha1.DrainFailurePolicyForce:
+		logr.Info("Draining node failed, forcing upgrade", "drainNode", node.Name)
+	case nodeDrain.Status.Phase == updatev1alpha1.NodeDrainPhaseFailed:
+		return fmt.Errorf("%w: node %s", errNodeDrainFailed, node.Name)
+	case nodeDrain.Status.Phase != updatev1alpha1.NodeDrainPhaseSucceeded:
+		logr.Info("Cordon & drain in progress", "drainNode", node.Name, "nodeDrainPhase", nodeDrain.Status.Phase)
+		return nil
+	}
+
+	if job == nil {
+		job := newKubernetesUpgradeJob(r.upgradeImage, node.Name, desiredVersion.Spec.Components, kubeadmArgs)
+		if err := ctrl.SetControllerReference(desiredVersion, job, r.Scheme); err != nil {
+			return err
+		}
+		return r.Create(ctx, job)
+	}
+	if jobFailed(job) {
+		return fmt.Errorf("%w: node %s", errUpgradeJobFailed, node.Name)
+	}
+	return nil
+}
+
+// finishNodeUpgrade removes the upgrade job and uncordons a node that runs the desired version.
+func (r *NodeKubernetesVersionReconciler) finishNodeUpgrade(
+	ctx context.Context, nodeName string,
+	jobs map[string]*batchv1.Job, drains map[string]*updatev1alpha1.NodeDrain,
+) error {
+	if job, ok := jobs[nodeName]; ok {
+		if err := r.Delete(ctx, job, client.PropagationPolicy(metav1.DeletePropagationBackground)); client.IgnoreNotFound(err) != nil {
+			return err
+		}
+	}
+	if nodeDrain, ok := drains[nodeName]; ok {
+		if err := r.Delete(ctx, nodeDrain); client.IgnoreNotFound(err) != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// nodeImageRolloutInProgress checks if nodes are being replaced because of an image update.
+// Nodes are not upgraded in place while they are being replaced.
+func (r *NodeKubernetesVersionReconciler) nodeImageRolloutInProgress(ctx context.Context) (bool, error) {
+	var nodeImageList updatev1alpha1.NodeImageList
+	if err := r.List(ctx, &nodeImageList); err != nil {
+		return false, err
+	}
+	for _, nodeImage := range nodeImageList.Items {
+		status := nodeImage.Status
+		if len(status.Outdated)+len(status.Donors)+len(status.Heirs)+len(status.Pending) > 0 {
+			return true, nil
+		}
+	}
+	return false, nil
+}
+
+// ensureK8sVersionConfigMap sets the Kubernetes version and components installed on joining nodes to the ones of desiredVersion.
+func (r *NodeKubernetesVersionReconciler) ensureK8sVersionConfigMap(ctx context.Context, target *version.Version, components []updatev1alpha1.KubernetesComponent) error {
+	return retry.RetryOnConflict(retry.DefaultRetry, func() error {
+		var configMap corev1.ConfigMap
+		if err := r.Get(ctx, types.NamespacedName{Namespace: kubernetesUpgradeNamespace, Name: constants.K8sVersionConfigMapName}, &configMap); err != nil {
+			return err
+		}
+		changed, err := setJoinKubernetesVersion(&configMap, target, components)
+		if err != nil || !changed {
+			return err
+		}
+		return r.Update(ctx, &configMap)
+	})
+}
+
+// setJoinKubernetesVersion writes the minor version of target and the pinned components to the k8s-version ConfigMap.
+// The bootstrapper of joining nodes installs the components on top of the release it knows for the minor version,
+// so nodes joining after a patch upgrade run the same binaries as the upgraded nodes.
+func setJoinKubernetesVersion(configMap *corev1.ConfigMap, target *version.Version, components []updatev1alpha1.KubernetesComponent) (bool, error) {
+	minorVersion := fmt.Sprintf("%d.%d", target.Major(), target.Minor())
+	var componentsJSON string
+	if len(components) > 0 {
+		raw, err := json.Marshal(components)
+		if err != nil {
+			return false, fmt.Errorf("encoding Kubernetes components: %w", err)
+		}
+		componentsJSON = string(raw)
+	}
+	if configMap.Data[constants.K8sVersionConfigMapKey] == minorVersion && configMap.Data[constants.K8sComponentsConfigMapKey] == componentsJSON {
+		return false, nil
+	}
+	if configMap.Data == nil {
+		configMap.Data = map[string]string{}
+	}
+	configMap.Data[constants.K8sVersionConfigMapKey] = minorVersion
+	if componentsJSON == "" {
+		delete(configMap.Data, constants.K8sComponentsConfigMapKey)
+	} else {
+		configMap.Data[constants.K8sComponentsConfigMapKey] = componentsJSON
+	}
+	return true, nil
+}
+
+// tryUpdateStatus attempts to update the NodeKubernetesVersion status field in a retry loop.
+func (r *NodeKubernetesVersionReconciler) tryUpdateStatus(ctx context.Context, name types.NamespacedName, status updatev1alpha1.NodeKubernetesVersionStatus) error {
+	return retry.RetryOnConflict(retry.DefaultRetry, func() error {
+		var kubernetesVersion updatev1alpha1.NodeKubernetesVersion
+		if err := r.Get(ctx, name, &kubernetesVersion); err != nil {
+			return err
+		}
+		kubernetesVersion.Status = *status.DeepCopy()
+		return r.Status().Update(ctx, &kubernetesVersion)
+	})
+}
+
+// kubernetesVersionGroups is a grouping of nodes by their kubelet version.
+type kubernetesVersionGroups struct {
+	// ControlPlaneOutdated are control plane nodes running an outdated version.
+	ControlPlaneOutdated []corev1.Node
+	// ControlPlaneUpToDate are control plane nodes running the desired version.
+	ControlPlaneUpToDate []corev1.Node
+	// WorkerOutdated are worker nodes running an outdated version.
+	WorkerOutdated []corev1.Node
+	// UpToDate are all nodes running the desired version.
+	UpToDate []corev1.Node
+	// Invalid are nodes reporting an unparsable kubelet version.
+	Invalid []corev1.Node
+}
+
+// groupNodesByKubernetesVersion groups nodes by their role and whether their kubelet runs the target version.
+func groupNodesByKubernetesVersion(nodes []corev1.Node, target *version.Version) kubernetesVersionGroups {
+	var groups kubernetesVersionGroups
+	for _, node := range nodes {
+		kubeletVersion, err := version.ParseSemantic(node.Status.NodeInfo.KubeletVersion)
+		if err != nil {
+			groups.Invalid = append(groups.Invalid, node)
+			continue
+		}
+		upToDate := !kubeletVersion.LessThan(target) && !target.LessThan(kubeletVersion)
+		controlPlane := nodeutil.IsControlPlaneNode(&node)
+		switch {
+		case upToDate && controlPlane:
+			groups.ControlPlaneUpToDate = append(groups.ControlPlaneUpToDate, node)
+			groups.UpToDate = append(groups.UpToDate, node)
+		case upToDate:
+			groups.UpToDate = append(groups.UpToDate, node)
+		case controlPlane:
+			groups.ControlPlaneOutdated = append(groups.ControlPlaneOutdated, node)
+		default:
+			groups.WorkerOutdated = append(groups.WorkerOutdated, node)
+		}
+	}
+	return groups
+}
+
+// checkVersionSkew returns an error if upgrading the nodes to the target version violates the Kubernetes version skew policy.
+// Downgrades are not supported, control plane nodes may only be upgraded by one minor version
+// and the kubelet of worker nodes may be up to two minor versions older than the control plane.
+// Reference: https://kubernetes.io/releases/version-skew-policy/ .
+func checkVersionSkew(nodes []corev1.Node, target *version.Version) error {
+	for _, node := range nodes {
+		kubeletVersion, err := version.ParseSemantic(node.Status.NodeInfo.KubeletVersion)
+		if err != nil {
+			continue
+		}
+		if kubeletVersion.Major() != target.Major() {
+			return fmt.Errorf("node %s runs Kubernetes %s, upgrades to another major version are not supported", node.Name, node.Status.NodeInfo.KubeletVersion)
+		}
+		if target.LessThan(kubeletVersion) {
+			return fmt.Errorf("node %s runs Kubernetes %s, downgrades are not supported", node.Name, node.Status.NodeInfo.KubeletVersion)
+		}
+		if nodeutil.IsControlPlaneNode(&node) && target.Minor() > kubeletVersion.Minor()+1 {
+			return fmt.Errorf("control plane node %s runs Kubernetes %s, minor versions cannot be skipped", node.Name, node.Status.NodeInfo.KubeletVersion)
+		}
+		if target.Minor() > kubeletVersion.Minor()+2 {
+			return fmt.Errorf("worker node %s runs Kubernetes %s, which is more than two minor versions older", node.Name, node.Status.NodeInfo.KubeletVersion)
+		}
+	}
+	return nil
+}
+
+// selectNodesForUpgrade returns the outdated nodes that are being upgraded
+// and adds further nodes until limit nodes are upgraded at the same time.
+func selectNodesForUpgrade(
+	outdated []corev1.Node, jobs map[string]*batchv1.Job,
+	drains map[string]*updatev1alpha1.NodeDrain, limit int,
+) []corev1.Node {
+	sorted := append([]corev1.Node{}, outdated...)
+	sort.Slice(sorted, func(i, j int) bool { return sorted[i].Name < sorted[j].Name })
+
+	var selected, waiting []corev1.Node
+	for _, node := range sorted {
+		_, hasJob := jobs[node.Name]
+		_, hasDrain := drains[node.Name]
+		if hasJob || hasDrain {
+			selected = append(selected, node)
+		} else {
+			waiting = append(waiting, node)
+		}
+	}
+	for _, node := range waiting {
+		if len(selected) >= limit {
+			break
+		}
+		selected = append(selected, node)
+	}
+	return selected
+}
+
+// upgradeJobsByNode maps upgrade jobs to the nodes they upgrade.
+func upgradeJobsByNode(jobs []batchv1.Job) map[string]*batchv1.Job {
+	result := make(map[string]*batchv1.Job, len(jobs))
+	for i := range jobs {
+		result[jobs[i].Labels[kubernetesUpgradeNodeLabel]] = &jobs[i]
+	}
+	return result
+}
+
+// upgradeDrainsByNode maps node drains created for Kubernetes upgrades to the nodes they drain.
+// Node drains created for other reasons, e.g. node replacements, are ignored.
+func upgradeDrainsByNode(drains []updatev1alpha1.NodeDrain) map[string]*updatev1alpha1.NodeDrain {
+	result := make(map[string]*updatev1alpha1.NodeDrain)
+	for i := range drains {
+		if strings.HasPrefix(drains[i].Spec.Reason, kubernetesUpgradeReason) {
+			result[drains[i].Spec.NodeName] = &drains[i]
+		}
+	}
+	return result
+}
+
+// kubernetesMaxUnavailable returns the maximum number of worker nodes upgraded at the same time.
+func kubernetesMaxUnavailable(spec updatev1alpha1.NodeKubernetesVersionSpec) int {
+	if spec.MaxUnavailable == nil || *spec.MaxUnavailable < 1 {
+		return 1
+	}
+	return int(*spec.MaxUnavailable)
+}
+
+// jobFailed checks if a job has reached its backoff limit.
+func jobFailed(job *batchv1.Job) bool {
+	for _, cond := range job.Status.Conditions {
+		if cond.Type == batchv1.JobFailed && cond.Status == corev1.ConditionTrue {
+			return true
+		}
+	}
+	return false
+}
+
+// newKubernetesUpgradeJob returns a job installing the components on a node, running kubeadm and restarting the kubelet.
+// The job runs in the host namespaces, so components are installed to the node's file system.
+func newKubernetesUpgradeJob(image, nodeName string, components []updatev1alpha1.KubernetesComponent, kubeadmArgs []string) *batchv1.Job {
+	backoffLimit := int32(kubernetesUpgradeBackoffLimit)
+	privileged := true
+	return &batchv1.Job{
+		ObjectMeta: metav1.ObjectMeta{
+			GenerateName: "kubernetes-upgrade-",
+			Namespace:    kubernetesUpgradeNamespace,
+			Labels:       map[string]string{kubernetesUpgradeNodeLabel: nodeName},
+		},
+		Spec: batchv1.JobSpec{
+			BackoffLimit: &backoffLimit,
+			Template: corev1.PodTemplateSpec{
+				Spec: corev1.PodSpec{
+					NodeName:      nodeName,
+					HostPID:       true,
+					RestartPolicy: corev1.RestartPolicyNever,
+					Tolerations:   []corev1.Toleration{{Operator: corev1.TolerationOpExists}},
+					Containers: []corev1.Container{
+						{
+							Name:  "upgrade",
+							Image: image,
+							Command: []string{
+								"nsenter", "--target", "1", "--mount", "--uts", "--ipc", "--net", "--pid", "--",
+								"sh", "-c", kubernetesUpgradeScript(components, kubeadmArgs),
+							},
+							SecurityContext: &corev1.SecurityContext{Privileged: &privileged},
+						},
+					},
+				},
+			},
+		},
+	}
+}
+
+// kubernetesUpgradeScript returns a shell script upgrading the Kubernetes components of a node.
+// All components are downloaded and their checksums verified before the first one is installed.
+func kubernetesUpgradeScript(components []updatev1alpha1.KubernetesComponent, kubeadmArgs []string) string {
+	script := []string{
+		"set -eu",
+		"tmp=$(mktemp -d)",
+		`trap 'rm -rf "$tmp"' EXIT`,
+	}
+	for i, component := range components {
+		script = append(script,
+			fmt.Sprintf(`curl -fsSL --retry 5 -o "$tmp/%d" %s`, i, shellQuote(component.URL)),
+			fmt.Sprintf(`echo %s"$tmp/%d" | sha256sum --check --strict --quiet`, shellQuote(component.SHA256+"  "), i),
+		)
+	}
+	for i, component := range components {
+		if component.Extract {
+			script = append(script, fmt.Sprintf(`mkdir -p %[2]s && tar -xzf "$tmp/%[1]d" -C %[2]s`, i, shellQuote(component.InstallPath)))
+		} else {
+			script = append(script, fmt.Sprintf(`install -D -m 0544 "$tmp/%d" %s`, i, shellQuote(component.InstallPath)))
+		}
+	}
+	kubeadm := []string{kubeadmPath}
+	for _, arg := range kubeadmArgs {
+		kubeadm = append(kubeadm, shellQuote(arg))
+	}
+	script = append(script,
+		strings.Join(kubeadm, " "),
+		"systemctl daemon-reload",
+		"systemctl restart kubelet",
+	)
+	return strings.Join(script, "\n")
+}
+
+// shellQuote quotes a string for use as a single word in a shell script.
+func shellQuote(s string) string {
+	return "'" + strings.ReplaceAll(s, "'", `'\''`) + "'"
+}
+
+// kubernetesVersionStatus generates the NodeKubernetesVersion.Status field given node groups.
+// Conditions are taken over from the current status.
+func kubernetesVersionStatus(scheme *runtime.Scheme, conditions []metav1.Condition, groups kubernetesVersionGroups) updatev1alpha1.NodeKubernetesVersionStatus {
+	status := updatev1alpha1.NodeKubernetesVersionStatus{
+		Conditions: append([]metav1.Condition{}, conditions...),
+	}
+	for _, node := range append(append([]corev1.Node{}, groups.ControlPlaneOutdated...), groups.WorkerOutdated...) {
+		nodeRef, err := ref.GetReference(scheme, &node)
+		if err != nil {
+			continue
+		}
+		status.Outdated = append(status.Outdated, *nodeRef)
+	}
+	for _, node := range groups.UpToDate {
+		nodeRef, err := ref.GetReference(scheme, &node)
+		if err != nil {
+			continue
+		}
+		status.UpToDate = append(status.UpToDate, *nodeRef)
+	}
+	return status
+}
+
+// upgradeProgressCondition returns the UpgradeBlocked condition for an upgrade that is not blocked.
+func upgradeProgressCondition(outdated int) metav1.Condition {
+	if outdated == 0 {
+		return metav1.Condition{
+			Type:    updatev1alpha1.ConditionKubernetesUpgradeBlocked,
+			Status:  metav1.ConditionFalse,
+			Reason:  conditionKubernetesUpToDateReason,
+			Message: conditionKubernetesUpToDateMessage,
+		}
+	}
+	return metav1.Condition{
+		Type:    updatev1alpha1.ConditionKubernetesUpgradeBlocked,
+		Status:  metav1.ConditionFalse,
+		Reason:  conditionKubernetesUpgradingReason,
+		Message: fmt.Sprintf(conditionKubernetesUpgradingMessageTemplate, outdated),
+	}
+}
+
+// setUpgradeBlocked sets the UpgradeBlocked condition to true.
+func setUpgradeBlocked(status *updatev1alpha1.NodeKubernetesVersionStatus, reason, message string) {
+	meta.SetStatusCondition(&status.Conditions, metav1.Condition{
+		Type:    updatev1alpha1.ConditionKubernetesUpgradeBlocked,
+		Status:  metav1.ConditionTrue,
+		Reason:  reason,
+		Message: message,
+	})
+}
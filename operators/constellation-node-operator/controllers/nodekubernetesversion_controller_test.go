@@ -0,0 +1,389 @@
+/*
+Copyright (c) Edgeless Systems GmbH
+
+SPDX-License-Identifier: AGPL-3.0-only
+*/
+
+package controllers
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"testing"
+
+	"github.com/edgelesssys/constellation/operators/constellation-node-operator/v2/internal/constants"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	batchv1 "k8s.io/api/batch/v1"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/util/version"
+
+	updatev1alpha1 "github.com/edgelesssys/constellation/operators/constellation-node-operator/v2/api/v1alpha1"
+)
+
+func TestGroupNodesByKubernetesVersion(t *testing.T) {
+	assert := assert.New(t)
+
+	nodes := []corev1.Node{
+		kubernetesVersionNode("control-plane-outdated", "v1.23.6", true),
+		kubernetesVersionNode("control-plane-up-to-date", "v1.24.3", true),
+		kubernetesVersionNode("worker-outdated", "v1.23.6", false),
+		kubernetesVersionNode("worker-up-to-date", "v1.24.3", false),
+		kubernetesVersionNode("invalid", "", false),
+	}
+
+	groups := groupNodesByKubernetesVersion(nodes, version.MustParseSemantic("v1.24.3"))
+
+	assert.Equal([]corev1.Node{nodes[0]}, groups.ControlPlaneOutdated)
+	assert.Equal([]corev1.Node{nodes[1]}, groups.ControlPlaneUpToDate)
+	assert.Equal([]corev1.Node{nodes[2]}, groups.WorkerOutdated)
+	assert.Equal([]corev1.Node{nodes[1], nodes[3]}, groups.UpToDate)
+	assert.Equal([]corev1.Node{nodes[4]}, groups.Invalid)
+}
+
+func TestCheckVersionSkew(t *testing.T) {
+	testCases := map[string]struct {
+		nodes   []corev1.Node
+		target  string
+		wantErr bool
+	}{
+		"patch upgrade": {
+			nodes: []corev1.Node{
+				kubernetesVersionNode("control-plane", "v1.24.1", true),
+				kubernetesVersionNode("worker", "v1.24.1", false),
+			},
+			target: "v1.24.3",
+		},
+		"minor upgrade": {
+			nodes: []corev1.Node{
+				kubernetesVersionNode("control-plane", "v1.23.6", true),
+				kubernetesVersionNode("worker", "v1.23.6", false),
+			},
+			target: "v1.24.3",
+		},
+		"worker two minor versions behind": {
+			nodes: []corev1.Node{
+				kubernetesVersionNode("control-plane", "v1.24.3", true),
+				kubernetesVersionNode("worker", "v1.23.6", false),
+			},
+			target: "v1.25.0",
+		},
+		"worker three minor versions behind": {
+			nodes: []corev1.Node{
+				kubernetesVersionNode("control-plane", "v1.24.3", true),
+				kubernetesVersionNode("worker", "v1.22.2", false),
+			},
+			target:  "v1.25.0",
+			wantErr: true,
+		},
+		"control plane skips minor version": {
+			nodes: []corev1.Node{
+				kubernetesVersionNode("control-plane", "v1.23.6", true),
+			},
+			target:  "v1.25.0",
+			wantErr: true,
+		},
+		"downgrade": {
+			nodes: []corev1.Node{
+				kubernetesVersionNode("control-plane", "v1.24.3", true),
+			},
+			target:  "v1.24.1",
+			wantErr: true,
+		},
+		"major upgrade": {
+			nodes: []corev1.Node{
+				kubernetesVersionNode("control-plane", "v1.24.3", true),
+			},
+			target:  "v2.0.0",
+			wantErr: true,
+		},
+		"invalid kubelet versions are ignored": {
+			nodes: []corev1.Node{
+				kubernetesVersionNode("control-plane", "invalid", true),
+			},
+			target: "v1.24.3",
+		},
+	}
+
+	for name, tc := range testCases {
+		t.Run(name, func(t *testing.T) {
+			assert := assert.New(t)
+
+			err := checkVersionSkew(tc.nodes, version.MustParseSemantic(tc.target))
+			if tc.wantErr {
+				assert.Error(err)
+				return
+			}
+			assert.NoError(err)
+		})
+	}
+}
+
+func TestSelectNodesForUpgrade(t *testing.T) {
+	nodes := []corev1.Node{
+		kubernetesVersionNode("node-c", "v1.23.6", false),
+		kubernetesVersionNode("node-b", "v1.23.6", false),
+		kubernetesVersionNode("node-a", "v1.23.6", false),
+	}
+
+	testCases := map[string]struct {
+		jobs      map[string]*batchv1.Job
+		drains    map[string]*updatev1alpha1.NodeDrain
+		limit     int
+		wantNodes []string
+	}{
+		"nothing in progress": {
+			limit:     1,
+			wantNodes: []string{"node-a"},
+		},
+		"limit larger than one": {
+			limit:     2,
+			wantNodes: []string{"node-a", "node-b"},
+		},
+		"limit larger than number of nodes": {
+			limit:     5,
+			wantNodes: []string{"node-a", "node-b", "node-c"},
+		},
+		"node with job is preferred": {
+			jobs:      map[string]*batchv1.Job{"node-c": {}},
+			limit:     1,
+			wantNodes: []string{"node-c"},
+		},
+		"node with drain is preferred": {
+			drains:    map[string]*updatev1alpha1.NodeDrain{"node-b": {}},
+			limit:     2,
+			wantNodes: []string{"node-b", "node-a"},
+		},
+		"nodes in progress exceed limit": {
+			jobs:      map[string]*batchv1.Job{"node-c": {}},
+			drains:    map[string]*updatev1alpha1.NodeDrain{"node-b": {}},
+			limit:     1,
+			wantNodes: []string{"node-b", "node-c"},
+		},
+	}
+
+	for name, tc := range testCases {
+		t.Run(name, func(t *testing.T) {
+			assert := assert.New(t)
+
+			selected := selectNodesForUpgrade(nodes, tc.jobs, tc.drains, tc.limit)
+			var names []string
+			for _, node := range selected {
+				names = append(names, node.Name)
+			}
+			assert.Equal(tc.wantNodes, names)
+		})
+	}
+}
+
+func TestUpgradeDrainsByNode(t *testing.T) {
+	assert := assert.New(t)
+
+	drains := []updatev1alpha1.NodeDrain{
+		{Spec: updatev1alpha1.NodeDrainSpec{NodeName: "upgraded-node", Reason: kubernetesUpgradeReason + " v1.24.3"}},
+		{Spec: updatev1alpha1.NodeDrainSpec{NodeName: "replaced-node", Reason: nodeReplacementReason}},
+	}
+
+	byNode := upgradeDrainsByNode(drains)
+
+	assert.Len(byNode, 1)
+	assert.Equal(&drains[0], byNode["upgraded-node"])
+}
+
+func TestUpgradeNode(t *testing.T) {
+	someErr := errors.New("failed")
+	desiredVersion := &updatev1alpha1.NodeKubernetesVersion{
+		ObjectMeta: metav1.ObjectMeta{Name: "constellation-kubernetes", UID: "uid"},
+		Spec:       updatev1alpha1.NodeKubernetesVersionSpec{Version: "v1.24.3"},
+	}
+	drained := &updatev1alpha1.NodeDrain{
+		Status: updatev1alpha1.NodeDrainStatus{Phase: updatev1alpha1.NodeDrainPhaseSucceeded},
+	}
+	draining := &updatev1alpha1.NodeDrain{
+		Status: updatev1alpha1.NodeDrainStatus{Phase: updatev1alpha1.NodeDrainPhaseEvicting},
+	}
+	drainFailed := &updatev1alpha1.NodeDrain{
+		Status: updatev1alpha1.NodeDrainStatus{Phase: updatev1alpha1.NodeDrainPhaseFailed},
+	}
+	drainFailedRetry := drainFailed.DeepCopy()
+	drainFailedRetry.Spec.FailurePolicy = updatev1alpha1.DrainFailurePolicyRetry
+	drainFailedForce := drainFailed.DeepCopy()
+	drainFailedForce.Spec.FailurePolicy = updatev1alpha1.DrainFailurePolicyForce
+	failedJob := &batchv1.Job{
+		Status: batchv1.JobStatus{Conditions: []batchv1.JobCondition{
+			{Type: batchv1.JobFailed, Status: corev1.ConditionTrue},
+		}},
+	}
+
+	testCases := map[string]struct {
+		job       *batchv1.Job
+		nodeDrain *updatev1alpha1.NodeDrain
+		createErr error
+		wantErr   error
+	}{
+		"node drain is created": {},
+		"creating node drain fails": {
+			createErr: someErr,
+			wantErr:   someErr,
+		},
+		"drain in progress": {
+			nodeDrain: draining,
+		},
+		"job is created": {
+			nodeDrain: drained,
+		},
+		"failed drain blocks upgrade": {
+			nodeDrain: drainFailed,
+			wantErr:   errNodeDrainFailed,
+		},
+		"failed drain waiting for retry blocks upgrade": {
+			nodeDrain: drainFailedRetry,
+			wantErr:   errNodeDrainFailed,
+		},
+		"failed drain is forced": {
+			nodeDrain: drainFailedForce,
+			createErr: someErr,
+			wantErr:   someErr,
+		},
+		"creating job fails": {
+			nodeDrain: drained,
+			createErr: someErr,
+			wantErr:   someErr,
+		},
+		"job in progress": {
+			nodeDrain: drained,
+			job:       &batchv1.Job{},
+		},
+		"job failed": {
+			nodeDrain: drained,
+			job:       failedJob,
+			wantErr:   errUpgradeJobFailed,
+		},
+	}
+
+	for name, tc := range testCases {
+		t.Run(name, func(t *testing.T) {
+			assert := assert.New(t)
+
+			reconciler := NodeKubernetesVersionReconciler{
+				Client: &stubWriterClient{createErr: tc.createErr},
+				Scheme: getScheme(t),
+			}
+			node := kubernetesVersionNode("node", "v1.23.6", false)
+			err := reconciler.upgradeNode(context.Background(), desiredVersion, node, []string{"upgrade", "node"}, tc.job, tc.nodeDrain)
+			if tc.wantErr != nil {
+				assert.ErrorIs(err, tc.wantErr)
+				return
+			}
+			assert.NoError(err)
+		})
+	}
+}
+
+func TestNewKubernetesUpgradeJob(t *testing.T) {
+	assert := assert.New(t)
+	require := require.New(t)
+
+	components := []updatev1alpha1.KubernetesComponent{
+		{URL: "https://example.com/kubeadm", SHA256: strings.Repeat("a", 64), InstallPath: "/run/state/bin/kubeadm"},
+		{URL: "https://example.com/cni.tgz", SHA256: strings.Repeat("b", 64), InstallPath: "/opt/cni/bin", Extract: true},
+	}
+
+	job := newKubernetesUpgradeJob("example.com/image@sha256:"+strings.Repeat("c", 64), "node", components, []string{"upgrade", "apply", "v1.24.3", "--yes"})
+
+	assert.Equal(kubernetesUpgradeNamespace, job.Namespace)
+	assert.Equal("node", job.Labels[kubernetesUpgradeNodeLabel])
+	assert.Equal("node", job.Spec.Template.Spec.NodeName)
+	assert.True(job.Spec.Template.Spec.HostPID)
+	require.Len(job.Spec.Template.Spec.Containers, 1)
+	assert.Equal("example.com/image@sha256:"+strings.Repeat("c", 64), job.Spec.Template.Spec.Containers[0].Image)
+	command := job.Spec.Template.Spec.Containers[0].Command
+	require.NotEmpty(command)
+	script := command[len(command)-1]
+	assert.Contains(script, `curl -fsSL --retry 5 -o "$tmp/0" 'https://example.com/kubeadm'`)
+	verify := `echo '` + strings.Repeat("b", 64) + `  '"$tmp/1" | sha256sum --check --strict --quiet`
+	assert.Contains(script, verify)
+	// all checksums are verified before anything is installed
+	assert.Less(strings.Index(script, verify), strings.Index(script, "install -D"))
+	assert.Contains(script, `install -D -m 0544 "$tmp/0" '/run/state/bin/kubeadm'`)
+	assert.Contains(script, `tar -xzf "$tmp/1" -C '/opt/cni/bin'`)
+	assert.Contains(script, "/run/state/bin/kubeadm 'upgrade' 'apply' 'v1.24.3' '--yes'")
+	assert.Contains(script, "systemctl restart kubelet")
+}
+
+func TestSetJoinKubernetesVersion(t *testing.T) {
+	components := []updatev1alpha1.KubernetesComponent{
+		{URL: "https://example.com/kubeadm", SHA256: strings.Repeat("a", 64), InstallPath: "/run/state/bin/kubeadm"},
+	}
+	componentsJSON := `[{"url":"https://example.com/kubeadm","sha256":"` + strings.Repeat("a", 64) + `","installPath":"/run/state/bin/kubeadm"}]`
+
+	testCases := map[string]struct {
+		data        map[string]string
+		components  []updatev1alpha1.KubernetesComponent
+		wantChanged bool
+		wantData    map[string]string
+	}{
+		"minor version and components are set": {
+			data:        map[string]string{constants.K8sVersionConfigMapKey: "1.23"},
+			components:  components,
+			wantChanged: true,
+			wantData:    map[string]string{constants.K8sVersionConfigMapKey: "1.24", constants.K8sComponentsConfigMapKey: componentsJSON},
+		},
+		"patch upgrade changes components only": {
+			data:        map[string]string{constants.K8sVersionConfigMapKey: "1.24"},
+			components:  components,
+			wantChanged: true,
+			wantData:    map[string]string{constants.K8sVersionConfigMapKey: "1.24", constants.K8sComponentsConfigMapKey: componentsJSON},
+		},
+		"up to date": {
+			data:       map[string]string{constants.K8sVersionConfigMapKey: "1.24", constants.K8sComponentsConfigMapKey: componentsJSON},
+			components: components,
+			wantData:   map[string]string{constants.K8sVersionConfigMapKey: "1.24", constants.K8sComponentsConfigMapKey: componentsJSON},
+		},
+		"stale components are removed": {
+			data:        map[string]string{constants.K8sVersionConfigMapKey: "1.24", constants.K8sComponentsConfigMapKey: componentsJSON},
+			wantChanged: true,
+			wantData:    map[string]string{constants.K8sVersionConfigMapKey: "1.24"},
+		},
+		"empty ConfigMap": {
+			wantChanged: true,
+			wantData:    map[string]string{constants.K8sVersionConfigMapKey: "1.24"},
+		},
+	}
+
+	for name, tc := range testCases {
+		t.Run(name, func(t *testing.T) {
+			assert := assert.New(t)
+			require := require.New(t)
+
+			configMap := &corev1.ConfigMap{Data: tc.data}
+			changed, err := setJoinKubernetesVersion(configMap, version.MustParseSemantic("v1.24.5"), tc.components)
+			require.NoError(err)
+			assert.Equal(tc.wantChanged, changed)
+			assert.Equal(tc.wantData, configMap.Data)
+		})
+	}
+}
+
+func TestShellQuote(t *testing.T) {
+	assert := assert.New(t)
+
+	assert.Equal(`'plain'`, shellQuote("plain"))
+	assert.Equal(`'with space'`, shellQuote("with space"))
+	assert.Equal(`'it'\''s'`, shellQuote("it's"))
+}
+
+func kubernetesVersionNode(name, kubeletVersion string, controlPlane bool) corev1.Node {
+	node := corev1.Node{
+		ObjectMeta: metav1.ObjectMeta{Name: name},
+		Status: corev1.NodeStatus{
+			NodeInfo: corev1.NodeSystemInfo{KubeletVersion: kubeletVersion},
+		},
+	}
+	if controlPlane {
+		node.Labels = map[string]string{"node-role.kubernetes.io/control-plane": ""}
+	}
+	return node
+}
@@ -10,6 +10,7 @@ import (
 	"context"
 	"strings"
 
+	corev1 "k8s.io/api/core/v1"
 	"k8s.io/apimachinery/pkg/api/meta"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	"k8s.io/apimachinery/pkg/fields"
@@ -56,8 +57,10 @@ func NewScalingGroupReconciler(scalingGroupUpdater scalingGroupUpdater, client c
 //+kubebuilder:rbac:groups=update.edgeless.systems,resources=scalinggroups/finalizers,verbs=update
 //+kubebuilder:rbac:groups=update.edgeless.systems,resources=nodeimage,verbs=get;list;watch
 //+kubebuilder:rbac:groups=update.edgeless.systems,resources=nodeimages/status,verbs=get
+//+kubebuilder:rbac:groups="",resources=nodes,verbs=get;list;watch;patch
 
 // Reconcile reads the latest node image from the referenced NodeImage spec and updates the scaling group to match.
+// It also applies the node template of the scaling group to its nodes.
 func (r *ScalingGroupReconciler) Reconcile(ctx context.Context, req ctrl.Request) (ctrl.Result, error) {
 	logr := log.FromContext(ctx)
 
@@ -66,6 +69,12 @@ func (r *ScalingGroupReconciler) Reconcile(ctx context.Context, req ctrl.Request
 		logr.Error(err, "Unable to fetch ScalingGroup")
 		return ctrl.Result{}, client.IgnoreNotFound(err)
 	}
+	// a failure to apply the node template must not block updating the scaling group image.
+	// The error is returned once the image is reconciled, so the node template is retried with backoff.
+	nodeTemplateErr := r.applyNodeTemplate(ctx, &desiredScalingGroup)
+	if nodeTemplateErr != nil {
+		logr.Error(nodeTemplateErr, "Unable to apply ScalingGroup node template")
+	}
 	var desiredNodeImage updatev1alpha1.NodeImage
 	if err := r.Get(ctx, client.ObjectKey{Name: desiredScalingGroup.Spec.NodeImage}, &desiredNodeImage); err != nil {
 		logr.Error(err, "Unable to fetch NodeImage")
@@ -106,7 +115,7 @@ func (r *ScalingGroupReconciler) Reconcile(ctx context.Context, req ctrl.Request
 		// requeue to update status
 		return ctrl.Result{Requeue: true}, nil
 	}
-	return ctrl.Result{}, nil
+	return ctrl.Result{}, nodeTemplateErr
 }
 
 // SetupWithManager sets up the controller with the Manager.
@@ -128,6 +137,11 @@ func (r *ScalingGroupReconciler) SetupWithManager(mgr ctrl.Manager) error {
 			handler.EnqueueRequestsFromMapFunc(r.findObjectsForNodeImage),
 			builder.WithPredicates(predicate.ResourceVersionChangedPredicate{}),
 		).
+		Watches(
+			&source.Kind{Type: &corev1.Node{}},
+			handler.EnqueueRequestsFromMapFunc(r.findObjectsForNode),
+			builder.WithPredicates(nodeScalingGroupAssignedPredicate()),
+		).
 		Complete(r)
 }
 
@@ -0,0 +1,137 @@
+/*
+Copyright (c) Edgeless Systems GmbH
+
+SPDX-License-Identifier: AGPL-3.0-only
+*/
+
+package controllers
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/types"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/event"
+	"sigs.k8s.io/controller-runtime/pkg/predicate"
+	"sigs.k8s.io/controller-runtime/pkg/reconcile"
+
+	updatev1alpha1 "github.com/edgelesssys/constellation/operators/constellation-node-operator/v2/api/v1alpha1"
+)
+
+// applyNodeTemplate adds the labels and taints of the scaling group node template to every node of the scaling group.
+// Labels and taints that are not part of the template are left untouched.
+// A node that cannot be patched does not prevent the template from being applied to the remaining nodes.
+func (r *ScalingGroupReconciler) applyNodeTemplate(ctx context.Context, scalingGroup *updatev1alpha1.ScalingGroup) error {
+	template := scalingGroup.Spec.NodeTemplate
+	if len(template.Labels) == 0 && len(template.Taints) == 0 {
+		return nil
+	}
+	var nodeList corev1.NodeList
+	if err := r.List(ctx, &nodeList); err != nil {
+		return fmt.Errorf("listing nodes: %w", err)
+	}
+	var patchErr error
+	for _, node := range nodeList.Items {
+		if !strings.EqualFold(node.Annotations[scalingGroupAnnotation], scalingGroup.Spec.GroupID) {
+			continue
+		}
+		patchedNode, changed := nodeWithTemplate(&node, template)
+		if !changed {
+			continue
+		}
+		if err := r.Patch(ctx, patchedNode, client.MergeFrom(&node)); err != nil && patchErr == nil {
+			patchErr = fmt.Errorf("applying node template to node %q: %w", node.Name, err)
+		}
+	}
+	return patchErr
+}
+
+// nodeWithTemplate returns a copy of the node with the labels and taints of the template applied.
+// A taint of the template replaces an existing taint with the same key and effect.
+func nodeWithTemplate(node *corev1.Node, template updatev1alpha1.NodeTemplate) (*corev1.Node, bool) {
+	patchedNode := node.DeepCopy()
+	changed := false
+	for key, value := range template.Labels {
+		if existing, ok := patchedNode.Labels[key]; ok && existing == value {
+			continue
+		}
+		if patchedNode.Labels == nil {
+			patchedNode.Labels = make(map[string]string)
+		}
+		patchedNode.Labels[key] = value
+		changed = true
+	}
+	for _, taint := range template.Taints {
+		found := false
+		for i, existing := range patchedNode.Spec.Taints {
+			if existing.Key != taint.Key || existing.Effect != taint.Effect {
+				continue
+			}
+			found = true
+			if existing.Value != taint.Value {
+				patchedNode.Spec.Taints[i].Value = taint.Value
+				changed = true
+			}
+			break
+		}
+		if !found {
+			patchedNode.Spec.Taints = append(patchedNode.Spec.Taints, taint)
+			changed = true
+		}
+	}
+	return patchedNode, changed
+}
+
+// nodeScalingGroupAssignedPredicate checks if a node was assigned to a scaling group or lost a label or taint.
+func nodeScalingGroupAssignedPredicate() predicate.Predicate {
+	return predicate.Funcs{
+		UpdateFunc: func(e event.UpdateEvent) bool {
+			oldNode, ok := e.ObjectOld.(*corev1.Node)
+			if !ok {
+				return false
+			}
+			newNode, ok := e.ObjectNew.(*corev1.Node)
+			if !ok {
+				return false
+			}
+			if len(newNode.Annotations[scalingGroupAnnotation]) == 0 {
+				return false
+			}
+			assigned := oldNode.Annotations[scalingGroupAnnotation] != newNode.Annotations[scalingGroupAnnotation]
+			lostLabel := len(newNode.Labels) < len(oldNode.Labels)
+			lostTaint := len(newNode.Spec.Taints) < len(oldNode.Spec.Taints)
+			return assigned || lostLabel || lostTaint
+		},
+		DeleteFunc: func(e event.DeleteEvent) bool {
+			return false
+		},
+		GenericFunc: func(e event.GenericEvent) bool {
+			return false
+		},
+	}
+}
+
+// findObjectsForNode requests a reconcile call for the scaling group of a node.
+func (r *ScalingGroupReconciler) findObjectsForNode(rawNode client.Object) []reconcile.Request {
+	scalingGroupID := rawNode.GetAnnotations()[scalingGroupAnnotation]
+	if len(scalingGroupID) == 0 {
+		return []reconcile.Request{}
+	}
+	var scalingGroupList updatev1alpha1.ScalingGroupList
+	if err := r.List(context.TODO(), &scalingGroupList); err != nil {
+		return []reconcile.Request{}
+	}
+	requests := []reconcile.Request{}
+	for _, scalingGroup := range scalingGroupList.Items {
+		if !strings.EqualFold(scalingGroup.Spec.GroupID, scalingGroupID) {
+			continue
+		}
+		requests = append(requests, reconcile.Request{
+			NamespacedName: types.NamespacedName{Name: scalingGroup.GetName()},
+		})
+	}
+	return requests
+}
@@ -0,0 +1,355 @@
+/*
+Copyright (c) Edgeless Systems GmbH
+
+SPDX-License-Identifier: AGPL-3.0-only
+*/
+
+package controllers
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/types"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/event"
+	"sigs.k8s.io/controller-runtime/pkg/reconcile"
+
+	updatev1alpha1 "github.com/edgelesssys/constellation/operators/constellation-node-operator/v2/api/v1alpha1"
+)
+
+func TestNodeWithTemplate(t *testing.T) {
+	spotTaint := corev1.Taint{Key: "pool", Value: "spot", Effect: corev1.TaintEffectNoSchedule}
+
+	testCases := map[string]struct {
+		node        corev1.Node
+		template    updatev1alpha1.NodeTemplate
+		wantNode    corev1.Node
+		wantChanged bool
+	}{
+		"empty template": {
+			node:     corev1.Node{ObjectMeta: metav1.ObjectMeta{Labels: map[string]string{"a": "b"}}},
+			wantNode: corev1.Node{ObjectMeta: metav1.ObjectMeta{Labels: map[string]string{"a": "b"}}},
+		},
+		"labels and taints are added": {
+			template: updatev1alpha1.NodeTemplate{
+				Labels: map[string]string{"pool": "spot"},
+				Taints: []corev1.Taint{spotTaint},
+			},
+			wantNode: corev1.Node{
+				ObjectMeta: metav1.ObjectMeta{Labels: map[string]string{"pool": "spot"}},
+				Spec:       corev1.NodeSpec{Taints: []corev1.Taint{spotTaint}},
+			},
+			wantChanged: true,
+		},
+		"other labels and taints are kept": {
+			node: corev1.Node{
+				ObjectMeta: metav1.ObjectMeta{Labels: map[string]string{"a": "b", "pool": "default"}},
+				Spec:       corev1.NodeSpec{Taints: []corev1.Taint{{Key: "other", Effect: corev1.TaintEffectNoExecute}}},
+			},
+			template: updatev1alpha1.NodeTemplate{
+				Labels: map[string]string{"pool": "spot"},
+				Taints: []corev1.Taint{spotTaint},
+			},
+			wantNode: corev1.Node{
+				ObjectMeta: metav1.ObjectMeta{Labels: map[string]string{"a": "b", "pool": "spot"}},
+				Spec:       corev1.NodeSpec{Taints: []corev1.Taint{{Key: "other", Effect: corev1.TaintEffectNoExecute}, spotTaint}},
+			},
+			wantChanged: true,
+		},
+		"taint value is replaced": {
+			node: corev1.Node{
+				Spec: corev1.NodeSpec{Taints: []corev1.Taint{{Key: "pool", Value: "default", Effect: corev1.TaintEffectNoSchedule}}},
+			},
+			template: updatev1alpha1.NodeTemplate{Taints: []corev1.Taint{spotTaint}},
+			wantNode: corev1.Node{
+				Spec: corev1.NodeSpec{Taints: []corev1.Taint{spotTaint}},
+			},
+			wantChanged: true,
+		},
+		"template already applied": {
+			node: corev1.Node{
+				ObjectMeta: metav1.ObjectMeta{Labels: map[string]string{"pool": "spot"}},
+				Spec:       corev1.NodeSpec{Taints: []corev1.Taint{spotTaint}},
+			},
+			template: updatev1alpha1.NodeTemplate{
+				Labels: map[string]string{"pool": "spot"},
+				Taints: []corev1.Taint{spotTaint},
+			},
+			wantNode: corev1.Node{
+				ObjectMeta: metav1.ObjectMeta{Labels: map[string]string{"pool": "spot"}},
+				Spec:       corev1.NodeSpec{Taints: []corev1.Taint{spotTaint}},
+			},
+		},
+	}
+
+	for name, tc := range testCases {
+		t.Run(name, func(t *testing.T) {
+			assert := assert.New(t)
+
+			original := tc.node.DeepCopy()
+			patchedNode, changed := nodeWithTemplate(&tc.node, tc.template)
+			assert.Equal(tc.wantChanged, changed)
+			assert.Equal(tc.wantNode, *patchedNode)
+			assert.Equal(*original, tc.node)
+		})
+	}
+}
+
+func TestApplyNodeTemplate(t *testing.T) {
+	template := updatev1alpha1.NodeTemplate{Labels: map[string]string{"pool": "spot"}}
+	groupNode := &corev1.Node{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:        "group-node",
+			Annotations: map[string]string{scalingGroupAnnotation: "Group-ID"},
+		},
+	}
+	secondGroupNode := &corev1.Node{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:        "second-group-node",
+			Annotations: map[string]string{scalingGroupAnnotation: "group-id"},
+		},
+	}
+	otherNode := &corev1.Node{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:        "other-node",
+			Annotations: map[string]string{scalingGroupAnnotation: "other-group-id"},
+		},
+	}
+
+	testCases := map[string]struct {
+		template    updatev1alpha1.NodeTemplate
+		listErr     error
+		patchErr    error
+		wantPatched []string
+		wantErr     bool
+	}{
+		"nodes of the scaling group are patched": {
+			template:    template,
+			wantPatched: []string{"group-node", "second-group-node"},
+		},
+		"empty template": {},
+		"listing nodes fails": {
+			template: template,
+			listErr:  errors.New("list failed"),
+			wantErr:  true,
+		},
+		"patching node fails": {
+			template:    template,
+			patchErr:    errors.New("patch failed"),
+			wantPatched: []string{"group-node", "second-group-node"},
+			wantErr:     true,
+		},
+	}
+
+	for name, tc := range testCases {
+		t.Run(name, func(t *testing.T) {
+			assert := assert.New(t)
+			require := require.New(t)
+
+			k8sClient := &patchRecordingClient{
+				stubReadWriterClient: stubReadWriterClient{
+					stubReaderClient: *newStubReaderClient(t, []runtime.Object{groupNode, secondGroupNode, otherNode}, nil, tc.listErr),
+					stubWriterClient: stubWriterClient{patchErr: tc.patchErr},
+				},
+			}
+			reconciler := ScalingGroupReconciler{Client: k8sClient}
+			scalingGroup := &updatev1alpha1.ScalingGroup{
+				Spec: updatev1alpha1.ScalingGroupSpec{GroupID: "group-id", NodeTemplate: tc.template},
+			}
+			err := reconciler.applyNodeTemplate(context.Background(), scalingGroup)
+			assert.ElementsMatch(tc.wantPatched, k8sClient.patched)
+			if tc.wantErr {
+				assert.Error(err)
+				return
+			}
+			require.NoError(err)
+		})
+	}
+}
+
+func TestReconcileNodeTemplateFailure(t *testing.T) {
+	scalingGroup := &updatev1alpha1.ScalingGroup{
+		ObjectMeta: metav1.ObjectMeta{Name: "scaling-group"},
+		Spec: updatev1alpha1.ScalingGroupSpec{
+			NodeImage:    "node-image",
+			GroupID:      "group-id",
+			NodeTemplate: updatev1alpha1.NodeTemplate{Labels: map[string]string{"pool": "spot"}},
+		},
+	}
+	nodeImage := &updatev1alpha1.NodeImage{
+		ObjectMeta: metav1.ObjectMeta{Name: "node-image"},
+		Spec:       updatev1alpha1.NodeImageSpec{ImageReference: "image-2"},
+	}
+	groupNode := &corev1.Node{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:        "group-node",
+			Annotations: map[string]string{scalingGroupAnnotation: "group-id"},
+		},
+	}
+
+	testCases := map[string]struct {
+		scalingGroupImage string
+		wantResult        reconcile.Result
+	}{
+		"outdated image is updated": {
+			scalingGroupImage: "image-1",
+			wantResult:        reconcile.Result{Requeue: true},
+		},
+		"node template is retried": {
+			scalingGroupImage: "image-2",
+		},
+	}
+
+	for name, tc := range testCases {
+		t.Run(name, func(t *testing.T) {
+			assert := assert.New(t)
+
+			updater := newFakeScalingGroupUpdater()
+			updater.scalingGroupImage["group-id"] = tc.scalingGroupImage
+			reconciler := ScalingGroupReconciler{
+				scalingGroupUpdater: updater,
+				Client: &stubReadWriterClient{
+					stubReaderClient: *newStubReaderClient(t, []runtime.Object{scalingGroup, nodeImage, groupNode}, nil, nil),
+					stubWriterClient: stubWriterClient{patchErr: errors.New("patch failed")},
+				},
+			}
+
+			result, err := reconciler.Reconcile(context.Background(), reconcile.Request{NamespacedName: types.NamespacedName{Name: "scaling-group"}})
+			assert.Equal(tc.wantResult, result)
+			assert.Equal("image-2", updater.scalingGroupImage["group-id"])
+			if tc.wantResult.Requeue {
+				assert.NoError(err)
+				return
+			}
+			assert.Error(err)
+		})
+	}
+}
+
+type patchRecordingClient struct {
+	stubReadWriterClient
+	patched []string
+}
+
+func (c *patchRecordingClient) Patch(ctx context.Context, obj client.Object, patch client.Patch, opts ...client.PatchOption) error {
+	c.patched = append(c.patched, obj.GetName())
+	return c.stubReadWriterClient.Patch(ctx, obj, patch, opts...)
+}
+
+func TestNodeScalingGroupAssignedPredicate(t *testing.T) {
+	testCases := map[string]struct {
+		event          event.UpdateEvent
+		wantProcessing bool
+	}{
+		"old object is not a node": {
+			event: event.UpdateEvent{
+				ObjectNew: &corev1.Node{},
+			},
+		},
+		"new object is not a node": {
+			event: event.UpdateEvent{
+				ObjectOld: &corev1.Node{},
+			},
+		},
+		"node has no scaling group": {
+			event: event.UpdateEvent{
+				ObjectOld: &corev1.Node{ObjectMeta: metav1.ObjectMeta{Labels: map[string]string{"a": "b"}}},
+				ObjectNew: &corev1.Node{},
+			},
+		},
+		"node is unchanged": {
+			event: event.UpdateEvent{
+				ObjectOld: &corev1.Node{ObjectMeta: metav1.ObjectMeta{Annotations: map[string]string{scalingGroupAnnotation: "group-id"}}},
+				ObjectNew: &corev1.Node{ObjectMeta: metav1.ObjectMeta{Annotations: map[string]string{scalingGroupAnnotation: "group-id"}}},
+			},
+		},
+		"node was assigned to scaling group": {
+			event: event.UpdateEvent{
+				ObjectOld: &corev1.Node{},
+				ObjectNew: &corev1.Node{ObjectMeta: metav1.ObjectMeta{Annotations: map[string]string{scalingGroupAnnotation: "group-id"}}},
+			},
+			wantProcessing: true,
+		},
+		"node lost a label": {
+			event: event.UpdateEvent{
+				ObjectOld: &corev1.Node{ObjectMeta: metav1.ObjectMeta{
+					Annotations: map[string]string{scalingGroupAnnotation: "group-id"},
+					Labels:      map[string]string{"pool": "spot"},
+				}},
+				ObjectNew: &corev1.Node{ObjectMeta: metav1.ObjectMeta{Annotations: map[string]string{scalingGroupAnnotation: "group-id"}}},
+			},
+			wantProcessing: true,
+		},
+		"node lost a taint": {
+			event: event.UpdateEvent{
+				ObjectOld: &corev1.Node{
+					ObjectMeta: metav1.ObjectMeta{Annotations: map[string]string{scalingGroupAnnotation: "group-id"}},
+					Spec:       corev1.NodeSpec{Taints: []corev1.Taint{{Key: "pool", Effect: corev1.TaintEffectNoSchedule}}},
+				},
+				ObjectNew: &corev1.Node{ObjectMeta: metav1.ObjectMeta{Annotations: map[string]string{scalingGroupAnnotation: "group-id"}}},
+			},
+			wantProcessing: true,
+		},
+	}
+
+	for name, tc := range testCases {
+		t.Run(name, func(t *testing.T) {
+			assert := assert.New(t)
+			predicate := nodeScalingGroupAssignedPredicate()
+			assert.Equal(tc.wantProcessing, predicate.Update(tc.event))
+		})
+	}
+}
+
+func TestFindScalingGroupsForNode(t *testing.T) {
+	testCases := map[string]struct {
+		node         *corev1.Node
+		listErr      error
+		wantRequests []reconcile.Request
+	}{
+		"node without scaling group": {
+			node:         &corev1.Node{},
+			wantRequests: []reconcile.Request{},
+		},
+		"listing scaling groups fails": {
+			node:         &corev1.Node{ObjectMeta: metav1.ObjectMeta{Annotations: map[string]string{scalingGroupAnnotation: "group-id"}}},
+			listErr:      errors.New("list failed"),
+			wantRequests: []reconcile.Request{},
+		},
+		"scaling group of node is returned": {
+			node: &corev1.Node{ObjectMeta: metav1.ObjectMeta{Annotations: map[string]string{scalingGroupAnnotation: "Group-ID"}}},
+			wantRequests: []reconcile.Request{
+				{NamespacedName: types.NamespacedName{Name: "scaling-group"}},
+			},
+		},
+	}
+
+	for name, tc := range testCases {
+		t.Run(name, func(t *testing.T) {
+			assert := assert.New(t)
+
+			scalingGroups := []runtime.Object{
+				&updatev1alpha1.ScalingGroup{
+					ObjectMeta: metav1.ObjectMeta{Name: "scaling-group"},
+					Spec:       updatev1alpha1.ScalingGroupSpec{GroupID: "group-id"},
+				},
+				&updatev1alpha1.ScalingGroup{
+					ObjectMeta: metav1.ObjectMeta{Name: "other-scaling-group"},
+					Spec:       updatev1alpha1.ScalingGroupSpec{GroupID: "other-group-id"},
+				},
+			}
+			reconciler := ScalingGroupReconciler{
+				Client: newStubReaderClient(t, scalingGroups, nil, tc.listErr),
+			}
+			requests := reconciler.findObjectsForNode(tc.node)
+			assert.ElementsMatch(tc.wantRequests, requests)
+		})
+	}
+}
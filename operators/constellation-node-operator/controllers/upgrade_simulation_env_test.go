@@ -0,0 +1,354 @@
+//go:build integration
+
+/*
+Copyright (c) Edgeless Systems GmbH
+
+SPDX-License-Identifier: AGPL-3.0-only
+*/
+
+package controllers
+
+import (
+	"context"
+	"fmt"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	corev1 "k8s.io/api/core/v1"
+	k8sErrors "k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/api/meta"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/types"
+	clientgoscheme "k8s.io/client-go/kubernetes/scheme"
+	testclock "k8s.io/utils/clock/testing"
+	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/envtest"
+
+	updatev1alpha1 "github.com/edgelesssys/constellation/operators/constellation-node-operator/v2/api/v1alpha1"
+)
+
+const (
+	simulationNodeImage    = "nodeimage"
+	simulationControlPlane = "control-plane"
+	simulationWorker       = "worker"
+	simulationOldImage     = "image-1"
+	simulationNewImage     = "image-2"
+	simulationTimeout      = 60 * time.Second
+	simulationInterval     = 100 * time.Millisecond
+	simulationCloudEvent   = "simulation.edgeless.systems/cloud-event"
+)
+
+// TestUpgradeSimulation drives the NodeImage, PendingNode and ScalingGroup controllers through complete
+// multi-node upgrades on top of a fake cloud.
+// Every scenario runs against its own API server, since the controllers of the Ginkgo suite use stubbed clouds.
+func TestUpgradeSimulation(t *testing.T) {
+	two := int32(2)
+	testCases := map[string]struct {
+		controlPlanes  int
+		workers        int
+		strategy       updatev1alpha1.RolloutStrategy
+		bootLatency    time.Duration
+		deleteLatency  time.Duration
+		createFailures int
+		joinFailures   int
+		stuckDeletion  bool
+	}{
+		"default strategy": {
+			controlPlanes: 3,
+			workers:       2,
+		},
+		"higher surge": {
+			controlPlanes: 1,
+			workers:       4,
+			strategy:      updatev1alpha1.RolloutStrategy{MaxSurge: &two, MaxUnavailable: &two},
+		},
+		"slow cloud": {
+			controlPlanes: 1,
+			workers:       2,
+			bootLatency:   2 * time.Second,
+			deleteLatency: 2 * time.Second,
+		},
+		"workers first": {
+			controlPlanes: 2,
+			workers:       2,
+			strategy:      updatev1alpha1.RolloutStrategy{Order: updatev1alpha1.RolloutOrderWorkersFirst},
+		},
+		"creation failures": {
+			controlPlanes:  1,
+			workers:        2,
+			createFailures: 3,
+		},
+		"node never joins": {
+			controlPlanes: 1,
+			workers:       2,
+			joinFailures:  1,
+		},
+		"stuck deletion": {
+			controlPlanes: 1,
+			workers:       2,
+			stuckDeletion: true,
+		},
+	}
+
+	for name, tc := range testCases {
+		t.Run(name, func(t *testing.T) {
+			assert := assert.New(t)
+			require := require.New(t)
+
+			sim := newUpgradeSimulation(t)
+			sim.cloud.setLatency(tc.bootLatency, tc.deleteLatency)
+			initialNodes := sim.createCluster(tc.controlPlanes, tc.workers, tc.strategy)
+			var controlPlaneIPs []string
+			for _, node := range initialNodes {
+				if _, ok := node.Labels[controlPlaneRoleLabel]; ok {
+					controlPlaneIPs = append(controlPlaneIPs, node.Status.Addresses[0].Address)
+				}
+			}
+
+			sim.cloud.failCreations(tc.createFailures)
+			sim.cloud.failJoins(tc.joinFailures)
+			sim.cloud.setStuckDeletion(tc.stuckDeletion)
+			sim.setImage(simulationNewImage)
+
+			if tc.joinFailures > 0 {
+				require.Eventually(func() bool {
+					return len(sim.pendingNodes(func(pendingNode updatev1alpha1.PendingNode) bool {
+						return pendingNode.Spec.Goal == updatev1alpha1.NodeGoalJoin &&
+							pendingNode.Status.CSPNodeState == updatev1alpha1.NodeStateReady &&
+							!pendingNode.Status.ReachedGoal
+					})) > 0
+				}, simulationTimeout, simulationInterval, "no node failed to join")
+				sim.expireJoinDeadlines()
+			}
+			if tc.stuckDeletion {
+				require.Eventually(func() bool {
+					return len(sim.pendingNodes(func(pendingNode updatev1alpha1.PendingNode) bool {
+						return pendingNode.Spec.Goal == updatev1alpha1.NodeGoalLeave
+					})) > 0
+				}, simulationTimeout, simulationInterval, "no node is leaving")
+				assert.Never(sim.upgraded, 5*time.Second, simulationInterval, "upgrade finished while a node is stuck in deletion")
+				sim.cloud.setStuckDeletion(false)
+			}
+
+			require.Eventually(sim.upgraded, simulationTimeout, simulationInterval, "upgrade did not finish")
+
+			// invariants
+			assert.Equal(len(initialNodes), sim.cloud.instanceCount())
+			for providerID, image := range sim.cloud.instanceImages() {
+				assert.Equal(simulationNewImage, image, "instance %s uses an outdated image", providerID)
+			}
+			assert.LessOrEqual(sim.cloud.peakInstanceCount(), len(initialNodes)+maxSurge(tc.strategy), "surge limit exceeded")
+			assert.ElementsMatch(controlPlaneIPs, sim.cloud.etcdMembersRemoved())
+			var nodeList corev1.NodeList
+			require.NoError(sim.client.List(sim.ctx, &nodeList))
+			assert.Len(nodeList.Items, len(initialNodes))
+			for _, node := range nodeList.Items {
+				assert.Equal(simulationNewImage, node.Annotations[nodeImageAnnotation])
+				assert.Empty(node.Annotations[donorAnnotation])
+				assert.Empty(node.Annotations[heirAnnotation])
+			}
+			assert.Empty(sim.cloud.errors())
+		})
+	}
+}
+
+// upgradeSimulation is an API server running the node operator controllers on top of a fake cloud.
+// Nodes are drained immediately, since there are no pods to evict.
+type upgradeSimulation struct {
+	t      *testing.T
+	ctx    context.Context
+	client client.Client
+	cloud  *fakeCloud
+	clock  *testclock.FakeClock
+}
+
+func newUpgradeSimulation(t *testing.T) *upgradeSimulation {
+	require := require.New(t)
+
+	testEnv := &envtest.Environment{
+		CRDDirectoryPaths: []string{
+			filepath.Join("..", "config", "crd", "bases"),
+		},
+		ErrorIfCRDPathMissing: true,
+	}
+	cfg, err := testEnv.Start()
+	require.NoError(err)
+	t.Cleanup(func() { assert.NoError(t, testEnv.Stop()) })
+
+	scheme := runtime.NewScheme()
+	require.NoError(clientgoscheme.AddToScheme(scheme))
+	require.NoError(updatev1alpha1.AddToScheme(scheme))
+	k8sClient, err := client.New(cfg, client.Options{Scheme: scheme})
+	require.NoError(err)
+	k8sManager, err := ctrl.NewManager(cfg, ctrl.Options{
+		Scheme:             scheme,
+		MetricsBindAddress: "0",
+	})
+	require.NoError(err)
+
+	sim := &upgradeSimulation{
+		t:      t,
+		client: k8sClient,
+		cloud:  newFakeCloud(k8sClient),
+		clock:  testclock.NewFakeClock(time.Now()),
+	}
+	require.NoError(NewScalingGroupReconciler(sim.cloud, k8sManager.GetClient(), k8sManager.GetScheme()).SetupWithManager(k8sManager))
+	pendingNodeReconciler := NewPendingNodeReconciler(sim.cloud, k8sManager.GetClient(), k8sManager.GetScheme())
+	pendingNodeReconciler.Clock = sim.clock
+	require.NoError(pendingNodeReconciler.SetupWithManager(k8sManager))
+	require.NoError(NewNodeImageReconciler(sim.cloud, sim.cloud, k8sManager.GetClient(), k8sManager.GetScheme(), k8sManager.GetEventRecorderFor("nodeimage-controller")).SetupWithManager(k8sManager))
+
+	ctx, cancel := context.WithCancel(context.Background())
+	sim.ctx = ctx
+	managerDone := make(chan struct{})
+	go func() {
+		defer close(managerDone)
+		assert.NoError(t, k8sManager.Start(ctx))
+	}()
+	go sim.drainNodes()
+	t.Cleanup(func() {
+		cancel()
+		<-managerDone
+	})
+
+	// the PendingNode controller does not poll the cloud, so every state change of an instance
+	// triggers a reconciliation of its PendingNode.
+	sim.cloud.setOnStateChange(sim.touchPendingNode)
+	return sim
+}
+
+// createCluster creates a control plane and a worker scaling group using the old image
+// and returns the initial nodes.
+func (s *upgradeSimulation) createCluster(controlPlanes, workers int, strategy updatev1alpha1.RolloutStrategy) []corev1.Node {
+	require := require.New(s.t)
+
+	nodeImage := &updatev1alpha1.NodeImage{
+		ObjectMeta: metav1.ObjectMeta{Name: simulationNodeImage},
+		Spec: updatev1alpha1.NodeImageSpec{
+			ImageReference: simulationOldImage,
+			Strategy:       strategy,
+		},
+	}
+	require.NoError(s.client.Create(s.ctx, nodeImage))
+
+	var nodes []corev1.Node
+	for _, group := range []struct {
+		id    string
+		role  updatev1alpha1.NodeRole
+		count int
+	}{
+		{id: simulationControlPlane, role: updatev1alpha1.ControlPlaneRole, count: controlPlanes},
+		{id: simulationWorker, role: updatev1alpha1.WorkerRole, count: workers},
+	} {
+		s.cloud.addScalingGroup(group.id, simulationOldImage, group.role == updatev1alpha1.ControlPlaneRole)
+		scalingGroup := &updatev1alpha1.ScalingGroup{
+			ObjectMeta: metav1.ObjectMeta{Name: group.id},
+			Spec: updatev1alpha1.ScalingGroupSpec{
+				NodeImage: simulationNodeImage,
+				GroupID:   group.id,
+				Role:      group.role,
+			},
+		}
+		require.NoError(s.client.Create(s.ctx, scalingGroup))
+		for i := 0; i < group.count; i++ {
+			node, err := s.cloud.addInstance(s.ctx, group.id)
+			require.NoError(err)
+			nodes = append(nodes, *node)
+		}
+	}
+
+	require.Eventually(func() bool {
+		var nodeImage updatev1alpha1.NodeImage
+		if err := s.client.Get(s.ctx, types.NamespacedName{Name: simulationNodeImage}, &nodeImage); err != nil {
+			return false
+		}
+		return len(nodeImage.Status.UpToDate) == len(nodes)
+	}, simulationTimeout, simulationInterval, "cluster did not settle")
+	return nodes
+}
+
+// setImage updates the image of the NodeImage resource.
+func (s *upgradeSimulation) setImage(image string) {
+	var nodeImage updatev1alpha1.NodeImage
+	require.NoError(s.t, s.client.Get(s.ctx, types.NamespacedName{Name: simulationNodeImage}, &nodeImage))
+	nodeImage.Spec.ImageReference = image
+	require.NoError(s.t, s.client.Update(s.ctx, &nodeImage))
+}
+
+// upgraded checks if the NodeImage reports that all nodes use the desired image.
+func (s *upgradeSimulation) upgraded() bool {
+	var nodeImage updatev1alpha1.NodeImage
+	if err := s.client.Get(s.ctx, types.NamespacedName{Name: simulationNodeImage}, &nodeImage); err != nil {
+		return false
+	}
+	return nodeImage.Status.Rollout.ImageReference == nodeImage.Spec.ImageReference &&
+		meta.IsStatusConditionFalse(nodeImage.Status.Conditions, updatev1alpha1.ConditionOutdated)
+}
+
+// pendingNodes returns all PendingNode resources matching a filter.
+func (s *upgradeSimulation) pendingNodes(filter func(updatev1alpha1.PendingNode) bool) []updatev1alpha1.PendingNode {
+	var pendingNodeList updatev1alpha1.PendingNodeList
+	if err := s.client.List(s.ctx, &pendingNodeList); err != nil {
+		return nil
+	}
+	var pendingNodes []updatev1alpha1.PendingNode
+	for _, pendingNode := range pendingNodeList.Items {
+		if filter(pendingNode) {
+			pendingNodes = append(pendingNodes, pendingNode)
+		}
+	}
+	return pendingNodes
+}
+
+// expireJoinDeadlines advances the clock of the PendingNode controller past the join deadline of all pending nodes.
+func (s *upgradeSimulation) expireJoinDeadlines() {
+	s.clock.Step(nodeJoinTimeout + time.Minute)
+	for _, pendingNode := range s.pendingNodes(func(updatev1alpha1.PendingNode) bool { return true }) {
+		s.touchPendingNode(pendingNode.Name)
+	}
+}
+
+// touchPendingNode updates an annotation of a PendingNode to trigger its reconciliation.
+func (s *upgradeSimulation) touchPendingNode(name string) {
+	var pendingNode updatev1alpha1.PendingNode
+	if err := s.client.Get(s.ctx, types.NamespacedName{Name: name}, &pendingNode); err != nil {
+		return
+	}
+	patch := client.MergeFrom(pendingNode.DeepCopy())
+	if pendingNode.Annotations == nil {
+		pendingNode.Annotations = make(map[string]string)
+	}
+	pendingNode.Annotations[simulationCloudEvent] = fmt.Sprint(time.Now().UnixNano())
+	if err := s.client.Patch(s.ctx, &pendingNode, patch); err != nil && !k8sErrors.IsNotFound(err) {
+		s.t.Logf("touching pending node %s: %v", name, err)
+	}
+}
+
+// drainNodes acts as the NodeDrain controller and marks every NodeDrain as succeeded.
+func (s *upgradeSimulation) drainNodes() {
+	ticker := time.NewTicker(simulationInterval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-s.ctx.Done():
+			return
+		case <-ticker.C:
+		}
+		var nodeDrainList updatev1alpha1.NodeDrainList
+		if err := s.client.List(s.ctx, &nodeDrainList); err != nil {
+			continue
+		}
+		for _, nodeDrain := range nodeDrainList.Items {
+			if nodeDrain.Status.Phase == updatev1alpha1.NodeDrainPhaseSucceeded {
+				continue
+			}
+			nodeDrain.Status.Phase = updatev1alpha1.NodeDrainPhaseSucceeded
+			_ = s.client.Status().Update(s.ctx, &nodeDrain)
+		}
+	}
+}
@@ -13,6 +13,8 @@ import (
 
 	"github.com/Azure/azure-sdk-for-go/sdk/azcore/to"
 	"github.com/Azure/azure-sdk-for-go/sdk/resourcemanager/compute/armcompute/v2"
+	updatev1alpha1 "github.com/edgelesssys/constellation/operators/constellation-node-operator/v2/api/v1alpha1"
+	"github.com/edgelesssys/constellation/operators/constellation-node-operator/v2/internal/workergroup"
 )
 
 // GetScalingGroupImage returns the image URI of the scaling group.
@@ -63,6 +65,25 @@ func (c *Client) SetScalingGroupImage(ctx context.Context, scalingGroupID, image
 	return nil
 }
 
+// GetScalingGroupSize returns the minimum and maximum number of nodes of a worker group as configured at creation.
+// Zero values are returned if the scaling group was not created from a worker group.
+func (c *Client) GetScalingGroupSize(ctx context.Context, scalingGroupID string) (min, max int32, err error) {
+	tags, err := c.getScaleSetTags(ctx, scalingGroupID)
+	if err != nil {
+		return 0, 0, err
+	}
+	return workergroup.Size(tags)
+}
+
+// GetScalingGroupNodeTemplate returns the labels and taints of the nodes of a scaling group.
+func (c *Client) GetScalingGroupNodeTemplate(ctx context.Context, scalingGroupID string) (updatev1alpha1.NodeTemplate, error) {
+	tags, err := c.getScaleSetTags(ctx, scalingGroupID)
+	if err != nil {
+		return updatev1alpha1.NodeTemplate{}, err
+	}
+	return workergroup.NodeTemplate(tags)
+}
+
 // GetScalingGroupName retrieves the name of a scaling group, as expected by Kubernetes.
 // This keeps the casing of the original name, but Kubernetes requires the name to be lowercase,
 // so use strings.ToLower() on the result if using the name in a Kubernetes context.
@@ -99,6 +120,24 @@ func (c *Client) ListScalingGroups(ctx context.Context, uid string) (controlPlan
 	return controlPlaneGroupIDs, workerGroupIDs, nil
 }
 
+func (c *Client) getScaleSetTags(ctx context.Context, scalingGroupID string) (map[string]string, error) {
+	_, resourceGroup, scaleSet, err := splitVMSSID(scalingGroupID)
+	if err != nil {
+		return nil, err
+	}
+	res, err := c.scaleSetsAPI.Get(ctx, resourceGroup, scaleSet, nil)
+	if err != nil {
+		return nil, err
+	}
+	tags := make(map[string]string, len(res.Tags))
+	for key, value := range res.Tags {
+		if value != nil {
+			tags[key] = *value
+		}
+	}
+	return tags, nil
+}
+
 func imageReferenceFromImage(img string) *armcompute.ImageReference {
 	ref := &armcompute.ImageReference{}
 
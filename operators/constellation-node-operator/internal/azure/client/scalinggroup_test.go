@@ -13,8 +13,10 @@ import (
 
 	"github.com/Azure/azure-sdk-for-go/sdk/azcore/to"
 	armcomputev2 "github.com/Azure/azure-sdk-for-go/sdk/resourcemanager/compute/armcompute/v2"
+	updatev1alpha1 "github.com/edgelesssys/constellation/operators/constellation-node-operator/v2/api/v1alpha1"
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/require"
+	corev1 "k8s.io/api/core/v1"
 )
 
 func TestGetScalingGroupImage(t *testing.T) {
@@ -94,6 +96,123 @@ func TestGetScalingGroupImage(t *testing.T) {
 	}
 }
 
+func TestGetScalingGroupSize(t *testing.T) {
+	testCases := map[string]struct {
+		scalingGroupID string
+		scaleSet       armcomputev2.VirtualMachineScaleSet
+		getScaleSetErr error
+		wantMin        int32
+		wantMax        int32
+		wantErr        bool
+	}{
+		"worker group": {
+			scalingGroupID: "/subscriptions/subscription-id/resourceGroups/resource-group/providers/Microsoft.Compute/virtualMachineScaleSets/scale-set-name",
+			scaleSet: armcomputev2.VirtualMachineScaleSet{
+				Tags: map[string]*string{
+					"constellation-min-count": to.Ptr("1"),
+					"constellation-max-count": to.Ptr("4"),
+				},
+			},
+			wantMin: 1,
+			wantMax: 4,
+		},
+		"no worker group": {
+			scalingGroupID: "/subscriptions/subscription-id/resourceGroups/resource-group/providers/Microsoft.Compute/virtualMachineScaleSets/scale-set-name",
+			scaleSet: armcomputev2.VirtualMachineScaleSet{
+				Tags: map[string]*string{"uid": to.Ptr("uid")},
+			},
+		},
+		"splitting scalingGroupID fails": {
+			scalingGroupID: "invalid",
+			wantErr:        true,
+		},
+		"get scale set fails": {
+			scalingGroupID: "/subscriptions/subscription-id/resourceGroups/resource-group/providers/Microsoft.Compute/virtualMachineScaleSets/scale-set-name",
+			getScaleSetErr: errors.New("get scale set error"),
+			wantErr:        true,
+		},
+	}
+
+	for name, tc := range testCases {
+		t.Run(name, func(t *testing.T) {
+			assert := assert.New(t)
+			require := require.New(t)
+
+			client := Client{
+				scaleSetsAPI: &stubScaleSetsAPI{
+					scaleSet: armcomputev2.VirtualMachineScaleSetsClientGetResponse{
+						VirtualMachineScaleSet: tc.scaleSet,
+					},
+					getErr: tc.getScaleSetErr,
+				},
+			}
+			min, max, err := client.GetScalingGroupSize(context.Background(), tc.scalingGroupID)
+			if tc.wantErr {
+				assert.Error(err)
+				return
+			}
+			require.NoError(err)
+			assert.Equal(tc.wantMin, min)
+			assert.Equal(tc.wantMax, max)
+		})
+	}
+}
+
+func TestGetScalingGroupNodeTemplate(t *testing.T) {
+	testCases := map[string]struct {
+		scalingGroupID string
+		scaleSet       armcomputev2.VirtualMachineScaleSet
+		getScaleSetErr error
+		wantTemplate   updatev1alpha1.NodeTemplate
+		wantErr        bool
+	}{
+		"worker group": {
+			scalingGroupID: "/subscriptions/subscription-id/resourceGroups/resource-group/providers/Microsoft.Compute/virtualMachineScaleSets/scale-set-name",
+			scaleSet: armcomputev2.VirtualMachineScaleSet{
+				Tags: map[string]*string{
+					"constellation-node-labels": to.Ptr("pool=highmem"),
+					"constellation-node-taints": to.Ptr("pool=highmem:NoSchedule"),
+				},
+			},
+			wantTemplate: updatev1alpha1.NodeTemplate{
+				Labels: map[string]string{"pool": "highmem"},
+				Taints: []corev1.Taint{{Key: "pool", Value: "highmem", Effect: corev1.TaintEffectNoSchedule}},
+			},
+		},
+		"no worker group": {
+			scalingGroupID: "/subscriptions/subscription-id/resourceGroups/resource-group/providers/Microsoft.Compute/virtualMachineScaleSets/scale-set-name",
+		},
+		"get scale set fails": {
+			scalingGroupID: "/subscriptions/subscription-id/resourceGroups/resource-group/providers/Microsoft.Compute/virtualMachineScaleSets/scale-set-name",
+			getScaleSetErr: errors.New("get scale set error"),
+			wantErr:        true,
+		},
+	}
+
+	for name, tc := range testCases {
+		t.Run(name, func(t *testing.T) {
+			assert := assert.New(t)
+			require := require.New(t)
+
+			client := Client{
+				scaleSetsAPI: &stubScaleSetsAPI{
+					scaleSet: armcomputev2.VirtualMachineScaleSetsClientGetResponse{
+						VirtualMachineScaleSet: tc.scaleSet,
+					},
+					getErr: tc.getScaleSetErr,
+				},
+			}
+			template, err := client.GetScalingGroupNodeTemplate(context.Background(), tc.scalingGroupID)
+			if tc.wantErr {
+				assert.Error(err)
+				return
+			}
+			require.NoError(err)
+			assert.Equal(tc.wantTemplate, template)
+		})
+	}
+}
+
 func TestSetScalingGroupImage(t *testing.T) {
 	testCases := map[string]struct {
 		scalingGroupID string
@@ -12,3 +12,46 @@ const (
 	ControlPlaneScalingGroupResourceName = "scalinggroup-controlplane"
 	WorkerScalingGroupResourceName       = "scalinggroup-worker"
 )
+
+// QEMUMetadataEndpoint is the endpoint of the QEMU metadata API, which emulates scaling groups on QEMU.
+// It matches QEMUMetadataEndpoint of Constellation's internal/constants, which can't be imported by the operator.
+const QEMUMetadataEndpoint = "10.42.0.1:8080"
+
+const (
+	// EtcdBackupKeyID is the key ID used to derive the etcd snapshot encryption key from the Constellation master secret.
+	EtcdBackupKeyID = "etcd-backup"
+	// EtcdBackupKeyLength is the length of the etcd snapshot encryption key in bytes.
+	EtcdBackupKeyLength = 32
+)
+
+const (
+	// DrainHookNamespace is the namespace of the jobs run as drain hooks.
+	// It is separate from kube-system, so drain hooks cannot use the service accounts of system components.
+	DrainHookNamespace = "constellation-drain-hooks"
+	// DrainHookServiceAccountLabel has to be set to "allowed" on service accounts used by drain hook jobs.
+	DrainHookServiceAccountLabel = "update.edgeless.systems/drain-hook"
+)
+
+const (
+	// K8sVersionConfigMapName is the name of the ConfigMap holding the Kubernetes version installed on joining nodes.
+	K8sVersionConfigMapName = "k8s-version"
+	// K8sVersionConfigMapKey is the key of the Kubernetes version in the k8s-version ConfigMap.
+	K8sVersionConfigMapKey = "k8s-version"
+	// K8sComponentsConfigMapKey is the key of the JSON encoded Kubernetes components installed on joining nodes in the k8s-version ConfigMap.
+	K8sComponentsConfigMapKey = "k8s-components"
+	// DefaultKubernetesUpgradeImage is the image of the jobs upgrading the Kubernetes components of a node,
+	// if no image is configured by the bootstrapper. The jobs run privileged on every node, so the image is pinned by digest.
+	// It must match versions.BusyboxImage of the Constellation module.
+	DefaultKubernetesUpgradeImage = "ghcr.io/edgelesssys/constellation/busybox:1.35.0@sha256:8c40df61d40166f5791f44b3d90b77b4c7f59ed39a992fd9046886d3126ffa68"
+)
+
+const (
+	// WorkerGroupMinTag is the key of the tag (Azure) or metadata item (GCP) holding the minimum size of a worker group.
+	WorkerGroupMinTag = "constellation-min-count"
+	// WorkerGroupMaxTag is the key of the tag or metadata item holding the maximum size of a worker group.
+	WorkerGroupMaxTag = "constellation-max-count"
+	// WorkerGroupLabelsTag is the key of the tag or metadata item holding the node labels of a worker group.
+	WorkerGroupLabelsTag = "constellation-node-labels"
+	// WorkerGroupTaintsTag is the key of the tag or metadata item holding the node taints of a worker group.
+	WorkerGroupTaintsTag = "constellation-node-taints"
+)
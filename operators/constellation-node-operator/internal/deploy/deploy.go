@@ -15,11 +15,14 @@ import (
 
 	updatev1alpha1 "github.com/edgelesssys/constellation/operators/constellation-node-operator/v2/api/v1alpha1"
 	"github.com/edgelesssys/constellation/operators/constellation-node-operator/v2/internal/constants"
+	corev1 "k8s.io/api/core/v1"
 	k8sErrors "k8s.io/apimachinery/pkg/api/errors"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	"sigs.k8s.io/controller-runtime/pkg/client"
 )
 
+//+kubebuilder:rbac:groups="",resources=namespaces,verbs=create
+
 // InitialResources creates the initial resources for the node operator.
 func InitialResources(ctx context.Context, k8sClient client.Writer, scalingGroupGetter scalingGroupGetter, uid string) error {
 	controlPlaneGroupIDs, workerGroupIDs, err := scalingGroupGetter.ListScalingGroups(ctx, uid)
@@ -33,6 +36,9 @@ func InitialResources(ctx context.Context, k8sClient client.Writer, scalingGroup
 		return errors.New("determining initial node image: no worker scaling group found")
 	}
 
+	if err := createDrainHookNamespace(ctx, k8sClient); err != nil {
+		return fmt.Errorf("creating drain hook namespace: %w", err)
+	}
 	if err := createAutoscalingStrategy(ctx, k8sClient, scalingGroupGetter.AutoscalingCloudProvider()); err != nil {
 		return fmt.Errorf("creating initial autoscaling strategy: %w", err)
 	}
@@ -44,28 +50,12 @@ func InitialResources(ctx context.Context, k8sClient client.Writer, scalingGroup
 		return fmt.Errorf("creating initial node image %q: %w", imageReference, err)
 	}
 	for _, groupID := range controlPlaneGroupIDs {
-		groupName, err := scalingGroupGetter.GetScalingGroupName(groupID)
-		if err != nil {
-			return fmt.Errorf("determining scaling group name of %q: %w", groupID, err)
-		}
-		autoscalingGroupName, err := scalingGroupGetter.GetAutoscalingGroupName(groupID)
-		if err != nil {
-			return fmt.Errorf("determining autoscaling group name of %q: %w", groupID, err)
-		}
-		if err := createScalingGroup(ctx, k8sClient, groupID, groupName, autoscalingGroupName, updatev1alpha1.ControlPlaneRole); err != nil {
+		if err := createScalingGroup(ctx, k8sClient, scalingGroupGetter, groupID, updatev1alpha1.ControlPlaneRole); err != nil {
 			return fmt.Errorf("creating initial control plane scaling group: %w", err)
 		}
 	}
 	for _, groupID := range workerGroupIDs {
-		groupName, err := scalingGroupGetter.GetScalingGroupName(groupID)
-		if err != nil {
-			return fmt.Errorf("determining scaling group name of %q: %w", groupID, err)
-		}
-		autoscalingGroupName, err := scalingGroupGetter.GetAutoscalingGroupName(groupID)
-		if err != nil {
-			return fmt.Errorf("determining autoscaling group name of %q: %w", groupID, err)
-		}
-		if err := createScalingGroup(ctx, k8sClient, groupID, groupName, autoscalingGroupName, updatev1alpha1.WorkerRole); err != nil {
+		if err := createScalingGroup(ctx, k8sClient, scalingGroupGetter, groupID, updatev1alpha1.WorkerRole); err != nil {
 			return fmt.Errorf("creating initial worker scaling group: %w", err)
 		}
 	}
@@ -98,6 +88,24 @@ func createAutoscalingStrategy(ctx context.Context, k8sClient client.Writer, pro
 	return err
 }
 
+// createDrainHookNamespace creates the namespace of drain hook jobs if it does not exist yet.
+// Pods in the namespace have to comply with the restricted Pod Security Standard.
+func createDrainHookNamespace(ctx context.Context, k8sClient client.Writer) error {
+	err := k8sClient.Create(ctx, &corev1.Namespace{
+		TypeMeta: metav1.TypeMeta{APIVersion: "v1", Kind: "Namespace"},
+		ObjectMeta: metav1.ObjectMeta{
+			Name: constants.DrainHookNamespace,
+			Labels: map[string]string{
+				"pod-security.kubernetes.io/enforce": "restricted",
+			},
+		},
+	})
+	if k8sErrors.IsAlreadyExists(err) {
+		return nil
+	}
+	return err
+}
+
 // createNodeImage creates the initial nodeimage resource if it does not exist yet.
 func createNodeImage(ctx context.Context, k8sClient client.Writer, imageReference string) error {
 	err := k8sClient.Create(ctx, &updatev1alpha1.NodeImage{
@@ -116,20 +124,48 @@ func createNodeImage(ctx context.Context, k8sClient client.Writer, imageReferenc
 }
 
 // createScalingGroup creates an initial scaling group resource if it does not exist yet.
-func createScalingGroup(ctx context.Context, k8sClient client.Writer, groupID, groupName, autoscalingGroupName string, role updatev1alpha1.NodeRole) error {
-	err := k8sClient.Create(ctx, &updatev1alpha1.ScalingGroup{
+// Worker groups defined in the Constellation config are autoscaled within their configured size
+// and carry the labels and taints of their nodes. All other scaling groups default to a size of 1 to 10 nodes
+// and are not autoscaled until enabled by the user.
+func createScalingGroup(ctx context.Context, k8sClient client.Writer, scalingGroupGetter scalingGroupGetter, groupID string, role updatev1alpha1.NodeRole) error {
+	groupName, err := scalingGroupGetter.GetScalingGroupName(groupID)
+	if err != nil {
+		return fmt.Errorf("determining scaling group name of %q: %w", groupID, err)
+	}
+	autoscalingGroupName, err := scalingGroupGetter.GetAutoscalingGroupName(groupID)
+	if err != nil {
+		return fmt.Errorf("determining autoscaling group name of %q: %w", groupID, err)
+	}
+	spec := updatev1alpha1.ScalingGroupSpec{
+		NodeImage:           constants.NodeImageResourceName,
+		GroupID:             groupID,
+		AutoscalerGroupName: autoscalingGroupName,
+		Min:                 1,
+		Max:                 10,
+		Role:                role,
+	}
+	if role == updatev1alpha1.WorkerRole {
+		min, max, err := scalingGroupGetter.GetScalingGroupSize(ctx, groupID)
+		if err != nil {
+			return fmt.Errorf("determining size of scaling group %q: %w", groupID, err)
+		}
+		if max > 0 {
+			spec.Min = min
+			spec.Max = max
+			spec.Autoscaling = true
+		}
+		spec.NodeTemplate, err = scalingGroupGetter.GetScalingGroupNodeTemplate(ctx, groupID)
+		if err != nil {
+			return fmt.Errorf("determining node template of scaling group %q: %w", groupID, err)
+		}
+	}
+
+	err = k8sClient.Create(ctx, &updatev1alpha1.ScalingGroup{
 		TypeMeta: metav1.TypeMeta{APIVersion: "update.edgeless.systems/v1alpha1", Kind: "ScalingGroup"},
 		ObjectMeta: metav1.ObjectMeta{
 			Name: strings.ToLower(groupName),
 		},
-		Spec: updatev1alpha1.ScalingGroupSpec{
-			NodeImage:           constants.NodeImageResourceName,
-			GroupID:             groupID,
-			AutoscalerGroupName: autoscalingGroupName,
-			Min:                 1,
-			Max:                 10,
-			Role:                role,
-		},
+		Spec: spec,
 	})
 	if k8sErrors.IsAlreadyExists(err) {
 		return nil
@@ -140,6 +176,10 @@ func createScalingGroup(ctx context.Context, k8sClient client.Writer, groupID, g
 type scalingGroupGetter interface {
 	// GetScalingGroupImage retrieves the image currently used by a scaling group.
 	GetScalingGroupImage(ctx context.Context, scalingGroupID string) (string, error)
+	// GetScalingGroupSize retrieves the minimum and maximum number of nodes of a worker group.
+	GetScalingGroupSize(ctx context.Context, scalingGroupID string) (min, max int32, err error)
+	// GetScalingGroupNodeTemplate retrieves the labels and taints of the nodes of a scaling group.
+	GetScalingGroupNodeTemplate(ctx context.Context, scalingGroupID string) (updatev1alpha1.NodeTemplate, error)
 	// GetScalingGroupName retrieves the name of a scaling group.
 	GetScalingGroupName(scalingGroupID string) (string, error)
 	// GetScalingGroupName retrieves the name of a scaling group as needed by the cluster-autoscaler.
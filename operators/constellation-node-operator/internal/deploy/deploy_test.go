@@ -15,6 +15,7 @@ import (
 	"github.com/edgelesssys/constellation/operators/constellation-node-operator/v2/internal/constants"
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/require"
+	corev1 "k8s.io/api/core/v1"
 	k8sErrors "k8s.io/apimachinery/pkg/api/errors"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	"k8s.io/apimachinery/pkg/runtime/schema"
@@ -36,7 +37,15 @@ func TestInitialResources(t *testing.T) {
 				{groupID: "control-plane", image: "image-1", name: "control-plane", isControlPlane: true},
 				{groupID: "worker", image: "image-1", name: "worker"},
 			},
-			wantResources: 4,
+			wantResources: 5,
+		},
+		"creating initial resources with worker groups works": {
+			items: []scalingGroupStoreItem{
+				{groupID: "control-plane", image: "image-1", name: "control-plane", isControlPlane: true},
+				{groupID: "worker-highmem", image: "image-1", name: "worker-highmem", min: 1, max: 5},
+				{groupID: "worker-spot", image: "image-1", name: "worker-spot", min: 0, max: 10},
+			},
+			wantResources: 6,
 		},
 		"missing control planes": {
 			items: []scalingGroupStoreItem{
@@ -169,6 +178,47 @@ func TestCreateAutoscalingStrategy(t *testing.T) {
 	}
 }
 
+func TestCreateDrainHookNamespace(t *testing.T) {
+	wantNamespace := &corev1.Namespace{
+		TypeMeta: metav1.TypeMeta{APIVersion: "v1", Kind: "Namespace"},
+		ObjectMeta: metav1.ObjectMeta{
+			Name:   constants.DrainHookNamespace,
+			Labels: map[string]string{"pod-security.kubernetes.io/enforce": "restricted"},
+		},
+	}
+
+	testCases := map[string]struct {
+		createErr error
+		wantErr   bool
+	}{
+		"create works": {},
+		"create fails": {
+			createErr: errors.New("create failed"),
+			wantErr:   true,
+		},
+		"namespace exists": {
+			createErr: k8sErrors.NewAlreadyExists(schema.GroupResource{}, constants.DrainHookNamespace),
+		},
+	}
+
+	for name, tc := range testCases {
+		t.Run(name, func(t *testing.T) {
+			assert := assert.New(t)
+			require := require.New(t)
+
+			k8sClient := &stubK8sClient{createErr: tc.createErr}
+			err := createDrainHookNamespace(context.Background(), k8sClient)
+			if tc.wantErr {
+				assert.Error(err)
+				return
+			}
+			require.NoError(err)
+			assert.Len(k8sClient.createdObjects, 1)
+			assert.Equal(wantNamespace, k8sClient.createdObjects[0])
+		})
+	}
+}
+
 func TestCreateNodeImage(t *testing.T) {
 	testCases := map[string]struct {
 		createErr     error
@@ -224,11 +274,17 @@ func TestCreateNodeImage(t *testing.T) {
 
 func TestCreateScalingGroup(t *testing.T) {
 	testCases := map[string]struct {
+		item             scalingGroupStoreItem
+		role             updatev1alpha1.NodeRole
+		nameErr          error
+		sizeErr          error
 		createErr        error
 		wantScalingGroup *updatev1alpha1.ScalingGroup
 		wantErr          bool
 	}{
 		"create works": {
+			item: scalingGroupStoreItem{groupID: "group-id", name: "group-Name"},
+			role: updatev1alpha1.WorkerRole,
 			wantScalingGroup: &updatev1alpha1.ScalingGroup{
 				TypeMeta: metav1.TypeMeta{APIVersion: "update.edgeless.systems/v1alpha1", Kind: "ScalingGroup"},
 				ObjectMeta: metav1.ObjectMeta{
@@ -244,11 +300,77 @@ func TestCreateScalingGroup(t *testing.T) {
 				},
 			},
 		},
+		"worker group": {
+			item: scalingGroupStoreItem{
+				groupID: "group-id",
+				name:    "group-Name",
+				min:     0,
+				max:     5,
+				nodeTemplate: updatev1alpha1.NodeTemplate{
+					Labels: map[string]string{"pool": "spot"},
+					Taints: []corev1.Taint{{Key: "pool", Value: "spot", Effect: corev1.TaintEffectNoSchedule}},
+				},
+			},
+			role: updatev1alpha1.WorkerRole,
+			wantScalingGroup: &updatev1alpha1.ScalingGroup{
+				TypeMeta: metav1.TypeMeta{APIVersion: "update.edgeless.systems/v1alpha1", Kind: "ScalingGroup"},
+				ObjectMeta: metav1.ObjectMeta{
+					Name: "group-name",
+				},
+				Spec: updatev1alpha1.ScalingGroupSpec{
+					NodeImage:           constants.NodeImageResourceName,
+					GroupID:             "group-id",
+					AutoscalerGroupName: "group-Name",
+					Autoscaling:         true,
+					Min:                 0,
+					Max:                 5,
+					Role:                updatev1alpha1.WorkerRole,
+					NodeTemplate: updatev1alpha1.NodeTemplate{
+						Labels: map[string]string{"pool": "spot"},
+						Taints: []corev1.Taint{{Key: "pool", Value: "spot", Effect: corev1.TaintEffectNoSchedule}},
+					},
+				},
+			},
+		},
+		"control plane ignores size": {
+			item: scalingGroupStoreItem{groupID: "group-id", name: "group-Name", min: 3, max: 3, isControlPlane: true},
+			role: updatev1alpha1.ControlPlaneRole,
+			wantScalingGroup: &updatev1alpha1.ScalingGroup{
+				TypeMeta: metav1.TypeMeta{APIVersion: "update.edgeless.systems/v1alpha1", Kind: "ScalingGroup"},
+				ObjectMeta: metav1.ObjectMeta{
+					Name: "group-name",
+				},
+				Spec: updatev1alpha1.ScalingGroupSpec{
+					NodeImage:           constants.NodeImageResourceName,
+					GroupID:             "group-id",
+					AutoscalerGroupName: "group-Name",
+					Min:                 1,
+					Max:                 10,
+					Role:                updatev1alpha1.ControlPlaneRole,
+				},
+			},
+		},
+		"getting name fails": {
+			item:    scalingGroupStoreItem{groupID: "group-id", name: "group-Name"},
+			role:    updatev1alpha1.WorkerRole,
+			nameErr: errors.New("getting name failed"),
+			wantErr: true,
+		},
+		"getting size fails": {
+			item:    scalingGroupStoreItem{groupID: "group-id", name: "group-Name"},
+			role:    updatev1alpha1.WorkerRole,
+			sizeErr: errors.New("getting size failed"),
+			wantErr: true,
+		},
 		"create fails": {
+			item:      scalingGroupStoreItem{groupID: "group-id", name: "group-Name"},
+			role:      updatev1alpha1.WorkerRole,
 			createErr: errors.New("create failed"),
 			wantErr:   true,
 		},
 		"image exists": {
+			item:      scalingGroupStoreItem{groupID: "group-id", name: "group-Name"},
+			role:      updatev1alpha1.WorkerRole,
 			createErr: k8sErrors.NewAlreadyExists(schema.GroupResource{}, constants.AutoscalingStrategyResourceName),
 			wantScalingGroup: &updatev1alpha1.ScalingGroup{
 				TypeMeta: metav1.TypeMeta{APIVersion: "update.edgeless.systems/v1alpha1", Kind: "ScalingGroup"},
@@ -273,7 +395,9 @@ func TestCreateScalingGroup(t *testing.T) {
 			require := require.New(t)
 
 			k8sClient := &stubK8sClient{createErr: tc.createErr}
-			err := createScalingGroup(context.Background(), k8sClient, "group-id", "group-Name", "group-Name", updatev1alpha1.WorkerRole)
+			scalingGroupGetter := newScalingGroupGetter([]scalingGroupStoreItem{tc.item}, nil, tc.nameErr, nil)
+			scalingGroupGetter.sizeErr = tc.sizeErr
+			err := createScalingGroup(context.Background(), k8sClient, scalingGroupGetter, "group-id", tc.role)
 			if tc.wantErr {
 				assert.Error(err)
 				return
@@ -300,6 +424,7 @@ type stubScalingGroupGetter struct {
 	store    map[string]scalingGroupStoreItem
 	imageErr error
 	nameErr  error
+	sizeErr  error
 	listErr  error
 }
 
@@ -320,6 +445,14 @@ func (g *stubScalingGroupGetter) GetScalingGroupImage(ctx context.Context, scali
 	return g.store[scalingGroupID].image, g.imageErr
 }
 
+func (g *stubScalingGroupGetter) GetScalingGroupSize(ctx context.Context, scalingGroupID string) (min, max int32, err error) {
+	return g.store[scalingGroupID].min, g.store[scalingGroupID].max, g.sizeErr
+}
+
+func (g *stubScalingGroupGetter) GetScalingGroupNodeTemplate(ctx context.Context, scalingGroupID string) (updatev1alpha1.NodeTemplate, error) {
+	return g.store[scalingGroupID].nodeTemplate, nil
+}
+
 func (g *stubScalingGroupGetter) GetScalingGroupName(scalingGroupID string) (string, error) {
 	return g.store[scalingGroupID].name, g.nameErr
 }
@@ -347,5 +480,8 @@ type scalingGroupStoreItem struct {
 	groupID        string
 	name           string
 	image          string
+	min            int32
+	max            int32
+	nodeTemplate   updatev1alpha1.NodeTemplate
 	isControlPlane bool
 }
@@ -0,0 +1,157 @@
+/*
+Copyright (c) Edgeless Systems GmbH
+
+SPDX-License-Identifier: AGPL-3.0-only
+*/
+
+// Package drain implements evicting pods from nodes and calling drain webhooks.
+// Pods are evicted using the Kubernetes eviction API, so PodDisruptionBudgets are honoured.
+package drain
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"time"
+
+	corev1 "k8s.io/api/core/v1"
+	policyv1 "k8s.io/api/policy/v1"
+	k8serrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/fields"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/client-go/kubernetes"
+)
+
+// mirrorPodAnnotation is set on mirror pods of static pods, which cannot be evicted.
+const mirrorPodAnnotation = "kubernetes.io/config.mirror"
+
+// webhookTimeout is the timeout of a single request to a drain webhook.
+// Webhooks are retried until the drain times out, so a webhook that doesn't respond must not block the drain.
+const webhookTimeout = 30 * time.Second
+
+// ErrEvictionBlocked is returned if an eviction is rejected, e.g. because it would violate a PodDisruptionBudget.
+var ErrEvictionBlocked = errors.New("eviction blocked")
+
+// Client evicts pods from nodes.
+type Client struct {
+	kubeClient     kubernetes.Interface
+	httpClient     httpClient
+	webhookTimeout time.Duration
+	ownPod         types.NamespacedName
+}
+
+// New creates a new Client.
+// ownPod is the pod of the operator itself. It is never evicted, since evicting it would interrupt the drain.
+// On clusters with a single control-plane node, the operator runs on the node being drained
+// and is rescheduled once the node is removed.
+func New(kubeClient kubernetes.Interface, ownPod types.NamespacedName) *Client {
+	return &Client{
+		kubeClient:     kubeClient,
+		httpClient:     &http.Client{Timeout: webhookTimeout},
+		webhookTimeout: webhookTimeout,
+		ownPod:         ownPod,
+	}
+}
+
+// PodsToEvict returns the pods running on a node that have to be evicted before the node can be removed.
+// Pods owned by DaemonSets, mirror pods, pods that already terminated and the pod of the operator are skipped.
+func (c *Client) PodsToEvict(ctx context.Context, nodeName string) ([]corev1.Pod, error) {
+	podList, err := c.kubeClient.CoreV1().Pods(metav1.NamespaceAll).List(ctx, metav1.ListOptions{
+		FieldSelector: fields.OneTermEqualSelector("spec.nodeName", nodeName).String(),
+	})
+	if err != nil {
+		return nil, err
+	}
+	var pods []corev1.Pod
+	for _, pod := range podList.Items {
+		if pod.Namespace == c.ownPod.Namespace && pod.Name == c.ownPod.Name {
+			continue
+		}
+		if evictable(pod) {
+			pods = append(pods, pod)
+		}
+	}
+	return pods, nil
+}
+
+// EvictPod evicts a pod. If gracePeriodSeconds is nil, the termination grace period of the pod is used.
+// ErrEvictionBlocked is returned if the eviction is rejected.
+func (c *Client) EvictPod(ctx context.Context, pod corev1.Pod, gracePeriodSeconds *int64) error {
+	eviction := &policyv1.Eviction{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      pod.Name,
+			Namespace: pod.Namespace,
+		},
+		DeleteOptions: &metav1.DeleteOptions{
+			GracePeriodSeconds: gracePeriodSeconds,
+		},
+	}
+	err := c.kubeClient.PolicyV1().Evictions(pod.Namespace).Evict(ctx, eviction)
+	switch {
+	case err == nil, k8serrors.IsNotFound(err):
+		return nil
+	case k8serrors.IsTooManyRequests(err):
+		// the API server rejects evictions violating a PodDisruptionBudget with 429
+		return fmt.Errorf("%w: %s", ErrEvictionBlocked, err.Error())
+	default:
+		return err
+	}
+}
+
+// WebhookRequest is the body sent to drain webhooks.
+type WebhookRequest struct {
+	NodeName string `json:"nodeName"`
+	Reason   string `json:"reason"`
+	Phase    string `json:"phase"`
+}
+
+// CallWebhook sends a POST request to a drain webhook.
+// An error is returned if the webhook does not respond with a 2xx status code within the webhook timeout.
+func (c *Client) CallWebhook(ctx context.Context, url string, request WebhookRequest) error {
+	ctx, cancel := context.WithTimeout(ctx, c.webhookTimeout)
+	defer cancel()
+	body, err := json.Marshal(request)
+	if err != nil {
+		return err
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, url, bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	resp, err := c.httpClient.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	_, _ = io.Copy(io.Discard, resp.Body)
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		return fmt.Errorf("webhook responded with status code %d", resp.StatusCode)
+	}
+	return nil
+}
+
+// evictable checks if a pod has to be evicted before its node can be removed.
+func evictable(pod corev1.Pod) bool {
+	if pod.Status.Phase == corev1.PodSucceeded || pod.Status.Phase == corev1.PodFailed {
+		return false
+	}
+	if _, ok := pod.Annotations[mirrorPodAnnotation]; ok {
+		return false
+	}
+	for _, owner := range pod.OwnerReferences {
+		if owner.Controller != nil && *owner.Controller && owner.Kind == "DaemonSet" {
+			return false
+		}
+	}
+	return true
+}
+
+type httpClient interface {
+	Do(req *http.Request) (*http.Response, error)
+}
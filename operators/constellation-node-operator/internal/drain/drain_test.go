@@ -0,0 +1,206 @@
+/*
+Copyright (c) Edgeless Systems GmbH
+
+SPDX-License-Identifier: AGPL-3.0-only
+*/
+
+package drain
+
+import (
+	"context"
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	corev1 "k8s.io/api/core/v1"
+	policyv1 "k8s.io/api/policy/v1"
+	k8serrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/client-go/kubernetes/fake"
+	k8stesting "k8s.io/client-go/testing"
+)
+
+func TestPodsToEvict(t *testing.T) {
+	assert := assert.New(t)
+	require := require.New(t)
+
+	controller := true
+	pods := []runtime.Object{
+		&corev1.Pod{ObjectMeta: metav1.ObjectMeta{Name: "workload", Namespace: "default"}},
+		&corev1.Pod{ObjectMeta: metav1.ObjectMeta{
+			Name:      "daemonset",
+			Namespace: "default",
+			OwnerReferences: []metav1.OwnerReference{
+				{Kind: "DaemonSet", Name: "daemonset", Controller: &controller},
+			},
+		}},
+		&corev1.Pod{ObjectMeta: metav1.ObjectMeta{
+			Name:        "mirror",
+			Namespace:   "kube-system",
+			Annotations: map[string]string{mirrorPodAnnotation: "hash"},
+		}},
+		&corev1.Pod{
+			ObjectMeta: metav1.ObjectMeta{Name: "completed", Namespace: "default"},
+			Status:     corev1.PodStatus{Phase: corev1.PodSucceeded},
+		},
+		&corev1.Pod{
+			ObjectMeta: metav1.ObjectMeta{Name: "failed", Namespace: "default"},
+			Status:     corev1.PodStatus{Phase: corev1.PodFailed},
+		},
+		&corev1.Pod{ObjectMeta: metav1.ObjectMeta{Name: "operator", Namespace: "kube-system"}},
+		&corev1.Pod{ObjectMeta: metav1.ObjectMeta{Name: "operator", Namespace: "default"}},
+	}
+	client := New(fake.NewSimpleClientset(pods...), types.NamespacedName{Namespace: "kube-system", Name: "operator"})
+
+	toEvict, err := client.PodsToEvict(context.Background(), "node")
+	require.NoError(err)
+	var names []string
+	for _, pod := range toEvict {
+		names = append(names, pod.Namespace+"/"+pod.Name)
+	}
+	assert.ElementsMatch([]string{"default/workload", "default/operator"}, names)
+}
+
+func TestEvictPod(t *testing.T) {
+	testCases := map[string]struct {
+		evictErr    error
+		wantBlocked bool
+		wantErr     bool
+	}{
+		"eviction succeeds": {},
+		"pod is already gone": {
+			evictErr: k8serrors.NewNotFound(schema.GroupResource{Resource: "pods"}, "pod"),
+		},
+		"eviction is blocked": {
+			evictErr:    k8serrors.NewTooManyRequests("Cannot evict pod as it would violate the pod's disruption budget.", 10),
+			wantBlocked: true,
+			wantErr:     true,
+		},
+		"eviction fails": {
+			evictErr: errors.New("failed"),
+			wantErr:  true,
+		},
+	}
+
+	for name, tc := range testCases {
+		t.Run(name, func(t *testing.T) {
+			assert := assert.New(t)
+
+			kubeClient := fake.NewSimpleClientset()
+			var gracePeriod *int64
+			kubeClient.PrependReactor("create", "pods", func(action k8stesting.Action) (bool, runtime.Object, error) {
+				if action.GetSubresource() != "eviction" {
+					return false, nil, nil
+				}
+				gracePeriod = action.(k8stesting.CreateAction).GetObject().(*policyv1.Eviction).DeleteOptions.GracePeriodSeconds
+				return true, nil, tc.evictErr
+			})
+			client := New(kubeClient, types.NamespacedName{})
+
+			wantGracePeriod := int64(30)
+			err := client.EvictPod(context.Background(), corev1.Pod{ObjectMeta: metav1.ObjectMeta{Name: "pod", Namespace: "default"}}, &wantGracePeriod)
+			if tc.wantErr {
+				assert.Error(err)
+			} else {
+				assert.NoError(err)
+			}
+			assert.Equal(tc.wantBlocked, errors.Is(err, ErrEvictionBlocked))
+			assert.Equal(&wantGracePeriod, gracePeriod)
+		})
+	}
+}
+
+func TestCallWebhook(t *testing.T) {
+	testCases := map[string]struct {
+		statusCode int
+		doErr      error
+		wantErr    bool
+	}{
+		"webhook succeeds": {
+			statusCode: http.StatusOK,
+		},
+		"webhook rejects": {
+			statusCode: http.StatusConflict,
+			wantErr:    true,
+		},
+		"request fails": {
+			doErr:   errors.New("failed"),
+			wantErr: true,
+		},
+	}
+
+	for name, tc := range testCases {
+		t.Run(name, func(t *testing.T) {
+			assert := assert.New(t)
+
+			httpClient := &stubHTTPClient{statusCode: tc.statusCode, err: tc.doErr}
+			client := &Client{httpClient: httpClient, webhookTimeout: time.Minute}
+
+			err := client.CallWebhook(context.Background(), "http://hook.example.com", WebhookRequest{NodeName: "node", Reason: "reason", Phase: "PreDrain"})
+			if tc.wantErr {
+				assert.Error(err)
+			} else {
+				assert.NoError(err)
+			}
+			assert.Equal(http.MethodPost, httpClient.method)
+			assert.JSONEq(`{"nodeName":"node","reason":"reason","phase":"PreDrain"}`, httpClient.body)
+		})
+	}
+}
+
+func TestCallWebhookTimeout(t *testing.T) {
+	assert := assert.New(t)
+
+	unblock := make(chan struct{})
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		// the webhook never responds
+		<-unblock
+	}))
+	defer server.Close()
+	defer close(unblock)
+
+	client := &Client{httpClient: server.Client(), webhookTimeout: 10 * time.Millisecond}
+
+	done := make(chan error, 1)
+	go func() {
+		done <- client.CallWebhook(context.Background(), server.URL, WebhookRequest{NodeName: "node", Reason: "reason", Phase: "PreDrain"})
+	}()
+	select {
+	case err := <-done:
+		assert.ErrorIs(err, context.DeadlineExceeded)
+	case <-time.After(10 * time.Second):
+		t.Fatal("CallWebhook did not return")
+	}
+}
+
+type stubHTTPClient struct {
+	statusCode int
+	err        error
+	method     string
+	body       string
+}
+
+func (c *stubHTTPClient) Do(req *http.Request) (*http.Response, error) {
+	c.method = req.Method
+	body, err := io.ReadAll(req.Body)
+	if err != nil {
+		return nil, err
+	}
+	c.body = string(body)
+	if c.err != nil {
+		return nil, c.err
+	}
+	return &http.Response{
+		StatusCode: c.statusCode,
+		Body:       io.NopCloser(strings.NewReader("")),
+	}, nil
+}
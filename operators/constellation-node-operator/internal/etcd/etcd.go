@@ -10,10 +10,12 @@ import (
 	"context"
 	"errors"
 	"fmt"
+	"io"
 	"net"
 	"net/url"
 
 	"github.com/edgelesssys/constellation/operators/constellation-node-operator/v2/internal/controlplane"
+	"github.com/edgelesssys/constellation/operators/constellation-node-operator/v2/internal/metrics"
 	"go.etcd.io/etcd/client/pkg/v3/transport"
 	clientv3 "go.etcd.io/etcd/client/v3"
 	"sigs.k8s.io/controller-runtime/pkg/client"
@@ -85,12 +87,20 @@ func (c *Client) RemoveEtcdMemberFromCluster(ctx context.Context, vpcIP string)
 		return err
 	}
 	_, err = c.etcdClient.MemberRemove(ctx, memberID)
+	metrics.EtcdMemberOperations.WithLabelValues("remove", metrics.Result(err)).Inc()
 	return err
 }
 
+// Snapshot streams a snapshot of the etcd keyspace from one of the etcd members.
+// The caller is responsible for closing the returned reader.
+func (c *Client) Snapshot(ctx context.Context) (io.ReadCloser, error) {
+	return c.etcdClient.Snapshot(ctx)
+}
+
 // getMemberID returns the member ID of the member with the given vpcIP.
 func (c *Client) getMemberID(ctx context.Context, vpcIP string) (uint64, error) {
 	listResponse, err := c.etcdClient.MemberList(ctx)
+	metrics.EtcdMemberOperations.WithLabelValues("list", metrics.Result(err)).Inc()
 	if err != nil {
 		return 0, err
 	}
@@ -129,6 +139,7 @@ func getInitialEndpoints(k8sClient client.Client) ([]string, error) {
 type etcdClient interface {
 	MemberList(ctx context.Context) (*clientv3.MemberListResponse, error)
 	MemberRemove(ctx context.Context, memberID uint64) (*clientv3.MemberRemoveResponse, error)
+	Snapshot(ctx context.Context) (io.ReadCloser, error)
 	Sync(ctx context.Context) error
 	Close() error
 }
@@ -7,8 +7,10 @@ SPDX-License-Identifier: AGPL-3.0-only
 package etcd
 
 import (
+	"bytes"
 	"context"
 	"errors"
+	"io"
 	"testing"
 
 	"github.com/stretchr/testify/assert"
@@ -64,6 +66,44 @@ func TestRemoveEtcdMemberFromCluster(t *testing.T) {
 	}
 }
 
+func TestSnapshot(t *testing.T) {
+	testCases := map[string]struct {
+		snapshot    []byte
+		snapshotErr error
+		wantErr     bool
+	}{
+		"snapshot works": {
+			snapshot: []byte("snapshot"),
+		},
+		"snapshot fails": {
+			snapshotErr: errors.New("snapshot failed"),
+			wantErr:     true,
+		},
+	}
+
+	for name, tc := range testCases {
+		t.Run(name, func(t *testing.T) {
+			assert := assert.New(t)
+			require := require.New(t)
+
+			client := Client{etcdClient: &stubEtcdClient{
+				snapshot:    tc.snapshot,
+				snapshotErr: tc.snapshotErr,
+			}}
+			reader, err := client.Snapshot(context.Background())
+			if tc.wantErr {
+				assert.Error(err)
+				return
+			}
+			require.NoError(err)
+			defer reader.Close()
+			snapshot, err := io.ReadAll(reader)
+			require.NoError(err)
+			assert.Equal(tc.snapshot, snapshot)
+		})
+	}
+}
+
 func TestGetMemberID(t *testing.T) {
 	testCases := map[string]struct {
 		members       []*pb.Member
@@ -178,11 +218,13 @@ func (c *stubK8sClient) List(ctx context.Context, list client.ObjectList, opts .
 }
 
 type stubEtcdClient struct {
-	members   []*pb.Member
-	listErr   error
-	removeErr error
-	syncErr   error
-	closeErr  error
+	members     []*pb.Member
+	listErr     error
+	removeErr   error
+	snapshot    []byte
+	snapshotErr error
+	syncErr     error
+	closeErr    error
 }
 
 func (c *stubEtcdClient) MemberList(ctx context.Context) (*clientv3.MemberListResponse, error) {
@@ -197,6 +239,13 @@ func (c *stubEtcdClient) MemberRemove(ctx context.Context, memberID uint64) (*cl
 	}, c.removeErr
 }
 
+func (c *stubEtcdClient) Snapshot(ctx context.Context) (io.ReadCloser, error) {
+	if c.snapshotErr != nil {
+		return nil, c.snapshotErr
+	}
+	return io.NopCloser(bytes.NewReader(c.snapshot)), nil
+}
+
 func (c *stubEtcdClient) Sync(ctx context.Context) error {
 	return c.syncErr
 }
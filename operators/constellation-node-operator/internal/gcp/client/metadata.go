@@ -25,3 +25,18 @@ func getMetadataByKey(metadata *computepb.Metadata, key string) string {
 	}
 	return ""
 }
+
+// metadataToMap returns the items of the given metadata as map.
+func metadataToMap(metadata *computepb.Metadata) map[string]string {
+	items := make(map[string]string)
+	if metadata == nil {
+		return items
+	}
+	for _, item := range metadata.Items {
+		if item.Key == nil || item.Value == nil {
+			continue
+		}
+		items[*item.Key] = *item.Value
+	}
+	return items
+}
@@ -11,6 +11,8 @@ import (
 	"errors"
 	"fmt"
 
+	updatev1alpha1 "github.com/edgelesssys/constellation/operators/constellation-node-operator/v2/api/v1alpha1"
+	"github.com/edgelesssys/constellation/operators/constellation-node-operator/v2/internal/workergroup"
 	"google.golang.org/api/iterator"
 	computepb "google.golang.org/genproto/googleapis/cloud/compute/v1"
 	"google.golang.org/protobuf/proto"
@@ -85,6 +87,25 @@ func (c *Client) SetScalingGroupImage(ctx context.Context, scalingGroupID, image
 	return nil
 }
 
+// GetScalingGroupSize returns the minimum and maximum number of nodes of a worker group as configured at creation.
+// Zero values are returned if the scaling group was not created from a worker group.
+func (c *Client) GetScalingGroupSize(ctx context.Context, scalingGroupID string) (min, max int32, err error) {
+	instanceTemplate, err := c.getScalingGroupTemplate(ctx, scalingGroupID)
+	if err != nil {
+		return 0, 0, err
+	}
+	return workergroup.Size(instanceTemplateMetadata(instanceTemplate))
+}
+
+// GetScalingGroupNodeTemplate returns the labels and taints of the nodes of a scaling group.
+func (c *Client) GetScalingGroupNodeTemplate(ctx context.Context, scalingGroupID string) (updatev1alpha1.NodeTemplate, error) {
+	instanceTemplate, err := c.getScalingGroupTemplate(ctx, scalingGroupID)
+	if err != nil {
+		return updatev1alpha1.NodeTemplate{}, err
+	}
+	return workergroup.NodeTemplate(instanceTemplateMetadata(instanceTemplate))
+}
+
 // GetScalingGroupName retrieves the name of a scaling group.
 // This keeps the casing of the original name, but Kubernetes requires the name to be lowercase,
 // so use strings.ToLower() on the result if using the name in a Kubernetes context.
@@ -179,3 +200,10 @@ func instanceTemplateSourceImage(instanceTemplate *computepb.InstanceTemplate) (
 	}
 	return uriNormalize(*instanceTemplate.Properties.Disks[0].InitializeParams.SourceImage), nil
 }
+
+func instanceTemplateMetadata(instanceTemplate *computepb.InstanceTemplate) map[string]string {
+	if instanceTemplate.Properties == nil {
+		return map[string]string{}
+	}
+	return metadataToMap(instanceTemplate.Properties.Metadata)
+}
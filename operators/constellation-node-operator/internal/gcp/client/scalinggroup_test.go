@@ -11,10 +11,12 @@ import (
 	"errors"
 	"testing"
 
+	updatev1alpha1 "github.com/edgelesssys/constellation/operators/constellation-node-operator/v2/api/v1alpha1"
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/require"
 	computepb "google.golang.org/genproto/googleapis/cloud/compute/v1"
 	"google.golang.org/protobuf/proto"
+	corev1 "k8s.io/api/core/v1"
 )
 
 func TestGetScalingGroupImage(t *testing.T) {
@@ -105,6 +107,141 @@ func TestGetScalingGroupImage(t *testing.T) {
 	}
 }
 
+func TestGetScalingGroupSize(t *testing.T) {
+	testCases := map[string]struct {
+		instanceTemplate       *computepb.InstanceTemplate
+		getInstanceTemplateErr error
+		wantMin                int32
+		wantMax                int32
+		wantErr                bool
+	}{
+		"worker group": {
+			instanceTemplate: &computepb.InstanceTemplate{
+				Properties: &computepb.InstanceProperties{
+					Metadata: &computepb.Metadata{
+						Items: []*computepb.Items{
+							{Key: proto.String("constellation-min-count"), Value: proto.String("2")},
+							{Key: proto.String("constellation-max-count"), Value: proto.String("8")},
+						},
+					},
+				},
+			},
+			wantMin: 2,
+			wantMax: 8,
+		},
+		"no worker group": {
+			instanceTemplate: &computepb.InstanceTemplate{
+				Properties: &computepb.InstanceProperties{},
+			},
+		},
+		"invalid size": {
+			instanceTemplate: &computepb.InstanceTemplate{
+				Properties: &computepb.InstanceProperties{
+					Metadata: &computepb.Metadata{
+						Items: []*computepb.Items{
+							{Key: proto.String("constellation-min-count"), Value: proto.String("two")},
+							{Key: proto.String("constellation-max-count"), Value: proto.String("8")},
+						},
+					},
+				},
+			},
+			wantErr: true,
+		},
+		"get instance template fails": {
+			getInstanceTemplateErr: errors.New("get instance template error"),
+			wantErr:                true,
+		},
+	}
+
+	for name, tc := range testCases {
+		t.Run(name, func(t *testing.T) {
+			assert := assert.New(t)
+			require := require.New(t)
+
+			client := Client{
+				instanceGroupManagersAPI: &stubInstanceGroupManagersAPI{
+					instanceGroupManager: &computepb.InstanceGroupManager{
+						InstanceTemplate: proto.String("projects/project/global/instanceTemplates/instance-template"),
+					},
+				},
+				instanceTemplateAPI: &stubInstanceTemplateAPI{
+					getErr:   tc.getInstanceTemplateErr,
+					template: tc.instanceTemplate,
+				},
+			}
+			min, max, err := client.GetScalingGroupSize(context.Background(), "projects/project/zones/zone/instanceGroupManagers/instance-group")
+			if tc.wantErr {
+				assert.Error(err)
+				return
+			}
+			require.NoError(err)
+			assert.Equal(tc.wantMin, min)
+			assert.Equal(tc.wantMax, max)
+		})
+	}
+}
+
+func TestGetScalingGroupNodeTemplate(t *testing.T) {
+	testCases := map[string]struct {
+		instanceTemplate       *computepb.InstanceTemplate
+		getInstanceTemplateErr error
+		wantTemplate           updatev1alpha1.NodeTemplate
+		wantErr                bool
+	}{
+		"worker group": {
+			instanceTemplate: &computepb.InstanceTemplate{
+				Properties: &computepb.InstanceProperties{
+					Metadata: &computepb.Metadata{
+						Items: []*computepb.Items{
+							{Key: proto.String("constellation-node-labels"), Value: proto.String("pool=spot")},
+							{Key: proto.String("constellation-node-taints"), Value: proto.String("pool=spot:NoSchedule")},
+						},
+					},
+				},
+			},
+			wantTemplate: updatev1alpha1.NodeTemplate{
+				Labels: map[string]string{"pool": "spot"},
+				Taints: []corev1.Taint{{Key: "pool", Value: "spot", Effect: corev1.TaintEffectNoSchedule}},
+			},
+		},
+		"no worker group": {
+			instanceTemplate: &computepb.InstanceTemplate{
+				Properties: &computepb.InstanceProperties{},
+			},
+		},
+		"get instance template fails": {
+			getInstanceTemplateErr: errors.New("get instance template error"),
+			wantErr:                true,
+		},
+	}
+
+	for name, tc := range testCases {
+		t.Run(name, func(t *testing.T) {
+			assert := assert.New(t)
+			require := require.New(t)
+
+			client := Client{
+				instanceGroupManagersAPI: &stubInstanceGroupManagersAPI{
+					instanceGroupManager: &computepb.InstanceGroupManager{
+						InstanceTemplate: proto.String("projects/project/global/instanceTemplates/instance-template"),
+					},
+				},
+				instanceTemplateAPI: &stubInstanceTemplateAPI{
+					getErr:   tc.getInstanceTemplateErr,
+					template: tc.instanceTemplate,
+				},
+			}
+			template, err := client.GetScalingGroupNodeTemplate(context.Background(), "projects/project/zones/zone/instanceGroupManagers/instance-group")
+			if tc.wantErr {
+				assert.Error(err)
+				return
+			}
+			require.NoError(err)
+			assert.Equal(tc.wantTemplate, template)
+		})
+	}
+}
+
 func TestSetScalingGroupImage(t *testing.T) {
 	testCases := map[string]struct {
 		scalingGroupID                 string
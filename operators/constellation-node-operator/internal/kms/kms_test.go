@@ -0,0 +1,70 @@
+/*
+Copyright (c) Edgeless Systems GmbH
+
+SPDX-License-Identifier: AGPL-3.0-only
+*/
+
+package kms
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/test/bufconn"
+
+	"github.com/edgelesssys/constellation/operators/constellation-node-operator/v2/internal/kms/kmsproto"
+)
+
+func TestGetDataKey(t *testing.T) {
+	testCases := map[string]struct {
+		grpc    *stubGRPCClient
+		wantErr bool
+	}{
+		"GetDataKey success": {
+			grpc: &stubGRPCClient{dataKey: []byte{0x1, 0x2, 0x3}},
+		},
+		"GetDataKey error": {
+			grpc:    &stubGRPCClient{getDataKeyErr: errors.New("error")},
+			wantErr: true,
+		},
+	}
+
+	for name, tc := range testCases {
+		t.Run(name, func(t *testing.T) {
+			assert := assert.New(t)
+
+			listener := bufconn.Listen(1)
+			defer listener.Close()
+
+			client := &Client{
+				endpoint: listener.Addr().String(),
+				grpc:     tc.grpc,
+			}
+			key, err := client.GetDataKey(context.Background(), "data-key", 32)
+			if tc.wantErr {
+				assert.Error(err)
+				return
+			}
+			assert.NoError(err)
+			assert.Equal(tc.grpc.dataKey, key)
+			assert.Equal("data-key", tc.grpc.keyID)
+			assert.Equal(uint32(32), tc.grpc.length)
+		})
+	}
+}
+
+type stubGRPCClient struct {
+	dataKey       []byte
+	getDataKeyErr error
+	keyID         string
+	length        uint32
+}
+
+func (c *stubGRPCClient) GetDataKey(_ context.Context, req *kmsproto.GetDataKeyRequest, _ *grpc.ClientConn) (*kmsproto.GetDataKeyResponse, error) {
+	c.keyID = req.DataKeyId
+	c.length = req.Length
+	return &kmsproto.GetDataKeyResponse{DataKey: c.dataKey}, c.getDataKeyErr
+}
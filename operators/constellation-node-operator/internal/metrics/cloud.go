@@ -0,0 +1,135 @@
+/*
+Copyright (c) Edgeless Systems GmbH
+
+SPDX-License-Identifier: AGPL-3.0-only
+*/
+
+package metrics
+
+import (
+	"context"
+
+	updatev1alpha1 "github.com/edgelesssys/constellation/operators/constellation-node-operator/v2/api/v1alpha1"
+)
+
+// CloudClient wraps a cloud provider client and counts failed calls in CloudAPIErrors.
+type CloudClient struct {
+	client cloudClient
+}
+
+// NewCloudClient creates a new CloudClient wrapping the given cloud provider client.
+func NewCloudClient(client cloudClient) *CloudClient {
+	return &CloudClient{client: client}
+}
+
+// GetNodeImage retrieves the image currently used by a node.
+func (c *CloudClient) GetNodeImage(ctx context.Context, providerID string) (string, error) {
+	image, err := c.client.GetNodeImage(ctx, providerID)
+	countError("GetNodeImage", err)
+	return image, err
+}
+
+// GetScalingGroupID retrieves the scaling group that a node is part of.
+func (c *CloudClient) GetScalingGroupID(ctx context.Context, providerID string) (string, error) {
+	scalingGroupID, err := c.client.GetScalingGroupID(ctx, providerID)
+	countError("GetScalingGroupID", err)
+	return scalingGroupID, err
+}
+
+// CreateNode creates a new node inside a specified scaling group at the CSP and returns its future name and provider id.
+func (c *CloudClient) CreateNode(ctx context.Context, scalingGroupID string) (nodeName, providerID string, err error) {
+	nodeName, providerID, err = c.client.CreateNode(ctx, scalingGroupID)
+	countError("CreateNode", err)
+	return nodeName, providerID, err
+}
+
+// DeleteNode starts the termination of the node at the CSP.
+func (c *CloudClient) DeleteNode(ctx context.Context, providerID string) error {
+	err := c.client.DeleteNode(ctx, providerID)
+	countError("DeleteNode", err)
+	return err
+}
+
+// GetNodeState retrieves the state of a pending node from a CSP.
+func (c *CloudClient) GetNodeState(ctx context.Context, providerID string) (updatev1alpha1.CSPNodeState, error) {
+	state, err := c.client.GetNodeState(ctx, providerID)
+	countError("GetNodeState", err)
+	return state, err
+}
+
+// GetScalingGroupImage retrieves the image currently used by a scaling group.
+func (c *CloudClient) GetScalingGroupImage(ctx context.Context, scalingGroupID string) (string, error) {
+	image, err := c.client.GetScalingGroupImage(ctx, scalingGroupID)
+	countError("GetScalingGroupImage", err)
+	return image, err
+}
+
+// SetScalingGroupImage sets the image to be used by newly created nodes in a scaling group.
+func (c *CloudClient) SetScalingGroupImage(ctx context.Context, scalingGroupID, imageURI string) error {
+	err := c.client.SetScalingGroupImage(ctx, scalingGroupID, imageURI)
+	countError("SetScalingGroupImage", err)
+	return err
+}
+
+// GetScalingGroupSize retrieves the minimum and maximum number of nodes of a worker group.
+func (c *CloudClient) GetScalingGroupSize(ctx context.Context, scalingGroupID string) (min, max int32, err error) {
+	min, max, err = c.client.GetScalingGroupSize(ctx, scalingGroupID)
+	countError("GetScalingGroupSize", err)
+	return min, max, err
+}
+
+// GetScalingGroupNodeTemplate retrieves the labels and taints of the nodes of a scaling group.
+func (c *CloudClient) GetScalingGroupNodeTemplate(ctx context.Context, scalingGroupID string) (updatev1alpha1.NodeTemplate, error) {
+	template, err := c.client.GetScalingGroupNodeTemplate(ctx, scalingGroupID)
+	countError("GetScalingGroupNodeTemplate", err)
+	return template, err
+}
+
+// GetScalingGroupName retrieves the name of a scaling group.
+func (c *CloudClient) GetScalingGroupName(scalingGroupID string) (string, error) {
+	name, err := c.client.GetScalingGroupName(scalingGroupID)
+	countError("GetScalingGroupName", err)
+	return name, err
+}
+
+// GetAutoscalingGroupName retrieves the name of a scaling group as needed by the cluster-autoscaler.
+func (c *CloudClient) GetAutoscalingGroupName(scalingGroupID string) (string, error) {
+	name, err := c.client.GetAutoscalingGroupName(scalingGroupID)
+	countError("GetAutoscalingGroupName", err)
+	return name, err
+}
+
+// ListScalingGroups retrieves a list of scaling groups for the cluster.
+func (c *CloudClient) ListScalingGroups(ctx context.Context, uid string) (controlPlaneGroupIDs []string, workerGroupIDs []string, err error) {
+	controlPlaneGroupIDs, workerGroupIDs, err = c.client.ListScalingGroups(ctx, uid)
+	countError("ListScalingGroups", err)
+	return controlPlaneGroupIDs, workerGroupIDs, err
+}
+
+// AutoscalingCloudProvider returns the cloud-provider name as used by k8s cluster-autoscaler.
+func (c *CloudClient) AutoscalingCloudProvider() string {
+	return c.client.AutoscalingCloudProvider()
+}
+
+// countError increments CloudAPIErrors for the operation if err is not nil.
+func countError(operation string, err error) {
+	if err != nil {
+		CloudAPIErrors.WithLabelValues(operation).Inc()
+	}
+}
+
+type cloudClient interface {
+	GetNodeImage(ctx context.Context, providerID string) (string, error)
+	GetScalingGroupID(ctx context.Context, providerID string) (string, error)
+	CreateNode(ctx context.Context, scalingGroupID string) (nodeName, providerID string, err error)
+	DeleteNode(ctx context.Context, providerID string) error
+	GetNodeState(ctx context.Context, providerID string) (updatev1alpha1.CSPNodeState, error)
+	GetScalingGroupImage(ctx context.Context, scalingGroupID string) (string, error)
+	SetScalingGroupImage(ctx context.Context, scalingGroupID, imageURI string) error
+	GetScalingGroupSize(ctx context.Context, scalingGroupID string) (min, max int32, err error)
+	GetScalingGroupNodeTemplate(ctx context.Context, scalingGroupID string) (updatev1alpha1.NodeTemplate, error)
+	GetScalingGroupName(scalingGroupID string) (string, error)
+	GetAutoscalingGroupName(scalingGroupID string) (string, error)
+	ListScalingGroups(ctx context.Context, uid string) (controlPlaneGroupIDs []string, workerGroupIDs []string, err error)
+	AutoscalingCloudProvider() string
+}
@@ -0,0 +1,44 @@
+/*
+Copyright (c) Edgeless Systems GmbH
+
+SPDX-License-Identifier: AGPL-3.0-only
+*/
+
+package metrics
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/prometheus/client_golang/prometheus/testutil"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestCloudClientCountsErrors(t *testing.T) {
+	assert := assert.New(t)
+	someErr := errors.New("failed")
+	client := NewCloudClient(&stubCloudClient{createErr: someErr})
+
+	before := testutil.ToFloat64(CloudAPIErrors.WithLabelValues("CreateNode"))
+	_, _, err := client.CreateNode(context.Background(), "scaling-group")
+	assert.ErrorIs(err, someErr)
+	assert.Equal(before+1, testutil.ToFloat64(CloudAPIErrors.WithLabelValues("CreateNode")))
+
+	before = testutil.ToFloat64(CloudAPIErrors.WithLabelValues("DeleteNode"))
+	assert.NoError(client.DeleteNode(context.Background(), "provider-id"))
+	assert.Equal(before, testutil.ToFloat64(CloudAPIErrors.WithLabelValues("DeleteNode")))
+}
+
+type stubCloudClient struct {
+	createErr error
+	cloudClient
+}
+
+func (c *stubCloudClient) CreateNode(_ context.Context, _ string) (nodeName, providerID string, err error) {
+	return "node", "provider-id", c.createErr
+}
+
+func (c *stubCloudClient) DeleteNode(_ context.Context, _ string) error {
+	return nil
+}
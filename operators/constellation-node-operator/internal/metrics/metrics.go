@@ -0,0 +1,88 @@
+/*
+Copyright (c) Edgeless Systems GmbH
+
+SPDX-License-Identifier: AGPL-3.0-only
+*/
+
+// Package metrics defines the Prometheus metrics exposed by the node operator.
+// The metrics are registered with the controller-runtime registry and served on the manager's metrics endpoint.
+package metrics
+
+import (
+	"github.com/prometheus/client_golang/prometheus"
+	"sigs.k8s.io/controller-runtime/pkg/metrics"
+)
+
+const namespace = "constellation_node_operator"
+
+// Node states used as values of the state label of the Nodes metric.
+const (
+	NodeStateOutdated = "outdated"
+	NodeStateUpToDate = "up_to_date"
+	NodeStatePending  = "pending"
+	NodeStateInvalid  = "invalid"
+)
+
+// Results used as values of the result label of the EtcdMemberOperations metric.
+const (
+	ResultSuccess = "success"
+	ResultError   = "error"
+)
+
+var (
+	// Nodes is the number of nodes per scaling group and state.
+	Nodes = prometheus.NewGaugeVec(
+		prometheus.GaugeOpts{
+			Namespace: namespace,
+			Name:      "nodes",
+			Help:      "Number of nodes per scaling group and state (outdated, up_to_date, pending, invalid).",
+		},
+		[]string{"scaling_group", "state"},
+	)
+	// NodeReplacementDuration is the time it took to replace an outdated node, from the heir joining until the donor is deleted.
+	NodeReplacementDuration = prometheus.NewHistogramVec(
+		prometheus.HistogramOpts{
+			Namespace: namespace,
+			Name:      "node_replacement_duration_seconds",
+			Help:      "Time from an heir node joining the cluster until its outdated donor node is deleted.",
+			// 1m up to ~8.5h
+			Buckets: prometheus.ExponentialBuckets(60, 2, 10),
+		},
+		[]string{"scaling_group"},
+	)
+	// CloudAPIErrors is the number of failed calls to the cloud provider API.
+	CloudAPIErrors = prometheus.NewCounterVec(
+		prometheus.CounterOpts{
+			Namespace: namespace,
+			Name:      "cloud_api_errors_total",
+			Help:      "Number of failed calls to the cloud provider API per operation.",
+		},
+		[]string{"operation"},
+	)
+	// EtcdMemberOperations is the number of operations on etcd cluster members.
+	EtcdMemberOperations = prometheus.NewCounterVec(
+		prometheus.CounterOpts{
+			Namespace: namespace,
+			Name:      "etcd_member_operations_total",
+			Help:      "Number of operations on etcd cluster members per operation and result.",
+		},
+		[]string{"operation", "result"},
+	)
+)
+
+// Result returns the result label value for an operation that returned err.
+func Result(err error) string {
+	if err != nil {
+		return ResultError
+	}
+	return ResultSuccess
+}
+
+func init() {
+	metrics.Registry.MustRegister(
+		Nodes,
+		NodeReplacementDuration,
+		CloudAPIErrors,
+		EtcdMemberOperations,
+	)
+}
@@ -0,0 +1,119 @@
+/*
+Copyright (c) Edgeless Systems GmbH
+
+SPDX-License-Identifier: AGPL-3.0-only
+*/
+
+package objectstore
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/credentials"
+	"github.com/aws/aws-sdk-go-v2/service/s3"
+	"github.com/aws/aws-sdk-go-v2/service/s3/types"
+)
+
+type awsS3ClientAPI interface {
+	GetObject(ctx context.Context, params *s3.GetObjectInput, optFns ...func(*s3.Options)) (*s3.GetObjectOutput, error)
+	PutObject(ctx context.Context, params *s3.PutObjectInput, optFns ...func(*s3.Options)) (*s3.PutObjectOutput, error)
+	ListObjectsV2(ctx context.Context, params *s3.ListObjectsV2Input, optFns ...func(*s3.Options)) (*s3.ListObjectsV2Output, error)
+	DeleteObject(ctx context.Context, params *s3.DeleteObjectInput, optFns ...func(*s3.Options)) (*s3.DeleteObjectOutput, error)
+}
+
+// S3Store stores objects in an S3-compatible bucket.
+type S3Store struct {
+	bucket string
+	client awsS3ClientAPI
+}
+
+// NewS3Store creates an object store for AWS S3 or any S3-compatible service.
+//
+// If endpoint is empty, the AWS endpoint for the region is used.
+// Otherwise, requests are sent to the given endpoint using path-style addressing.
+// The bucket must already exist.
+func NewS3Store(bucket, region, endpoint, accessKeyID, secretAccessKey string) *S3Store {
+	opts := s3.Options{
+		Region:      region,
+		Credentials: credentials.NewStaticCredentialsProvider(accessKeyID, secretAccessKey, ""),
+	}
+	if endpoint != "" {
+		opts.EndpointResolver = s3.EndpointResolverFromURL(endpoint)
+		opts.UsePathStyle = true
+	}
+	return &S3Store{
+		bucket: bucket,
+		client: s3.New(opts),
+	}
+}
+
+// Put uploads an object.
+func (s *S3Store) Put(ctx context.Context, name string, data io.ReadSeeker) error {
+	if _, err := s.client.PutObject(ctx, &s3.PutObjectInput{
+		Bucket: &s.bucket,
+		Key:    &name,
+		Body:   data,
+	}); err != nil {
+		return fmt.Errorf("uploading object %q: %w", name, err)
+	}
+	return nil
+}
+
+// Get downloads an object.
+func (s *S3Store) Get(ctx context.Context, name string) ([]byte, error) {
+	output, err := s.client.GetObject(ctx, &s3.GetObjectInput{
+		Bucket: &s.bucket,
+		Key:    &name,
+	})
+	if err != nil {
+		var nsk *types.NoSuchKey
+		if errors.As(err, &nsk) {
+			return nil, ErrObjectNotFound
+		}
+		return nil, fmt.Errorf("downloading object %q: %w", name, err)
+	}
+	defer output.Body.Close()
+	return io.ReadAll(output.Body)
+}
+
+// List returns all objects with the given prefix.
+func (s *S3Store) List(ctx context.Context, prefix string) ([]Object, error) {
+	var objects []Object
+	var continuationToken *string
+	for {
+		output, err := s.client.ListObjectsV2(ctx, &s3.ListObjectsV2Input{
+			Bucket:            &s.bucket,
+			Prefix:            &prefix,
+			ContinuationToken: continuationToken,
+		})
+		if err != nil {
+			return nil, fmt.Errorf("listing objects: %w", err)
+		}
+		for _, obj := range output.Contents {
+			objects = append(objects, Object{
+				Name:         aws.ToString(obj.Key),
+				Size:         obj.Size,
+				LastModified: aws.ToTime(obj.LastModified),
+			})
+		}
+		if !output.IsTruncated {
+			return objects, nil
+		}
+		continuationToken = output.NextContinuationToken
+	}
+}
+
+// Delete removes an object.
+func (s *S3Store) Delete(ctx context.Context, name string) error {
+	if _, err := s.client.DeleteObject(ctx, &s3.DeleteObjectInput{
+		Bucket: &s.bucket,
+		Key:    &name,
+	}); err != nil {
+		return fmt.Errorf("deleting object %q: %w", name, err)
+	}
+	return nil
+}
@@ -0,0 +1,192 @@
+/*
+Copyright (c) Edgeless Systems GmbH
+
+SPDX-License-Identifier: AGPL-3.0-only
+*/
+
+package objectstore
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"io"
+	"testing"
+	"time"
+
+	"github.com/aws/aws-sdk-go-v2/service/s3"
+	"github.com/aws/aws-sdk-go-v2/service/s3/types"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestS3Put(t *testing.T) {
+	testCases := map[string]struct {
+		client  *stubAWSS3Client
+		wantErr bool
+	}{
+		"success": {
+			client: &stubAWSS3Client{},
+		},
+		"put fails": {
+			client:  &stubAWSS3Client{putErr: errors.New("failed")},
+			wantErr: true,
+		},
+	}
+
+	for name, tc := range testCases {
+		t.Run(name, func(t *testing.T) {
+			assert := assert.New(t)
+
+			store := &S3Store{bucket: "bucket", client: tc.client}
+			err := store.Put(context.Background(), "object", bytes.NewReader([]byte("data")))
+			if tc.wantErr {
+				assert.Error(err)
+				return
+			}
+			assert.NoError(err)
+			assert.Equal([]byte("data"), tc.client.putData)
+		})
+	}
+}
+
+func TestS3Get(t *testing.T) {
+	testCases := map[string]struct {
+		client      *stubAWSS3Client
+		wantData    []byte
+		wantErr     bool
+		wantErrType error
+	}{
+		"success": {
+			client:   &stubAWSS3Client{getData: []byte("data")},
+			wantData: []byte("data"),
+		},
+		"object not found": {
+			client:      &stubAWSS3Client{getErr: &types.NoSuchKey{}},
+			wantErr:     true,
+			wantErrType: ErrObjectNotFound,
+		},
+		"get fails": {
+			client:  &stubAWSS3Client{getErr: errors.New("failed")},
+			wantErr: true,
+		},
+	}
+
+	for name, tc := range testCases {
+		t.Run(name, func(t *testing.T) {
+			assert := assert.New(t)
+
+			store := &S3Store{bucket: "bucket", client: tc.client}
+			data, err := store.Get(context.Background(), "object")
+			if tc.wantErr {
+				assert.Error(err)
+				if tc.wantErrType != nil {
+					assert.ErrorIs(err, tc.wantErrType)
+				}
+				return
+			}
+			assert.NoError(err)
+			assert.Equal(tc.wantData, data)
+		})
+	}
+}
+
+func TestS3List(t *testing.T) {
+	first, second := "first", "second"
+	modified := time.Date(2022, time.August, 10, 0, 0, 0, 0, time.UTC)
+	token := "token"
+
+	testCases := map[string]struct {
+		client      *stubAWSS3Client
+		wantObjects []Object
+		wantErr     bool
+	}{
+		"single page": {
+			client: &stubAWSS3Client{listPages: []*s3.ListObjectsV2Output{
+				{Contents: []types.Object{{Key: &first, Size: 1, LastModified: &modified}}},
+			}},
+			wantObjects: []Object{{Name: first, Size: 1, LastModified: modified}},
+		},
+		"multiple pages": {
+			client: &stubAWSS3Client{listPages: []*s3.ListObjectsV2Output{
+				{Contents: []types.Object{{Key: &first, Size: 1}}, IsTruncated: true, NextContinuationToken: &token},
+				{Contents: []types.Object{{Key: &second, Size: 2}}},
+			}},
+			wantObjects: []Object{{Name: first, Size: 1}, {Name: second, Size: 2}},
+		},
+		"list fails": {
+			client:  &stubAWSS3Client{listErr: errors.New("failed")},
+			wantErr: true,
+		},
+	}
+
+	for name, tc := range testCases {
+		t.Run(name, func(t *testing.T) {
+			assert := assert.New(t)
+
+			store := &S3Store{bucket: "bucket", client: tc.client}
+			objects, err := store.List(context.Background(), "")
+			if tc.wantErr {
+				assert.Error(err)
+				return
+			}
+			assert.NoError(err)
+			assert.Equal(tc.wantObjects, objects)
+		})
+	}
+}
+
+func TestS3Delete(t *testing.T) {
+	assert := assert.New(t)
+
+	client := &stubAWSS3Client{}
+	store := &S3Store{bucket: "bucket", client: client}
+	assert.NoError(store.Delete(context.Background(), "object"))
+	assert.Equal([]string{"object"}, client.deleted)
+
+	client.deleteErr = errors.New("failed")
+	assert.Error(store.Delete(context.Background(), "object"))
+}
+
+type stubAWSS3Client struct {
+	putData   []byte
+	putErr    error
+	getData   []byte
+	getErr    error
+	listPages []*s3.ListObjectsV2Output
+	listErr   error
+	deleted   []string
+	deleteErr error
+}
+
+func (s *stubAWSS3Client) GetObject(ctx context.Context, params *s3.GetObjectInput, optFns ...func(*s3.Options)) (*s3.GetObjectOutput, error) {
+	if s.getErr != nil {
+		return nil, s.getErr
+	}
+	return &s3.GetObjectOutput{Body: io.NopCloser(bytes.NewReader(s.getData))}, nil
+}
+
+func (s *stubAWSS3Client) PutObject(ctx context.Context, params *s3.PutObjectInput, optFns ...func(*s3.Options)) (*s3.PutObjectOutput, error) {
+	data, err := io.ReadAll(params.Body)
+	if err != nil {
+		return nil, err
+	}
+	s.putData = data
+	return &s3.PutObjectOutput{}, s.putErr
+}
+
+func (s *stubAWSS3Client) ListObjectsV2(ctx context.Context, params *s3.ListObjectsV2Input, optFns ...func(*s3.Options)) (*s3.ListObjectsV2Output, error) {
+	if s.listErr != nil {
+		return nil, s.listErr
+	}
+	page := s.listPages[0]
+	s.listPages = s.listPages[1:]
+	return page, nil
+}
+
+func (s *stubAWSS3Client) DeleteObject(ctx context.Context, params *s3.DeleteObjectInput, optFns ...func(*s3.Options)) (*s3.DeleteObjectOutput, error) {
+	if s.deleteErr != nil {
+		return nil, s.deleteErr
+	}
+	s.deleted = append(s.deleted, *params.Key)
+	return &s3.DeleteObjectOutput{}, nil
+}
@@ -0,0 +1,144 @@
+/*
+Copyright (c) Edgeless Systems GmbH
+
+SPDX-License-Identifier: AGPL-3.0-only
+*/
+
+package objectstore
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+
+	"github.com/Azure/azure-sdk-for-go/sdk/storage/azblob"
+)
+
+type azureContainerAPI interface {
+	NewBlockBlobClient(blobName string) (azureBlobAPI, error)
+	ListBlobs(ctx context.Context, prefix string) ([]*azblob.BlobItemInternal, error)
+}
+
+type azureBlobAPI interface {
+	Upload(ctx context.Context, body io.ReadSeekCloser, options *azblob.BlockBlobUploadOptions) (azblob.BlockBlobUploadResponse, error)
+	Download(ctx context.Context, options *azblob.BlobDownloadOptions) (azblob.BlobDownloadResponse, error)
+	Delete(ctx context.Context, options *azblob.BlobDeleteOptions) (azblob.BlobDeleteResponse, error)
+}
+
+type wrappedAzureClient struct {
+	*azblob.ContainerClient
+}
+
+func (c wrappedAzureClient) NewBlockBlobClient(blobName string) (azureBlobAPI, error) {
+	return c.ContainerClient.NewBlockBlobClient(blobName)
+}
+
+func (c wrappedAzureClient) ListBlobs(ctx context.Context, prefix string) ([]*azblob.BlobItemInternal, error) {
+	var blobs []*azblob.BlobItemInternal
+	pager := c.ContainerClient.ListBlobsFlat(&azblob.ContainerListBlobsFlatOptions{Prefix: &prefix})
+	for pager.NextPage(ctx) {
+		if segment := pager.PageResponse().Segment; segment != nil {
+			blobs = append(blobs, segment.BlobItems...)
+		}
+	}
+	return blobs, pager.Err()
+}
+
+// AzureBlobStore stores objects in an Azure Blob Storage container.
+type AzureBlobStore struct {
+	client azureContainerAPI
+}
+
+// NewAzureBlobStore creates an object store for Azure Blob Storage: https://azure.microsoft.com/en-us/services/storage/blobs/
+//
+// A connection string is required to connect to the Storage Account, see https://docs.microsoft.com/en-us/azure/storage/common/storage-configure-connection-string
+// The container must already exist.
+func NewAzureBlobStore(connectionString, containerName string) (*AzureBlobStore, error) {
+	service, err := azblob.NewServiceClientFromConnectionString(connectionString, nil)
+	if err != nil {
+		return nil, fmt.Errorf("creating storage client from connection string: %w", err)
+	}
+	containerClient, err := service.NewContainerClient(containerName)
+	if err != nil {
+		return nil, fmt.Errorf("creating storage container client: %w", err)
+	}
+	return &AzureBlobStore{client: wrappedAzureClient{containerClient}}, nil
+}
+
+// Put uploads an object.
+func (s *AzureBlobStore) Put(ctx context.Context, name string, data io.ReadSeeker) error {
+	blob, err := s.client.NewBlockBlobClient(name)
+	if err != nil {
+		return err
+	}
+	if _, err := blob.Upload(ctx, readSeekNopCloser{data}, nil); err != nil {
+		return fmt.Errorf("uploading object %q: %w", name, err)
+	}
+	return nil
+}
+
+// Get downloads an object.
+func (s *AzureBlobStore) Get(ctx context.Context, name string) ([]byte, error) {
+	blob, err := s.client.NewBlockBlobClient(name)
+	if err != nil {
+		return nil, err
+	}
+	resp, err := blob.Download(ctx, nil)
+	if err != nil {
+		var storeErr *azblob.StorageError
+		if errors.As(err, &storeErr) && (storeErr.ErrorCode == azblob.StorageErrorCodeBlobNotFound) {
+			return nil, ErrObjectNotFound
+		}
+		return nil, fmt.Errorf("downloading object %q: %w", name, err)
+	}
+	body := resp.Body(&azblob.RetryReaderOptions{MaxRetryRequests: 5, TreatEarlyCloseAsError: true})
+	defer body.Close()
+	return io.ReadAll(body)
+}
+
+// List returns all objects with the given prefix.
+func (s *AzureBlobStore) List(ctx context.Context, prefix string) ([]Object, error) {
+	blobs, err := s.client.ListBlobs(ctx, prefix)
+	if err != nil {
+		return nil, fmt.Errorf("listing objects: %w", err)
+	}
+	objects := make([]Object, 0, len(blobs))
+	for _, blob := range blobs {
+		if blob == nil || blob.Name == nil {
+			continue
+		}
+		object := Object{Name: *blob.Name}
+		if blob.Properties != nil {
+			if blob.Properties.ContentLength != nil {
+				object.Size = *blob.Properties.ContentLength
+			}
+			if blob.Properties.LastModified != nil {
+				object.LastModified = *blob.Properties.LastModified
+			}
+		}
+		objects = append(objects, object)
+	}
+	return objects, nil
+}
+
+// Delete removes an object.
+func (s *AzureBlobStore) Delete(ctx context.Context, name string) error {
+	blob, err := s.client.NewBlockBlobClient(name)
+	if err != nil {
+		return err
+	}
+	if _, err := blob.Delete(ctx, nil); err != nil {
+		return fmt.Errorf("deleting object %q: %w", name, err)
+	}
+	return nil
+}
+
+// readSeekNopCloser is a wrapper for io.ReadSeeker implementing the Close method. This is required by the Azure SDK.
+type readSeekNopCloser struct {
+	io.ReadSeeker
+}
+
+func (n readSeekNopCloser) Close() error {
+	return nil
+}
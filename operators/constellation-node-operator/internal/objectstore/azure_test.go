@@ -0,0 +1,200 @@
+/*
+Copyright (c) Edgeless Systems GmbH
+
+SPDX-License-Identifier: AGPL-3.0-only
+*/
+
+package objectstore
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"io"
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/Azure/azure-sdk-for-go/sdk/storage/azblob"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestAzurePut(t *testing.T) {
+	someErr := errors.New("failed")
+
+	testCases := map[string]struct {
+		client  *stubAzureContainerAPI
+		wantErr bool
+	}{
+		"success": {
+			client: &stubAzureContainerAPI{blob: &stubAzureBlobAPI{}},
+		},
+		"creating blob client fails": {
+			client:  &stubAzureContainerAPI{newBlobErr: someErr},
+			wantErr: true,
+		},
+		"upload fails": {
+			client:  &stubAzureContainerAPI{blob: &stubAzureBlobAPI{uploadErr: someErr}},
+			wantErr: true,
+		},
+	}
+
+	for name, tc := range testCases {
+		t.Run(name, func(t *testing.T) {
+			assert := assert.New(t)
+
+			store := &AzureBlobStore{client: tc.client}
+			err := store.Put(context.Background(), "object", bytes.NewReader([]byte("data")))
+			if tc.wantErr {
+				assert.Error(err)
+				return
+			}
+			assert.NoError(err)
+			assert.Equal([]byte("data"), tc.client.blob.uploaded)
+		})
+	}
+}
+
+func TestAzureGet(t *testing.T) {
+	testCases := map[string]struct {
+		client      *stubAzureContainerAPI
+		wantData    []byte
+		wantErr     bool
+		wantErrType error
+	}{
+		"success": {
+			client:   &stubAzureContainerAPI{blob: &stubAzureBlobAPI{downloadData: []byte("data")}},
+			wantData: []byte("data"),
+		},
+		"creating blob client fails": {
+			client:  &stubAzureContainerAPI{newBlobErr: errors.New("failed")},
+			wantErr: true,
+		},
+		"blob not found": {
+			client: &stubAzureContainerAPI{blob: &stubAzureBlobAPI{
+				downloadErr: &azblob.StorageError{ErrorCode: azblob.StorageErrorCodeBlobNotFound},
+			}},
+			wantErr:     true,
+			wantErrType: ErrObjectNotFound,
+		},
+		"download fails": {
+			client:  &stubAzureContainerAPI{blob: &stubAzureBlobAPI{downloadErr: errors.New("failed")}},
+			wantErr: true,
+		},
+	}
+
+	for name, tc := range testCases {
+		t.Run(name, func(t *testing.T) {
+			assert := assert.New(t)
+
+			store := &AzureBlobStore{client: tc.client}
+			data, err := store.Get(context.Background(), "object")
+			if tc.wantErr {
+				assert.Error(err)
+				if tc.wantErrType != nil {
+					assert.ErrorIs(err, tc.wantErrType)
+				}
+				return
+			}
+			assert.NoError(err)
+			assert.Equal(tc.wantData, data)
+		})
+	}
+}
+
+func TestAzureList(t *testing.T) {
+	name := "snapshot"
+	size := int64(42)
+	modified := time.Date(2022, time.August, 10, 0, 0, 0, 0, time.UTC)
+
+	testCases := map[string]struct {
+		client      *stubAzureContainerAPI
+		wantObjects []Object
+		wantErr     bool
+	}{
+		"success": {
+			client: &stubAzureContainerAPI{blobs: []*azblob.BlobItemInternal{
+				{Name: &name, Properties: &azblob.BlobPropertiesInternal{ContentLength: &size, LastModified: &modified}},
+				nil,
+			}},
+			wantObjects: []Object{{Name: name, Size: size, LastModified: modified}},
+		},
+		"list fails": {
+			client:  &stubAzureContainerAPI{listErr: errors.New("failed")},
+			wantErr: true,
+		},
+	}
+
+	for name, tc := range testCases {
+		t.Run(name, func(t *testing.T) {
+			assert := assert.New(t)
+
+			store := &AzureBlobStore{client: tc.client}
+			objects, err := store.List(context.Background(), "")
+			if tc.wantErr {
+				assert.Error(err)
+				return
+			}
+			assert.NoError(err)
+			assert.Equal(tc.wantObjects, objects)
+		})
+	}
+}
+
+func TestAzureDelete(t *testing.T) {
+	require := require.New(t)
+	assert := assert.New(t)
+
+	blob := &stubAzureBlobAPI{}
+	store := &AzureBlobStore{client: &stubAzureContainerAPI{blob: blob}}
+	require.NoError(store.Delete(context.Background(), "object"))
+	assert.True(blob.deleted)
+
+	blob.deleteErr = errors.New("failed")
+	assert.Error(store.Delete(context.Background(), "object"))
+}
+
+type stubAzureContainerAPI struct {
+	blob       *stubAzureBlobAPI
+	newBlobErr error
+	blobs      []*azblob.BlobItemInternal
+	listErr    error
+}
+
+func (s *stubAzureContainerAPI) NewBlockBlobClient(blobName string) (azureBlobAPI, error) {
+	return s.blob, s.newBlobErr
+}
+
+func (s *stubAzureContainerAPI) ListBlobs(ctx context.Context, prefix string) ([]*azblob.BlobItemInternal, error) {
+	return s.blobs, s.listErr
+}
+
+type stubAzureBlobAPI struct {
+	uploaded     []byte
+	uploadErr    error
+	downloadData []byte
+	downloadErr  error
+	deleted      bool
+	deleteErr    error
+}
+
+func (s *stubAzureBlobAPI) Upload(ctx context.Context, body io.ReadSeekCloser, options *azblob.BlockBlobUploadOptions) (azblob.BlockBlobUploadResponse, error) {
+	data, err := io.ReadAll(body)
+	if err != nil {
+		return azblob.BlockBlobUploadResponse{}, err
+	}
+	s.uploaded = data
+	return azblob.BlockBlobUploadResponse{}, s.uploadErr
+}
+
+func (s *stubAzureBlobAPI) Download(ctx context.Context, options *azblob.BlobDownloadOptions) (azblob.BlobDownloadResponse, error) {
+	resp := azblob.BlobDownloadResponse{}
+	resp.RawResponse = &http.Response{Body: io.NopCloser(bytes.NewReader(s.downloadData))}
+	return resp, s.downloadErr
+}
+
+func (s *stubAzureBlobAPI) Delete(ctx context.Context, options *azblob.BlobDeleteOptions) (azblob.BlobDeleteResponse, error) {
+	s.deleted = true
+	return azblob.BlobDeleteResponse{}, s.deleteErr
+}
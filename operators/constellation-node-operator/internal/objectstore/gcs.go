@@ -0,0 +1,159 @@
+/*
+Copyright (c) Edgeless Systems GmbH
+
+SPDX-License-Identifier: AGPL-3.0-only
+*/
+
+package objectstore
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+
+	"cloud.google.com/go/storage"
+	"google.golang.org/api/iterator"
+	"google.golang.org/api/option"
+)
+
+type gcpStorageAPI interface {
+	NewWriter(ctx context.Context, bucketName, objectName string) io.WriteCloser
+	NewReader(ctx context.Context, bucketName, objectName string) (io.ReadCloser, error)
+	List(ctx context.Context, bucketName, prefix string) ([]*storage.ObjectAttrs, error)
+	Delete(ctx context.Context, bucketName, objectName string) error
+	Close() error
+}
+
+type wrappedGCPClient struct {
+	*storage.Client
+}
+
+func (c *wrappedGCPClient) NewWriter(ctx context.Context, bucketName, objectName string) io.WriteCloser {
+	return c.Client.Bucket(bucketName).Object(objectName).NewWriter(ctx)
+}
+
+func (c *wrappedGCPClient) NewReader(ctx context.Context, bucketName, objectName string) (io.ReadCloser, error) {
+	return c.Client.Bucket(bucketName).Object(objectName).NewReader(ctx)
+}
+
+func (c *wrappedGCPClient) List(ctx context.Context, bucketName, prefix string) ([]*storage.ObjectAttrs, error) {
+	var objects []*storage.ObjectAttrs
+	it := c.Client.Bucket(bucketName).Objects(ctx, &storage.Query{Prefix: prefix})
+	for {
+		attrs, err := it.Next()
+		if errors.Is(err, iterator.Done) {
+			return objects, nil
+		}
+		if err != nil {
+			return nil, err
+		}
+		objects = append(objects, attrs)
+	}
+}
+
+func (c *wrappedGCPClient) Delete(ctx context.Context, bucketName, objectName string) error {
+	return c.Client.Bucket(bucketName).Object(objectName).Delete(ctx)
+}
+
+// GCSStore stores objects in a Google Cloud Storage bucket.
+type GCSStore struct {
+	newClient  func(ctx context.Context, opts ...option.ClientOption) (gcpStorageAPI, error)
+	bucketName string
+	opts       []option.ClientOption
+}
+
+// NewGCSStore creates an object store for Google Cloud Storage: https://cloud.google.com/storage/docs/
+//
+// serviceAccountKey is the JSON key of a service account with access to the bucket.
+// The bucket must already exist.
+func NewGCSStore(bucketName string, serviceAccountKey []byte) *GCSStore {
+	return &GCSStore{
+		newClient:  gcpStorageClientFactory,
+		bucketName: bucketName,
+		opts:       []option.ClientOption{option.WithCredentialsJSON(serviceAccountKey)},
+	}
+}
+
+// Put uploads an object.
+func (s *GCSStore) Put(ctx context.Context, name string, data io.ReadSeeker) error {
+	client, err := s.newClient(ctx, s.opts...)
+	if err != nil {
+		return err
+	}
+	defer client.Close()
+
+	writer := client.NewWriter(ctx, s.bucketName, name)
+	if _, err := io.Copy(writer, data); err != nil {
+		_ = writer.Close()
+		return fmt.Errorf("uploading object %q: %w", name, err)
+	}
+	if err := writer.Close(); err != nil {
+		return fmt.Errorf("uploading object %q: %w", name, err)
+	}
+	return nil
+}
+
+// Get downloads an object.
+func (s *GCSStore) Get(ctx context.Context, name string) ([]byte, error) {
+	client, err := s.newClient(ctx, s.opts...)
+	if err != nil {
+		return nil, err
+	}
+	defer client.Close()
+
+	reader, err := client.NewReader(ctx, s.bucketName, name)
+	if err != nil {
+		if errors.Is(err, storage.ErrObjectNotExist) {
+			return nil, ErrObjectNotFound
+		}
+		return nil, fmt.Errorf("downloading object %q: %w", name, err)
+	}
+	defer reader.Close()
+	return io.ReadAll(reader)
+}
+
+// List returns all objects with the given prefix.
+func (s *GCSStore) List(ctx context.Context, prefix string) ([]Object, error) {
+	client, err := s.newClient(ctx, s.opts...)
+	if err != nil {
+		return nil, err
+	}
+	defer client.Close()
+
+	attrs, err := client.List(ctx, s.bucketName, prefix)
+	if err != nil {
+		return nil, fmt.Errorf("listing objects: %w", err)
+	}
+	objects := make([]Object, 0, len(attrs))
+	for _, attr := range attrs {
+		objects = append(objects, Object{
+			Name:         attr.Name,
+			Size:         attr.Size,
+			LastModified: attr.Updated,
+		})
+	}
+	return objects, nil
+}
+
+// Delete removes an object.
+func (s *GCSStore) Delete(ctx context.Context, name string) error {
+	client, err := s.newClient(ctx, s.opts...)
+	if err != nil {
+		return err
+	}
+	defer client.Close()
+
+	if err := client.Delete(ctx, s.bucketName, name); err != nil {
+		return fmt.Errorf("deleting object %q: %w", name, err)
+	}
+	return nil
+}
+
+func gcpStorageClientFactory(ctx context.Context, opts ...option.ClientOption) (gcpStorageAPI, error) {
+	client, err := storage.NewClient(ctx, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return &wrappedGCPClient{client}, nil
+}
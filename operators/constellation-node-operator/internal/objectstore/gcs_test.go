@@ -0,0 +1,214 @@
+/*
+Copyright (c) Edgeless Systems GmbH
+
+SPDX-License-Identifier: AGPL-3.0-only
+*/
+
+package objectstore
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"io"
+	"testing"
+	"time"
+
+	"cloud.google.com/go/storage"
+	"github.com/stretchr/testify/assert"
+	"google.golang.org/api/option"
+)
+
+func TestGCSPut(t *testing.T) {
+	someErr := errors.New("failed")
+
+	testCases := map[string]struct {
+		client       *stubGCPStorageAPI
+		newClientErr error
+		wantErr      bool
+	}{
+		"success": {
+			client: &stubGCPStorageAPI{writer: &stubWriteCloser{}},
+		},
+		"creating client fails": {
+			client:       &stubGCPStorageAPI{},
+			newClientErr: someErr,
+			wantErr:      true,
+		},
+		"write fails": {
+			client:  &stubGCPStorageAPI{writer: &stubWriteCloser{writeErr: someErr}},
+			wantErr: true,
+		},
+		"close fails": {
+			client:  &stubGCPStorageAPI{writer: &stubWriteCloser{closeErr: someErr}},
+			wantErr: true,
+		},
+	}
+
+	for name, tc := range testCases {
+		t.Run(name, func(t *testing.T) {
+			assert := assert.New(t)
+
+			store := &GCSStore{newClient: newStubGCPClientFactory(tc.client, tc.newClientErr), bucketName: "bucket"}
+			err := store.Put(context.Background(), "object", bytes.NewReader([]byte("data")))
+			if tc.wantErr {
+				assert.Error(err)
+				return
+			}
+			assert.NoError(err)
+			assert.Equal([]byte("data"), tc.client.writer.Bytes())
+			assert.True(tc.client.closed)
+		})
+	}
+}
+
+func TestGCSGet(t *testing.T) {
+	testCases := map[string]struct {
+		client      *stubGCPStorageAPI
+		wantData    []byte
+		wantErr     bool
+		wantErrType error
+	}{
+		"success": {
+			client:   &stubGCPStorageAPI{readData: []byte("data")},
+			wantData: []byte("data"),
+		},
+		"object not found": {
+			client:      &stubGCPStorageAPI{readErr: storage.ErrObjectNotExist},
+			wantErr:     true,
+			wantErrType: ErrObjectNotFound,
+		},
+		"read fails": {
+			client:  &stubGCPStorageAPI{readErr: errors.New("failed")},
+			wantErr: true,
+		},
+	}
+
+	for name, tc := range testCases {
+		t.Run(name, func(t *testing.T) {
+			assert := assert.New(t)
+
+			store := &GCSStore{newClient: newStubGCPClientFactory(tc.client, nil), bucketName: "bucket"}
+			data, err := store.Get(context.Background(), "object")
+			if tc.wantErr {
+				assert.Error(err)
+				if tc.wantErrType != nil {
+					assert.ErrorIs(err, tc.wantErrType)
+				}
+				return
+			}
+			assert.NoError(err)
+			assert.Equal(tc.wantData, data)
+		})
+	}
+}
+
+func TestGCSList(t *testing.T) {
+	modified := time.Date(2022, time.August, 10, 0, 0, 0, 0, time.UTC)
+
+	testCases := map[string]struct {
+		client      *stubGCPStorageAPI
+		wantObjects []Object
+		wantErr     bool
+	}{
+		"success": {
+			client: &stubGCPStorageAPI{objects: []*storage.ObjectAttrs{
+				{Name: "snapshot", Size: 42, Updated: modified},
+			}},
+			wantObjects: []Object{{Name: "snapshot", Size: 42, LastModified: modified}},
+		},
+		"list fails": {
+			client:  &stubGCPStorageAPI{listErr: errors.New("failed")},
+			wantErr: true,
+		},
+	}
+
+	for name, tc := range testCases {
+		t.Run(name, func(t *testing.T) {
+			assert := assert.New(t)
+
+			store := &GCSStore{newClient: newStubGCPClientFactory(tc.client, nil), bucketName: "bucket"}
+			objects, err := store.List(context.Background(), "")
+			if tc.wantErr {
+				assert.Error(err)
+				return
+			}
+			assert.NoError(err)
+			assert.Equal(tc.wantObjects, objects)
+		})
+	}
+}
+
+func TestGCSDelete(t *testing.T) {
+	assert := assert.New(t)
+
+	client := &stubGCPStorageAPI{}
+	store := &GCSStore{newClient: newStubGCPClientFactory(client, nil), bucketName: "bucket"}
+	assert.NoError(store.Delete(context.Background(), "object"))
+	assert.Equal([]string{"object"}, client.deleted)
+
+	client.deleteErr = errors.New("failed")
+	assert.Error(store.Delete(context.Background(), "object"))
+}
+
+func newStubGCPClientFactory(client *stubGCPStorageAPI, err error) func(ctx context.Context, opts ...option.ClientOption) (gcpStorageAPI, error) {
+	return func(ctx context.Context, opts ...option.ClientOption) (gcpStorageAPI, error) {
+		return client, err
+	}
+}
+
+type stubGCPStorageAPI struct {
+	writer    *stubWriteCloser
+	readData  []byte
+	readErr   error
+	objects   []*storage.ObjectAttrs
+	listErr   error
+	deleted   []string
+	deleteErr error
+	closed    bool
+}
+
+func (s *stubGCPStorageAPI) NewWriter(ctx context.Context, bucketName, objectName string) io.WriteCloser {
+	return s.writer
+}
+
+func (s *stubGCPStorageAPI) NewReader(ctx context.Context, bucketName, objectName string) (io.ReadCloser, error) {
+	if s.readErr != nil {
+		return nil, s.readErr
+	}
+	return io.NopCloser(bytes.NewReader(s.readData)), nil
+}
+
+func (s *stubGCPStorageAPI) List(ctx context.Context, bucketName, prefix string) ([]*storage.ObjectAttrs, error) {
+	return s.objects, s.listErr
+}
+
+func (s *stubGCPStorageAPI) Delete(ctx context.Context, bucketName, objectName string) error {
+	if s.deleteErr != nil {
+		return s.deleteErr
+	}
+	s.deleted = append(s.deleted, objectName)
+	return nil
+}
+
+func (s *stubGCPStorageAPI) Close() error {
+	s.closed = true
+	return nil
+}
+
+type stubWriteCloser struct {
+	bytes.Buffer
+	writeErr error
+	closeErr error
+}
+
+func (s *stubWriteCloser) Write(p []byte) (int, error) {
+	if s.writeErr != nil {
+		return 0, s.writeErr
+	}
+	return s.Buffer.Write(p)
+}
+
+func (s *stubWriteCloser) Close() error {
+	return s.closeErr
+}
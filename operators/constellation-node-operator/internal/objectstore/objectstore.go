@@ -0,0 +1,27 @@
+/*
+Copyright (c) Edgeless Systems GmbH
+
+SPDX-License-Identifier: AGPL-3.0-only
+*/
+
+// Package objectstore implements object storage backends (S3-compatible, Azure Blob and Google Cloud Storage)
+// used to store etcd snapshots outside of the cluster.
+package objectstore
+
+import (
+	"errors"
+	"time"
+)
+
+// ErrObjectNotFound indicates that an object does not exist in the object store.
+var ErrObjectNotFound = errors.New("object not found")
+
+// Object describes an object stored in an object store.
+type Object struct {
+	// Name is the name (key) of the object.
+	Name string
+	// Size is the size of the object in bytes.
+	Size int64
+	// LastModified is the time the object was last written.
+	LastModified time.Time
+}
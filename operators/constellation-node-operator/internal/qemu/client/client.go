@@ -0,0 +1,106 @@
+/*
+Copyright (c) Edgeless Systems GmbH
+
+SPDX-License-Identifier: AGPL-3.0-only
+*/
+
+package client
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strings"
+)
+
+// errNotFound is returned if the QEMU metadata API does not know a requested scaling group or node.
+var errNotFound = errors.New("not found")
+
+// Client is a client for the scaling group emulation of the QEMU metadata API.
+type Client struct {
+	endpoint   string
+	httpClient *http.Client
+}
+
+// New creates a new client for the QEMU metadata API listening on endpoint (host:port).
+func New(endpoint string) *Client {
+	return &Client{
+		endpoint:   endpoint,
+		httpClient: &http.Client{},
+	}
+}
+
+// do sends a request to the QEMU metadata API and decodes the JSON response into out, if out is not nil.
+func (c *Client) do(ctx context.Context, method, path string, query url.Values, out any) error {
+	reqURL := &url.URL{
+		Scheme:   "http",
+		Host:     c.endpoint,
+		Path:     path,
+		RawQuery: query.Encode(),
+	}
+	req, err := http.NewRequestWithContext(ctx, method, reqURL.String(), http.NoBody)
+	if err != nil {
+		return err
+	}
+	res, err := c.httpClient.Do(req)
+	if err != nil {
+		return err
+	}
+	defer res.Body.Close()
+
+	switch res.StatusCode {
+	case http.StatusOK:
+	case http.StatusNotFound:
+		return fmt.Errorf("%s %s: %w", method, path, errNotFound)
+	default:
+		body, _ := io.ReadAll(res.Body)
+		return fmt.Errorf("%s %s: unexpected status %q: %s", method, path, res.Status, strings.TrimSpace(string(body)))
+	}
+	if out == nil {
+		return nil
+	}
+	if err := json.NewDecoder(res.Body).Decode(out); err != nil {
+		return fmt.Errorf("decoding response of %s %s: %w", method, path, err)
+	}
+	return nil
+}
+
+// node is a node as returned by the QEMU metadata API.
+type node struct {
+	Name       string `json:"name"`
+	ProviderID string `json:"providerID"`
+	GroupID    string `json:"groupID"`
+	Image      string `json:"image"`
+	State      string `json:"state"`
+}
+
+// scalingGroup is a scaling group as returned by the QEMU metadata API.
+type scalingGroup struct {
+	ID    string `json:"id"`
+	Role  string `json:"role"`
+	Image string `json:"image"`
+}
+
+func (c *Client) getNode(ctx context.Context, providerID string) (node, error) {
+	name, err := splitProviderID(providerID)
+	if err != nil {
+		return node{}, err
+	}
+	var n node
+	if err := c.do(ctx, http.MethodGet, "/nodes", url.Values{"name": {name}}, &n); err != nil {
+		return node{}, err
+	}
+	return n, nil
+}
+
+func (c *Client) listScalingGroups(ctx context.Context) ([]scalingGroup, error) {
+	var groups []scalingGroup
+	if err := c.do(ctx, http.MethodGet, "/scalinggroups", nil, &groups); err != nil {
+		return nil, err
+	}
+	return groups, nil
+}
@@ -0,0 +1,81 @@
+/*
+Copyright (c) Edgeless Systems GmbH
+
+SPDX-License-Identifier: AGPL-3.0-only
+*/
+
+package client
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+// newTestClient returns a client talking to a fake QEMU metadata API.
+func newTestClient(t *testing.T, api *fakeMetadataAPI) *Client {
+	server := httptest.NewServer(api)
+	t.Cleanup(server.Close)
+	client := New(strings.TrimPrefix(server.URL, "http://"))
+	client.httpClient = server.Client()
+	return client
+}
+
+type fakeMetadataAPI struct {
+	groups  []scalingGroup
+	nodes   map[string]node
+	failing bool
+}
+
+func (a *fakeMetadataAPI) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	if a.failing {
+		http.Error(w, "internal error", http.StatusInternalServerError)
+		return
+	}
+	switch {
+	case r.URL.Path == "/scalinggroups" && r.Method == http.MethodGet:
+		a.writeJSON(w, a.groups)
+	case r.URL.Path == "/scalinggroups/image" && r.Method == http.MethodPost:
+		for i := range a.groups {
+			if a.groups[i].ID == r.FormValue("group") {
+				a.groups[i].Image = r.FormValue("image")
+				return
+			}
+		}
+		http.Error(w, "scaling group not found", http.StatusNotFound)
+	case r.URL.Path == "/nodes" && r.Method == http.MethodGet:
+		n, ok := a.nodes[r.FormValue("name")]
+		if !ok {
+			http.Error(w, "node not found", http.StatusNotFound)
+			return
+		}
+		a.writeJSON(w, n)
+	case r.URL.Path == "/nodes" && r.Method == http.MethodPost:
+		for _, group := range a.groups {
+			if group.ID == r.FormValue("group") {
+				name := fmt.Sprintf("worker-%d", len(a.nodes))
+				n := node{Name: name, ProviderID: "qemu:///hostname/" + name, GroupID: group.ID, Image: group.Image, State: "running"}
+				a.nodes[name] = n
+				a.writeJSON(w, n)
+				return
+			}
+		}
+		http.Error(w, "scaling group not found", http.StatusNotFound)
+	case r.URL.Path == "/nodes" && r.Method == http.MethodDelete:
+		if _, ok := a.nodes[r.FormValue("name")]; !ok {
+			http.Error(w, "node not found", http.StatusNotFound)
+			return
+		}
+		delete(a.nodes, r.FormValue("name"))
+	default:
+		http.Error(w, "not implemented", http.StatusMethodNotAllowed)
+	}
+}
+
+func (a *fakeMetadataAPI) writeJSON(w http.ResponseWriter, v any) {
+	w.Header().Set("Content-Type", "application/json")
+	_ = json.NewEncoder(w).Encode(v)
+}
@@ -0,0 +1,58 @@
+/*
+Copyright (c) Edgeless Systems GmbH
+
+SPDX-License-Identifier: AGPL-3.0-only
+*/
+
+package client
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/url"
+)
+
+// GetNodeImage returns the image path of the volume backing the boot disk of a node.
+func (c *Client) GetNodeImage(ctx context.Context, providerID string) (string, error) {
+	node, err := c.getNode(ctx, providerID)
+	if err != nil {
+		return "", err
+	}
+	return node.Image, nil
+}
+
+// GetScalingGroupID returns the scaling group ID of the node.
+func (c *Client) GetScalingGroupID(ctx context.Context, providerID string) (string, error) {
+	node, err := c.getNode(ctx, providerID)
+	if err != nil {
+		return "", err
+	}
+	return node.GroupID, nil
+}
+
+// CreateNode creates a node in the specified scaling group.
+func (c *Client) CreateNode(ctx context.Context, scalingGroupID string) (nodeName, providerID string, err error) {
+	var node node
+	if err := c.do(ctx, http.MethodPost, "/nodes", url.Values{"group": {scalingGroupID}}, &node); err != nil {
+		return "", "", fmt.Errorf("creating node in scaling group %q: %w", scalingGroupID, err)
+	}
+	return node.Name, node.ProviderID, nil
+}
+
+// DeleteNode deletes a node specified by its provider ID.
+func (c *Client) DeleteNode(ctx context.Context, providerID string) error {
+	name, err := splitProviderID(providerID)
+	if err != nil {
+		return err
+	}
+	err = c.do(ctx, http.MethodDelete, "/nodes", url.Values{"name": {name}}, nil)
+	if errors.Is(err, errNotFound) {
+		return nil
+	}
+	if err != nil {
+		return fmt.Errorf("deleting node %q: %w", name, err)
+	}
+	return nil
+}
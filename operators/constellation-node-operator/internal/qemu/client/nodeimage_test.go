@@ -0,0 +1,169 @@
+/*
+Copyright (c) Edgeless Systems GmbH
+
+SPDX-License-Identifier: AGPL-3.0-only
+*/
+
+package client
+
+import (
+	"context"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestGetNodeImage(t *testing.T) {
+	testCases := map[string]struct {
+		providerID       string
+		failing          bool
+		wantImage        string
+		wantScalingGroup string
+		wantErr          bool
+	}{
+		"getting node image works": {
+			providerID:       "qemu:///hostname/worker-0",
+			wantImage:        "/var/lib/libvirt/images/image",
+			wantScalingGroup: "constell-worker",
+		},
+		"unknown node": {
+			providerID: "qemu:///hostname/worker-1",
+			wantErr:    true,
+		},
+		"invalid provider id": {
+			providerID: "invalid",
+			wantErr:    true,
+		},
+		"api fails": {
+			providerID: "qemu:///hostname/worker-0",
+			failing:    true,
+			wantErr:    true,
+		},
+	}
+
+	for name, tc := range testCases {
+		t.Run(name, func(t *testing.T) {
+			assert := assert.New(t)
+
+			client := newTestClient(t, &fakeMetadataAPI{
+				nodes: map[string]node{
+					"worker-0": {
+						Name:       "worker-0",
+						ProviderID: "qemu:///hostname/worker-0",
+						GroupID:    "constell-worker",
+						Image:      "/var/lib/libvirt/images/image",
+						State:      "running",
+					},
+				},
+				failing: tc.failing,
+			})
+
+			image, err := client.GetNodeImage(context.Background(), tc.providerID)
+			if tc.wantErr {
+				assert.Error(err)
+				return
+			}
+			assert.NoError(err)
+			assert.Equal(tc.wantImage, image)
+
+			scalingGroupID, err := client.GetScalingGroupID(context.Background(), tc.providerID)
+			assert.NoError(err)
+			assert.Equal(tc.wantScalingGroup, scalingGroupID)
+		})
+	}
+}
+
+func TestCreateNode(t *testing.T) {
+	testCases := map[string]struct {
+		scalingGroupID string
+		failing        bool
+		wantNodeName   string
+		wantProviderID string
+		wantErr        bool
+	}{
+		"creating node works": {
+			scalingGroupID: "constell-worker",
+			wantNodeName:   "worker-0",
+			wantProviderID: "qemu:///hostname/worker-0",
+		},
+		"unknown scaling group": {
+			scalingGroupID: "constell-other",
+			wantErr:        true,
+		},
+		"api fails": {
+			scalingGroupID: "constell-worker",
+			failing:        true,
+			wantErr:        true,
+		},
+	}
+
+	for name, tc := range testCases {
+		t.Run(name, func(t *testing.T) {
+			assert := assert.New(t)
+			require := require.New(t)
+
+			api := &fakeMetadataAPI{
+				groups:  []scalingGroup{{ID: "constell-worker", Role: "Worker", Image: "/var/lib/libvirt/images/image"}},
+				nodes:   map[string]node{},
+				failing: tc.failing,
+			}
+			client := newTestClient(t, api)
+
+			nodeName, providerID, err := client.CreateNode(context.Background(), tc.scalingGroupID)
+			if tc.wantErr {
+				assert.Error(err)
+				return
+			}
+			require.NoError(err)
+			assert.Equal(tc.wantNodeName, nodeName)
+			assert.Equal(tc.wantProviderID, providerID)
+			assert.Contains(api.nodes, nodeName)
+		})
+	}
+}
+
+func TestDeleteNode(t *testing.T) {
+	testCases := map[string]struct {
+		providerID string
+		failing    bool
+		wantErr    bool
+	}{
+		"deleting node works": {
+			providerID: "qemu:///hostname/worker-0",
+		},
+		"deleting unknown node works": {
+			providerID: "qemu:///hostname/worker-1",
+		},
+		"invalid provider id": {
+			providerID: "invalid",
+			wantErr:    true,
+		},
+		"api fails": {
+			providerID: "qemu:///hostname/worker-0",
+			failing:    true,
+			wantErr:    true,
+		},
+	}
+
+	for name, tc := range testCases {
+		t.Run(name, func(t *testing.T) {
+			assert := assert.New(t)
+
+			api := &fakeMetadataAPI{
+				nodes:   map[string]node{"worker-0": {Name: "worker-0"}},
+				failing: tc.failing,
+			}
+			client := newTestClient(t, api)
+
+			err := client.DeleteNode(context.Background(), tc.providerID)
+			if tc.wantErr {
+				assert.Error(err)
+				return
+			}
+			assert.NoError(err)
+			assert.NotContains(api.nodes, strings.TrimPrefix(tc.providerID, "qemu:///hostname/"))
+		})
+	}
+}
@@ -0,0 +1,33 @@
+/*
+Copyright (c) Edgeless Systems GmbH
+
+SPDX-License-Identifier: AGPL-3.0-only
+*/
+
+package client
+
+import (
+	"context"
+	"errors"
+
+	updatev1alpha1 "github.com/edgelesssys/constellation/operators/constellation-node-operator/v2/api/v1alpha1"
+)
+
+// GetNodeState returns the state of the node.
+func (c *Client) GetNodeState(ctx context.Context, providerID string) (updatev1alpha1.CSPNodeState, error) {
+	node, err := c.getNode(ctx, providerID)
+	if errors.Is(err, errNotFound) {
+		return updatev1alpha1.NodeStateTerminated, nil
+	}
+	if err != nil {
+		return "", err
+	}
+
+	switch node.State {
+	case "running":
+		return updatev1alpha1.NodeStateReady, nil
+	case "stopped":
+		return updatev1alpha1.NodeStateStopped, nil
+	}
+	return updatev1alpha1.NodeStateUnknown, nil
+}
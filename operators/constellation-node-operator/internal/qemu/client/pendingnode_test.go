@@ -0,0 +1,69 @@
+/*
+Copyright (c) Edgeless Systems GmbH
+
+SPDX-License-Identifier: AGPL-3.0-only
+*/
+
+package client
+
+import (
+	"context"
+	"testing"
+
+	updatev1alpha1 "github.com/edgelesssys/constellation/operators/constellation-node-operator/v2/api/v1alpha1"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestGetNodeState(t *testing.T) {
+	testCases := map[string]struct {
+		providerID string
+		state      string
+		failing    bool
+		wantState  updatev1alpha1.CSPNodeState
+		wantErr    bool
+	}{
+		"running node is ready": {
+			providerID: "qemu:///hostname/worker-0",
+			state:      "running",
+			wantState:  updatev1alpha1.NodeStateReady,
+		},
+		"stopped node": {
+			providerID: "qemu:///hostname/worker-0",
+			state:      "stopped",
+			wantState:  updatev1alpha1.NodeStateStopped,
+		},
+		"unknown state": {
+			providerID: "qemu:///hostname/worker-0",
+			state:      "paused",
+			wantState:  updatev1alpha1.NodeStateUnknown,
+		},
+		"missing node is terminated": {
+			providerID: "qemu:///hostname/worker-1",
+			wantState:  updatev1alpha1.NodeStateTerminated,
+		},
+		"api fails": {
+			providerID: "qemu:///hostname/worker-0",
+			failing:    true,
+			wantErr:    true,
+		},
+	}
+
+	for name, tc := range testCases {
+		t.Run(name, func(t *testing.T) {
+			assert := assert.New(t)
+
+			client := newTestClient(t, &fakeMetadataAPI{
+				nodes:   map[string]node{"worker-0": {Name: "worker-0", State: tc.state}},
+				failing: tc.failing,
+			})
+
+			state, err := client.GetNodeState(context.Background(), tc.providerID)
+			if tc.wantErr {
+				assert.Error(err)
+				return
+			}
+			assert.NoError(err)
+			assert.Equal(tc.wantState, state)
+		})
+	}
+}
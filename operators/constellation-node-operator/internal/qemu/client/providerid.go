@@ -0,0 +1,24 @@
+/*
+Copyright (c) Edgeless Systems GmbH
+
+SPDX-License-Identifier: AGPL-3.0-only
+*/
+
+package client
+
+import (
+	"fmt"
+	"regexp"
+)
+
+var providerIDRegex = regexp.MustCompile(`^qemu:///hostname/([^/]+)$`)
+
+// splitProviderID splits a provider's id into core components.
+// A providerID is build after the schema 'qemu:///hostname/<instance-name>'
+func splitProviderID(providerID string) (instance string, err error) {
+	matches := providerIDRegex.FindStringSubmatch(providerID)
+	if len(matches) != 2 {
+		return "", fmt.Errorf("splitting providerID: %q. matches: %v", providerID, matches)
+	}
+	return matches[1], nil
+}
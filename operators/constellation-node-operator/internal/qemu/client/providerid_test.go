@@ -0,0 +1,49 @@
+/*
+Copyright (c) Edgeless Systems GmbH
+
+SPDX-License-Identifier: AGPL-3.0-only
+*/
+
+package client
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestSplitProviderID(t *testing.T) {
+	testCases := map[string]struct {
+		providerID   string
+		wantInstance string
+		wantErr      bool
+	}{
+		"simple id": {
+			providerID:   "qemu:///hostname/worker-0",
+			wantInstance: "worker-0",
+		},
+		"incomplete id": {
+			providerID: "qemu:///hostname/",
+			wantErr:    true,
+		},
+		"wrong provider": {
+			providerID: "gce://someProject/someZone/someInstance",
+			wantErr:    true,
+		},
+	}
+
+	for name, tc := range testCases {
+		t.Run(name, func(t *testing.T) {
+			assert := assert.New(t)
+
+			instance, err := splitProviderID(tc.providerID)
+
+			if tc.wantErr {
+				assert.Error(err)
+				return
+			}
+			assert.NoError(err)
+			assert.Equal(tc.wantInstance, instance)
+		})
+	}
+}
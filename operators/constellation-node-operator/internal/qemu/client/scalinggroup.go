@@ -0,0 +1,81 @@
+/*
+Copyright (c) Edgeless Systems GmbH
+
+SPDX-License-Identifier: AGPL-3.0-only
+*/
+
+package client
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strings"
+
+	updatev1alpha1 "github.com/edgelesssys/constellation/operators/constellation-node-operator/v2/api/v1alpha1"
+)
+
+// GetScalingGroupImage returns the image path used by new nodes of the scaling group.
+func (c *Client) GetScalingGroupImage(ctx context.Context, scalingGroupID string) (string, error) {
+	groups, err := c.listScalingGroups(ctx)
+	if err != nil {
+		return "", err
+	}
+	for _, group := range groups {
+		if group.ID == scalingGroupID {
+			return group.Image, nil
+		}
+	}
+	return "", fmt.Errorf("scaling group %q: %w", scalingGroupID, errNotFound)
+}
+
+// SetScalingGroupImage sets the image path used by new nodes of the scaling group.
+func (c *Client) SetScalingGroupImage(ctx context.Context, scalingGroupID, imageURI string) error {
+	query := url.Values{"group": {scalingGroupID}, "image": {imageURI}}
+	if err := c.do(ctx, http.MethodPost, "/scalinggroups/image", query, nil); err != nil {
+		return fmt.Errorf("setting image of scaling group %q: %w", scalingGroupID, err)
+	}
+	return nil
+}
+
+// GetScalingGroupSize returns the minimum and maximum number of nodes of a worker group.
+// Worker groups are not supported on QEMU, so zero values are returned.
+func (c *Client) GetScalingGroupSize(ctx context.Context, scalingGroupID string) (min, max int32, err error) {
+	return 0, 0, nil
+}
+
+// GetScalingGroupNodeTemplate returns the labels and taints of the nodes of a scaling group.
+// Worker groups are not supported on QEMU, so an empty template is returned.
+func (c *Client) GetScalingGroupNodeTemplate(ctx context.Context, scalingGroupID string) (updatev1alpha1.NodeTemplate, error) {
+	return updatev1alpha1.NodeTemplate{}, nil
+}
+
+// GetScalingGroupName retrieves the name of a scaling group.
+// On QEMU, the name of a scaling group is its ID.
+func (c *Client) GetScalingGroupName(scalingGroupID string) (string, error) {
+	return scalingGroupID, nil
+}
+
+// GetAutoscalingGroupName retrieves the name of a scaling group as needed by the cluster-autoscaler.
+func (c *Client) GetAutoscalingGroupName(scalingGroupID string) (string, error) {
+	return scalingGroupID, nil
+}
+
+// ListScalingGroups retrieves a list of scaling groups for the cluster.
+// The uid is ignored, since only one cluster is expected to run in the same libvirt environment.
+func (c *Client) ListScalingGroups(ctx context.Context, uid string) (controlPlaneGroupIDs []string, workerGroupIDs []string, err error) {
+	groups, err := c.listScalingGroups(ctx)
+	if err != nil {
+		return nil, nil, fmt.Errorf("listing scaling groups: %w", err)
+	}
+	for _, group := range groups {
+		switch strings.ToLower(group.Role) {
+		case "controlplane":
+			controlPlaneGroupIDs = append(controlPlaneGroupIDs, group.ID)
+		case "worker":
+			workerGroupIDs = append(workerGroupIDs, group.ID)
+		}
+	}
+	return controlPlaneGroupIDs, workerGroupIDs, nil
+}
@@ -0,0 +1,107 @@
+/*
+Copyright (c) Edgeless Systems GmbH
+
+SPDX-License-Identifier: AGPL-3.0-only
+*/
+
+package client
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestScalingGroupImage(t *testing.T) {
+	testCases := map[string]struct {
+		scalingGroupID string
+		failing        bool
+		wantErr        bool
+	}{
+		"setting image works": {
+			scalingGroupID: "constell-worker",
+		},
+		"unknown scaling group": {
+			scalingGroupID: "constell-other",
+			wantErr:        true,
+		},
+		"api fails": {
+			scalingGroupID: "constell-worker",
+			failing:        true,
+			wantErr:        true,
+		},
+	}
+
+	for name, tc := range testCases {
+		t.Run(name, func(t *testing.T) {
+			assert := assert.New(t)
+			require := require.New(t)
+
+			client := newTestClient(t, &fakeMetadataAPI{
+				groups:  []scalingGroup{{ID: "constell-worker", Role: "Worker", Image: "/var/lib/libvirt/images/old"}},
+				failing: tc.failing,
+			})
+
+			err := client.SetScalingGroupImage(context.Background(), tc.scalingGroupID, "/var/lib/libvirt/images/new")
+			if tc.wantErr {
+				assert.Error(err)
+				_, err := client.GetScalingGroupImage(context.Background(), tc.scalingGroupID)
+				assert.Error(err)
+				return
+			}
+			require.NoError(err)
+			image, err := client.GetScalingGroupImage(context.Background(), tc.scalingGroupID)
+			require.NoError(err)
+			assert.Equal("/var/lib/libvirt/images/new", image)
+		})
+	}
+}
+
+func TestListScalingGroups(t *testing.T) {
+	testCases := map[string]struct {
+		groups                   []scalingGroup
+		failing                  bool
+		wantControlPlaneGroupIDs []string
+		wantWorkerGroupIDs       []string
+		wantErr                  bool
+	}{
+		"listing scaling groups works": {
+			groups: []scalingGroup{
+				{ID: "constell-control-plane", Role: "ControlPlane"},
+				{ID: "constell-worker", Role: "Worker"},
+			},
+			wantControlPlaneGroupIDs: []string{"constell-control-plane"},
+			wantWorkerGroupIDs:       []string{"constell-worker"},
+		},
+		"unknown role is ignored": {
+			groups: []scalingGroup{
+				{ID: "constell-worker", Role: "Worker"},
+				{ID: "constell-other", Role: "Unknown"},
+			},
+			wantWorkerGroupIDs: []string{"constell-worker"},
+		},
+		"api fails": {
+			failing: true,
+			wantErr: true,
+		},
+	}
+
+	for name, tc := range testCases {
+		t.Run(name, func(t *testing.T) {
+			assert := assert.New(t)
+
+			client := newTestClient(t, &fakeMetadataAPI{groups: tc.groups, failing: tc.failing})
+
+			controlPlaneGroupIDs, workerGroupIDs, err := client.ListScalingGroups(context.Background(), "uid")
+			if tc.wantErr {
+				assert.Error(err)
+				return
+			}
+			assert.NoError(err)
+			assert.Equal(tc.wantControlPlaneGroupIDs, controlPlaneGroupIDs)
+			assert.Equal(tc.wantWorkerGroupIDs, workerGroupIDs)
+		})
+	}
+}
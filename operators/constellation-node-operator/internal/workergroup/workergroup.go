@@ -0,0 +1,87 @@
+/*
+Copyright (c) Edgeless Systems GmbH
+
+SPDX-License-Identifier: AGPL-3.0-only
+*/
+
+// Package workergroup parses the settings of worker groups that the Constellation CLI stores
+// as tags (Azure) or instance metadata (GCP) of a scaling group.
+package workergroup
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+
+	updatev1alpha1 "github.com/edgelesssys/constellation/operators/constellation-node-operator/v2/api/v1alpha1"
+	"github.com/edgelesssys/constellation/operators/constellation-node-operator/v2/internal/constants"
+	corev1 "k8s.io/api/core/v1"
+)
+
+// Size parses the minimum and maximum number of nodes of a worker group.
+// If the scaling group is not a worker group, zero values are returned.
+func Size(tags map[string]string) (min, max int32, err error) {
+	minTag, hasMin := tags[constants.WorkerGroupMinTag]
+	maxTag, hasMax := tags[constants.WorkerGroupMaxTag]
+	if !hasMin || !hasMax {
+		return 0, 0, nil
+	}
+	min64, err := strconv.ParseInt(minTag, 10, 32)
+	if err != nil {
+		return 0, 0, fmt.Errorf("parsing minimum size %q: %w", minTag, err)
+	}
+	max64, err := strconv.ParseInt(maxTag, 10, 32)
+	if err != nil {
+		return 0, 0, fmt.Errorf("parsing maximum size %q: %w", maxTag, err)
+	}
+	return int32(min64), int32(max64), nil
+}
+
+// NodeTemplate parses the labels and taints of the nodes of a worker group.
+// Labels are stored as comma separated list of key=value pairs,
+// taints as comma separated list in the format key[=value]:effect.
+func NodeTemplate(tags map[string]string) (updatev1alpha1.NodeTemplate, error) {
+	var template updatev1alpha1.NodeTemplate
+	for _, label := range splitList(tags[constants.WorkerGroupLabelsTag]) {
+		key, value, _ := strings.Cut(label, "=")
+		if key == "" {
+			return updatev1alpha1.NodeTemplate{}, fmt.Errorf("invalid node label %q", label)
+		}
+		if template.Labels == nil {
+			template.Labels = make(map[string]string)
+		}
+		template.Labels[key] = value
+	}
+	for _, taint := range splitList(tags[constants.WorkerGroupTaintsTag]) {
+		keyValue, effect, ok := cutLast(taint, ":")
+		key, value, _ := strings.Cut(keyValue, "=")
+		if !ok || key == "" {
+			return updatev1alpha1.NodeTemplate{}, fmt.Errorf("invalid node taint %q", taint)
+		}
+		switch corev1.TaintEffect(effect) {
+		case corev1.TaintEffectNoSchedule, corev1.TaintEffectPreferNoSchedule, corev1.TaintEffectNoExecute:
+		default:
+			return updatev1alpha1.NodeTemplate{}, fmt.Errorf("invalid effect of node taint %q", taint)
+		}
+		template.Taints = append(template.Taints, corev1.Taint{
+			Key:    key,
+			Value:  value,
+			Effect: corev1.TaintEffect(effect),
+		})
+	}
+	return template, nil
+}
+
+func splitList(list string) []string {
+	if list == "" {
+		return nil
+	}
+	return strings.Split(list, ",")
+}
+
+func cutLast(s, sep string) (before, after string, found bool) {
+	if i := strings.LastIndex(s, sep); i >= 0 {
+		return s[:i], s[i+len(sep):], true
+	}
+	return s, "", false
+}
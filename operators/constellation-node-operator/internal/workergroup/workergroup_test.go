@@ -0,0 +1,116 @@
+/*
+Copyright (c) Edgeless Systems GmbH
+
+SPDX-License-Identifier: AGPL-3.0-only
+*/
+
+package workergroup
+
+import (
+	"testing"
+
+	updatev1alpha1 "github.com/edgelesssys/constellation/operators/constellation-node-operator/v2/api/v1alpha1"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	corev1 "k8s.io/api/core/v1"
+)
+
+func TestSize(t *testing.T) {
+	testCases := map[string]struct {
+		tags    map[string]string
+		wantMin int32
+		wantMax int32
+		wantErr bool
+	}{
+		"worker group": {
+			tags:    map[string]string{"constellation-min-count": "1", "constellation-max-count": "5"},
+			wantMin: 1,
+			wantMax: 5,
+		},
+		"no worker group": {
+			tags: map[string]string{"constellation-uid": "uid"},
+		},
+		"missing max": {
+			tags: map[string]string{"constellation-min-count": "1"},
+		},
+		"invalid min": {
+			tags:    map[string]string{"constellation-min-count": "one", "constellation-max-count": "5"},
+			wantErr: true,
+		},
+		"invalid max": {
+			tags:    map[string]string{"constellation-min-count": "1", "constellation-max-count": "99999999999"},
+			wantErr: true,
+		},
+	}
+
+	for name, tc := range testCases {
+		t.Run(name, func(t *testing.T) {
+			assert := assert.New(t)
+			require := require.New(t)
+
+			min, max, err := Size(tc.tags)
+			if tc.wantErr {
+				assert.Error(err)
+				return
+			}
+			require.NoError(err)
+			assert.Equal(tc.wantMin, min)
+			assert.Equal(tc.wantMax, max)
+		})
+	}
+}
+
+func TestNodeTemplate(t *testing.T) {
+	testCases := map[string]struct {
+		tags         map[string]string
+		wantTemplate updatev1alpha1.NodeTemplate
+		wantErr      bool
+	}{
+		"no worker group": {
+			tags: map[string]string{"constellation-uid": "uid"},
+		},
+		"empty labels and taints": {
+			tags: map[string]string{"constellation-node-labels": "", "constellation-node-taints": ""},
+		},
+		"labels and taints": {
+			tags: map[string]string{
+				"constellation-node-labels": "example.com/pool=highmem,tier=",
+				"constellation-node-taints": "example.com/pool=highmem:NoSchedule,dedicated:NoExecute",
+			},
+			wantTemplate: updatev1alpha1.NodeTemplate{
+				Labels: map[string]string{"example.com/pool": "highmem", "tier": ""},
+				Taints: []corev1.Taint{
+					{Key: "example.com/pool", Value: "highmem", Effect: corev1.TaintEffectNoSchedule},
+					{Key: "dedicated", Effect: corev1.TaintEffectNoExecute},
+				},
+			},
+		},
+		"invalid label": {
+			tags:    map[string]string{"constellation-node-labels": "=value"},
+			wantErr: true,
+		},
+		"taint without effect": {
+			tags:    map[string]string{"constellation-node-taints": "pool=highmem"},
+			wantErr: true,
+		},
+		"taint with invalid effect": {
+			tags:    map[string]string{"constellation-node-taints": "pool=highmem:Sometimes"},
+			wantErr: true,
+		},
+	}
+
+	for name, tc := range testCases {
+		t.Run(name, func(t *testing.T) {
+			assert := assert.New(t)
+			require := require.New(t)
+
+			template, err := NodeTemplate(tc.tags)
+			if tc.wantErr {
+				assert.Error(err)
+				return
+			}
+			require.NoError(err)
+			assert.Equal(tc.wantTemplate, template)
+		})
+	}
+}
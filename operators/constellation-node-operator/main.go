@@ -17,7 +17,9 @@ import (
 	_ "k8s.io/client-go/plugin/pkg/client/auth"
 
 	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/types"
 	utilruntime "k8s.io/apimachinery/pkg/util/runtime"
+	"k8s.io/client-go/kubernetes"
 	clientgoscheme "k8s.io/client-go/kubernetes/scheme"
 	ctrl "sigs.k8s.io/controller-runtime"
 	"sigs.k8s.io/controller-runtime/pkg/client"
@@ -25,13 +27,17 @@ import (
 	"sigs.k8s.io/controller-runtime/pkg/log/zap"
 
 	azureclient "github.com/edgelesssys/constellation/operators/constellation-node-operator/v2/internal/azure/client"
+	"github.com/edgelesssys/constellation/operators/constellation-node-operator/v2/internal/constants"
 	"github.com/edgelesssys/constellation/operators/constellation-node-operator/v2/internal/deploy"
 	gcpclient "github.com/edgelesssys/constellation/operators/constellation-node-operator/v2/internal/gcp/client"
+	qemuclient "github.com/edgelesssys/constellation/operators/constellation-node-operator/v2/internal/qemu/client"
 
 	updatev1alpha1 "github.com/edgelesssys/constellation/operators/constellation-node-operator/v2/api/v1alpha1"
 	"github.com/edgelesssys/constellation/operators/constellation-node-operator/v2/controllers"
+	"github.com/edgelesssys/constellation/operators/constellation-node-operator/v2/internal/drain"
 	"github.com/edgelesssys/constellation/operators/constellation-node-operator/v2/internal/etcd"
-	nodemaintenancev1beta1 "github.com/medik8s/node-maintenance-operator/api/v1beta1"
+	"github.com/edgelesssys/constellation/operators/constellation-node-operator/v2/internal/kms"
+	"github.com/edgelesssys/constellation/operators/constellation-node-operator/v2/internal/metrics"
 	//+kubebuilder:scaffold:imports
 )
 
@@ -45,13 +51,19 @@ const (
 	defaultGCPCloudConfigPath   = "/etc/gce/gce.conf"
 	// constellationCSP is the environment variable stating which Cloud Service Provider Constellation is running on.
 	constellationCSP = "CONSTEL_CSP"
+	// defaultKMSEndpoint is the endpoint of the Constellation KMS inside the cluster.
+	defaultKMSEndpoint = "kms.kube-system:9000"
 	// constellationUID is the environment variable stating which uid is used to tag / label cloud provider resources belonging to one constellation.
 	constellationUID = "constellation-uid"
+	// podName and podNamespace are the environment variables stating the pod the operator is running in.
+	podName      = "POD_NAME"
+	podNamespace = "POD_NAMESPACE"
+	// kubernetesUpgradeImage is the environment variable stating the image of the jobs upgrading the Kubernetes components of nodes.
+	kubernetesUpgradeImage = "CONSTEL_KUBERNETES_UPGRADE_IMAGE"
 )
 
 func init() {
 	utilruntime.Must(clientgoscheme.AddToScheme(scheme))
-	utilruntime.Must(nodemaintenancev1beta1.AddToScheme(scheme))
 	utilruntime.Must(updatev1alpha1.AddToScheme(scheme))
 	//+kubebuilder:scaffold:scheme
 }
@@ -61,9 +73,11 @@ func main() {
 	var metricsAddr string
 	var enableLeaderElection bool
 	var probeAddr string
+	var kmsEndpoint string
 	flag.StringVar(&cloudConfigPath, "cloud-config", "", "Path to provider specific cloud config. Optional.")
 	flag.StringVar(&metricsAddr, "metrics-bind-address", ":8080", "The address the metric endpoint binds to.")
 	flag.StringVar(&probeAddr, "health-probe-bind-address", ":8081", "The address the probe endpoint binds to.")
+	flag.StringVar(&kmsEndpoint, "kms-endpoint", defaultKMSEndpoint, "The endpoint of the Constellation KMS used to derive the etcd backup encryption key.")
 	flag.BoolVar(&enableLeaderElection, "leader-elect", false,
 		"Enable leader election for controller manager. "+
 			"Enabling this will ensure there is only one active controller manager.")
@@ -97,11 +111,16 @@ func main() {
 			setupLog.Error(clientErr, "unable to create GCP client")
 			os.Exit(1)
 		}
+	case "qemu":
+		cspClient = qemuclient.New(constants.QEMUMetadataEndpoint)
 	default:
 		setupLog.Info("Unknown CSP", "csp", csp)
 		os.Exit(1)
 	}
 
+	// count failed cloud provider API calls
+	cspClient = metrics.NewCloudClient(cspClient)
+
 	mgr, err := ctrl.NewManager(ctrl.GetConfigOrDie(), ctrl.Options{
 		Scheme:                 scheme,
 		MetricsBindAddress:     metricsAddr,
@@ -126,6 +145,11 @@ func main() {
 		os.Exit(1)
 	}
 	defer etcdClient.Close()
+	clientset, err := kubernetes.NewForConfig(ctrl.GetConfigOrDie())
+	if err != nil {
+		setupLog.Error(err, "Unable to create k8s clientset")
+		os.Exit(1)
+	}
 
 	if err := deploy.InitialResources(context.Background(), k8sClient, cspClient, os.Getenv(constellationUID)); err != nil {
 		setupLog.Error(err, "Unable to deploy initial resources")
@@ -133,7 +157,7 @@ func main() {
 	}
 
 	if err = controllers.NewNodeImageReconciler(
-		cspClient, etcdClient, mgr.GetClient(), mgr.GetScheme(),
+		cspClient, etcdClient, mgr.GetClient(), mgr.GetScheme(), mgr.GetEventRecorderFor("nodeimage-controller"),
 	).SetupWithManager(mgr); err != nil {
 		setupLog.Error(err, "Unable to create controller", "controller", "NodeImage")
 		os.Exit(1)
@@ -157,6 +181,31 @@ func main() {
 		setupLog.Error(err, "Unable to create controller", "controller", "PendingNode")
 		os.Exit(1)
 	}
+	if err = controllers.NewEtcdBackupReconciler(
+		etcdClient, kms.New(kmsEndpoint), mgr.GetClient(), mgr.GetAPIReader(), mgr.GetScheme(), os.Getenv(podNamespace),
+	).SetupWithManager(mgr); err != nil {
+		setupLog.Error(err, "Unable to create controller", "controller", "EtcdBackup")
+		os.Exit(1)
+	}
+	if err = controllers.NewNodeKubernetesVersionReconciler(
+		mgr.GetClient(), mgr.GetScheme(), os.Getenv(kubernetesUpgradeImage),
+	).SetupWithManager(mgr); err != nil {
+		setupLog.Error(err, "Unable to create controller", "controller", "NodeKubernetesVersion")
+		os.Exit(1)
+	}
+	if err = controllers.NewNodeDrainReconciler(
+		drain.New(clientset, types.NamespacedName{Namespace: os.Getenv(podNamespace), Name: os.Getenv(podName)}),
+		mgr.GetClient(), mgr.GetScheme(),
+	).SetupWithManager(mgr); err != nil {
+		setupLog.Error(err, "Unable to create controller", "controller", "NodeDrain")
+		os.Exit(1)
+	}
+	if err = controllers.NewNodeHealthCheckReconciler(
+		cspClient, etcdClient, mgr.GetClient(), mgr.GetScheme(),
+	).SetupWithManager(mgr); err != nil {
+		setupLog.Error(err, "Unable to create controller", "controller", "NodeHealthCheck")
+		os.Exit(1)
+	}
 	//+kubebuilder:scaffold:builder
 
 	if err := mgr.AddHealthzCheck("healthz", healthz.Ping); err != nil {
@@ -190,6 +239,10 @@ type cspAPI interface {
 	GetScalingGroupImage(ctx context.Context, scalingGroupID string) (string, error)
 	// SetScalingGroupImage sets the image to be used by newly created nodes in a scaling group.
 	SetScalingGroupImage(ctx context.Context, scalingGroupID, imageURI string) error
+	// GetScalingGroupSize retrieves the minimum and maximum number of nodes of a worker group.
+	GetScalingGroupSize(ctx context.Context, scalingGroupID string) (min, max int32, err error)
+	// GetScalingGroupNodeTemplate retrieves the labels and taints of the nodes of a scaling group.
+	GetScalingGroupNodeTemplate(ctx context.Context, scalingGroupID string) (updatev1alpha1.NodeTemplate, error)
 	// GetScalingGroupName retrieves the name of a scaling group.
 	GetScalingGroupName(scalingGroupID string) (string, error)
 	// GetScalingGroupName retrieves the name of a scaling group as needed by the cluster-autoscaler.
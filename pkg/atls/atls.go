@@ -0,0 +1,184 @@
+/*
+Copyright (c) Edgeless Systems GmbH
+
+SPDX-License-Identifier: AGPL-3.0-only
+*/
+
+/*
+Package atls provides attested TLS (aTLS) for HTTP servers and clients.
+
+aTLS binds the TLS connection to a remote attestation of the peer: the certificate presented during the handshake
+embeds an attestation statement, which is created for a nonce chosen by the other party for this connection.
+See internal/atls/README.md for the protocol.
+
+Applications running inside Constellation use an Issuer to attest themselves,
+and one Validator for every attestation variant they accept from their peers.
+Both are interfaces, so any attestation mechanism can be plugged in.
+
+A server serving attested HTTPS:
+
+	server, err := atls.NewHTTPServer(":8443", handler, issuer, nil)
+	// ...
+	err = server.ListenAndServeTLS("", "")
+
+A client verifying the server's attestation:
+
+	client := atls.NewHTTPClient(nil, []atls.Validator{validator})
+	resp, err := client.Get("https://10.0.0.1:8443/")
+
+If the server is created with validators, clients must attest themselves as well (mutual aTLS).
+Handlers can then retrieve the validated claims of the client using ClaimsFromContext.
+
+Attesting every connection is slow. Servers and clients sharing a Cache reuse attestations for a limited time,
+and resume TLS sessions instead of attesting again:
+
+	cache := atls.NewCache(5 * time.Minute)
+	client := atls.NewHTTPClient(nil, []atls.Validator{validator}, atls.WithCache(cache))
+*/
+package atls
+
+import (
+	"context"
+	"encoding/asn1"
+	"time"
+
+	"github.com/edgelesssys/constellation/v2/internal/atls"
+	"github.com/edgelesssys/constellation/v2/internal/attestation/policy"
+	"github.com/edgelesssys/constellation/v2/internal/cloud/cloudprovider"
+	"github.com/edgelesssys/constellation/v2/internal/cloud/vmtype"
+)
+
+// Issuer issues attestation statements.
+// The statement must bind userData, and must be verifiable as fresh for nonce.
+type Issuer interface {
+	// OID identifies the attestation variant of the issued statements.
+	OID() asn1.ObjectIdentifier
+	Issue(userData []byte, nonce []byte) ([]byte, error)
+}
+
+// Validator validates attestation statements created by an Issuer with the same OID.
+// It returns the user data bound by the attestation statement.
+type Validator interface {
+	// OID identifies the attestation variant of the validated statements.
+	OID() asn1.ObjectIdentifier
+	Validate(attDoc []byte, nonce []byte) ([]byte, error)
+}
+
+// ClaimsValidator is a Validator that also reports the ID of the attested instance.
+// Instance IDs are only available for peers validated by a ClaimsValidator.
+type ClaimsValidator interface {
+	Validator
+	// ValidateWithInstanceID validates an attestation statement, and returns the user data it binds
+	// and the ID of the instance it identifies, or an empty string if the statement doesn't identify an instance.
+	ValidateWithInstanceID(attDoc []byte, nonce []byte) (userData []byte, instanceID string, err error)
+}
+
+// Claims describe an attested peer.
+type Claims struct {
+	// Variant is the OID of the attestation variant used by the peer.
+	Variant asn1.ObjectIdentifier
+	// Provider is the cloud provider the peer is running on, e.g. "GCP", or empty if the variant is unknown.
+	Provider string
+	// VMType is the type of confidential VM the peer is running on, if the provider offers several.
+	VMType string
+	// InstanceID identifies the attested instance, if it was validated by a ClaimsValidator reporting it.
+	InstanceID string
+}
+
+// Cache reuses attestations to speed up repeated handshakes.
+type Cache struct {
+	cache *atls.Cache
+}
+
+// NewCache creates a new Cache reusing attestation results for up to maxAge.
+func NewCache(maxAge time.Duration) *Cache {
+	return &Cache{cache: atls.NewCache(maxAge)}
+}
+
+// Invalidate discards all cached validation results and resumable sessions.
+// Call it whenever the validators used with the cache change, e.g. after updating expected measurements,
+// so that peers are validated again on their next connection.
+func (c *Cache) Invalidate() {
+	c.cache.Invalidate()
+}
+
+// Option configures aTLS servers and clients.
+type Option func(*options)
+
+type options struct {
+	cache *Cache
+}
+
+// WithCache enables caching of attestations using cache.
+// Cached attestations are only exchanged with peers using a cache as well.
+func WithCache(cache *Cache) Option {
+	return func(o *options) {
+		o.cache = cache
+	}
+}
+
+// ClaimsFromContext returns the claims of the attested client of a request served by a server created with NewHTTPServer.
+// The second return value is false if the client was not attested, i.e. if the server has no validators.
+func ClaimsFromContext(ctx context.Context) (*Claims, bool) {
+	peer := atls.PeerFromContext(ctx)
+	if peer == nil || peer.Claims() == nil {
+		return nil, false
+	}
+	return newClaims(peer.Claims()), true
+}
+
+// internalOptions converts opts to options of the internal aTLS implementation.
+func internalOptions(opts []Option) []atls.Option {
+	var o options
+	for _, opt := range opts {
+		opt(&o)
+	}
+	var internalOpts []atls.Option
+	if o.cache != nil {
+		internalOpts = append(internalOpts, atls.WithCache(o.cache.cache))
+	}
+	return internalOpts
+}
+
+// internalValidators converts validators to validators of the internal aTLS implementation.
+func internalValidators(validators []Validator) []atls.Validator {
+	if validators == nil {
+		return nil
+	}
+	internal := make([]atls.Validator, 0, len(validators))
+	for _, validator := range validators {
+		if claimsValidator, ok := validator.(ClaimsValidator); ok {
+			internal = append(internal, instanceIDValidator{claimsValidator})
+			continue
+		}
+		internal = append(internal, validator)
+	}
+	return internal
+}
+
+// instanceIDValidator reports the instance ID of a ClaimsValidator to the internal aTLS implementation.
+type instanceIDValidator struct {
+	ClaimsValidator
+}
+
+func (v instanceIDValidator) ValidateWithClaims(attDoc []byte, nonce []byte) ([]byte, policy.Claims, error) {
+	userData, instanceID, err := v.ValidateWithInstanceID(attDoc, nonce)
+	if err != nil {
+		return nil, policy.Claims{}, err
+	}
+	return userData, policy.Claims{InstanceID: instanceID}, nil
+}
+
+func newClaims(peerClaims *atls.PeerClaims) *Claims {
+	claims := &Claims{
+		Variant:    peerClaims.Variant,
+		InstanceID: peerClaims.InstanceID,
+	}
+	if peerClaims.Provider != cloudprovider.Unknown {
+		claims.Provider = peerClaims.Provider.String()
+	}
+	if peerClaims.VMType != vmtype.Unknown {
+		claims.VMType = peerClaims.VMType.String()
+	}
+	return claims
+}
@@ -0,0 +1,93 @@
+/*
+Copyright (c) Edgeless Systems GmbH
+
+SPDX-License-Identifier: AGPL-3.0-only
+*/
+
+package atls
+
+import (
+	"context"
+	"crypto/tls"
+	"fmt"
+	"net"
+	"net/http"
+	"time"
+
+	"github.com/edgelesssys/constellation/v2/internal/atls"
+)
+
+// NewHTTPServer returns an http.Server serving handler on addr using aTLS.
+// The server attests itself using issuer. If validators are set, clients must attest themselves,
+// and their claims are available to handler using ClaimsFromContext.
+//
+// Start the server using ListenAndServeTLS or ServeTLS with empty certificate and key file arguments.
+// A fresh nonce is used for every connection, unless caching is enabled using WithCache.
+func NewHTTPServer(addr string, handler http.Handler, issuer Issuer, validators []Validator, opts ...Option) (*http.Server, error) {
+	tlsConfig, err := atls.CreateAttestationServerTLSConfig(issuer, internalValidators(validators), internalOptions(opts)...)
+	if err != nil {
+		return nil, fmt.Errorf("creating aTLS config: %w", err)
+	}
+	// older versions of ServeTLS try to load certificate files unless GetCertificate is set.
+	// It is never called, since the config returned by GetConfigForClient takes precedence.
+	tlsConfig.GetCertificate = func(*tls.ClientHelloInfo) (*tls.Certificate, error) {
+		return nil, fmt.Errorf("aTLS certificate is created per connection")
+	}
+
+	return &http.Server{
+		Addr:      addr,
+		Handler:   handler,
+		TLSConfig: tlsConfig,
+		// the handshake uses this context, so the claims of the client are recorded in it
+		ConnContext: func(ctx context.Context, _ net.Conn) context.Context {
+			return atls.ContextWithPeer(ctx, &atls.Peer{})
+		},
+		ReadHeaderTimeout: 10 * time.Second,
+	}, nil
+}
+
+// NewHTTPTransport returns an http.Transport connecting to aTLS servers.
+// The server's attestation is verified using validators. If issuer is set, the client attests itself to servers requiring it.
+//
+// If no validators are set, the server's attestation is not verified.
+// A fresh nonce is used for every connection. If caching is enabled using WithCache,
+// sessions with servers the transport connected to before are resumed.
+//
+// Proxies are not supported, since the aTLS handshake must be performed with the server itself.
+// Connections are always dialed directly, regardless of proxy environment variables.
+func NewHTTPTransport(issuer Issuer, validators []Validator, opts ...Option) *http.Transport {
+	dialer := &net.Dialer{Timeout: 30 * time.Second, KeepAlive: 30 * time.Second}
+	internalVals := internalValidators(validators)
+	internalOpts := internalOptions(opts)
+
+	return &http.Transport{
+		DialTLSContext: func(ctx context.Context, network, addr string) (net.Conn, error) {
+			// a new config is created for every connection, since the nonce is bound to it
+			connOpts := append([]atls.Option{atls.WithSessionKey(addr)}, internalOpts...)
+			tlsConfig, err := atls.CreateAttestationClientTLSConfig(issuer, internalVals, connOpts...)
+			if err != nil {
+				return nil, fmt.Errorf("creating aTLS config: %w", err)
+			}
+
+			rawConn, err := dialer.DialContext(ctx, network, addr)
+			if err != nil {
+				return nil, err
+			}
+			conn := tls.Client(rawConn, tlsConfig)
+			if err := conn.HandshakeContext(ctx); err != nil {
+				rawConn.Close()
+				return nil, fmt.Errorf("aTLS handshake with %s: %w", addr, err)
+			}
+			return conn, nil
+		},
+		MaxIdleConns:          100,
+		IdleConnTimeout:       90 * time.Second,
+		TLSHandshakeTimeout:   10 * time.Second,
+		ExpectContinueTimeout: 1 * time.Second,
+	}
+}
+
+// NewHTTPClient returns an http.Client connecting to aTLS servers using NewHTTPTransport.
+func NewHTTPClient(issuer Issuer, validators []Validator, opts ...Option) *http.Client {
+	return &http.Client{Transport: NewHTTPTransport(issuer, validators, opts...)}
+}
@@ -0,0 +1,157 @@
+/*
+Copyright (c) Edgeless Systems GmbH
+
+SPDX-License-Identifier: AGPL-3.0-only
+*/
+
+package atls
+
+import (
+	"context"
+	"io"
+	"net"
+	"net/http"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/edgelesssys/constellation/v2/internal/atls"
+	"github.com/edgelesssys/constellation/v2/internal/oid"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/goleak"
+)
+
+func TestMain(m *testing.M) {
+	goleak.VerifyTestMain(m)
+}
+
+func TestHTTP(t *testing.T) {
+	testCases := map[string]struct {
+		serverIssuer     Issuer
+		serverValidators []Validator
+		clientIssuer     Issuer
+		clientValidators []Validator
+		serverOpts       []Option
+		clientOpts       []Option
+		wantClaims       string
+		wantErr          bool
+	}{
+		"server attested": {
+			serverIssuer:     atls.NewFakeIssuer(oid.QEMU{}),
+			clientValidators: []Validator{atls.NewFakeValidator(oid.QEMU{})},
+		},
+		"mutual attestation": {
+			serverIssuer:     atls.NewFakeIssuer(oid.QEMU{}),
+			serverValidators: []Validator{atls.NewFakeValidator(oid.GCP{})},
+			clientIssuer:     atls.NewFakeIssuer(oid.GCP{}),
+			clientValidators: []Validator{atls.NewFakeValidator(oid.QEMU{})},
+			wantClaims:       "GCP",
+		},
+		"mutual attestation with instance ID": {
+			serverIssuer:     atls.NewFakeIssuer(oid.QEMU{}),
+			serverValidators: []Validator{&fakeClaimsValidator{FakeValidator: atls.NewFakeValidator(oid.GCP{}), instanceID: "instance-0"}},
+			clientIssuer:     atls.NewFakeIssuer(oid.GCP{}),
+			clientValidators: []Validator{atls.NewFakeValidator(oid.QEMU{})},
+			wantClaims:       "GCP instance-0",
+		},
+		"mutual attestation with cache": {
+			serverIssuer:     atls.NewFakeIssuer(oid.QEMU{}),
+			serverValidators: []Validator{atls.NewFakeValidator(oid.GCP{})},
+			clientIssuer:     atls.NewFakeIssuer(oid.GCP{}),
+			clientValidators: []Validator{atls.NewFakeValidator(oid.QEMU{})},
+			serverOpts:       []Option{WithCache(NewCache(time.Hour))},
+			clientOpts:       []Option{WithCache(NewCache(time.Hour))},
+			wantClaims:       "GCP",
+		},
+		"server attestation not verified": {
+			serverIssuer: atls.NewFakeIssuer(oid.QEMU{}),
+		},
+		"server uses unknown variant": {
+			serverIssuer:     atls.NewFakeIssuer(oid.QEMU{}),
+			clientValidators: []Validator{atls.NewFakeValidator(oid.GCP{})},
+			wantErr:          true,
+		},
+		"client does not attest itself": {
+			serverIssuer:     atls.NewFakeIssuer(oid.QEMU{}),
+			serverValidators: []Validator{atls.NewFakeValidator(oid.GCP{})},
+			clientValidators: []Validator{atls.NewFakeValidator(oid.QEMU{})},
+			wantErr:          true,
+		},
+	}
+
+	for name, tc := range testCases {
+		t.Run(name, func(t *testing.T) {
+			assert := assert.New(t)
+			require := require.New(t)
+
+			handler := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				claims, ok := ClaimsFromContext(r.Context())
+				if !ok {
+					_, _ = io.WriteString(w, "unattested")
+					return
+				}
+				_, _ = io.WriteString(w, strings.TrimSpace(claims.Provider+" "+claims.InstanceID))
+			})
+			server, err := NewHTTPServer("", handler, tc.serverIssuer, tc.serverValidators, tc.serverOpts...)
+			require.NoError(err)
+			lis, err := net.Listen("tcp", "127.0.0.1:0")
+			require.NoError(err)
+			serveErr := make(chan error, 1)
+			go func() { serveErr <- server.ServeTLS(lis, "", "") }()
+			defer func() {
+				assert.NoError(server.Shutdown(context.Background()))
+				assert.ErrorIs(<-serveErr, http.ErrServerClosed)
+			}()
+
+			transport := NewHTTPTransport(tc.clientIssuer, tc.clientValidators, tc.clientOpts...)
+			defer transport.CloseIdleConnections()
+			// a proxy would receive the aTLS handshake intended for the server
+			assert.Nil(transport.Proxy)
+			client := &http.Client{Transport: transport}
+
+			// each connection is attested using a fresh nonce, or resumes the previous session if caching is enabled
+			for i := 0; i < 2; i++ {
+				req, err := http.NewRequestWithContext(context.Background(), http.MethodGet, "https://"+lis.Addr().String(), http.NoBody)
+				require.NoError(err)
+				req.Close = true
+
+				resp, err := client.Do(req)
+				if tc.wantErr {
+					assert.Error(err)
+					return
+				}
+				require.NoError(err)
+				body, err := io.ReadAll(resp.Body)
+				resp.Body.Close()
+				require.NoError(err)
+
+				if tc.wantClaims != "" {
+					assert.Equal(tc.wantClaims, string(body))
+				} else {
+					assert.Equal("unattested", string(body))
+				}
+			}
+		})
+	}
+}
+
+func TestClaimsFromContext(t *testing.T) {
+	assert := assert.New(t)
+
+	_, ok := ClaimsFromContext(context.Background())
+	assert.False(ok)
+
+	_, ok = ClaimsFromContext(atls.ContextWithPeer(context.Background(), &atls.Peer{}))
+	assert.False(ok)
+}
+
+type fakeClaimsValidator struct {
+	*atls.FakeValidator
+	instanceID string
+}
+
+func (v *fakeClaimsValidator) ValidateWithInstanceID(attDoc []byte, nonce []byte) ([]byte, string, error) {
+	userData, err := v.Validate(attDoc, nonce)
+	return userData, v.instanceID, err
+}
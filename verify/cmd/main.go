@@ -14,6 +14,7 @@ import (
 	"github.com/edgelesssys/constellation/v2/internal/attestation/azure"
 	"github.com/edgelesssys/constellation/v2/internal/attestation/gcp"
 	"github.com/edgelesssys/constellation/v2/internal/attestation/qemu"
+	"github.com/edgelesssys/constellation/v2/internal/attestation/tdx"
 	"github.com/edgelesssys/constellation/v2/internal/constants"
 	"github.com/edgelesssys/constellation/v2/internal/logger"
 	"github.com/edgelesssys/constellation/v2/verify/server"
@@ -37,7 +38,11 @@ func main() {
 	case "azure":
 		issuer = azure.NewIssuer()
 	case "qemu":
-		issuer = qemu.NewIssuer()
+		if tdx.Available() {
+			issuer = tdx.NewIssuer()
+		} else {
+			issuer = qemu.NewIssuer()
+		}
 	default:
 		log.With(zap.String("cloudProvider", *provider)).Fatalf("Unknown cloud provider")
 	}